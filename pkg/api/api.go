@@ -6,6 +6,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"time"
 
@@ -14,12 +15,18 @@ import (
 	_ "github.com/ksysoev/make-it-public/pkg/api/docs" // needed for swagger
 	"github.com/ksysoev/make-it-public/pkg/api/middleware"
 	"github.com/ksysoev/make-it-public/pkg/core"
+	"github.com/ksysoev/make-it-public/pkg/core/audit"
+	"github.com/ksysoev/make-it-public/pkg/core/domain"
+	"github.com/ksysoev/make-it-public/pkg/core/ipfilter"
 	"github.com/ksysoev/make-it-public/pkg/core/token"
+	"github.com/ksysoev/make-it-public/pkg/metrics"
 	httpSwagger "github.com/swaggo/http-swagger/v2"
 )
 
 type Config struct {
-	Listen string `mapstructure:"listen"`
+	Listen string     `mapstructure:"listen"`
+	TLS    TLSConfig  `mapstructure:"tls"`
+	Auth   AuthConfig `mapstructure:"auth"`
 }
 
 type API struct {
@@ -28,16 +35,42 @@ type API struct {
 }
 
 type Service interface {
-	GenerateToken(ctx context.Context, keyID string, ttl int, tokenType token.TokenType) (*token.Token, error)
+	GenerateToken(ctx context.Context, keyID string, ttl int, tokenType token.TokenType, ipFilter *ipfilter.Filter, port int) (*token.Token, error)
 	DeleteToken(ctx context.Context, tokenID string) error
+	GetToken(ctx context.Context, keyID string) (*core.TokenInfo, error)
+	ListTokens(ctx context.Context) ([]*core.TokenInfo, error)
+	UpdateTokenTTL(ctx context.Context, keyID string, ttl int) (*core.TokenInfo, error)
+	AddDomain(ctx context.Context, keyID, name string) (*domain.Domain, error)
+	VerifyDomain(ctx context.Context, keyID, name string) (*domain.Domain, error)
+	ListDomains(ctx context.Context, keyID string) ([]*domain.Domain, error)
+	RemoveDomain(ctx context.Context, keyID, name string) error
+	ResolveDomain(ctx context.Context, host string) (string, error)
+	SetOfflinePage(ctx context.Context, keyID, page string) error
+	RemoveOfflinePage(ctx context.Context, keyID string) error
+	RecentSessions(ctx context.Context, keyID string, limit int) ([]*audit.Record, error)
 	CheckHealth(ctx context.Context) error
 }
 
+// HealthDraining is the body of health check responses while the server drains before stopping.
+const HealthDraining = "draining"
+
 const (
-	HealthCheckEndpoint   = "GET /health"
-	GenerateTokenEndpoint = "POST /token"
-	RevokeTokenEndpoint   = "DELETE /token/{keyID}" //nolint:gosec // false positive, no hardcoded credentials
-	SwaggerEndpoint       = "/swagger/"
+	HealthCheckEndpoint       = "GET /health"
+	GenerateTokenEndpoint     = "POST /token"
+	RevokeTokenEndpoint       = "DELETE /token/{keyID}" //nolint:gosec // false positive, no hardcoded credentials
+	ListTokensEndpoint        = "GET /token"
+	GetTokenEndpoint          = "GET /token/{keyID}"
+	UpdateTokenEndpoint       = "PATCH /token/{keyID}"
+	AddDomainEndpoint         = "POST /token/{keyID}/domains"
+	ListDomainsEndpoint       = "GET /token/{keyID}/domains"
+	VerifyDomainEndpoint      = "POST /token/{keyID}/domains/{domain}/verify"
+	RemoveDomainEndpoint      = "DELETE /token/{keyID}/domains/{domain}"
+	CheckDomainEndpoint       = "GET /domains/check"
+	ListSessionsEndpoint      = "GET /token/{keyID}/sessions"
+	SetOfflinePageEndpoint    = "PUT /token/{keyID}/offline-page"
+	RemoveOfflinePageEndpoint = "DELETE /token/{keyID}/offline-page"
+	SwaggerEndpoint           = "/swagger/"
+	MetricsEndpoint           = "GET /metrics"
 )
 
 // New initializes and returns a new API instance configured with the provided Config and Service.
@@ -58,23 +91,28 @@ func New(cfg Config, svc Service) *API {
 
 // Run starts the API server and handles incoming HTTP requests.
 // It configures the HTTP routes, middleware, and server settings based on the API's configuration.
+// The server uses HTTPS when a TLS certificate is configured.
 // Accepts ctx to gracefully shut down the server when context is canceled.
 // Returns error if the server fails to start or encounters issues during runtime.
 func (a *API) Run(ctx context.Context) error {
-	router := http.NewServeMux()
-	genToken := middleware.Metrics()(http.HandlerFunc(a.generateTokenHandler))
-	revokeToken := middleware.Metrics()(http.HandlerFunc(a.RevokeTokenHandler))
-
-	router.Handle(GenerateTokenEndpoint, genToken)
-	router.Handle(RevokeTokenEndpoint, revokeToken)
-	router.HandleFunc(HealthCheckEndpoint, a.healthCheckHandler)
-	router.HandleFunc(SwaggerEndpoint, httpSwagger.WrapHandler)
+	handler, err := a.handler()
+	if err != nil {
+		return err
+	}
 
 	server := &http.Server{
 		Addr:              a.config.Listen,
 		ReadHeaderTimeout: 5 * time.Second,
 		WriteTimeout:      5 * time.Second,
-		Handler:           router,
+		Handler:           handler,
+	}
+
+	useTLS := a.config.TLS.CertFile != ""
+	if useTLS {
+		server.TLSConfig, err = a.config.TLS.tlsConfig()
+		if err != nil {
+			return fmt.Errorf("failed to configure TLS: %w", err)
+		}
 	}
 
 	go func() {
@@ -83,25 +121,79 @@ func (a *API) Run(ctx context.Context) error {
 		_ = server.Close()
 	}()
 
-	if err := server.ListenAndServe(); err != http.ErrServerClosed {
+	if useTLS {
+		err = server.ListenAndServeTLS(a.config.TLS.CertFile, a.config.TLS.KeyFile)
+	} else {
+		err = server.ListenAndServe()
+	}
+
+	if err != http.ErrServerClosed {
 		return err
 	}
 
 	return nil
 }
 
+// handler builds the router of the API. Management endpoints require the scope they are registered with,
+// unless authentication is disabled; health, metrics, documentation and domain check endpoints are always public.
+func (a *API) handler() (http.Handler, error) {
+	auth, err := newAuthenticator(a.config.Auth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure authentication: %w", err)
+	}
+
+	var authenticate middleware.Authenticator
+	if auth != nil {
+		authenticate = auth.authenticate
+	} else {
+		slog.Warn("management API authentication is disabled, configure api.auth to enable it")
+	}
+
+	router := http.NewServeMux()
+	protect := func(scope string, h http.HandlerFunc) http.Handler {
+		return middleware.Metrics()(middleware.RequireScope(authenticate, scope)(h))
+	}
+
+	router.Handle(GenerateTokenEndpoint, protect(ScopeTokenCreate, a.generateTokenHandler))
+	router.Handle(RevokeTokenEndpoint, protect(ScopeTokenRevoke, a.RevokeTokenHandler))
+	router.Handle(ListTokensEndpoint, protect(ScopeTokenRead, a.listTokensHandler))
+	router.Handle(GetTokenEndpoint, protect(ScopeTokenRead, a.getTokenHandler))
+	router.Handle(UpdateTokenEndpoint, protect(ScopeTokenUpdate, a.updateTokenHandler))
+	router.Handle(AddDomainEndpoint, protect(ScopeDomainWrite, a.addDomainHandler))
+	router.Handle(ListDomainsEndpoint, protect(ScopeDomainRead, a.listDomainsHandler))
+	router.Handle(VerifyDomainEndpoint, protect(ScopeDomainWrite, a.verifyDomainHandler))
+	router.Handle(RemoveDomainEndpoint, protect(ScopeDomainWrite, a.removeDomainHandler))
+	router.Handle(ListSessionsEndpoint, protect(ScopeAuditRead, a.listSessionsHandler))
+	router.Handle(SetOfflinePageEndpoint, protect(ScopeTokenUpdate, a.setOfflinePageHandler))
+	router.Handle(RemoveOfflinePageEndpoint, protect(ScopeTokenUpdate, a.removeOfflinePageHandler))
+	router.HandleFunc(CheckDomainEndpoint, a.checkDomainHandler)
+	router.HandleFunc(HealthCheckEndpoint, a.healthCheckHandler)
+	router.HandleFunc(SwaggerEndpoint, httpSwagger.WrapHandler)
+	router.Handle(MetricsEndpoint, metrics.Handler())
+
+	return router, nil
+}
+
 // healthCheckHandler handles health check requests and validates the service's health status.
 // It queries the service's health and responds with "healthy" if all checks are successful.
+// Returns HTTP 503 with "draining" while the server drains before stopping.
 // Returns HTTP 500 if the health check fails or if writing the response encounters an error.
 // @Summary Health Check
 // @Description Returns the health status of the API.
 // @Tags Health
 // @Produce text/plain
 // @Success 200 {string} string "healthy"
+// @Failure 503 {string} string "draining"
 // @Failure 500 {string} string "Internal Server Error"
 // @Router /health [get]
 func (a *API) healthCheckHandler(w http.ResponseWriter, r *http.Request) {
-	if err := a.svc.CheckHealth(r.Context()); err != nil {
+	err := a.svc.CheckHealth(r.Context())
+
+	switch {
+	case errors.Is(err, core.ErrDraining):
+		http.Error(w, HealthDraining, http.StatusServiceUnavailable)
+		return
+	case err != nil:
 		slog.ErrorContext(r.Context(), "Health check failed", "error", err)
 
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
@@ -123,17 +215,19 @@ func (a *API) healthCheckHandler(w http.ResponseWriter, r *http.Request) {
 // generateTokenHandler is an endpoint to create API token.
 // It optionally accepts a key ID, which is automatically generated if not provided.
 // It also optionally accepts a TTL for API token, which is set to a default value if not provided.
-// It accepts a token type (web or tcp), which defaults to web if not provided.
-// As a part of response, it returns the key ID, generated token, TTL in seconds, and token type.
+// It accepts a token type (web, tcp, grpc, udp or tls), which defaults to web if not provided.
+// It optionally accepts lists of allowed and denied CIDRs restricting which client IPs can reach the tunnel.
+// For tcp tokens it optionally accepts a port that is reserved for the token.
+// As a part of response, it returns the key ID, generated token, TTL in seconds, token type, CIDR rules and reserved port.
 // @Summary Generate Token
-// @Description Generates an API token with an optional key ID, TTL, and type.
+// @Description Generates an API token with an optional key ID, TTL, type, client IP allow/deny lists, and reserved TCP port.
 // @Tags Token
 // @Accept json
 // @Produce json
 // @Param request body GenerateTokenRequest true "Generate Token Request"
 // @Success 201 {object} GenerateTokenResponse
 // @Failure 400 {string} string "Bad Request"
-// @Failure 409 {string} string "Duplicate token ID"
+// @Failure 409 {string} string "Duplicate token ID or port already reserved"
 // @Failure 500 {string} string "Internal Server Error"
 // @Router /token [post]
 func (a *API) generateTokenHandler(w http.ResponseWriter, r *http.Request) {
@@ -158,12 +252,24 @@ func (a *API) generateTokenHandler(w http.ResponseWriter, r *http.Request) {
 		tokenType = token.TokenTypeWeb
 	case "tcp":
 		tokenType = token.TokenTypeTCP
+	case "grpc":
+		tokenType = token.TokenTypeGRPC
+	case "udp":
+		tokenType = token.TokenTypeUDP
+	case "tls":
+		tokenType = token.TokenTypeTLS
 	default:
-		http.Error(w, "Invalid token type: must be 'web' or 'tcp'", http.StatusBadRequest)
+		http.Error(w, "Invalid token type: must be 'web', 'tcp', 'grpc', 'udp' or 'tls'", http.StatusBadRequest)
 		return
 	}
 
-	t, err := a.svc.GenerateToken(r.Context(), req.KeyID, req.TTL, tokenType)
+	ipFilter, err := ipfilter.New(req.AllowedCIDRs, req.DeniedCIDRs)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	t, err := a.svc.GenerateToken(r.Context(), req.KeyID, req.TTL, tokenType, ipFilter, req.Port)
 
 	switch {
 	case errors.Is(err, token.ErrTokenInvalid):
@@ -178,9 +284,18 @@ func (a *API) generateTokenHandler(w http.ResponseWriter, r *http.Request) {
 	case errors.Is(err, token.ErrInvalidTokenType):
 		http.Error(w, token.ErrInvalidTokenType.Error(), http.StatusBadRequest)
 		return
+	case errors.Is(err, token.ErrInvalidPort):
+		http.Error(w, token.ErrInvalidPort.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, token.ErrPortNotSupported):
+		http.Error(w, token.ErrPortNotSupported.Error(), http.StatusBadRequest)
+		return
 	case errors.Is(err, core.ErrDuplicateTokenID):
 		http.Error(w, "Duplicate token ID", http.StatusConflict)
 		return
+	case errors.Is(err, core.ErrPortReserved):
+		http.Error(w, core.ErrPortReserved.Error(), http.StatusConflict)
+		return
 	case err != nil:
 		slog.ErrorContext(r.Context(), "Failed to generate token", "error", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
@@ -193,6 +308,12 @@ func (a *API) generateTokenHandler(w http.ResponseWriter, r *http.Request) {
 		KeyID: t.ID,
 		TTL:   int(t.TTL.Seconds()),
 		Type:  t.Type.String(),
+		Port:  t.Port,
+	}
+
+	if t.IPFilter != nil {
+		resp.AllowedCIDRs = t.IPFilter.AllowedStrings()
+		resp.DeniedCIDRs = t.IPFilter.DeniedStrings()
 	}
 
 	w.WriteHeader(http.StatusCreated)
@@ -241,3 +362,125 @@ func (a *API) RevokeTokenHandler(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// listTokensHandler lists all tokens with their type, remaining TTL and connection status.
+// @Summary List Tokens
+// @Description Lists all API tokens with their type, remaining TTL in seconds and whether a client is connected.
+// @Tags Token
+// @Produce json
+// @Success 200 {array} TokenResponse
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /token [get]
+func (a *API) listTokensHandler(w http.ResponseWriter, r *http.Request) {
+	infos, err := a.svc.ListTokens(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to list tokens", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+		return
+	}
+
+	resp := make([]TokenResponse, 0, len(infos))
+	for _, info := range infos {
+		resp = append(resp, newTokenResponse(info))
+	}
+
+	writeJSON(w, r, http.StatusOK, resp)
+}
+
+// getTokenHandler returns the type, remaining TTL and connection status of the token identified by the key ID in the request path.
+// @Summary Get Token
+// @Description Returns the type, remaining TTL in seconds and connection status of an API token.
+// @Tags Token
+// @Produce json
+// @Param keyID path string true "API Key ID"
+// @Success 200 {object} TokenResponse
+// @Failure 404 {string} string "Token not found"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /token/{keyID} [get]
+func (a *API) getTokenHandler(w http.ResponseWriter, r *http.Request) {
+	info, err := a.svc.GetToken(r.Context(), r.PathValue("keyID"))
+
+	switch {
+	case errors.Is(err, core.ErrTokenNotFound):
+		http.Error(w, "Token not found", http.StatusNotFound)
+		return
+	case err != nil:
+		slog.ErrorContext(r.Context(), "Failed to get token", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, newTokenResponse(info))
+}
+
+// updateTokenHandler sets the remaining TTL of the token identified by the key ID in the request path.
+// The secret of the token is not changed, so the token keeps working for connected clients.
+// @Summary Update Token
+// @Description Extends or shortens the TTL of an API token without rotating its secret.
+// @Tags Token
+// @Accept json
+// @Produce json
+// @Param keyID path string true "API Key ID"
+// @Param request body UpdateTokenRequest true "Update Token Request"
+// @Success 200 {object} TokenResponse
+// @Failure 400 {string} string "Bad Request"
+// @Failure 404 {string} string "Token not found"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /token/{keyID} [patch]
+func (a *API) updateTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var req UpdateTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+
+		return
+	}
+
+	info, err := a.svc.UpdateTokenTTL(r.Context(), r.PathValue("keyID"), req.TTL)
+
+	switch {
+	case errors.Is(err, token.ErrInvalidTokenTTL):
+		http.Error(w, token.ErrInvalidTokenTTL.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, core.ErrTokenNotFound):
+		http.Error(w, "Token not found", http.StatusNotFound)
+		return
+	case err != nil:
+		slog.ErrorContext(r.Context(), "Failed to update token", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, newTokenResponse(info))
+}
+
+func newTokenResponse(info *core.TokenInfo) TokenResponse {
+	resp := TokenResponse{
+		KeyID:     info.Token.ID,
+		TTL:       int(info.Token.TTL.Seconds()),
+		Port:      info.Token.Port,
+		Connected: info.Connected,
+	}
+
+	if info.Token.Type != "" {
+		resp.Type = info.Token.Type.String()
+	}
+
+	if info.Token.IPFilter != nil {
+		resp.AllowedCIDRs = info.Token.IPFilter.AllowedStrings()
+		resp.DeniedCIDRs = info.Token.IPFilter.DeniedStrings()
+	}
+
+	return resp
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to encode response", "error", err)
+	}
+}
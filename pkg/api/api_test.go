@@ -11,9 +11,11 @@ import (
 	"time"
 
 	"github.com/ksysoev/make-it-public/pkg/core"
+	"github.com/ksysoev/make-it-public/pkg/core/ipfilter"
 	"github.com/ksysoev/make-it-public/pkg/core/token"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 type mockResponseWriter struct {
@@ -53,6 +55,21 @@ func TestHealthCheckHandler_Error(t *testing.T) {
 	assert.Equal(t, rr.Body.String(), "Internal Server Error\n", "Body body does not match expected")
 }
 
+func TestHealthCheckHandler_Draining(t *testing.T) {
+	svc := NewMockService(t)
+	svc.EXPECT().CheckHealth(mock.Anything).Return(core.ErrDraining).Once()
+
+	api := New(Config{Listen: ":0"}, svc)
+	req := httptest.NewRequest(http.MethodGet, "/health", http.NoBody)
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(api.healthCheckHandler)
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	assert.Equal(t, HealthDraining+"\n", rr.Body.String())
+}
+
 func TestGenerateTokenHandler(t *testing.T) {
 	auth := NewMockService(t)
 	api := New(Config{}, auth)
@@ -68,7 +85,7 @@ func TestGenerateTokenHandler(t *testing.T) {
 	})
 
 	t.Run("Success token generation", func(t *testing.T) {
-		auth.EXPECT().GenerateToken(mock.Anything, mock.Anything, 3600, mock.Anything).Return(&token.Token{
+		auth.EXPECT().GenerateToken(mock.Anything, mock.Anything, 3600, mock.Anything, mock.Anything, 0).Return(&token.Token{
 			ID:     "random-key-id",
 			Secret: "test-token",
 			TTL:    time.Hour,
@@ -95,7 +112,7 @@ func TestGenerateTokenHandler(t *testing.T) {
 	})
 
 	t.Run("Giving 0 TTL defaults to TTL of one hour", func(t *testing.T) {
-		auth.EXPECT().GenerateToken(mock.Anything, "test-key-id", 0, mock.Anything).Return(&token.Token{
+		auth.EXPECT().GenerateToken(mock.Anything, "test-key-id", 0, mock.Anything, mock.Anything, 0).Return(&token.Token{
 			ID:     "test-key-id",
 			Secret: "test-token",
 			TTL:    time.Hour,
@@ -122,8 +139,52 @@ func TestGenerateTokenHandler(t *testing.T) {
 		assert.Equal(t, 3600, response.TTL)
 	})
 
+	t.Run("Token with CIDR rules", func(t *testing.T) {
+		auth.EXPECT().GenerateToken(mock.Anything, "test-key-id", 3600, mock.Anything, mock.MatchedBy(func(f *ipfilter.Filter) bool {
+			return f != nil && f.Allow("10.1.1.1") && !f.Allow("10.0.0.1")
+		}), 0).RunAndReturn(func(_ context.Context, keyID string, _ int, _ token.TokenType, f *ipfilter.Filter, _ int) (*token.Token, error) {
+			return &token.Token{ID: keyID, Secret: "test-token", TTL: time.Hour, IPFilter: f}, nil
+		}).Once()
+
+		requestBody := GenerateTokenRequest{
+			KeyID:        "test-key-id",
+			TTL:          3600,
+			AllowedCIDRs: []string{"10.0.0.0/8"},
+			DeniedCIDRs:  []string{"10.0.0.1"},
+		}
+		body, _ := json.Marshal(requestBody)
+		req := httptest.NewRequest(http.MethodPost, "/token", bytes.NewBuffer(body))
+		rec := httptest.NewRecorder()
+
+		api.generateTokenHandler(rec, req)
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+
+		var response GenerateTokenResponse
+
+		err := json.Unmarshal(rec.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"10.0.0.0/8"}, response.AllowedCIDRs)
+		assert.Equal(t, []string{"10.0.0.1/32"}, response.DeniedCIDRs)
+	})
+
+	t.Run("Invalid CIDR", func(t *testing.T) {
+		requestBody := GenerateTokenRequest{
+			KeyID:        "test-key-id",
+			AllowedCIDRs: []string{"10.0.0.0/99"},
+		}
+		body, _ := json.Marshal(requestBody)
+		req := httptest.NewRequest(http.MethodPost, "/token", bytes.NewBuffer(body))
+		rec := httptest.NewRecorder()
+
+		api.generateTokenHandler(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "invalid CIDR")
+	})
+
 	t.Run("Token Generation Error", func(t *testing.T) {
-		auth.EXPECT().GenerateToken(mock.Anything, "test-key-id", 3600, mock.Anything).Return(nil, errors.New("token generation error")).Once()
+		auth.EXPECT().GenerateToken(mock.Anything, "test-key-id", 3600, mock.Anything, mock.Anything, 0).Return(nil, errors.New("token generation error")).Once()
 
 		requestBody := GenerateTokenRequest{
 			KeyID: "test-key-id",
@@ -138,7 +199,7 @@ func TestGenerateTokenHandler(t *testing.T) {
 	})
 
 	t.Run("Duplicate Token ID Error", func(t *testing.T) {
-		auth.EXPECT().GenerateToken(mock.Anything, "test-key-id", 3600, mock.Anything).Return(nil, core.ErrDuplicateTokenID).Once()
+		auth.EXPECT().GenerateToken(mock.Anything, "test-key-id", 3600, mock.Anything, mock.Anything, 0).Return(nil, core.ErrDuplicateTokenID).Once()
 
 		requestBody := GenerateTokenRequest{
 			KeyID: "test-key-id",
@@ -153,8 +214,121 @@ func TestGenerateTokenHandler(t *testing.T) {
 		assert.Equal(t, "Duplicate token ID\n", rec.Body.String())
 	})
 
+	t.Run("TCP token with reserved port", func(t *testing.T) {
+		auth.EXPECT().GenerateToken(mock.Anything, "test-key-id", 3600, token.TokenTypeTCP, mock.Anything, 30000).Return(&token.Token{
+			ID:     "test-key-id",
+			Secret: "test-token",
+			TTL:    time.Hour,
+			Type:   token.TokenTypeTCP,
+			Port:   30000,
+		}, nil).Once()
+
+		body, _ := json.Marshal(GenerateTokenRequest{KeyID: "test-key-id", TTL: 3600, Type: "tcp", Port: 30000})
+		req := httptest.NewRequest(http.MethodPost, "/token", bytes.NewBuffer(body))
+		rec := httptest.NewRecorder()
+
+		api.generateTokenHandler(rec, req)
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+
+		var response GenerateTokenResponse
+
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		assert.Equal(t, 30000, response.Port)
+	})
+
+	t.Run("gRPC token", func(t *testing.T) {
+		auth.EXPECT().GenerateToken(mock.Anything, "test-key-id", 3600, token.TokenTypeGRPC, mock.Anything, 0).Return(&token.Token{
+			ID:     "test-key-id",
+			Secret: "test-token",
+			TTL:    time.Hour,
+			Type:   token.TokenTypeGRPC,
+		}, nil).Once()
+
+		body, _ := json.Marshal(GenerateTokenRequest{KeyID: "test-key-id", TTL: 3600, Type: "grpc"})
+		req := httptest.NewRequest(http.MethodPost, "/token", bytes.NewBuffer(body))
+		rec := httptest.NewRecorder()
+
+		api.generateTokenHandler(rec, req)
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+
+		var response GenerateTokenResponse
+
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		assert.Equal(t, "grpc", response.Type)
+	})
+
+	t.Run("UDP token", func(t *testing.T) {
+		auth.EXPECT().GenerateToken(mock.Anything, "test-key-id", 3600, token.TokenTypeUDP, mock.Anything, 0).Return(&token.Token{
+			ID:     "test-key-id",
+			Secret: "test-token",
+			TTL:    time.Hour,
+			Type:   token.TokenTypeUDP,
+		}, nil).Once()
+
+		body, _ := json.Marshal(GenerateTokenRequest{KeyID: "test-key-id", TTL: 3600, Type: "udp"})
+		req := httptest.NewRequest(http.MethodPost, "/token", bytes.NewBuffer(body))
+		rec := httptest.NewRecorder()
+
+		api.generateTokenHandler(rec, req)
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+
+		var response GenerateTokenResponse
+
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		assert.Equal(t, "udp", response.Type)
+	})
+
+	t.Run("TLS token", func(t *testing.T) {
+		auth.EXPECT().GenerateToken(mock.Anything, "test-key-id", 3600, token.TokenTypeTLS, mock.Anything, 0).Return(&token.Token{
+			ID:     "test-key-id",
+			Secret: "test-token",
+			TTL:    time.Hour,
+			Type:   token.TokenTypeTLS,
+		}, nil).Once()
+
+		body, _ := json.Marshal(GenerateTokenRequest{KeyID: "test-key-id", TTL: 3600, Type: "tls"})
+		req := httptest.NewRequest(http.MethodPost, "/token", bytes.NewBuffer(body))
+		rec := httptest.NewRecorder()
+
+		api.generateTokenHandler(rec, req)
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+
+		var response GenerateTokenResponse
+
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		assert.Equal(t, "tls", response.Type)
+	})
+
+	t.Run("Port already reserved", func(t *testing.T) {
+		auth.EXPECT().GenerateToken(mock.Anything, "test-key-id", 3600, token.TokenTypeTCP, mock.Anything, 30000).Return(nil, core.ErrPortReserved).Once()
+
+		body, _ := json.Marshal(GenerateTokenRequest{KeyID: "test-key-id", TTL: 3600, Type: "tcp", Port: 30000})
+		req := httptest.NewRequest(http.MethodPost, "/token", bytes.NewBuffer(body))
+		rec := httptest.NewRecorder()
+
+		api.generateTokenHandler(rec, req)
+
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	})
+
+	t.Run("Port for web token", func(t *testing.T) {
+		auth.EXPECT().GenerateToken(mock.Anything, "test-key-id", 3600, token.TokenTypeWeb, mock.Anything, 30000).Return(nil, token.ErrPortNotSupported).Once()
+
+		body, _ := json.Marshal(GenerateTokenRequest{KeyID: "test-key-id", TTL: 3600, Port: 30000})
+		req := httptest.NewRequest(http.MethodPost, "/token", bytes.NewBuffer(body))
+		rec := httptest.NewRecorder()
+
+		api.generateTokenHandler(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
 	t.Run("JSON Encoding Error", func(_ *testing.T) {
-		auth.EXPECT().GenerateToken(mock.Anything, "test-key-id", 3600, mock.Anything).Return(&token.Token{
+		auth.EXPECT().GenerateToken(mock.Anything, "test-key-id", 3600, mock.Anything, mock.Anything, 0).Return(&token.Token{
 			ID:     "test-key-id",
 			Secret: "test-token",
 			TTL:    3600,
@@ -259,3 +433,119 @@ func TestRevokeTokenHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestListTokensHandler(t *testing.T) {
+	svc := NewMockService(t)
+	api := New(Config{}, svc)
+
+	filter, err := ipfilter.New([]string{"10.0.0.0/8"}, nil)
+	require.NoError(t, err)
+
+	svc.EXPECT().ListTokens(mock.Anything).Return([]*core.TokenInfo{
+		{Token: &token.Token{ID: "a", Type: token.TokenTypeWeb, TTL: time.Hour, IPFilter: filter}, Connected: true},
+		{Token: &token.Token{ID: "b"}},
+	}, nil).Once()
+
+	rec := httptest.NewRecorder()
+
+	api.listTokensHandler(rec, httptest.NewRequest(http.MethodGet, "/token", http.NoBody))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp []TokenResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	assert.Equal(t, []TokenResponse{
+		{KeyID: "a", Type: "web", TTL: 3600, Connected: true, AllowedCIDRs: []string{"10.0.0.0/8"}},
+		{KeyID: "b"},
+	}, resp)
+
+	svc.EXPECT().ListTokens(mock.Anything).Return(nil, errors.New("boom")).Once()
+
+	rec = httptest.NewRecorder()
+
+	api.listTokensHandler(rec, httptest.NewRequest(http.MethodGet, "/token", http.NoBody))
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+}
+
+func TestGetTokenHandler(t *testing.T) {
+	tests := []struct {
+		svcErr       error
+		name         string
+		expectedCode int
+	}{
+		{name: "found", expectedCode: http.StatusOK},
+		{name: "not found", svcErr: core.ErrTokenNotFound, expectedCode: http.StatusNotFound},
+		{name: "internal error", svcErr: errors.New("boom"), expectedCode: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewMockService(t)
+			api := New(Config{}, svc)
+
+			var info *core.TokenInfo
+			if tt.svcErr == nil {
+				info = &core.TokenInfo{Token: &token.Token{ID: "key1", Type: token.TokenTypeTCP, TTL: time.Minute}}
+			}
+
+			svc.EXPECT().GetToken(mock.Anything, "key1").Return(info, tt.svcErr)
+
+			req := httptest.NewRequest(http.MethodGet, "/token/key1", http.NoBody)
+			req.SetPathValue("keyID", "key1")
+
+			rec := httptest.NewRecorder()
+
+			api.getTokenHandler(rec, req)
+
+			assert.Equal(t, tt.expectedCode, rec.Code)
+
+			if tt.svcErr == nil {
+				var resp TokenResponse
+				require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+				assert.Equal(t, TokenResponse{KeyID: "key1", Type: "tcp", TTL: 60}, resp)
+			}
+		})
+	}
+}
+
+func TestUpdateTokenHandler(t *testing.T) {
+	tests := []struct {
+		svcErr       error
+		name         string
+		body         string
+		expectedCode int
+		callsService bool
+	}{
+		{name: "updated", body: `{"ttl": 7200}`, callsService: true, expectedCode: http.StatusOK},
+		{name: "invalid body", body: `{`, expectedCode: http.StatusBadRequest},
+		{name: "invalid ttl", body: `{"ttl": -1}`, callsService: true, svcErr: token.ErrInvalidTokenTTL, expectedCode: http.StatusBadRequest},
+		{name: "not found", body: `{"ttl": 7200}`, callsService: true, svcErr: core.ErrTokenNotFound, expectedCode: http.StatusNotFound},
+		{name: "internal error", body: `{"ttl": 7200}`, callsService: true, svcErr: errors.New("boom"), expectedCode: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewMockService(t)
+			api := New(Config{}, svc)
+
+			if tt.callsService {
+				var info *core.TokenInfo
+				if tt.svcErr == nil {
+					info = &core.TokenInfo{Token: &token.Token{ID: "key1", Type: token.TokenTypeWeb, TTL: 2 * time.Hour}}
+				}
+
+				svc.EXPECT().UpdateTokenTTL(mock.Anything, "key1", mock.Anything).Return(info, tt.svcErr)
+			}
+
+			req := httptest.NewRequest(http.MethodPatch, "/token/key1", bytes.NewBufferString(tt.body))
+			req.SetPathValue("keyID", "key1")
+
+			rec := httptest.NewRecorder()
+
+			api.updateTokenHandler(rec, req)
+
+			assert.Equal(t, tt.expectedCode, rec.Code)
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package api
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/ksysoev/make-it-public/pkg/api/middleware"
+)
+
+// Scopes granted to admin keys and client certificates.
+const (
+	ScopeTokenCreate = "token:create"
+	ScopeTokenRevoke = "token:revoke"
+	ScopeTokenRead   = "token:read"
+	ScopeTokenUpdate = "token:update"
+	ScopeDomainRead  = "domain:read"
+	ScopeDomainWrite = "domain:write"
+	ScopeAuditRead   = "audit:read"
+)
+
+var knownScopes = []string{ScopeTokenCreate, ScopeTokenRevoke, ScopeTokenRead, ScopeTokenUpdate, ScopeDomainRead, ScopeDomainWrite, ScopeAuditRead}
+
+// TLSConfig enables HTTPS on the API listener.
+// When ClientCAFile is set, client certificates signed by it are accepted as credentials.
+type TLSConfig struct {
+	CertFile     string `mapstructure:"cert_file"`
+	KeyFile      string `mapstructure:"key_file"`
+	ClientCAFile string `mapstructure:"client_ca_file"`
+}
+
+// AuthConfig lists the callers allowed to use the API.
+// When neither keys nor clients are configured, the API does not require authentication.
+type AuthConfig struct {
+	Keys    []KeyConfig    `mapstructure:"keys"`
+	Clients []ClientConfig `mapstructure:"clients"`
+}
+
+// KeyConfig is an admin API key, sent as a Bearer token.
+// Only the hex-encoded SHA-256 hash of the key is configured.
+type KeyConfig struct {
+	Name   string   `mapstructure:"name"`
+	Hash   string   `mapstructure:"hash"`
+	Scopes []string `mapstructure:"scopes"`
+}
+
+// ClientConfig grants scopes to client certificates with the given subject common name.
+type ClientConfig struct {
+	CommonName string   `mapstructure:"common_name"`
+	Scopes     []string `mapstructure:"scopes"`
+}
+
+// Enabled reports whether any callers are configured, which makes authentication mandatory.
+func (c *AuthConfig) Enabled() bool {
+	return len(c.Keys) > 0 || len(c.Clients) > 0
+}
+
+// Validate checks the TLS and authentication settings of the API.
+// Returns an error if TLS files are only partially configured, client certificates are configured without a CA,
+// or an admin key has an invalid hash or unknown scopes.
+func (c *Config) Validate() error {
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		return errors.New("both tls cert_file and key_file must be set")
+	}
+
+	if c.TLS.ClientCAFile != "" && c.TLS.CertFile == "" {
+		return errors.New("tls client_ca_file requires cert_file and key_file")
+	}
+
+	if len(c.Auth.Clients) > 0 && c.TLS.ClientCAFile == "" {
+		return errors.New("auth clients require tls client_ca_file")
+	}
+
+	for _, k := range c.Auth.Keys {
+		if _, err := parseKeyHash(k.Hash); err != nil {
+			return fmt.Errorf("invalid hash of key %q: %w", k.Name, err)
+		}
+
+		if err := validateScopes(k.Scopes); err != nil {
+			return fmt.Errorf("invalid scopes of key %q: %w", k.Name, err)
+		}
+	}
+
+	for _, cl := range c.Auth.Clients {
+		if cl.CommonName == "" {
+			return errors.New("auth client common_name must not be empty")
+		}
+
+		if err := validateScopes(cl.Scopes); err != nil {
+			return fmt.Errorf("invalid scopes of client %q: %w", cl.CommonName, err)
+		}
+	}
+
+	return nil
+}
+
+// HashKey returns the hex-encoded SHA-256 hash of an admin API key, as expected in KeyConfig.Hash.
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// authenticator identifies API callers by their admin key or client certificate.
+type authenticator struct {
+	keys    map[[sha256.Size]byte]*middleware.Principal
+	clients map[string]*middleware.Principal
+}
+
+// newAuthenticator creates an authenticator for the callers in cfg.
+// Returns nil if authentication is disabled, or an error if a key hash is invalid.
+func newAuthenticator(cfg AuthConfig) (*authenticator, error) {
+	if !cfg.Enabled() {
+		return nil, nil
+	}
+
+	a := &authenticator{
+		keys:    make(map[[sha256.Size]byte]*middleware.Principal, len(cfg.Keys)),
+		clients: make(map[string]*middleware.Principal, len(cfg.Clients)),
+	}
+
+	for _, k := range cfg.Keys {
+		hash, err := parseKeyHash(k.Hash)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hash of key %q: %w", k.Name, err)
+		}
+
+		a.keys[hash] = &middleware.Principal{Name: k.Name, Scopes: k.Scopes}
+	}
+
+	for _, cl := range cfg.Clients {
+		a.clients[cl.CommonName] = &middleware.Principal{Name: cl.CommonName, Scopes: cl.Scopes}
+	}
+
+	return a, nil
+}
+
+// authenticate identifies the caller of r by a verified client certificate or, failing that, by a Bearer admin key.
+func (a *authenticator) authenticate(r *http.Request) (*middleware.Principal, bool) {
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+		if p, ok := a.clients[r.TLS.VerifiedChains[0][0].Subject.CommonName]; ok {
+			return p, true
+		}
+	}
+
+	key, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || key == "" {
+		return nil, false
+	}
+
+	p, ok := a.keys[sha256.Sum256([]byte(key))]
+
+	return p, ok
+}
+
+// tlsConfig builds the TLS configuration of the API listener.
+// Client certificates are optional, so callers may still authenticate with admin keys.
+func (c *TLSConfig) tlsConfig() (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if c.ClientCAFile == "" {
+		return cfg, nil
+	}
+
+	pem, err := os.ReadFile(c.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client CA: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.New("no certificates found in client CA file")
+	}
+
+	cfg.ClientCAs = pool
+	cfg.ClientAuth = tls.VerifyClientCertIfGiven
+
+	return cfg, nil
+}
+
+func parseKeyHash(s string) ([sha256.Size]byte, error) {
+	var hash [sha256.Size]byte
+
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return hash, fmt.Errorf("hash must be hex-encoded: %w", err)
+	}
+
+	if len(b) != sha256.Size {
+		return hash, fmt.Errorf("hash must be %d bytes long", sha256.Size)
+	}
+
+	copy(hash[:], b)
+
+	return hash, nil
+}
+
+func validateScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return errors.New("at least one scope is required")
+	}
+
+	for _, s := range scopes {
+		if !slices.Contains(knownScopes, s) {
+			return fmt.Errorf("unknown scope %q", s)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,160 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	validKey := KeyConfig{Name: "admin", Hash: HashKey("secret"), Scopes: []string{ScopeTokenRead}}
+
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{name: "no auth", cfg: Config{}},
+		{name: "valid key", cfg: Config{Auth: AuthConfig{Keys: []KeyConfig{validKey}}}},
+		{
+			name: "valid client",
+			cfg: Config{
+				TLS:  TLSConfig{CertFile: "cert.pem", KeyFile: "key.pem", ClientCAFile: "ca.pem"},
+				Auth: AuthConfig{Clients: []ClientConfig{{CommonName: "ops", Scopes: []string{ScopeTokenCreate}}}},
+			},
+		},
+		{name: "cert without key", cfg: Config{TLS: TLSConfig{CertFile: "cert.pem"}}, wantErr: true},
+		{name: "client CA without cert", cfg: Config{TLS: TLSConfig{ClientCAFile: "ca.pem"}}, wantErr: true},
+		{
+			name:    "client without CA",
+			cfg:     Config{Auth: AuthConfig{Clients: []ClientConfig{{CommonName: "ops", Scopes: []string{ScopeTokenRead}}}}},
+			wantErr: true,
+		},
+		{
+			name:    "plain text key",
+			cfg:     Config{Auth: AuthConfig{Keys: []KeyConfig{{Name: "admin", Hash: "secret", Scopes: []string{ScopeTokenRead}}}}},
+			wantErr: true,
+		},
+		{
+			name:    "unknown scope",
+			cfg:     Config{Auth: AuthConfig{Keys: []KeyConfig{{Name: "admin", Hash: HashKey("secret"), Scopes: []string{"token:*"}}}}},
+			wantErr: true,
+		},
+		{
+			name:    "no scopes",
+			cfg:     Config{Auth: AuthConfig{Keys: []KeyConfig{{Name: "admin", Hash: HashKey("secret")}}}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestHandler_Auth(t *testing.T) {
+	svc := NewMockService(t)
+	api := New(Config{
+		TLS: TLSConfig{CertFile: "cert.pem", KeyFile: "key.pem", ClientCAFile: "ca.pem"},
+		Auth: AuthConfig{
+			Keys: []KeyConfig{
+				{Name: "reader", Hash: HashKey("reader-key"), Scopes: []string{ScopeTokenRead}},
+				{Name: "admin", Hash: HashKey("admin-key"), Scopes: []string{ScopeTokenRead, ScopeTokenRevoke}},
+			},
+			Clients: []ClientConfig{{CommonName: "ops", Scopes: []string{ScopeTokenRevoke}}},
+		},
+	}, svc)
+
+	handler, err := api.handler()
+	require.NoError(t, err)
+
+	svc.EXPECT().DeleteToken(mock.Anything, "key1").Return(nil)
+
+	tests := []struct {
+		name       string
+		authHeader string
+		clientCN   string
+		wantStatus int
+	}{
+		{name: "no credentials", wantStatus: http.StatusUnauthorized},
+		{name: "unknown key", authHeader: "Bearer wrong-key", wantStatus: http.StatusUnauthorized},
+		{name: "key without scope", authHeader: "Bearer reader-key", wantStatus: http.StatusForbidden},
+		{name: "key with scope", authHeader: "Bearer admin-key", wantStatus: http.StatusNoContent},
+		{name: "client certificate with scope", clientCN: "ops", wantStatus: http.StatusNoContent},
+		{name: "unknown client certificate", clientCN: "stranger", wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodDelete, "/token/key1", http.NoBody)
+
+			if tt.authHeader != "" {
+				req.Header.Set("Authorization", tt.authHeader)
+			}
+
+			if tt.clientCN != "" {
+				cert := &x509.Certificate{Subject: pkix.Name{CommonName: tt.clientCN}}
+				req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+			}
+
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+
+			if tt.wantStatus == http.StatusUnauthorized {
+				assert.NotEmpty(t, rec.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+
+	// Health checks stay public.
+	svc.EXPECT().CheckHealth(mock.Anything).Return(nil)
+
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", http.NoBody))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// So do domain checks, which TLS terminating proxies make without credentials.
+	svc.EXPECT().ResolveDomain(mock.Anything, "app.example.com").Return("key1", nil)
+
+	rec = httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/domains/check?domain=app.example.com", http.NoBody))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestHandler_AuthDisabled(t *testing.T) {
+	svc := NewMockService(t)
+	api := New(Config{}, svc)
+
+	handler, err := api.handler()
+	require.NoError(t, err)
+
+	svc.EXPECT().DeleteToken(mock.Anything, "key1").Return(nil)
+
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/token/key1", http.NoBody))
+
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+}
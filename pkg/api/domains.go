@@ -0,0 +1,190 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/ksysoev/make-it-public/pkg/core"
+	"github.com/ksysoev/make-it-public/pkg/core/domain"
+)
+
+// addDomainHandler registers a custom domain for the token identified by the key ID in the request path.
+// The response contains the TXT record that must be published to verify ownership of the domain.
+// @Summary Add Domain
+// @Description Registers a custom domain for a token. The domain is routed to the tunnel once its ownership is verified.
+// @Tags Domain
+// @Accept json
+// @Produce json
+// @Param keyID path string true "API Key ID"
+// @Param request body AddDomainRequest true "Add Domain Request"
+// @Success 201 {object} DomainResponse
+// @Failure 400 {string} string "Bad Request"
+// @Failure 404 {string} string "Token not found"
+// @Failure 409 {string} string "Domain is already registered by the token or verified by another one"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /token/{keyID}/domains [post]
+func (a *API) addDomainHandler(w http.ResponseWriter, r *http.Request) {
+	var req AddDomainRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+
+		return
+	}
+
+	d, err := a.svc.AddDomain(r.Context(), r.PathValue("keyID"), req.Domain)
+
+	switch {
+	case errors.Is(err, domain.ErrInvalidDomain):
+		http.Error(w, domain.ErrInvalidDomain.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, core.ErrTokenNotFound):
+		http.Error(w, "Token not found", http.StatusNotFound)
+		return
+	case errors.Is(err, core.ErrDuplicateDomain):
+		http.Error(w, core.ErrDuplicateDomain.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		slog.ErrorContext(r.Context(), "Failed to add domain", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+		return
+	}
+
+	writeJSON(w, r, http.StatusCreated, newDomainResponse(d))
+}
+
+// listDomainsHandler lists the custom domains registered for the token identified by the key ID in the request path.
+// @Summary List Domains
+// @Description Lists the custom domains registered for a token.
+// @Tags Domain
+// @Produce json
+// @Param keyID path string true "API Key ID"
+// @Success 200 {array} DomainResponse
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /token/{keyID}/domains [get]
+func (a *API) listDomainsHandler(w http.ResponseWriter, r *http.Request) {
+	domains, err := a.svc.ListDomains(r.Context(), r.PathValue("keyID"))
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to list domains", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+		return
+	}
+
+	resp := make([]DomainResponse, 0, len(domains))
+	for _, d := range domains {
+		resp = append(resp, newDomainResponse(d))
+	}
+
+	writeJSON(w, r, http.StatusOK, resp)
+}
+
+// verifyDomainHandler checks the ownership TXT record of a custom domain and enables routing of the domain to the tunnel.
+// @Summary Verify Domain
+// @Description Checks the ownership TXT record of a custom domain and enables routing to the tunnel.
+// @Tags Domain
+// @Produce json
+// @Param keyID path string true "API Key ID"
+// @Param domain path string true "Domain name"
+// @Success 200 {object} DomainResponse
+// @Failure 404 {string} string "Domain not found"
+// @Failure 409 {string} string "Domain is verified by another token"
+// @Failure 422 {string} string "Domain ownership is not verified"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /token/{keyID}/domains/{domain}/verify [post]
+func (a *API) verifyDomainHandler(w http.ResponseWriter, r *http.Request) {
+	d, err := a.svc.VerifyDomain(r.Context(), r.PathValue("keyID"), r.PathValue("domain"))
+
+	switch {
+	case errors.Is(err, core.ErrDomainNotFound):
+		http.Error(w, "Domain not found", http.StatusNotFound)
+		return
+	case errors.Is(err, core.ErrDomainNotVerified):
+		http.Error(w, core.ErrDomainNotVerified.Error(), http.StatusUnprocessableEntity)
+		return
+	case errors.Is(err, core.ErrDuplicateDomain):
+		http.Error(w, core.ErrDuplicateDomain.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		slog.ErrorContext(r.Context(), "Failed to verify domain", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, newDomainResponse(d))
+}
+
+// removeDomainHandler removes a custom domain from the token identified by the key ID in the request path.
+// @Summary Remove Domain
+// @Description Removes a custom domain from a token.
+// @Tags Domain
+// @Param keyID path string true "API Key ID"
+// @Param domain path string true "Domain name"
+// @Success 204
+// @Failure 404 {string} string "Domain not found"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /token/{keyID}/domains/{domain} [delete]
+func (a *API) removeDomainHandler(w http.ResponseWriter, r *http.Request) {
+	err := a.svc.RemoveDomain(r.Context(), r.PathValue("keyID"), r.PathValue("domain"))
+
+	switch {
+	case errors.Is(err, core.ErrDomainNotFound):
+		http.Error(w, "Domain not found", http.StatusNotFound)
+		return
+	case err != nil:
+		slog.ErrorContext(r.Context(), "Failed to remove domain", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// checkDomainHandler reports whether the domain in the query is a verified custom domain of a tunnel.
+// It is public, so that a TLS terminating proxy can ask it before it requests a certificate for the domain
+// on demand, e.g. with the ask endpoint of Caddy's on_demand_tls.
+// @Summary Check Domain
+// @Description Responds with 200 if the domain is a verified custom domain, so that a certificate can be issued for it.
+// @Tags Domain
+// @Param domain query string true "Domain name"
+// @Success 200
+// @Failure 400 {string} string "Bad Request"
+// @Failure 404 {string} string "Domain not found"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /domains/check [get]
+func (a *API) checkDomainHandler(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("domain")
+	if name == "" {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	keyID, err := a.svc.ResolveDomain(r.Context(), name)
+
+	switch {
+	case err != nil:
+		slog.ErrorContext(r.Context(), "Failed to check domain", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+		return
+	case keyID == "":
+		http.Error(w, "Domain not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func newDomainResponse(d *domain.Domain) DomainResponse {
+	return DomainResponse{
+		Domain:    d.Name,
+		KeyID:     d.KeyID,
+		Verified:  d.Verified,
+		TXTRecord: d.ChallengeRecord(),
+		TXTValue:  d.Challenge,
+	}
+}
@@ -0,0 +1,205 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ksysoev/make-it-public/pkg/core"
+	"github.com/ksysoev/make-it-public/pkg/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddDomainHandler(t *testing.T) {
+	tests := []struct {
+		svcErr       error
+		name         string
+		body         string
+		expectedCode int
+		callsService bool
+	}{
+		{name: "success", body: `{"domain":"app.example.com"}`, callsService: true, expectedCode: http.StatusCreated},
+		{name: "invalid body", body: `{`, expectedCode: http.StatusBadRequest},
+		{name: "invalid domain", body: `{"domain":"app"}`, callsService: true, svcErr: domain.ErrInvalidDomain, expectedCode: http.StatusBadRequest},
+		{name: "token not found", body: `{"domain":"app.example.com"}`, callsService: true, svcErr: core.ErrTokenNotFound, expectedCode: http.StatusNotFound},
+		{name: "duplicate domain", body: `{"domain":"app.example.com"}`, callsService: true, svcErr: core.ErrDuplicateDomain, expectedCode: http.StatusConflict},
+		{name: "internal error", body: `{"domain":"app.example.com"}`, callsService: true, svcErr: errors.New("boom"), expectedCode: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewMockService(t)
+			api := New(Config{}, svc)
+
+			if tt.callsService {
+				var d *domain.Domain
+				if tt.svcErr == nil {
+					d = &domain.Domain{Name: "app.example.com", KeyID: "key1", Challenge: "secret"}
+				}
+
+				svc.EXPECT().AddDomain(mock.Anything, "key1", mock.Anything).Return(d, tt.svcErr)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/token/key1/domains", strings.NewReader(tt.body))
+			req.SetPathValue("keyID", "key1")
+
+			rec := httptest.NewRecorder()
+
+			api.addDomainHandler(rec, req)
+
+			assert.Equal(t, tt.expectedCode, rec.Code)
+
+			if tt.expectedCode != http.StatusCreated {
+				return
+			}
+
+			var resp DomainResponse
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+			assert.Equal(t, DomainResponse{
+				Domain:    "app.example.com",
+				KeyID:     "key1",
+				TXTRecord: "_mit-challenge.app.example.com",
+				TXTValue:  "secret",
+			}, resp)
+		})
+	}
+}
+
+func TestListDomainsHandler(t *testing.T) {
+	svc := NewMockService(t)
+	api := New(Config{}, svc)
+
+	svc.EXPECT().ListDomains(mock.Anything, "key1").Return([]*domain.Domain{
+		{Name: "app.example.com", KeyID: "key1", Challenge: "secret", Verified: true},
+	}, nil).Once()
+
+	req := httptest.NewRequest(http.MethodGet, "/token/key1/domains", http.NoBody)
+	req.SetPathValue("keyID", "key1")
+
+	rec := httptest.NewRecorder()
+
+	api.listDomainsHandler(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp []DomainResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	require.Len(t, resp, 1)
+	assert.True(t, resp[0].Verified)
+
+	svc.EXPECT().ListDomains(mock.Anything, "key1").Return(nil, errors.New("boom")).Once()
+
+	rec = httptest.NewRecorder()
+
+	api.listDomainsHandler(rec, req)
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+}
+
+func TestVerifyDomainHandler(t *testing.T) {
+	tests := []struct {
+		svcErr       error
+		name         string
+		expectedCode int
+	}{
+		{name: "verified", expectedCode: http.StatusOK},
+		{name: "not found", svcErr: core.ErrDomainNotFound, expectedCode: http.StatusNotFound},
+		{name: "challenge missing", svcErr: core.ErrDomainNotVerified, expectedCode: http.StatusUnprocessableEntity},
+		{name: "verified by another token", svcErr: core.ErrDuplicateDomain, expectedCode: http.StatusConflict},
+		{name: "internal error", svcErr: errors.New("boom"), expectedCode: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewMockService(t)
+			api := New(Config{}, svc)
+
+			var d *domain.Domain
+			if tt.svcErr == nil {
+				d = &domain.Domain{Name: "app.example.com", KeyID: "key1", Verified: true}
+			}
+
+			svc.EXPECT().VerifyDomain(mock.Anything, "key1", "app.example.com").Return(d, tt.svcErr)
+
+			req := httptest.NewRequest(http.MethodPost, "/token/key1/domains/app.example.com/verify", http.NoBody)
+			req.SetPathValue("keyID", "key1")
+			req.SetPathValue("domain", "app.example.com")
+
+			rec := httptest.NewRecorder()
+
+			api.verifyDomainHandler(rec, req)
+
+			assert.Equal(t, tt.expectedCode, rec.Code)
+		})
+	}
+}
+
+func TestRemoveDomainHandler(t *testing.T) {
+	tests := []struct {
+		svcErr       error
+		name         string
+		expectedCode int
+	}{
+		{name: "removed", expectedCode: http.StatusNoContent},
+		{name: "not found", svcErr: core.ErrDomainNotFound, expectedCode: http.StatusNotFound},
+		{name: "internal error", svcErr: errors.New("boom"), expectedCode: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewMockService(t)
+			api := New(Config{}, svc)
+
+			svc.EXPECT().RemoveDomain(mock.Anything, "key1", "app.example.com").Return(tt.svcErr)
+
+			req := httptest.NewRequest(http.MethodDelete, "/token/key1/domains/app.example.com", http.NoBody)
+			req.SetPathValue("keyID", "key1")
+			req.SetPathValue("domain", "app.example.com")
+
+			rec := httptest.NewRecorder()
+
+			api.removeDomainHandler(rec, req)
+
+			assert.Equal(t, tt.expectedCode, rec.Code)
+		})
+	}
+}
+
+func TestCheckDomainHandler(t *testing.T) {
+	tests := []struct {
+		svcErr       error
+		name         string
+		query        string
+		keyID        string
+		expectedCode int
+		callsService bool
+	}{
+		{name: "verified domain", query: "?domain=app.example.com", keyID: "key1", callsService: true, expectedCode: http.StatusOK},
+		{name: "unknown domain", query: "?domain=app.example.com", callsService: true, expectedCode: http.StatusNotFound},
+		{name: "missing domain", expectedCode: http.StatusBadRequest},
+		{name: "internal error", query: "?domain=app.example.com", callsService: true, svcErr: errors.New("boom"), expectedCode: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewMockService(t)
+			api := New(Config{}, svc)
+
+			if tt.callsService {
+				svc.EXPECT().ResolveDomain(mock.Anything, "app.example.com").Return(tt.keyID, tt.svcErr)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/domains/check"+tt.query, http.NoBody)
+			rec := httptest.NewRecorder()
+
+			api.checkDomainHandler(rec, req)
+
+			assert.Equal(t, tt.expectedCode, rec.Code)
+		})
+	}
+}
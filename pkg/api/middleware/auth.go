@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"slices"
+)
+
+// authChallenge is sent in the WWW-Authenticate header of responses to unauthenticated requests.
+const authChallenge = `Bearer realm="make-it-public-api"`
+
+// Principal is an authenticated caller of the management API with the scopes it was granted.
+type Principal struct {
+	Name   string
+	Scopes []string
+}
+
+// Authenticator identifies the caller of a request from its credentials.
+// It returns false if the request carries no valid credentials.
+type Authenticator func(r *http.Request) (*Principal, bool)
+
+// RequireScope rejects requests whose caller was not granted scope.
+// Requests without valid credentials receive a 401 response, and callers lacking the scope a 403 response.
+// A nil authenticate disables authentication, so every request is passed to next.
+func RequireScope(authenticate Authenticator, scope string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if authenticate == nil {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p, ok := authenticate(r)
+			if !ok {
+				w.Header().Set("WWW-Authenticate", authChallenge)
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+
+				return
+			}
+
+			if !slices.Contains(p.Scopes, scope) {
+				slog.WarnContext(r.Context(), "mng api request denied", slog.String("principal", p.Name), slog.String("scope", scope))
+				http.Error(w, "Forbidden", http.StatusForbidden)
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
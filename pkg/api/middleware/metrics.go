@@ -3,7 +3,10 @@ package middleware
 import (
 	"log/slog"
 	"net/http"
+	"strconv"
 	"time"
+
+	"github.com/ksysoev/make-it-public/pkg/metrics"
 )
 
 type respWriter struct {
@@ -19,6 +22,7 @@ func (rw *respWriter) WriteHeader(status int) {
 
 // Metrics wraps an HTTP handler to log request details such as duration, status code, and path.
 // It returns a middleware handler function that records metrics for incoming requests.
+// Request latency is also observed in metrics.APIRequestDuration, labelled by the matched route pattern.
 // Returns an HTTP handler middleware for logging request metrics.
 func Metrics() func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -31,7 +35,10 @@ func Metrics() func(next http.Handler) http.Handler {
 			}
 
 			next.ServeHTTP(rw, r)
-			slog.InfoContext(r.Context(), "mng api request", slog.Duration("duration", time.Since(now)), slog.Int("status", rw.status), slog.String("path", r.URL.Path))
+
+			elapsed := time.Since(now)
+			metrics.APIRequestDuration.WithLabelValues(r.Pattern, strconv.Itoa(rw.status)).Observe(elapsed.Seconds())
+			slog.InfoContext(r.Context(), "mng api request", slog.Duration("duration", elapsed), slog.Int("status", rw.status), slog.String("path", r.URL.Path))
 		})
 	}
 }
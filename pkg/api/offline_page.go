@@ -0,0 +1,73 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/ksysoev/make-it-public/pkg/core"
+)
+
+// setOfflinePageHandler sets the page shown to visitors of the tunnel of the key ID in the request path
+// while its client is disconnected. The request body is the HTML page.
+// @Summary Set Offline Page
+// @Description Sets the HTML page shown instead of the generic 502 error page while the client of the tunnel is disconnected.
+// @Tags Token
+// @Accept html
+// @Param keyID path string true "API Key ID"
+// @Success 204
+// @Failure 400 {string} string "Bad Request"
+// @Failure 404 {string} string "Token not found"
+// @Failure 413 {string} string "Offline page is too large"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /token/{keyID}/offline-page [put]
+func (a *API) setOfflinePageHandler(w http.ResponseWriter, r *http.Request) {
+	// One byte more than allowed is read, so that pages over the limit are rejected rather than truncated.
+	page, err := io.ReadAll(io.LimitReader(r.Body, core.MaxOfflinePageSize+1))
+	if err != nil {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+
+		return
+	}
+
+	err = a.svc.SetOfflinePage(r.Context(), r.PathValue("keyID"), string(page))
+
+	switch {
+	case errors.Is(err, core.ErrEmptyOfflinePage):
+		http.Error(w, core.ErrEmptyOfflinePage.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, core.ErrOfflinePageTooLarge):
+		http.Error(w, core.ErrOfflinePageTooLarge.Error(), http.StatusRequestEntityTooLarge)
+		return
+	case errors.Is(err, core.ErrTokenNotFound):
+		http.Error(w, "Token not found", http.StatusNotFound)
+		return
+	case err != nil:
+		slog.ErrorContext(r.Context(), "Failed to set offline page", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// removeOfflinePageHandler removes the offline page of the tunnel of the key ID in the request path.
+// @Summary Remove Offline Page
+// @Description Removes the offline page of a token, so that the generic 502 error page is shown while its client is disconnected.
+// @Tags Token
+// @Param keyID path string true "API Key ID"
+// @Success 204
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /token/{keyID}/offline-page [delete]
+func (a *API) removeOfflinePageHandler(w http.ResponseWriter, r *http.Request) {
+	if err := a.svc.RemoveOfflinePage(r.Context(), r.PathValue("keyID")); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to remove offline page", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
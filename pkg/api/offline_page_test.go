@@ -0,0 +1,93 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ksysoev/make-it-public/pkg/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSetOfflinePageHandler(t *testing.T) {
+	tests := []struct {
+		svcErr       error
+		name         string
+		expectedCode int
+	}{
+		{name: "set", expectedCode: http.StatusNoContent},
+		{name: "empty page", svcErr: core.ErrEmptyOfflinePage, expectedCode: http.StatusBadRequest},
+		{name: "page too large", svcErr: core.ErrOfflinePageTooLarge, expectedCode: http.StatusRequestEntityTooLarge},
+		{name: "token not found", svcErr: core.ErrTokenNotFound, expectedCode: http.StatusNotFound},
+		{name: "internal error", svcErr: errors.New("boom"), expectedCode: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewMockService(t)
+			api := New(Config{}, svc)
+
+			svc.EXPECT().SetOfflinePage(mock.Anything, "key1", "<h1>Back soon</h1>").Return(tt.svcErr)
+
+			req := httptest.NewRequest(http.MethodPut, "/token/key1/offline-page", strings.NewReader("<h1>Back soon</h1>"))
+			req.SetPathValue("keyID", "key1")
+
+			rec := httptest.NewRecorder()
+
+			api.setOfflinePageHandler(rec, req)
+
+			assert.Equal(t, tt.expectedCode, rec.Code)
+		})
+	}
+}
+
+func TestSetOfflinePageHandler_OversizedBody(t *testing.T) {
+	svc := NewMockService(t)
+	api := New(Config{}, svc)
+
+	// Oversized pages reach the service one byte over the limit, so that it rejects them.
+	svc.EXPECT().SetOfflinePage(mock.Anything, "key1", mock.MatchedBy(func(page string) bool {
+		return len(page) == core.MaxOfflinePageSize+1
+	})).Return(core.ErrOfflinePageTooLarge)
+
+	req := httptest.NewRequest(http.MethodPut, "/token/key1/offline-page", strings.NewReader(strings.Repeat("a", 2*core.MaxOfflinePageSize)))
+	req.SetPathValue("keyID", "key1")
+
+	rec := httptest.NewRecorder()
+
+	api.setOfflinePageHandler(rec, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+}
+
+func TestRemoveOfflinePageHandler(t *testing.T) {
+	tests := []struct {
+		svcErr       error
+		name         string
+		expectedCode int
+	}{
+		{name: "removed", expectedCode: http.StatusNoContent},
+		{name: "internal error", svcErr: errors.New("boom"), expectedCode: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewMockService(t)
+			api := New(Config{}, svc)
+
+			svc.EXPECT().RemoveOfflinePage(mock.Anything, "key1").Return(tt.svcErr)
+
+			req := httptest.NewRequest(http.MethodDelete, "/token/key1/offline-page", http.NoBody)
+			req.SetPathValue("keyID", "key1")
+
+			rec := httptest.NewRecorder()
+
+			api.removeOfflinePageHandler(rec, req)
+
+			assert.Equal(t, tt.expectedCode, rec.Code)
+		})
+	}
+}
@@ -1,14 +1,46 @@
 package api
 
 type GenerateTokenRequest struct {
-	KeyID string `json:"key_id"`
-	Type  string `json:"type"`
-	TTL   int    `json:"ttl"`
+	KeyID        string   `json:"key_id"`
+	Type         string   `json:"type"`
+	AllowedCIDRs []string `json:"allowed_cidrs"`
+	DeniedCIDRs  []string `json:"denied_cidrs"`
+	TTL          int      `json:"ttl"`
+	Port         int      `json:"port"`
 }
 
 type GenerateTokenResponse struct {
-	Token string `json:"token"`
-	KeyID string `json:"key_id"`
-	Type  string `json:"type"`
-	TTL   int    `json:"ttl"`
+	Token        string   `json:"token"`
+	KeyID        string   `json:"key_id"`
+	Type         string   `json:"type"`
+	AllowedCIDRs []string `json:"allowed_cidrs,omitempty"`
+	DeniedCIDRs  []string `json:"denied_cidrs,omitempty"`
+	TTL          int      `json:"ttl"`
+	Port         int      `json:"port,omitempty"`
+}
+
+type AddDomainRequest struct {
+	Domain string `json:"domain"`
+}
+
+type DomainResponse struct {
+	Domain    string `json:"domain"`
+	KeyID     string `json:"key_id"`
+	TXTRecord string `json:"txt_record"`
+	TXTValue  string `json:"txt_value"`
+	Verified  bool   `json:"verified"`
+}
+
+type UpdateTokenRequest struct {
+	TTL int `json:"ttl"`
+}
+
+type TokenResponse struct {
+	KeyID        string   `json:"key_id"`
+	Type         string   `json:"type,omitempty"`
+	AllowedCIDRs []string `json:"allowed_cidrs,omitempty"`
+	DeniedCIDRs  []string `json:"denied_cidrs,omitempty"`
+	TTL          int      `json:"ttl"`
+	Port         int      `json:"port,omitempty"`
+	Connected    bool     `json:"connected"`
 }
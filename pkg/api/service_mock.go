@@ -7,8 +7,17 @@ package api
 import (
 	context "context"
 
-	token "github.com/ksysoev/make-it-public/pkg/core/token"
+	audit "github.com/ksysoev/make-it-public/pkg/core/audit"
+
+	core "github.com/ksysoev/make-it-public/pkg/core"
+
+	domain "github.com/ksysoev/make-it-public/pkg/core/domain"
+
+	ipfilter "github.com/ksysoev/make-it-public/pkg/core/ipfilter"
+
 	mock "github.com/stretchr/testify/mock"
+
+	token "github.com/ksysoev/make-it-public/pkg/core/token"
 )
 
 // MockService is an autogenerated mock type for the Service type
@@ -24,6 +33,66 @@ func (_m *MockService) EXPECT() *MockService_Expecter {
 	return &MockService_Expecter{mock: &_m.Mock}
 }
 
+// AddDomain provides a mock function with given fields: ctx, keyID, name
+func (_m *MockService) AddDomain(ctx context.Context, keyID string, name string) (*domain.Domain, error) {
+	ret := _m.Called(ctx, keyID, name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddDomain")
+	}
+
+	var r0 *domain.Domain
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*domain.Domain, error)); ok {
+		return rf(ctx, keyID, name)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *domain.Domain); ok {
+		r0 = rf(ctx, keyID, name)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*domain.Domain)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, keyID, name)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockService_AddDomain_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AddDomain'
+type MockService_AddDomain_Call struct {
+	*mock.Call
+}
+
+// AddDomain is a helper method to define mock.On call
+//   - ctx context.Context
+//   - keyID string
+//   - name string
+func (_e *MockService_Expecter) AddDomain(ctx interface{}, keyID interface{}, name interface{}) *MockService_AddDomain_Call {
+	return &MockService_AddDomain_Call{Call: _e.mock.On("AddDomain", ctx, keyID, name)}
+}
+
+func (_c *MockService_AddDomain_Call) Run(run func(ctx context.Context, keyID string, name string)) *MockService_AddDomain_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *MockService_AddDomain_Call) Return(_a0 *domain.Domain, _a1 error) *MockService_AddDomain_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockService_AddDomain_Call) RunAndReturn(run func(context.Context, string, string) (*domain.Domain, error)) *MockService_AddDomain_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // CheckHealth provides a mock function with given fields: ctx
 func (_m *MockService) CheckHealth(ctx context.Context) error {
 	ret := _m.Called(ctx)
@@ -117,9 +186,9 @@ func (_c *MockService_DeleteToken_Call) RunAndReturn(run func(context.Context, s
 	return _c
 }
 
-// GenerateToken provides a mock function with given fields: ctx, keyID, ttl, tokenType
-func (_m *MockService) GenerateToken(ctx context.Context, keyID string, ttl int, tokenType token.TokenType) (*token.Token, error) {
-	ret := _m.Called(ctx, keyID, ttl, tokenType)
+// GenerateToken provides a mock function with given fields: ctx, keyID, ttl, tokenType, ipFilter, port
+func (_m *MockService) GenerateToken(ctx context.Context, keyID string, ttl int, tokenType token.TokenType, ipFilter *ipfilter.Filter, port int) (*token.Token, error) {
+	ret := _m.Called(ctx, keyID, ttl, tokenType, ipFilter, port)
 
 	if len(ret) == 0 {
 		panic("no return value specified for GenerateToken")
@@ -127,19 +196,19 @@ func (_m *MockService) GenerateToken(ctx context.Context, keyID string, ttl int,
 
 	var r0 *token.Token
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, int, token.TokenType) (*token.Token, error)); ok {
-		return rf(ctx, keyID, ttl, tokenType)
+	if rf, ok := ret.Get(0).(func(context.Context, string, int, token.TokenType, *ipfilter.Filter, int) (*token.Token, error)); ok {
+		return rf(ctx, keyID, ttl, tokenType, ipFilter, port)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string, int, token.TokenType) *token.Token); ok {
-		r0 = rf(ctx, keyID, ttl, tokenType)
+	if rf, ok := ret.Get(0).(func(context.Context, string, int, token.TokenType, *ipfilter.Filter, int) *token.Token); ok {
+		r0 = rf(ctx, keyID, ttl, tokenType, ipFilter, port)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*token.Token)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string, int, token.TokenType) error); ok {
-		r1 = rf(ctx, keyID, ttl, tokenType)
+	if rf, ok := ret.Get(1).(func(context.Context, string, int, token.TokenType, *ipfilter.Filter, int) error); ok {
+		r1 = rf(ctx, keyID, ttl, tokenType, ipFilter, port)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -157,13 +226,15 @@ type MockService_GenerateToken_Call struct {
 //   - keyID string
 //   - ttl int
 //   - tokenType token.TokenType
-func (_e *MockService_Expecter) GenerateToken(ctx interface{}, keyID interface{}, ttl interface{}, tokenType interface{}) *MockService_GenerateToken_Call {
-	return &MockService_GenerateToken_Call{Call: _e.mock.On("GenerateToken", ctx, keyID, ttl, tokenType)}
+//   - ipFilter *ipfilter.Filter
+//   - port int
+func (_e *MockService_Expecter) GenerateToken(ctx interface{}, keyID interface{}, ttl interface{}, tokenType interface{}, ipFilter interface{}, port interface{}) *MockService_GenerateToken_Call {
+	return &MockService_GenerateToken_Call{Call: _e.mock.On("GenerateToken", ctx, keyID, ttl, tokenType, ipFilter, port)}
 }
 
-func (_c *MockService_GenerateToken_Call) Run(run func(ctx context.Context, keyID string, ttl int, tokenType token.TokenType)) *MockService_GenerateToken_Call {
+func (_c *MockService_GenerateToken_Call) Run(run func(ctx context.Context, keyID string, ttl int, tokenType token.TokenType, ipFilter *ipfilter.Filter, port int)) *MockService_GenerateToken_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(context.Context), args[1].(string), args[2].(int), args[3].(token.TokenType))
+		run(args[0].(context.Context), args[1].(string), args[2].(int), args[3].(token.TokenType), args[4].(*ipfilter.Filter), args[5].(int))
 	})
 	return _c
 }
@@ -173,7 +244,563 @@ func (_c *MockService_GenerateToken_Call) Return(_a0 *token.Token, _a1 error) *M
 	return _c
 }
 
-func (_c *MockService_GenerateToken_Call) RunAndReturn(run func(context.Context, string, int, token.TokenType) (*token.Token, error)) *MockService_GenerateToken_Call {
+func (_c *MockService_GenerateToken_Call) RunAndReturn(run func(context.Context, string, int, token.TokenType, *ipfilter.Filter, int) (*token.Token, error)) *MockService_GenerateToken_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetToken provides a mock function with given fields: ctx, keyID
+func (_m *MockService) GetToken(ctx context.Context, keyID string) (*core.TokenInfo, error) {
+	ret := _m.Called(ctx, keyID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetToken")
+	}
+
+	var r0 *core.TokenInfo
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*core.TokenInfo, error)); ok {
+		return rf(ctx, keyID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *core.TokenInfo); ok {
+		r0 = rf(ctx, keyID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*core.TokenInfo)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, keyID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockService_GetToken_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetToken'
+type MockService_GetToken_Call struct {
+	*mock.Call
+}
+
+// GetToken is a helper method to define mock.On call
+//   - ctx context.Context
+//   - keyID string
+func (_e *MockService_Expecter) GetToken(ctx interface{}, keyID interface{}) *MockService_GetToken_Call {
+	return &MockService_GetToken_Call{Call: _e.mock.On("GetToken", ctx, keyID)}
+}
+
+func (_c *MockService_GetToken_Call) Run(run func(ctx context.Context, keyID string)) *MockService_GetToken_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *MockService_GetToken_Call) Return(_a0 *core.TokenInfo, _a1 error) *MockService_GetToken_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockService_GetToken_Call) RunAndReturn(run func(context.Context, string) (*core.TokenInfo, error)) *MockService_GetToken_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListDomains provides a mock function with given fields: ctx, keyID
+func (_m *MockService) ListDomains(ctx context.Context, keyID string) ([]*domain.Domain, error) {
+	ret := _m.Called(ctx, keyID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListDomains")
+	}
+
+	var r0 []*domain.Domain
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]*domain.Domain, error)); ok {
+		return rf(ctx, keyID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []*domain.Domain); ok {
+		r0 = rf(ctx, keyID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*domain.Domain)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, keyID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockService_ListDomains_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListDomains'
+type MockService_ListDomains_Call struct {
+	*mock.Call
+}
+
+// ListDomains is a helper method to define mock.On call
+//   - ctx context.Context
+//   - keyID string
+func (_e *MockService_Expecter) ListDomains(ctx interface{}, keyID interface{}) *MockService_ListDomains_Call {
+	return &MockService_ListDomains_Call{Call: _e.mock.On("ListDomains", ctx, keyID)}
+}
+
+func (_c *MockService_ListDomains_Call) Run(run func(ctx context.Context, keyID string)) *MockService_ListDomains_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *MockService_ListDomains_Call) Return(_a0 []*domain.Domain, _a1 error) *MockService_ListDomains_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockService_ListDomains_Call) RunAndReturn(run func(context.Context, string) ([]*domain.Domain, error)) *MockService_ListDomains_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListTokens provides a mock function with given fields: ctx
+func (_m *MockService) ListTokens(ctx context.Context) ([]*core.TokenInfo, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListTokens")
+	}
+
+	var r0 []*core.TokenInfo
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]*core.TokenInfo, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []*core.TokenInfo); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*core.TokenInfo)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockService_ListTokens_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListTokens'
+type MockService_ListTokens_Call struct {
+	*mock.Call
+}
+
+// ListTokens is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *MockService_Expecter) ListTokens(ctx interface{}) *MockService_ListTokens_Call {
+	return &MockService_ListTokens_Call{Call: _e.mock.On("ListTokens", ctx)}
+}
+
+func (_c *MockService_ListTokens_Call) Run(run func(ctx context.Context)) *MockService_ListTokens_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context))
+	})
+	return _c
+}
+
+func (_c *MockService_ListTokens_Call) Return(_a0 []*core.TokenInfo, _a1 error) *MockService_ListTokens_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockService_ListTokens_Call) RunAndReturn(run func(context.Context) ([]*core.TokenInfo, error)) *MockService_ListTokens_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RecentSessions provides a mock function with given fields: ctx, keyID, limit
+func (_m *MockService) RecentSessions(ctx context.Context, keyID string, limit int) ([]*audit.Record, error) {
+	ret := _m.Called(ctx, keyID, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RecentSessions")
+	}
+
+	var r0 []*audit.Record
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) ([]*audit.Record, error)); ok {
+		return rf(ctx, keyID, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) []*audit.Record); ok {
+		r0 = rf(ctx, keyID, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*audit.Record)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, int) error); ok {
+		r1 = rf(ctx, keyID, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockService_RecentSessions_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RecentSessions'
+type MockService_RecentSessions_Call struct {
+	*mock.Call
+}
+
+// RecentSessions is a helper method to define mock.On call
+//   - ctx context.Context
+//   - keyID string
+//   - limit int
+func (_e *MockService_Expecter) RecentSessions(ctx interface{}, keyID interface{}, limit interface{}) *MockService_RecentSessions_Call {
+	return &MockService_RecentSessions_Call{Call: _e.mock.On("RecentSessions", ctx, keyID, limit)}
+}
+
+func (_c *MockService_RecentSessions_Call) Run(run func(ctx context.Context, keyID string, limit int)) *MockService_RecentSessions_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(int))
+	})
+	return _c
+}
+
+func (_c *MockService_RecentSessions_Call) Return(_a0 []*audit.Record, _a1 error) *MockService_RecentSessions_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockService_RecentSessions_Call) RunAndReturn(run func(context.Context, string, int) ([]*audit.Record, error)) *MockService_RecentSessions_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RemoveDomain provides a mock function with given fields: ctx, keyID, name
+func (_m *MockService) RemoveDomain(ctx context.Context, keyID string, name string) error {
+	ret := _m.Called(ctx, keyID, name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RemoveDomain")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, keyID, name)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockService_RemoveDomain_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RemoveDomain'
+type MockService_RemoveDomain_Call struct {
+	*mock.Call
+}
+
+// RemoveDomain is a helper method to define mock.On call
+//   - ctx context.Context
+//   - keyID string
+//   - name string
+func (_e *MockService_Expecter) RemoveDomain(ctx interface{}, keyID interface{}, name interface{}) *MockService_RemoveDomain_Call {
+	return &MockService_RemoveDomain_Call{Call: _e.mock.On("RemoveDomain", ctx, keyID, name)}
+}
+
+func (_c *MockService_RemoveDomain_Call) Run(run func(ctx context.Context, keyID string, name string)) *MockService_RemoveDomain_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *MockService_RemoveDomain_Call) Return(_a0 error) *MockService_RemoveDomain_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockService_RemoveDomain_Call) RunAndReturn(run func(context.Context, string, string) error) *MockService_RemoveDomain_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RemoveOfflinePage provides a mock function with given fields: ctx, keyID
+func (_m *MockService) RemoveOfflinePage(ctx context.Context, keyID string) error {
+	ret := _m.Called(ctx, keyID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RemoveOfflinePage")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, keyID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockService_RemoveOfflinePage_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RemoveOfflinePage'
+type MockService_RemoveOfflinePage_Call struct {
+	*mock.Call
+}
+
+// RemoveOfflinePage is a helper method to define mock.On call
+//   - ctx context.Context
+//   - keyID string
+func (_e *MockService_Expecter) RemoveOfflinePage(ctx interface{}, keyID interface{}) *MockService_RemoveOfflinePage_Call {
+	return &MockService_RemoveOfflinePage_Call{Call: _e.mock.On("RemoveOfflinePage", ctx, keyID)}
+}
+
+func (_c *MockService_RemoveOfflinePage_Call) Run(run func(ctx context.Context, keyID string)) *MockService_RemoveOfflinePage_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *MockService_RemoveOfflinePage_Call) Return(_a0 error) *MockService_RemoveOfflinePage_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockService_RemoveOfflinePage_Call) RunAndReturn(run func(context.Context, string) error) *MockService_RemoveOfflinePage_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ResolveDomain provides a mock function with given fields: ctx, host
+func (_m *MockService) ResolveDomain(ctx context.Context, host string) (string, error) {
+	ret := _m.Called(ctx, host)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ResolveDomain")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (string, error)); ok {
+		return rf(ctx, host)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) string); ok {
+		r0 = rf(ctx, host)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, host)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockService_ResolveDomain_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ResolveDomain'
+type MockService_ResolveDomain_Call struct {
+	*mock.Call
+}
+
+// ResolveDomain is a helper method to define mock.On call
+//   - ctx context.Context
+//   - host string
+func (_e *MockService_Expecter) ResolveDomain(ctx interface{}, host interface{}) *MockService_ResolveDomain_Call {
+	return &MockService_ResolveDomain_Call{Call: _e.mock.On("ResolveDomain", ctx, host)}
+}
+
+func (_c *MockService_ResolveDomain_Call) Run(run func(ctx context.Context, host string)) *MockService_ResolveDomain_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *MockService_ResolveDomain_Call) Return(_a0 string, _a1 error) *MockService_ResolveDomain_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockService_ResolveDomain_Call) RunAndReturn(run func(context.Context, string) (string, error)) *MockService_ResolveDomain_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SetOfflinePage provides a mock function with given fields: ctx, keyID, page
+func (_m *MockService) SetOfflinePage(ctx context.Context, keyID string, page string) error {
+	ret := _m.Called(ctx, keyID, page)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetOfflinePage")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, keyID, page)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockService_SetOfflinePage_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetOfflinePage'
+type MockService_SetOfflinePage_Call struct {
+	*mock.Call
+}
+
+// SetOfflinePage is a helper method to define mock.On call
+//   - ctx context.Context
+//   - keyID string
+//   - page string
+func (_e *MockService_Expecter) SetOfflinePage(ctx interface{}, keyID interface{}, page interface{}) *MockService_SetOfflinePage_Call {
+	return &MockService_SetOfflinePage_Call{Call: _e.mock.On("SetOfflinePage", ctx, keyID, page)}
+}
+
+func (_c *MockService_SetOfflinePage_Call) Run(run func(ctx context.Context, keyID string, page string)) *MockService_SetOfflinePage_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *MockService_SetOfflinePage_Call) Return(_a0 error) *MockService_SetOfflinePage_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockService_SetOfflinePage_Call) RunAndReturn(run func(context.Context, string, string) error) *MockService_SetOfflinePage_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UpdateTokenTTL provides a mock function with given fields: ctx, keyID, ttl
+func (_m *MockService) UpdateTokenTTL(ctx context.Context, keyID string, ttl int) (*core.TokenInfo, error) {
+	ret := _m.Called(ctx, keyID, ttl)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateTokenTTL")
+	}
+
+	var r0 *core.TokenInfo
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) (*core.TokenInfo, error)); ok {
+		return rf(ctx, keyID, ttl)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) *core.TokenInfo); ok {
+		r0 = rf(ctx, keyID, ttl)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*core.TokenInfo)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, int) error); ok {
+		r1 = rf(ctx, keyID, ttl)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockService_UpdateTokenTTL_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateTokenTTL'
+type MockService_UpdateTokenTTL_Call struct {
+	*mock.Call
+}
+
+// UpdateTokenTTL is a helper method to define mock.On call
+//   - ctx context.Context
+//   - keyID string
+//   - ttl int
+func (_e *MockService_Expecter) UpdateTokenTTL(ctx interface{}, keyID interface{}, ttl interface{}) *MockService_UpdateTokenTTL_Call {
+	return &MockService_UpdateTokenTTL_Call{Call: _e.mock.On("UpdateTokenTTL", ctx, keyID, ttl)}
+}
+
+func (_c *MockService_UpdateTokenTTL_Call) Run(run func(ctx context.Context, keyID string, ttl int)) *MockService_UpdateTokenTTL_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(int))
+	})
+	return _c
+}
+
+func (_c *MockService_UpdateTokenTTL_Call) Return(_a0 *core.TokenInfo, _a1 error) *MockService_UpdateTokenTTL_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockService_UpdateTokenTTL_Call) RunAndReturn(run func(context.Context, string, int) (*core.TokenInfo, error)) *MockService_UpdateTokenTTL_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// VerifyDomain provides a mock function with given fields: ctx, keyID, name
+func (_m *MockService) VerifyDomain(ctx context.Context, keyID string, name string) (*domain.Domain, error) {
+	ret := _m.Called(ctx, keyID, name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for VerifyDomain")
+	}
+
+	var r0 *domain.Domain
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*domain.Domain, error)); ok {
+		return rf(ctx, keyID, name)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *domain.Domain); ok {
+		r0 = rf(ctx, keyID, name)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*domain.Domain)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, keyID, name)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockService_VerifyDomain_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'VerifyDomain'
+type MockService_VerifyDomain_Call struct {
+	*mock.Call
+}
+
+// VerifyDomain is a helper method to define mock.On call
+//   - ctx context.Context
+//   - keyID string
+//   - name string
+func (_e *MockService_Expecter) VerifyDomain(ctx interface{}, keyID interface{}, name interface{}) *MockService_VerifyDomain_Call {
+	return &MockService_VerifyDomain_Call{Call: _e.mock.On("VerifyDomain", ctx, keyID, name)}
+}
+
+func (_c *MockService_VerifyDomain_Call) Run(run func(ctx context.Context, keyID string, name string)) *MockService_VerifyDomain_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *MockService_VerifyDomain_Call) Return(_a0 *domain.Domain, _a1 error) *MockService_VerifyDomain_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockService_VerifyDomain_Call) RunAndReturn(run func(context.Context, string, string) (*domain.Domain, error)) *MockService_VerifyDomain_Call {
 	_c.Call.Return(run)
 	return _c
 }
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/ksysoev/make-it-public/pkg/core"
+)
+
+const (
+	defaultSessionsLimit = 50
+	maxSessionsLimit     = 1000
+)
+
+// listSessionsHandler returns the latest audit records of the token identified by the key ID in the request path:
+// control connections opened and closed by its clients, and public connections served through its tunnel.
+// @Summary List Sessions
+// @Description Returns the latest audit records of a token, newest first.
+// @Tags Audit
+// @Produce json
+// @Param keyID path string true "API Key ID"
+// @Param limit query int false "Maximum number of records, 50 by default and at most 1000"
+// @Success 200 {array} audit.Record
+// @Failure 400 {string} string "Bad Request"
+// @Failure 500 {string} string "Internal Server Error"
+// @Failure 501 {string} string "Audit log is not enabled"
+// @Router /token/{keyID}/sessions [get]
+func (a *API) listSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	limit := defaultSessionsLimit
+
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxSessionsLimit {
+			http.Error(w, "invalid limit, expected a number from 1 to 1000", http.StatusBadRequest)
+			return
+		}
+
+		limit = n
+	}
+
+	recs, err := a.svc.RecentSessions(r.Context(), r.PathValue("keyID"), limit)
+
+	switch {
+	case errors.Is(err, core.ErrAuditDisabled):
+		http.Error(w, "Audit log is not enabled", http.StatusNotImplemented)
+		return
+	case err != nil:
+		slog.ErrorContext(r.Context(), "Failed to list sessions", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, recs)
+}
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ksysoev/make-it-public/pkg/core"
+	"github.com/ksysoev/make-it-public/pkg/core/audit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListSessionsHandler(t *testing.T) {
+	tests := []struct {
+		svcErr        error
+		name          string
+		query         string
+		expectedLimit int
+		expectedCode  int
+	}{
+		{name: "default limit", expectedLimit: defaultSessionsLimit, expectedCode: http.StatusOK},
+		{name: "custom limit", query: "?limit=5", expectedLimit: 5, expectedCode: http.StatusOK},
+		{name: "invalid limit", query: "?limit=abc", expectedCode: http.StatusBadRequest},
+		{name: "zero limit", query: "?limit=0", expectedCode: http.StatusBadRequest},
+		{name: "limit too large", query: "?limit=1001", expectedCode: http.StatusBadRequest},
+		{name: "audit disabled", expectedLimit: defaultSessionsLimit, svcErr: core.ErrAuditDisabled, expectedCode: http.StatusNotImplemented},
+		{name: "internal error", expectedLimit: defaultSessionsLimit, svcErr: errors.New("boom"), expectedCode: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewMockService(t)
+			api := New(Config{}, svc)
+
+			recs := []*audit.Record{{Kind: audit.KindControlOpened, KeyID: "key1", RemoteIP: "10.0.0.1"}}
+
+			if tt.expectedLimit > 0 {
+				if tt.svcErr != nil {
+					recs = nil
+				}
+
+				svc.EXPECT().RecentSessions(mock.Anything, "key1", tt.expectedLimit).Return(recs, tt.svcErr)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/token/key1/sessions"+tt.query, http.NoBody)
+			req.SetPathValue("keyID", "key1")
+
+			rec := httptest.NewRecorder()
+
+			api.listSessionsHandler(rec, req)
+
+			assert.Equal(t, tt.expectedCode, rec.Code)
+
+			if tt.expectedCode != http.StatusOK {
+				return
+			}
+
+			var resp []audit.Record
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+
+			require.Len(t, resp, 1)
+			assert.Equal(t, audit.KindControlOpened, resp[0].Kind)
+			assert.Equal(t, "10.0.0.1", resp[0].RemoteIP)
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package cluster
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Config holds configuration for cluster mode.
+// Cluster mode is enabled when Listen is set.
+type Config struct {
+	Listen        string    `mapstructure:"listen"`
+	AdvertiseAddr string    `mapstructure:"advertise_addr"`
+	Secret        string    `mapstructure:"secret"` // #nosec G117 -- This is a config field name, not an exposed secret
+	TLS           TLSConfig `mapstructure:"tls"`
+}
+
+// TLSConfig secures the links between cluster nodes with mutual TLS.
+// Every node presents the certificate in CertFile, and only accepts peers whose certificates are signed by CAFile.
+// ServerName is the name verified in the certificates of other nodes; it defaults to the host of their advertise address.
+type TLSConfig struct {
+	CertFile   string `mapstructure:"cert_file"`
+	KeyFile    string `mapstructure:"key_file"`
+	CAFile     string `mapstructure:"ca_file"`
+	ServerName string `mapstructure:"server_name"`
+}
+
+// Enabled reports whether the node should join a cluster.
+func (c *Config) Enabled() bool {
+	return c.Listen != ""
+}
+
+// Validate checks that the Config is valid. It returns an error describing the
+// first violation found.
+func (c *Config) Validate() error {
+	if c.Listen == "" {
+		return errors.New("listen must not be empty")
+	}
+
+	if c.AdvertiseAddr == "" {
+		return errors.New("advertise_addr must not be empty")
+	}
+
+	if c.Secret == "" {
+		return errors.New("secret must not be empty")
+	}
+
+	if c.TLS.CertFile == "" || c.TLS.KeyFile == "" {
+		return errors.New("tls.cert_file and tls.key_file must not be empty")
+	}
+
+	if c.TLS.CAFile == "" {
+		return errors.New("tls.ca_file must not be empty")
+	}
+
+	return nil
+}
+
+// tlsConfig loads the certificate of this node and the CA of the cluster.
+// The returned configuration serves internal links and, with the server name of the peer set, dials them.
+func (c *TLSConfig) tlsConfig() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load certificate: %w", err)
+	}
+
+	pem, err := os.ReadFile(c.CAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.New("no certificates found in CA file")
+	}
+
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS13,
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      pool,
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}, nil
+}
@@ -0,0 +1,149 @@
+package cluster
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestTLS writes a cluster CA and a node certificate valid for 127.0.0.1 to a temporary directory.
+func newTestTLS(t *testing.T) TLSConfig {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "cluster CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+
+	caCert, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	nodeKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	nodeTmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "node"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+
+	nodeDER, err := x509.CreateCertificate(rand.Reader, nodeTmpl, caCert, &nodeKey.PublicKey, caKey)
+	require.NoError(t, err)
+
+	nodeKeyDER, err := x509.MarshalECPrivateKey(nodeKey)
+	require.NoError(t, err)
+
+	cfg := TLSConfig{
+		CertFile: filepath.Join(dir, "node.crt"),
+		KeyFile:  filepath.Join(dir, "node.key"),
+		CAFile:   filepath.Join(dir, "ca.crt"),
+	}
+
+	require.NoError(t, os.WriteFile(cfg.CAFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}), 0o600))
+	require.NoError(t, os.WriteFile(cfg.CertFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: nodeDER}), 0o600))
+	require.NoError(t, os.WriteFile(cfg.KeyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: nodeKeyDER}), 0o600))
+
+	return cfg
+}
+
+func TestConfig_Enabled(t *testing.T) {
+	assert.False(t, (&Config{}).Enabled())
+	assert.True(t, (&Config{Listen: ":8083"}).Enabled())
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tlsCfg := TLSConfig{CertFile: "node.crt", KeyFile: "node.key", CAFile: "ca.crt"}
+
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			cfg:  Config{Listen: ":8083", AdvertiseAddr: "10.0.0.1:8083", Secret: "secret", TLS: tlsCfg},
+		},
+		{
+			name:    "empty listen",
+			cfg:     Config{AdvertiseAddr: "10.0.0.1:8083", Secret: "secret"},
+			wantErr: true,
+		},
+		{
+			name:    "empty advertise address",
+			cfg:     Config{Listen: ":8083", Secret: "secret"},
+			wantErr: true,
+		},
+		{
+			name:    "empty secret",
+			cfg:     Config{Listen: ":8083", AdvertiseAddr: "10.0.0.1:8083", TLS: tlsCfg},
+			wantErr: true,
+		},
+		{
+			name:    "without TLS",
+			cfg:     Config{Listen: ":8083", AdvertiseAddr: "10.0.0.1:8083", Secret: "secret"},
+			wantErr: true,
+		},
+		{
+			name:    "without CA",
+			cfg:     Config{Listen: ":8083", AdvertiseAddr: "10.0.0.1:8083", Secret: "secret", TLS: TLSConfig{CertFile: "node.crt", KeyFile: "node.key"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestTLSConfig_TLSConfig(t *testing.T) {
+	cfg := newTestTLS(t)
+
+	tlsConfig, err := cfg.tlsConfig()
+	require.NoError(t, err)
+
+	assert.Len(t, tlsConfig.Certificates, 1)
+	assert.NotNil(t, tlsConfig.ClientCAs)
+
+	_, err = (&TLSConfig{CertFile: cfg.CertFile, KeyFile: cfg.KeyFile, CAFile: cfg.KeyFile}).tlsConfig()
+	assert.ErrorContains(t, err, "no certificates found")
+
+	_, err = (&TLSConfig{CertFile: cfg.CAFile, KeyFile: cfg.KeyFile, CAFile: cfg.CAFile}).tlsConfig()
+	assert.ErrorContains(t, err, "failed to load certificate")
+}
@@ -0,0 +1,347 @@
+package cluster
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ksysoev/make-it-public/pkg/core"
+	"github.com/ksysoev/make-it-public/pkg/core/conn"
+	"github.com/ksysoev/make-it-public/pkg/core/conn/meta"
+	"github.com/ksysoev/make-it-public/pkg/core/token"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	nodesKeyPrefix = "NODES::"
+	authKeyPrefix  = "TUNAUTH::"
+
+	// ownershipTTL is how long a node's claim on a keyID stays valid without being refreshed.
+	// Claims of a crashed node therefore disappear after at most this period.
+	ownershipTTL = 30 * time.Second
+
+	// refreshInterval is how often a node re-publishes the keyIDs it owns.
+	refreshInterval = ownershipTTL / 3
+
+	dialTimeout = 5 * time.Second
+)
+
+// Redis is the subset of the Redis client used by the Registry.
+type Redis interface {
+	ZAdd(ctx context.Context, key string, members ...redis.Z) *redis.IntCmd
+	ZRem(ctx context.Context, key string, members ...interface{}) *redis.IntCmd
+	ZRangeByScore(ctx context.Context, key string, opt *redis.ZRangeBy) *redis.StringSliceCmd
+	ZRemRangeByScore(ctx context.Context, key, minScore, maxScore string) *redis.IntCmd
+	Expire(ctx context.Context, key string, expiration time.Duration) *redis.BoolCmd
+	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd
+	Get(ctx context.Context, key string) *redis.StringCmd
+	ZCount(ctx context.Context, key, minScore, maxScore string) *redis.IntCmd
+	Del(ctx context.Context, keys ...string) *redis.IntCmd
+}
+
+// claim tracks the control connections of a keyID established on this node.
+type claim struct {
+	auth  *meta.TunnelAuth
+	count int
+}
+
+// Registry publishes the keyIDs whose control connections terminate on this node to Redis,
+// and forwards connections for keyIDs owned by other nodes over an internal link.
+// It implements core.ConnRegistry.
+//
+// Every keyID is stored as a sorted set of node addresses scored by claim expiry time,
+// so several nodes can own the same keyID when its clients are spread across the cluster.
+// The tunnel credentials of every keyID are stored next to its claims, so that any node can check them.
+type Registry struct {
+	db         Redis
+	owned      map[string]*claim
+	now        func() time.Time
+	tlsConfig  *tls.Config
+	keyPrefix  string
+	self       string
+	secret     string
+	serverName string
+	mu         sync.Mutex
+}
+
+// NewRegistry creates a Registry for the node described by cfg.
+// tlsConfig secures the internal links to other nodes, db is the Redis client shared with the auth repository,
+// and keyPrefix is prepended to every Redis key.
+func NewRegistry(cfg Config, tlsConfig *tls.Config, db Redis, keyPrefix string) *Registry {
+	return &Registry{
+		db:         db,
+		owned:      make(map[string]*claim),
+		now:        time.Now,
+		tlsConfig:  tlsConfig,
+		keyPrefix:  keyPrefix,
+		self:       cfg.AdvertiseAddr,
+		secret:     cfg.Secret,
+		serverName: cfg.TLS.ServerName,
+	}
+}
+
+// Register records that a control connection for keyID is established on this node.
+// The claim and the tunnel credentials auth, sealed by the caller, are published to Redis. Every client of keyID
+// requires the same credentials, so the copy sent by the most recent one is kept.
+// Returns an error if the claim cannot be stored.
+func (r *Registry) Register(ctx context.Context, keyID string, auth *meta.TunnelAuth) error {
+	r.mu.Lock()
+
+	c, ok := r.owned[keyID]
+	if !ok {
+		c = &claim{}
+		r.owned[keyID] = c
+	}
+
+	c.count++
+	c.auth = auth
+	r.mu.Unlock()
+
+	return r.publish(ctx, keyID, auth)
+}
+
+// Unregister records that a control connection for keyID on this node is closed.
+// The claim is removed from Redis when the last control connection for keyID is gone, and the tunnel
+// credentials with it when no other node holds a claim.
+// Returns an error if the claim cannot be removed.
+func (r *Registry) Unregister(ctx context.Context, keyID string) error {
+	r.mu.Lock()
+
+	c, ok := r.owned[keyID]
+	if !ok {
+		r.mu.Unlock()
+		return nil
+	}
+
+	c.count--
+	last := c.count == 0
+
+	if last {
+		delete(r.owned, keyID)
+	}
+
+	r.mu.Unlock()
+
+	if !last {
+		return nil
+	}
+
+	if err := r.db.ZRem(ctx, r.nodesKey(keyID), r.self).Err(); err != nil {
+		return fmt.Errorf("failed to remove keyID claim: %w", err)
+	}
+
+	// The credentials go with the last claim, so a client reconnecting with other credentials is not refused.
+	claims, err := r.db.ZCount(ctx, r.nodesKey(keyID), strconv.FormatInt(r.now().Unix(), 10), "+inf").Result()
+	if err != nil {
+		return fmt.Errorf("failed to count keyID claims: %w", err)
+	}
+
+	if claims == 0 {
+		if err := r.db.Del(ctx, r.authKey(keyID)).Err(); err != nil {
+			return fmt.Errorf("failed to remove tunnel auth: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Forward opens an internal mutual TLS link to a node that owns keyID and sends the signed forwarding header over it.
+// Nodes are picked at random when several of them own the keyID; this node is never picked.
+// Returns core.ErrKeyIDNotFound if no other node owns keyID, or an error if the lookup or dial fails.
+func (r *Registry) Forward(ctx context.Context, keyID string, tokenType token.TokenType, clientIP string) (conn.WithWriteCloser, error) {
+	nodes, err := r.lookup(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(nodes) == 0 {
+		return nil, core.ErrKeyIDNotFound
+	}
+
+	addr := nodes[rand.IntN(len(nodes))] //nolint:gosec // non-cryptographic node selection is intentional
+
+	d := tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: dialTimeout},
+		Config:    r.dialConfig(addr),
+	}
+
+	c, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial cluster node %s: %w", addr, err)
+	}
+
+	tlsConn, ok := c.(*tls.Conn)
+	if !ok {
+		_ = c.Close()
+		return nil, fmt.Errorf("unexpected connection type %T for cluster node %s", c, addr)
+	}
+
+	hdr := &forwardHeader{
+		KeyID: keyID,
+		Type:  tokenType,
+		IP:    clientIP,
+	}
+	hdr.sign(r.secret, r.now())
+
+	if err := meta.WriteData(tlsConn, hdr); err != nil {
+		_ = tlsConn.Close()
+		return nil, fmt.Errorf("failed to write forward header: %w", err)
+	}
+
+	slog.DebugContext(ctx, "connection forwarded to cluster node", slog.String("keyID", keyID), slog.String("node", addr))
+
+	return tlsConn, nil
+}
+
+// dialConfig returns the TLS configuration for a link to the node at addr.
+// The certificate of the node must be valid for the configured server name, or else for the host of addr.
+func (r *Registry) dialConfig(addr string) *tls.Config {
+	cfg := r.tlsConfig.Clone()
+	cfg.ServerName = r.serverName
+
+	if cfg.ServerName == "" {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			host = addr
+		}
+
+		cfg.ServerName = host
+	}
+
+	return cfg
+}
+
+// TunnelAuth returns the tunnel credentials published for keyID by any node of the cluster.
+// Returns core.ErrKeyIDNotFound if no node published credentials for keyID, or an error if the lookup fails.
+func (r *Registry) TunnelAuth(ctx context.Context, keyID string) (*meta.TunnelAuth, error) {
+	data, err := r.db.Get(ctx, r.authKey(keyID)).Result()
+
+	switch {
+	case errors.Is(err, redis.Nil):
+		return nil, core.ErrKeyIDNotFound
+	case err != nil:
+		return nil, fmt.Errorf("failed to get tunnel auth: %w", err)
+	}
+
+	var auth meta.TunnelAuth
+	if err := json.Unmarshal([]byte(data), &auth); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal tunnel auth: %w", err)
+	}
+
+	return &auth, nil
+}
+
+// Run periodically refreshes the claims of every keyID owned by this node until ctx is cancelled.
+// On shutdown, all claims of this node are removed so other nodes stop forwarding to it.
+func (r *Registry) Run(ctx context.Context) error {
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			r.releaseAll(context.WithoutCancel(ctx))
+			return nil
+		case <-ticker.C:
+			for keyID, auth := range r.ownedKeys() {
+				if err := r.publish(ctx, keyID, auth); err != nil {
+					slog.ErrorContext(ctx, "failed to refresh keyID claim", slog.Any("error", err), slog.String("keyID", keyID))
+				}
+			}
+		}
+	}
+}
+
+// publish stores or refreshes this node's claim on keyID and its tunnel credentials, and drops expired claims of other nodes.
+func (r *Registry) publish(ctx context.Context, keyID string, auth *meta.TunnelAuth) error {
+	key := r.nodesKey(keyID)
+	now := r.now()
+
+	if err := r.db.ZAdd(ctx, key, redis.Z{Score: float64(now.Add(ownershipTTL).Unix()), Member: r.self}).Err(); err != nil {
+		return fmt.Errorf("failed to store keyID claim: %w", err)
+	}
+
+	if err := r.db.ZRemRangeByScore(ctx, key, "-inf", strconv.FormatInt(now.Unix(), 10)).Err(); err != nil {
+		return fmt.Errorf("failed to drop expired keyID claims: %w", err)
+	}
+
+	if err := r.db.Expire(ctx, key, ownershipTTL).Err(); err != nil {
+		return fmt.Errorf("failed to set keyID claim expiration: %w", err)
+	}
+
+	if auth == nil {
+		auth = &meta.TunnelAuth{}
+	}
+
+	data, err := json.Marshal(auth)
+	if err != nil {
+		return fmt.Errorf("failed to marshal tunnel auth: %w", err)
+	}
+
+	if err := r.db.Set(ctx, r.authKey(keyID), data, ownershipTTL).Err(); err != nil {
+		return fmt.Errorf("failed to store tunnel auth: %w", err)
+	}
+
+	return nil
+}
+
+// lookup returns the addresses of the other nodes holding a valid claim on keyID.
+func (r *Registry) lookup(ctx context.Context, keyID string) ([]string, error) {
+	res := r.db.ZRangeByScore(ctx, r.nodesKey(keyID), &redis.ZRangeBy{
+		Min: strconv.FormatInt(r.now().Unix(), 10),
+		Max: "+inf",
+	})
+
+	if res.Err() != nil {
+		return nil, fmt.Errorf("failed to look up keyID owners: %w", res.Err())
+	}
+
+	nodes := make([]string, 0, len(res.Val()))
+
+	for _, addr := range res.Val() {
+		if addr != r.self {
+			nodes = append(nodes, addr)
+		}
+	}
+
+	return nodes, nil
+}
+
+// releaseAll removes the claims of every keyID owned by this node.
+func (r *Registry) releaseAll(ctx context.Context) {
+	for keyID := range r.ownedKeys() {
+		if err := r.db.ZRem(ctx, r.nodesKey(keyID), r.self).Err(); err != nil {
+			slog.ErrorContext(ctx, "failed to release keyID claim", slog.Any("error", err), slog.String("keyID", keyID))
+		}
+	}
+}
+
+// ownedKeys returns a snapshot of the keyIDs owned by this node with their tunnel credentials.
+func (r *Registry) ownedKeys() map[string]*meta.TunnelAuth {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	keys := make(map[string]*meta.TunnelAuth, len(r.owned))
+
+	for k, c := range r.owned {
+		keys[k] = c.auth
+	}
+
+	return keys
+}
+
+func (r *Registry) nodesKey(keyID string) string {
+	return r.keyPrefix + nodesKeyPrefix + keyID
+}
+
+func (r *Registry) authKey(keyID string) string {
+	return r.keyPrefix + authKeyPrefix + keyID
+}
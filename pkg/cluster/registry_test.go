@@ -0,0 +1,245 @@
+package cluster
+
+import (
+	"context"
+	"crypto/tls"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/ksysoev/make-it-public/pkg/core"
+	"github.com/ksysoev/make-it-public/pkg/core/conn/meta"
+	"github.com/ksysoev/make-it-public/pkg/core/token"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testPrefix   = "prefix::"
+	testNodesKey = "prefix::NODES::key1"
+	testAuthKey  = "prefix::TUNAUTH::key1"
+	testSelf     = "10.0.0.1:8083"
+)
+
+func newTestRegistry(t *testing.T) (*Registry, redismock.ClientMock, time.Time) {
+	t.Helper()
+
+	rdb, mockRDB := redismock.NewClientMock()
+	now := time.Unix(1700000000, 0)
+
+	r := NewRegistry(Config{AdvertiseAddr: testSelf, Secret: "secret"}, nil, rdb, testPrefix)
+	r.now = func() time.Time { return now }
+
+	return r, mockRDB, now
+}
+
+func expectPublish(m redismock.ClientMock, now time.Time, authData string) {
+	m.ExpectZAdd(testNodesKey, redis.Z{Score: float64(now.Add(ownershipTTL).Unix()), Member: testSelf}).SetVal(1)
+	m.ExpectZRemRangeByScore(testNodesKey, "-inf", "1700000000").SetVal(0)
+	m.ExpectExpire(testNodesKey, ownershipTTL).SetVal(true)
+	m.ExpectSet(testAuthKey, []byte(authData), ownershipTTL).SetVal("OK")
+}
+
+func TestRegistry_RegisterAndUnregister(t *testing.T) {
+	r, mockRDB, now := newTestRegistry(t)
+
+	// Every control connection refreshes the claim with its credentials, only the last one removes it.
+	expectPublish(mockRDB, now, "{}")
+	expectPublish(mockRDB, now, `{"bearer":"abc"}`)
+	mockRDB.ExpectZRem(testNodesKey, testSelf).SetVal(1)
+	mockRDB.ExpectZCount(testNodesKey, "1700000000", "+inf").SetVal(0)
+	mockRDB.ExpectDel(testAuthKey).SetVal(1)
+
+	require.NoError(t, r.Register(context.Background(), "key1", nil))
+	require.NoError(t, r.Register(context.Background(), "key1", &meta.TunnelAuth{BearerToken: "abc"}))
+	require.NoError(t, r.Unregister(context.Background(), "key1"))
+	require.NoError(t, r.Unregister(context.Background(), "key1"))
+
+	assert.Empty(t, r.ownedKeys())
+	assert.NoError(t, mockRDB.ExpectationsWereMet())
+}
+
+func TestRegistry_Register_Error(t *testing.T) {
+	r, mockRDB, now := newTestRegistry(t)
+
+	mockRDB.ExpectZAdd(testNodesKey, redis.Z{Score: float64(now.Add(ownershipTTL).Unix()), Member: testSelf}).SetErr(assert.AnError)
+
+	err := r.Register(context.Background(), "key1", nil)
+
+	assert.ErrorIs(t, err, assert.AnError)
+}
+
+func TestRegistry_Unregister_KeepsAuthOfOtherNodes(t *testing.T) {
+	r, mockRDB, now := newTestRegistry(t)
+
+	// Another node still serves keyID, so the published credentials stay.
+	expectPublish(mockRDB, now, `{"bearer":"abc"}`)
+	mockRDB.ExpectZRem(testNodesKey, testSelf).SetVal(1)
+	mockRDB.ExpectZCount(testNodesKey, "1700000000", "+inf").SetVal(1)
+
+	require.NoError(t, r.Register(context.Background(), "key1", &meta.TunnelAuth{BearerToken: "abc"}))
+	require.NoError(t, r.Unregister(context.Background(), "key1"))
+
+	assert.NoError(t, mockRDB.ExpectationsWereMet())
+}
+
+func TestRegistry_Unregister_NotOwned(t *testing.T) {
+	r, mockRDB, _ := newTestRegistry(t)
+
+	require.NoError(t, r.Unregister(context.Background(), "key1"))
+	assert.NoError(t, mockRDB.ExpectationsWereMet())
+}
+
+func TestRegistry_Forward_NoRemoteOwner(t *testing.T) {
+	r, mockRDB, _ := newTestRegistry(t)
+
+	// The only owner is this node, so there is nowhere to forward to.
+	mockRDB.ExpectZRangeByScore(testNodesKey, &redis.ZRangeBy{Min: "1700000000", Max: "+inf"}).SetVal([]string{testSelf})
+
+	_, err := r.Forward(context.Background(), "key1", token.TokenTypeWeb, "1.2.3.4")
+
+	assert.ErrorIs(t, err, core.ErrKeyIDNotFound)
+}
+
+func TestRegistry_Forward_LookupError(t *testing.T) {
+	r, mockRDB, _ := newTestRegistry(t)
+
+	mockRDB.ExpectZRangeByScore(testNodesKey, &redis.ZRangeBy{Min: "1700000000", Max: "+inf"}).SetErr(assert.AnError)
+
+	_, err := r.Forward(context.Background(), "key1", token.TokenTypeWeb, "1.2.3.4")
+
+	assert.ErrorIs(t, err, assert.AnError)
+}
+
+func TestRegistry_Forward_WritesHeader(t *testing.T) {
+	cfg := newTestTLS(t)
+
+	tlsConfig, err := cfg.tlsConfig()
+	require.NoError(t, err)
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", tlsConfig)
+	require.NoError(t, err)
+
+	defer func() { _ = ln.Close() }()
+
+	r, mockRDB, now := newTestRegistry(t)
+	r.tlsConfig = tlsConfig
+
+	mockRDB.ExpectZRangeByScore(testNodesKey, &redis.ZRangeBy{Min: "1700000000", Max: "+inf"}).SetVal([]string{ln.Addr().String()})
+
+	received := make(chan forwardHeader, 1)
+
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+
+		defer func() { _ = c.Close() }()
+
+		var hdr forwardHeader
+		if err := meta.ReadData(c, &hdr); err == nil {
+			received <- hdr
+		}
+	}()
+
+	fwdConn, err := r.Forward(context.Background(), "key1", token.TokenTypeTCP, "1.2.3.4")
+	require.NoError(t, err)
+
+	defer func() { _ = fwdConn.Close() }()
+
+	select {
+	case hdr := <-received:
+		assert.Equal(t, "key1", hdr.KeyID)
+		assert.Equal(t, token.TokenTypeTCP, hdr.Type)
+		assert.Equal(t, "1.2.3.4", hdr.IP)
+		assert.True(t, hdr.verify("secret", now))
+		assert.NotContains(t, hdr.MAC, "secret")
+	case <-time.After(time.Second):
+		t.Fatal("forward header was not received")
+	}
+}
+
+func TestRegistry_Forward_UntrustedNode(t *testing.T) {
+	nodeCfg := newTestTLS(t)
+
+	nodeTLS, err := nodeCfg.tlsConfig()
+	require.NoError(t, err)
+
+	// The listening node has a certificate of another CA.
+	otherCfg := newTestTLS(t)
+
+	otherTLS, err := otherCfg.tlsConfig()
+	require.NoError(t, err)
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", otherTLS)
+	require.NoError(t, err)
+
+	defer func() { _ = ln.Close() }()
+
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+
+		_ = c.(*tls.Conn).Handshake()
+		_ = c.Close()
+	}()
+
+	r, mockRDB, _ := newTestRegistry(t)
+	r.tlsConfig = nodeTLS
+
+	mockRDB.ExpectZRangeByScore(testNodesKey, &redis.ZRangeBy{Min: "1700000000", Max: "+inf"}).SetVal([]string{ln.Addr().String()})
+
+	_, err = r.Forward(context.Background(), "key1", token.TokenTypeTCP, "1.2.3.4")
+
+	assert.ErrorContains(t, err, "failed to dial cluster node")
+}
+
+func TestRegistry_Run_ReleasesClaimsOnShutdown(t *testing.T) {
+	r, mockRDB, now := newTestRegistry(t)
+
+	expectPublish(mockRDB, now, "{}")
+	mockRDB.ExpectZRem(testNodesKey, testSelf).SetVal(1)
+
+	require.NoError(t, r.Register(context.Background(), "key1", nil))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	require.NoError(t, r.Run(ctx))
+	assert.NoError(t, mockRDB.ExpectationsWereMet())
+}
+
+func TestRegistry_TunnelAuth(t *testing.T) {
+	r, mockRDB, _ := newTestRegistry(t)
+
+	mockRDB.ExpectGet(testAuthKey).SetVal(`{"basic":"abc"}`)
+
+	auth, err := r.TunnelAuth(context.Background(), "key1")
+
+	require.NoError(t, err)
+	assert.Equal(t, &meta.TunnelAuth{BasicAuth: "abc"}, auth)
+}
+
+func TestRegistry_TunnelAuth_NotFound(t *testing.T) {
+	r, mockRDB, _ := newTestRegistry(t)
+
+	mockRDB.ExpectGet(testAuthKey).RedisNil()
+
+	_, err := r.TunnelAuth(context.Background(), "key1")
+
+	assert.ErrorIs(t, err, core.ErrKeyIDNotFound)
+}
+
+func TestRegistry_TunnelAuth_Error(t *testing.T) {
+	r, mockRDB, _ := newTestRegistry(t)
+
+	mockRDB.ExpectGet(testAuthKey).SetErr(assert.AnError)
+
+	_, err := r.TunnelAuth(context.Background(), "key1")
+
+	assert.ErrorIs(t, err, assert.AnError)
+}
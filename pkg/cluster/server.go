@@ -0,0 +1,198 @@
+package cluster
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ksysoev/make-it-public/pkg/core"
+	"github.com/ksysoev/make-it-public/pkg/core/conn/meta"
+	"github.com/ksysoev/make-it-public/pkg/core/token"
+)
+
+const (
+	// headerTimeout bounds how long a peer node may take to complete the TLS handshake and send the forwarding header.
+	headerTimeout = 5 * time.Second
+
+	// maxClockSkew is how far the time of a forwarding header may be from the clock of the receiving node.
+	maxClockSkew = 30 * time.Second
+)
+
+// ConnService is the subset of core.Service required by the cluster link server.
+type ConnService interface {
+	HandleForwardedConn(ctx context.Context, keyID string, tokenType token.TokenType, conn net.Conn, clientIP string) error
+	SetConnRegistry(registry core.ConnRegistry)
+}
+
+// forwardHeader is sent by the forwarding node as the first message of every internal link.
+// MAC authenticates the other fields with the shared cluster secret, so the secret itself is never sent.
+type forwardHeader struct {
+	KeyID string          `json:"key_id"`
+	Type  token.TokenType `json:"type"`
+	IP    string          `json:"ip"`
+	MAC   string          `json:"mac"`
+	Time  int64           `json:"time"`
+}
+
+// sign sets the time of the header to now and its MAC to the one computed with secret.
+func (h *forwardHeader) sign(secret string, now time.Time) {
+	h.Time = now.Unix()
+	h.MAC = hex.EncodeToString(h.sum(secret))
+}
+
+// verify reports whether the MAC of the header was computed with secret, and its time is within maxClockSkew of now.
+func (h *forwardHeader) verify(secret string, now time.Time) bool {
+	sent := time.Unix(h.Time, 0)
+	if sent.Before(now.Add(-maxClockSkew)) || sent.After(now.Add(maxClockSkew)) {
+		return false
+	}
+
+	mac, err := hex.DecodeString(h.MAC)
+	if err != nil {
+		return false
+	}
+
+	return hmac.Equal(mac, h.sum(secret))
+}
+
+// sum returns the HMAC-SHA256 of the header fields, keyed with secret.
+func (h *forwardHeader) sum(secret string) []byte {
+	m := hmac.New(sha256.New, []byte(secret))
+
+	for _, field := range []string{h.KeyID, string(h.Type), h.IP, strconv.FormatInt(h.Time, 10)} {
+		_, _ = m.Write([]byte(field))
+		_, _ = m.Write([]byte{0})
+	}
+
+	return m.Sum(nil)
+}
+
+// Server accepts internal links from other cluster nodes and serves the forwarded
+// connections through the control connections that terminate on this node.
+type Server struct {
+	connService ConnService
+	registry    *Registry
+	tlsConfig   *tls.Config
+	now         func() time.Time
+	config      Config
+}
+
+// New validates cfg, creates a Server and a Registry backed by db, and injects the registry
+// into connService so that connections for keyIDs owned by other nodes are forwarded.
+// keyPrefix is prepended to every Redis key used by the registry.
+// Returns an error if the configuration is invalid or its certificates cannot be loaded.
+func New(cfg Config, connService ConnService, db Redis, keyPrefix string) (*Server, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid cluster config: %w", err)
+	}
+
+	tlsConfig, err := cfg.TLS.tlsConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load cluster TLS config: %w", err)
+	}
+
+	registry := NewRegistry(cfg, tlsConfig, db, keyPrefix)
+	connService.SetConnRegistry(registry)
+
+	return &Server{
+		connService: connService,
+		registry:    registry,
+		tlsConfig:   tlsConfig,
+		now:         time.Now,
+		config:      cfg,
+	}, nil
+}
+
+// Run listens for internal links and keeps the registry claims of this node fresh until ctx is cancelled.
+// Returns an error if the listener cannot be created or stops unexpectedly.
+func (s *Server) Run(ctx context.Context) error {
+	tcpLn, err := net.Listen("tcp", s.config.Listen)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.config.Listen, err)
+	}
+
+	ln := tls.NewListener(tcpLn, s.tlsConfig)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	wg := sync.WaitGroup{}
+	defer wg.Wait()
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		_ = s.registry.Run(ctx)
+	}()
+
+	go func() {
+		<-ctx.Done()
+
+		_ = ln.Close()
+	}()
+
+	for {
+		c, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			return fmt.Errorf("failed to accept connection: %w", err)
+		}
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			defer func() { _ = c.Close() }()
+
+			//nolint:staticcheck,revive // don't want to couple with cmd package for now
+			ctx := context.WithValue(ctx, "req_id", uuid.New().String())
+
+			s.handleConn(ctx, c)
+		}()
+	}
+}
+
+// handleConn authenticates an internal link by its forwarding header and hands the connection to the service.
+// The peer node has already been authenticated by its certificate when the link is served over TLS.
+func (s *Server) handleConn(ctx context.Context, c net.Conn) {
+	if err := c.SetReadDeadline(time.Now().Add(headerTimeout)); err != nil {
+		slog.ErrorContext(ctx, "failed to set read deadline on cluster link", slog.Any("error", err))
+		return
+	}
+
+	var hdr forwardHeader
+	if err := meta.ReadData(c, &hdr); err != nil {
+		slog.DebugContext(ctx, "failed to read forward header", slog.Any("error", err), slog.Any("remote", c.RemoteAddr()))
+		return
+	}
+
+	if !hdr.verify(s.config.Secret, s.now()) {
+		slog.WarnContext(ctx, "rejected cluster link with invalid forward header", slog.Any("remote", c.RemoteAddr()))
+		return
+	}
+
+	if err := c.SetReadDeadline(time.Time{}); err != nil {
+		slog.ErrorContext(ctx, "failed to clear read deadline on cluster link", slog.Any("error", err))
+		return
+	}
+
+	slog.DebugContext(ctx, "forwarded connection received", slog.String("keyID", hdr.KeyID), slog.Any("remote", c.RemoteAddr()))
+
+	if err := s.connService.HandleForwardedConn(ctx, hdr.KeyID, hdr.Type, c, hdr.IP); err != nil {
+		slog.DebugContext(ctx, "failed to handle forwarded connection", slog.Any("error", err), slog.String("keyID", hdr.KeyID))
+	}
+}
@@ -0,0 +1,247 @@
+package cluster
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/ksysoev/make-it-public/pkg/core/conn/meta"
+	"github.com/ksysoev/make-it-public/pkg/core/token"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew_InvalidConfig(t *testing.T) {
+	connService := NewMockConnService(t)
+	rdb, _ := redismock.NewClientMock()
+
+	_, err := New(Config{}, connService, rdb, testPrefix)
+
+	assert.Error(t, err)
+}
+
+func TestNew_InjectsRegistry(t *testing.T) {
+	connService := NewMockConnService(t)
+	rdb, _ := redismock.NewClientMock()
+
+	connService.EXPECT().SetConnRegistry(mock.AnythingOfType("*cluster.Registry")).Return()
+
+	srv, err := New(Config{Listen: "127.0.0.1:0", AdvertiseAddr: testSelf, Secret: "secret", TLS: newTestTLS(t)}, connService, rdb, testPrefix)
+
+	require.NoError(t, err)
+	assert.NotNil(t, srv.registry)
+}
+
+func TestNew_InvalidCertificates(t *testing.T) {
+	connService := NewMockConnService(t)
+	rdb, _ := redismock.NewClientMock()
+
+	cfg := Config{
+		Listen:        "127.0.0.1:0",
+		AdvertiseAddr: testSelf,
+		Secret:        "secret",
+		TLS:           TLSConfig{CertFile: "missing.crt", KeyFile: "missing.key", CAFile: "missing.crt"},
+	}
+
+	_, err := New(cfg, connService, rdb, testPrefix)
+
+	assert.ErrorContains(t, err, "failed to load cluster TLS config")
+}
+
+func TestServer_HandleConn(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		sentAt    time.Time
+		name      string
+		secret    string
+		forwarded bool
+	}{
+		{name: "valid MAC", secret: "secret", sentAt: now, forwarded: true},
+		{name: "clock skew within limit", secret: "secret", sentAt: now.Add(-maxClockSkew), forwarded: true},
+		{name: "invalid MAC", secret: "wrong", sentAt: now, forwarded: false},
+		{name: "stale header", secret: "secret", sentAt: now.Add(-maxClockSkew - time.Second), forwarded: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			connService := NewMockConnService(t)
+			srv := &Server{
+				connService: connService,
+				now:         func() time.Time { return now },
+				config:      Config{Secret: "secret"},
+			}
+
+			if tt.forwarded {
+				connService.EXPECT().HandleForwardedConn(mock.Anything, "key1", token.TokenTypeWeb, mock.Anything, "1.2.3.4").Return(nil)
+			}
+
+			local, remote := net.Pipe()
+
+			defer func() { _ = remote.Close() }()
+
+			hdr := &forwardHeader{KeyID: "key1", Type: token.TokenTypeWeb, IP: "1.2.3.4"}
+			hdr.sign(tt.secret, tt.sentAt)
+
+			go func() {
+				_ = meta.WriteData(remote, hdr)
+			}()
+
+			done := make(chan struct{})
+
+			go func() {
+				srv.handleConn(context.Background(), local)
+				close(done)
+			}()
+
+			select {
+			case <-done:
+			case <-time.After(time.Second):
+				t.Fatal("handleConn did not return")
+			}
+		})
+	}
+}
+
+func TestServer_HandleConn_TamperedHeader(t *testing.T) {
+	connService := NewMockConnService(t)
+	srv := &Server{connService: connService, now: time.Now, config: Config{Secret: "secret"}}
+
+	local, remote := net.Pipe()
+
+	defer func() { _ = remote.Close() }()
+
+	// The client IP is changed after the header was signed.
+	hdr := &forwardHeader{KeyID: "key1", Type: token.TokenTypeWeb, IP: "1.2.3.4"}
+	hdr.sign("secret", time.Now())
+	hdr.IP = "10.0.0.1"
+
+	go func() {
+		_ = meta.WriteData(remote, hdr)
+	}()
+
+	srv.handleConn(context.Background(), local)
+}
+
+func TestServer_Run_ForwardsOverTLS(t *testing.T) {
+	tlsCfg := newTestTLS(t)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	addr := ln.Addr().String()
+	require.NoError(t, ln.Close())
+
+	connService := NewMockConnService(t)
+	connService.EXPECT().SetConnRegistry(mock.Anything).Return()
+
+	received := make(chan string, 1)
+
+	connService.EXPECT().HandleForwardedConn(mock.Anything, "key1", token.TokenTypeTCP, mock.Anything, "1.2.3.4").
+		RunAndReturn(func(_ context.Context, _ string, _ token.TokenType, c net.Conn, _ string) error {
+			buf := make([]byte, 4)
+			_, err := io.ReadFull(c, buf)
+			received <- string(buf)
+
+			return err
+		})
+
+	rdb, mockRDB := redismock.NewClientMock()
+	mockRDB.MatchExpectationsInOrder(false)
+
+	srv, err := New(Config{Listen: addr, AdvertiseAddr: addr, Secret: "secret", TLS: tlsCfg}, connService, rdb, testPrefix)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+
+	go func() { done <- srv.Run(ctx) }()
+
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// Another node of the cluster, sharing the CA and the secret.
+	peer := NewRegistry(Config{AdvertiseAddr: "10.0.0.2:8083", Secret: "secret"}, srv.tlsConfig, rdb, testPrefix)
+	mockRDB.ExpectZRangeByScore(testNodesKey, &redis.ZRangeBy{Min: strconv.FormatInt(time.Now().Unix(), 10), Max: "+inf"}).SetVal([]string{addr})
+
+	var fwdConn io.WriteCloser
+
+	require.Eventually(t, func() bool {
+		c, err := peer.Forward(ctx, "key1", token.TokenTypeTCP, "1.2.3.4")
+		if err != nil {
+			mockRDB.ExpectZRangeByScore(testNodesKey, &redis.ZRangeBy{Min: strconv.FormatInt(time.Now().Unix(), 10), Max: "+inf"}).SetVal([]string{addr})
+			return false
+		}
+
+		fwdConn = c
+
+		return true
+	}, 2*time.Second, 20*time.Millisecond)
+
+	defer func() { _ = fwdConn.Close() }()
+
+	_, err = fwdConn.Write([]byte("ping"))
+	require.NoError(t, err)
+
+	select {
+	case got := <-received:
+		assert.Equal(t, "ping", got)
+	case <-time.After(2 * time.Second):
+		t.Fatal("forwarded connection was not served")
+	}
+}
+
+func TestServer_Run_RejectsPeerWithoutCertificate(t *testing.T) {
+	connService := NewMockConnService(t)
+	connService.EXPECT().SetConnRegistry(mock.Anything).Return()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	addr := ln.Addr().String()
+	require.NoError(t, ln.Close())
+
+	rdb, mockRDB := redismock.NewClientMock()
+	mockRDB.MatchExpectationsInOrder(false)
+
+	srv, err := New(Config{Listen: addr, AdvertiseAddr: addr, Secret: "secret", TLS: newTestTLS(t)}, connService, rdb, testPrefix)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+
+	go func() { done <- srv.Run(ctx) }()
+
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	clientCfg := srv.tlsConfig.Clone()
+	clientCfg.Certificates = nil
+	clientCfg.ServerName = "127.0.0.1"
+
+	var c *tls.Conn
+
+	require.Eventually(t, func() bool {
+		c, err = tls.Dial("tcp", addr, clientCfg)
+		return err == nil
+	}, 2*time.Second, 20*time.Millisecond)
+
+	defer func() { _ = c.Close() }()
+
+	// TLS 1.3 reports the missing client certificate on the first read.
+	_ = c.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, err = c.Read(make([]byte, 1))
+
+	assert.Error(t, err)
+}
@@ -2,18 +2,29 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"strings"
 	"time"
+
+	"github.com/ksysoev/make-it-public/pkg/api"
 )
 
+// maxHealthBodySize is the largest health check response body that is read.
+const maxHealthBodySize = 1024
+
+// ErrServerDraining is returned by RunHealthCheck while the server drains before stopping.
+var ErrServerDraining = errors.New("server is draining")
+
 // RunHealthCheck performs a health check on the local server's API endpoint.
 // It initializes the logger and loads the configuration. It requires the API listen address to be properly configured.
 // Accepts ctx for managing the lifecycle and arg for configuration options.
 // Returns an error if the logger fails to initialize, the configuration cannot be loaded, the API address is invalid,
 // or the health check HTTP request fails, including non-200 HTTP response statuses.
+// Returns ErrServerDraining if the server is draining before it stops.
 func RunHealthCheck(ctx context.Context, arg *args) error {
 	if err := initLogger(arg); err != nil {
 		return fmt.Errorf("failed to init logger: %w", err)
@@ -42,10 +53,20 @@ func RunHealthCheck(ctx context.Context, arg *args) error {
 		return fmt.Errorf("failed to perform health check: %w", err)
 	}
 
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxHealthBodySize))
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to read response body", slog.Any("error", err))
+	}
+
 	if err := resp.Body.Close(); err != nil {
 		slog.ErrorContext(ctx, "failed to close response body", slog.Any("error", err))
 	}
 
+	if resp.StatusCode == http.StatusServiceUnavailable && strings.TrimSpace(string(body)) == api.HealthDraining {
+		slog.InfoContext(ctx, "server is draining", "status", resp.StatusCode)
+		return ErrServerDraining
+	}
+
 	if resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("health check failed with status code: %d", resp.StatusCode)
 	}
@@ -35,3 +35,16 @@ func TestRunHealthCheck_Failure(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "health check failed")
 }
+
+func TestRunHealthCheck_Draining(t *testing.T) {
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "draining", http.StatusServiceUnavailable)
+	}))
+
+	defer httpServer.Close()
+
+	t.Setenv("API_LISTEN", httpServer.Listener.Addr().String())
+
+	err := RunHealthCheck(t.Context(), &args{LogLevel: "error"})
+	assert.ErrorIs(t, err, ErrServerDraining)
+}
@@ -4,11 +4,17 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
 
+	"github.com/ksysoev/make-it-public/pkg/core/conn/meta"
 	"github.com/ksysoev/make-it-public/pkg/core/token"
 	"github.com/ksysoev/make-it-public/pkg/display"
 	"github.com/ksysoev/make-it-public/pkg/dummy"
+	"github.com/ksysoev/make-it-public/pkg/inspect"
 	"github.com/ksysoev/make-it-public/pkg/revclient"
+	"github.com/ksysoev/make-it-public/pkg/target"
 
 	"golang.org/x/sync/errgroup"
 )
@@ -21,6 +27,10 @@ func RunClientCommand(ctx context.Context, args *args) error {
 		return fmt.Errorf("failed to init logger: %w", err)
 	}
 
+	if args.ClientConfig != "" {
+		return runTunnels(ctx, args, disp)
+	}
+
 	// Validate token with improved error messaging
 	tkn, err := token.Decode(args.Token)
 	if err != nil {
@@ -34,15 +44,78 @@ func RunClientCommand(ctx context.Context, args *args) error {
 	exposeAddr := args.Expose
 	eg, ctx := errgroup.WithContext(ctx)
 
-	// Reject --dummy and --echo-ws for TCP tokens — these flags start HTTP-specific services
-	if tkn.Type == token.TokenTypeTCP && (args.LocalServer || args.EchoWS) {
+	// Reject --dummy and --echo-ws for tokens other than web — these flags start HTTP/1.1 services
+	if tkn.Type != token.TokenTypeWeb && (args.LocalServer || args.EchoWS) {
 		disp.ShowError("Invalid configuration", nil,
 			"--dummy and --echo-ws are only supported with web tokens.\n"+
-				"  Use --expose to forward a TCP service.")
+				"  Use --expose to forward a local service.")
 
 		return fmt.Errorf("--dummy and --echo-ws are only supported with web tokens")
 	}
 
+	// Reject --inspect for tokens other than web — only HTTP/1.1 traffic can be recorded
+	if tkn.Type != token.TokenTypeWeb && args.Inspect != "" {
+		disp.ShowError("Invalid configuration", nil,
+			"--inspect is only supported with web tokens.")
+
+		return fmt.Errorf("--inspect is only supported with web tokens")
+	}
+
+	routes, err := parseRoutes(args.Routes, args.StripPrefix)
+	if err != nil {
+		disp.ShowError("Invalid configuration", nil,
+			"--route must be in the '/prefix=host:port' format.")
+
+		return err
+	}
+
+	// Reject --inspect with --route — captured requests are replayed to the single service of --expose
+	if len(routes) > 0 && args.Inspect != "" {
+		disp.ShowError("Invalid configuration", nil,
+			"--inspect cannot be combined with --route.")
+
+		return fmt.Errorf("--inspect cannot be combined with --route")
+	}
+
+	flagTunnel := tunnelConfig{
+		Expose:                args.Expose,
+		BasicAuth:             args.BasicAuth,
+		BearerToken:           args.BearerToken,
+		HostHeader:            args.HostHeader,
+		Routes:                routes,
+		RequestHeaders:        args.ReqHeaders,
+		RemoveRequestHeaders:  args.RmReqHeaders,
+		ResponseHeaders:       args.RespHeaders,
+		RemoveResponseHeaders: args.RmRespHeaders,
+		UpstreamServerName:    args.UpstreamSNI,
+		UpstreamCA:            args.UpstreamCA,
+		UpstreamCert:          args.UpstreamCert,
+		UpstreamKey:           args.UpstreamKey,
+		Port:                  args.Port,
+		H2C:                   args.H2C,
+		UpstreamInsecure:      args.UpstreamSkipVerify,
+	}
+
+	if hint, err := checkTunnelOptions(tkn, flagTunnel); err != nil {
+		disp.ShowError("Invalid configuration", nil, hint)
+
+		return err
+	}
+
+	headers, err := headerRules(flagTunnel)
+	if err != nil {
+		disp.ShowError("Invalid configuration", nil,
+			"--request-header and --response-header must be in the 'Name:Value' format.")
+
+		return err
+	}
+
+	upstream, err := upstreamTLS(flagTunnel)
+	if err != nil {
+		disp.ShowError("Invalid upstream TLS options", err, "")
+		return err
+	}
+
 	// Validate mutual exclusivity of --dummy and --echo-ws
 	if args.LocalServer && args.EchoWS {
 		disp.ShowError("Invalid configuration", nil,
@@ -52,8 +125,10 @@ func RunClientCommand(ctx context.Context, args *args) error {
 		return fmt.Errorf("cannot use both --dummy and --echo-ws flags")
 	}
 
-	if exposeAddr == "" && args.LocalServer {
+	// With --expose, the local servers listen on its address instead of a random port
+	if args.LocalServer {
 		lclSrv, err := dummy.New(dummy.Config{
+			Listen:      args.Expose,
 			Status:      args.Status,
 			JSON:        args.JSON,
 			Body:        args.Body,
@@ -70,8 +145,9 @@ func RunClientCommand(ctx context.Context, args *args) error {
 		exposeAddr = lclSrv.Addr()
 	}
 
-	if exposeAddr == "" && args.EchoWS {
+	if args.EchoWS {
 		wsSrv, err := dummy.NewWSEchoServer(dummy.WSConfig{
+			Listen:      args.Expose,
 			Interactive: args.Interactive,
 		})
 		if err != nil {
@@ -85,7 +161,7 @@ func RunClientCommand(ctx context.Context, args *args) error {
 	}
 
 	// Validate that we have something to expose
-	if exposeAddr == "" {
+	if exposeAddr == "" && len(routes) == 0 {
 		disp.ShowError("No service to expose", nil,
 			"Specify a local service with --expose or use --dummy/--echo-ws for testing:\n"+
 				"  mit --token <token> --expose localhost:8080\n"+
@@ -96,11 +172,39 @@ func RunClientCommand(ctx context.Context, args *args) error {
 	}
 
 	cfg := revclient.Config{
-		ServerAddr: args.Server,
-		DestAddr:   exposeAddr,
-		NoTLS:      args.NoTLS,
-		Insecure:   args.Insecure,
-		EnableV2:   !args.DisableV2, // V2 enabled by default, use --disable-v2 for old servers
+		ServerAddr:  args.Server,
+		DestAddr:    exposeAddr,
+		Routes:      revclientRoutes(routes),
+		Headers:     headers,
+		UpstreamTLS: upstream,
+		NoTLS:       args.NoTLS,
+		Insecure:    args.Insecure,
+		EnableV2:    !args.DisableV2, // V2 enabled by default, use --disable-v2 for old servers
+		Auth:        meta.NewTunnelAuth(args.BasicAuth, args.BearerToken),
+		Port:        args.Port,
+		H2C:         args.H2C,
+	}
+
+	var interceptor revclient.Interceptor
+
+	if args.Inspect != "" {
+		// Captured requests are replayed to the service the way the tunnel reaches it; its address is validated above
+		dest, _ := target.Parse(exposeAddr)
+
+		insp, err := inspect.New(inspect.Config{
+			Listen: args.Inspect,
+			Target: dest,
+			TLS:    upstream,
+			H2C:    args.H2C,
+		})
+		if err != nil {
+			disp.ShowError("Failed to create request inspector", err, "")
+			return fmt.Errorf("failed to create request inspector: %w", err)
+		}
+
+		eg.Go(func() error { return insp.Run(ctx) })
+
+		interceptor = insp.Recorder()
 	}
 
 	// Start spinner while connecting
@@ -110,14 +214,14 @@ func RunClientCommand(ctx context.Context, args *args) error {
 	}
 
 	// Create client with callbacks for display
-	revcli := revclient.NewClientServer(cfg, tkn,
+	opts := []revclient.Option{
 		revclient.WithOnConnected(func(url string) {
 			// Stop the initial connecting spinner and show the success banner.
 			if spinner != nil {
 				spinner.Success("Connected!")
 			}
 
-			disp.ShowConnected(url, exposeAddr, string(tkn.Type))
+			disp.ShowConnected(url, forwardingTo(exposeAddr, routes), string(tkn.Type))
 		}),
 		revclient.WithOnReconnected(func(url string) {
 			// Stop the spinner if it is still active (e.g. the initial connection was
@@ -127,13 +231,19 @@ func RunClientCommand(ctx context.Context, args *args) error {
 				spinner.Success("Reconnected!")
 			}
 
-			disp.ShowConnected(url, exposeAddr, string(tkn.Type))
+			disp.ShowConnected(url, forwardingTo(exposeAddr, routes), string(tkn.Type))
 		}),
 		revclient.WithOnRequest(func(clientIP string) {
 			// Show request separator for each incoming connection
 			disp.ShowRequestSeparator(clientIP)
 		}),
-	)
+	}
+
+	if interceptor != nil {
+		opts = append(opts, revclient.WithInterceptor(interceptor))
+	}
+
+	revcli := revclient.NewClientServer(cfg, tkn, opts...)
 
 	slog.InfoContext(ctx, "mit client started", "server", args.Server)
 	eg.Go(func() error { return revcli.Run(ctx) })
@@ -148,3 +258,186 @@ func RunClientCommand(ctx context.Context, args *args) error {
 
 	return err
 }
+
+// checkTunnelOptions checks that the options of tunnel are supported by the type of tkn.
+// Returns the hint shown to the user and the error describing the first unsupported option, or nil if all are supported.
+func checkTunnelOptions(tkn *token.Token, tunnel tunnelConfig) (string, error) {
+	// Reject --basic-auth and --bearer-token for tokens other than web — credentials are checked by the HTTP edge
+	if tkn.Type != token.TokenTypeWeb && (tunnel.BasicAuth != "" || tunnel.BearerToken != "") {
+		return "--basic-auth and --bearer-token are only supported with web tokens.",
+			fmt.Errorf("--basic-auth and --bearer-token are only supported with web tokens")
+	}
+
+	// Reject --h2c for tokens other than web — gRPC tunnels always reach the local service over HTTP/2
+	if tkn.Type != token.TokenTypeWeb && tunnel.H2C {
+		return "--h2c is only supported with web tokens.", fmt.Errorf("--h2c is only supported with web tokens")
+	}
+
+	// Reject --route for tokens other than web — requests are routed by their path
+	if tkn.Type != token.TokenTypeWeb && len(tunnel.Routes) > 0 {
+		return "--route is only supported with web tokens.", fmt.Errorf("--route is only supported with web tokens")
+	}
+
+	anyTLS := false
+
+	if tunnel.Expose != "" {
+		dest, err := target.Parse(tunnel.Expose)
+		if err != nil {
+			return "--expose must be host:port, an http:// or https:// URL, or a unix:// socket path.",
+				fmt.Errorf("invalid --expose value: %w", err)
+		}
+
+		// UDP tunnels relay datagrams, which can not be sent to a URL or a Unix domain socket
+		if tkn.Type == token.TokenTypeUDP && tunnel.Expose != dest.Address {
+			return "--expose must be host:port for udp tokens.", fmt.Errorf("invalid --expose value for udp token: %s", tunnel.Expose)
+		}
+
+		anyTLS = dest.TLS
+	}
+
+	for _, r := range tunnel.Routes {
+		dest, err := target.Parse(r.Expose)
+		if !strings.HasPrefix(r.Path, "/") || err != nil {
+			return "--route must be in the '/prefix=host:port' format.", fmt.Errorf("invalid --route value: %s=%s", r.Path, r.Expose)
+		}
+
+		anyTLS = anyTLS || dest.TLS
+	}
+
+	// Reject https:// services for TLS passthrough tokens — the TLS sessions of visitors already reach the local service
+	if tkn.Type == token.TokenTypeTLS && anyTLS {
+		return "https:// services are not supported with tls tokens.\n  Use host:port to pass TLS sessions through.",
+			fmt.Errorf("https:// services are not supported with tls tokens")
+	}
+
+	if tunnel.H2C && anyTLS {
+		return "--h2c cannot be combined with an https:// service.", fmt.Errorf("--h2c cannot be combined with an https:// service")
+	}
+
+	if tunnel.hasUpstreamTLS() && !anyTLS {
+		return "--upstream-* options require an https:// service, e.g. --expose https://localhost:8443.",
+			fmt.Errorf("--upstream-* options require an https:// service")
+	}
+
+	// Reject header rules for tokens other than web — only HTTP requests have headers to rewrite
+	if tkn.Type != token.TokenTypeWeb && tunnel.hasHeaderRules() {
+		return "--host-header and header rules are only supported with web tokens.",
+			fmt.Errorf("--host-header and header rules are only supported with web tokens")
+	}
+
+	// Reject --port for tokens other than TCP — only TCP tunnels get a dedicated public port
+	if tkn.Type != token.TokenTypeTCP && tunnel.Port != 0 {
+		return "--port is only supported with tcp tokens.", fmt.Errorf("--port is only supported with tcp tokens")
+	}
+
+	if tunnel.Port < 0 || tunnel.Port > 65535 {
+		return "--port must be between 1 and 65535.", fmt.Errorf("invalid --port value: %d", tunnel.Port)
+	}
+
+	if tunnel.BasicAuth != "" && !strings.Contains(tunnel.BasicAuth, ":") {
+		return "--basic-auth must be in the 'user:pass' format.", fmt.Errorf("invalid --basic-auth value: expected 'user:pass' format")
+	}
+
+	return "", nil
+}
+
+// parseRoutes parses the --route flags, in the '/prefix=host:port' format, into the routes of a tunnel.
+// stripPrefix sets whether the prefix is removed from the path of the requests of every route.
+func parseRoutes(flags []string, stripPrefix bool) ([]routeConfig, error) {
+	routes := make([]routeConfig, 0, len(flags))
+
+	for _, f := range flags {
+		path, expose, ok := strings.Cut(f, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid --route value: %s", f)
+		}
+
+		routes = append(routes, routeConfig{Path: path, Expose: expose, StripPrefix: stripPrefix})
+	}
+
+	return routes, nil
+}
+
+// revclientRoutes converts the routes of a tunnel to the routes of its revclient.Config.
+func revclientRoutes(routes []routeConfig) []revclient.Route {
+	if len(routes) == 0 {
+		return nil
+	}
+
+	res := make([]revclient.Route, 0, len(routes))
+
+	for _, r := range routes {
+		res = append(res, revclient.Route{Path: r.Path, DestAddr: r.Expose, StripPrefix: r.StripPrefix})
+	}
+
+	return res
+}
+
+// forwardingTo describes the local services of a tunnel exposing expose and routes for the banner.
+func forwardingTo(expose string, routes []routeConfig) string {
+	targets := make([]string, 0, len(routes)+1)
+
+	for _, r := range routes {
+		targets = append(targets, r.Path+" -> "+r.Expose)
+	}
+
+	if expose != "" {
+		if len(routes) == 0 {
+			return expose
+		}
+
+		targets = append(targets, "/ -> "+expose)
+	}
+
+	return strings.Join(targets, ", ")
+}
+
+// headerRules parses the header rules of tunnel, with headers to set in the 'Name:Value' format.
+// Returns an error if a header to set is not in this format or a header name is empty.
+func headerRules(tunnel tunnelConfig) (revclient.HeaderRules, error) {
+	rules := revclient.HeaderRules{
+		Host:           tunnel.HostHeader,
+		RemoveRequest:  tunnel.RemoveRequestHeaders,
+		RemoveResponse: tunnel.RemoveResponseHeaders,
+	}
+
+	var err error
+
+	if rules.SetRequest, err = parseHeaders(tunnel.RequestHeaders); err != nil {
+		return revclient.HeaderRules{}, fmt.Errorf("invalid --request-header value: %w", err)
+	}
+
+	if rules.SetResponse, err = parseHeaders(tunnel.ResponseHeaders); err != nil {
+		return revclient.HeaderRules{}, fmt.Errorf("invalid --response-header value: %w", err)
+	}
+
+	for _, name := range slices.Concat(rules.RemoveRequest, rules.RemoveResponse) {
+		if strings.TrimSpace(name) == "" {
+			return revclient.HeaderRules{}, fmt.Errorf("empty header name to remove")
+		}
+	}
+
+	return rules, nil
+}
+
+// parseHeaders parses headers in the 'Name:Value' format. Returns nil if headers is empty.
+func parseHeaders(headers []string) (http.Header, error) {
+	if len(headers) == 0 {
+		return nil, nil
+	}
+
+	res := make(http.Header, len(headers))
+
+	for _, h := range headers {
+		name, value, ok := strings.Cut(h, ":")
+		name = strings.TrimSpace(name)
+
+		if !ok || name == "" {
+			return nil, fmt.Errorf("%s (expected 'Name:Value')", h)
+		}
+
+		res.Set(name, strings.TrimSpace(value))
+	}
+
+	return res, nil
+}
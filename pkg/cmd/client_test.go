@@ -2,16 +2,23 @@ package cmd
 
 import (
 	"context"
+	"net/http"
+	"path/filepath"
 	"testing"
 
+	"github.com/ksysoev/make-it-public/pkg/revclient"
+
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
 func TestRunClientCommand(t *testing.T) {
-	testToken := "dGVzdDp0ZXN0"                // #nosec G101 -- base64("test:test"), old-format web token for tests
-	tcpToken := "dGVzdGtleS10OnRlc3RzZWNyZXQ=" // #nosec G101 -- base64("testkey-t:testsecret"), TCP token for tests
-	webToken := "dGVzdGtleS13OnRlc3RzZWNyZXQ=" // #nosec G101 -- base64("testkey-w:testsecret"), web token for tests
+	testToken := "dGVzdDp0ZXN0"                 // #nosec G101 -- base64("test:test"), old-format web token for tests
+	tcpToken := "dGVzdGtleS10OnRlc3RzZWNyZXQ="  // #nosec G101 -- base64("testkey-t:testsecret"), TCP token for tests
+	webToken := "dGVzdGtleS13OnRlc3RzZWNyZXQ="  // #nosec G101 -- base64("testkey-w:testsecret"), web token for tests
+	grpcToken := "dGVzdGtleS1nOnRlc3RzZWNyZXQ=" // #nosec G101 -- base64("testkey-g:testsecret"), gRPC token for tests
+	udpToken := "dGVzdGtleS11OnRlc3RzZWNyZXQ="  // #nosec G101 -- base64("testkey-u:testsecret"), UDP token for tests
+	tlsToken := "dGVzdGtleS1zOnRlc3RzZWNyZXQ="  // #nosec G101 -- base64("testkey-s:testsecret"), TLS token for tests
 
 	tests := []struct {
 		name    string
@@ -120,6 +127,331 @@ func TestRunClientCommand(t *testing.T) {
 			},
 			wantErr: "--dummy and --echo-ws are only supported with web tokens",
 		},
+		{
+			name: "TCP token with --inspect flag is rejected",
+			args: args{
+				Token:    tcpToken,
+				Server:   "test-server:8080",
+				Expose:   "localhost:5432",
+				Inspect:  "localhost:4040",
+				LogLevel: "info",
+			},
+			wantErr: "--inspect is only supported with web tokens",
+		},
+		{
+			name: "TCP token with --h2c flag is rejected",
+			args: args{
+				Token:    tcpToken,
+				Server:   "test-server:8080",
+				Expose:   "localhost:5432",
+				H2C:      true,
+				LogLevel: "info",
+			},
+			wantErr: "--h2c is only supported with web tokens",
+		},
+		{
+			name: "gRPC token with --h2c flag is rejected",
+			args: args{
+				Token:    grpcToken,
+				Server:   "test-server:8080",
+				Expose:   "localhost:50051",
+				H2C:      true,
+				LogLevel: "info",
+			},
+			wantErr: "--h2c is only supported with web tokens",
+		},
+		{
+			name: "gRPC token with --dummy flag is rejected",
+			args: args{
+				Token:       grpcToken,
+				Server:      "test-server:8080",
+				LocalServer: true,
+				LogLevel:    "info",
+			},
+			wantErr: "--dummy and --echo-ws are only supported with web tokens",
+		},
+		{
+			name: "UDP token with --dummy flag is rejected",
+			args: args{
+				Token:       udpToken,
+				Server:      "test-server:8080",
+				LocalServer: true,
+				LogLevel:    "info",
+			},
+			wantErr: "--dummy and --echo-ws are only supported with web tokens",
+		},
+		{
+			name: "TLS token with --inspect flag is rejected",
+			args: args{
+				Token:    tlsToken,
+				Server:   "test-server:8080",
+				Expose:   "localhost:8443",
+				Inspect:  "localhost:4040",
+				LogLevel: "info",
+			},
+			wantErr: "--inspect is only supported with web tokens",
+		},
+		{
+			name: "UDP token with --port flag is rejected",
+			args: args{
+				Token:    udpToken,
+				Server:   "test-server:8080",
+				Expose:   "localhost:53",
+				Port:     20000,
+				LogLevel: "info",
+			},
+			wantErr: "--port is only supported with tcp tokens",
+		},
+		{
+			name: "TCP token with --basic-auth flag is rejected",
+			args: args{
+				Token:     tcpToken,
+				Server:    "test-server:8080",
+				Expose:    "localhost:5432",
+				BasicAuth: "user:pass",
+				LogLevel:  "info",
+			},
+			wantErr: "--basic-auth and --bearer-token are only supported with web tokens",
+		},
+		{
+			name: "web token with --port flag is rejected",
+			args: args{
+				Token:    webToken,
+				Server:   "test-server:8080",
+				Expose:   "localhost:8080",
+				Port:     30000,
+				LogLevel: "info",
+			},
+			wantErr: "--port is only supported with tcp tokens",
+		},
+		{
+			name: "invalid --port value",
+			args: args{
+				Token:    tcpToken,
+				Server:   "test-server:8080",
+				Expose:   "localhost:5432",
+				Port:     70000,
+				LogLevel: "info",
+			},
+			wantErr: "invalid --port value: 70000",
+		},
+		{
+			name: "invalid --basic-auth format",
+			args: args{
+				Token:     webToken,
+				Server:    "test-server:8080",
+				Expose:    "localhost:8080",
+				BasicAuth: "user-without-password",
+				LogLevel:  "info",
+			},
+			wantErr: "invalid --basic-auth value: expected 'user:pass' format",
+		},
+		{
+			name: "TCP token with --route flag is rejected",
+			args: args{
+				Token:    tcpToken,
+				Server:   "test-server:8080",
+				Expose:   "localhost:5432",
+				Routes:   []string{"/api=localhost:8080"},
+				LogLevel: "info",
+			},
+			wantErr: "--route is only supported with web tokens",
+		},
+		{
+			name: "invalid --route format",
+			args: args{
+				Token:    webToken,
+				Server:   "test-server:8080",
+				Routes:   []string{"/api"},
+				LogLevel: "info",
+			},
+			wantErr: "invalid --route value: /api",
+		},
+		{
+			name: "--route without leading slash is rejected",
+			args: args{
+				Token:    webToken,
+				Server:   "test-server:8080",
+				Routes:   []string{"api=localhost:8080"},
+				LogLevel: "info",
+			},
+			wantErr: "invalid --route value: api=localhost:8080",
+		},
+		{
+			name: "--inspect with --route is rejected",
+			args: args{
+				Token:    webToken,
+				Server:   "test-server:8080",
+				Routes:   []string{"/api=localhost:8080"},
+				Inspect:  "localhost:4040",
+				LogLevel: "info",
+			},
+			wantErr: "--inspect cannot be combined with --route",
+		},
+		{
+			name: "web token with --route and no --expose is allowed",
+			args: args{
+				Token:       webToken,
+				Server:      "test-server:8080",
+				Routes:      []string{"/api=localhost:8080", "/=localhost:3000"},
+				StripPrefix: true,
+				LogLevel:    "info",
+			},
+			wantErr: "lookup test-server",
+		},
+		{
+			name: "TCP token with --host-header flag is rejected",
+			args: args{
+				Token:      tcpToken,
+				Server:     "test-server:8080",
+				Expose:     "localhost:5432",
+				HostHeader: "localhost",
+				LogLevel:   "info",
+			},
+			wantErr: "--host-header and header rules are only supported with web tokens",
+		},
+		{
+			name: "invalid --request-header format",
+			args: args{
+				Token:      webToken,
+				Server:     "test-server:8080",
+				Expose:     "localhost:8080",
+				ReqHeaders: []string{"X-Forwarded-Proto"},
+				LogLevel:   "info",
+			},
+			wantErr: "invalid --request-header value: X-Forwarded-Proto (expected 'Name:Value')",
+		},
+		{
+			name: "web token with header rules is allowed",
+			args: args{
+				Token:         webToken,
+				Server:        "test-server:8080",
+				Expose:        "localhost:8080",
+				HostHeader:    "localhost:8080",
+				ReqHeaders:    []string{"X-Forwarded-Proto: https"},
+				RmRespHeaders: []string{"Server"},
+				LogLevel:      "info",
+			},
+			wantErr: "lookup test-server",
+		},
+		{
+			name: "invalid --expose value",
+			args: args{
+				Token:    webToken,
+				Server:   "test-server:8080",
+				Expose:   "ftp://localhost:21",
+				LogLevel: "info",
+			},
+			wantErr: "invalid --expose value",
+		},
+		{
+			name: "UDP token with URL --expose is rejected",
+			args: args{
+				Token:    udpToken,
+				Server:   "test-server:8080",
+				Expose:   "http://localhost:53",
+				LogLevel: "info",
+			},
+			wantErr: "invalid --expose value for udp token",
+		},
+		{
+			name: "TLS token with https:// service is rejected",
+			args: args{
+				Token:    tlsToken,
+				Server:   "test-server:8080",
+				Expose:   "https://localhost:8443",
+				LogLevel: "info",
+			},
+			wantErr: "https:// services are not supported with tls tokens",
+		},
+		{
+			name: "--h2c with https:// service is rejected",
+			args: args{
+				Token:    webToken,
+				Server:   "test-server:8080",
+				Expose:   "https://localhost:8443",
+				H2C:      true,
+				LogLevel: "info",
+			},
+			wantErr: "--h2c cannot be combined with an https:// service",
+		},
+		{
+			name: "upstream TLS options without https:// service are rejected",
+			args: args{
+				Token:              webToken,
+				Server:             "test-server:8080",
+				Expose:             "localhost:8080",
+				UpstreamSkipVerify: true,
+				LogLevel:           "info",
+			},
+			wantErr: "--upstream-* options require an https:// service",
+		},
+		{
+			name: "missing upstream CA file",
+			args: args{
+				Token:      webToken,
+				Server:     "test-server:8080",
+				Expose:     "https://localhost:8443",
+				UpstreamCA: "/nonexistent/ca.pem",
+				LogLevel:   "info",
+			},
+			wantErr: "failed to read upstream CA",
+		},
+		{
+			name: "UDP token with unix:// service is rejected",
+			args: args{
+				Token:    udpToken,
+				Server:   "test-server:8080",
+				Expose:   "unix:///var/run/app.sock",
+				LogLevel: "info",
+			},
+			wantErr: "invalid --expose value for udp token",
+		},
+		{
+			name: "TCP token with unix:// service is allowed",
+			args: args{
+				Token:    tcpToken,
+				Server:   "test-server:8080",
+				Expose:   "unix:///var/run/postgresql/.s.PGSQL.5432",
+				LogLevel: "info",
+			},
+			wantErr: "lookup test-server",
+		},
+		{
+			name: "--dummy listening on the unix:// --expose address",
+			args: args{
+				Token:       webToken,
+				Server:      "test-server:8080",
+				Expose:      "unix://" + filepath.Join(t.TempDir(), "dummy.sock"),
+				LocalServer: true,
+				LogLevel:    "info",
+				Status:      200,
+			},
+			wantErr: "lookup test-server",
+		},
+		{
+			name: "--echo-ws listening on an https:// address is rejected",
+			args: args{
+				Token:    webToken,
+				Server:   "test-server:8080",
+				Expose:   "https://localhost:8443",
+				EchoWS:   true,
+				LogLevel: "info",
+			},
+			wantErr: "failed to create websocket echo server: invalid listen address https://localhost:8443: TLS is not supported",
+		},
+		{
+			name: "web token with https:// service is allowed",
+			args: args{
+				Token:              webToken,
+				Server:             "test-server:8080",
+				Expose:             "https://localhost:8443",
+				UpstreamSNI:        "api.internal",
+				UpstreamSkipVerify: true,
+				LogLevel:           "info",
+			},
+			wantErr: "lookup test-server",
+		},
 		{
 			name: "TCP token with --echo-ws flag is rejected",
 			args: args{
@@ -164,3 +496,40 @@ func TestRunClientCommand(t *testing.T) {
 		})
 	}
 }
+
+func TestForwardingTo(t *testing.T) {
+	routes := []routeConfig{{Path: "/api", Expose: "localhost:8080"}}
+
+	assert.Equal(t, "localhost:3000", forwardingTo("localhost:3000", nil))
+	assert.Equal(t, "/api -> localhost:8080", forwardingTo("", routes))
+	assert.Equal(t, "/api -> localhost:8080, / -> localhost:3000", forwardingTo("localhost:3000", routes))
+}
+
+func TestHeaderRules(t *testing.T) {
+	rules, err := headerRules(tunnelConfig{
+		HostHeader:            "localhost:3000",
+		RequestHeaders:        []string{"x-forwarded-proto: https", "X-Empty:"},
+		RemoveRequestHeaders:  []string{"Cookie"},
+		ResponseHeaders:       []string{"X-Frame-Options:SAMEORIGIN"},
+		RemoveResponseHeaders: []string{"Server"},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, revclient.HeaderRules{
+		Host:           "localhost:3000",
+		SetRequest:     http.Header{"X-Forwarded-Proto": {"https"}, "X-Empty": {""}},
+		RemoveRequest:  []string{"Cookie"},
+		SetResponse:    http.Header{"X-Frame-Options": {"SAMEORIGIN"}},
+		RemoveResponse: []string{"Server"},
+	}, rules)
+
+	rules, err = headerRules(tunnelConfig{})
+	require.NoError(t, err)
+	assert.True(t, rules.IsZero())
+
+	_, err = headerRules(tunnelConfig{ResponseHeaders: []string{":value"}})
+	assert.ErrorContains(t, err, "invalid --response-header value")
+
+	_, err = headerRules(tunnelConfig{RemoveRequestHeaders: []string{" "}})
+	assert.ErrorContains(t, err, "empty header name to remove")
+}
@@ -6,19 +6,34 @@ import (
 	"strings"
 
 	"github.com/ksysoev/make-it-public/pkg/api"
+	"github.com/ksysoev/make-it-public/pkg/cluster"
+	"github.com/ksysoev/make-it-public/pkg/core"
 	"github.com/ksysoev/make-it-public/pkg/edge"
+	"github.com/ksysoev/make-it-public/pkg/grpcedge"
+	"github.com/ksysoev/make-it-public/pkg/repo/auditlog"
 	"github.com/ksysoev/make-it-public/pkg/repo/auth"
+	"github.com/ksysoev/make-it-public/pkg/repo/connmng"
 	"github.com/ksysoev/make-it-public/pkg/revproxy"
 	"github.com/ksysoev/make-it-public/pkg/tcpedge"
+	"github.com/ksysoev/make-it-public/pkg/tlsedge"
+	"github.com/ksysoev/make-it-public/pkg/udpedge"
 	"github.com/spf13/viper"
 )
 
 type appConfig struct {
-	Auth     auth.Config     `mapstructure:"auth"`
-	RevProxy revproxy.Config `mapstructure:"reverse_proxy"`
-	API      api.Config      `mapstructure:"api"`
-	TCP      tcpedge.Config  `mapstructure:"tcp"`
-	HTTP     edge.Config     `mapstructure:"http"`
+	Cluster     cluster.Config    `mapstructure:"cluster"`
+	Auth        auth.Config       `mapstructure:"auth"`
+	RevProxy    revproxy.Config   `mapstructure:"reverse_proxy"`
+	ConnManager connmng.Config    `mapstructure:"conn_manager"`
+	API         api.Config        `mapstructure:"api"`
+	GRPC        grpcedge.Config   `mapstructure:"grpc"`
+	Audit       auditlog.Config   `mapstructure:"audit"`
+	TLS         tlsedge.Config    `mapstructure:"tls"`
+	Drain       core.DrainConfig  `mapstructure:"drain"`
+	TCP         tcpedge.Config    `mapstructure:"tcp"`
+	Limits      core.LimitsConfig `mapstructure:"limits"`
+	HTTP        edge.Config       `mapstructure:"http"`
+	UDP         udpedge.Config    `mapstructure:"udp"`
 }
 
 // loadConfig loads the application configuration from the specified file path and environment variables.
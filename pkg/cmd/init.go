@@ -13,23 +13,41 @@ type BuildInfo struct {
 	Version       string
 }
 type args struct {
-	Body        string `mapstructure:"body"`
-	Expose      string `mapstructure:"expose"`
-	Token       string `mapstructure:"token"`
-	ConfigPath  string `mapstructure:"config"`
-	LogLevel    string `mapstructure:"log_level"`
-	Version     string
-	Server      string   `mapstructure:"server"`
-	JSON        string   `mapstructure:"json"`
-	Headers     []string `mapstructure:"headers"`
-	Status      int      `mapstructure:"status"`
-	NoTLS       bool     `mapstructure:"no_tls"`
-	Interactive bool     `mapstructure:"interactive"`
-	LocalServer bool     `mapstructure:"local"`
-	TextFormat  bool     `mapstructure:"log_text"`
-	Insecure    bool     `mapstructure:"insecure"`
-	DisableV2   bool     `mapstructure:"disable_v2"`
-	EchoWS      bool     `mapstructure:"echo_ws"`
+	Body               string `mapstructure:"body"`
+	Expose             string `mapstructure:"expose"`
+	BasicAuth          string `mapstructure:"basic_auth"`
+	BearerToken        string `mapstructure:"bearer_token"` // #nosec G117 -- This is a config field name, not an exposed secret
+	Inspect            string `mapstructure:"inspect"`
+	HostHeader         string `mapstructure:"host_header"`
+	UpstreamSNI        string `mapstructure:"upstream_server_name"`
+	UpstreamCA         string `mapstructure:"upstream_ca"`
+	UpstreamCert       string `mapstructure:"upstream_cert"`
+	UpstreamKey        string `mapstructure:"upstream_key"`
+	Token              string `mapstructure:"token"`
+	ConfigPath         string `mapstructure:"config"`
+	ClientConfig       string `mapstructure:"client_config"`
+	LogLevel           string `mapstructure:"log_level"`
+	Version            string
+	Server             string   `mapstructure:"server"`
+	JSON               string   `mapstructure:"json"`
+	Headers            []string `mapstructure:"headers"`
+	Routes             []string `mapstructure:"routes"`
+	ReqHeaders         []string `mapstructure:"request_headers"`
+	RmReqHeaders       []string `mapstructure:"remove_request_headers"`
+	RespHeaders        []string `mapstructure:"response_headers"`
+	RmRespHeaders      []string `mapstructure:"remove_response_headers"`
+	Status             int      `mapstructure:"status"`
+	Port               int      `mapstructure:"port"`
+	NoTLS              bool     `mapstructure:"no_tls"`
+	Interactive        bool     `mapstructure:"interactive"`
+	LocalServer        bool     `mapstructure:"local"`
+	TextFormat         bool     `mapstructure:"log_text"`
+	Insecure           bool     `mapstructure:"insecure"`
+	DisableV2          bool     `mapstructure:"disable_v2"`
+	EchoWS             bool     `mapstructure:"echo_ws"`
+	StripPrefix        bool     `mapstructure:"strip_prefix"`
+	H2C                bool     `mapstructure:"h2c"`
+	UpstreamSkipVerify bool     `mapstructure:"upstream_insecure"`
 }
 
 // InitCommand initializes the root command of the CLI application with its subcommands and flags.
@@ -54,25 +72,44 @@ func InitCommand(build BuildInfo) cobra.Command {
 	isInteractive := os.Stdout != nil && (os.Stdout.Fd() == 1 || os.Stdout.Fd() == 2) && os.Getenv("TERM") != ""
 
 	cmd.Flags().StringVar(&arg.Server, "server", build.DefaultServer, "server address")
-	cmd.Flags().StringVar(&arg.Expose, "expose", "", "expose service")
+	cmd.Flags().StringVar(&arg.Expose, "expose", "", "expose service (host:port, an http:// or https:// URL, or a unix:// socket path)")
 	cmd.Flags().StringVar(&arg.Token, "token", "", "token")
+	cmd.Flags().StringVar(&arg.BasicAuth, "basic-auth", "", "require HTTP basic auth credentials from visitors (format: 'user:pass')")
+	cmd.Flags().StringVar(&arg.BearerToken, "bearer-token", "", "require a bearer token from visitors in the Authorization header")
+	cmd.Flags().StringVar(&arg.Inspect, "inspect", "", "record HTTP requests and serve the request inspector web UI on the given address (e.g. 'localhost:4040')")
+	cmd.Flags().IntVar(&arg.Port, "port", 0, "preferred public port for TCP tunnels, a random port is used when it is not available")
 	cmd.Flags().BoolVar(&arg.NoTLS, "no-tls", false, "disable TLS")
 	cmd.Flags().BoolVar(&arg.Insecure, "insecure", false, "skip TLS verification")
 	cmd.Flags().BoolVar(&arg.DisableV2, "disable-v2", false, "disable V2 protocol (fallback to V1 for old servers)")
+	cmd.Flags().StringArrayVar(&arg.Routes, "route", []string{}, "send HTTP requests whose path starts with a prefix to another local service (format: '/prefix=host:port'), may be repeated")
+	cmd.Flags().BoolVar(&arg.StripPrefix, "strip-prefix", false, "remove the prefix of the matching --route from the path of requests")
+	cmd.Flags().StringVar(&arg.HostHeader, "host-header", "", "replace the Host header of HTTP requests sent to the exposed service (e.g. 'localhost:3000')")
+	cmd.Flags().StringArrayVar(&arg.ReqHeaders, "request-header", []string{}, "set a header on HTTP requests sent to the exposed service (format: 'Name:Value'), may be repeated")
+	cmd.Flags().StringArrayVar(&arg.RmReqHeaders, "remove-request-header", []string{}, "remove a header from HTTP requests sent to the exposed service, may be repeated")
+	cmd.Flags().StringArrayVar(&arg.RespHeaders, "response-header", []string{}, "set a header on HTTP responses of the exposed service (format: 'Name:Value'), may be repeated")
+	cmd.Flags().StringArrayVar(&arg.RmRespHeaders, "remove-response-header", []string{}, "remove a header from HTTP responses of the exposed service, may be repeated")
+	cmd.Flags().StringVar(&arg.UpstreamSNI, "upstream-server-name", "", "server name sent to and verified for an https:// exposed service, defaults to its host")
+	cmd.Flags().StringVar(&arg.UpstreamCA, "upstream-ca", "", "PEM file of the CA certificates trusted for an https:// exposed service, instead of the system ones")
+	cmd.Flags().StringVar(&arg.UpstreamCert, "upstream-cert", "", "PEM file of the client certificate presented to an https:// exposed service")
+	cmd.Flags().StringVar(&arg.UpstreamKey, "upstream-key", "", "PEM file of the private key of --upstream-cert")
+	cmd.Flags().BoolVar(&arg.UpstreamSkipVerify, "upstream-insecure", false, "skip verification of the certificate of an https:// exposed service")
+	cmd.Flags().BoolVar(&arg.H2C, "h2c", false, "send requests to the exposed service over HTTP/2 without TLS, e.g. for gRPC servers")
 	cmd.Flags().BoolVar(&arg.LocalServer, "dummy", false, "run local dummy web server that will print incoming requests(experimental feature)")
 	cmd.Flags().BoolVar(&arg.EchoWS, "echo-ws", false, "run local WebSocket echo server that echoes incoming messages")
 	cmd.Flags().StringVar(&arg.Body, "body", "", "response to send back to the client by the dummy server")
 	cmd.Flags().StringVar(&arg.JSON, "json", "", "JSON response to send back to the client by the dummy server")
 	cmd.Flags().IntVar(&arg.Status, "status", 200, "HTTP status code to return by the dummy server")
 	cmd.Flags().StringArrayVar(&arg.Headers, "headers", []string{}, "custom HTTP headers to return by the dummy server (format: 'Name:Value')")
+	cmd.Flags().StringVar(&arg.ClientConfig, "config", "", "path to a client config file listing the tunnels to expose, instead of --token and --expose")
 	cmd.Flags().BoolVar(&arg.Interactive, "interactive", isInteractive, "run in interactive mode")
 
 	cmd.PersistentFlags().StringVar(&arg.LogLevel, "log-level", "info", "log level (debug, info, warn, error)")
 	cmd.PersistentFlags().BoolVar(&arg.TextFormat, "log-text", true, "log in text format, otherwise JSON")
 
 	cmd.AddCommand(initServerCommand(&arg))
+	cmd.AddCommand(initReplayCommand())
 
-	for _, name := range []string{"server", "expose", "token", "log_level", "log_text"} {
+	for _, name := range []string{"server", "expose", "token", "basic_auth", "bearer_token", "inspect", "port", "client_config", "log_level", "log_text"} {
 		if err := viper.BindEnv(name); err != nil {
 			slog.Error("failed to bind env var", "name", name, "error", err)
 		}
@@ -87,6 +124,27 @@ func InitCommand(build BuildInfo) cobra.Command {
 	return cmd
 }
 
+// initReplayCommand initializes the "replay" command, which re-sends a request captured by the request
+// inspector of a running client to the local service.
+// Returns a pointer to the initialized cobra.Command.
+func initReplayCommand() *cobra.Command {
+	var inspectAddr string
+
+	cmd := cobra.Command{
+		Use:   "replay <id>",
+		Short: "Replay a captured request",
+		Long:  "Re-send a request captured by the request inspector of a running client (started with --inspect) to the local service.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, cmdArgs []string) error {
+			return RunReplay(cmd.Context(), cmd.OutOrStdout(), inspectAddr, cmdArgs[0])
+		},
+	}
+
+	cmd.Flags().StringVar(&inspectAddr, "inspect", defaultInspectAddr, "address of the request inspector web UI")
+
+	return &cmd
+}
+
 // initServerCommand initializes the "server" command for the CLI application, adding necessary flags and subcommands.
 // It configures the command with options for specifying the configuration file, log level, and log format.
 // Accepts arg of type *args to set up custom behavior and flag bindings.
@@ -159,6 +217,7 @@ func initTokenCommand(arg *args) *cobra.Command {
 	var (
 		keyID     string
 		keyTTL    int
+		port      int
 		tokenType string
 	)
 
@@ -167,13 +226,14 @@ func initTokenCommand(arg *args) *cobra.Command {
 		Short: "Generate a new token",
 		Long:  "Generate a new token for authentication.",
 		RunE: func(cmd *cobra.Command, _ []string) error {
-			return RunGenerateToken(cmd.Context(), arg, keyID, keyTTL, tokenType)
+			return RunGenerateToken(cmd.Context(), arg, keyID, keyTTL, tokenType, port)
 		},
 	}
 
 	cmdGenerateToken.Flags().StringVar(&keyID, "key-id", "", "Key ID for the token")
 	cmdGenerateToken.Flags().IntVar(&keyTTL, "ttl", 1, "Token time to live in hours")
-	cmdGenerateToken.Flags().StringVar(&tokenType, "type", "web", "Token type: 'web' for HTTP tunnels or 'tcp' for TCP tunnels")
+	cmdGenerateToken.Flags().StringVar(&tokenType, "type", "web", "Token type: 'web' for HTTP tunnels, 'tcp' for TCP tunnels, 'grpc' for gRPC tunnels, 'udp' for UDP tunnels or 'tls' for TLS passthrough tunnels")
+	cmdGenerateToken.Flags().IntVar(&port, "port", 0, "Public port reserved for a tcp token")
 
 	cmd.AddCommand(cmdGenerateToken)
 
@@ -14,8 +14,20 @@ func TestInitCommand(t *testing.T) {
 	assert.Contains(t, cmd.Short, "Make It Public")
 	assert.Contains(t, cmd.Long, "Make It Public Reverse Connect Proxy is a tool for exposing local services to the internet.")
 
-	require.Len(t, cmd.Commands(), 1)
-	assert.Equal(t, "server", cmd.Commands()[0].Use)
+	require.Len(t, cmd.Commands(), 2)
+	assert.Equal(t, "replay <id>", cmd.Commands()[0].Use)
+	assert.Equal(t, "server", cmd.Commands()[1].Use)
+}
+
+func TestInitReplayCommand(t *testing.T) {
+	cmd := initReplayCommand()
+
+	assert.Equal(t, "replay <id>", cmd.Use)
+	assert.Contains(t, cmd.Short, "Replay a captured request")
+
+	flag := cmd.Flags().Lookup("inspect")
+	require.NotNil(t, flag)
+	assert.Equal(t, defaultInspectAddr, flag.DefValue)
 }
 
 func TestInitRunCommand(t *testing.T) {
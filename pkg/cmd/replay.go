@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ksysoev/make-it-public/pkg/inspect"
+)
+
+// defaultInspectAddr is the address the replay command expects the request inspector on.
+const defaultInspectAddr = "localhost:4040"
+
+// RunReplay asks the request inspector of a running client to re-send a captured request to the local service.
+// Accepts ctx for managing the lifecycle, out for printing the outcome, inspectAddr as the address of the
+// inspector web UI, and id as the ID of the captured request.
+// Returns an error if the inspector can not be reached or rejects the replay.
+func RunReplay(ctx context.Context, out io.Writer, inspectAddr, id string) error {
+	url := fmt.Sprintf("http://%s/api/requests/%s/replay", inspectAddr, id)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, http.NoBody)
+	if err != nil {
+		return fmt.Errorf("failed to create replay request: %w", err)
+	}
+
+	cl := http.Client{Timeout: 35 * time.Second}
+
+	resp, err := cl.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach request inspector at %s (is the client running with --inspect?): %w", inspectAddr, err)
+	}
+
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			slog.ErrorContext(ctx, "failed to close response body", slog.Any("error", err))
+		}
+	}()
+
+	if resp.StatusCode != http.StatusCreated {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("failed to replay request %s: %s", id, strings.TrimSpace(string(msg)))
+	}
+
+	var ex inspect.Exchange
+	if err := json.NewDecoder(resp.Body).Decode(&ex); err != nil {
+		return fmt.Errorf("failed to decode replay result: %w", err)
+	}
+
+	_, _ = fmt.Fprintf(out, "Replayed %s %s as request %s: %d %s (%s)\n",
+		ex.Request.Method, ex.Request.URL, ex.ID, ex.Response.Status, http.StatusText(ex.Response.Status), ex.Duration)
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ksysoev/make-it-public/pkg/inspect"
+	"github.com/ksysoev/make-it-public/pkg/target"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunReplay(t *testing.T) {
+	local := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer local.Close()
+
+	dest, err := target.Parse(local.URL)
+	require.NoError(t, err)
+
+	insp, err := inspect.New(inspect.Config{Listen: "localhost:0", Target: dest})
+	require.NoError(t, err)
+
+	inspSrv := httptest.NewServer(insp.Handler())
+	defer inspSrv.Close()
+
+	inspAddr := strings.TrimPrefix(inspSrv.URL, "http://")
+
+	var out bytes.Buffer
+
+	err = RunReplay(t.Context(), &out, inspAddr, "1")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "request not found")
+
+	toDest, toSource := insp.Recorder().Intercept(t.Context(), "1.2.3.4")
+	_, _ = toDest.Write([]byte("GET /hook HTTP/1.1\r\nHost: example.com\r\n\r\n"))
+	_, _ = toSource.Write([]byte("HTTP/1.1 204 No Content\r\n\r\n"))
+	_ = toDest.Close()
+	_ = toSource.Close()
+
+	require.Eventually(t, func() bool {
+		out.Reset()
+		return RunReplay(t.Context(), &out, inspAddr, "1") == nil
+	}, time.Second, 10*time.Millisecond)
+
+	assert.Contains(t, out.String(), "Replayed GET /hook as request 2: 200 OK")
+}
+
+func TestRunReplay_InspectorUnavailable(t *testing.T) {
+	err := RunReplay(t.Context(), &bytes.Buffer{}, "127.0.0.1:1", "1")
+	assert.ErrorContains(t, err, "failed to reach request inspector")
+}
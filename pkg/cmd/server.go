@@ -4,14 +4,24 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/ksysoev/make-it-public/pkg/api"
+	"github.com/ksysoev/make-it-public/pkg/cluster"
 	"github.com/ksysoev/make-it-public/pkg/core"
 	"github.com/ksysoev/make-it-public/pkg/edge"
+	"github.com/ksysoev/make-it-public/pkg/grpcedge"
+	"github.com/ksysoev/make-it-public/pkg/repo/auditlog"
 	"github.com/ksysoev/make-it-public/pkg/repo/auth"
 	"github.com/ksysoev/make-it-public/pkg/repo/connmng"
+	"github.com/ksysoev/make-it-public/pkg/repo/quota"
+	"github.com/ksysoev/make-it-public/pkg/repo/ratelimit"
 	"github.com/ksysoev/make-it-public/pkg/revproxy"
 	"github.com/ksysoev/make-it-public/pkg/tcpedge"
+	"github.com/ksysoev/make-it-public/pkg/tlsedge"
+	"github.com/ksysoev/make-it-public/pkg/udpedge"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -28,13 +38,42 @@ func RunServerCommand(ctx context.Context, args *args) error {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
-	authRepo := auth.New(&cfg.Auth)
+	// The Redis client is shared between the auth repository, the cluster registry, the audit log, traffic quotas
+	// and request rate limits.
+	rdb := auth.NewRedisClient(&cfg.Auth)
+	authRepo := auth.NewWithClient(&cfg.Auth, rdb)
+
+	if err := cfg.ConnManager.Validate(); err != nil {
+		return fmt.Errorf("invalid connection manager config: %w", err)
+	}
+
+	if err := cfg.API.Validate(); err != nil {
+		return fmt.Errorf("invalid api config: %w", err)
+	}
+
+	if err := cfg.Limits.Validate(); err != nil {
+		return fmt.Errorf("invalid limits config: %w", err)
+	}
 
 	// Create two separate connection managers for web and TCP connections
-	webConnManager := connmng.New()
-	tcpConnManager := connmng.New()
+	webConnManager := connmng.New(connmng.WithStrategy(cfg.ConnManager.Strategy))
+	tcpConnManager := connmng.New(connmng.WithStrategy(cfg.ConnManager.Strategy))
 
 	connService := core.New(webConnManager, tcpConnManager, authRepo)
+
+	connService.SetLimits(cfg.Limits, quota.New(rdb, cfg.Auth.KeyPrefix))
+
+	if cfg.Audit.Enabled() {
+		auditSink, err := auditlog.New(cfg.Audit, rdb, cfg.Auth.KeyPrefix)
+		if err != nil {
+			return fmt.Errorf("failed to create audit log: %w", err)
+		}
+
+		defer func() { _ = auditSink.Close() }()
+
+		connService.SetAuditSink(auditSink)
+	}
+
 	apiServ := api.New(cfg.API, connService)
 
 	revServ, err := revproxy.New(&cfg.RevProxy, connService)
@@ -47,6 +86,23 @@ func RunServerCommand(ctx context.Context, args *args) error {
 		return fmt.Errorf("failed to create http server: %w", err)
 	}
 
+	if cfg.HTTP.RateLimit.Store == edge.RateLimitStoreRedis && cfg.HTTP.RateLimit.Rate > 0 {
+		httpServ.SetRateLimiter(ratelimit.New(rdb, cfg.Auth.KeyPrefix, cfg.HTTP.RateLimit.Rate, cfg.HTTP.RateLimit.Burst))
+	}
+
+	grpcEnabled := cfg.GRPC.Enabled()
+
+	var grpcServ *grpcedge.GRPCServer
+
+	if grpcEnabled {
+		connService.SetGRPCConnManager(connmng.New(connmng.WithStrategy(cfg.ConnManager.Strategy)))
+
+		grpcServ, err = grpcedge.New(cfg.GRPC, connService)
+		if err != nil {
+			return fmt.Errorf("failed to create gRPC edge server: %w", err)
+		}
+	}
+
 	tcpEnabled := cfg.TCP.PortRange.Min > 0 && cfg.TCP.PortRange.Max > 0
 
 	var tcpServ *tcpedge.TCPServer
@@ -58,6 +114,43 @@ func RunServerCommand(ctx context.Context, args *args) error {
 		}
 	}
 
+	tlsEnabled := cfg.TLS.Enabled()
+
+	var tlsServ *tlsedge.TLSServer
+
+	if tlsEnabled {
+		connService.SetTLSConnManager(connmng.New(connmng.WithStrategy(cfg.ConnManager.Strategy)))
+
+		tlsServ, err = tlsedge.New(cfg.TLS, connService)
+		if err != nil {
+			return fmt.Errorf("failed to create TLS edge server: %w", err)
+		}
+	}
+
+	udpEnabled := cfg.UDP.Enabled()
+
+	var udpServ *udpedge.UDPServer
+
+	if udpEnabled {
+		connService.SetUDPConnManager(connmng.New(connmng.WithStrategy(cfg.ConnManager.Strategy)))
+
+		udpServ, err = udpedge.New(cfg.UDP, connService)
+		if err != nil {
+			return fmt.Errorf("failed to create UDP edge server: %w", err)
+		}
+	}
+
+	clusterEnabled := cfg.Cluster.Enabled()
+
+	var clusterServ *cluster.Server
+
+	if clusterEnabled {
+		clusterServ, err = cluster.New(cfg.Cluster, connService, rdb, cfg.Auth.KeyPrefix)
+		if err != nil {
+			return fmt.Errorf("failed to create cluster server: %w", err)
+		}
+	}
+
 	logAttrs := []any{
 		"http", cfg.HTTP.Listen,
 		"rev", cfg.RevProxy.Listen,
@@ -70,17 +163,94 @@ func RunServerCommand(ctx context.Context, args *args) error {
 		logAttrs = append(logAttrs, "tcp", "disabled")
 	}
 
+	if udpEnabled {
+		logAttrs = append(logAttrs, "udp_port_range", fmt.Sprintf("%d-%d", cfg.UDP.PortRange.Min, cfg.UDP.PortRange.Max))
+	}
+
+	if grpcEnabled {
+		logAttrs = append(logAttrs, "grpc", cfg.GRPC.Listen)
+	}
+
+	if tlsEnabled {
+		logAttrs = append(logAttrs, "tls", cfg.TLS.Listen)
+	}
+
+	if clusterEnabled {
+		logAttrs = append(logAttrs, "cluster", cfg.Cluster.Listen, "advertise", cfg.Cluster.AdvertiseAddr)
+	}
+
+	if cfg.Audit.Enabled() {
+		logAttrs = append(logAttrs, "audit", cfg.Audit.Sink)
+	}
+
 	slog.InfoContext(ctx, "server started", logAttrs...)
 
-	eg, ctx := errgroup.WithContext(ctx)
+	// Servers keep running while the server drains after ctx is done, and are stopped once the drain is over
+	// or when another SIGINT or SIGTERM is received during the drain.
+	runCtx, stop := context.WithCancel(context.WithoutCancel(ctx))
+	defer stop()
 
-	eg.Go(func() error { return revServ.Run(ctx) })
-	eg.Go(func() error { return httpServ.Run(ctx) })
-	eg.Go(func() error { return apiServ.Run(ctx) })
+	eg, runCtx := errgroup.WithContext(runCtx)
+
+	eg.Go(func() error { return revServ.Run(runCtx) })
+	eg.Go(func() error { return httpServ.Run(runCtx) })
+	eg.Go(func() error { return apiServ.Run(runCtx) })
 
 	if tcpEnabled {
-		eg.Go(func() error { return tcpServ.Run(ctx) })
+		eg.Go(func() error { return tcpServ.Run(runCtx) })
+	}
+
+	if udpEnabled {
+		eg.Go(func() error { return udpServ.Run(runCtx) })
 	}
 
+	if grpcEnabled {
+		eg.Go(func() error { return grpcServ.Run(runCtx) })
+	}
+
+	if tlsEnabled {
+		eg.Go(func() error { return tlsServ.Run(runCtx) })
+	}
+
+	if clusterEnabled {
+		eg.Go(func() error { return clusterServ.Run(runCtx) })
+	}
+
+	eg.Go(func() error {
+		select {
+		case <-runCtx.Done():
+			return nil
+		case <-ctx.Done():
+		}
+
+		slog.InfoContext(runCtx, "server is draining")
+
+		sigs := make(chan os.Signal, 1)
+		signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+
+		defer signal.Stop(sigs)
+
+		go stopOnSignal(runCtx, sigs, stop)
+
+		if err := connService.Drain(runCtx, cfg.Drain); err != nil {
+			slog.WarnContext(runCtx, "drain grace period expired", slog.Any("error", err))
+		}
+
+		stop()
+
+		return nil
+	})
+
 	return eg.Wait()
 }
+
+// stopOnSignal calls stop when a signal is received from sigs before ctx is done,
+// so that a second SIGINT or SIGTERM cuts the drain short.
+func stopOnSignal(ctx context.Context, sigs <-chan os.Signal, stop context.CancelFunc) {
+	select {
+	case sig := <-sigs:
+		slog.WarnContext(ctx, "stopping without waiting for the drain", slog.String("signal", sig.String()))
+		stop()
+	case <-ctx.Done():
+	}
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStopOnSignal(t *testing.T) {
+	t.Run("stops on signal", func(t *testing.T) {
+		ctx, stop := context.WithCancel(context.Background())
+		defer stop()
+
+		sigs := make(chan os.Signal, 1)
+		sigs <- syscall.SIGTERM
+
+		stopOnSignal(ctx, sigs, stop)
+
+		assert.ErrorIs(t, ctx.Err(), context.Canceled)
+	})
+
+	t.Run("returns when context is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		stopped := false
+
+		done := make(chan struct{})
+
+		go func() {
+			stopOnSignal(ctx, make(chan os.Signal), func() { stopped = true })
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("stopOnSignal did not return")
+		}
+
+		assert.False(t, stopped)
+	})
+}
@@ -22,8 +22,9 @@ const (
 // keyID is the unique identifier for the token being generated.
 // keyTTL specifies the token's time to live in hours; it must be greater than 0.
 // tokenTypeStr specifies the token type: "web" or "tcp".
+// port is the public port reserved for a tcp token, or 0 for none.
 // Returns an error if any step in initialization, configuration loading, or token generation fails.
-func RunGenerateToken(ctx context.Context, args *args, keyID string, keyTTL int, tokenTypeStr string) error {
+func RunGenerateToken(ctx context.Context, args *args, keyID string, keyTTL int, tokenTypeStr string, port int) error {
 	if keyTTL < 1 {
 		return fmt.Errorf("key TTL must be greater than 0")
 	}
@@ -36,8 +37,14 @@ func RunGenerateToken(ctx context.Context, args *args, keyID string, keyTTL int,
 		tokenType = token.TokenTypeWeb
 	case "tcp":
 		tokenType = token.TokenTypeTCP
+	case "grpc":
+		tokenType = token.TokenTypeGRPC
+	case "udp":
+		tokenType = token.TokenTypeUDP
+	case "tls":
+		tokenType = token.TokenTypeTLS
 	default:
-		return fmt.Errorf("invalid token type: must be 'web' or 'tcp'")
+		return fmt.Errorf("invalid token type: must be 'web', 'tcp', 'grpc', 'udp' or 'tls'")
 	}
 
 	if err := initLogger(args); err != nil {
@@ -53,7 +60,7 @@ func RunGenerateToken(ctx context.Context, args *args, keyID string, keyTTL int,
 	// Pass nil for connection managers since token generation doesn't need them
 	svc := core.New(nil, nil, authRepo)
 
-	tok, err := svc.GenerateToken(ctx, keyID, keyTTL*secondsInHour, tokenType)
+	tok, err := svc.GenerateToken(ctx, keyID, keyTTL*secondsInHour, tokenType, nil, port)
 	if err != nil {
 		return fmt.Errorf("failed to generate token: %w", err)
 	}
@@ -61,6 +68,11 @@ func RunGenerateToken(ctx context.Context, args *args, keyID string, keyTTL int,
 	fmt.Println("Key ID:", tok.ID)
 	fmt.Println("Token:", tok.Encode())
 	fmt.Println("Type:", tok.Type.String())
+
+	if tok.Port > 0 {
+		fmt.Println("Port:", tok.Port)
+	}
+
 	fmt.Println("Valid until:", time.Now().Add(tok.TTL).Format(time.RFC3339))
 
 	return nil
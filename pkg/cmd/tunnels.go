@@ -0,0 +1,262 @@
+package cmd
+
+import (
+	"cmp"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+
+	"github.com/ksysoev/make-it-public/pkg/core/conn/meta"
+	"github.com/ksysoev/make-it-public/pkg/core/token"
+	"github.com/ksysoev/make-it-public/pkg/display"
+	"github.com/ksysoev/make-it-public/pkg/revclient"
+	"github.com/spf13/viper"
+)
+
+// clientConfig is the client config file, which lists the tunnels exposed by a single client.
+// Server settings left empty in the file are taken from the command line flags.
+type clientConfig struct {
+	Server    string         `mapstructure:"server"`
+	Tunnels   []tunnelConfig `mapstructure:"tunnels"`
+	NoTLS     bool           `mapstructure:"no_tls"`
+	Insecure  bool           `mapstructure:"insecure"`
+	DisableV2 bool           `mapstructure:"disable_v2"`
+}
+
+// tunnelConfig describes a tunnel exposed by the client: the token it is authorized with,
+// the local service it forwards to and its options.
+type tunnelConfig struct {
+	Name                  string        `mapstructure:"name"`
+	Token                 string        `mapstructure:"token"`
+	Expose                string        `mapstructure:"expose"`
+	BasicAuth             string        `mapstructure:"basic_auth"`
+	BearerToken           string        `mapstructure:"bearer_token"` // #nosec G117 -- This is a config field name, not an exposed secret
+	HostHeader            string        `mapstructure:"host_header"`
+	UpstreamServerName    string        `mapstructure:"upstream_server_name"`
+	UpstreamCA            string        `mapstructure:"upstream_ca"`
+	UpstreamCert          string        `mapstructure:"upstream_cert"`
+	UpstreamKey           string        `mapstructure:"upstream_key"`
+	Routes                []routeConfig `mapstructure:"routes"`
+	RequestHeaders        []string      `mapstructure:"request_headers"`
+	RemoveRequestHeaders  []string      `mapstructure:"remove_request_headers"`
+	ResponseHeaders       []string      `mapstructure:"response_headers"`
+	RemoveResponseHeaders []string      `mapstructure:"remove_response_headers"`
+	Port                  int           `mapstructure:"port"`
+	H2C                   bool          `mapstructure:"h2c"`
+	UpstreamInsecure      bool          `mapstructure:"upstream_insecure"`
+}
+
+// hasHeaderRules reports whether the tunnel rewrites the headers of requests or responses.
+func (t *tunnelConfig) hasHeaderRules() bool {
+	return t.HostHeader != "" || len(t.RequestHeaders) > 0 || len(t.RemoveRequestHeaders) > 0 ||
+		len(t.ResponseHeaders) > 0 || len(t.RemoveResponseHeaders) > 0
+}
+
+// routeConfig sends the HTTP requests of a web tunnel whose path starts with Path to the local service at Expose.
+// With StripPrefix, Path is removed from the path of the requests.
+type routeConfig struct {
+	Path        string `mapstructure:"path"`
+	Expose      string `mapstructure:"expose"`
+	StripPrefix bool   `mapstructure:"strip_prefix"`
+}
+
+// tunnel is a tunnel of the client config file, ready to be connected.
+type tunnel struct {
+	token    *token.Token
+	upstream *tls.Config
+	headers  revclient.HeaderRules
+	tunnelConfig
+}
+
+// loadClientConfig reads the client config file at path.
+// Returns an error if the file cannot be read or parsed, or if it lists no tunnels.
+func loadClientConfig(path string) (*clientConfig, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read client config: %w", err)
+	}
+
+	var cfg clientConfig
+
+	if err := v.Unmarshal(&cfg); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal client config: %w", err)
+	}
+
+	if len(cfg.Tunnels) == 0 {
+		return nil, fmt.Errorf("no tunnels in client config")
+	}
+
+	return &cfg, nil
+}
+
+// prepareTunnels decodes the tokens of the tunnels of cfg and checks their options.
+// Tunnels without a name are named after the key ID of their token.
+// Returns an error describing the first invalid tunnel, and the hint shown to the user.
+func prepareTunnels(cfg *clientConfig) ([]tunnel, string, error) {
+	tunnels := make([]tunnel, 0, len(cfg.Tunnels))
+	names := make(map[string]struct{}, len(cfg.Tunnels))
+
+	for i, tc := range cfg.Tunnels {
+		tkn, err := token.Decode(tc.Token)
+		if err != nil {
+			return nil, "Check the token of the tunnel in the client config.", fmt.Errorf("invalid token of tunnel %d: %w", i+1, err)
+		}
+
+		if tc.Name == "" {
+			tc.Name = tkn.ID
+		}
+
+		if _, ok := names[tc.Name]; ok {
+			return nil, "Give each tunnel a unique name.", fmt.Errorf("duplicate tunnel name %q", tc.Name)
+		}
+
+		names[tc.Name] = struct{}{}
+
+		if tc.Expose == "" && len(tc.Routes) == 0 {
+			return nil, "Set the address of the local service in the expose field or the routes of the tunnel.",
+				fmt.Errorf("tunnel %q: no service to expose", tc.Name)
+		}
+
+		if hint, err := checkTunnelOptions(tkn, tc); err != nil {
+			return nil, hint, fmt.Errorf("tunnel %q: %w", tc.Name, err)
+		}
+
+		headers, err := headerRules(tc)
+		if err != nil {
+			return nil, "Set headers in the 'Name:Value' format.", fmt.Errorf("tunnel %q: %w", tc.Name, err)
+		}
+
+		upstream, err := upstreamTLS(tc)
+		if err != nil {
+			return nil, "Check the upstream TLS options of the tunnel.", fmt.Errorf("tunnel %q: %w", tc.Name, err)
+		}
+
+		tunnels = append(tunnels, tunnel{tunnelConfig: tc, token: tkn, headers: headers, upstream: upstream})
+	}
+
+	return tunnels, "", nil
+}
+
+// runTunnels exposes the tunnels listed in the client config file of args, each with its own connection
+// to the server, and shows them in a single banner.
+// A tunnel that fails does not affect the others: it is reported, and the client keeps running while
+// any tunnel is running. Returns the errors of the failed tunnels once all tunnels have stopped.
+func runTunnels(ctx context.Context, args *args, disp *display.Display) error {
+	if args.Token != "" || args.Expose != "" || len(args.Routes) > 0 || args.LocalServer || args.EchoWS || args.Inspect != "" {
+		disp.ShowError("Invalid configuration", nil,
+			"--config cannot be combined with --token, --expose, --route, --dummy, --echo-ws or --inspect.\n"+
+				"  List the tunnels to expose in the client config file.")
+
+		return fmt.Errorf("--config cannot be combined with --token, --expose, --route, --dummy, --echo-ws or --inspect")
+	}
+
+	cfg, err := loadClientConfig(args.ClientConfig)
+	if err != nil {
+		disp.ShowError("Invalid client config", err, "")
+		return err
+	}
+
+	tunnels, hint, err := prepareTunnels(cfg)
+	if err != nil {
+		disp.ShowError("Invalid client config", err, hint)
+		return err
+	}
+
+	server := cmp.Or(cfg.Server, args.Server)
+
+	board := &tunnelBoard{
+		disp:    disp,
+		tunnels: make([]display.TunnelInfo, len(tunnels)),
+	}
+
+	for i, t := range tunnels {
+		board.tunnels[i] = display.TunnelInfo{Name: t.Name, LocalAddr: forwardingTo(t.Expose, t.Routes), TokenType: string(t.token.Type)}
+	}
+
+	board.spinner = disp.ShowConnecting(server)
+	if board.spinner != nil {
+		defer board.spinner.Stop()
+	}
+
+	slog.InfoContext(ctx, "mit client started", "server", server, "tunnels", len(tunnels))
+
+	var wg sync.WaitGroup
+
+	errs := make([]error, len(tunnels))
+
+	for i, t := range tunnels {
+		cli := revclient.NewClientServer(revclient.Config{
+			ServerAddr:  server,
+			DestAddr:    t.Expose,
+			Routes:      revclientRoutes(t.Routes),
+			Headers:     t.headers,
+			UpstreamTLS: t.upstream,
+			NoTLS:       args.NoTLS || cfg.NoTLS,
+			Insecure:    args.Insecure || cfg.Insecure,
+			EnableV2:    !args.DisableV2 && !cfg.DisableV2,
+			Auth:        meta.NewTunnelAuth(t.BasicAuth, t.BearerToken),
+			Port:        t.Port,
+			H2C:         t.H2C,
+		}, t.token,
+			revclient.WithOnConnected(func(url string) { board.connected(i, url) }),
+			revclient.WithOnReconnected(func(url string) { board.connected(i, url) }),
+			revclient.WithOnRequest(disp.ShowRequestSeparator),
+		)
+
+		wg.Go(func() {
+			if err := cli.Run(ctx); err != nil {
+				board.failed(i, err)
+
+				errs[i] = fmt.Errorf("tunnel %q: %w", t.Name, err)
+			}
+		})
+	}
+
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// tunnelBoard keeps the state of the tunnels of the client and shows it in a single banner,
+// updated whenever a tunnel connects or fails.
+type tunnelBoard struct {
+	disp    *display.Display
+	spinner *display.Spinner
+	tunnels []display.TunnelInfo
+	mu      sync.Mutex
+}
+
+// connected records that the tunnel at idx is accessible at url.
+func (b *tunnelBoard) connected(idx int, url string) {
+	b.update(idx, func(t *display.TunnelInfo) {
+		t.PublicURL, t.Err = url, nil
+	})
+}
+
+// failed records that the tunnel at idx stopped with err.
+func (b *tunnelBoard) failed(idx int, err error) {
+	b.update(idx, func(t *display.TunnelInfo) {
+		t.PublicURL, t.Err = "", err
+	})
+}
+
+// update applies fn to the tunnel at idx and shows the banner again.
+// The connecting spinner is stopped by the first update, since the banner lists the tunnels still connecting.
+func (b *tunnelBoard) update(idx int, fn func(t *display.TunnelInfo)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.spinner != nil {
+		b.spinner.Stop()
+	}
+
+	fn(&b.tunnels[idx])
+
+	b.disp.ShowTunnels(slices.Clone(b.tunnels), idx)
+}
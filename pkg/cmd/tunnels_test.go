@@ -0,0 +1,222 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	tunnelWebToken = "dGVzdGtleS13OnRlc3RzZWNyZXQ=" // #nosec G101 -- base64("testkey-w:testsecret"), web token for tests
+	tunnelTCPToken = "dGVzdGtleS10OnRlc3RzZWNyZXQ=" // #nosec G101 -- base64("testkey-t:testsecret"), TCP token for tests
+)
+
+func writeClientConfig(t *testing.T, data string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "tunnels.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+
+	return path
+}
+
+func TestLoadClientConfig(t *testing.T) {
+	path := writeClientConfig(t, `
+server: "example.com:8081"
+insecure: true
+tunnels:
+  - name: api
+    token: "`+tunnelWebToken+`"
+    expose: "localhost:8080"
+    basic_auth: "user:pass"
+    host_header: "localhost:8080"
+    request_headers:
+      - "X-Forwarded-Proto: https"
+    remove_response_headers:
+      - Server
+    routes:
+      - path: /admin
+        expose: "localhost:9090"
+        strip_prefix: true
+  - token: "`+tunnelTCPToken+`"
+    expose: "localhost:5432"
+    port: 20000
+`)
+
+	cfg, err := loadClientConfig(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, &clientConfig{
+		Server:   "example.com:8081",
+		Insecure: true,
+		Tunnels: []tunnelConfig{
+			{
+				Name:                  "api",
+				Token:                 tunnelWebToken,
+				Expose:                "localhost:8080",
+				BasicAuth:             "user:pass",
+				HostHeader:            "localhost:8080",
+				RequestHeaders:        []string{"X-Forwarded-Proto: https"},
+				RemoveResponseHeaders: []string{"Server"},
+				Routes:                []routeConfig{{Path: "/admin", Expose: "localhost:9090", StripPrefix: true}},
+			},
+			{Token: tunnelTCPToken, Expose: "localhost:5432", Port: 20000},
+		},
+	}, cfg)
+}
+
+func TestLoadClientConfig_Errors(t *testing.T) {
+	_, err := loadClientConfig(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.ErrorContains(t, err, "failed to read client config")
+
+	_, err = loadClientConfig(writeClientConfig(t, `tunnels: "invalid"`))
+	assert.ErrorContains(t, err, "failed to unmarshal client config")
+
+	_, err = loadClientConfig(writeClientConfig(t, `server: "example.com:8081"`))
+	assert.ErrorContains(t, err, "no tunnels in client config")
+}
+
+func TestPrepareTunnels(t *testing.T) {
+	tunnels, _, err := prepareTunnels(&clientConfig{Tunnels: []tunnelConfig{
+		{Name: "api", Token: tunnelWebToken, Expose: "localhost:8080"},
+		{Token: tunnelTCPToken, Expose: "localhost:5432", Port: 20000},
+		{
+			Name:           "app",
+			Token:          tunnelWebToken,
+			Routes:         []routeConfig{{Path: "/api", Expose: "localhost:8080", StripPrefix: true}},
+			HostHeader:     "localhost:8080",
+			RequestHeaders: []string{"X-Forwarded-Proto: https"},
+		},
+		{Name: "k8s", Token: tunnelWebToken, Expose: "https://localhost:8443", UpstreamInsecure: true},
+	}})
+	require.NoError(t, err)
+	require.Len(t, tunnels, 4)
+
+	assert.Equal(t, "api", tunnels[0].Name)
+	assert.Equal(t, "testkey", tunnels[1].Name, "tunnels without a name are named after their key ID")
+	assert.Equal(t, 20000, tunnels[1].Port)
+	assert.Equal(t, "localhost:8080", tunnels[2].headers.Host)
+	assert.Equal(t, "https", tunnels[2].headers.SetRequest.Get("X-Forwarded-Proto"))
+	assert.Nil(t, tunnels[2].upstream)
+	require.NotNil(t, tunnels[3].upstream)
+	assert.True(t, tunnels[3].upstream.InsecureSkipVerify)
+}
+
+func TestPrepareTunnels_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr string
+		tunnels []tunnelConfig
+	}{
+		{
+			name:    "invalid token",
+			tunnels: []tunnelConfig{{Token: "invalid-token", Expose: "localhost:8080"}},
+			wantErr: "invalid token of tunnel 1",
+		},
+		{
+			name: "duplicate name",
+			tunnels: []tunnelConfig{
+				{Name: "api", Token: tunnelWebToken, Expose: "localhost:8080"},
+				{Name: "api", Token: tunnelTCPToken, Expose: "localhost:5432"},
+			},
+			wantErr: `duplicate tunnel name "api"`,
+		},
+		{
+			name:    "no service to expose",
+			tunnels: []tunnelConfig{{Name: "api", Token: tunnelWebToken}},
+			wantErr: `tunnel "api": no service to expose`,
+		},
+		{
+			name:    "invalid route",
+			tunnels: []tunnelConfig{{Name: "app", Token: tunnelWebToken, Routes: []routeConfig{{Path: "/api"}}}},
+			wantErr: `tunnel "app": invalid --route value`,
+		},
+		{
+			name:    "invalid header",
+			tunnels: []tunnelConfig{{Name: "app", Token: tunnelWebToken, Expose: "localhost:8080", RequestHeaders: []string{"invalid"}}},
+			wantErr: `tunnel "app": invalid --request-header value`,
+		},
+		{
+			name:    "invalid upstream TLS options",
+			tunnels: []tunnelConfig{{Name: "app", Token: tunnelWebToken, Expose: "https://localhost:8443", UpstreamCert: "cert.pem"}},
+			wantErr: `tunnel "app": --upstream-cert and --upstream-key must be set together`,
+		},
+		{
+			name:    "unsupported option",
+			tunnels: []tunnelConfig{{Name: "db", Token: tunnelTCPToken, Expose: "localhost:5432", H2C: true}},
+			wantErr: `tunnel "db": --h2c is only supported with web tokens`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, hint, err := prepareTunnels(&clientConfig{Tunnels: tt.tunnels})
+
+			assert.ErrorContains(t, err, tt.wantErr)
+			assert.NotEmpty(t, hint)
+		})
+	}
+}
+
+func TestRunClientCommand_Config(t *testing.T) {
+	path := writeClientConfig(t, `
+tunnels:
+  - name: api
+    token: "`+tunnelWebToken+`"
+    expose: "localhost:8080"
+  - name: db
+    token: "`+tunnelTCPToken+`"
+    expose: "localhost:5432"
+`)
+
+	t.Run("every tunnel runs until it fails", func(t *testing.T) {
+		err := RunClientCommand(context.Background(), &args{
+			ClientConfig: path,
+			Server:       "test-server",
+			LogLevel:     "info",
+		})
+
+		assert.ErrorContains(t, err, `tunnel "api": failed to split host and port`)
+		assert.ErrorContains(t, err, `tunnel "db": failed to split host and port`)
+	})
+
+	t.Run("server of the config file takes precedence", func(t *testing.T) {
+		err := RunClientCommand(context.Background(), &args{
+			ClientConfig: writeClientConfig(t, `
+server: "test-server:8080"
+tunnels:
+  - token: "`+tunnelWebToken+`"
+    expose: "localhost:8080"
+`),
+			Server:   "other-server",
+			LogLevel: "info",
+		})
+
+		assert.ErrorContains(t, err, "lookup test-server")
+	})
+
+	t.Run("single tunnel flags are rejected", func(t *testing.T) {
+		err := RunClientCommand(context.Background(), &args{
+			ClientConfig: path,
+			Token:        tunnelWebToken,
+			Server:       "test-server:8080",
+			LogLevel:     "info",
+		})
+
+		assert.ErrorContains(t, err, "--config cannot be combined with --token")
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		err := RunClientCommand(context.Background(), &args{
+			ClientConfig: filepath.Join(t.TempDir(), "missing.yaml"),
+			Server:       "test-server:8080",
+			LogLevel:     "info",
+		})
+
+		assert.ErrorContains(t, err, "failed to read client config")
+	})
+}
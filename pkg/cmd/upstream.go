@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// hasUpstreamTLS reports whether the tunnel sets options of the TLS sessions to its https:// local services.
+func (t *tunnelConfig) hasUpstreamTLS() bool {
+	return t.UpstreamServerName != "" || t.UpstreamCA != "" || t.UpstreamCert != "" || t.UpstreamKey != "" || t.UpstreamInsecure
+}
+
+// upstreamTLS builds the TLS config of the sessions to the https:// local services of tunnel from its options,
+// loading the CA certificates and client certificate files. Returns nil if the tunnel sets no options.
+// Returns an error if a file cannot be read or has no valid certificate.
+func upstreamTLS(tunnel tunnelConfig) (*tls.Config, error) {
+	if !tunnel.hasUpstreamTLS() {
+		return nil, nil
+	}
+
+	cfg := &tls.Config{
+		ServerName:         tunnel.UpstreamServerName,
+		InsecureSkipVerify: tunnel.UpstreamInsecure, //nolint:gosec // opt-in for self-signed local services
+		MinVersion:         tls.VersionTLS12,
+	}
+
+	if tunnel.UpstreamCA != "" {
+		pem, err := os.ReadFile(tunnel.UpstreamCA)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read upstream CA: %w", err)
+		}
+
+		cfg.RootCAs = x509.NewCertPool()
+
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in upstream CA %s", tunnel.UpstreamCA)
+		}
+	}
+
+	if tunnel.UpstreamCert != "" || tunnel.UpstreamKey != "" {
+		if tunnel.UpstreamCert == "" || tunnel.UpstreamKey == "" {
+			return nil, fmt.Errorf("--upstream-cert and --upstream-key must be set together")
+		}
+
+		cert, err := tls.LoadX509KeyPair(tunnel.UpstreamCert, tunnel.UpstreamKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load upstream client certificate: %w", err)
+		}
+
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestCert writes a self-signed certificate and its private key as PEM files to dir
+// and returns their paths.
+func writeTestCert(t *testing.T, dir string) (certFile, keyFile string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "mit-test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+
+	return certFile, keyFile
+}
+
+func TestUpstreamTLS(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeTestCert(t, dir)
+
+	cfg, err := upstreamTLS(tunnelConfig{})
+	require.NoError(t, err)
+	assert.Nil(t, cfg)
+
+	cfg, err = upstreamTLS(tunnelConfig{
+		UpstreamServerName: "api.internal",
+		UpstreamCA:         certFile,
+		UpstreamCert:       certFile,
+		UpstreamKey:        keyFile,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "api.internal", cfg.ServerName)
+	assert.False(t, cfg.InsecureSkipVerify)
+	assert.NotNil(t, cfg.RootCAs)
+	assert.Len(t, cfg.Certificates, 1)
+	assert.Equal(t, uint16(tls.VersionTLS12), cfg.MinVersion)
+
+	cfg, err = upstreamTLS(tunnelConfig{UpstreamInsecure: true})
+	require.NoError(t, err)
+	assert.True(t, cfg.InsecureSkipVerify)
+}
+
+func TestUpstreamTLS_Errors(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeTestCert(t, dir)
+
+	_, err := upstreamTLS(tunnelConfig{UpstreamCA: filepath.Join(dir, "missing.pem")})
+	assert.ErrorContains(t, err, "failed to read upstream CA")
+
+	_, err = upstreamTLS(tunnelConfig{UpstreamCA: keyFile})
+	assert.ErrorContains(t, err, "no certificates found in upstream CA")
+
+	_, err = upstreamTLS(tunnelConfig{UpstreamCert: certFile})
+	assert.ErrorContains(t, err, "--upstream-cert and --upstream-key must be set together")
+
+	_, err = upstreamTLS(tunnelConfig{UpstreamCert: keyFile, UpstreamKey: keyFile})
+	assert.ErrorContains(t, err, "failed to load upstream client certificate")
+}
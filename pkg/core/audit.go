@@ -0,0 +1,114 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"time"
+
+	"github.com/ksysoev/make-it-public/pkg/core/audit"
+	"github.com/ksysoev/make-it-public/pkg/core/token"
+)
+
+var ErrAuditDisabled = errors.New("audit log is not enabled")
+
+// AuditSink stores the audit log of tunnel sessions.
+// Write appends a record, and Recent returns up to limit of the latest records of keyID, newest first.
+// Write is called in the path of every connection, so it must not block on storage; slow sinks queue records instead.
+type AuditSink interface {
+	Write(ctx context.Context, rec *audit.Record) error
+	Recent(ctx context.Context, keyID string, limit int) ([]*audit.Record, error)
+}
+
+// SetAuditSink sets the sink that receives the audit log of control and public connections.
+// It is called during initialisation when the audit log is enabled.
+func (s *Service) SetAuditSink(sink AuditSink) {
+	s.auditSink = sink
+}
+
+// RecentSessions returns up to limit of the latest audit records of the token identified by keyID, newest first.
+// Returns ErrAuditDisabled if no audit sink is configured.
+func (s *Service) RecentSessions(ctx context.Context, keyID string, limit int) ([]*audit.Record, error) {
+	recs, err := s.auditSink.Recent(ctx, keyID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+
+	return recs, nil
+}
+
+// connStats counts the bytes of a public connection in each direction.
+// in is the data sent by the end user, and out the data sent back. meter, when set, charges the data
+// to the quota of the keyID as it is piped.
+type connStats struct {
+	meter *quotaMeter
+	in    int64
+	out   int64
+}
+
+// countingConn counts the bytes written to a connection.
+type countingConn struct {
+	net.Conn
+	written *int64
+}
+
+func (c *countingConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	*c.written += int64(n)
+
+	return n, err
+}
+
+// auditPublicConn records a public connection served for keyID, whether it succeeded or not.
+func (s *Service) auditPublicConn(ctx context.Context, keyID string, tokenType token.TokenType, clientIP string, started time.Time, stats *connStats, err error) {
+	rec := &audit.Record{
+		Time:       started,
+		Kind:       audit.KindPublicConn,
+		KeyID:      keyID,
+		TokenType:  tokenType.String(),
+		RemoteIP:   clientIP,
+		Outcome:    audit.OutcomeOK,
+		DurationMS: time.Since(started).Milliseconds(),
+		BytesIn:    stats.in,
+		BytesOut:   stats.out,
+	}
+
+	if err != nil {
+		rec.Outcome = audit.OutcomeFailed
+		rec.Error = err.Error()
+	}
+
+	s.writeAudit(ctx, rec)
+}
+
+// writeAudit writes rec to the audit sink. Failures are logged, so that they never affect the connection.
+func (s *Service) writeAudit(ctx context.Context, rec *audit.Record) {
+	if err := s.auditSink.Write(context.WithoutCancel(ctx), rec); err != nil {
+		slog.ErrorContext(ctx, "failed to write audit record", slog.Any("error", err), slog.String("keyID", rec.KeyID), slog.String("kind", string(rec.Kind)))
+	}
+}
+
+// remoteIP returns the IP address of addr, or an empty string if addr is unknown.
+func remoteIP(addr net.Addr) string {
+	if addr == nil {
+		return ""
+	}
+
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+
+	return host
+}
+
+// noopAuditSink is the default sink used when the audit log is disabled.
+type noopAuditSink struct{}
+
+func (noopAuditSink) Write(_ context.Context, _ *audit.Record) error { return nil }
+
+func (noopAuditSink) Recent(_ context.Context, _ string, _ int) ([]*audit.Record, error) {
+	return nil, ErrAuditDisabled
+}
@@ -0,0 +1,43 @@
+// Package audit describes the records of the audit log of tunnel sessions.
+package audit
+
+import (
+	"time"
+)
+
+// Kind is the kind of event an audit Record describes.
+type Kind string
+
+const (
+	// KindControlOpened is recorded when a client establishes a control connection.
+	KindControlOpened Kind = "control_opened"
+	// KindControlClosed is recorded when a control connection is closed, with how long it was open.
+	KindControlClosed Kind = "control_closed"
+	// KindPublicConn is recorded for every public connection served through a tunnel.
+	KindPublicConn Kind = "public_conn"
+)
+
+// Outcomes of public connections.
+const (
+	OutcomeOK     = "ok"
+	OutcomeFailed = "failed"
+)
+
+// Record is an entry of the audit log.
+// TokenType is the readable name of the token type, e.g. "web" or "tcp".
+// RemoteIP is the address of the MIT client for control connections, and of the end user for public connections.
+// BytesIn counts the bytes sent by the end user to the tunnel, and BytesOut the bytes sent back.
+type Record struct {
+	Time       time.Time `json:"time"`
+	Kind       Kind      `json:"kind"`
+	KeyID      string    `json:"key_id"`
+	TokenType  string    `json:"token_type,omitempty"`
+	ConnID     string    `json:"conn_id,omitempty"`
+	RemoteIP   string    `json:"remote_ip,omitempty"`
+	Protocol   string    `json:"protocol,omitempty"`
+	Outcome    string    `json:"outcome,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMS int64     `json:"duration_ms,omitempty"`
+	BytesIn    int64     `json:"bytes_in,omitempty"`
+	BytesOut   int64     `json:"bytes_out,omitempty"`
+}
@@ -0,0 +1,143 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ksysoev/make-it-public/pkg/core/audit"
+	"github.com/ksysoev/make-it-public/pkg/core/conn"
+	"github.com/ksysoev/make-it-public/pkg/core/conn/meta"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_RecentSessions_Disabled(t *testing.T) {
+	svc := New(nil, nil, nil)
+
+	_, err := svc.RecentSessions(t.Context(), "key1", 10)
+
+	assert.ErrorIs(t, err, ErrAuditDisabled)
+}
+
+func TestService_RecentSessions(t *testing.T) {
+	recs := []*audit.Record{{Kind: audit.KindControlOpened, KeyID: "key1"}}
+
+	sink := NewMockAuditSink(t)
+	sink.EXPECT().Recent(mock.Anything, "key1", 10).Return(recs, nil)
+
+	svc := New(nil, nil, nil)
+	svc.SetAuditSink(sink)
+
+	got, err := svc.RecentSessions(t.Context(), "key1", 10)
+
+	require.NoError(t, err)
+	assert.Equal(t, recs, got)
+}
+
+func TestService_RecentSessions_Error(t *testing.T) {
+	sink := NewMockAuditSink(t)
+	sink.EXPECT().Recent(mock.Anything, "key1", 10).Return(nil, assert.AnError)
+
+	svc := New(nil, nil, nil)
+	svc.SetAuditSink(sink)
+
+	_, err := svc.RecentSessions(t.Context(), "key1", 10)
+
+	assert.ErrorIs(t, err, assert.AnError)
+}
+
+func TestHandleTCPConnection_AuditsFailedConnection(t *testing.T) {
+	tcpConnMng := NewMockConnManager(t)
+	tcpConnMng.EXPECT().RequestConnection(mock.Anything, "test-user").Return(nil, errors.New("connection failed"))
+
+	sink := NewMockAuditSink(t)
+	sink.EXPECT().Write(mock.Anything, mock.MatchedBy(func(rec *audit.Record) bool {
+		return rec.Kind == audit.KindPublicConn &&
+			rec.KeyID == "test-user" &&
+			rec.TokenType == "tcp" &&
+			rec.RemoteIP == "10.0.0.1" &&
+			rec.Outcome == audit.OutcomeFailed &&
+			rec.Error != ""
+	})).Return(nil)
+
+	service := New(NewMockConnManager(t), tcpConnMng, NewMockAuthRepo(t))
+	service.SetAuditSink(sink)
+
+	clientConn := conn.NewMockWithWriteCloser(t)
+	clientConn.EXPECT().RemoteAddr().Return(&net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 9000})
+
+	err := service.HandleTCPConnection(t.Context(), "test-user", clientConn, "10.0.0.1")
+	require.ErrorIs(t, err, ErrFailedToConnect)
+}
+
+func TestHandleTCPConnection_AuditsPipedBytes(t *testing.T) {
+	revServer, revClient := net.Pipe()
+	cliServer, cliClient := net.Pipe()
+
+	mockReq := conn.NewMockRequest(t)
+	mockReq.EXPECT().WaitConn(mock.Anything).Return(&yamuxStreamWrapper{Conn: revServer}, nil)
+	mockReq.EXPECT().ParentContext().Return(context.Background())
+
+	tcpConnMng := NewMockConnManager(t)
+	tcpConnMng.EXPECT().RequestConnection(mock.Anything, "test-user").Return(mockReq, nil)
+
+	recorded := make(chan *audit.Record, 1)
+
+	sink := NewMockAuditSink(t)
+	sink.EXPECT().Write(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, rec *audit.Record) error {
+		recorded <- rec
+		return nil
+	})
+
+	service := New(NewMockConnManager(t), tcpConnMng, NewMockAuthRepo(t))
+	service.SetAuditSink(sink)
+
+	go func() {
+		_ = service.HandleTCPConnection(t.Context(), "test-user", cliServer, "127.0.0.1")
+	}()
+
+	// The client receives the connection metadata first, then the user's data.
+	var connMeta meta.ClientConnMeta
+	require.NoError(t, meta.ReadData(revClient, &connMeta))
+	assert.Equal(t, "127.0.0.1", connMeta.IP)
+
+	go func() {
+		_, _ = cliClient.Write([]byte("ping"))
+		_, _ = io.ReadFull(cliClient, make([]byte, 5))
+		_ = cliClient.Close()
+	}()
+
+	_, err := io.ReadFull(revClient, make([]byte, 4))
+	require.NoError(t, err)
+
+	_, err = revClient.Write([]byte("pong!"))
+	require.NoError(t, err)
+
+	_ = revClient.Close()
+
+	select {
+	case rec := <-recorded:
+		assert.Equal(t, audit.KindPublicConn, rec.Kind)
+		assert.Equal(t, audit.OutcomeOK, rec.Outcome)
+		assert.Equal(t, int64(4), rec.BytesIn)
+		assert.Equal(t, int64(5), rec.BytesOut)
+	case <-time.After(2 * time.Second):
+		t.Fatal("connection was not audited")
+	}
+}
+
+func TestRemoteIP(t *testing.T) {
+	assert.Equal(t, "10.0.0.1", remoteIP(&net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 8081}))
+	assert.Equal(t, "pipe", remoteIP(pipeAddr{}))
+	assert.Empty(t, remoteIP(nil))
+}
+
+type pipeAddr struct{}
+
+func (pipeAddr) Network() string { return "pipe" }
+func (pipeAddr) String() string  { return "pipe" }
@@ -7,8 +7,14 @@ package core
 import (
 	context "context"
 
-	token "github.com/ksysoev/make-it-public/pkg/core/token"
+	domain "github.com/ksysoev/make-it-public/pkg/core/domain"
+	ipfilter "github.com/ksysoev/make-it-public/pkg/core/ipfilter"
+
 	mock "github.com/stretchr/testify/mock"
+
+	time "time"
+
+	token "github.com/ksysoev/make-it-public/pkg/core/token"
 )
 
 // MockAuthRepo is an autogenerated mock type for the AuthRepo type
@@ -24,6 +30,53 @@ func (_m *MockAuthRepo) EXPECT() *MockAuthRepo_Expecter {
 	return &MockAuthRepo_Expecter{mock: &_m.Mock}
 }
 
+// AddDomain provides a mock function with given fields: ctx, d
+func (_m *MockAuthRepo) AddDomain(ctx context.Context, d *domain.Domain) error {
+	ret := _m.Called(ctx, d)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddDomain")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *domain.Domain) error); ok {
+		r0 = rf(ctx, d)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockAuthRepo_AddDomain_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AddDomain'
+type MockAuthRepo_AddDomain_Call struct {
+	*mock.Call
+}
+
+// AddDomain is a helper method to define mock.On call
+//   - ctx context.Context
+//   - d *domain.Domain
+func (_e *MockAuthRepo_Expecter) AddDomain(ctx interface{}, d interface{}) *MockAuthRepo_AddDomain_Call {
+	return &MockAuthRepo_AddDomain_Call{Call: _e.mock.On("AddDomain", ctx, d)}
+}
+
+func (_c *MockAuthRepo_AddDomain_Call) Run(run func(ctx context.Context, d *domain.Domain)) *MockAuthRepo_AddDomain_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*domain.Domain))
+	})
+	return _c
+}
+
+func (_c *MockAuthRepo_AddDomain_Call) Return(_a0 error) *MockAuthRepo_AddDomain_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockAuthRepo_AddDomain_Call) RunAndReturn(run func(context.Context, *domain.Domain) error) *MockAuthRepo_AddDomain_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // CheckHealth provides a mock function with given fields: ctx
 func (_m *MockAuthRepo) CheckHealth(ctx context.Context) error {
 	ret := _m.Called(ctx)
@@ -117,6 +170,183 @@ func (_c *MockAuthRepo_DeleteToken_Call) RunAndReturn(run func(context.Context,
 	return _c
 }
 
+// GetDomain provides a mock function with given fields: ctx, name
+func (_m *MockAuthRepo) GetDomain(ctx context.Context, name string) (*domain.Domain, error) {
+	ret := _m.Called(ctx, name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetDomain")
+	}
+
+	var r0 *domain.Domain
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*domain.Domain, error)); ok {
+		return rf(ctx, name)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *domain.Domain); ok {
+		r0 = rf(ctx, name)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*domain.Domain)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, name)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockAuthRepo_GetDomain_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetDomain'
+type MockAuthRepo_GetDomain_Call struct {
+	*mock.Call
+}
+
+// GetDomain is a helper method to define mock.On call
+//   - ctx context.Context
+//   - name string
+func (_e *MockAuthRepo_Expecter) GetDomain(ctx interface{}, name interface{}) *MockAuthRepo_GetDomain_Call {
+	return &MockAuthRepo_GetDomain_Call{Call: _e.mock.On("GetDomain", ctx, name)}
+}
+
+func (_c *MockAuthRepo_GetDomain_Call) Run(run func(ctx context.Context, name string)) *MockAuthRepo_GetDomain_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *MockAuthRepo_GetDomain_Call) Return(_a0 *domain.Domain, _a1 error) *MockAuthRepo_GetDomain_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockAuthRepo_GetDomain_Call) RunAndReturn(run func(context.Context, string) (*domain.Domain, error)) *MockAuthRepo_GetDomain_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetToken provides a mock function with given fields: ctx, keyID
+func (_m *MockAuthRepo) GetToken(ctx context.Context, keyID string) (*token.Token, error) {
+	ret := _m.Called(ctx, keyID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetToken")
+	}
+
+	var r0 *token.Token
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*token.Token, error)); ok {
+		return rf(ctx, keyID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *token.Token); ok {
+		r0 = rf(ctx, keyID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*token.Token)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, keyID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockAuthRepo_GetToken_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetToken'
+type MockAuthRepo_GetToken_Call struct {
+	*mock.Call
+}
+
+// GetToken is a helper method to define mock.On call
+//   - ctx context.Context
+//   - keyID string
+func (_e *MockAuthRepo_Expecter) GetToken(ctx interface{}, keyID interface{}) *MockAuthRepo_GetToken_Call {
+	return &MockAuthRepo_GetToken_Call{Call: _e.mock.On("GetToken", ctx, keyID)}
+}
+
+func (_c *MockAuthRepo_GetToken_Call) Run(run func(ctx context.Context, keyID string)) *MockAuthRepo_GetToken_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *MockAuthRepo_GetToken_Call) Return(_a0 *token.Token, _a1 error) *MockAuthRepo_GetToken_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockAuthRepo_GetToken_Call) RunAndReturn(run func(context.Context, string) (*token.Token, error)) *MockAuthRepo_GetToken_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// IPFilter provides a mock function with given fields: ctx, keyID
+func (_m *MockAuthRepo) IPFilter(ctx context.Context, keyID string) (*ipfilter.Filter, error) {
+	ret := _m.Called(ctx, keyID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for IPFilter")
+	}
+
+	var r0 *ipfilter.Filter
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*ipfilter.Filter, error)); ok {
+		return rf(ctx, keyID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *ipfilter.Filter); ok {
+		r0 = rf(ctx, keyID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*ipfilter.Filter)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, keyID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockAuthRepo_IPFilter_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'IPFilter'
+type MockAuthRepo_IPFilter_Call struct {
+	*mock.Call
+}
+
+// IPFilter is a helper method to define mock.On call
+//   - ctx context.Context
+//   - keyID string
+func (_e *MockAuthRepo_Expecter) IPFilter(ctx interface{}, keyID interface{}) *MockAuthRepo_IPFilter_Call {
+	return &MockAuthRepo_IPFilter_Call{Call: _e.mock.On("IPFilter", ctx, keyID)}
+}
+
+func (_c *MockAuthRepo_IPFilter_Call) Run(run func(ctx context.Context, keyID string)) *MockAuthRepo_IPFilter_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *MockAuthRepo_IPFilter_Call) Return(_a0 *ipfilter.Filter, _a1 error) *MockAuthRepo_IPFilter_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockAuthRepo_IPFilter_Call) RunAndReturn(run func(context.Context, string) (*ipfilter.Filter, error)) *MockAuthRepo_IPFilter_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // IsKeyExists provides a mock function with given fields: ctx, keyID
 func (_m *MockAuthRepo) IsKeyExists(ctx context.Context, keyID string) (bool, error) {
 	ret := _m.Called(ctx, keyID)
@@ -174,49 +404,635 @@ func (_c *MockAuthRepo_IsKeyExists_Call) RunAndReturn(run func(context.Context,
 	return _c
 }
 
-// SaveToken provides a mock function with given fields: ctx, t
-func (_m *MockAuthRepo) SaveToken(ctx context.Context, t *token.Token) error {
-	ret := _m.Called(ctx, t)
+// ListDomains provides a mock function with given fields: ctx, keyID
+func (_m *MockAuthRepo) ListDomains(ctx context.Context, keyID string) ([]*domain.Domain, error) {
+	ret := _m.Called(ctx, keyID)
 
 	if len(ret) == 0 {
-		panic("no return value specified for SaveToken")
+		panic("no return value specified for ListDomains")
 	}
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, *token.Token) error); ok {
-		r0 = rf(ctx, t)
+	var r0 []*domain.Domain
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]*domain.Domain, error)); ok {
+		return rf(ctx, keyID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []*domain.Domain); ok {
+		r0 = rf(ctx, keyID)
 	} else {
-		r0 = ret.Error(0)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*domain.Domain)
+		}
 	}
 
-	return r0
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, keyID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
-// MockAuthRepo_SaveToken_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SaveToken'
-type MockAuthRepo_SaveToken_Call struct {
+// MockAuthRepo_ListDomains_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListDomains'
+type MockAuthRepo_ListDomains_Call struct {
 	*mock.Call
 }
 
-// SaveToken is a helper method to define mock.On call
+// ListDomains is a helper method to define mock.On call
 //   - ctx context.Context
-//   - t *token.Token
-func (_e *MockAuthRepo_Expecter) SaveToken(ctx interface{}, t interface{}) *MockAuthRepo_SaveToken_Call {
-	return &MockAuthRepo_SaveToken_Call{Call: _e.mock.On("SaveToken", ctx, t)}
+//   - keyID string
+func (_e *MockAuthRepo_Expecter) ListDomains(ctx interface{}, keyID interface{}) *MockAuthRepo_ListDomains_Call {
+	return &MockAuthRepo_ListDomains_Call{Call: _e.mock.On("ListDomains", ctx, keyID)}
 }
 
-func (_c *MockAuthRepo_SaveToken_Call) Run(run func(ctx context.Context, t *token.Token)) *MockAuthRepo_SaveToken_Call {
+func (_c *MockAuthRepo_ListDomains_Call) Run(run func(ctx context.Context, keyID string)) *MockAuthRepo_ListDomains_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(context.Context), args[1].(*token.Token))
+		run(args[0].(context.Context), args[1].(string))
 	})
 	return _c
 }
 
-func (_c *MockAuthRepo_SaveToken_Call) Return(_a0 error) *MockAuthRepo_SaveToken_Call {
-	_c.Call.Return(_a0)
+func (_c *MockAuthRepo_ListDomains_Call) Return(_a0 []*domain.Domain, _a1 error) *MockAuthRepo_ListDomains_Call {
+	_c.Call.Return(_a0, _a1)
 	return _c
 }
 
-func (_c *MockAuthRepo_SaveToken_Call) RunAndReturn(run func(context.Context, *token.Token) error) *MockAuthRepo_SaveToken_Call {
+func (_c *MockAuthRepo_ListDomains_Call) RunAndReturn(run func(context.Context, string) ([]*domain.Domain, error)) *MockAuthRepo_ListDomains_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListTokens provides a mock function with given fields: ctx
+func (_m *MockAuthRepo) ListTokens(ctx context.Context) ([]*token.Token, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListTokens")
+	}
+
+	var r0 []*token.Token
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]*token.Token, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []*token.Token); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*token.Token)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockAuthRepo_ListTokens_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListTokens'
+type MockAuthRepo_ListTokens_Call struct {
+	*mock.Call
+}
+
+// ListTokens is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *MockAuthRepo_Expecter) ListTokens(ctx interface{}) *MockAuthRepo_ListTokens_Call {
+	return &MockAuthRepo_ListTokens_Call{Call: _e.mock.On("ListTokens", ctx)}
+}
+
+func (_c *MockAuthRepo_ListTokens_Call) Run(run func(ctx context.Context)) *MockAuthRepo_ListTokens_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context))
+	})
+	return _c
+}
+
+func (_c *MockAuthRepo_ListTokens_Call) Return(_a0 []*token.Token, _a1 error) *MockAuthRepo_ListTokens_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockAuthRepo_ListTokens_Call) RunAndReturn(run func(context.Context) ([]*token.Token, error)) *MockAuthRepo_ListTokens_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// OfflinePage provides a mock function with given fields: ctx, keyID
+func (_m *MockAuthRepo) OfflinePage(ctx context.Context, keyID string) (string, error) {
+	ret := _m.Called(ctx, keyID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for OfflinePage")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (string, error)); ok {
+		return rf(ctx, keyID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) string); ok {
+		r0 = rf(ctx, keyID)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, keyID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockAuthRepo_OfflinePage_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'OfflinePage'
+type MockAuthRepo_OfflinePage_Call struct {
+	*mock.Call
+}
+
+// OfflinePage is a helper method to define mock.On call
+//   - ctx context.Context
+//   - keyID string
+func (_e *MockAuthRepo_Expecter) OfflinePage(ctx interface{}, keyID interface{}) *MockAuthRepo_OfflinePage_Call {
+	return &MockAuthRepo_OfflinePage_Call{Call: _e.mock.On("OfflinePage", ctx, keyID)}
+}
+
+func (_c *MockAuthRepo_OfflinePage_Call) Run(run func(ctx context.Context, keyID string)) *MockAuthRepo_OfflinePage_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *MockAuthRepo_OfflinePage_Call) Return(_a0 string, _a1 error) *MockAuthRepo_OfflinePage_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockAuthRepo_OfflinePage_Call) RunAndReturn(run func(context.Context, string) (string, error)) *MockAuthRepo_OfflinePage_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// PortOwner provides a mock function with given fields: ctx, port
+func (_m *MockAuthRepo) PortOwner(ctx context.Context, port int) (string, error) {
+	ret := _m.Called(ctx, port)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PortOwner")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) (string, error)); ok {
+		return rf(ctx, port)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) string); ok {
+		r0 = rf(ctx, port)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, port)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockAuthRepo_PortOwner_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'PortOwner'
+type MockAuthRepo_PortOwner_Call struct {
+	*mock.Call
+}
+
+// PortOwner is a helper method to define mock.On call
+//   - ctx context.Context
+//   - port int
+func (_e *MockAuthRepo_Expecter) PortOwner(ctx interface{}, port interface{}) *MockAuthRepo_PortOwner_Call {
+	return &MockAuthRepo_PortOwner_Call{Call: _e.mock.On("PortOwner", ctx, port)}
+}
+
+func (_c *MockAuthRepo_PortOwner_Call) Run(run func(ctx context.Context, port int)) *MockAuthRepo_PortOwner_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int))
+	})
+	return _c
+}
+
+func (_c *MockAuthRepo_PortOwner_Call) Return(_a0 string, _a1 error) *MockAuthRepo_PortOwner_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockAuthRepo_PortOwner_Call) RunAndReturn(run func(context.Context, int) (string, error)) *MockAuthRepo_PortOwner_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RemoveDomain provides a mock function with given fields: ctx, keyID, name
+func (_m *MockAuthRepo) RemoveDomain(ctx context.Context, keyID string, name string) error {
+	ret := _m.Called(ctx, keyID, name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RemoveDomain")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, keyID, name)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockAuthRepo_RemoveDomain_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RemoveDomain'
+type MockAuthRepo_RemoveDomain_Call struct {
+	*mock.Call
+}
+
+// RemoveDomain is a helper method to define mock.On call
+//   - ctx context.Context
+//   - keyID string
+//   - name string
+func (_e *MockAuthRepo_Expecter) RemoveDomain(ctx interface{}, keyID interface{}, name interface{}) *MockAuthRepo_RemoveDomain_Call {
+	return &MockAuthRepo_RemoveDomain_Call{Call: _e.mock.On("RemoveDomain", ctx, keyID, name)}
+}
+
+func (_c *MockAuthRepo_RemoveDomain_Call) Run(run func(ctx context.Context, keyID string, name string)) *MockAuthRepo_RemoveDomain_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *MockAuthRepo_RemoveDomain_Call) Return(_a0 error) *MockAuthRepo_RemoveDomain_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockAuthRepo_RemoveDomain_Call) RunAndReturn(run func(context.Context, string, string) error) *MockAuthRepo_RemoveDomain_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RemoveOfflinePage provides a mock function with given fields: ctx, keyID
+func (_m *MockAuthRepo) RemoveOfflinePage(ctx context.Context, keyID string) error {
+	ret := _m.Called(ctx, keyID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RemoveOfflinePage")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, keyID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockAuthRepo_RemoveOfflinePage_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RemoveOfflinePage'
+type MockAuthRepo_RemoveOfflinePage_Call struct {
+	*mock.Call
+}
+
+// RemoveOfflinePage is a helper method to define mock.On call
+//   - ctx context.Context
+//   - keyID string
+func (_e *MockAuthRepo_Expecter) RemoveOfflinePage(ctx interface{}, keyID interface{}) *MockAuthRepo_RemoveOfflinePage_Call {
+	return &MockAuthRepo_RemoveOfflinePage_Call{Call: _e.mock.On("RemoveOfflinePage", ctx, keyID)}
+}
+
+func (_c *MockAuthRepo_RemoveOfflinePage_Call) Run(run func(ctx context.Context, keyID string)) *MockAuthRepo_RemoveOfflinePage_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *MockAuthRepo_RemoveOfflinePage_Call) Return(_a0 error) *MockAuthRepo_RemoveOfflinePage_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockAuthRepo_RemoveOfflinePage_Call) RunAndReturn(run func(context.Context, string) error) *MockAuthRepo_RemoveOfflinePage_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ReservedPort provides a mock function with given fields: ctx, keyID
+func (_m *MockAuthRepo) ReservedPort(ctx context.Context, keyID string) (int, error) {
+	ret := _m.Called(ctx, keyID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ReservedPort")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (int, error)); ok {
+		return rf(ctx, keyID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) int); ok {
+		r0 = rf(ctx, keyID)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, keyID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockAuthRepo_ReservedPort_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ReservedPort'
+type MockAuthRepo_ReservedPort_Call struct {
+	*mock.Call
+}
+
+// ReservedPort is a helper method to define mock.On call
+//   - ctx context.Context
+//   - keyID string
+func (_e *MockAuthRepo_Expecter) ReservedPort(ctx interface{}, keyID interface{}) *MockAuthRepo_ReservedPort_Call {
+	return &MockAuthRepo_ReservedPort_Call{Call: _e.mock.On("ReservedPort", ctx, keyID)}
+}
+
+func (_c *MockAuthRepo_ReservedPort_Call) Run(run func(ctx context.Context, keyID string)) *MockAuthRepo_ReservedPort_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *MockAuthRepo_ReservedPort_Call) Return(_a0 int, _a1 error) *MockAuthRepo_ReservedPort_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockAuthRepo_ReservedPort_Call) RunAndReturn(run func(context.Context, string) (int, error)) *MockAuthRepo_ReservedPort_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SaveToken provides a mock function with given fields: ctx, t
+func (_m *MockAuthRepo) SaveToken(ctx context.Context, t *token.Token) error {
+	ret := _m.Called(ctx, t)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveToken")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *token.Token) error); ok {
+		r0 = rf(ctx, t)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockAuthRepo_SaveToken_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SaveToken'
+type MockAuthRepo_SaveToken_Call struct {
+	*mock.Call
+}
+
+// SaveToken is a helper method to define mock.On call
+//   - ctx context.Context
+//   - t *token.Token
+func (_e *MockAuthRepo_Expecter) SaveToken(ctx interface{}, t interface{}) *MockAuthRepo_SaveToken_Call {
+	return &MockAuthRepo_SaveToken_Call{Call: _e.mock.On("SaveToken", ctx, t)}
+}
+
+func (_c *MockAuthRepo_SaveToken_Call) Run(run func(ctx context.Context, t *token.Token)) *MockAuthRepo_SaveToken_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*token.Token))
+	})
+	return _c
+}
+
+func (_c *MockAuthRepo_SaveToken_Call) Return(_a0 error) *MockAuthRepo_SaveToken_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockAuthRepo_SaveToken_Call) RunAndReturn(run func(context.Context, *token.Token) error) *MockAuthRepo_SaveToken_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SetOfflinePage provides a mock function with given fields: ctx, keyID, page
+func (_m *MockAuthRepo) SetOfflinePage(ctx context.Context, keyID string, page string) error {
+	ret := _m.Called(ctx, keyID, page)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetOfflinePage")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, keyID, page)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockAuthRepo_SetOfflinePage_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetOfflinePage'
+type MockAuthRepo_SetOfflinePage_Call struct {
+	*mock.Call
+}
+
+// SetOfflinePage is a helper method to define mock.On call
+//   - ctx context.Context
+//   - keyID string
+//   - page string
+func (_e *MockAuthRepo_Expecter) SetOfflinePage(ctx interface{}, keyID interface{}, page interface{}) *MockAuthRepo_SetOfflinePage_Call {
+	return &MockAuthRepo_SetOfflinePage_Call{Call: _e.mock.On("SetOfflinePage", ctx, keyID, page)}
+}
+
+func (_c *MockAuthRepo_SetOfflinePage_Call) Run(run func(ctx context.Context, keyID string, page string)) *MockAuthRepo_SetOfflinePage_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *MockAuthRepo_SetOfflinePage_Call) Return(_a0 error) *MockAuthRepo_SetOfflinePage_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockAuthRepo_SetOfflinePage_Call) RunAndReturn(run func(context.Context, string, string) error) *MockAuthRepo_SetOfflinePage_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// TokenDomain provides a mock function with given fields: ctx, keyID, name
+func (_m *MockAuthRepo) TokenDomain(ctx context.Context, keyID string, name string) (*domain.Domain, error) {
+	ret := _m.Called(ctx, keyID, name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for TokenDomain")
+	}
+
+	var r0 *domain.Domain
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*domain.Domain, error)); ok {
+		return rf(ctx, keyID, name)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *domain.Domain); ok {
+		r0 = rf(ctx, keyID, name)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*domain.Domain)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, keyID, name)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockAuthRepo_TokenDomain_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'TokenDomain'
+type MockAuthRepo_TokenDomain_Call struct {
+	*mock.Call
+}
+
+// TokenDomain is a helper method to define mock.On call
+//   - ctx context.Context
+//   - keyID string
+//   - name string
+func (_e *MockAuthRepo_Expecter) TokenDomain(ctx interface{}, keyID interface{}, name interface{}) *MockAuthRepo_TokenDomain_Call {
+	return &MockAuthRepo_TokenDomain_Call{Call: _e.mock.On("TokenDomain", ctx, keyID, name)}
+}
+
+func (_c *MockAuthRepo_TokenDomain_Call) Run(run func(ctx context.Context, keyID string, name string)) *MockAuthRepo_TokenDomain_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *MockAuthRepo_TokenDomain_Call) Return(_a0 *domain.Domain, _a1 error) *MockAuthRepo_TokenDomain_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockAuthRepo_TokenDomain_Call) RunAndReturn(run func(context.Context, string, string) (*domain.Domain, error)) *MockAuthRepo_TokenDomain_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UpdateDomain provides a mock function with given fields: ctx, d
+func (_m *MockAuthRepo) UpdateDomain(ctx context.Context, d *domain.Domain) error {
+	ret := _m.Called(ctx, d)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateDomain")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *domain.Domain) error); ok {
+		r0 = rf(ctx, d)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockAuthRepo_UpdateDomain_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateDomain'
+type MockAuthRepo_UpdateDomain_Call struct {
+	*mock.Call
+}
+
+// UpdateDomain is a helper method to define mock.On call
+//   - ctx context.Context
+//   - d *domain.Domain
+func (_e *MockAuthRepo_Expecter) UpdateDomain(ctx interface{}, d interface{}) *MockAuthRepo_UpdateDomain_Call {
+	return &MockAuthRepo_UpdateDomain_Call{Call: _e.mock.On("UpdateDomain", ctx, d)}
+}
+
+func (_c *MockAuthRepo_UpdateDomain_Call) Run(run func(ctx context.Context, d *domain.Domain)) *MockAuthRepo_UpdateDomain_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*domain.Domain))
+	})
+	return _c
+}
+
+func (_c *MockAuthRepo_UpdateDomain_Call) Return(_a0 error) *MockAuthRepo_UpdateDomain_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockAuthRepo_UpdateDomain_Call) RunAndReturn(run func(context.Context, *domain.Domain) error) *MockAuthRepo_UpdateDomain_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UpdateTokenTTL provides a mock function with given fields: ctx, keyID, ttl
+func (_m *MockAuthRepo) UpdateTokenTTL(ctx context.Context, keyID string, ttl time.Duration) error {
+	ret := _m.Called(ctx, keyID, ttl)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateTokenTTL")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Duration) error); ok {
+		r0 = rf(ctx, keyID, ttl)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockAuthRepo_UpdateTokenTTL_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateTokenTTL'
+type MockAuthRepo_UpdateTokenTTL_Call struct {
+	*mock.Call
+}
+
+// UpdateTokenTTL is a helper method to define mock.On call
+//   - ctx context.Context
+//   - keyID string
+//   - ttl time.Duration
+func (_e *MockAuthRepo_Expecter) UpdateTokenTTL(ctx interface{}, keyID interface{}, ttl interface{}) *MockAuthRepo_UpdateTokenTTL_Call {
+	return &MockAuthRepo_UpdateTokenTTL_Call{Call: _e.mock.On("UpdateTokenTTL", ctx, keyID, ttl)}
+}
+
+func (_c *MockAuthRepo_UpdateTokenTTL_Call) Run(run func(ctx context.Context, keyID string, ttl time.Duration)) *MockAuthRepo_UpdateTokenTTL_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(time.Duration))
+	})
+	return _c
+}
+
+func (_c *MockAuthRepo_UpdateTokenTTL_Call) Return(_a0 error) *MockAuthRepo_UpdateTokenTTL_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockAuthRepo_UpdateTokenTTL_Call) RunAndReturn(run func(context.Context, string, time.Duration) error) *MockAuthRepo_UpdateTokenTTL_Call {
 	_c.Call.Return(run)
 	return _c
 }
@@ -12,9 +12,11 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/ksysoev/make-it-public/pkg/core/audit"
 	"github.com/ksysoev/make-it-public/pkg/core/conn"
 	"github.com/ksysoev/make-it-public/pkg/core/conn/meta"
 	"github.com/ksysoev/make-it-public/pkg/core/token"
+	"github.com/ksysoev/make-it-public/pkg/metrics"
 	"github.com/ksysoev/revdial/proto"
 	"golang.org/x/sync/errgroup"
 )
@@ -27,6 +29,13 @@ var (
 	ErrFailedToConnect = errors.New("failed to connect")
 	ErrKeyIDNotFound   = errors.New("keyID not found")
 	ErrConnClosed      = errors.New("connection closed")
+	ErrUnauthorized    = errors.New("unauthorized")
+	ErrForbidden       = errors.New("forbidden")
+	ErrPortUnavailable = errors.New("port is not available")
+	ErrAuthMismatch    = errors.New("tunnel credentials differ from those of connected clients")
+
+	// ErrTunnelOffline is returned for tokens whose client is not connected. It is an ErrFailedToConnect.
+	ErrTunnelOffline = fmt.Errorf("tunnel is offline: %w", ErrFailedToConnect)
 )
 
 func (s *Service) HandleReverseConn(ctx context.Context, revConn net.Conn) error {
@@ -39,10 +48,18 @@ func (s *Service) HandleReverseConn(ctx context.Context, revConn net.Conn) error
 
 	var connTokenType token.TokenType
 
+	var connOpts meta.ClientOptions
+
 	// Use NewServerV2 to support both V1 and V2 protocols
 	// V2 provides yamux multiplexing for better performance
 	baseOpts := []proto.ServerOption{
-		proto.WithUserPassAuth(func(keyID, secret string) bool {
+		proto.WithUserPassAuth(func(keyID, password string) bool {
+			secret, opts, err := meta.DecodeSecret(password)
+			if err != nil {
+				slog.ErrorContext(ctx, "failed to decode client options", slog.Any("error", err))
+				return false
+			}
+
 			t, err := s.auth.Verify(ctx, keyID, secret)
 			if err != nil {
 				slog.ErrorContext(ctx, "failed to verify user", slog.Any("error", err))
@@ -55,6 +72,7 @@ func (s *Service) HandleReverseConn(ctx context.Context, revConn net.Conn) error
 
 			connKeyID = t.ID
 			connTokenType = t.Type
+			connOpts = opts
 
 			return true
 		}),
@@ -86,18 +104,28 @@ func (s *Service) HandleReverseConn(ctx context.Context, revConn net.Conn) error
 	case proto.StateRegistered:
 		srvConn := conn.NewServerConn(ctx, servConn)
 
-		// Route to the correct connection manager based on token type.
-		connMng := s.webConnMng
-		if connTokenType == token.TokenTypeTCP {
-			connMng = s.tcpConnMng
+		// Clients connecting while the server drains are asked to reconnect before they are refused, so that they
+		// move to another node instead of waiting for their tunnel URL.
+		if s.IsDraining() {
+			if err := srvConn.SendReconnectEvent(s.reconnectAddr); err != nil {
+				slog.DebugContext(ctx, "failed to send reconnect event", slog.Any("error", err), slog.String("keyID", connKeyID))
+			}
+
+			return fmt.Errorf("refused control connection for keyID %s: %w", connKeyID, ErrDraining)
 		}
 
+		srvConn.SetAuth(connOpts.Auth)
+
+		// Route to the correct connection manager based on token type.
+		connMng := s.connManager(connTokenType)
+
 		// Generate the public endpoint for the client to advertise.
-		// TCP tokens get a dynamically allocated port; web tokens get a subdomain URL.
+		// TCP and UDP tokens get a dynamically allocated port; web, gRPC and TLS tokens get a subdomain of their edge.
 		var endpoint string
 
-		if connTokenType == token.TokenTypeTCP {
-			ep, err := s.tcpEndpointAllocator.Allocate(srvConn.Context(), connKeyID)
+		switch connTokenType {
+		case token.TokenTypeTCP:
+			ep, err := s.allocateTCPEndpoint(srvConn.Context(), connKeyID, connOpts.Port)
 			if err != nil {
 				return fmt.Errorf("failed to allocate TCP endpoint: %w", err)
 			}
@@ -105,7 +133,30 @@ func (s *Service) HandleReverseConn(ctx context.Context, revConn net.Conn) error
 			defer s.tcpEndpointAllocator.Release(connKeyID)
 
 			endpoint = ep
-		} else {
+		case token.TokenTypeUDP:
+			ep, err := s.udpEndpointAllocator.Allocate(srvConn.Context(), connKeyID)
+			if err != nil {
+				return fmt.Errorf("failed to allocate UDP endpoint: %w", err)
+			}
+
+			defer s.udpEndpointAllocator.Release(connKeyID)
+
+			endpoint = ep
+		case token.TokenTypeGRPC:
+			ep, err := s.grpcEndpointGenerator(connKeyID)
+			if err != nil {
+				return fmt.Errorf("failed to generate gRPC endpoint: %w", err)
+			}
+
+			endpoint = ep
+		case token.TokenTypeTLS:
+			ep, err := s.tlsEndpointGenerator(connKeyID)
+			if err != nil {
+				return fmt.Errorf("failed to generate TLS endpoint: %w", err)
+			}
+
+			endpoint = ep
+		default:
 			ep, err := s.endpointGenerator(connKeyID)
 			if err != nil {
 				return fmt.Errorf("failed to generate endpoint: %w", err)
@@ -114,13 +165,41 @@ func (s *Service) HandleReverseConn(ctx context.Context, revConn net.Conn) error
 			endpoint = ep
 		}
 
+		// Clients of one keyID share its public endpoint, so they must all require the same credentials.
+		// The check against clients on other nodes is best effort, as they may connect at the same time.
+		if published, err := s.connRegistry.TunnelAuth(ctx, connKeyID); err == nil && !connOpts.Auth.Matches(published) {
+			return fmt.Errorf("refused control connection for keyID %s: %w", connKeyID, ErrAuthMismatch)
+		}
+
+		if err := connMng.AddConnection(connKeyID, srvConn); err != nil {
+			return err
+		}
+
+		defer connMng.RemoveConnection(connKeyID, srvConn.ID())
+
 		if err := srvConn.SendURLToConnectUpdatedEvent(endpoint); err != nil {
 			return fmt.Errorf("failed to send url to connect updated event: %w", err)
 		}
 
-		connMng.AddConnection(connKeyID, srvConn)
+		activeConns := metrics.ControlConnections.WithLabelValues(string(connTokenType))
+		activeConns.Inc()
 
-		defer connMng.RemoveConnection(connKeyID, srvConn.ID())
+		defer activeConns.Dec()
+
+		sealedAuth, err := connOpts.Auth.Seal()
+		if err != nil {
+			return fmt.Errorf("failed to seal tunnel auth: %w", err)
+		}
+
+		if err := s.connRegistry.Register(ctx, connKeyID, sealedAuth); err != nil {
+			slog.ErrorContext(ctx, "failed to register keyID in cluster registry", slog.Any("error", err), slog.String("keyID", connKeyID))
+		}
+
+		defer func() {
+			if err := s.connRegistry.Unregister(context.WithoutCancel(ctx), connKeyID); err != nil {
+				slog.ErrorContext(ctx, "failed to unregister keyID from cluster registry", slog.Any("error", err), slog.String("keyID", connKeyID))
+			}
+		}()
 
 		protocolVersion := "V1"
 		if servConn.IsV2() {
@@ -130,7 +209,30 @@ func (s *Service) HandleReverseConn(ctx context.Context, revConn net.Conn) error
 		slog.InfoContext(ctx, "control conn established",
 			slog.String("keyID", connKeyID),
 			slog.String("tokenType", string(connTokenType)),
-			slog.String("protocol", protocolVersion))
+			slog.String("protocol", protocolVersion),
+			slog.Bool("protected", connOpts.Auth.Enabled()))
+
+		established := time.Now()
+		controlRec := audit.Record{
+			KeyID:     connKeyID,
+			TokenType: connTokenType.String(),
+			ConnID:    srvConn.ID().String(),
+			RemoteIP:  remoteIP(revConn.RemoteAddr()),
+			Protocol:  protocolVersion,
+		}
+
+		openedRec := controlRec
+		openedRec.Time = established
+		openedRec.Kind = audit.KindControlOpened
+		s.writeAudit(ctx, &openedRec)
+
+		defer func() {
+			closedRec := controlRec
+			closedRec.Time = time.Now()
+			closedRec.Kind = audit.KindControlClosed
+			closedRec.DurationMS = time.Since(established).Milliseconds()
+			s.writeAudit(ctx, &closedRec)
+		}()
 
 		// For V2 connections, start accepting yamux streams in the background.
 		// The client opens new streams (instead of new TCP connections) for each data connection.
@@ -138,10 +240,21 @@ func (s *Service) HandleReverseConn(ctx context.Context, revConn net.Conn) error
 			go s.acceptV2Streams(srvConn.Context(), servConn, connKeyID, connMng)
 		}
 
+		draining := s.Draining()
+
 		for {
 			select {
 			case <-srvConn.Context().Done():
 				return nil
+			case <-draining:
+				// The client is asked to reconnect once, and keeps being served until it does.
+				draining = nil
+
+				if err := srvConn.SendReconnectEvent(s.reconnectAddr); err != nil {
+					slog.ErrorContext(ctx, "failed to send reconnect event", slog.Any("error", err), slog.String("keyID", connKeyID))
+				}
+
+				continue
 			case <-time.After(200 * time.Millisecond):
 			}
 
@@ -158,10 +271,7 @@ func (s *Service) HandleReverseConn(ctx context.Context, revConn net.Conn) error
 		}
 
 		// Route to the correct connection manager based on token type
-		connMng := s.webConnMng
-		if connTokenType == token.TokenTypeTCP {
-			connMng = s.tcpConnMng
-		}
+		connMng := s.connManager(connTokenType)
 
 		connMng.ResolveRequest(servConn.ID(), notifier)
 		slog.InfoContext(ctx, "rev conn established", slog.String("keyID", connKeyID), slog.String("tokenType", string(connTokenType)))
@@ -175,7 +285,114 @@ func (s *Service) HandleReverseConn(ctx context.Context, revConn net.Conn) error
 	}
 }
 
+// allocateTCPEndpoint allocates the public endpoint of the TCP tunnel of keyID.
+// The port reserved for keyID is always used. Otherwise preferredPort is tried first, if set,
+// and any free port is used when it is not available.
+func (s *Service) allocateTCPEndpoint(ctx context.Context, keyID string, preferredPort int) (string, error) {
+	reserved, err := s.auth.ReservedPort(ctx, keyID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get reserved port: %w", err)
+	}
+
+	if reserved > 0 {
+		return s.tcpEndpointAllocator.Allocate(ctx, keyID, reserved)
+	}
+
+	if preferredPort > 0 {
+		ep, err := s.tcpEndpointAllocator.Allocate(ctx, keyID, preferredPort)
+		if !errors.Is(err, ErrPortUnavailable) {
+			return ep, err
+		}
+
+		slog.InfoContext(ctx, "preferred TCP port is not available", slog.String("keyID", keyID), slog.Int("port", preferredPort))
+	}
+
+	return s.tcpEndpointAllocator.Allocate(ctx, keyID, 0)
+}
+
+// TCPPortOwner returns the keyID of the token that reserved port, or an empty string if the port is not reserved.
+func (s *Service) TCPPortOwner(ctx context.Context, port int) (string, error) {
+	keyID, err := s.auth.PortOwner(ctx, port)
+	if err != nil {
+		return "", fmt.Errorf("failed to get port owner: %w", err)
+	}
+
+	return keyID, nil
+}
+
+// CheckClientIP checks clientIP against the IP filter of the token identified by keyID.
+// Returns ErrForbidden if the address is not allowed to reach the tunnel, or ErrFailedToConnect if the
+// filter cannot be loaded.
+func (s *Service) CheckClientIP(ctx context.Context, keyID, clientIP string) error {
+	f, err := s.auth.IPFilter(ctx, keyID)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to load IP filter", slog.Any("error", err), slog.String("keyID", keyID))
+		return fmt.Errorf("failed to load IP filter: %w", ErrFailedToConnect)
+	}
+
+	if !f.Allow(clientIP) {
+		return fmt.Errorf("client IP %s is not allowed for keyID %s: %w", clientIP, keyID, ErrForbidden)
+	}
+
+	return nil
+}
+
+// AuthorizeHTTP checks the value of an Authorization header against the credentials required by the tunnel of keyID.
+// Credentials of local control connections are checked first; otherwise the credentials published by other cluster nodes are used.
+// It reports whether the tunnel is protected, in which case the header is meant for the edge and not for the tunnel.
+// Returns ErrUnauthorized if the tunnel is protected and the credentials do not match, or ErrFailedToConnect if the
+// credentials cannot be looked up. Unknown keyIDs are allowed so that HandleHTTPConnection can report them.
+func (s *Service) AuthorizeHTTP(ctx context.Context, keyID, authorization string) (bool, error) {
+	tunAuth, ok := s.webConnMng.TunnelAuth(keyID)
+	if !ok {
+		var err error
+
+		tunAuth, err = s.connRegistry.TunnelAuth(ctx, keyID)
+
+		switch {
+		case errors.Is(err, ErrKeyIDNotFound):
+			return false, nil
+		case err != nil:
+			slog.ErrorContext(ctx, "failed to look up tunnel auth", slog.Any("error", err), slog.String("keyID", keyID))
+			return false, fmt.Errorf("failed to look up tunnel auth: %w", ErrFailedToConnect)
+		}
+	}
+
+	if !tunAuth.Allow(authorization) {
+		return true, fmt.Errorf("invalid credentials for keyID %s: %w", keyID, ErrUnauthorized)
+	}
+
+	return tunAuth.Enabled(), nil
+}
+
 func (s *Service) HandleHTTPConnection(ctx context.Context, keyID string, cliConn net.Conn, write func(net.Conn) error, clientIP string) error {
+	defer s.trackConn()()
+
+	started := time.Now()
+	stats := &connStats{}
+
+	err := s.checkQuota(ctx, keyID)
+	if err == nil {
+		connCtx, stopMeter := s.meterQuota(ctx, keyID, stats)
+		err = s.handleHTTPConnection(connCtx, keyID, cliConn, write, clientIP, true, stats)
+
+		// Responses may already have been sent on the connection, so it is closed without an error response.
+		if quotaErr := stopMeter(); quotaErr != nil {
+			slog.DebugContext(ctx, "HTTP connection closed", slog.Any("error", quotaErr), slog.String("keyID", keyID))
+		}
+	}
+
+	s.auditPublicConn(ctx, keyID, token.TokenTypeWeb, clientIP, started, stats, err)
+
+	return err
+}
+
+// handleHTTPConnection serves an HTTP connection through a local control connection of keyID.
+// When the keyID has no local control connection and forward is true, the connection is forwarded
+// to the cluster node that owns the keyID. Forwarded connections are served with forward set to false
+// so that a connection never travels more than one hop inside the cluster.
+// The bytes piped in each direction are counted in stats.
+func (s *Service) handleHTTPConnection(ctx context.Context, keyID string, cliConn net.Conn, write func(net.Conn) error, clientIP string, forward bool, stats *connStats) error {
 	slog.DebugContext(ctx, "new HTTP connection", slog.Any("remote", cliConn.RemoteAddr()))
 	defer slog.DebugContext(ctx, "closing HTTP connection", slog.Any("remote", cliConn.RemoteAddr()))
 
@@ -184,6 +401,17 @@ func (s *Service) HandleHTTPConnection(ctx context.Context, keyID string, cliCon
 
 	switch {
 	case errors.Is(err, ErrKeyIDNotFound):
+		if forward {
+			fwdConn, fwdErr := s.connRegistry.Forward(ctx, keyID, token.TokenTypeWeb, clientIP)
+			if fwdErr == nil {
+				return s.pipeForwarded(ctx, cliConn, fwdConn, write, stats)
+			}
+
+			if !errors.Is(fwdErr, ErrKeyIDNotFound) {
+				slog.ErrorContext(ctx, "failed to forward HTTP connection", slog.Any("error", fwdErr), slog.String("keyID", keyID))
+			}
+		}
+
 		ok, err := s.auth.IsKeyExists(ctx, keyID)
 		if err != nil {
 			return fmt.Errorf("failed to check key existence: %w", err)
@@ -193,7 +421,7 @@ func (s *Service) HandleHTTPConnection(ctx context.Context, keyID string, cliCon
 			return fmt.Errorf("keyID %s not found: %w", keyID, ErrKeyIDNotFound)
 		}
 
-		return fmt.Errorf("no connections available for keyID %s: %w", keyID, ErrFailedToConnect)
+		return fmt.Errorf("no connections available for keyID %s: %w", keyID, ErrTunnelOffline)
 	case err != nil:
 		return fmt.Errorf("failed to request connection: %w", ErrFailedToConnect)
 	}
@@ -213,25 +441,29 @@ func (s *Service) HandleHTTPConnection(ctx context.Context, keyID string, cliCon
 	}
 
 	// Write initial request data
-	if err := write(revConn); err != nil {
+	if err := write(&countingConn{Conn: revConn, written: &stats.in}); err != nil {
 		slog.DebugContext(ctx, "failed to write initial request", slog.Any("error", err))
 
 		return fmt.Errorf("failed to write initial request: %w", ErrFailedToConnect)
 	}
 
+	bw, release := s.acquireBandwidth(keyID)
+	defer release()
+
 	eg, ctx := errgroup.WithContext(ctx)
 	connNopCloser := conn.NewContextConnNopCloser(ctx, cliConn)
-	respBytesWritten := int64(0)
+	reqBytesWritten := int64(0)
 
-	eg.Go(pipeToDest(ctx, connNopCloser, revConn))
-	eg.Go(pipeToSource(ctx, revConn, connNopCloser, &respBytesWritten))
+	eg.Go(pipeToDest(ctx, stats.meter.reader(bw.reader(ctx, connNopCloser)), revConn, &reqBytesWritten))
+	eg.Go(pipeToSource(ctx, revConn, stats.meter.writer(bw.writer(ctx, connNopCloser)), &stats.out))
 
 	guard := closeOnContextDone(ctx, req.ParentContext(), revConn)
 	defer guard.Wait()
 
 	err = eg.Wait()
+	stats.in += reqBytesWritten
 
-	if respBytesWritten <= 0 {
+	if stats.out <= 0 {
 		slog.DebugContext(ctx, "no data written to reverse connection", slog.Any("error", err))
 		return fmt.Errorf("no data written to reverse connection: %w", ErrFailedToConnect)
 	}
@@ -410,13 +642,16 @@ func (w *yamuxStreamWrapper) CloseWrite() error {
 }
 
 // pipeToDest copies data from the source Reader to the destination Conn in a streaming manner.
-// It manages specific error conditions such as closed or reset connections.
+// It stores the number of bytes copied in written, and manages specific error conditions such as closed or reset connections.
 // Returns a function that executes the copy process, returning ErrConnClosed for io.ErrClosedPipe or connection reset errors.
 // Also returns a wrapped error for other errors encountered during the copy process, or nil if the operation completes successfully.
-func pipeToDest(ctx context.Context, src io.Reader, dst conn.WithWriteCloser) func() error {
+func pipeToDest(ctx context.Context, src io.Reader, dst conn.WithWriteCloser, written *int64) func() error {
 	return func() error {
-		n, err := io.Copy(dst, src)
-		slog.DebugContext(ctx, "data copied to reverse connection", slog.Any("error", err), slog.Int64("bytes_written", n))
+		var err error
+
+		*written, err = io.Copy(dst, src)
+		metrics.BytesPiped.WithLabelValues(metrics.DirectionInbound).Add(float64(*written))
+		slog.DebugContext(ctx, "data copied to reverse connection", slog.Any("error", err), slog.Int64("bytes_written", *written))
 
 		switch {
 		case errors.Is(err, net.ErrClosed), errors.Is(err, syscall.ECONNRESET):
@@ -443,6 +678,7 @@ func pipeToSource(ctx context.Context, src conn.WithWriteCloser, dst io.Writer,
 		var err error
 
 		*written, err = io.Copy(dst, src)
+		metrics.BytesPiped.WithLabelValues(metrics.DirectionOutbound).Add(float64(*written))
 		slog.DebugContext(ctx, "data copied from reverse connection", slog.Int64("bytes_written", *written), slog.Any("error", err))
 
 		switch {
@@ -489,13 +725,95 @@ func closeOnContextDone(reqCtx, parentCtx context.Context, c conn.WithWriteClose
 // writes connection metadata, and then bidirectionally pipes data between the
 // end-user connection and the reverse tunnel.
 func (s *Service) HandleTCPConnection(ctx context.Context, keyID string, cliConn net.Conn, clientIP string) error {
-	slog.DebugContext(ctx, "new TCP connection", slog.Any("remote", cliConn.RemoteAddr()))
-	defer slog.DebugContext(ctx, "closing TCP connection", slog.Any("remote", cliConn.RemoteAddr()))
+	defer s.trackConn()()
+
+	return s.servePipedConn(ctx, keyID, token.TokenTypeTCP, cliConn, clientIP)
+}
 
-	req, err := s.tcpConnMng.RequestConnection(ctx, keyID)
+// HandleGRPCConnection handles a connection opened by the gRPC edge server for a visitor of keyID.
+// The HTTP/2 frames of the connection are piped as they are to the MIT client, which passes them on
+// to the local gRPC service, so that streams and trailers are preserved end to end.
+func (s *Service) HandleGRPCConnection(ctx context.Context, keyID string, cliConn net.Conn, clientIP string) error {
+	defer s.trackConn()()
+
+	return s.servePipedConn(ctx, keyID, token.TokenTypeGRPC, cliConn, clientIP)
+}
+
+// HandleTLSConnection handles a connection accepted by the TLS passthrough edge server for a visitor of keyID.
+// The connection is piped as it is, TLS handshake included, so that the TLS session is terminated
+// by the local service rather than by the server.
+func (s *Service) HandleTLSConnection(ctx context.Context, keyID string, cliConn net.Conn, clientIP string) error {
+	defer s.trackConn()()
+
+	return s.servePipedConn(ctx, keyID, token.TokenTypeTLS, cliConn, clientIP)
+}
+
+// HandleUDPSession handles a session of a UDP visitor of keyID opened by the UDP edge server.
+// conn carries the datagrams of the session framed with meta.WriteDatagram, which are piped as they are
+// to the MIT client, so that the client relays every datagram to the local service on its own.
+func (s *Service) HandleUDPSession(ctx context.Context, keyID string, conn net.Conn, clientIP string) error {
+	defer s.trackConn()()
+
+	return s.servePipedConn(ctx, keyID, token.TokenTypeUDP, conn, clientIP)
+}
+
+// servePipedConn serves a connection piped to a tunnel of tokenType, counting its traffic against the quota
+// of keyID and recording it in the audit log.
+// Errors are only returned before the connection is piped, so that edges can still answer the visitor;
+// a connection cut short by the quota is closed and recorded in the audit log with the quota error.
+func (s *Service) servePipedConn(ctx context.Context, keyID string, tokenType token.TokenType, cliConn net.Conn, clientIP string) error {
+	started := time.Now()
+	stats := &connStats{}
+
+	err := s.checkQuota(ctx, keyID)
+	auditErr := err
+
+	if err == nil {
+		connCtx, stopMeter := s.meterQuota(ctx, keyID, stats)
+		err = s.handleRawConnection(connCtx, keyID, tokenType, cliConn, clientIP, true, stats)
+		auditErr = err
+
+		// Data may already have been exchanged on the connection, so the quota error is not returned.
+		if quotaErr := stopMeter(); quotaErr != nil {
+			slog.DebugContext(ctx, "piped connection closed", slog.Any("error", quotaErr), slog.String("keyID", keyID),
+				slog.String("tokenType", tokenType.String()))
+
+			if err == nil {
+				auditErr = quotaErr
+			}
+		}
+	}
+
+	s.auditPublicConn(ctx, keyID, tokenType, clientIP, started, stats, auditErr)
+
+	return err
+}
+
+// handleRawConnection pipes a connection through a local control connection of keyID for tokens of tokenType.
+// When the keyID has no local control connection and forward is true, the connection is forwarded
+// to the cluster node that owns the keyID. The bytes piped in each direction are counted in stats.
+func (s *Service) handleRawConnection(ctx context.Context, keyID string, tokenType token.TokenType, cliConn net.Conn, clientIP string, forward bool, stats *connStats) error {
+	slog.DebugContext(ctx, "new piped connection", slog.Any("remote", cliConn.RemoteAddr()), slog.String("tokenType", tokenType.String()))
+	defer slog.DebugContext(ctx, "closing piped connection", slog.Any("remote", cliConn.RemoteAddr()), slog.String("tokenType", tokenType.String()))
+
+	connMng := s.connManager(tokenType)
+
+	req, err := connMng.RequestConnection(ctx, keyID)
 
 	switch {
 	case errors.Is(err, ErrKeyIDNotFound):
+		if forward {
+			fwdConn, fwdErr := s.connRegistry.Forward(ctx, keyID, tokenType, clientIP)
+			if fwdErr == nil {
+				return s.pipeForwarded(ctx, cliConn, fwdConn, nil, stats)
+			}
+
+			if !errors.Is(fwdErr, ErrKeyIDNotFound) {
+				slog.ErrorContext(ctx, "failed to forward connection", slog.Any("error", fwdErr), slog.String("keyID", keyID),
+					slog.String("tokenType", tokenType.String()))
+			}
+		}
+
 		ok, authErr := s.auth.IsKeyExists(ctx, keyID)
 		if authErr != nil {
 			return fmt.Errorf("failed to check key existence: %w", authErr)
@@ -505,39 +823,110 @@ func (s *Service) HandleTCPConnection(ctx context.Context, keyID string, cliConn
 			return fmt.Errorf("keyID %s not found: %w", keyID, ErrKeyIDNotFound)
 		}
 
-		return fmt.Errorf("no connections available for keyID %s: %w", keyID, ErrFailedToConnect)
+		return fmt.Errorf("no connections available for keyID %s: %w", keyID, ErrTunnelOffline)
 	case err != nil:
-		return fmt.Errorf("failed to request TCP connection: %w", ErrFailedToConnect)
+		return fmt.Errorf("failed to request %s connection: %w", tokenType, ErrFailedToConnect)
 	}
 
 	revConn, err := req.WaitConn(ctx)
 	if err != nil {
-		s.tcpConnMng.CancelRequest(req.ID())
-		return fmt.Errorf("TCP connection request failed: %w", ErrFailedToConnect)
+		connMng.CancelRequest(req.ID())
+		return fmt.Errorf("%s connection request failed: %w", tokenType, ErrFailedToConnect)
 	}
 
-	slog.DebugContext(ctx, "TCP reverse connection received", slog.Any("remote", cliConn.RemoteAddr()))
+	slog.DebugContext(ctx, "reverse connection received", slog.Any("remote", cliConn.RemoteAddr()), slog.String("tokenType", tokenType.String()))
 
 	if err := meta.WriteData(revConn, &meta.ClientConnMeta{IP: clientIP}); err != nil {
-		slog.DebugContext(ctx, "failed to write TCP client connection meta", slog.Any("error", err))
+		slog.DebugContext(ctx, "failed to write client connection meta", slog.Any("error", err))
 
 		_ = revConn.Close()
 
-		return fmt.Errorf("failed to write TCP client connection meta: %w", ErrFailedToConnect)
+		return fmt.Errorf("failed to write %s client connection meta: %w", tokenType, ErrFailedToConnect)
 	}
 
+	bw, release := s.acquireBandwidth(keyID)
+	defer release()
+
 	eg, egCtx := errgroup.WithContext(ctx)
 	connNopCloser := conn.NewContextConnNopCloser(egCtx, cliConn)
-	respBytesWritten := int64(0)
 
-	eg.Go(pipeToDest(egCtx, connNopCloser, revConn))
-	eg.Go(pipeToSource(egCtx, revConn, connNopCloser, &respBytesWritten))
+	eg.Go(pipeToDest(egCtx, stats.meter.reader(bw.reader(egCtx, connNopCloser)), revConn, &stats.in))
+	eg.Go(pipeToSource(egCtx, revConn, stats.meter.writer(bw.writer(egCtx, connNopCloser)), &stats.out))
 
 	guard := closeOnContextDone(egCtx, req.ParentContext(), revConn)
 	defer guard.Wait()
 
 	if err := eg.Wait(); err != nil && !errors.Is(err, ErrConnClosed) {
-		slog.DebugContext(ctx, "TCP data pipe closed", slog.Any("error", err))
+		slog.DebugContext(ctx, "data pipe closed", slog.Any("error", err), slog.String("tokenType", tokenType.String()))
+	}
+
+	return nil
+}
+
+// HandleForwardedConn serves a connection forwarded by another node of the cluster.
+// The connection is routed to a local control connection of keyID according to tokenType,
+// and is never forwarded again, so that a missing keyID cannot bounce between nodes.
+// The client IP is checked against the IP filter of the token again, so a node does not rely on the filter of its peers.
+// Tunnel credentials are not checked again: the request carrying them was authorized by the forwarding edge,
+// and the header naming keyID and clientIP is authenticated by the cluster link.
+// It is not added to the audit log, which records the connection on the node that received it.
+// Returns ErrForbidden if the client IP is not allowed, or an error if the token type is unknown or the connection
+// cannot be served.
+func (s *Service) HandleForwardedConn(ctx context.Context, keyID string, tokenType token.TokenType, cliConn net.Conn, clientIP string) error {
+	defer s.trackConn()()
+
+	if err := s.CheckClientIP(ctx, keyID, clientIP); err != nil {
+		return err
+	}
+
+	switch tokenType {
+	case token.TokenTypeWeb:
+		// The initial request has already been written to the link by the forwarding node,
+		// so it is piped to the client together with the rest of the stream.
+		return s.handleHTTPConnection(ctx, keyID, cliConn, func(net.Conn) error { return nil }, clientIP, false, &connStats{})
+	case token.TokenTypeTCP, token.TokenTypeGRPC, token.TokenTypeUDP, token.TokenTypeTLS:
+		return s.handleRawConnection(ctx, keyID, tokenType, cliConn, clientIP, false, &connStats{})
+	default:
+		return fmt.Errorf("unsupported token type for forwarded connection: %s", tokenType)
+	}
+}
+
+// pipeForwarded pipes data between an end-user connection and an internal link to another cluster node.
+// If write is not nil, it is used to send the initial HTTP request data over the link before piping starts.
+// The bytes piped in each direction are counted in stats.
+// Returns ErrFailedToConnect if the initial data cannot be written or the remote node sends no HTTP response back.
+func (s *Service) pipeForwarded(ctx context.Context, cliConn net.Conn, fwdConn conn.WithWriteCloser, write func(net.Conn) error, stats *connStats) error {
+	defer func() { _ = fwdConn.Close() }()
+
+	if write != nil {
+		if err := write(&countingConn{Conn: fwdConn, written: &stats.in}); err != nil {
+			slog.DebugContext(ctx, "failed to write initial request to cluster node", slog.Any("error", err))
+
+			return fmt.Errorf("failed to write initial request to cluster node: %w", ErrFailedToConnect)
+		}
+	}
+
+	eg, egCtx := errgroup.WithContext(ctx)
+	connNopCloser := conn.NewContextConnNopCloser(egCtx, cliConn)
+	reqBytesWritten := int64(0)
+
+	eg.Go(pipeToDest(egCtx, stats.meter.reader(connNopCloser), fwdConn, &reqBytesWritten))
+	eg.Go(pipeToSource(egCtx, fwdConn, stats.meter.writer(connNopCloser), &stats.out))
+
+	guard := closeOnContextDone(egCtx, ctx, fwdConn)
+	defer guard.Wait()
+
+	err := eg.Wait()
+	stats.in += reqBytesWritten
+
+	// Raw TCP sessions may legitimately end without any data sent back, so only
+	// HTTP connections treat an empty response as a failure to connect.
+	if write != nil && stats.out <= 0 {
+		return fmt.Errorf("no data received from cluster node: %w", ErrFailedToConnect)
+	}
+
+	if err != nil && !errors.Is(err, ErrConnClosed) {
+		return fmt.Errorf("failed to copy data: %w", err)
 	}
 
 	return nil
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/google/uuid"
+	"github.com/ksysoev/make-it-public/pkg/core/conn/meta"
 	"github.com/ksysoev/revdial/proto"
 )
 
@@ -29,6 +30,7 @@ type ControlConn struct {
 	conn   serverConn
 	ctx    context.Context
 	cancel context.CancelFunc
+	auth   *meta.TunnelAuth
 }
 
 // NewServerConn creates a new managed server connection with context support.
@@ -56,6 +58,17 @@ func (r *ControlConn) Context() context.Context {
 	return r.ctx
 }
 
+// SetAuth stores the credentials the client requires from end users of its tunnel.
+// It must be called before the ControlConn is shared with other goroutines.
+func (r *ControlConn) SetAuth(auth *meta.TunnelAuth) {
+	r.auth = auth
+}
+
+// Auth returns the credentials the client requires from end users of its tunnel, or nil if the tunnel is not protected.
+func (r *ControlConn) Auth() *meta.TunnelAuth {
+	return r.auth
+}
+
 // Close releases the server connection and cancels the associated context to free resources of the ControlConn instance.
 // It returns an error if the underlying connection cannot be successfully closed.
 func (r *ControlConn) Close() error {
@@ -64,16 +77,20 @@ func (r *ControlConn) Close() error {
 	return r.conn.Close()
 }
 
-// RequestConnection initiates a new connection request by issuing a connect command to the server.
-// It ensures the server is in a registered state before proceeding.
-// Returns a pointer to request containing the connection request details and an error if the server is not connected or if the command fails to send.
-func (r *ControlConn) RequestConnection() (Request, error) {
-	req := newRequest(r.Context())
+// NewRequest creates a connection request bound to the lifetime of the control connection.
+// The client is asked to fulfil it by RequestConnection.
+func (r *ControlConn) NewRequest() Request {
+	return newRequest(r.Context())
+}
+
+// RequestConnection asks the client to open a connection for req by issuing a connect command to it.
+// Returns an error if the server is not connected or if the command fails to send.
+func (r *ControlConn) RequestConnection(req Request) error {
 	if err := r.conn.SendConnectCommand(req.ID()); err != nil {
-		return nil, fmt.Errorf("failed to send connect command: %w", err)
+		return fmt.Errorf("failed to send connect command: %w", err)
 	}
 
-	return req, nil
+	return nil
 }
 
 // Ping sends a ping command to the server to verify the connection's responsiveness.
@@ -89,3 +106,9 @@ func (r *ControlConn) Ping() error {
 func (r *ControlConn) SendURLToConnectUpdatedEvent(url string) error {
 	return r.conn.SendCustomEvent("urlToConnectUpdated", url)
 }
+
+// SendReconnectEvent asks the client to move its tunnel to the server at addr.
+// It returns an error if the event cannot be sent.
+func (r *ControlConn) SendReconnectEvent(addr string) error {
+	return r.conn.SendCustomEvent(meta.ReconnectEvent, addr)
+}
@@ -70,13 +70,15 @@ func TestServConn_RequestConnection(t *testing.T) {
 			mockConn.EXPECT().SendConnectCommand(mock.Anything).Return(tt.mockSendResponse)
 
 			sc := NewServerConn(context.Background(), mockConn)
-			req, err := sc.RequestConnection()
+			req := sc.NewRequest()
+
+			require.NotNil(t, req)
+
+			err := sc.RequestConnection(req)
 
 			if tt.expectedError != nil {
-				assert.Nil(t, req)
 				assert.ErrorIs(t, err, tt.expectedError)
 			} else {
-				assert.NotNil(t, req)
 				assert.NoError(t, err)
 			}
 		})
@@ -0,0 +1,49 @@
+package meta
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// MaxDatagramSize is the largest datagram that can be framed, which covers any UDP payload.
+const MaxDatagramSize = maxDataSize
+
+// WriteDatagram writes p to w as a single frame, prefixed with its length as a uint16,
+// so that datagram boundaries are kept on stream connections.
+// The frame is written with a single call to w.Write, so frames written concurrently are not interleaved.
+func WriteDatagram(w io.Writer, p []byte) error {
+	if len(p) > MaxDatagramSize {
+		return fmt.Errorf("datagram length exceeds maximum allowed size (65535 bytes)")
+	}
+
+	frame := make([]byte, 2+len(p))
+	binary.BigEndian.PutUint16(frame, uint16(len(p)))
+	copy(frame[2:], p)
+
+	if _, err := w.Write(frame); err != nil {
+		return fmt.Errorf("failed to write datagram: %w", err)
+	}
+
+	return nil
+}
+
+// ReadDatagram reads a frame written by WriteDatagram from r into buf and returns the datagram.
+// buf must be large enough to hold the datagram; MaxDatagramSize bytes fit any datagram.
+func ReadDatagram(r io.Reader, buf []byte) ([]byte, error) {
+	lenBuf := make([]byte, 2)
+	if _, err := io.ReadFull(r, lenBuf); err != nil {
+		return nil, fmt.Errorf("failed to read datagram length: %w", err)
+	}
+
+	dataLen := int(binary.BigEndian.Uint16(lenBuf))
+	if dataLen > len(buf) {
+		return nil, fmt.Errorf("datagram of %d bytes exceeds buffer size %d", dataLen, len(buf))
+	}
+
+	if _, err := io.ReadFull(r, buf[:dataLen]); err != nil {
+		return nil, fmt.Errorf("failed to read datagram: %w", err)
+	}
+
+	return buf[:dataLen], nil
+}
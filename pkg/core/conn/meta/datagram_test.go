@@ -0,0 +1,51 @@
+package meta
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteAndReadDatagram(t *testing.T) {
+	var buf bytes.Buffer
+
+	datagrams := [][]byte{[]byte("ping"), {}, bytes.Repeat([]byte{1}, MaxDatagramSize)}
+
+	for _, d := range datagrams {
+		require.NoError(t, WriteDatagram(&buf, d))
+	}
+
+	readBuf := make([]byte, MaxDatagramSize)
+
+	for _, d := range datagrams {
+		got, err := ReadDatagram(&buf, readBuf)
+		require.NoError(t, err)
+		assert.Equal(t, d, got)
+	}
+
+	_, err := ReadDatagram(&buf, readBuf)
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestWriteDatagram_TooLarge(t *testing.T) {
+	var buf bytes.Buffer
+
+	err := WriteDatagram(&buf, make([]byte, MaxDatagramSize+1))
+	assert.Error(t, err)
+	assert.Zero(t, buf.Len())
+}
+
+func TestReadDatagram_Errors(t *testing.T) {
+	var buf bytes.Buffer
+
+	require.NoError(t, WriteDatagram(&buf, []byte("ping")))
+
+	_, err := ReadDatagram(&buf, make([]byte, 2))
+	assert.Error(t, err)
+
+	_, err = ReadDatagram(bytes.NewReader([]byte{0, 4, 'p'}), make([]byte, 4))
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
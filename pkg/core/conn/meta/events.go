@@ -0,0 +1,6 @@
+package meta
+
+// ReconnectEvent is the name of the event that asks a client to move its tunnel to another server.
+// The payload of the event is the address the client should connect to; an empty address means
+// the address the client is already using, which reaches another node behind a load balancer.
+const ReconnectEvent = "reconnect"
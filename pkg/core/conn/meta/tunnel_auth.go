@@ -0,0 +1,225 @@
+package meta
+
+import (
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	// secretSeparator separates the token secret from the encoded ClientOptions in the password
+	// sent during the revdial handshake. Token secrets only contain letters and digits.
+	secretSeparator = "\n"
+
+	// sealIterations is the number of PBKDF2 iterations used to seal credentials.
+	sealIterations = 4096
+	// sealKeyLength is the length in bytes of a sealed credential hash.
+	sealKeyLength = 32
+)
+
+// TunnelAuth holds the credentials a client requires from end users of its tunnel.
+// Credentials are stored as hex encoded SHA-256 hashes, so the server never learns them.
+// Sealed credentials, the form published to other cluster nodes, additionally carry a random Salt,
+// and their hashes are derived from the SHA-256 hashes with PBKDF2.
+type TunnelAuth struct {
+	BasicAuth   string `json:"basic,omitempty"`
+	BearerToken string `json:"bearer,omitempty"` // #nosec G117 -- hash of the credential, not the credential itself
+	Salt        string `json:"salt,omitempty"`
+}
+
+// NewTunnelAuth creates a TunnelAuth from plain text credentials.
+// basicAuth is expected in the "user:pass" form; either argument may be empty to disable the scheme.
+// Returns nil if both arguments are empty.
+func NewTunnelAuth(basicAuth, bearerToken string) *TunnelAuth {
+	if basicAuth == "" && bearerToken == "" {
+		return nil
+	}
+
+	a := &TunnelAuth{}
+
+	if basicAuth != "" {
+		a.BasicAuth = hashCredential(basicAuth)
+	}
+
+	if bearerToken != "" {
+		a.BearerToken = hashCredential(bearerToken)
+	}
+
+	return a
+}
+
+// Enabled reports whether the tunnel requires any credentials.
+func (a *TunnelAuth) Enabled() bool {
+	return a != nil && (a.BasicAuth != "" || a.BearerToken != "")
+}
+
+// Allow reports whether the value of an Authorization header satisfies the tunnel credentials.
+// A tunnel without credentials allows every request.
+func (a *TunnelAuth) Allow(authorization string) bool {
+	if !a.Enabled() {
+		return true
+	}
+
+	scheme, cred, ok := strings.Cut(authorization, " ")
+	if !ok {
+		return false
+	}
+
+	switch {
+	case strings.EqualFold(scheme, "Basic") && a.BasicAuth != "":
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(cred))
+		if err != nil {
+			return false
+		}
+
+		return a.compare(a.BasicAuth, string(decoded))
+	case strings.EqualFold(scheme, "Bearer") && a.BearerToken != "":
+		return a.compare(a.BearerToken, strings.TrimSpace(cred))
+	default:
+		return false
+	}
+}
+
+// Seal returns a copy of the unsealed credentials a with a random salt, for publishing them where they may be read by others.
+// Returns nil if the tunnel requires no credentials, or an error if the hashes cannot be derived.
+func (a *TunnelAuth) Seal() (*TunnelAuth, error) {
+	if !a.Enabled() {
+		return nil, nil
+	}
+
+	sealed := &TunnelAuth{Salt: rand.Text()}
+
+	var err error
+
+	if a.BasicAuth != "" {
+		if sealed.BasicAuth, err = deriveHash(a.BasicAuth, sealed.Salt); err != nil {
+			return nil, err
+		}
+	}
+
+	if a.BearerToken != "" {
+		if sealed.BearerToken, err = deriveHash(a.BearerToken, sealed.Salt); err != nil {
+			return nil, err
+		}
+	}
+
+	return sealed, nil
+}
+
+// Matches reports whether a and other require the same credentials. other may be sealed, a must not be.
+func (a *TunnelAuth) Matches(other *TunnelAuth) bool {
+	if !a.Enabled() || !other.Enabled() {
+		return a.Enabled() == other.Enabled()
+	}
+
+	if a.Salt != "" {
+		return false
+	}
+
+	return a.matchHash(a.BasicAuth, other.BasicAuth, other.Salt) && a.matchHash(a.BearerToken, other.BearerToken, other.Salt)
+}
+
+// matchHash reports whether the unsealed hash equals other, sealed with salt unless salt is empty.
+func (a *TunnelAuth) matchHash(hash, other, salt string) bool {
+	if hash == "" || other == "" || salt == "" {
+		return hash == other
+	}
+
+	sealed, err := deriveHash(hash, salt)
+
+	return err == nil && subtle.ConstantTimeCompare([]byte(sealed), []byte(other)) == 1
+}
+
+// compare reports whether cred matches hash, in constant time. cred is sealed with the salt of a, if any.
+func (a *TunnelAuth) compare(hash, cred string) bool {
+	sum := hashCredential(cred)
+
+	if a.Salt != "" {
+		var err error
+		if sum, err = deriveHash(sum, a.Salt); err != nil {
+			return false
+		}
+	}
+
+	return subtle.ConstantTimeCompare([]byte(hash), []byte(sum)) == 1
+}
+
+// ClientOptions holds the settings a client sends to the server together with its token secret.
+// Auth is the tunnel credentials, and Port the public port a TCP client prefers; zero means any port.
+type ClientOptions struct {
+	Auth *TunnelAuth
+	Port int
+}
+
+// clientOptionsData is the encoded form of ClientOptions. The credentials are embedded, so that passwords
+// of clients that only protect their tunnel keep the format used before other options were added.
+type clientOptionsData struct {
+	*TunnelAuth
+	Port int `json:"port,omitempty"`
+}
+
+// EncodeSecret appends the encoded ClientOptions to the token secret, producing the password
+// sent by the client during the revdial handshake. The secret is returned unchanged if no options are set.
+// Returns an error if opts cannot be encoded.
+func EncodeSecret(secret string, opts ClientOptions) (string, error) {
+	data := clientOptionsData{Port: opts.Port}
+	if opts.Auth.Enabled() {
+		data.TunnelAuth = opts.Auth
+	}
+
+	if data.TunnelAuth == nil && data.Port == 0 {
+		return secret, nil
+	}
+
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal client options: %w", err)
+	}
+
+	return secret + secretSeparator + string(encoded), nil
+}
+
+// DecodeSecret splits a password produced by EncodeSecret into the token secret and the ClientOptions.
+// Passwords of clients that set no options are returned as the secret with empty ClientOptions.
+// Returns an error if the options part is malformed.
+func DecodeSecret(password string) (string, ClientOptions, error) {
+	secret, encoded, ok := strings.Cut(password, secretSeparator)
+	if !ok {
+		return password, ClientOptions{}, nil
+	}
+
+	var data clientOptionsData
+	if err := json.Unmarshal([]byte(encoded), &data); err != nil {
+		return "", ClientOptions{}, fmt.Errorf("failed to unmarshal client options: %w", err)
+	}
+
+	// Clients send plain SHA-256 hashes; a salt sent by a client would make its credentials unverifiable.
+	if data.TunnelAuth != nil {
+		data.Salt = ""
+	}
+
+	return secret, ClientOptions{Auth: data.TunnelAuth, Port: data.Port}, nil
+}
+
+// hashCredential returns the hex encoded SHA-256 hash of cred.
+func hashCredential(cred string) string {
+	sum := sha256.Sum256([]byte(cred))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// deriveHash returns the hex encoded PBKDF2 key derived from the credential hash and salt.
+func deriveHash(hash, salt string) (string, error) {
+	key, err := pbkdf2.Key(sha256.New, hash, []byte(salt), sealIterations, sealKeyLength)
+	if err != nil {
+		return "", fmt.Errorf("failed to seal credentials: %w", err)
+	}
+
+	return hex.EncodeToString(key), nil
+}
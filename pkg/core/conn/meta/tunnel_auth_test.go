@@ -0,0 +1,160 @@
+package meta
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTunnelAuth(t *testing.T) {
+	assert.Nil(t, NewTunnelAuth("", ""))
+
+	auth := NewTunnelAuth("user:pass", "token")
+
+	require.NotNil(t, auth)
+	assert.True(t, auth.Enabled())
+	assert.NotContains(t, auth.BasicAuth, "pass")
+	assert.NotEqual(t, "token", auth.BearerToken)
+}
+
+func TestTunnelAuth_Allow(t *testing.T) {
+	basic := "Basic " + base64.StdEncoding.EncodeToString([]byte("user:pass"))
+	wrongBasic := "Basic " + base64.StdEncoding.EncodeToString([]byte("user:wrong"))
+
+	tests := []struct {
+		auth          *TunnelAuth
+		name          string
+		authorization string
+		want          bool
+	}{
+		{name: "no auth", auth: nil, authorization: "", want: true},
+		{name: "basic valid", auth: NewTunnelAuth("user:pass", ""), authorization: basic, want: true},
+		{name: "basic lowercase scheme", auth: NewTunnelAuth("user:pass", ""), authorization: "basic " + basic[6:], want: true},
+		{name: "basic invalid", auth: NewTunnelAuth("user:pass", ""), authorization: wrongBasic, want: false},
+		{name: "basic malformed", auth: NewTunnelAuth("user:pass", ""), authorization: "Basic !!!", want: false},
+		{name: "missing header", auth: NewTunnelAuth("user:pass", ""), authorization: "", want: false},
+		{name: "bearer valid", auth: NewTunnelAuth("", "token"), authorization: "Bearer token", want: true},
+		{name: "bearer invalid", auth: NewTunnelAuth("", "token"), authorization: "Bearer other", want: false},
+		{name: "bearer not configured", auth: NewTunnelAuth("user:pass", ""), authorization: "Bearer token", want: false},
+		{name: "either scheme", auth: NewTunnelAuth("user:pass", "token"), authorization: "Bearer token", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.auth.Allow(tt.authorization))
+		})
+	}
+}
+
+func TestEncodeDecodeSecret(t *testing.T) {
+	opts := ClientOptions{Auth: NewTunnelAuth("user:pass", "token"), Port: 22022}
+
+	password, err := EncodeSecret("secret", opts)
+	require.NoError(t, err)
+
+	secret, decoded, err := DecodeSecret(password)
+	require.NoError(t, err)
+
+	assert.Equal(t, "secret", secret)
+	assert.Equal(t, opts, decoded)
+	assert.LessOrEqual(t, len(password), 255, "password must fit the revdial handshake")
+}
+
+func TestEncodeDecodeSecret_PortOnly(t *testing.T) {
+	password, err := EncodeSecret("secret", ClientOptions{Port: 2222})
+	require.NoError(t, err)
+
+	_, decoded, err := DecodeSecret(password)
+	require.NoError(t, err)
+
+	assert.Nil(t, decoded.Auth)
+	assert.Equal(t, 2222, decoded.Port)
+}
+
+func TestDecodeSecret_AuthOnlyFormat(t *testing.T) {
+	// Clients that only protect their tunnel send the credentials without any other options.
+	secret, decoded, err := DecodeSecret("secret\n{\"basic\":\"hash\"}")
+	require.NoError(t, err)
+
+	assert.Equal(t, "secret", secret)
+	assert.Equal(t, ClientOptions{Auth: &TunnelAuth{BasicAuth: "hash"}}, decoded)
+}
+
+func TestEncodeDecodeSecret_NoOptions(t *testing.T) {
+	password, err := EncodeSecret("secret", ClientOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "secret", password)
+
+	secret, opts, err := DecodeSecret(password)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", secret)
+	assert.Equal(t, ClientOptions{}, opts)
+}
+
+func TestDecodeSecret_Malformed(t *testing.T) {
+	_, _, err := DecodeSecret("secret\n{not json")
+
+	assert.Error(t, err)
+}
+
+func TestTunnelAuth_Seal(t *testing.T) {
+	auth := NewTunnelAuth("user:pass", "token")
+
+	sealed, err := auth.Seal()
+	require.NoError(t, err)
+
+	assert.NotEmpty(t, sealed.Salt)
+	assert.NotEqual(t, auth.BasicAuth, sealed.BasicAuth)
+	assert.NotEqual(t, auth.BearerToken, sealed.BearerToken)
+
+	// Every seal uses a new salt.
+	again, err := auth.Seal()
+	require.NoError(t, err)
+	assert.NotEqual(t, sealed.BearerToken, again.BearerToken)
+
+	assert.True(t, sealed.Allow("Bearer token"))
+	assert.True(t, sealed.Allow("Basic "+base64.StdEncoding.EncodeToString([]byte("user:pass"))))
+	assert.False(t, sealed.Allow("Bearer other"))
+
+	unprotected, err := (*TunnelAuth)(nil).Seal()
+	require.NoError(t, err)
+	assert.Nil(t, unprotected)
+}
+
+func TestTunnelAuth_Matches(t *testing.T) {
+	auth := NewTunnelAuth("", "token")
+
+	sealed, err := auth.Seal()
+	require.NoError(t, err)
+
+	tests := []struct {
+		auth  *TunnelAuth
+		other *TunnelAuth
+		name  string
+		want  bool
+	}{
+		{name: "both unprotected", auth: nil, other: &TunnelAuth{}, want: true},
+		{name: "same credentials", auth: auth, other: NewTunnelAuth("", "token"), want: true},
+		{name: "same sealed credentials", auth: auth, other: sealed, want: true},
+		{name: "other credentials", auth: auth, other: NewTunnelAuth("", "other"), want: false},
+		{name: "other sealed credentials", auth: NewTunnelAuth("", "other"), other: sealed, want: false},
+		{name: "additional scheme", auth: NewTunnelAuth("user:pass", "token"), other: sealed, want: false},
+		{name: "protected and unprotected", auth: auth, other: nil, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.auth.Matches(tt.other))
+		})
+	}
+}
+
+func TestDecodeSecret_DropsSalt(t *testing.T) {
+	_, decoded, err := DecodeSecret("secret\n{\"bearer\":\"hash\",\"salt\":\"x\"}")
+	require.NoError(t, err)
+
+	require.NotNil(t, decoded.Auth)
+	assert.Empty(t, decoded.Auth.Salt)
+}
@@ -3,8 +3,10 @@ package conn
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/ksysoev/make-it-public/pkg/metrics"
 )
 
 // Request represents an interface for managing connection requests and their lifecycle.
@@ -48,21 +50,34 @@ func (r *request) ParentContext() context.Context {
 
 // WaitConn waits for a network connection to be delivered through the request's channel or observes context cancellations.
 // It returns the established net.Conn if successful or an error if the provided context, parent context, or request is canceled.
+// The time spent waiting is recorded in metrics.ConnWaitDuration.
 func (r *request) WaitConn(ctx context.Context) (WithWriteCloser, error) {
+	start := time.Now()
+
 	select {
 	case <-ctx.Done():
+		observeWait(start, "canceled")
 		return nil, ctx.Err()
 	case <-r.ctx.Done():
+		observeWait(start, "canceled")
 		return nil, fmt.Errorf("parent context is canceled")
 	case conn, ok := <-r.ch:
 		if !ok {
+			observeWait(start, "canceled")
 			return nil, fmt.Errorf("request is canceled")
 		}
 
+		observeWait(start, "ok")
+
 		return conn, nil
 	}
 }
 
+// observeWait records the time elapsed since start in the connection wait histogram under the given result label.
+func observeWait(start time.Time, result string) {
+	metrics.ConnWaitDuration.WithLabelValues(result).Observe(time.Since(start).Seconds())
+}
+
 // SendConn delivers the provided connection to the request's channel, allowing it to be accessed by a waiting operation.
 // It returns immediately if the provided context or the parent context is done, ensuring no blocking occurs.
 // ctx represents the context to observe for cancellation or deadlines.
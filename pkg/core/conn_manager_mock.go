@@ -9,6 +9,8 @@ import (
 
 	conn "github.com/ksysoev/make-it-public/pkg/core/conn"
 
+	meta "github.com/ksysoev/make-it-public/pkg/core/conn/meta"
+
 	mock "github.com/stretchr/testify/mock"
 
 	uuid "github.com/google/uuid"
@@ -28,8 +30,21 @@ func (_m *MockConnManager) EXPECT() *MockConnManager_Expecter {
 }
 
 // AddConnection provides a mock function with given fields: keyID, _a1
-func (_m *MockConnManager) AddConnection(keyID string, _a1 ControlConn) {
-	_m.Called(keyID, _a1)
+func (_m *MockConnManager) AddConnection(keyID string, _a1 ControlConn) error {
+	ret := _m.Called(keyID, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddConnection")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, ControlConn) error); ok {
+		r0 = rf(keyID, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
 }
 
 // MockConnManager_AddConnection_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AddConnection'
@@ -51,13 +66,13 @@ func (_c *MockConnManager_AddConnection_Call) Run(run func(keyID string, _a1 Con
 	return _c
 }
 
-func (_c *MockConnManager_AddConnection_Call) Return() *MockConnManager_AddConnection_Call {
-	_c.Call.Return()
+func (_c *MockConnManager_AddConnection_Call) Return(_a0 error) *MockConnManager_AddConnection_Call {
+	_c.Call.Return(_a0)
 	return _c
 }
 
-func (_c *MockConnManager_AddConnection_Call) RunAndReturn(run func(string, ControlConn)) *MockConnManager_AddConnection_Call {
-	_c.Run(run)
+func (_c *MockConnManager_AddConnection_Call) RunAndReturn(run func(string, ControlConn) error) *MockConnManager_AddConnection_Call {
+	_c.Call.Return(run)
 	return _c
 }
 
@@ -221,6 +236,64 @@ func (_c *MockConnManager_ResolveRequest_Call) RunAndReturn(run func(uuid.UUID,
 	return _c
 }
 
+// TunnelAuth provides a mock function with given fields: keyID
+func (_m *MockConnManager) TunnelAuth(keyID string) (*meta.TunnelAuth, bool) {
+	ret := _m.Called(keyID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for TunnelAuth")
+	}
+
+	var r0 *meta.TunnelAuth
+	var r1 bool
+	if rf, ok := ret.Get(0).(func(string) (*meta.TunnelAuth, bool)); ok {
+		return rf(keyID)
+	}
+	if rf, ok := ret.Get(0).(func(string) *meta.TunnelAuth); ok {
+		r0 = rf(keyID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*meta.TunnelAuth)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) bool); ok {
+		r1 = rf(keyID)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	return r0, r1
+}
+
+// MockConnManager_TunnelAuth_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'TunnelAuth'
+type MockConnManager_TunnelAuth_Call struct {
+	*mock.Call
+}
+
+// TunnelAuth is a helper method to define mock.On call
+//   - keyID string
+func (_e *MockConnManager_Expecter) TunnelAuth(keyID interface{}) *MockConnManager_TunnelAuth_Call {
+	return &MockConnManager_TunnelAuth_Call{Call: _e.mock.On("TunnelAuth", keyID)}
+}
+
+func (_c *MockConnManager_TunnelAuth_Call) Run(run func(keyID string)) *MockConnManager_TunnelAuth_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string))
+	})
+	return _c
+}
+
+func (_c *MockConnManager_TunnelAuth_Call) Return(_a0 *meta.TunnelAuth, _a1 bool) *MockConnManager_TunnelAuth_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockConnManager_TunnelAuth_Call) RunAndReturn(run func(string) (*meta.TunnelAuth, bool)) *MockConnManager_TunnelAuth_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // NewMockConnManager creates a new instance of MockConnManager. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewMockConnManager(t interface {
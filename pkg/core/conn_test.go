@@ -14,6 +14,9 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/ksysoev/make-it-public/pkg/core/conn"
+	"github.com/ksysoev/make-it-public/pkg/core/conn/meta"
+	"github.com/ksysoev/make-it-public/pkg/core/ipfilter"
+	"github.com/ksysoev/make-it-public/pkg/core/token"
 	"github.com/ksysoev/revdial/proto"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -261,11 +264,14 @@ func TestPipeToDest_SuccessfulCopy(t *testing.T) {
 	dst.EXPECT().CloseWrite().Return(nil)
 
 	// Execute the function
-	pipeFunc := pipeToDest(t.Context(), src, dst)
+	var written int64
+
+	pipeFunc := pipeToDest(t.Context(), src, dst, &written)
 	err := pipeFunc()
 
 	// Verify results
 	assert.NoError(t, err)
+	assert.Equal(t, int64(len(testData)), written)
 }
 
 func TestPipeToDest_NetErrClosed(t *testing.T) {
@@ -279,7 +285,9 @@ func TestPipeToDest_NetErrClosed(t *testing.T) {
 	dst.EXPECT().Write(mock.Anything).Return(0, net.ErrClosed)
 
 	// Execute the function
-	pipeFunc := pipeToDest(t.Context(), src, dst)
+	var written int64
+
+	pipeFunc := pipeToDest(t.Context(), src, dst, &written)
 	err := pipeFunc()
 
 	// Verify results
@@ -297,7 +305,9 @@ func TestPipeToDest_EconnReset(t *testing.T) {
 	dst.EXPECT().Write(mock.Anything).Return(0, syscall.ECONNRESET)
 
 	// Execute the function
-	pipeFunc := pipeToDest(t.Context(), src, dst)
+	var written int64
+
+	pipeFunc := pipeToDest(t.Context(), src, dst, &written)
 	err := pipeFunc()
 
 	// Verify results
@@ -316,7 +326,9 @@ func TestPipeToDest_OtherCopyError(t *testing.T) {
 	dst.EXPECT().Write(mock.Anything).Return(0, customErr)
 
 	// Execute the function
-	pipeFunc := pipeToDest(t.Context(), src, dst)
+	var written int64
+
+	pipeFunc := pipeToDest(t.Context(), src, dst, &written)
 	err := pipeFunc()
 
 	// Verify results
@@ -340,7 +352,9 @@ func TestPipeToDest_CloseWriteError(t *testing.T) {
 	dst.EXPECT().CloseWrite().Return(closeErr)
 
 	// Execute the function
-	pipeFunc := pipeToDest(t.Context(), src, dst)
+	var written int64
+
+	pipeFunc := pipeToDest(t.Context(), src, dst, &written)
 	err := pipeFunc()
 
 	// Verify results
@@ -362,7 +376,9 @@ func TestPipeToDest_CloseWriteNetErrClosed(t *testing.T) {
 	dst.EXPECT().CloseWrite().Return(net.ErrClosed)
 
 	// Execute the function
-	pipeFunc := pipeToDest(t.Context(), src, dst)
+	var written int64
+
+	pipeFunc := pipeToDest(t.Context(), src, dst, &written)
 	err := pipeFunc()
 
 	// Verify results
@@ -777,6 +793,7 @@ func TestHandleTCPConnection_KeyNotFound_NoActiveConn(t *testing.T) {
 
 	err := service.HandleTCPConnection(ctx, "test-user", clientConn, "127.0.0.1")
 	require.ErrorIs(t, err, ErrFailedToConnect)
+	assert.ErrorIs(t, err, ErrTunnelOffline)
 }
 
 func TestHandleTCPConnection_KeyNotFound_KeyDoesNotExist(t *testing.T) {
@@ -842,6 +859,102 @@ func TestHandleTCPConnection_WaitConnCancellation(t *testing.T) {
 	require.ErrorIs(t, err, ErrFailedToConnect)
 }
 
+// --- HandleGRPCConnection tests ---
+
+func TestHandleGRPCConnection_NotEnabled(t *testing.T) {
+	authRepo := NewMockAuthRepo(t)
+	authRepo.EXPECT().IsKeyExists(mock.Anything, "test-user").Return(false, nil)
+
+	service := New(NewMockConnManager(t), NewMockConnManager(t), authRepo)
+	clientConn := conn.NewMockWithWriteCloser(t)
+	clientConn.EXPECT().RemoteAddr().Return(&net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 9000})
+
+	err := service.HandleGRPCConnection(t.Context(), "test-user", clientConn, "127.0.0.1")
+	require.ErrorIs(t, err, ErrKeyIDNotFound)
+}
+
+func TestHandleGRPCConnection_ForwardsToClusterNode(t *testing.T) {
+	grpcConnMng := NewMockConnManager(t)
+	grpcConnMng.EXPECT().RequestConnection(mock.Anything, "test-user").Return(nil, ErrKeyIDNotFound)
+
+	registry := NewMockConnRegistry(t)
+	registry.EXPECT().Forward(mock.Anything, "test-user", token.TokenTypeGRPC, "127.0.0.1").Return(nil, ErrKeyIDNotFound)
+
+	authRepo := NewMockAuthRepo(t)
+	authRepo.EXPECT().IsKeyExists(mock.Anything, "test-user").Return(true, nil)
+
+	// Web and TCP connection managers must not be used for gRPC connections.
+	service := New(NewMockConnManager(t), NewMockConnManager(t), authRepo)
+	service.SetGRPCConnManager(grpcConnMng)
+	service.SetConnRegistry(registry)
+
+	clientConn := conn.NewMockWithWriteCloser(t)
+	clientConn.EXPECT().RemoteAddr().Return(&net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 9000})
+
+	err := service.HandleGRPCConnection(t.Context(), "test-user", clientConn, "127.0.0.1")
+	require.ErrorIs(t, err, ErrFailedToConnect)
+}
+
+// --- HandleTLSConnection tests ---
+
+func TestHandleTLSConnection_ForwardsToClusterNode(t *testing.T) {
+	tlsConnMng := NewMockConnManager(t)
+	tlsConnMng.EXPECT().RequestConnection(mock.Anything, "test-user").Return(nil, ErrKeyIDNotFound)
+
+	registry := NewMockConnRegistry(t)
+	registry.EXPECT().Forward(mock.Anything, "test-user", token.TokenTypeTLS, "127.0.0.1").Return(nil, ErrKeyIDNotFound)
+
+	authRepo := NewMockAuthRepo(t)
+	authRepo.EXPECT().IsKeyExists(mock.Anything, "test-user").Return(false, nil)
+
+	// Web and TCP connection managers must not be used for TLS passthrough connections.
+	service := New(NewMockConnManager(t), NewMockConnManager(t), authRepo)
+	service.SetTLSConnManager(tlsConnMng)
+	service.SetConnRegistry(registry)
+
+	clientConn := conn.NewMockWithWriteCloser(t)
+	clientConn.EXPECT().RemoteAddr().Return(&net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 9000})
+
+	err := service.HandleTLSConnection(t.Context(), "test-user", clientConn, "127.0.0.1")
+	require.ErrorIs(t, err, ErrKeyIDNotFound)
+}
+
+// --- HandleUDPSession tests ---
+
+func TestHandleUDPSession_NotEnabled(t *testing.T) {
+	authRepo := NewMockAuthRepo(t)
+	authRepo.EXPECT().IsKeyExists(mock.Anything, "test-user").Return(false, nil)
+
+	service := New(NewMockConnManager(t), NewMockConnManager(t), authRepo)
+	clientConn := conn.NewMockWithWriteCloser(t)
+	clientConn.EXPECT().RemoteAddr().Return(&net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 9000})
+
+	err := service.HandleUDPSession(t.Context(), "test-user", clientConn, "127.0.0.1")
+	require.ErrorIs(t, err, ErrKeyIDNotFound)
+}
+
+func TestHandleUDPSession_ForwardsToClusterNode(t *testing.T) {
+	udpConnMng := NewMockConnManager(t)
+	udpConnMng.EXPECT().RequestConnection(mock.Anything, "test-user").Return(nil, ErrKeyIDNotFound)
+
+	registry := NewMockConnRegistry(t)
+	registry.EXPECT().Forward(mock.Anything, "test-user", token.TokenTypeUDP, "127.0.0.1").Return(nil, ErrKeyIDNotFound)
+
+	authRepo := NewMockAuthRepo(t)
+	authRepo.EXPECT().IsKeyExists(mock.Anything, "test-user").Return(true, nil)
+
+	// Web and TCP connection managers must not be used for UDP sessions.
+	service := New(NewMockConnManager(t), NewMockConnManager(t), authRepo)
+	service.SetUDPConnManager(udpConnMng)
+	service.SetConnRegistry(registry)
+
+	clientConn := conn.NewMockWithWriteCloser(t)
+	clientConn.EXPECT().RemoteAddr().Return(&net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 9000})
+
+	err := service.HandleUDPSession(t.Context(), "test-user", clientConn, "127.0.0.1")
+	require.ErrorIs(t, err, ErrFailedToConnect)
+}
+
 func TestHandleTCPConnection_MetaWriteFailure(t *testing.T) {
 	webConnMng := NewMockConnManager(t)
 	tcpConnMng := NewMockConnManager(t)
@@ -1019,3 +1132,359 @@ func TestAcceptV2Streams_ContextCancellation(t *testing.T) {
 		t.Fatal("acceptV2Streams did not return after context cancellation")
 	}
 }
+
+func TestHandleHTTPConnection_ForwardedToClusterNode(t *testing.T) {
+	webConnMng := NewMockConnManager(t)
+	tcpConnMng := NewMockConnManager(t)
+	authRepo := NewMockAuthRepo(t)
+	registry := NewMockConnRegistry(t)
+
+	fwdServer, fwdClient := net.Pipe()
+	cliServer, cliClient := net.Pipe()
+
+	webConnMng.EXPECT().RequestConnection(mock.Anything, "test-user").Return(nil, ErrKeyIDNotFound)
+	registry.EXPECT().Forward(mock.Anything, "test-user", token.TokenTypeWeb, "127.0.0.1").Return(&yamuxStreamWrapper{Conn: fwdServer}, nil)
+
+	service := New(webConnMng, tcpConnMng, authRepo)
+	service.SetConnRegistry(registry)
+
+	// Remote node: read the initial request and reply.
+	go func() {
+		buf := make([]byte, len("request"))
+		if _, err := io.ReadFull(fwdClient, buf); err != nil {
+			return
+		}
+
+		_, _ = fwdClient.Write([]byte("response"))
+		_ = fwdClient.Close()
+	}()
+
+	received := make(chan []byte, 1)
+
+	go func() {
+		data, _ := io.ReadAll(cliClient)
+		received <- data
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	err := service.HandleHTTPConnection(ctx, "test-user", cliServer, func(c net.Conn) error {
+		_, err := c.Write([]byte("request"))
+		return err
+	}, "127.0.0.1")
+
+	require.NoError(t, err)
+
+	_ = cliServer.Close()
+
+	assert.Equal(t, []byte("response"), <-received)
+}
+
+func TestHandleHTTPConnection_ForwardFailsFallsBackToLocalCheck(t *testing.T) {
+	webConnMng := NewMockConnManager(t)
+	tcpConnMng := NewMockConnManager(t)
+	authRepo := NewMockAuthRepo(t)
+	registry := NewMockConnRegistry(t)
+
+	webConnMng.EXPECT().RequestConnection(mock.Anything, "test-user").Return(nil, ErrKeyIDNotFound)
+	registry.EXPECT().Forward(mock.Anything, "test-user", token.TokenTypeWeb, "127.0.0.1").Return(nil, ErrKeyIDNotFound)
+	authRepo.EXPECT().IsKeyExists(mock.Anything, "test-user").Return(false, nil)
+
+	service := New(webConnMng, tcpConnMng, authRepo)
+	service.SetConnRegistry(registry)
+
+	clientConn := conn.NewMockWithWriteCloser(t)
+	clientConn.EXPECT().RemoteAddr().Return(&net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 8080})
+
+	err := service.HandleHTTPConnection(context.Background(), "test-user", clientConn, func(net.Conn) error { return nil }, "127.0.0.1")
+
+	assert.ErrorIs(t, err, ErrKeyIDNotFound)
+}
+
+func TestHandleHTTPConnection_TunnelOffline(t *testing.T) {
+	webConnMng := NewMockConnManager(t)
+	authRepo := NewMockAuthRepo(t)
+
+	webConnMng.EXPECT().RequestConnection(mock.Anything, "test-user").Return(nil, ErrKeyIDNotFound)
+	authRepo.EXPECT().IsKeyExists(mock.Anything, "test-user").Return(true, nil)
+
+	service := New(webConnMng, NewMockConnManager(t), authRepo)
+
+	clientConn := conn.NewMockWithWriteCloser(t)
+	clientConn.EXPECT().RemoteAddr().Return(&net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 8080})
+
+	err := service.HandleHTTPConnection(context.Background(), "test-user", clientConn, func(net.Conn) error { return nil }, "127.0.0.1")
+
+	assert.ErrorIs(t, err, ErrTunnelOffline)
+	assert.ErrorIs(t, err, ErrFailedToConnect)
+}
+
+func TestHandleForwardedConn_NotForwardedAgain(t *testing.T) {
+	webConnMng := NewMockConnManager(t)
+	tcpConnMng := NewMockConnManager(t)
+	authRepo := NewMockAuthRepo(t)
+	registry := NewMockConnRegistry(t)
+
+	// The registry must not be consulted for connections that were already forwarded.
+	tcpConnMng.EXPECT().RequestConnection(mock.Anything, "test-user").Return(nil, ErrKeyIDNotFound)
+	authRepo.EXPECT().IsKeyExists(mock.Anything, "test-user").Return(true, nil)
+	authRepo.EXPECT().IPFilter(mock.Anything, "test-user").Return(nil, nil)
+
+	service := New(webConnMng, tcpConnMng, authRepo)
+	service.SetConnRegistry(registry)
+
+	clientConn := conn.NewMockWithWriteCloser(t)
+	clientConn.EXPECT().RemoteAddr().Return(&net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 8080})
+
+	err := service.HandleForwardedConn(context.Background(), "test-user", token.TokenTypeTCP, clientConn, "127.0.0.1")
+
+	assert.ErrorIs(t, err, ErrFailedToConnect)
+}
+
+func TestHandleForwardedConn_GRPC(t *testing.T) {
+	grpcConnMng := NewMockConnManager(t)
+	grpcConnMng.EXPECT().RequestConnection(mock.Anything, "test-user").Return(nil, ErrKeyIDNotFound)
+
+	authRepo := NewMockAuthRepo(t)
+	authRepo.EXPECT().IsKeyExists(mock.Anything, "test-user").Return(true, nil)
+	authRepo.EXPECT().IPFilter(mock.Anything, "test-user").Return(nil, nil)
+
+	service := New(NewMockConnManager(t), NewMockConnManager(t), authRepo)
+	service.SetGRPCConnManager(grpcConnMng)
+	service.SetConnRegistry(NewMockConnRegistry(t))
+
+	clientConn := conn.NewMockWithWriteCloser(t)
+	clientConn.EXPECT().RemoteAddr().Return(&net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 8080})
+
+	err := service.HandleForwardedConn(context.Background(), "test-user", token.TokenTypeGRPC, clientConn, "127.0.0.1")
+
+	assert.ErrorIs(t, err, ErrFailedToConnect)
+}
+
+func TestHandleForwardedConn_UDP(t *testing.T) {
+	udpConnMng := NewMockConnManager(t)
+	udpConnMng.EXPECT().RequestConnection(mock.Anything, "test-user").Return(nil, ErrKeyIDNotFound)
+
+	authRepo := NewMockAuthRepo(t)
+	authRepo.EXPECT().IsKeyExists(mock.Anything, "test-user").Return(true, nil)
+	authRepo.EXPECT().IPFilter(mock.Anything, "test-user").Return(nil, nil)
+
+	service := New(NewMockConnManager(t), NewMockConnManager(t), authRepo)
+	service.SetUDPConnManager(udpConnMng)
+	service.SetConnRegistry(NewMockConnRegistry(t))
+
+	clientConn := conn.NewMockWithWriteCloser(t)
+	clientConn.EXPECT().RemoteAddr().Return(&net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 8080})
+
+	err := service.HandleForwardedConn(context.Background(), "test-user", token.TokenTypeUDP, clientConn, "127.0.0.1")
+
+	assert.ErrorIs(t, err, ErrFailedToConnect)
+}
+
+func TestHandleForwardedConn_TLS(t *testing.T) {
+	tlsConnMng := NewMockConnManager(t)
+	tlsConnMng.EXPECT().RequestConnection(mock.Anything, "test-user").Return(nil, ErrKeyIDNotFound)
+
+	authRepo := NewMockAuthRepo(t)
+	authRepo.EXPECT().IsKeyExists(mock.Anything, "test-user").Return(true, nil)
+	authRepo.EXPECT().IPFilter(mock.Anything, "test-user").Return(nil, nil)
+
+	service := New(NewMockConnManager(t), NewMockConnManager(t), authRepo)
+	service.SetTLSConnManager(tlsConnMng)
+	service.SetConnRegistry(NewMockConnRegistry(t))
+
+	clientConn := conn.NewMockWithWriteCloser(t)
+	clientConn.EXPECT().RemoteAddr().Return(&net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 8080})
+
+	err := service.HandleForwardedConn(context.Background(), "test-user", token.TokenTypeTLS, clientConn, "127.0.0.1")
+
+	assert.ErrorIs(t, err, ErrFailedToConnect)
+}
+
+func TestHandleForwardedConn_ForbiddenClientIP(t *testing.T) {
+	filter, err := ipfilter.New([]string{"10.0.0.0/8"}, nil)
+	require.NoError(t, err)
+
+	// The forwarding node is not trusted to have applied the filter, so the connection is never requested.
+	authRepo := NewMockAuthRepo(t)
+	authRepo.EXPECT().IPFilter(mock.Anything, "test-user").Return(filter, nil)
+
+	service := New(NewMockConnManager(t), NewMockConnManager(t), authRepo)
+
+	err = service.HandleForwardedConn(context.Background(), "test-user", token.TokenTypeTCP, conn.NewMockWithWriteCloser(t), "192.0.2.1")
+
+	assert.ErrorIs(t, err, ErrForbidden)
+}
+
+func TestHandleForwardedConn_UnknownType(t *testing.T) {
+	authRepo := NewMockAuthRepo(t)
+	authRepo.EXPECT().IPFilter(mock.Anything, "test-user").Return(nil, nil)
+
+	service := New(NewMockConnManager(t), NewMockConnManager(t), authRepo)
+
+	err := service.HandleForwardedConn(context.Background(), "test-user", token.TokenType("x"), conn.NewMockWithWriteCloser(t), "127.0.0.1")
+
+	assert.ErrorContains(t, err, "unsupported token type")
+}
+
+func TestAuthorizeHTTP(t *testing.T) {
+	protected := meta.NewTunnelAuth("", "token")
+
+	// Other nodes publish sealed credentials.
+	sealed, err := protected.Seal()
+	require.NoError(t, err)
+
+	tests := []struct {
+		localAuth     *meta.TunnelAuth
+		remoteAuth    *meta.TunnelAuth
+		remoteErr     error
+		wantErr       error
+		name          string
+		authorization string
+		local         bool
+		wantProtected bool
+	}{
+		{name: "local unprotected", local: true, localAuth: nil, authorization: "Bearer app"},
+		{name: "local valid credentials", local: true, localAuth: protected, authorization: "Bearer token", wantProtected: true},
+		{name: "local invalid credentials", local: true, localAuth: protected, authorization: "Bearer wrong", wantErr: ErrUnauthorized},
+		{name: "remote invalid credentials", remoteAuth: sealed, wantErr: ErrUnauthorized},
+		{name: "remote valid credentials", remoteAuth: sealed, authorization: "Bearer token", wantProtected: true},
+		{name: "unknown keyID", remoteErr: ErrKeyIDNotFound},
+		{name: "registry failure", remoteErr: assert.AnError, wantErr: ErrFailedToConnect},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			connManager := NewMockConnManager(t)
+			registry := NewMockConnRegistry(t)
+
+			connManager.EXPECT().TunnelAuth("key").Return(tt.localAuth, tt.local)
+
+			if !tt.local {
+				registry.EXPECT().TunnelAuth(mock.Anything, "key").Return(tt.remoteAuth, tt.remoteErr)
+			}
+
+			service := New(connManager, connManager, NewMockAuthRepo(t))
+			service.SetConnRegistry(registry)
+
+			isProtected, err := service.AuthorizeHTTP(context.Background(), "key", tt.authorization)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.wantProtected, isProtected)
+			}
+		})
+	}
+}
+
+func TestCheckClientIP(t *testing.T) {
+	filter, err := ipfilter.New([]string{"10.0.0.0/8"}, nil)
+	require.NoError(t, err)
+
+	tests := []struct {
+		filter   *ipfilter.Filter
+		repoErr  error
+		wantErr  error
+		name     string
+		clientIP string
+	}{
+		{name: "no filter", clientIP: "192.168.0.1"},
+		{name: "allowed address", filter: filter, clientIP: "10.0.0.1"},
+		{name: "forbidden address", filter: filter, clientIP: "192.168.0.1", wantErr: ErrForbidden},
+		{name: "repo failure", repoErr: assert.AnError, clientIP: "10.0.0.1", wantErr: ErrFailedToConnect},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authRepo := NewMockAuthRepo(t)
+			authRepo.EXPECT().IPFilter(mock.Anything, "key").Return(tt.filter, tt.repoErr)
+
+			service := New(NewMockConnManager(t), NewMockConnManager(t), authRepo)
+
+			err := service.CheckClientIP(context.Background(), "key", tt.clientIP)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestAllocateTCPEndpoint(t *testing.T) {
+	tests := []struct {
+		setup     func(auth *MockAuthRepo, alloc *MockTCPEndpointAllocator)
+		wantErr   error
+		name      string
+		want      string
+		preferred int
+	}{
+		{
+			name:      "reserved port is always used",
+			preferred: 30001,
+			setup: func(auth *MockAuthRepo, alloc *MockTCPEndpointAllocator) {
+				auth.EXPECT().ReservedPort(mock.Anything, "key1").Return(30000, nil)
+				alloc.EXPECT().Allocate(mock.Anything, "key1", 30000).Return("example.com:30000", nil)
+			},
+			want: "example.com:30000",
+		},
+		{
+			name: "reserved port unavailable",
+			setup: func(auth *MockAuthRepo, alloc *MockTCPEndpointAllocator) {
+				auth.EXPECT().ReservedPort(mock.Anything, "key1").Return(30000, nil)
+				alloc.EXPECT().Allocate(mock.Anything, "key1", 30000).Return("", ErrPortUnavailable)
+			},
+			wantErr: ErrPortUnavailable,
+		},
+		{
+			name:      "preferred port",
+			preferred: 30001,
+			setup: func(auth *MockAuthRepo, alloc *MockTCPEndpointAllocator) {
+				auth.EXPECT().ReservedPort(mock.Anything, "key1").Return(0, nil)
+				alloc.EXPECT().Allocate(mock.Anything, "key1", 30001).Return("example.com:30001", nil)
+			},
+			want: "example.com:30001",
+		},
+		{
+			name:      "preferred port unavailable falls back to any port",
+			preferred: 30001,
+			setup: func(auth *MockAuthRepo, alloc *MockTCPEndpointAllocator) {
+				auth.EXPECT().ReservedPort(mock.Anything, "key1").Return(0, nil)
+				alloc.EXPECT().Allocate(mock.Anything, "key1", 30001).Return("", ErrPortUnavailable)
+				alloc.EXPECT().Allocate(mock.Anything, "key1", 0).Return("example.com:30005", nil)
+			},
+			want: "example.com:30005",
+		},
+		{
+			name: "repository error",
+			setup: func(auth *MockAuthRepo, _ *MockTCPEndpointAllocator) {
+				auth.EXPECT().ReservedPort(mock.Anything, "key1").Return(0, assert.AnError)
+			},
+			wantErr: assert.AnError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authRepo := NewMockAuthRepo(t)
+			alloc := NewMockTCPEndpointAllocator(t)
+			tt.setup(authRepo, alloc)
+
+			service := New(nil, nil, authRepo)
+			service.SetTCPEndpointAllocator(alloc)
+
+			got, err := service.allocateTCPEndpoint(context.Background(), "key1", tt.preferred)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
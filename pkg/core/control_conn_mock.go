@@ -9,6 +9,8 @@ import (
 
 	conn "github.com/ksysoev/make-it-public/pkg/core/conn"
 
+	meta "github.com/ksysoev/make-it-public/pkg/core/conn/meta"
+
 	mock "github.com/stretchr/testify/mock"
 
 	uuid "github.com/google/uuid"
@@ -27,6 +29,53 @@ func (_m *MockControlConn) EXPECT() *MockControlConn_Expecter {
 	return &MockControlConn_Expecter{mock: &_m.Mock}
 }
 
+// Auth provides a mock function with no fields
+func (_m *MockControlConn) Auth() *meta.TunnelAuth {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for Auth")
+	}
+
+	var r0 *meta.TunnelAuth
+	if rf, ok := ret.Get(0).(func() *meta.TunnelAuth); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*meta.TunnelAuth)
+		}
+	}
+
+	return r0
+}
+
+// MockControlConn_Auth_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Auth'
+type MockControlConn_Auth_Call struct {
+	*mock.Call
+}
+
+// Auth is a helper method to define mock.On call
+func (_e *MockControlConn_Expecter) Auth() *MockControlConn_Auth_Call {
+	return &MockControlConn_Auth_Call{Call: _e.mock.On("Auth")}
+}
+
+func (_c *MockControlConn_Auth_Call) Run(run func()) *MockControlConn_Auth_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockControlConn_Auth_Call) Return(_a0 *meta.TunnelAuth) *MockControlConn_Auth_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockControlConn_Auth_Call) RunAndReturn(run func() *meta.TunnelAuth) *MockControlConn_Auth_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Close provides a mock function with no fields
 func (_m *MockControlConn) Close() error {
 	ret := _m.Called()
@@ -166,19 +215,15 @@ func (_c *MockControlConn_ID_Call) RunAndReturn(run func() uuid.UUID) *MockContr
 	return _c
 }
 
-// RequestConnection provides a mock function with no fields
-func (_m *MockControlConn) RequestConnection() (conn.Request, error) {
+// NewRequest provides a mock function with no fields
+func (_m *MockControlConn) NewRequest() conn.Request {
 	ret := _m.Called()
 
 	if len(ret) == 0 {
-		panic("no return value specified for RequestConnection")
+		panic("no return value specified for NewRequest")
 	}
 
 	var r0 conn.Request
-	var r1 error
-	if rf, ok := ret.Get(0).(func() (conn.Request, error)); ok {
-		return rf()
-	}
 	if rf, ok := ret.Get(0).(func() conn.Request); ok {
 		r0 = rf()
 	} else {
@@ -187,13 +232,52 @@ func (_m *MockControlConn) RequestConnection() (conn.Request, error) {
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func() error); ok {
-		r1 = rf()
+	return r0
+}
+
+// MockControlConn_NewRequest_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'NewRequest'
+type MockControlConn_NewRequest_Call struct {
+	*mock.Call
+}
+
+// NewRequest is a helper method to define mock.On call
+func (_e *MockControlConn_Expecter) NewRequest() *MockControlConn_NewRequest_Call {
+	return &MockControlConn_NewRequest_Call{Call: _e.mock.On("NewRequest")}
+}
+
+func (_c *MockControlConn_NewRequest_Call) Run(run func()) *MockControlConn_NewRequest_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockControlConn_NewRequest_Call) Return(_a0 conn.Request) *MockControlConn_NewRequest_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockControlConn_NewRequest_Call) RunAndReturn(run func() conn.Request) *MockControlConn_NewRequest_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RequestConnection provides a mock function with given fields: req
+func (_m *MockControlConn) RequestConnection(req conn.Request) error {
+	ret := _m.Called(req)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RequestConnection")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(conn.Request) error); ok {
+		r0 = rf(req)
 	} else {
-		r1 = ret.Error(1)
+		r0 = ret.Error(0)
 	}
 
-	return r0, r1
+	return r0
 }
 
 // MockControlConn_RequestConnection_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RequestConnection'
@@ -202,23 +286,24 @@ type MockControlConn_RequestConnection_Call struct {
 }
 
 // RequestConnection is a helper method to define mock.On call
-func (_e *MockControlConn_Expecter) RequestConnection() *MockControlConn_RequestConnection_Call {
-	return &MockControlConn_RequestConnection_Call{Call: _e.mock.On("RequestConnection")}
+//   - req conn.Request
+func (_e *MockControlConn_Expecter) RequestConnection(req interface{}) *MockControlConn_RequestConnection_Call {
+	return &MockControlConn_RequestConnection_Call{Call: _e.mock.On("RequestConnection", req)}
 }
 
-func (_c *MockControlConn_RequestConnection_Call) Run(run func()) *MockControlConn_RequestConnection_Call {
+func (_c *MockControlConn_RequestConnection_Call) Run(run func(req conn.Request)) *MockControlConn_RequestConnection_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run()
+		run(args[0].(conn.Request))
 	})
 	return _c
 }
 
-func (_c *MockControlConn_RequestConnection_Call) Return(_a0 conn.Request, _a1 error) *MockControlConn_RequestConnection_Call {
-	_c.Call.Return(_a0, _a1)
+func (_c *MockControlConn_RequestConnection_Call) Return(_a0 error) *MockControlConn_RequestConnection_Call {
+	_c.Call.Return(_a0)
 	return _c
 }
 
-func (_c *MockControlConn_RequestConnection_Call) RunAndReturn(run func() (conn.Request, error)) *MockControlConn_RequestConnection_Call {
+func (_c *MockControlConn_RequestConnection_Call) RunAndReturn(run func(conn.Request) error) *MockControlConn_RequestConnection_Call {
 	_c.Call.Return(run)
 	return _c
 }
@@ -0,0 +1,143 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/ksysoev/make-it-public/pkg/core/domain"
+)
+
+var (
+	ErrDomainNotFound    = errors.New("domain not found")
+	ErrDuplicateDomain   = errors.New("domain is already registered")
+	ErrDomainNotVerified = errors.New("domain ownership is not verified")
+)
+
+// TXTResolver looks up the TXT records of a DNS name. *net.Resolver satisfies it.
+type TXTResolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+// SetTXTResolver sets the resolver used to check the ownership challenges of custom domains.
+// It defaults to net.DefaultResolver.
+func (s *Service) SetTXTResolver(resolver TXTResolver) {
+	s.txtResolver = resolver
+}
+
+// AddDomain registers the custom domain name for the token identified by keyID.
+// The domain is not routed to the tunnel until VerifyDomain confirms its ownership, and other tokens can claim it
+// as well until then.
+// Returns the registered domain with its ownership challenge.
+// Returns domain.ErrInvalidDomain if name is not a valid host name, ErrTokenNotFound if the token does not exist,
+// or ErrDuplicateDomain if the token already registered the domain or another token verified it.
+func (s *Service) AddDomain(ctx context.Context, keyID, name string) (*domain.Domain, error) {
+	d, err := domain.New(name, keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.auth.AddDomain(ctx, d); err != nil {
+		return nil, fmt.Errorf("failed to add domain: %w", err)
+	}
+
+	return d, nil
+}
+
+// VerifyDomain checks that the challenge of the domain name is published in its TXT record
+// and marks the domain as verified.
+// Returns the verified domain. Domains that are already verified are returned as they are.
+// Returns ErrDomainNotFound if the domain is not registered for keyID, ErrDomainNotVerified if
+// the challenge is not found, or ErrDuplicateDomain if another token verified the domain first.
+func (s *Service) VerifyDomain(ctx context.Context, keyID, name string) (*domain.Domain, error) {
+	d, err := s.getDomain(ctx, keyID, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.Verified {
+		return d, nil
+	}
+
+	records, err := s.txtResolver.LookupTXT(ctx, d.ChallengeRecord())
+	if err != nil {
+		slog.DebugContext(ctx, "failed to look up domain challenge", slog.Any("error", err), slog.String("domain", d.Name))
+		return nil, fmt.Errorf("failed to look up %s: %w", d.ChallengeRecord(), ErrDomainNotVerified)
+	}
+
+	if !slices.Contains(records, d.Challenge) {
+		return nil, fmt.Errorf("challenge not found in %s: %w", d.ChallengeRecord(), ErrDomainNotVerified)
+	}
+
+	d.Verified = true
+
+	if err := s.auth.UpdateDomain(ctx, d); err != nil {
+		return nil, fmt.Errorf("failed to update domain: %w", err)
+	}
+
+	return d, nil
+}
+
+// ListDomains returns the custom domains registered for the token identified by keyID.
+func (s *Service) ListDomains(ctx context.Context, keyID string) ([]*domain.Domain, error) {
+	domains, err := s.auth.ListDomains(ctx, keyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list domains: %w", err)
+	}
+
+	return domains, nil
+}
+
+// RemoveDomain removes the custom domain name from the token identified by keyID.
+// Returns ErrDomainNotFound if the domain is not registered for the token.
+func (s *Service) RemoveDomain(ctx context.Context, keyID, name string) error {
+	name, err := domain.Normalize(name)
+	if err != nil {
+		return fmt.Errorf("failed to remove domain: %w", ErrDomainNotFound)
+	}
+
+	if err := s.auth.RemoveDomain(ctx, keyID, name); err != nil {
+		return fmt.Errorf("failed to remove domain: %w", err)
+	}
+
+	return nil
+}
+
+// ResolveDomain returns the keyID of the tunnel serving the custom domain host.
+// It returns an empty keyID if host is not a verified custom domain.
+func (s *Service) ResolveDomain(ctx context.Context, host string) (string, error) {
+	name, err := domain.Normalize(host)
+	if err != nil {
+		return "", nil
+	}
+
+	d, err := s.auth.GetDomain(ctx, name)
+
+	switch {
+	case errors.Is(err, ErrDomainNotFound):
+		return "", nil
+	case err != nil:
+		return "", fmt.Errorf("failed to resolve domain: %w", err)
+	case !d.Verified:
+		return "", nil
+	}
+
+	return d.KeyID, nil
+}
+
+// getDomain returns the domain name if it is registered for keyID, or ErrDomainNotFound otherwise.
+func (s *Service) getDomain(ctx context.Context, keyID, name string) (*domain.Domain, error) {
+	name, err := domain.Normalize(name)
+	if err != nil {
+		return nil, ErrDomainNotFound
+	}
+
+	d, err := s.auth.TokenDomain(ctx, keyID, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get domain: %w", err)
+	}
+
+	return d, nil
+}
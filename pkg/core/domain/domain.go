@@ -0,0 +1,86 @@
+// Package domain describes custom domains that users point at their tunnels.
+package domain
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// ChallengePrefix is prepended to a domain to build the name of its ownership TXT record.
+	ChallengePrefix = "_mit-challenge."
+
+	challengeLength = 16
+	maxNameLength   = 253
+)
+
+var (
+	ErrInvalidDomain = fmt.Errorf("invalid domain name")
+
+	labelRe = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+)
+
+// Domain is a custom domain mapped to the tunnel of a token.
+// Requests for the domain are routed to the tunnel only once its ownership is Verified
+// by publishing Challenge in the TXT record returned by ChallengeRecord.
+type Domain struct {
+	CreatedAt time.Time `json:"created_at"`
+	Name      string    `json:"name"`
+	KeyID     string    `json:"key_id"`
+	Challenge string    `json:"challenge"`
+	Verified  bool      `json:"verified"`
+}
+
+// New creates an unverified Domain for keyID with a random ownership challenge.
+// The name is normalized to lower case without a trailing dot.
+// Returns ErrInvalidDomain if the name is not a valid multi-label host name.
+func New(name, keyID string) (*Domain, error) {
+	name, err := Normalize(name)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, challengeLength)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("failed to generate challenge: %w", err)
+	}
+
+	return &Domain{
+		Name:      name,
+		KeyID:     keyID,
+		Challenge: hex.EncodeToString(buf),
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+// Normalize lower-cases name, strips a trailing dot, and validates it as a host name with at least two labels.
+// Returns ErrInvalidDomain if the name is not valid.
+func Normalize(name string) (string, error) {
+	name = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), ".")
+
+	if name == "" || len(name) > maxNameLength {
+		return "", fmt.Errorf("%w: %q", ErrInvalidDomain, name)
+	}
+
+	labels := strings.Split(name, ".")
+	if len(labels) < 2 {
+		return "", fmt.Errorf("%w: %q", ErrInvalidDomain, name)
+	}
+
+	for _, l := range labels {
+		if !labelRe.MatchString(l) {
+			return "", fmt.Errorf("%w: %q", ErrInvalidDomain, name)
+		}
+	}
+
+	return name, nil
+}
+
+// ChallengeRecord returns the name of the TXT record that must contain the challenge.
+func (d *Domain) ChallengeRecord() string {
+	return ChallengePrefix + d.Name
+}
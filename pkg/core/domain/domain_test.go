@@ -0,0 +1,55 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	d, err := New("App.Example.com.", "key1")
+	require.NoError(t, err)
+
+	assert.Equal(t, "app.example.com", d.Name)
+	assert.Equal(t, "key1", d.KeyID)
+	assert.Len(t, d.Challenge, challengeLength*2)
+	assert.False(t, d.Verified)
+	assert.Equal(t, "_mit-challenge.app.example.com", d.ChallengeRecord())
+
+	other, err := New("app.example.com", "key1")
+	require.NoError(t, err)
+	assert.NotEqual(t, d.Challenge, other.Challenge)
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{name: "simple domain", input: "example.com", want: "example.com"},
+		{name: "mixed case with trailing dot", input: " App.Example.COM. ", want: "app.example.com"},
+		{name: "hyphenated label", input: "my-app.example.com", want: "my-app.example.com"},
+		{name: "empty", input: "", wantErr: true},
+		{name: "single label", input: "localhost", wantErr: true},
+		{name: "empty label", input: "app..example.com", wantErr: true},
+		{name: "leading hyphen", input: "-app.example.com", wantErr: true},
+		{name: "port", input: "app.example.com:443", wantErr: true},
+		{name: "wildcard", input: "*.example.com", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Normalize(tt.input)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidDomain)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ksysoev/make-it-public/pkg/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_AddDomain(t *testing.T) {
+	mockAuth := NewMockAuthRepo(t)
+	svc := New(nil, nil, mockAuth)
+
+	mockAuth.EXPECT().AddDomain(mock.Anything, mock.MatchedBy(func(d *domain.Domain) bool {
+		return d.Name == "app.example.com" && d.KeyID == "key1" && d.Challenge != "" && !d.Verified
+	})).Return(nil).Once()
+
+	d, err := svc.AddDomain(context.Background(), "key1", "App.Example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "app.example.com", d.Name)
+
+	_, err = svc.AddDomain(context.Background(), "key1", "invalid")
+	assert.ErrorIs(t, err, domain.ErrInvalidDomain)
+
+	mockAuth.EXPECT().AddDomain(mock.Anything, mock.Anything).Return(ErrTokenNotFound).Once()
+
+	_, err = svc.AddDomain(context.Background(), "key1", "app.example.com")
+	assert.ErrorIs(t, err, ErrTokenNotFound)
+}
+
+func TestService_VerifyDomain(t *testing.T) {
+	tests := []struct {
+		domain    *domain.Domain
+		repoErr   error
+		lookupErr error
+		saveErr   error
+		wantErr   error
+		name      string
+		records   []string
+		wantSaved bool
+	}{
+		{
+			name:      "challenge published",
+			domain:    &domain.Domain{Name: "app.example.com", KeyID: "key1", Challenge: "secret"},
+			records:   []string{"other", "secret"},
+			wantSaved: true,
+		},
+		{
+			name:    "challenge missing",
+			domain:  &domain.Domain{Name: "app.example.com", KeyID: "key1", Challenge: "secret"},
+			records: []string{"other"},
+			wantErr: ErrDomainNotVerified,
+		},
+		{
+			name:      "lookup failure",
+			domain:    &domain.Domain{Name: "app.example.com", KeyID: "key1", Challenge: "secret"},
+			lookupErr: assert.AnError,
+			wantErr:   ErrDomainNotVerified,
+		},
+		{
+			name:   "already verified",
+			domain: &domain.Domain{Name: "app.example.com", KeyID: "key1", Challenge: "secret", Verified: true},
+		},
+		{
+			name:    "domain not claimed by the token",
+			repoErr: ErrDomainNotFound,
+			wantErr: ErrDomainNotFound,
+		},
+		{
+			name:      "verified by another token first",
+			domain:    &domain.Domain{Name: "app.example.com", KeyID: "key1", Challenge: "secret"},
+			records:   []string{"secret"},
+			wantSaved: true,
+			saveErr:   ErrDuplicateDomain,
+			wantErr:   ErrDuplicateDomain,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockAuth := NewMockAuthRepo(t)
+			resolver := NewMockTXTResolver(t)
+			svc := New(nil, nil, mockAuth)
+			svc.SetTXTResolver(resolver)
+
+			mockAuth.EXPECT().TokenDomain(mock.Anything, "key1", "app.example.com").Return(tt.domain, tt.repoErr)
+
+			if tt.records != nil || tt.lookupErr != nil {
+				resolver.EXPECT().LookupTXT(mock.Anything, "_mit-challenge.app.example.com").Return(tt.records, tt.lookupErr)
+			}
+
+			if tt.wantSaved {
+				mockAuth.EXPECT().UpdateDomain(mock.Anything, mock.MatchedBy(func(d *domain.Domain) bool {
+					return d.Verified
+				})).Return(tt.saveErr)
+			}
+
+			d, err := svc.VerifyDomain(context.Background(), "key1", "app.example.com")
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.True(t, d.Verified)
+		})
+	}
+}
+
+func TestService_ResolveDomain(t *testing.T) {
+	tests := []struct {
+		domain    *domain.Domain
+		repoErr   error
+		name      string
+		host      string
+		wantKeyID string
+		wantErr   bool
+	}{
+		{
+			name:      "verified domain",
+			host:      "App.example.com",
+			domain:    &domain.Domain{Name: "app.example.com", KeyID: "key1", Verified: true},
+			wantKeyID: "key1",
+		},
+		{
+			name:   "unverified domain",
+			host:   "app.example.com",
+			domain: &domain.Domain{Name: "app.example.com", KeyID: "key1"},
+		},
+		{
+			name:    "unknown domain",
+			host:    "app.example.com",
+			repoErr: ErrDomainNotFound,
+		},
+		{
+			name:    "repo failure",
+			host:    "app.example.com",
+			repoErr: assert.AnError,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockAuth := NewMockAuthRepo(t)
+			svc := New(nil, nil, mockAuth)
+
+			mockAuth.EXPECT().GetDomain(mock.Anything, "app.example.com").Return(tt.domain, tt.repoErr)
+
+			keyID, err := svc.ResolveDomain(context.Background(), tt.host)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantKeyID, keyID)
+		})
+	}
+}
+
+func TestService_RemoveDomain(t *testing.T) {
+	mockAuth := NewMockAuthRepo(t)
+	svc := New(nil, nil, mockAuth)
+
+	mockAuth.EXPECT().RemoveDomain(mock.Anything, "key1", "app.example.com").Return(nil).Once()
+	require.NoError(t, svc.RemoveDomain(context.Background(), "key1", "APP.example.com"))
+
+	assert.ErrorIs(t, svc.RemoveDomain(context.Background(), "key1", "invalid"), ErrDomainNotFound)
+}
+
+func TestService_ListDomains(t *testing.T) {
+	mockAuth := NewMockAuthRepo(t)
+	svc := New(nil, nil, mockAuth)
+
+	domains := []*domain.Domain{{Name: "app.example.com", KeyID: "key1"}}
+	mockAuth.EXPECT().ListDomains(mock.Anything, "key1").Return(domains, nil)
+
+	got, err := svc.ListDomains(context.Background(), "key1")
+	require.NoError(t, err)
+	assert.Equal(t, domains, got)
+}
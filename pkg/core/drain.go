@@ -0,0 +1,84 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+const (
+	defaultDrainGracePeriod = 30 * time.Second
+	drainPollInterval       = 100 * time.Millisecond
+)
+
+var ErrDraining = errors.New("server is draining")
+
+// DrainConfig holds the configuration of the drain performed before the server stops.
+// GracePeriod bounds how long connections being served may keep running, and defaults to 30 seconds.
+// ReconnectAddr is the server address connected clients are asked to reconnect to.
+type DrainConfig struct {
+	ReconnectAddr string        `mapstructure:"reconnect_addr"`
+	GracePeriod   time.Duration `mapstructure:"grace_period"`
+}
+
+// Drain puts the service into drain mode and waits until the connections being served are finished.
+// Edge servers watching Draining stop accepting connections, new clients are refused, and connected clients
+// are asked to reconnect to cfg.ReconnectAddr.
+// Returns an error if connections are still open when the grace period expires or ctx is done.
+func (s *Service) Drain(ctx context.Context, cfg DrainConfig) error {
+	s.drainOnce.Do(func() {
+		s.reconnectAddr = cfg.ReconnectAddr
+		close(s.draining)
+	})
+
+	grace := cfg.GracePeriod
+	if grace <= 0 {
+		grace = defaultDrainGracePeriod
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, grace)
+	defer cancel()
+
+	slog.InfoContext(ctx, "draining connections", slog.Int64("active", s.pipedConns.Load()), slog.Duration("grace_period", grace))
+
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+
+	for {
+		active := s.pipedConns.Load()
+		if active == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%d connections are still open: %w", active, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// Draining returns a channel that is closed once the service starts draining.
+func (s *Service) Draining() <-chan struct{} {
+	return s.draining
+}
+
+// IsDraining reports whether the service is draining.
+func (s *Service) IsDraining() bool {
+	select {
+	case <-s.draining:
+		return true
+	default:
+		return false
+	}
+}
+
+// trackConn counts a connection being served until the returned function is called,
+// so that Drain can wait for it to finish.
+func (s *Service) trackConn() func() {
+	s.pipedConns.Add(1)
+
+	return func() { s.pipedConns.Add(-1) }
+}
@@ -0,0 +1,74 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_Drain_NoConnections(t *testing.T) {
+	svc := New(nil, nil, nil)
+
+	require.False(t, svc.IsDraining())
+
+	err := svc.Drain(t.Context(), DrainConfig{ReconnectAddr: "other:8081", GracePeriod: time.Second})
+
+	require.NoError(t, err)
+	assert.True(t, svc.IsDraining())
+	assert.Equal(t, "other:8081", svc.reconnectAddr)
+
+	select {
+	case <-svc.Draining():
+	default:
+		t.Fatal("Draining channel is not closed")
+	}
+}
+
+func TestService_Drain_WaitsForConnections(t *testing.T) {
+	svc := New(nil, nil, nil)
+
+	done := svc.trackConn()
+
+	go func() {
+		time.Sleep(2 * drainPollInterval)
+		done()
+	}()
+
+	err := svc.Drain(t.Context(), DrainConfig{GracePeriod: time.Second})
+
+	require.NoError(t, err)
+	assert.Zero(t, svc.pipedConns.Load())
+}
+
+func TestService_Drain_GracePeriodExpires(t *testing.T) {
+	svc := New(nil, nil, nil)
+
+	defer svc.trackConn()()
+
+	err := svc.Drain(t.Context(), DrainConfig{GracePeriod: 50 * time.Millisecond})
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorContains(t, err, "1 connections are still open")
+}
+
+func TestService_Drain_Twice(t *testing.T) {
+	svc := New(nil, nil, nil)
+
+	require.NoError(t, svc.Drain(t.Context(), DrainConfig{ReconnectAddr: "first:8081"}))
+	require.NoError(t, svc.Drain(t.Context(), DrainConfig{ReconnectAddr: "second:8081"}))
+
+	assert.Equal(t, "first:8081", svc.reconnectAddr)
+}
+
+func TestService_CheckHealth_Draining(t *testing.T) {
+	svc := New(nil, nil, nil)
+
+	require.NoError(t, svc.Drain(t.Context(), DrainConfig{}))
+
+	err := svc.CheckHealth(t.Context())
+
+	assert.ErrorIs(t, err, ErrDraining)
+}
@@ -0,0 +1,149 @@
+// Package ipfilter restricts access to a tunnel by the IP address of the end user.
+package ipfilter
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+)
+
+// ErrInvalidCIDR is returned when a rule is neither a valid CIDR nor a valid IP address.
+var ErrInvalidCIDR = errors.New("invalid CIDR")
+
+// Filter holds the CIDR rules of a tunnel.
+// Denied ranges take precedence over allowed ones; when Allowed is empty, every address
+// that is not denied is allowed.
+type Filter struct {
+	Allowed []netip.Prefix `json:"allowed,omitempty"`
+	Denied  []netip.Prefix `json:"denied,omitempty"`
+}
+
+// New parses allowed and denied rules into a Filter. Rules may be CIDRs or single IP addresses.
+// Returns nil if both lists are empty, or an error wrapping ErrInvalidCIDR if a rule cannot be parsed.
+func New(allowed, denied []string) (*Filter, error) {
+	if len(allowed) == 0 && len(denied) == 0 {
+		return nil, nil
+	}
+
+	a, err := ParsePrefixes(allowed)
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := ParsePrefixes(denied)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Filter{Allowed: a, Denied: d}, nil
+}
+
+// Allow reports whether clientIP may access the tunnel.
+// clientIP may include a port. A nil Filter allows every address, while addresses that cannot be
+// parsed are rejected by any non-empty Filter.
+func (f *Filter) Allow(clientIP string) bool {
+	if f == nil || (len(f.Allowed) == 0 && len(f.Denied) == 0) {
+		return true
+	}
+
+	addr, ok := parseAddr(clientIP)
+	if !ok {
+		return false
+	}
+
+	for _, p := range f.Denied {
+		if p.Contains(addr) {
+			return false
+		}
+	}
+
+	if len(f.Allowed) == 0 {
+		return true
+	}
+
+	for _, p := range f.Allowed {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AllowedStrings returns the allowed rules in CIDR notation.
+func (f *Filter) AllowedStrings() []string {
+	if f == nil {
+		return nil
+	}
+
+	return prefixStrings(f.Allowed)
+}
+
+// DeniedStrings returns the denied rules in CIDR notation.
+func (f *Filter) DeniedStrings() []string {
+	if f == nil {
+		return nil
+	}
+
+	return prefixStrings(f.Denied)
+}
+
+// parsePrefixes parses every rule as a CIDR, or as a single address when it has no prefix length.
+func ParsePrefixes(rules []string) ([]netip.Prefix, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	prefixes := make([]netip.Prefix, 0, len(rules))
+
+	for _, rule := range rules {
+		p, err := netip.ParsePrefix(rule)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(rule)
+			if addrErr != nil {
+				return nil, fmt.Errorf("%w: %q", ErrInvalidCIDR, rule)
+			}
+
+			addr = addr.Unmap()
+			p = netip.PrefixFrom(addr, addr.BitLen())
+		}
+
+		prefixes = append(prefixes, p.Masked())
+	}
+
+	return prefixes, nil
+}
+
+// parseAddr parses an IP address that may be followed by a port.
+func parseAddr(s string) (netip.Addr, bool) {
+	if addr, err := netip.ParseAddr(s); err == nil {
+		return addr.Unmap(), true
+	}
+
+	host, _, err := net.SplitHostPort(s)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+
+	return addr.Unmap(), true
+}
+
+func prefixStrings(prefixes []netip.Prefix) []string {
+	if len(prefixes) == 0 {
+		return nil
+	}
+
+	out := make([]string, 0, len(prefixes))
+
+	for _, p := range prefixes {
+		out = append(out, p.String())
+	}
+
+	return out
+}
@@ -0,0 +1,113 @@
+package ipfilter
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		wantErr     error
+		name        string
+		allowed     []string
+		denied      []string
+		wantAllowed []string
+		wantDenied  []string
+		wantNil     bool
+	}{
+		{
+			name:    "no rules",
+			wantNil: true,
+		},
+		{
+			name:        "cidrs and addresses",
+			allowed:     []string{"10.1.2.3/8", "192.168.1.1"},
+			denied:      []string{"2001:db8::/32"},
+			wantAllowed: []string{"10.0.0.0/8", "192.168.1.1/32"},
+			wantDenied:  []string{"2001:db8::/32"},
+		},
+		{
+			name:    "invalid rule",
+			allowed: []string{"not-a-cidr"},
+			wantErr: ErrInvalidCIDR,
+		},
+		{
+			name:    "invalid prefix length",
+			denied:  []string{"10.0.0.0/33"},
+			wantErr: ErrInvalidCIDR,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := New(tt.allowed, tt.denied)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+
+			if tt.wantNil {
+				assert.Nil(t, f)
+				return
+			}
+
+			assert.Equal(t, tt.wantAllowed, f.AllowedStrings())
+			assert.Equal(t, tt.wantDenied, f.DeniedStrings())
+		})
+	}
+}
+
+func TestFilter_Allow(t *testing.T) {
+	tests := []struct {
+		name     string
+		clientIP string
+		allowed  []string
+		denied   []string
+		want     bool
+	}{
+		{name: "allowed range", allowed: []string{"10.0.0.0/8"}, clientIP: "10.2.3.4", want: true},
+		{name: "outside allowed range", allowed: []string{"10.0.0.0/8"}, clientIP: "11.0.0.1", want: false},
+		{name: "denied range", denied: []string{"10.0.0.0/8"}, clientIP: "10.2.3.4", want: false},
+		{name: "outside denied range", denied: []string{"10.0.0.0/8"}, clientIP: "11.0.0.1", want: true},
+		{name: "deny takes precedence", allowed: []string{"10.0.0.0/8"}, denied: []string{"10.0.0.1"}, clientIP: "10.0.0.1", want: false},
+		{name: "address with port", allowed: []string{"10.0.0.0/8"}, clientIP: "10.0.0.1:5555", want: true},
+		{name: "ipv4-mapped ipv6", allowed: []string{"10.0.0.0/8"}, clientIP: "::ffff:10.0.0.1", want: true},
+		{name: "ipv6", allowed: []string{"2001:db8::/32"}, clientIP: "[2001:db8::1]:443", want: true},
+		{name: "unparseable address", allowed: []string{"10.0.0.0/8"}, clientIP: "unknown", want: false},
+		{name: "empty address", denied: []string{"10.0.0.0/8"}, clientIP: "", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := New(tt.allowed, tt.denied)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.want, f.Allow(tt.clientIP))
+		})
+	}
+}
+
+func TestFilter_AllowNil(t *testing.T) {
+	var f *Filter
+
+	assert.True(t, f.Allow("10.0.0.1"))
+	assert.True(t, f.Allow(""))
+}
+
+func TestFilter_JSONRoundTrip(t *testing.T) {
+	f, err := New([]string{"10.0.0.0/8"}, []string{"10.0.0.1"})
+	require.NoError(t, err)
+
+	data, err := json.Marshal(f)
+	require.NoError(t, err)
+
+	var got Filter
+
+	require.NoError(t, json.Unmarshal(data, &got))
+	assert.Equal(t, f, &got)
+}
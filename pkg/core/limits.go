@@ -0,0 +1,340 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ksysoev/make-it-public/pkg/core/ratelimit"
+)
+
+const (
+	QuotaDaily   = "daily"
+	QuotaMonthly = "monthly"
+
+	// quotaRetention is how long the usage of a quota period is kept after the period ends.
+	quotaRetention = 24 * time.Hour
+
+	// quotaChargeBytes and quotaChargeInterval bound the traffic of a connection that is not charged to its quota
+	// yet, so that connections are closed soon after the quota is used up.
+	quotaChargeBytes    = 1 << 20
+	quotaChargeInterval = 10 * time.Second
+)
+
+var ErrQuotaExceeded = errors.New("traffic quota exceeded")
+
+// LimitsConfig holds the traffic limits applied to the tunnel of every token.
+// Bandwidth caps the bytes per second sent through the tunnels of a keyID in each direction,
+// shared by all of its connections. Quota caps the bytes a keyID may transfer in both directions
+// during a QuotaPeriod, which is QuotaDaily or QuotaMonthly and defaults to QuotaMonthly.
+// Zero values disable the limits.
+type LimitsConfig struct {
+	QuotaPeriod string `mapstructure:"quota_period"`
+	Bandwidth   int64  `mapstructure:"bandwidth"`
+	Quota       int64  `mapstructure:"quota"`
+}
+
+// Validate checks that the LimitsConfig is valid. It returns an error describing the
+// first violation found.
+func (c *LimitsConfig) Validate() error {
+	if c.Bandwidth < 0 {
+		return fmt.Errorf("bandwidth must not be negative: %d", c.Bandwidth)
+	}
+
+	if c.Quota < 0 {
+		return fmt.Errorf("quota must not be negative: %d", c.Quota)
+	}
+
+	switch c.QuotaPeriod {
+	case "", QuotaDaily, QuotaMonthly:
+	default:
+		return fmt.Errorf("unknown quota period %q, expected %q or %q", c.QuotaPeriod, QuotaDaily, QuotaMonthly)
+	}
+
+	return nil
+}
+
+// QuotaRepo tracks the traffic of tokens per quota period.
+// TrafficUsage returns the bytes transferred by keyID during period, and AddTraffic adds n bytes to it
+// and returns the resulting usage. The usage of a period may be discarded after expireAt.
+type QuotaRepo interface {
+	TrafficUsage(ctx context.Context, keyID, period string) (int64, error)
+	AddTraffic(ctx context.Context, keyID, period string, n int64, expireAt time.Time) (int64, error)
+}
+
+// SetLimits sets the traffic limits of tunnels and the repository tracking quota usage.
+// It is called during initialisation when limits are configured.
+func (s *Service) SetLimits(cfg LimitsConfig, quotas QuotaRepo) {
+	s.limits = cfg
+	s.quotaRepo = quotas
+}
+
+// quotaPeriod returns the identifier of the quota period containing now and the time it ends.
+func (s *Service) quotaPeriod(now time.Time) (string, time.Time) {
+	now = now.UTC()
+	year, month, day := now.Date()
+
+	if s.limits.QuotaPeriod == QuotaDaily {
+		return now.Format(time.DateOnly), time.Date(year, month, day+1, 0, 0, 0, 0, time.UTC)
+	}
+
+	return now.Format("2006-01"), time.Date(year, month+1, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// checkQuota returns ErrQuotaExceeded if keyID has used up its traffic quota in the current period.
+// Connections are allowed when the usage cannot be read, so that an unavailable repository does not stop tunnels.
+func (s *Service) checkQuota(ctx context.Context, keyID string) error {
+	if s.limits.Quota <= 0 {
+		return nil
+	}
+
+	period, _ := s.quotaPeriod(time.Now())
+
+	used, err := s.quotaRepo.TrafficUsage(ctx, keyID, period)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to get traffic usage", slog.Any("error", err), slog.String("keyID", keyID))
+		return nil
+	}
+
+	if used >= s.limits.Quota {
+		return fmt.Errorf("keyID %s transferred %d of %d bytes in %s: %w", keyID, used, s.limits.Quota, period, ErrQuotaExceeded)
+	}
+
+	return nil
+}
+
+// meterQuota starts charging the traffic of a connection of keyID, counted by stats, to the quota of keyID
+// while the data flows, rather than once the connection is finished, so that long-lived connections can not
+// move traffic past a used-up quota. The connection must be served with the returned context, which is cancelled
+// once the quota is used up. The returned function must be called once the connection is finished: it charges the
+// remaining traffic and returns an error wrapping ErrQuotaExceeded if the quota was used up by the connection.
+func (s *Service) meterQuota(ctx context.Context, keyID string, stats *connStats) (context.Context, func() error) {
+	if s.limits.Quota <= 0 {
+		return ctx, func() error { return nil }
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+
+	m := &quotaMeter{
+		svc:    s,
+		ctx:    ctx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+		keyID:  keyID,
+	}
+
+	stats.meter = m
+
+	go m.chargePeriodically()
+
+	return ctx, func() error {
+		close(m.done)
+
+		// Bytes written outside of the metered pipes, such as the initial request of HTTP connections, are only
+		// counted by stats.
+		m.charge(max(stats.in+stats.out, m.counted.Load()))
+
+		err := context.Cause(ctx)
+		cancel(nil)
+
+		if errors.Is(err, ErrQuotaExceeded) {
+			return err
+		}
+
+		return nil
+	}
+}
+
+// quotaMeter charges the traffic of a connection to the quota of its keyID, every quotaChargeBytes
+// and every quotaChargeInterval, and cancels the context of the connection once the quota is used up.
+type quotaMeter struct {
+	svc     *Service
+	ctx     context.Context
+	cancel  context.CancelCauseFunc
+	done    chan struct{}
+	keyID   string
+	counted atomic.Int64
+	charged atomic.Int64
+	mu      sync.Mutex
+}
+
+// add counts n bytes of the connection, and charges the traffic once enough of it is not charged yet.
+func (m *quotaMeter) add(n int) {
+	if m == nil || n <= 0 {
+		return
+	}
+
+	if counted := m.counted.Add(int64(n)); counted-m.charged.Load() >= quotaChargeBytes {
+		m.charge(counted)
+	}
+}
+
+// chargePeriodically charges the traffic of the connection every quotaChargeInterval, so that slow connections
+// are charged too, until the connection is finished.
+func (m *quotaMeter) chargePeriodically() {
+	ticker := time.NewTicker(quotaChargeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.done:
+			return
+		case <-m.ctx.Done():
+			return
+		case <-ticker.C:
+			m.charge(m.counted.Load())
+		}
+	}
+}
+
+// charge adds the traffic up to total bytes that is not charged yet to the usage of the keyID,
+// and cancels the connection if the quota is used up. The connection is kept when the usage cannot be recorded,
+// so that an unavailable repository does not stop tunnels.
+func (m *quotaMeter) charge(total int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n := total - m.charged.Load()
+	if n <= 0 {
+		return
+	}
+
+	m.charged.Store(total)
+
+	period, end := m.svc.quotaPeriod(time.Now())
+
+	used, err := m.svc.quotaRepo.AddTraffic(context.WithoutCancel(m.ctx), m.keyID, period, n, end.Add(quotaRetention))
+	if err != nil {
+		slog.ErrorContext(m.ctx, "failed to add traffic usage", slog.Any("error", err), slog.String("keyID", m.keyID))
+		return
+	}
+
+	if used >= m.svc.limits.Quota {
+		m.cancel(fmt.Errorf("keyID %s transferred %d of %d bytes in %s: %w", m.keyID, used, m.svc.limits.Quota, period, ErrQuotaExceeded))
+	}
+}
+
+// reader counts the bytes read from r, if the traffic is metered.
+func (m *quotaMeter) reader(r io.Reader) io.Reader {
+	if m == nil {
+		return r
+	}
+
+	return &meteredReader{r: r, m: m}
+}
+
+// writer counts the bytes written to w, if the traffic is metered.
+func (m *quotaMeter) writer(w io.Writer) io.Writer {
+	if m == nil {
+		return w
+	}
+
+	return &meteredWriter{w: w, m: m}
+}
+
+type meteredReader struct {
+	r io.Reader
+	m *quotaMeter
+}
+
+func (r *meteredReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.m.add(n)
+
+	return n, err
+}
+
+type meteredWriter struct {
+	w io.Writer
+	m *quotaMeter
+}
+
+func (w *meteredWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.m.add(n)
+
+	return n, err
+}
+
+// bandwidth holds the token buckets shared by the connections of a keyID, one for each direction.
+type bandwidth struct {
+	in   *ratelimit.Bucket
+	out  *ratelimit.Bucket
+	refs int
+}
+
+// bandwidthLimiters keeps the buckets of the keyIDs that have connections in progress.
+type bandwidthLimiters struct {
+	keys map[string]*bandwidth
+	mu   sync.Mutex
+}
+
+// acquireBandwidth returns the buckets of keyID, or nil if bandwidth is not limited.
+// The returned function must be called once the connection is finished.
+func (s *Service) acquireBandwidth(keyID string) (*bandwidth, func()) {
+	if s.limits.Bandwidth <= 0 {
+		return nil, func() {}
+	}
+
+	l := &s.bandwidth
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.keys == nil {
+		l.keys = make(map[string]*bandwidth)
+	}
+
+	bw, ok := l.keys[keyID]
+	if !ok {
+		bw = &bandwidth{
+			in:  ratelimit.NewBucket(float64(s.limits.Bandwidth), 0),
+			out: ratelimit.NewBucket(float64(s.limits.Bandwidth), 0),
+		}
+
+		l.keys[keyID] = bw
+	}
+
+	bw.refs++
+
+	return bw, func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		if bw.refs--; bw.refs == 0 {
+			delete(l.keys, keyID)
+		}
+	}
+}
+
+// reader limits r to the inbound bandwidth, if any.
+func (bw *bandwidth) reader(ctx context.Context, r io.Reader) io.Reader {
+	if bw == nil {
+		return r
+	}
+
+	return ratelimit.NewReader(ctx, r, bw.in)
+}
+
+// writer limits w to the outbound bandwidth, if any.
+func (bw *bandwidth) writer(ctx context.Context, w io.Writer) io.Writer {
+	if bw == nil {
+		return w
+	}
+
+	return ratelimit.NewWriter(ctx, w, bw.out)
+}
+
+// noopQuotaRepo is the default repository used when no quota is configured.
+type noopQuotaRepo struct{}
+
+func (noopQuotaRepo) TrafficUsage(_ context.Context, _, _ string) (int64, error) { return 0, nil }
+
+func (noopQuotaRepo) AddTraffic(_ context.Context, _, _ string, _ int64, _ time.Time) (int64, error) {
+	return 0, nil
+}
@@ -0,0 +1,299 @@
+package core
+
+import (
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ksysoev/make-it-public/pkg/core/audit"
+	"github.com/ksysoev/make-it-public/pkg/core/conn"
+	"github.com/ksysoev/make-it-public/pkg/core/conn/meta"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimitsConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     LimitsConfig
+		wantErr bool
+	}{
+		{name: "empty", cfg: LimitsConfig{}},
+		{name: "daily", cfg: LimitsConfig{Bandwidth: 1024, Quota: 1 << 30, QuotaPeriod: QuotaDaily}},
+		{name: "monthly", cfg: LimitsConfig{Quota: 1 << 30, QuotaPeriod: QuotaMonthly}},
+		{name: "negative bandwidth", cfg: LimitsConfig{Bandwidth: -1}, wantErr: true},
+		{name: "negative quota", cfg: LimitsConfig{Quota: -1}, wantErr: true},
+		{name: "unknown period", cfg: LimitsConfig{QuotaPeriod: "weekly"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestService_QuotaPeriod(t *testing.T) {
+	tests := []struct {
+		now        time.Time
+		wantEnd    time.Time
+		name       string
+		period     string
+		wantPeriod string
+	}{
+		{
+			name:       "monthly",
+			now:        time.Date(2025, time.March, 15, 10, 0, 0, 0, time.UTC),
+			wantPeriod: "2025-03",
+			wantEnd:    time.Date(2025, time.April, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:       "monthly year rollover",
+			period:     QuotaMonthly,
+			now:        time.Date(2025, time.December, 31, 23, 0, 0, 0, time.UTC),
+			wantPeriod: "2025-12",
+			wantEnd:    time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:       "daily",
+			period:     QuotaDaily,
+			now:        time.Date(2025, time.February, 28, 12, 0, 0, 0, time.UTC),
+			wantPeriod: "2025-02-28",
+			wantEnd:    time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:       "daily in UTC",
+			period:     QuotaDaily,
+			now:        time.Date(2025, time.June, 1, 1, 0, 0, 0, time.FixedZone("UTC+3", 3*60*60)),
+			wantPeriod: "2025-05-31",
+			wantEnd:    time.Date(2025, time.June, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := New(nil, nil, nil)
+			svc.SetLimits(LimitsConfig{QuotaPeriod: tt.period}, noopQuotaRepo{})
+
+			period, end := svc.quotaPeriod(tt.now)
+
+			assert.Equal(t, tt.wantPeriod, period)
+			assert.Equal(t, tt.wantEnd, end)
+		})
+	}
+}
+
+func TestService_CheckQuota(t *testing.T) {
+	tests := []struct {
+		usageErr error
+		wantErr  error
+		name     string
+		used     int64
+	}{
+		{name: "under quota", used: 99},
+		{name: "quota exceeded", used: 100, wantErr: ErrQuotaExceeded},
+		{name: "repository error", usageErr: assert.AnError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quotas := NewMockQuotaRepo(t)
+			quotas.EXPECT().TrafficUsage(mock.Anything, "key1", mock.Anything).Return(tt.used, tt.usageErr)
+
+			svc := New(nil, nil, nil)
+			svc.SetLimits(LimitsConfig{Quota: 100}, quotas)
+
+			err := svc.checkQuota(t.Context(), "key1")
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestService_CheckQuota_Disabled(t *testing.T) {
+	svc := New(nil, nil, nil)
+	svc.SetLimits(LimitsConfig{}, NewMockQuotaRepo(t))
+
+	assert.NoError(t, svc.checkQuota(t.Context(), "key1"))
+}
+
+func TestService_MeterQuota(t *testing.T) {
+	quotas := NewMockQuotaRepo(t)
+	quotas.EXPECT().AddTraffic(mock.Anything, "key1", mock.Anything, int64(30), mock.Anything).
+		RunAndReturn(func(_ context.Context, _, period string, _ int64, expireAt time.Time) (int64, error) {
+			assert.Equal(t, time.Now().UTC().Format("2006-01"), period)
+			assert.True(t, expireAt.After(time.Now()))
+
+			return 50, nil
+		})
+
+	svc := New(nil, nil, nil)
+	svc.SetLimits(LimitsConfig{Quota: 100}, quotas)
+
+	stats := &connStats{}
+
+	ctx, stop := svc.meterQuota(t.Context(), "key1", stats)
+	require.NotNil(t, stats.meter)
+
+	stats.in, stats.out = 10, 20
+
+	require.NoError(t, stop())
+	assert.ErrorIs(t, ctx.Err(), context.Canceled, "the context of the connection is released once it is finished")
+
+	// Connections without traffic are not charged.
+	_, stop = svc.meterQuota(t.Context(), "key1", &connStats{})
+	require.NoError(t, stop())
+}
+
+func TestService_MeterQuota_Disabled(t *testing.T) {
+	svc := New(nil, nil, nil)
+	svc.SetLimits(LimitsConfig{}, NewMockQuotaRepo(t))
+
+	stats := &connStats{}
+
+	ctx, stop := svc.meterQuota(t.Context(), "key1", stats)
+
+	assert.Nil(t, stats.meter)
+	assert.Equal(t, t.Context(), ctx)
+	assert.NoError(t, stop())
+}
+
+func TestService_MeterQuota_Exceeded(t *testing.T) {
+	quotas := NewMockQuotaRepo(t)
+	quotas.EXPECT().AddTraffic(mock.Anything, "key1", mock.Anything, int64(quotaChargeBytes), mock.Anything).
+		Return(int64(quotaChargeBytes), nil).Once()
+
+	svc := New(nil, nil, nil)
+	svc.SetLimits(LimitsConfig{Quota: 1024}, quotas)
+
+	stats := &connStats{}
+
+	ctx, stop := svc.meterQuota(t.Context(), "key1", stats)
+
+	// Traffic is charged as it flows, before the connection is finished.
+	n, err := stats.meter.writer(io.Discard).Write(make([]byte, quotaChargeBytes))
+	require.NoError(t, err)
+
+	stats.out = int64(n)
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("connection was not cancelled once the quota was used up")
+	}
+
+	assert.ErrorIs(t, context.Cause(ctx), ErrQuotaExceeded)
+	assert.ErrorIs(t, stop(), ErrQuotaExceeded)
+}
+
+func TestHandleTCPConnection_QuotaUsedUpWhileOpen(t *testing.T) {
+	revServer, revClient := net.Pipe()
+	cliServer, cliClient := net.Pipe()
+
+	t.Cleanup(func() {
+		_ = revClient.Close()
+		_ = cliClient.Close()
+	})
+
+	mockReq := conn.NewMockRequest(t)
+	mockReq.EXPECT().WaitConn(mock.Anything).Return(&yamuxStreamWrapper{Conn: revServer}, nil)
+	mockReq.EXPECT().ParentContext().Return(context.Background())
+
+	tcpConnMng := NewMockConnManager(t)
+	tcpConnMng.EXPECT().RequestConnection(mock.Anything, "test-user").Return(mockReq, nil)
+
+	quotas := NewMockQuotaRepo(t)
+	quotas.EXPECT().TrafficUsage(mock.Anything, "test-user", mock.Anything).Return(int64(0), nil)
+	quotas.EXPECT().AddTraffic(mock.Anything, "test-user", mock.Anything, mock.Anything, mock.Anything).
+		RunAndReturn(func(_ context.Context, _, _ string, n int64, _ time.Time) (int64, error) {
+			return n, nil
+		})
+
+	// The audit log records why the connection was closed.
+	sink := NewMockAuditSink(t)
+	sink.EXPECT().Write(mock.Anything, mock.MatchedBy(func(rec *audit.Record) bool {
+		return rec.Outcome == audit.OutcomeFailed && strings.Contains(rec.Error, ErrQuotaExceeded.Error())
+	})).Return(nil)
+
+	service := New(NewMockConnManager(t), tcpConnMng, NewMockAuthRepo(t))
+	service.SetLimits(LimitsConfig{Quota: 1024}, quotas)
+	service.SetAuditSink(sink)
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- service.HandleTCPConnection(t.Context(), "test-user", cliServer, "127.0.0.1")
+	}()
+
+	require.NoError(t, meta.ReadData(revClient, &meta.ClientConnMeta{}))
+
+	// The end user keeps reading while the local service streams more than the quota.
+	go func() { _, _ = io.Copy(io.Discard, cliClient) }()
+	go func() {
+		chunk := make([]byte, 64<<10)
+		for {
+			if _, err := revClient.Write(chunk); err != nil {
+				return
+			}
+		}
+	}()
+
+	// The connection has been piped, so the edge is not asked to answer the visitor with the quota error.
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("connection was not closed once the quota was used up")
+	}
+}
+
+func TestService_AcquireBandwidth(t *testing.T) {
+	svc := New(nil, nil, nil)
+
+	bw, release := svc.acquireBandwidth("key1")
+	assert.Nil(t, bw)
+
+	release()
+
+	svc.SetLimits(LimitsConfig{Bandwidth: 1024}, noopQuotaRepo{})
+
+	bw1, release1 := svc.acquireBandwidth("key1")
+	bw2, release2 := svc.acquireBandwidth("key1")
+	other, releaseOther := svc.acquireBandwidth("key2")
+
+	require.NotNil(t, bw1)
+	assert.Same(t, bw1, bw2)
+	assert.NotSame(t, bw1, other)
+
+	release1()
+	assert.Contains(t, svc.bandwidth.keys, "key1")
+
+	release2()
+	releaseOther()
+	assert.Empty(t, svc.bandwidth.keys)
+}
+
+func TestHandleTCPConnection_QuotaExceeded(t *testing.T) {
+	quotas := NewMockQuotaRepo(t)
+	quotas.EXPECT().TrafficUsage(mock.Anything, "test-user", mock.Anything).Return(int64(200), nil)
+
+	service := New(NewMockConnManager(t), NewMockConnManager(t), NewMockAuthRepo(t))
+	service.SetLimits(LimitsConfig{Quota: 100}, quotas)
+
+	err := service.HandleTCPConnection(t.Context(), "test-user", conn.NewMockWithWriteCloser(t), "127.0.0.1")
+	require.ErrorIs(t, err, ErrQuotaExceeded)
+}
@@ -0,0 +1,53 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// MaxOfflinePageSize is the largest offline page, in bytes, that can be set for a token.
+const MaxOfflinePageSize = 64 << 10
+
+var (
+	ErrOfflinePageTooLarge = fmt.Errorf("offline page exceeds %d bytes", MaxOfflinePageSize)
+	ErrEmptyOfflinePage    = errors.New("offline page is empty")
+)
+
+// SetOfflinePage sets the HTML page shown to visitors of the web tunnel of keyID while its client is disconnected,
+// replacing the generic 502 error page. The page expires together with the token.
+// Returns ErrEmptyOfflinePage or ErrOfflinePageTooLarge if the page is invalid, or ErrTokenNotFound if the token does not exist.
+func (s *Service) SetOfflinePage(ctx context.Context, keyID, page string) error {
+	switch {
+	case page == "":
+		return ErrEmptyOfflinePage
+	case len(page) > MaxOfflinePageSize:
+		return ErrOfflinePageTooLarge
+	}
+
+	if err := s.auth.SetOfflinePage(ctx, keyID, page); err != nil {
+		return fmt.Errorf("failed to set offline page: %w", err)
+	}
+
+	return nil
+}
+
+// OfflinePage returns the offline page of the token identified by keyID, or an empty string if none is set.
+func (s *Service) OfflinePage(ctx context.Context, keyID string) (string, error) {
+	page, err := s.auth.OfflinePage(ctx, keyID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get offline page: %w", err)
+	}
+
+	return page, nil
+}
+
+// RemoveOfflinePage removes the offline page of the token identified by keyID, so that the generic error page is shown again.
+// Removing a page that is not set is not an error.
+func (s *Service) RemoveOfflinePage(ctx context.Context, keyID string) error {
+	if err := s.auth.RemoveOfflinePage(ctx, keyID); err != nil {
+		return fmt.Errorf("failed to remove offline page: %w", err)
+	}
+
+	return nil
+}
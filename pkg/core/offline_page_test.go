@@ -0,0 +1,56 @@
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_SetOfflinePage(t *testing.T) {
+	mockAuth := NewMockAuthRepo(t)
+	svc := New(nil, nil, mockAuth)
+
+	mockAuth.EXPECT().SetOfflinePage(mock.Anything, "key1", "<h1>Back soon</h1>").Return(nil).Once()
+
+	require.NoError(t, svc.SetOfflinePage(context.Background(), "key1", "<h1>Back soon</h1>"))
+
+	assert.ErrorIs(t, svc.SetOfflinePage(context.Background(), "key1", ""), ErrEmptyOfflinePage)
+	assert.ErrorIs(t, svc.SetOfflinePage(context.Background(), "key1", strings.Repeat("a", MaxOfflinePageSize+1)), ErrOfflinePageTooLarge)
+
+	mockAuth.EXPECT().SetOfflinePage(mock.Anything, "key1", "page").Return(ErrTokenNotFound).Once()
+
+	assert.ErrorIs(t, svc.SetOfflinePage(context.Background(), "key1", "page"), ErrTokenNotFound)
+}
+
+func TestService_OfflinePage(t *testing.T) {
+	mockAuth := NewMockAuthRepo(t)
+	svc := New(nil, nil, mockAuth)
+
+	mockAuth.EXPECT().OfflinePage(mock.Anything, "key1").Return("page", nil).Once()
+
+	page, err := svc.OfflinePage(context.Background(), "key1")
+	require.NoError(t, err)
+	assert.Equal(t, "page", page)
+
+	mockAuth.EXPECT().OfflinePage(mock.Anything, "key1").Return("", assert.AnError).Once()
+
+	_, err = svc.OfflinePage(context.Background(), "key1")
+	assert.ErrorIs(t, err, assert.AnError)
+}
+
+func TestService_RemoveOfflinePage(t *testing.T) {
+	mockAuth := NewMockAuthRepo(t)
+	svc := New(nil, nil, mockAuth)
+
+	mockAuth.EXPECT().RemoveOfflinePage(mock.Anything, "key1").Return(nil).Once()
+
+	require.NoError(t, svc.RemoveOfflinePage(context.Background(), "key1"))
+
+	mockAuth.EXPECT().RemoveOfflinePage(mock.Anything, "key1").Return(assert.AnError).Once()
+
+	assert.ErrorIs(t, svc.RemoveOfflinePage(context.Background(), "key1"), assert.AnError)
+}
@@ -0,0 +1,104 @@
+// Package ratelimit implements token buckets and the readers and writers they throttle.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Bucket is a token bucket refilled at a constant rate up to its burst size.
+// It is safe for concurrent use.
+type Bucket struct {
+	last   time.Time
+	now    func() time.Time
+	rate   float64
+	burst  float64
+	tokens float64
+	mu     sync.Mutex
+}
+
+// NewBucket creates a full Bucket refilled with rate tokens per second and holding at most burst tokens.
+// burst defaults to rate, and to 1 if rate is below 1.
+func NewBucket(rate float64, burst int) *Bucket {
+	if burst <= 0 {
+		burst = max(int(rate), 1)
+	}
+
+	b := &Bucket{
+		now:    time.Now,
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+	}
+
+	b.last = b.now()
+
+	return b
+}
+
+// Burst returns the largest number of tokens that can be taken at once.
+func (b *Bucket) Burst() int {
+	return int(b.burst)
+}
+
+// Allow takes one token if it is available, and otherwise reports how long to wait until it is.
+func (b *Bucket) Allow() (bool, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill()
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	return false, b.delay(1 - b.tokens)
+}
+
+// WaitN takes n tokens, waiting until they are available or ctx is done.
+// Returns an error if n exceeds the burst size or ctx is done first.
+func (b *Bucket) WaitN(ctx context.Context, n int) error {
+	if float64(n) > b.burst {
+		return fmt.Errorf("requested %d tokens exceed the burst size of %d", n, b.Burst())
+	}
+
+	b.mu.Lock()
+	b.refill()
+	b.tokens -= float64(n)
+	wait := b.delay(-b.tokens)
+	b.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// refill adds the tokens accumulated since the last call. It must be called with mu held.
+func (b *Bucket) refill() {
+	now := b.now()
+
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+}
+
+// delay returns how long it takes to accumulate missing tokens.
+func (b *Bucket) delay(missing float64) time.Duration {
+	if missing <= 0 {
+		return 0
+	}
+
+	return time.Duration(missing / b.rate * float64(time.Second))
+}
@@ -0,0 +1,85 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestBucket(rate float64, burst int) (*Bucket, *time.Time) {
+	now := time.Unix(1700000000, 0)
+
+	b := NewBucket(rate, burst)
+	b.now = func() time.Time { return now }
+	b.last = now
+
+	return b, &now
+}
+
+func TestNewBucket_DefaultBurst(t *testing.T) {
+	assert.Equal(t, 10, NewBucket(10, 0).Burst())
+	assert.Equal(t, 1, NewBucket(0.5, 0).Burst())
+	assert.Equal(t, 3, NewBucket(10, 3).Burst())
+}
+
+func TestBucket_Allow(t *testing.T) {
+	b, now := newTestBucket(2, 2)
+
+	ok, _ := b.Allow()
+	assert.True(t, ok)
+
+	ok, _ = b.Allow()
+	assert.True(t, ok)
+
+	ok, wait := b.Allow()
+	assert.False(t, ok)
+	assert.Equal(t, 500*time.Millisecond, wait)
+
+	*now = now.Add(500 * time.Millisecond)
+
+	ok, _ = b.Allow()
+	assert.True(t, ok)
+
+	// Tokens never accumulate beyond the burst size.
+	*now = now.Add(time.Hour)
+
+	for range 2 {
+		ok, _ = b.Allow()
+		assert.True(t, ok)
+	}
+
+	ok, _ = b.Allow()
+	assert.False(t, ok)
+}
+
+func TestBucket_WaitN(t *testing.T) {
+	b := NewBucket(1000, 100)
+
+	require.NoError(t, b.WaitN(t.Context(), 100))
+
+	start := time.Now()
+
+	require.NoError(t, b.WaitN(t.Context(), 50))
+
+	assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+}
+
+func TestBucket_WaitN_ExceedsBurst(t *testing.T) {
+	b := NewBucket(10, 10)
+
+	assert.ErrorContains(t, b.WaitN(t.Context(), 11), "exceed the burst size")
+}
+
+func TestBucket_WaitN_ContextDone(t *testing.T) {
+	b := NewBucket(1, 1)
+
+	require.NoError(t, b.WaitN(t.Context(), 1))
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+
+	assert.ErrorIs(t, b.WaitN(ctx, 1), context.DeadlineExceeded)
+}
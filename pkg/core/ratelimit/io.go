@@ -0,0 +1,68 @@
+package ratelimit
+
+import (
+	"context"
+	"io"
+)
+
+type reader struct {
+	ctx context.Context
+	r   io.Reader
+	b   *Bucket
+}
+
+// NewReader returns a reader that takes a token from b for every byte read from r,
+// so that data is read no faster than the rate of b. Waiting stops with an error when ctx is done.
+func NewReader(ctx context.Context, r io.Reader, b *Bucket) io.Reader {
+	return &reader{ctx: ctx, r: r, b: b}
+}
+
+func (l *reader) Read(p []byte) (int, error) {
+	if len(p) > l.b.Burst() {
+		p = p[:l.b.Burst()]
+	}
+
+	n, err := l.r.Read(p)
+	if n > 0 {
+		if waitErr := l.b.WaitN(l.ctx, n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+
+	return n, err
+}
+
+type writer struct {
+	ctx context.Context
+	w   io.Writer
+	b   *Bucket
+}
+
+// NewWriter returns a writer that takes a token from b for every byte written to w,
+// so that data is written no faster than the rate of b. Waiting stops with an error when ctx is done.
+func NewWriter(ctx context.Context, w io.Writer, b *Bucket) io.Writer {
+	return &writer{ctx: ctx, w: w, b: b}
+}
+
+func (l *writer) Write(p []byte) (int, error) {
+	written := 0
+
+	for len(p) > 0 {
+		chunk := p[:min(len(p), l.b.Burst())]
+
+		if err := l.b.WaitN(l.ctx, len(chunk)); err != nil {
+			return written, err
+		}
+
+		n, err := l.w.Write(chunk)
+		written += n
+
+		if err != nil {
+			return written, err
+		}
+
+		p = p[n:]
+	}
+
+	return written, nil
+}
@@ -0,0 +1,58 @@
+package ratelimit
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReader(t *testing.T) {
+	data := bytes.Repeat([]byte("a"), 300)
+	r := NewReader(t.Context(), bytes.NewReader(data), NewBucket(2000, 100))
+
+	start := time.Now()
+
+	got, err := io.ReadAll(r)
+
+	require.NoError(t, err)
+	assert.Equal(t, data, got)
+	// The first 100 bytes are the burst, the remaining 200 take at least 100ms at 2000 bytes per second.
+	assert.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+}
+
+func TestWriter(t *testing.T) {
+	data := bytes.Repeat([]byte("a"), 300)
+
+	var buf bytes.Buffer
+
+	w := NewWriter(t.Context(), &buf, NewBucket(2000, 100))
+
+	start := time.Now()
+
+	n, err := w.Write(data)
+
+	require.NoError(t, err)
+	assert.Equal(t, 300, n)
+	assert.Equal(t, data, buf.Bytes())
+	assert.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+}
+
+func TestWriter_ContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+
+	var buf bytes.Buffer
+
+	w := NewWriter(ctx, &buf, NewBucket(10, 10))
+
+	cancel()
+
+	n, err := w.Write(bytes.Repeat([]byte("a"), 20))
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 10, n)
+}
@@ -3,9 +3,16 @@ package core
 import (
 	"context"
 	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/ksysoev/make-it-public/pkg/core/conn"
+	"github.com/ksysoev/make-it-public/pkg/core/conn/meta"
+	"github.com/ksysoev/make-it-public/pkg/core/domain"
+	"github.com/ksysoev/make-it-public/pkg/core/ipfilter"
 	"github.com/ksysoev/make-it-public/pkg/core/token"
 )
 
@@ -13,40 +20,101 @@ type ControlConn interface {
 	ID() uuid.UUID
 	Context() context.Context
 	Close() error
-	RequestConnection() (conn.Request, error)
+	NewRequest() conn.Request
+	RequestConnection(req conn.Request) error
+	Auth() *meta.TunnelAuth
 }
 
 type AuthRepo interface {
 	Verify(ctx context.Context, keyID, secret string) (*token.Token, error)
 	SaveToken(ctx context.Context, t *token.Token) error
 	DeleteToken(ctx context.Context, tokenID string) error
+	GetToken(ctx context.Context, keyID string) (*token.Token, error)
+	ListTokens(ctx context.Context) ([]*token.Token, error)
+	UpdateTokenTTL(ctx context.Context, keyID string, ttl time.Duration) error
 	IsKeyExists(ctx context.Context, keyID string) (bool, error)
+	IPFilter(ctx context.Context, keyID string) (*ipfilter.Filter, error)
+	ReservedPort(ctx context.Context, keyID string) (int, error)
+	PortOwner(ctx context.Context, port int) (string, error)
+	AddDomain(ctx context.Context, d *domain.Domain) error
+	GetDomain(ctx context.Context, name string) (*domain.Domain, error)
+	TokenDomain(ctx context.Context, keyID, name string) (*domain.Domain, error)
+	UpdateDomain(ctx context.Context, d *domain.Domain) error
+	ListDomains(ctx context.Context, keyID string) ([]*domain.Domain, error)
+	RemoveDomain(ctx context.Context, keyID, name string) error
+	SetOfflinePage(ctx context.Context, keyID, page string) error
+	OfflinePage(ctx context.Context, keyID string) (string, error)
+	RemoveOfflinePage(ctx context.Context, keyID string) error
 	CheckHealth(ctx context.Context) error
 }
 
 type ConnManager interface {
 	RequestConnection(ctx context.Context, keyID string) (conn.Request, error)
-	AddConnection(keyID string, conn ControlConn)
+	AddConnection(keyID string, conn ControlConn) error
 	ResolveRequest(id uuid.UUID, conn conn.WithWriteCloser)
 	RemoveConnection(keyID string, id uuid.UUID)
 	CancelRequest(id uuid.UUID)
+	TunnelAuth(keyID string) (*meta.TunnelAuth, bool)
 }
 
 // TCPEndpointAllocator dynamically allocates and releases TCP listeners for
 // individual MIT clients that authenticate with a TCP token.
 // Allocate starts a TCP listener and returns the public endpoint (host:port).
-// Release stops the listener and frees the port back to the pool.
+// A non-zero port requests that exact port, and ErrPortUnavailable is returned if it cannot be used;
+// otherwise any free port that is not reserved by another token is used.
+// Control connections of the same keyID share its listener, and every Allocate is balanced by a Release;
+// the listener is stopped and its port freed back to the pool once the last one is released.
 type TCPEndpointAllocator interface {
+	Allocate(ctx context.Context, keyID string, port int) (string, error)
+	Release(keyID string)
+}
+
+// UDPEndpointAllocator dynamically allocates and releases UDP sockets for
+// individual MIT clients that authenticate with a UDP token.
+// Allocate binds a UDP socket on a free port and returns the public endpoint (host:port).
+// Control connections of the same keyID share its socket, and every Allocate is balanced by a Release;
+// the socket is closed, its sessions ended and its port freed back to the pool once the last one is released.
+type UDPEndpointAllocator interface {
 	Allocate(ctx context.Context, keyID string) (string, error)
 	Release(keyID string)
 }
 
+// ConnRegistry publishes which server node owns the control connections of a keyID,
+// so that nodes of a cluster can forward public connections to each other.
+// Register and Unregister are called when a control connection is established and closed on this node;
+// Register also publishes the tunnel credentials, sealed by the caller, so every node can check them.
+// Forward opens an internal link to a remote node that owns keyID; it returns ErrKeyIDNotFound
+// when no other node owns the keyID. TunnelAuth returns the credentials published for keyID,
+// or ErrKeyIDNotFound when none are known.
+type ConnRegistry interface {
+	Register(ctx context.Context, keyID string, auth *meta.TunnelAuth) error
+	Unregister(ctx context.Context, keyID string) error
+	Forward(ctx context.Context, keyID string, tokenType token.TokenType, clientIP string) (conn.WithWriteCloser, error)
+	TunnelAuth(ctx context.Context, keyID string) (*meta.TunnelAuth, error)
+}
+
 type Service struct {
-	endpointGenerator    func(string) (string, error)
-	tcpEndpointAllocator TCPEndpointAllocator
-	webConnMng           ConnManager
-	tcpConnMng           ConnManager
-	auth                 AuthRepo
+	webConnMng            ConnManager
+	tcpConnMng            ConnManager
+	grpcConnMng           ConnManager
+	udpConnMng            ConnManager
+	tlsConnMng            ConnManager
+	connRegistry          ConnRegistry
+	txtResolver           TXTResolver
+	auditSink             AuditSink
+	quotaRepo             QuotaRepo
+	tcpEndpointAllocator  TCPEndpointAllocator
+	udpEndpointAllocator  UDPEndpointAllocator
+	auth                  AuthRepo
+	endpointGenerator     func(string) (string, error)
+	grpcEndpointGenerator func(string) (string, error)
+	tlsEndpointGenerator  func(string) (string, error)
+	draining              chan struct{}
+	bandwidth             bandwidthLimiters
+	reconnectAddr         string
+	limits                LimitsConfig
+	pipedConns            atomic.Int64
+	drainOnce             sync.Once
 }
 
 // New initializes and returns a new Service instance with the provided ConnManagers and AuthRepo.
@@ -62,7 +130,22 @@ func New(webConnMng, tcpConnMng ConnManager, auth AuthRepo) *Service {
 		endpointGenerator: func(_ string) (string, error) {
 			return "", fmt.Errorf("endpoint generator is not set")
 		},
+		grpcConnMng: noopConnManager{},
+		grpcEndpointGenerator: func(_ string) (string, error) {
+			return "", fmt.Errorf("gRPC tunnels are not enabled")
+		},
+		tlsEndpointGenerator: func(_ string) (string, error) {
+			return "", fmt.Errorf("TLS passthrough tunnels are not enabled")
+		},
+		udpConnMng:           noopConnManager{},
+		tlsConnMng:           noopConnManager{},
 		tcpEndpointAllocator: noopTCPEndpointAllocator{},
+		udpEndpointAllocator: noopUDPEndpointAllocator{},
+		connRegistry:         noopConnRegistry{},
+		txtResolver:          net.DefaultResolver,
+		auditSink:            noopAuditSink{},
+		quotaRepo:            noopQuotaRepo{},
+		draining:             make(chan struct{}),
 	}
 }
 
@@ -74,13 +157,77 @@ func (s *Service) SetEndpointGenerator(generator func(string) (string, error)) {
 	s.endpointGenerator = generator
 }
 
+// SetGRPCConnManager sets the manager of the control connections of clients that authenticate with a gRPC token.
+// Until it is called, gRPC tunnels are not served.
+func (s *Service) SetGRPCConnManager(connMng ConnManager) {
+	s.grpcConnMng = connMng
+}
+
+// SetGRPCEndpointGenerator sets the function generating the public endpoint of the gRPC tunnel of a keyID.
+// It is called by the gRPC edge server during initialisation.
+func (s *Service) SetGRPCEndpointGenerator(generator func(string) (string, error)) {
+	s.grpcEndpointGenerator = generator
+}
+
+// connManager returns the manager of the control connections of clients authenticated with tokens of tokenType.
+func (s *Service) connManager(tokenType token.TokenType) ConnManager {
+	switch tokenType {
+	case token.TokenTypeTCP:
+		return s.tcpConnMng
+	case token.TokenTypeGRPC:
+		return s.grpcConnMng
+	case token.TokenTypeUDP:
+		return s.udpConnMng
+	case token.TokenTypeTLS:
+		return s.tlsConnMng
+	default:
+		return s.webConnMng
+	}
+}
+
 // SetTCPEndpointAllocator sets the allocator used to create per-keyID TCP listeners.
 // It is called by the TCP edge server during initialisation.
 func (s *Service) SetTCPEndpointAllocator(allocator TCPEndpointAllocator) {
 	s.tcpEndpointAllocator = allocator
 }
 
+// SetUDPConnManager sets the manager of the control connections of clients that authenticate with a UDP token.
+// Until it is called, UDP tunnels are not served.
+func (s *Service) SetUDPConnManager(connMng ConnManager) {
+	s.udpConnMng = connMng
+}
+
+// SetUDPEndpointAllocator sets the allocator used to create per-keyID UDP sockets.
+// It is called by the UDP edge server during initialisation.
+func (s *Service) SetUDPEndpointAllocator(allocator UDPEndpointAllocator) {
+	s.udpEndpointAllocator = allocator
+}
+
+// SetTLSConnManager sets the manager of the control connections of clients that authenticate with a TLS token.
+// Until it is called, TLS passthrough tunnels are not served.
+func (s *Service) SetTLSConnManager(connMng ConnManager) {
+	s.tlsConnMng = connMng
+}
+
+// SetTLSEndpointGenerator sets the function generating the public endpoint of the TLS passthrough tunnel of a keyID.
+// It is called by the TLS edge server during initialisation.
+func (s *Service) SetTLSEndpointGenerator(generator func(string) (string, error)) {
+	s.tlsEndpointGenerator = generator
+}
+
+// SetConnRegistry sets the registry used to share keyID ownership with other server nodes.
+// It is called during initialisation when the server runs in cluster mode.
+func (s *Service) SetConnRegistry(registry ConnRegistry) {
+	s.connRegistry = registry
+}
+
+// CheckHealth checks the storage used by the service.
+// Returns ErrDraining while the service is draining.
 func (s *Service) CheckHealth(ctx context.Context) error {
+	if s.IsDraining() {
+		return ErrDraining
+	}
+
 	return s.auth.CheckHealth(ctx)
 }
 
@@ -89,8 +236,52 @@ func (s *Service) CheckHealth(ctx context.Context) error {
 // TCP tokens are rejected cleanly rather than silently misbehaving.
 type noopTCPEndpointAllocator struct{}
 
-func (noopTCPEndpointAllocator) Allocate(_ context.Context, keyID string) (string, error) {
+func (noopTCPEndpointAllocator) Allocate(_ context.Context, keyID string, _ int) (string, error) {
 	return "", fmt.Errorf("TCP endpoint allocator is not configured (keyID=%s)", keyID)
 }
 
 func (noopTCPEndpointAllocator) Release(_ string) {}
+
+// noopUDPEndpointAllocator is the default allocator used when no UDP edge server has been wired in.
+// It rejects every UDP token with an error.
+type noopUDPEndpointAllocator struct{}
+
+func (noopUDPEndpointAllocator) Allocate(_ context.Context, keyID string) (string, error) {
+	return "", fmt.Errorf("UDP tunnels are not enabled (keyID=%s)", keyID)
+}
+
+func (noopUDPEndpointAllocator) Release(_ string) {}
+
+// noopConnManager is the default manager of gRPC, UDP and TLS control connections used when the edge server
+// of their tunnels has not been wired in. It never has a connection to offer.
+type noopConnManager struct{}
+
+func (noopConnManager) RequestConnection(_ context.Context, _ string) (conn.Request, error) {
+	return nil, ErrKeyIDNotFound
+}
+
+func (noopConnManager) AddConnection(_ string, _ ControlConn) error { return nil }
+
+func (noopConnManager) ResolveRequest(_ uuid.UUID, _ conn.WithWriteCloser) {}
+
+func (noopConnManager) RemoveConnection(_ string, _ uuid.UUID) {}
+
+func (noopConnManager) CancelRequest(_ uuid.UUID) {}
+
+func (noopConnManager) TunnelAuth(_ string) (*meta.TunnelAuth, bool) { return nil, false }
+
+// noopConnRegistry is the default registry used when the server runs as a single node.
+// It never finds a remote owner, so connections for unknown keyIDs are rejected locally.
+type noopConnRegistry struct{}
+
+func (noopConnRegistry) Register(_ context.Context, _ string, _ *meta.TunnelAuth) error { return nil }
+
+func (noopConnRegistry) Unregister(_ context.Context, _ string) error { return nil }
+
+func (noopConnRegistry) Forward(_ context.Context, _ string, _ token.TokenType, _ string) (conn.WithWriteCloser, error) {
+	return nil, ErrKeyIDNotFound
+}
+
+func (noopConnRegistry) TunnelAuth(_ context.Context, _ string) (*meta.TunnelAuth, error) {
+	return nil, ErrKeyIDNotFound
+}
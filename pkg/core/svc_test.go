@@ -3,6 +3,7 @@ package core
 import (
 	"testing"
 
+	"github.com/ksysoev/make-it-public/pkg/core/token"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -33,3 +34,73 @@ func TestService_CheckHealth(t *testing.T) {
 
 	assert.ErrorIs(t, err, assert.AnError)
 }
+
+func TestService_SetGRPCEndpointGenerator(t *testing.T) {
+	svc := New(nil, nil, nil)
+
+	_, err := svc.grpcEndpointGenerator("key1")
+	require.Error(t, err)
+
+	svc.SetGRPCEndpointGenerator(func(keyID string) (string, error) {
+		return "https://" + keyID + ".grpc.example.com", nil
+	})
+
+	endpoint, err := svc.grpcEndpointGenerator("key1")
+	require.NoError(t, err)
+	assert.Equal(t, "https://key1.grpc.example.com", endpoint)
+}
+
+func TestService_ConnManager(t *testing.T) {
+	webConnMng := NewMockConnManager(t)
+	tcpConnMng := NewMockConnManager(t)
+	grpcConnMng := NewMockConnManager(t)
+	udpConnMng := NewMockConnManager(t)
+	tlsConnMng := NewMockConnManager(t)
+
+	svc := New(webConnMng, tcpConnMng, nil)
+
+	assert.Equal(t, noopConnManager{}, svc.connManager(token.TokenTypeGRPC))
+	assert.Equal(t, noopConnManager{}, svc.connManager(token.TokenTypeUDP))
+	assert.Equal(t, noopConnManager{}, svc.connManager(token.TokenTypeTLS))
+
+	svc.SetGRPCConnManager(grpcConnMng)
+	svc.SetUDPConnManager(udpConnMng)
+	svc.SetTLSConnManager(tlsConnMng)
+
+	assert.Same(t, webConnMng, svc.connManager(token.TokenTypeWeb))
+	assert.Same(t, tcpConnMng, svc.connManager(token.TokenTypeTCP))
+	assert.Same(t, grpcConnMng, svc.connManager(token.TokenTypeGRPC))
+	assert.Same(t, udpConnMng, svc.connManager(token.TokenTypeUDP))
+	assert.Same(t, tlsConnMng, svc.connManager(token.TokenTypeTLS))
+}
+
+func TestService_SetTLSEndpointGenerator(t *testing.T) {
+	svc := New(nil, nil, nil)
+
+	_, err := svc.tlsEndpointGenerator("key1")
+	require.Error(t, err)
+
+	svc.SetTLSEndpointGenerator(func(keyID string) (string, error) {
+		return keyID + ".tls.example.com:443", nil
+	})
+
+	endpoint, err := svc.tlsEndpointGenerator("key1")
+	require.NoError(t, err)
+	assert.Equal(t, "key1.tls.example.com:443", endpoint)
+}
+
+func TestService_SetUDPEndpointAllocator(t *testing.T) {
+	svc := New(nil, nil, nil)
+
+	_, err := svc.udpEndpointAllocator.Allocate(t.Context(), "key1")
+	require.Error(t, err)
+
+	alloc := NewMockUDPEndpointAllocator(t)
+	alloc.EXPECT().Allocate(mock.Anything, "key1").Return("udp.example.com:20000", nil)
+
+	svc.SetUDPEndpointAllocator(alloc)
+
+	endpoint, err := svc.udpEndpointAllocator.Allocate(t.Context(), "key1")
+	require.NoError(t, err)
+	assert.Equal(t, "udp.example.com:20000", endpoint)
+}
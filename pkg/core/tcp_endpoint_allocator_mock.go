@@ -23,9 +23,9 @@ func (_m *MockTCPEndpointAllocator) EXPECT() *MockTCPEndpointAllocator_Expecter
 	return &MockTCPEndpointAllocator_Expecter{mock: &_m.Mock}
 }
 
-// Allocate provides a mock function with given fields: ctx, keyID
-func (_m *MockTCPEndpointAllocator) Allocate(ctx context.Context, keyID string) (string, error) {
-	ret := _m.Called(ctx, keyID)
+// Allocate provides a mock function with given fields: ctx, keyID, port
+func (_m *MockTCPEndpointAllocator) Allocate(ctx context.Context, keyID string, port int) (string, error) {
+	ret := _m.Called(ctx, keyID, port)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Allocate")
@@ -33,17 +33,17 @@ func (_m *MockTCPEndpointAllocator) Allocate(ctx context.Context, keyID string)
 
 	var r0 string
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) (string, error)); ok {
-		return rf(ctx, keyID)
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) (string, error)); ok {
+		return rf(ctx, keyID, port)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string) string); ok {
-		r0 = rf(ctx, keyID)
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) string); ok {
+		r0 = rf(ctx, keyID, port)
 	} else {
 		r0 = ret.Get(0).(string)
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = rf(ctx, keyID)
+	if rf, ok := ret.Get(1).(func(context.Context, string, int) error); ok {
+		r1 = rf(ctx, keyID, port)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -59,13 +59,14 @@ type MockTCPEndpointAllocator_Allocate_Call struct {
 // Allocate is a helper method to define mock.On call
 //   - ctx context.Context
 //   - keyID string
-func (_e *MockTCPEndpointAllocator_Expecter) Allocate(ctx interface{}, keyID interface{}) *MockTCPEndpointAllocator_Allocate_Call {
-	return &MockTCPEndpointAllocator_Allocate_Call{Call: _e.mock.On("Allocate", ctx, keyID)}
+//   - port int
+func (_e *MockTCPEndpointAllocator_Expecter) Allocate(ctx interface{}, keyID interface{}, port interface{}) *MockTCPEndpointAllocator_Allocate_Call {
+	return &MockTCPEndpointAllocator_Allocate_Call{Call: _e.mock.On("Allocate", ctx, keyID, port)}
 }
 
-func (_c *MockTCPEndpointAllocator_Allocate_Call) Run(run func(ctx context.Context, keyID string)) *MockTCPEndpointAllocator_Allocate_Call {
+func (_c *MockTCPEndpointAllocator_Allocate_Call) Run(run func(ctx context.Context, keyID string, port int)) *MockTCPEndpointAllocator_Allocate_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(context.Context), args[1].(string))
+		run(args[0].(context.Context), args[1].(string), args[2].(int))
 	})
 	return _c
 }
@@ -75,7 +76,7 @@ func (_c *MockTCPEndpointAllocator_Allocate_Call) Return(_a0 string, _a1 error)
 	return _c
 }
 
-func (_c *MockTCPEndpointAllocator_Allocate_Call) RunAndReturn(run func(context.Context, string) (string, error)) *MockTCPEndpointAllocator_Allocate_Call {
+func (_c *MockTCPEndpointAllocator_Allocate_Call) RunAndReturn(run func(context.Context, string, int) (string, error)) *MockTCPEndpointAllocator_Allocate_Call {
 	_c.Call.Return(run)
 	return _c
 }
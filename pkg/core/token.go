@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
+	"github.com/ksysoev/make-it-public/pkg/core/ipfilter"
 	"github.com/ksysoev/make-it-public/pkg/core/token"
 )
 
@@ -15,20 +17,30 @@ const (
 var (
 	ErrDuplicateTokenID = fmt.Errorf("duplicate token ID")
 	ErrTokenNotFound    = fmt.Errorf("token not found")
+	ErrPortReserved     = fmt.Errorf("port is already reserved")
 )
 
 // GenerateToken generates a new token with the given keyID, time-to-live (TTL), and token type.
 // It attempts to save the token to the authentication repository, retrying on duplicate token ID errors.
 // Accepts ctx which is the context for the request, keyID as the identifier for the token, ttl as the duration in seconds,
-// and tokenType as the type of token (web or tcp).
+// tokenType as the type of token (web, tcp, grpc, udp or tls), an optional ipFilter restricting who can reach the tunnel,
+// and an optional public port reserved for a TCP token.
 // Returns the generated token and an error if generation or saving fails, or if all retry attempts are exhausted.
-func (s *Service) GenerateToken(ctx context.Context, keyID string, ttl int, tokenType token.TokenType) (*token.Token, error) {
+// Returns ErrPortReserved if the port is already reserved by another token.
+func (s *Service) GenerateToken(ctx context.Context, keyID string, ttl int, tokenType token.TokenType, ipFilter *ipfilter.Filter, port int) (*token.Token, error) {
+	if err := token.ValidatePort(port, tokenType); err != nil {
+		return nil, err
+	}
+
 	for i := 0; i < attemptsToGenerateToken; i++ {
 		t, err := token.GenerateToken(keyID, ttl, tokenType)
 		if err != nil {
 			return nil, fmt.Errorf("failed to generate token: %w", err)
 		}
 
+		t.IPFilter = ipFilter
+		t.Port = port
+
 		err = s.auth.SaveToken(ctx, t)
 
 		switch {
@@ -55,3 +67,95 @@ func (s *Service) GenerateToken(ctx context.Context, keyID string, ttl int, toke
 func (s *Service) DeleteToken(ctx context.Context, tokenID string) error {
 	return s.auth.DeleteToken(ctx, tokenID)
 }
+
+// TokenInfo describes a stored token and whether a client is currently connected with it.
+// The secret of Token is never set.
+type TokenInfo struct {
+	Token     *token.Token
+	Connected bool
+}
+
+// GetToken returns the token identified by keyID together with its connection status.
+// Returns ErrTokenNotFound if the token does not exist.
+func (s *Service) GetToken(ctx context.Context, keyID string) (*TokenInfo, error) {
+	t, err := s.auth.GetToken(ctx, keyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get token: %w", err)
+	}
+
+	return s.tokenInfo(ctx, t)
+}
+
+// ListTokens returns all stored tokens together with their connection status.
+func (s *Service) ListTokens(ctx context.Context) ([]*TokenInfo, error) {
+	tokens, err := s.auth.ListTokens(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tokens: %w", err)
+	}
+
+	infos := make([]*TokenInfo, 0, len(tokens))
+
+	for _, t := range tokens {
+		info, err := s.tokenInfo(ctx, t)
+		if err != nil {
+			return nil, err
+		}
+
+		infos = append(infos, info)
+	}
+
+	return infos, nil
+}
+
+// UpdateTokenTTL sets the time remaining until the token identified by keyID expires to ttl seconds.
+// The secret of the token is kept, so connected clients are not affected.
+// Returns the updated token, token.ErrInvalidTokenTTL if ttl is not positive, or ErrTokenNotFound if the token does not exist.
+func (s *Service) UpdateTokenTTL(ctx context.Context, keyID string, ttl int) (*TokenInfo, error) {
+	if ttl <= 0 {
+		return nil, token.ErrInvalidTokenTTL
+	}
+
+	if err := s.auth.UpdateTokenTTL(ctx, keyID, time.Duration(ttl)*time.Second); err != nil {
+		return nil, fmt.Errorf("failed to update token TTL: %w", err)
+	}
+
+	return s.GetToken(ctx, keyID)
+}
+
+// tokenInfo looks up whether a client is connected with t, on this node or, in cluster mode, on any other node.
+func (s *Service) tokenInfo(ctx context.Context, t *token.Token) (*TokenInfo, error) {
+	var managers []ConnManager
+
+	switch t.Type {
+	case token.TokenTypeWeb:
+		managers = []ConnManager{s.webConnMng}
+	case token.TokenTypeTCP:
+		managers = []ConnManager{s.tcpConnMng}
+	case token.TokenTypeGRPC:
+		managers = []ConnManager{s.grpcConnMng}
+	case token.TokenTypeUDP:
+		managers = []ConnManager{s.udpConnMng}
+	case token.TokenTypeTLS:
+		managers = []ConnManager{s.tlsConnMng}
+	default:
+		// Tokens saved before their type was stored may serve either kind of tunnel.
+		managers = []ConnManager{s.webConnMng, s.tcpConnMng}
+	}
+
+	for _, mng := range managers {
+		if _, ok := mng.TunnelAuth(t.ID); ok {
+			return &TokenInfo{Token: t, Connected: true}, nil
+		}
+	}
+
+	_, err := s.connRegistry.TunnelAuth(ctx, t.ID)
+
+	switch {
+	case errors.Is(err, ErrKeyIDNotFound):
+		return &TokenInfo{Token: t}, nil
+	case err != nil:
+		return nil, fmt.Errorf("failed to look up connection status: %w", err)
+	}
+
+	return &TokenInfo{Token: t, Connected: true}, nil
+}
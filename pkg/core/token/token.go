@@ -8,6 +8,8 @@ import (
 	"fmt"
 	"slices"
 	"time"
+
+	"github.com/ksysoev/make-it-public/pkg/core/ipfilter"
 )
 
 const (
@@ -21,7 +23,7 @@ const (
 	defaultTTLSeconds   = 3600 // 1 hour
 )
 
-// TokenType represents the type of token (web or TCP).
+// TokenType represents the type of token (web, TCP, gRPC, UDP or TLS passthrough).
 type TokenType string
 
 const (
@@ -29,40 +31,73 @@ const (
 	TokenTypeWeb TokenType = "w"
 	// TokenTypeTCP represents a token for TCP tunnels.
 	TokenTypeTCP TokenType = "t"
+	// TokenTypeGRPC represents a token for gRPC tunnels.
+	TokenTypeGRPC TokenType = "g"
+	// TokenTypeUDP represents a token for UDP tunnels.
+	TokenTypeUDP TokenType = "u"
+	// TokenTypeTLS represents a token for TLS passthrough tunnels.
+	TokenTypeTLS TokenType = "s"
 )
 
 // String returns the user-facing string representation of the token type.
-// It maps internal codes to readable names: "w" -> "web", "t" -> "tcp".
+// It maps internal codes to readable names: "w" -> "web", "t" -> "tcp", "g" -> "grpc", "u" -> "udp", "s" -> "tls".
 func (t TokenType) String() string {
 	switch t {
 	case TokenTypeWeb:
 		return "web"
 	case TokenTypeTCP:
 		return "tcp"
+	case TokenTypeGRPC:
+		return "grpc"
+	case TokenTypeUDP:
+		return "udp"
+	case TokenTypeTLS:
+		return "tls"
 	default:
 		return string(t)
 	}
 }
 
 type Token struct {
-	ID     string
-	Secret string // #nosec G117 -- This is a field name, not an exposed secret value
-	Type   TokenType
-	TTL    time.Duration
+	// IPFilter restricts the end users allowed to reach the tunnel; nil allows everyone.
+	IPFilter *ipfilter.Filter
+	ID       string
+	Secret   string // #nosec G117 -- This is a field name, not an exposed secret value
+	Type     TokenType
+	TTL      time.Duration
+	// Port is the public port reserved for a TCP token; zero means a port is picked on every connection.
+	Port int
 }
 
 var (
 	ErrTokenTooLong      = fmt.Errorf("token length exceeds maximum limit of %d characters", maxIDLength)
 	ErrTokenInvalid      = fmt.Errorf("token contains invalid characters, only lowercase letters and digits are allowed")
 	ErrInvalidTokenTTL   = fmt.Errorf("ttl must be positive number")
-	ErrInvalidTokenType  = fmt.Errorf("token type must be 'w' (web) or 't' (tcp)")
+	ErrInvalidTokenType  = fmt.Errorf("token type must be 'w' (web), 't' (tcp), 'g' (grpc), 'u' (udp) or 's' (tls)")
 	ErrInvalidTypeSuffix = fmt.Errorf("invalid or missing type suffix in token ID")
+	ErrInvalidPort       = fmt.Errorf("port must be between 1 and 65535")
+	ErrPortNotSupported  = fmt.Errorf("ports can only be reserved for tcp tokens")
 )
 
 // IsValidTokenType checks if the provided token type is valid.
-// It returns true if the type is either TokenTypeWeb or TokenTypeTCP.
+// It returns true if the type is TokenTypeWeb, TokenTypeTCP, TokenTypeGRPC, TokenTypeUDP or TokenTypeTLS.
 func IsValidTokenType(t TokenType) bool {
-	return t == TokenTypeWeb || t == TokenTypeTCP
+	return t == TokenTypeWeb || t == TokenTypeTCP || t == TokenTypeGRPC || t == TokenTypeUDP || t == TokenTypeTLS
+}
+
+// ValidatePort checks that port can be reserved for a token of tokenType. A zero port means no reservation.
+// Returns ErrInvalidPort if the port is out of range, or ErrPortNotSupported if the token is not a TCP token.
+func ValidatePort(port int, tokenType TokenType) error {
+	switch {
+	case port == 0:
+		return nil
+	case port < 1 || port > 65535:
+		return ErrInvalidPort
+	case tokenType != TokenTypeTCP:
+		return ErrPortNotSupported
+	}
+
+	return nil
 }
 
 // GenerateToken creates a new token with the specified keyID, time-to-live (TTL), and token type.
@@ -122,7 +157,7 @@ func GenerateToken(keyID string, ttl int, tokenType TokenType) (*Token, error) {
 }
 
 // IDWithType returns the token ID with the type suffix appended.
-// The format is: <ID>-<type> where <type> is 'w' (web) or 't' (tcp).
+// The format is: <ID>-<type> where <type> is 'w' (web), 't' (tcp), 'g' (grpc), 'u' (udp) or 's' (tls).
 // If the token type is empty, it defaults to TokenTypeWeb.
 func (t *Token) IDWithType() string {
 	tokenType := t.Type
@@ -135,7 +170,7 @@ func (t *Token) IDWithType() string {
 
 // Encode generates a base64-encoded string representation of the token.
 // It combines the token's ID (with type suffix), and Secret, separated by a colon, before encoding.
-// The format is: base64(<ID>-<type>:<Secret>) where <type> is 'w' or 't'.
+// The format is: base64(<ID>-<type>:<Secret>) where <type> is 'w', 't', 'g', 'u' or 's'.
 // This format is backward compatible with old clients that expect only ID:Secret.
 // Returns the encoded token string.
 func (t *Token) Encode() string {
@@ -145,7 +180,7 @@ func (t *Token) Encode() string {
 // ExtractIDAndType extracts the base ID and token type from an ID with a type suffix.
 // It looks for a pattern like "mykey-w" or "mykey-t" and returns the base ID and type.
 // Returns an error if the ID doesn't have a valid type suffix.
-// Valid suffixes are 'w' (web) and 't' (tcp).
+// Valid suffixes are 'w' (web), 't' (tcp), 'g' (grpc), 'u' (udp) and 's' (tls).
 func ExtractIDAndType(idWithSuffix string) (string, TokenType, error) {
 	lastDash := bytes.LastIndexByte([]byte(idWithSuffix), '-')
 	if lastDash == -1 || lastDash == len(idWithSuffix)-1 {
@@ -153,7 +188,7 @@ func ExtractIDAndType(idWithSuffix string) (string, TokenType, error) {
 	}
 
 	suffix := idWithSuffix[lastDash+1:]
-	if suffix != string(TokenTypeWeb) && suffix != string(TokenTypeTCP) {
+	if !IsValidTokenType(TokenType(suffix)) {
 		return "", "", ErrInvalidTypeSuffix
 	}
 
@@ -172,7 +207,7 @@ func extractTypeFromIDWithValidation(id string) (TokenType, bool) {
 	}
 
 	suffix := id[lastDash+1:]
-	if suffix == string(TokenTypeWeb) || suffix == string(TokenTypeTCP) {
+	if IsValidTokenType(TokenType(suffix)) {
 		return TokenType(suffix), true
 	}
 
@@ -182,7 +217,7 @@ func extractTypeFromIDWithValidation(id string) (TokenType, bool) {
 // Decode parses a base64-encoded string into a Token instance.
 // It validates the encoding and token format, ensuring data integrity.
 // Supports two formats:
-// 1. New format: base64(<ID>-<type>:<Secret>) where <type> is 'w' or 't'
+// 1. New format: base64(<ID>-<type>:<Secret>) where <type> is 'w', 't', 'g', 'u' or 's'
 // 2. Old format: base64(<ID>:<Secret>) defaults to TokenTypeWeb
 // Accepts encoded which is a base64-encoded string containing token ID and Secret.
 // Returns a Token containing the Type, ID and Secret if decoding is successful.
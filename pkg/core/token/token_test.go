@@ -127,6 +127,31 @@ func TestDecode(t *testing.T) {
 		assert.Equal(t, TokenTypeTCP, token.Type, "Decoded token type should be TCP")
 	})
 
+	t.Run("Decode valid gRPC token (new format with suffix)", func(t *testing.T) {
+		encoded := base64.StdEncoding.EncodeToString([]byte("testID-g:testSecret"))
+		token, err := Decode(encoded)
+		assert.NoError(t, err, "Decoding should not return an error")
+		assert.Equal(t, "testID", token.ID, "Decoded token ID should match")
+		assert.Equal(t, "testSecret", token.Secret, "Decoded token Secret should match")
+		assert.Equal(t, TokenTypeGRPC, token.Type, "Decoded token type should be gRPC")
+	})
+
+	t.Run("Decode valid UDP token (new format with suffix)", func(t *testing.T) {
+		encoded := base64.StdEncoding.EncodeToString([]byte("testID-u:testSecret"))
+		token, err := Decode(encoded)
+		assert.NoError(t, err, "Decoding should not return an error")
+		assert.Equal(t, "testID", token.ID, "Decoded token ID should match")
+		assert.Equal(t, TokenTypeUDP, token.Type, "Decoded token type should be UDP")
+	})
+
+	t.Run("Decode valid TLS token (new format with suffix)", func(t *testing.T) {
+		encoded := base64.StdEncoding.EncodeToString([]byte("testID-s:testSecret"))
+		token, err := Decode(encoded)
+		assert.NoError(t, err, "Decoding should not return an error")
+		assert.Equal(t, "testID", token.ID, "Decoded token ID should match")
+		assert.Equal(t, TokenTypeTLS, token.Type, "Decoded token type should be TLS")
+	})
+
 	t.Run("Decode old token without type prefix defaults to web", func(t *testing.T) {
 		// Old format without type prefix - 2-part format
 		encoded := base64.StdEncoding.EncodeToString([]byte("abc123:testSecret"))
@@ -238,6 +263,18 @@ func TestTokenTypeString(t *testing.T) {
 		assert.Equal(t, "tcp", TokenTypeTCP.String(), "TokenTypeTCP.String() should return 'tcp'")
 	})
 
+	t.Run("TokenTypeGRPC String() returns 'grpc'", func(t *testing.T) {
+		assert.Equal(t, "grpc", TokenTypeGRPC.String(), "TokenTypeGRPC.String() should return 'grpc'")
+	})
+
+	t.Run("TokenTypeUDP String() returns 'udp'", func(t *testing.T) {
+		assert.Equal(t, "udp", TokenTypeUDP.String(), "TokenTypeUDP.String() should return 'udp'")
+	})
+
+	t.Run("TokenTypeTLS String() returns 'tls'", func(t *testing.T) {
+		assert.Equal(t, "tls", TokenTypeTLS.String(), "TokenTypeTLS.String() should return 'tls'")
+	})
+
 	t.Run("Invalid TokenType String() returns raw value", func(t *testing.T) {
 		invalid := TokenType("x")
 		assert.Equal(t, "x", invalid.String(), "Invalid TokenType.String() should return raw value")
@@ -261,6 +298,14 @@ func TestToken_IDWithType(t *testing.T) {
 		assert.Equal(t, "mykey-t", token.IDWithType())
 	})
 
+	t.Run("gRPC token returns ID with -g suffix", func(t *testing.T) {
+		token := &Token{
+			ID:   "mykey",
+			Type: TokenTypeGRPC,
+		}
+		assert.Equal(t, "mykey-g", token.IDWithType())
+	})
+
 	t.Run("Token without type defaults to web", func(t *testing.T) {
 		token := &Token{
 			ID:   "mykey",
@@ -285,6 +330,27 @@ func TestExtractIDAndType(t *testing.T) {
 		assert.Equal(t, TokenTypeTCP, tokenType)
 	})
 
+	t.Run("Extract gRPC token type", func(t *testing.T) {
+		id, tokenType, err := ExtractIDAndType("mykey-g")
+		assert.NoError(t, err)
+		assert.Equal(t, "mykey", id)
+		assert.Equal(t, TokenTypeGRPC, tokenType)
+	})
+
+	t.Run("Extract UDP token type", func(t *testing.T) {
+		id, tokenType, err := ExtractIDAndType("mykey-u")
+		assert.NoError(t, err)
+		assert.Equal(t, "mykey", id)
+		assert.Equal(t, TokenTypeUDP, tokenType)
+	})
+
+	t.Run("Extract TLS token type", func(t *testing.T) {
+		id, tokenType, err := ExtractIDAndType("mykey-s")
+		assert.NoError(t, err)
+		assert.Equal(t, "mykey", id)
+		assert.Equal(t, TokenTypeTLS, tokenType)
+	})
+
 	t.Run("ID without suffix returns error", func(t *testing.T) {
 		id, tokenType, err := ExtractIDAndType("mykey")
 		assert.ErrorIs(t, err, ErrInvalidTypeSuffix)
@@ -313,3 +379,11 @@ func TestExtractIDAndType(t *testing.T) {
 		assert.Equal(t, TokenTypeWeb, tokenType)
 	})
 }
+
+func TestValidatePort(t *testing.T) {
+	assert.NoError(t, ValidatePort(0, TokenTypeWeb))
+	assert.NoError(t, ValidatePort(30000, TokenTypeTCP))
+	assert.ErrorIs(t, ValidatePort(30000, TokenTypeWeb), ErrPortNotSupported)
+	assert.ErrorIs(t, ValidatePort(-1, TokenTypeTCP), ErrInvalidPort)
+	assert.ErrorIs(t, ValidatePort(65536, TokenTypeTCP), ErrInvalidPort)
+}
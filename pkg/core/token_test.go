@@ -25,7 +25,7 @@ func TestService_GenerateToken(t *testing.T) {
 			})).Return(nil)
 
 		// Execute
-		tkn, err := svc.GenerateToken(context.Background(), "", 0, token.TokenTypeWeb)
+		tkn, err := svc.GenerateToken(context.Background(), "", 0, token.TokenTypeWeb, nil, 0)
 
 		// Assert
 		require.NoError(t, err)
@@ -48,7 +48,7 @@ func TestService_GenerateToken(t *testing.T) {
 			})).Return(nil)
 
 		// Execute
-		tkn, err := svc.GenerateToken(context.Background(), keyID, ttl, token.TokenTypeWeb)
+		tkn, err := svc.GenerateToken(context.Background(), keyID, ttl, token.TokenTypeWeb, nil, 0)
 
 		// Assert
 		require.NoError(t, err)
@@ -64,7 +64,7 @@ func TestService_GenerateToken(t *testing.T) {
 		keyID := "INVALID_KEY!" // Contains invalid characters
 
 		// Execute
-		tkn, err := svc.GenerateToken(context.Background(), keyID, 0, token.TokenTypeWeb)
+		tkn, err := svc.GenerateToken(context.Background(), keyID, 0, token.TokenTypeWeb, nil, 0)
 
 		// Assert
 		require.Error(t, err)
@@ -80,7 +80,7 @@ func TestService_GenerateToken(t *testing.T) {
 		keyID := "thisistoolongforatokenid" // Exceeds maxIDLength
 
 		// Execute
-		tkn, err := svc.GenerateToken(context.Background(), keyID, 0, token.TokenTypeWeb)
+		tkn, err := svc.GenerateToken(context.Background(), keyID, 0, token.TokenTypeWeb, nil, 0)
 
 		// Assert
 		require.Error(t, err)
@@ -95,7 +95,7 @@ func TestService_GenerateToken(t *testing.T) {
 		svc := New(nil, nil, mockAuth)
 
 		// Execute
-		tkn, err := svc.GenerateToken(context.Background(), "validkeyid", -1, token.TokenTypeWeb) // Negative TTL
+		tkn, err := svc.GenerateToken(context.Background(), "validkeyid", -1, token.TokenTypeWeb, nil, 0) // Negative TTL
 
 		// Assert
 		require.Error(t, err)
@@ -117,7 +117,7 @@ func TestService_GenerateToken(t *testing.T) {
 			})).Return(expectedErr)
 
 		// Execute
-		tkn, err := svc.GenerateToken(context.Background(), "", 0, token.TokenTypeWeb)
+		tkn, err := svc.GenerateToken(context.Background(), "", 0, token.TokenTypeWeb, nil, 0)
 
 		// Assert
 		require.Error(t, err)
@@ -138,7 +138,7 @@ func TestService_GenerateToken(t *testing.T) {
 			})).Return(ErrDuplicateTokenID)
 
 		// Execute
-		tkn, err := svc.GenerateToken(context.Background(), keyID, 0, token.TokenTypeWeb)
+		tkn, err := svc.GenerateToken(context.Background(), keyID, 0, token.TokenTypeWeb, nil, 0)
 
 		// Assert
 		require.Error(t, err)
@@ -168,7 +168,7 @@ func TestService_GenerateToken(t *testing.T) {
 		})
 
 		// Execute
-		tkn, err := svc.GenerateToken(context.Background(), "", 0, token.TokenTypeWeb)
+		tkn, err := svc.GenerateToken(context.Background(), "", 0, token.TokenTypeWeb, nil, 0)
 
 		// Assert
 		require.NoError(t, err)
@@ -191,13 +191,58 @@ func TestService_GenerateToken(t *testing.T) {
 		}
 
 		// Execute
-		tkn, err := svc.GenerateToken(context.Background(), "", 0, token.TokenTypeWeb)
+		tkn, err := svc.GenerateToken(context.Background(), "", 0, token.TokenTypeWeb, nil, 0)
 
 		// Assert
 		require.Error(t, err)
 		assert.Nil(t, tkn)
 		assert.Contains(t, err.Error(), "failed to generate token after")
 	})
+
+	t.Run("tcp token with reserved port", func(t *testing.T) {
+		mockAuth := NewMockAuthRepo(t)
+		svc := New(nil, nil, mockAuth)
+
+		mockAuth.EXPECT().SaveToken(context.Background(),
+			mock.MatchedBy(func(t *token.Token) bool {
+				return t.Type == token.TokenTypeTCP && t.Port == 30000
+			})).Return(nil)
+
+		tkn, err := svc.GenerateToken(context.Background(), "", 0, token.TokenTypeTCP, nil, 30000)
+
+		require.NoError(t, err)
+		assert.Equal(t, 30000, tkn.Port)
+	})
+
+	t.Run("port already reserved", func(t *testing.T) {
+		mockAuth := NewMockAuthRepo(t)
+		svc := New(nil, nil, mockAuth)
+
+		mockAuth.EXPECT().SaveToken(context.Background(), mock.Anything).Return(ErrPortReserved)
+
+		tkn, err := svc.GenerateToken(context.Background(), "", 0, token.TokenTypeTCP, nil, 30000)
+
+		assert.ErrorIs(t, err, ErrPortReserved)
+		assert.Nil(t, tkn)
+	})
+
+	t.Run("port for web token", func(t *testing.T) {
+		svc := New(nil, nil, NewMockAuthRepo(t))
+
+		tkn, err := svc.GenerateToken(context.Background(), "", 0, token.TokenTypeWeb, nil, 30000)
+
+		assert.ErrorIs(t, err, token.ErrPortNotSupported)
+		assert.Nil(t, tkn)
+	})
+
+	t.Run("invalid port", func(t *testing.T) {
+		svc := New(nil, nil, NewMockAuthRepo(t))
+
+		tkn, err := svc.GenerateToken(context.Background(), "", 0, token.TokenTypeTCP, nil, 70000)
+
+		assert.ErrorIs(t, err, token.ErrInvalidPort)
+		assert.Nil(t, tkn)
+	})
 }
 
 func TestService_DeleteToken(t *testing.T) {
@@ -235,3 +280,122 @@ func TestService_DeleteToken(t *testing.T) {
 		assert.ErrorIs(t, err, ErrTokenNotFound)
 	})
 }
+
+func TestService_GetToken(t *testing.T) {
+	tests := []struct {
+		registryErr   error
+		repoErr       error
+		wantErr       error
+		name          string
+		tokenType     token.TokenType
+		webConnected  bool
+		tcpConnected  bool
+		wantConnected bool
+	}{
+		{name: "web token connected locally", tokenType: token.TokenTypeWeb, webConnected: true, wantConnected: true},
+		{name: "tcp token connected locally", tokenType: token.TokenTypeTCP, tcpConnected: true, wantConnected: true},
+		{name: "legacy token connected over tcp", tcpConnected: true, wantConnected: true},
+		{name: "connected on another node", tokenType: token.TokenTypeWeb, wantConnected: true},
+		{name: "not connected", tokenType: token.TokenTypeWeb, registryErr: ErrKeyIDNotFound},
+		{name: "registry failure", tokenType: token.TokenTypeWeb, registryErr: assert.AnError, wantErr: assert.AnError},
+		{name: "token not found", repoErr: ErrTokenNotFound, wantErr: ErrTokenNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockAuth := NewMockAuthRepo(t)
+			webConnMng := NewMockConnManager(t)
+			tcpConnMng := NewMockConnManager(t)
+			registry := NewMockConnRegistry(t)
+
+			svc := New(webConnMng, tcpConnMng, mockAuth)
+			svc.SetConnRegistry(registry)
+
+			if tt.repoErr != nil {
+				mockAuth.EXPECT().GetToken(mock.Anything, "key1").Return(nil, tt.repoErr)
+			} else {
+				mockAuth.EXPECT().GetToken(mock.Anything, "key1").Return(&token.Token{ID: "key1", Type: tt.tokenType, TTL: time.Hour}, nil)
+			}
+
+			if tt.repoErr == nil && tt.tokenType != token.TokenTypeTCP {
+				webConnMng.EXPECT().TunnelAuth("key1").Return(nil, tt.webConnected)
+			}
+
+			if tt.repoErr == nil && tt.tokenType != token.TokenTypeWeb && !tt.webConnected {
+				tcpConnMng.EXPECT().TunnelAuth("key1").Return(nil, tt.tcpConnected)
+			}
+
+			if tt.repoErr == nil && !tt.webConnected && !tt.tcpConnected {
+				registry.EXPECT().TunnelAuth(mock.Anything, "key1").Return(nil, tt.registryErr)
+			}
+
+			info, err := svc.GetToken(context.Background(), "key1")
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, "key1", info.Token.ID)
+			assert.Equal(t, tt.wantConnected, info.Connected)
+		})
+	}
+}
+
+func TestService_GetToken_GRPC(t *testing.T) {
+	mockAuth := NewMockAuthRepo(t)
+	mockAuth.EXPECT().GetToken(mock.Anything, "key1").Return(&token.Token{ID: "key1", Type: token.TokenTypeGRPC, TTL: time.Hour}, nil)
+
+	grpcConnMng := NewMockConnManager(t)
+	grpcConnMng.EXPECT().TunnelAuth("key1").Return(nil, true)
+
+	// Web and TCP connection managers must not be consulted for gRPC tokens.
+	svc := New(NewMockConnManager(t), NewMockConnManager(t), mockAuth)
+	svc.SetGRPCConnManager(grpcConnMng)
+
+	info, err := svc.GetToken(context.Background(), "key1")
+	require.NoError(t, err)
+	assert.True(t, info.Connected)
+}
+
+func TestService_ListTokens(t *testing.T) {
+	mockAuth := NewMockAuthRepo(t)
+	webConnMng := NewMockConnManager(t)
+	svc := New(webConnMng, nil, mockAuth)
+
+	mockAuth.EXPECT().ListTokens(mock.Anything).Return([]*token.Token{
+		{ID: "a", Type: token.TokenTypeWeb},
+		{ID: "b", Type: token.TokenTypeWeb},
+	}, nil)
+	webConnMng.EXPECT().TunnelAuth("a").Return(nil, true)
+	webConnMng.EXPECT().TunnelAuth("b").Return(nil, false)
+
+	infos, err := svc.ListTokens(context.Background())
+	require.NoError(t, err)
+	require.Len(t, infos, 2)
+	assert.True(t, infos[0].Connected)
+	assert.False(t, infos[1].Connected)
+}
+
+func TestService_UpdateTokenTTL(t *testing.T) {
+	mockAuth := NewMockAuthRepo(t)
+	webConnMng := NewMockConnManager(t)
+	svc := New(webConnMng, nil, mockAuth)
+
+	_, err := svc.UpdateTokenTTL(context.Background(), "key1", 0)
+	assert.ErrorIs(t, err, token.ErrInvalidTokenTTL)
+
+	mockAuth.EXPECT().UpdateTokenTTL(mock.Anything, "missing", 2*time.Hour).Return(ErrTokenNotFound).Once()
+
+	_, err = svc.UpdateTokenTTL(context.Background(), "missing", 7200)
+	assert.ErrorIs(t, err, ErrTokenNotFound)
+
+	mockAuth.EXPECT().UpdateTokenTTL(mock.Anything, "key1", 2*time.Hour).Return(nil).Once()
+	mockAuth.EXPECT().GetToken(mock.Anything, "key1").Return(&token.Token{ID: "key1", Type: token.TokenTypeWeb, TTL: 2 * time.Hour}, nil)
+	webConnMng.EXPECT().TunnelAuth("key1").Return(nil, true)
+
+	info, err := svc.UpdateTokenTTL(context.Background(), "key1", 7200)
+	require.NoError(t, err)
+	assert.Equal(t, 2*time.Hour, info.Token.TTL)
+	assert.True(t, info.Connected)
+}
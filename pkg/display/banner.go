@@ -14,17 +14,11 @@ const (
 )
 
 // ShowConnected displays a colorful banner with the public URL and forwarding info.
-// tokenType should be "t" for TCP or "w" (or empty) for web/HTTP tunnels.
+// tokenType should be "t" for TCP, "g" for gRPC, "u" for UDP, "s" for TLS passthrough or "w" (or empty) for web/HTTP tunnels.
 // In interactive mode, shows a colorful banner.
 // In non-interactive mode, logs connection details using structured logging.
 func (d *Display) ShowConnected(publicURL, localAddr, tokenType string) {
-	label := "Public URL"
-	logKey := "public_url"
-
-	if tokenType == "t" {
-		label = "TCP Endpoint"
-		logKey = "tcp_endpoint"
-	}
+	label, logKey := endpointLabel(tokenType)
 
 	if !d.interactive {
 		// In non-interactive mode, log connection info using slog
@@ -93,6 +87,110 @@ func (d *Display) ShowConnected(publicURL, localAddr, tokenType string) {
 	fmt.Fprintln(d.out)
 }
 
+// endpointLabel returns the banner label and the log key of the public endpoint of a tunnel with tokenType.
+func endpointLabel(tokenType string) (label, logKey string) {
+	switch tokenType {
+	case "t":
+		return "TCP Endpoint", "tcp_endpoint"
+	case "g":
+		return "gRPC Endpoint", "grpc_endpoint"
+	case "u":
+		return "UDP Endpoint", "udp_endpoint"
+	case "s":
+		return "TLS Endpoint", "tls_endpoint"
+	default:
+		return "Public URL", "public_url"
+	}
+}
+
+// TunnelInfo describes the state of a tunnel listed by ShowTunnels.
+// PublicURL is empty while the tunnel is connecting, and Err is set once it has failed.
+type TunnelInfo struct {
+	Err       error
+	Name      string
+	PublicURL string
+	LocalAddr string
+	TokenType string
+}
+
+// ShowTunnels displays the state of all the tunnels of a client exposing several tunnels.
+// updated is the index of the tunnel whose state has changed since the last call.
+// In interactive mode, shows a single banner listing every tunnel.
+// In non-interactive mode, logs the state of the updated tunnel using structured logging.
+func (d *Display) ShowTunnels(tunnels []TunnelInfo, updated int) {
+	if !d.interactive {
+		if updated < 0 || updated >= len(tunnels) {
+			return
+		}
+
+		t := tunnels[updated]
+		_, logKey := endpointLabel(t.TokenType)
+
+		switch {
+		case t.Err != nil:
+			slog.Error("tunnel is no longer publicly accessible", slog.String("tunnel", t.Name), slog.Any("error", t.Err))
+		case t.PublicURL != "":
+			slog.Info("service is now publicly accessible",
+				slog.String("tunnel", t.Name),
+				slog.String(logKey, t.PublicURL),
+				slog.String("forwarding", t.LocalAddr))
+		}
+
+		return
+	}
+
+	borderColor := color.New(color.FgCyan, color.Bold)
+	titleColor := color.New(color.FgGreen, color.Bold)
+	successColor := color.New(color.FgGreen)
+	failColor := color.New(color.FgRed, color.Bold)
+	nameColor := color.New(color.FgWhite, color.Bold)
+	labelColor := color.New(color.FgWhite)
+	urlColor := color.New(color.FgHiCyan, color.Bold)
+	addrColor := color.New(color.FgYellow)
+	hintColor := color.New(color.FgHiBlack)
+
+	connected := 0
+
+	for _, t := range tunnels {
+		if t.PublicURL != "" {
+			connected++
+		}
+	}
+
+	fmt.Fprintln(d.out)
+	borderColor.Fprintln(d.out, "+"+strings.Repeat("=", bannerWidth-2)+"+")
+	d.printBannerEmptyLine(borderColor)
+	d.printBannerLineColored(borderColor, titleColor, "make-it-public")
+	d.printBannerEmptyLine(borderColor)
+	d.printBannerLineWithPrefix(borderColor, successColor, "[OK]",
+		fmt.Sprintf(" %d of %d tunnels are publicly accessible", connected, len(tunnels)))
+
+	for _, t := range tunnels {
+		label, _ := endpointLabel(t.TokenType)
+
+		d.printBannerEmptyLine(borderColor)
+		d.printBannerLineColored(borderColor, nameColor, t.Name)
+
+		switch {
+		case t.Err != nil:
+			d.printBannerLineWithPrefix(borderColor, failColor, "[ERR]", " "+t.Err.Error())
+		case t.PublicURL == "":
+			d.printBannerKeyValue(borderColor, labelColor, hintColor, label, "connecting...")
+		default:
+			d.printBannerKeyValue(borderColor, labelColor, urlColor, label, t.PublicURL)
+		}
+
+		d.printBannerKeyValue(borderColor, labelColor, addrColor, "Forwarding", t.LocalAddr)
+	}
+
+	d.printBannerEmptyLine(borderColor)
+	d.printBannerLineColored(borderColor, hintColor, "Press Ctrl+C to disconnect")
+	d.printBannerEmptyLine(borderColor)
+	borderColor.Fprintln(d.out, "+"+strings.Repeat("=", bannerWidth-2)+"+")
+
+	fmt.Fprintln(d.out)
+}
+
 // printBannerEmptyLine prints an empty line within the banner borders.
 func (d *Display) printBannerEmptyLine(borderColor *color.Color) {
 	borderColor.Fprint(d.out, "|")
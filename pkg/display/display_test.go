@@ -129,6 +129,60 @@ func TestDisplay_ShowConnected(t *testing.T) {
 		assert.Contains(t, output, "localhost:5432")
 	})
 
+	t.Run("gRPC token shows gRPC Endpoint label", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		disp := &Display{
+			out:         &buf,
+			errOut:      &buf,
+			interactive: true,
+			noColor:     true,
+		}
+
+		disp.ShowConnected("https://mykey.grpc.example.com", "localhost:50051", "g")
+
+		output := buf.String()
+		assert.Contains(t, output, "gRPC Endpoint")
+		assert.NotContains(t, output, "Public URL")
+		assert.Contains(t, output, "https://mykey.grpc.example.com")
+	})
+
+	t.Run("UDP token shows UDP Endpoint label", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		disp := &Display{
+			out:         &buf,
+			errOut:      &buf,
+			interactive: true,
+			noColor:     true,
+		}
+
+		disp.ShowConnected("udp.example.com:20042", "localhost:53", "u")
+
+		output := buf.String()
+		assert.Contains(t, output, "UDP Endpoint")
+		assert.NotContains(t, output, "Public URL")
+		assert.Contains(t, output, "udp.example.com:20042")
+	})
+
+	t.Run("TLS token shows TLS Endpoint label", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		disp := &Display{
+			out:         &buf,
+			errOut:      &buf,
+			interactive: true,
+			noColor:     true,
+		}
+
+		disp.ShowConnected("mykey.tls.example.com:443", "localhost:8443", "s")
+
+		output := buf.String()
+		assert.Contains(t, output, "TLS Endpoint")
+		assert.NotContains(t, output, "Public URL")
+		assert.Contains(t, output, "mykey.tls.example.com:443")
+	})
+
 	t.Run("web token shows Public URL label", func(t *testing.T) {
 		var buf bytes.Buffer
 
@@ -374,6 +428,53 @@ func TestBannerFormatting(t *testing.T) {
 	})
 }
 
+func TestDisplay_ShowTunnels(t *testing.T) {
+	tunnels := []TunnelInfo{
+		{Name: "api", PublicURL: "https://api.example.com", LocalAddr: "localhost:8080", TokenType: "w"},
+		{Name: "db", LocalAddr: "localhost:5432", TokenType: "t"},
+		{Name: "dns", LocalAddr: "localhost:53", TokenType: "u", Err: errors.New("connection refused")},
+	}
+
+	t.Run("interactive mode lists every tunnel in one banner", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		disp := &Display{
+			out:         &buf,
+			errOut:      &buf,
+			interactive: true,
+			noColor:     true,
+		}
+
+		disp.ShowTunnels(tunnels, 0)
+
+		output := buf.String()
+		assert.Equal(t, 1, strings.Count(output, "make-it-public"))
+		assert.Contains(t, output, "1 of 3 tunnels are publicly accessible")
+		assert.Contains(t, output, "https://api.example.com")
+		assert.Contains(t, output, "localhost:8080")
+		assert.Contains(t, output, "TCP Endpoint   connecting...")
+		assert.Contains(t, output, "localhost:5432")
+		assert.Contains(t, output, "[ERR] connection refused")
+		assert.Contains(t, output, "localhost:53")
+	})
+
+	t.Run("non-interactive mode logs to slog", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		disp := &Display{
+			out:         &buf,
+			errOut:      &buf,
+			interactive: false,
+			noColor:     true,
+		}
+
+		disp.ShowTunnels(tunnels, 2)
+		disp.ShowTunnels(tunnels, 5)
+
+		assert.Empty(t, buf.String(), "stdout should be empty in non-interactive mode")
+	})
+}
+
 func TestDisplay_ShowRequestSeparator(t *testing.T) {
 	t.Run("interactive mode shows separator with client IP", func(t *testing.T) {
 		var buf bytes.Buffer
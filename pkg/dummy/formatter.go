@@ -42,6 +42,23 @@ func NewFormatterRegistry() *FormatterRegistry {
 	}
 }
 
+// NewDefaultFormatterRegistry creates a FormatterRegistry with formatters for every content type
+// supported out of the box: JSON, YAML, URL-encoded and multipart forms, and any text type.
+func NewDefaultFormatterRegistry() *FormatterRegistry {
+	registry := NewFormatterRegistry()
+	registry.Register(contentTypeJSON, NewJSONFormatter())
+	registry.Register("application/x-www-form-urlencoded", NewFormURLEncodedFormatter())
+
+	yamlFormatter := NewYAMLFormatter()
+	registry.Register("application/yaml", yamlFormatter)
+	registry.Register("application/x-yaml", yamlFormatter)
+	registry.Register("text/yaml", yamlFormatter)
+	registry.Register("multipart/form-data", NewMultipartFormatter())
+	registry.RegisterPrefix("text/", NewTextFormatter())
+
+	return registry
+}
+
 // Register adds a formatter for an exact content type match.
 // Accepts contentType as the MIME type string and formatter as the ContentFormatter implementation.
 func (r *FormatterRegistry) Register(contentType string, formatter ContentFormatter) {
@@ -78,6 +95,20 @@ func (r *FormatterRegistry) Get(contentType string) (ContentFormatter, bool) {
 	return nil, false
 }
 
+// Match retrieves the formatter for the value of a Content-Type header.
+// Accepts contentType as the full header value, including parameters.
+// Returns the ContentFormatter, the MIME type parameters to pass to it, and true if found, or false if not found.
+func (r *FormatterRegistry) Match(contentType string) (ContentFormatter, map[string]string, bool) {
+	mediaType, params := parseContentType(contentType)
+
+	formatter, ok := r.Get(mediaType)
+	if !ok {
+		return nil, nil, false
+	}
+
+	return formatter, params, true
+}
+
 // parseContentType extracts the MIME type and parameters from a Content-Type header.
 // Accepts contentType as the full Content-Type header value.
 // Returns the base MIME type and a map of parameters (e.g., charset, boundary).
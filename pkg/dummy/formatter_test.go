@@ -60,6 +60,18 @@ func TestFormatterRegistry(t *testing.T) {
 		_, ok := registry.Get("application/json")
 		assert.False(t, ok)
 	})
+
+	t.Run("match header value", func(t *testing.T) {
+		registry := NewDefaultFormatterRegistry()
+
+		formatter, params, ok := registry.Match("multipart/form-data; boundary=abc")
+		assert.True(t, ok)
+		assert.IsType(t, &MultipartFormatter{}, formatter)
+		assert.Equal(t, "abc", params["boundary"])
+
+		_, _, ok = registry.Match("application/octet-stream")
+		assert.False(t, ok)
+	})
 }
 
 func TestParseContentType(t *testing.T) {
@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
-	"net"
 	"net/http"
 	"os"
 	"sort"
@@ -13,14 +12,22 @@ import (
 	"time"
 
 	"github.com/fatih/color"
+	"github.com/ksysoev/make-it-public/pkg/target"
 )
 
 const (
 	contentTypeJSON  = "application/json"
 	contentTypePlain = "text/plain"
+
+	// defaultListen is the address local servers listen on when none is configured: a random port on localhost.
+	defaultListen = "localhost:0"
 )
 
+// Config holds the configuration of the dummy HTTP server.
+// Listen is the address the server listens on in the form accepted by target.Parse, such as localhost:8080
+// or unix:///tmp/app.sock; a random port on localhost is used when it is empty.
 type Config struct {
+	Listen      string   `mapstructure:"listen"`
 	Body        string   `mapstructure:"body"`
 	JSON        string   `mapstructure:"json"`
 	Headers     []string `mapstructure:"headers"`
@@ -39,19 +46,25 @@ type Server struct {
 	registry    *FormatterRegistry
 	isReady     chan struct{}
 	addr        string
+	listen      target.Target
 	resp        Response
 	interactive bool
 }
 
 // New creates and initializes a new Server instance configured with the provided settings.
 // It validates the Config parameters and determines the response type (JSON or plain text).
-// Accepts cfg Config containing the response body, JSON string, HTTP status code, and custom headers.
-// Returns a pointer to the Server instance and an error if the configuration is invalid (e.g., status code out of range, both body and JSON set, malformed headers).
+// Accepts cfg Config containing the listen address, response body, JSON string, HTTP status code, and custom headers.
+// Returns a pointer to the Server instance and an error if the configuration is invalid (e.g., status code out of range, both body and JSON set, malformed headers, invalid listen address).
 func New(cfg Config) (*Server, error) {
 	if cfg.Status < 200 || cfg.Status >= 600 {
 		return nil, fmt.Errorf("invalid status code: %d", cfg.Status)
 	}
 
+	listen, err := parseListen(cfg.Listen)
+	if err != nil {
+		return nil, err
+	}
+
 	resp := Response{
 		Status:  cfg.Status,
 		Headers: make(http.Header),
@@ -85,39 +98,47 @@ func New(cfg Config) (*Server, error) {
 		resp.Headers.Add(headerName, headerValue)
 	}
 
-	// Initialize formatter registry
-	registry := NewFormatterRegistry()
-	registry.Register(contentTypeJSON, NewJSONFormatter())
-	registry.Register("application/x-www-form-urlencoded", NewFormURLEncodedFormatter())
-
-	yamlFormatter := NewYAMLFormatter()
-	registry.Register("application/yaml", yamlFormatter)
-	registry.Register("application/x-yaml", yamlFormatter)
-	registry.Register("text/yaml", yamlFormatter)
-	registry.Register("multipart/form-data", NewMultipartFormatter())
-	registry.RegisterPrefix("text/", NewTextFormatter())
-
 	return &Server{
 		isReady:     make(chan struct{}),
-		registry:    registry,
+		registry:    NewDefaultFormatterRegistry(),
+		listen:      listen,
 		resp:        resp,
 		interactive: cfg.Interactive,
 	}, nil
 }
 
+// parseListen parses the listen address of a local server, defaulting to a random port on localhost.
+// Returns an error if addr is not a valid target or is an https:// URL, as local servers do not serve TLS.
+func parseListen(addr string) (target.Target, error) {
+	if addr == "" {
+		addr = defaultListen
+	}
+
+	listen, err := target.Parse(addr)
+	if err != nil {
+		return target.Target{}, fmt.Errorf("invalid listen address: %w", err)
+	}
+
+	if listen.TLS {
+		return target.Target{}, fmt.Errorf("invalid listen address %s: TLS is not supported", addr)
+	}
+
+	return listen, nil
+}
+
 // Run starts the server and listens for incoming HTTP connections.
-// It initializes a TCP listener on a random port, announces readiness by closing the isReady channel,
+// It initializes a listener on the configured address, announces readiness by closing the isReady channel,
 // and serves HTTP requests using the Server instance.
 // Accepts ctx to manage the server lifecycle and handle shutdown signals.
 // Returns an error if the listener fails to start or the server encounters issues during execution.
 func (s *Server) Run(ctx context.Context) error {
-	l, err := net.Listen("tcp", "localhost:0")
+	l, err := s.listen.Listen(ctx)
 	if err != nil {
 		close(s.isReady)
 		return fmt.Errorf("failed to start local server: %w", err)
 	}
 
-	s.addr = l.Addr().String()
+	s.addr = target.FromAddr(l.Addr()).String()
 
 	srv := http.Server{
 		Handler:           s,
@@ -140,7 +161,7 @@ func (s *Server) Run(ctx context.Context) error {
 
 // Addr waits for the server to be ready and retrieves the bound address as a string.
 // It blocks until the readiness signal is received by reading from isReady channel.
-// Returns the server's address in "host:port" format.
+// Returns the server's address in the form accepted by target.Parse, such as "host:port" or "unix:///path".
 func (s *Server) Addr() string {
 	<-s.isReady
 	return s.addr
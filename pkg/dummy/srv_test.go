@@ -6,9 +6,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"sort"
 	"strings"
 	"testing"
@@ -85,6 +87,21 @@ func TestNew(t *testing.T) {
 			config:      Config{Status: 200, Headers: []string{"X-Spaces-Header:   "}},
 			expectError: false,
 		},
+		{
+			name:        "Valid unix socket listen address",
+			config:      Config{Status: 200, Listen: "unix:///tmp/app.sock"},
+			expectError: false,
+		},
+		{
+			name:        "Invalid listen address",
+			config:      Config{Status: 200, Listen: "ftp://localhost"},
+			expectError: true,
+		},
+		{
+			name:        "TLS listen address",
+			config:      Config{Status: 200, Listen: "https://localhost:8443"},
+			expectError: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -158,6 +175,58 @@ func TestRun(t *testing.T) {
 	}
 }
 
+func TestRun_UnixSocket(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "app.sock")
+
+	server, err := New(Config{Status: 200, Body: "ok", Listen: "unix://" + sock})
+	require.NoError(t, err, "Failed to create server")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errCh := make(chan error, 1)
+
+	go func() {
+		errCh <- server.Run(ctx)
+	}()
+
+	assert.Equal(t, "unix://"+sock, server.Addr())
+
+	client := &http.Client{
+		Timeout: 2 * time.Second,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", sock)
+			},
+		},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://localhost/", http.NoBody)
+	require.NoError(t, err)
+
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", string(body))
+
+	cancel()
+
+	select {
+	case err := <-errCh:
+		assert.Error(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("Server did not stop within timeout")
+	}
+
+	// The socket file is removed once the server stops
+	assert.NoFileExists(t, sock)
+}
+
 func TestAddr(t *testing.T) {
 	server, err := New(Config{Status: 200})
 
@@ -5,7 +5,6 @@ import (
 	"encoding/hex"
 	"fmt"
 	"log/slog"
-	"net"
 	"net/http"
 	"os"
 	"strings"
@@ -13,44 +12,54 @@ import (
 
 	"github.com/coder/websocket"
 	"github.com/fatih/color"
+	"github.com/ksysoev/make-it-public/pkg/target"
 )
 
 // WSConfig holds configuration for the WebSocket echo server.
+// Listen is the address the server listens on, as in Config.
 type WSConfig struct {
-	Interactive bool `mapstructure:"interactive"`
+	Listen      string `mapstructure:"listen"`
+	Interactive bool   `mapstructure:"interactive"`
 }
 
 // WSEchoServer is a WebSocket echo server that logs incoming connections and messages.
 type WSEchoServer struct {
 	isReady     chan struct{}
 	addr        string
+	listen      target.Target
 	interactive bool
 }
 
 // NewWSEchoServer creates and initializes a new WebSocket echo server instance.
 // It validates the configuration and prepares the server for starting.
-// Accepts cfg WSConfig containing the interactive mode flag.
-// Returns a pointer to the WSEchoServer instance and an error if the configuration is invalid.
+// Accepts cfg WSConfig containing the listen address and the interactive mode flag.
+// Returns a pointer to the WSEchoServer instance and an error if the listen address is invalid.
 func NewWSEchoServer(cfg WSConfig) (*WSEchoServer, error) {
+	listen, err := parseListen(cfg.Listen)
+	if err != nil {
+		return nil, err
+	}
+
 	return &WSEchoServer{
 		isReady:     make(chan struct{}),
+		listen:      listen,
 		interactive: cfg.Interactive,
 	}, nil
 }
 
 // Run starts the WebSocket echo server and listens for incoming connections.
-// It initializes a TCP listener on a random port, announces readiness by closing the isReady channel,
+// It initializes a listener on the configured address, announces readiness by closing the isReady channel,
 // and serves WebSocket connections using the server instance.
 // Accepts ctx to manage the server lifecycle and handle shutdown signals.
 // Returns an error if the listener fails to start or the server encounters issues during execution.
 func (s *WSEchoServer) Run(ctx context.Context) error {
-	l, err := net.Listen("tcp", "localhost:0")
+	l, err := s.listen.Listen(ctx)
 	if err != nil {
 		close(s.isReady)
 		return fmt.Errorf("failed to start websocket echo server: %w", err)
 	}
 
-	s.addr = l.Addr().String()
+	s.addr = target.FromAddr(l.Addr()).String()
 
 	srv := http.Server{
 		Handler:           s,
@@ -73,7 +82,7 @@ func (s *WSEchoServer) Run(ctx context.Context) error {
 
 // Addr waits for the server to be ready and retrieves the bound address as a string.
 // It blocks until the readiness signal is received by reading from isReady channel.
-// Returns the server's address in "host:port" format.
+// Returns the server's address in the form accepted by target.Parse, such as "host:port" or "unix:///path".
 func (s *WSEchoServer) Addr() string {
 	<-s.isReady
 	return s.addr
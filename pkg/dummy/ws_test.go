@@ -3,6 +3,8 @@ package dummy
 import (
 	"context"
 	"net"
+	"net/http"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -106,6 +108,49 @@ func TestWSEchoServerAddr(t *testing.T) {
 	cancel()
 }
 
+func TestWSEchoServerUnixSocket(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "ws.sock")
+
+	server, err := NewWSEchoServer(WSConfig{Listen: "unix://" + sock})
+	require.NoError(t, err, "Failed to create server")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	go func() {
+		_ = server.Run(ctx)
+	}()
+
+	assert.Equal(t, "unix://"+sock, server.Addr())
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", sock)
+			},
+		},
+	}
+
+	conn, _, err := websocket.Dial(ctx, "ws://localhost", &websocket.DialOptions{HTTPClient: client}) //nolint:bodyclose // WebSocket connections don't have response bodies to close
+	require.NoError(t, err, "Failed to connect to WebSocket server")
+
+	defer func() { _ = conn.Close(websocket.StatusNormalClosure, "") }()
+
+	require.NoError(t, conn.Write(ctx, websocket.MessageText, []byte("hello")))
+
+	_, data, err := conn.Read(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(data))
+}
+
+func TestNewWSEchoServer_InvalidListen(t *testing.T) {
+	server, err := NewWSEchoServer(WSConfig{Listen: "https://localhost:8443"})
+
+	assert.ErrorContains(t, err, "TLS is not supported")
+	assert.Nil(t, server)
+}
+
 func TestWSEchoServerEchoText(t *testing.T) {
 	server, err := NewWSEchoServer(WSConfig{Interactive: false})
 	require.NoError(t, err, "Failed to create server")
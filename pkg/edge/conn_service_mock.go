@@ -24,6 +24,159 @@ func (_m *MockConnService) EXPECT() *MockConnService_Expecter {
 	return &MockConnService_Expecter{mock: &_m.Mock}
 }
 
+// AuthorizeHTTP provides a mock function with given fields: ctx, keyID, authorization
+func (_m *MockConnService) AuthorizeHTTP(ctx context.Context, keyID string, authorization string) (bool, error) {
+	ret := _m.Called(ctx, keyID, authorization)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AuthorizeHTTP")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (bool, error)); ok {
+		return rf(ctx, keyID, authorization)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) bool); ok {
+		r0 = rf(ctx, keyID, authorization)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, keyID, authorization)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockConnService_AuthorizeHTTP_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AuthorizeHTTP'
+type MockConnService_AuthorizeHTTP_Call struct {
+	*mock.Call
+}
+
+// AuthorizeHTTP is a helper method to define mock.On call
+//   - ctx context.Context
+//   - keyID string
+//   - authorization string
+func (_e *MockConnService_Expecter) AuthorizeHTTP(ctx interface{}, keyID interface{}, authorization interface{}) *MockConnService_AuthorizeHTTP_Call {
+	return &MockConnService_AuthorizeHTTP_Call{Call: _e.mock.On("AuthorizeHTTP", ctx, keyID, authorization)}
+}
+
+func (_c *MockConnService_AuthorizeHTTP_Call) Run(run func(ctx context.Context, keyID string, authorization string)) *MockConnService_AuthorizeHTTP_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *MockConnService_AuthorizeHTTP_Call) Return(_a0 bool, _a1 error) *MockConnService_AuthorizeHTTP_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockConnService_AuthorizeHTTP_Call) RunAndReturn(run func(context.Context, string, string) (bool, error)) *MockConnService_AuthorizeHTTP_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// CheckClientIP provides a mock function with given fields: ctx, keyID, clientIP
+func (_m *MockConnService) CheckClientIP(ctx context.Context, keyID string, clientIP string) error {
+	ret := _m.Called(ctx, keyID, clientIP)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CheckClientIP")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, keyID, clientIP)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockConnService_CheckClientIP_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CheckClientIP'
+type MockConnService_CheckClientIP_Call struct {
+	*mock.Call
+}
+
+// CheckClientIP is a helper method to define mock.On call
+//   - ctx context.Context
+//   - keyID string
+//   - clientIP string
+func (_e *MockConnService_Expecter) CheckClientIP(ctx interface{}, keyID interface{}, clientIP interface{}) *MockConnService_CheckClientIP_Call {
+	return &MockConnService_CheckClientIP_Call{Call: _e.mock.On("CheckClientIP", ctx, keyID, clientIP)}
+}
+
+func (_c *MockConnService_CheckClientIP_Call) Run(run func(ctx context.Context, keyID string, clientIP string)) *MockConnService_CheckClientIP_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *MockConnService_CheckClientIP_Call) Return(_a0 error) *MockConnService_CheckClientIP_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockConnService_CheckClientIP_Call) RunAndReturn(run func(context.Context, string, string) error) *MockConnService_CheckClientIP_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Draining provides a mock function with no fields
+func (_m *MockConnService) Draining() <-chan struct{} {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for Draining")
+	}
+
+	var r0 <-chan struct{}
+	if rf, ok := ret.Get(0).(func() <-chan struct{}); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(<-chan struct{})
+		}
+	}
+
+	return r0
+}
+
+// MockConnService_Draining_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Draining'
+type MockConnService_Draining_Call struct {
+	*mock.Call
+}
+
+// Draining is a helper method to define mock.On call
+func (_e *MockConnService_Expecter) Draining() *MockConnService_Draining_Call {
+	return &MockConnService_Draining_Call{Call: _e.mock.On("Draining")}
+}
+
+func (_c *MockConnService_Draining_Call) Run(run func()) *MockConnService_Draining_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockConnService_Draining_Call) Return(_a0 <-chan struct{}) *MockConnService_Draining_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockConnService_Draining_Call) RunAndReturn(run func() <-chan struct{}) *MockConnService_Draining_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // HandleHTTPConnection provides a mock function with given fields: ctx, keyID, conn, write, clientIP
 func (_m *MockConnService) HandleHTTPConnection(ctx context.Context, keyID string, conn net.Conn, write func(net.Conn) error, clientIP string) error {
 	ret := _m.Called(ctx, keyID, conn, write, clientIP)
@@ -74,6 +227,120 @@ func (_c *MockConnService_HandleHTTPConnection_Call) RunAndReturn(run func(conte
 	return _c
 }
 
+// OfflinePage provides a mock function with given fields: ctx, keyID
+func (_m *MockConnService) OfflinePage(ctx context.Context, keyID string) (string, error) {
+	ret := _m.Called(ctx, keyID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for OfflinePage")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (string, error)); ok {
+		return rf(ctx, keyID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) string); ok {
+		r0 = rf(ctx, keyID)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, keyID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockConnService_OfflinePage_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'OfflinePage'
+type MockConnService_OfflinePage_Call struct {
+	*mock.Call
+}
+
+// OfflinePage is a helper method to define mock.On call
+//   - ctx context.Context
+//   - keyID string
+func (_e *MockConnService_Expecter) OfflinePage(ctx interface{}, keyID interface{}) *MockConnService_OfflinePage_Call {
+	return &MockConnService_OfflinePage_Call{Call: _e.mock.On("OfflinePage", ctx, keyID)}
+}
+
+func (_c *MockConnService_OfflinePage_Call) Run(run func(ctx context.Context, keyID string)) *MockConnService_OfflinePage_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *MockConnService_OfflinePage_Call) Return(_a0 string, _a1 error) *MockConnService_OfflinePage_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockConnService_OfflinePage_Call) RunAndReturn(run func(context.Context, string) (string, error)) *MockConnService_OfflinePage_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ResolveDomain provides a mock function with given fields: ctx, host
+func (_m *MockConnService) ResolveDomain(ctx context.Context, host string) (string, error) {
+	ret := _m.Called(ctx, host)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ResolveDomain")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (string, error)); ok {
+		return rf(ctx, host)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) string); ok {
+		r0 = rf(ctx, host)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, host)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockConnService_ResolveDomain_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ResolveDomain'
+type MockConnService_ResolveDomain_Call struct {
+	*mock.Call
+}
+
+// ResolveDomain is a helper method to define mock.On call
+//   - ctx context.Context
+//   - host string
+func (_e *MockConnService_Expecter) ResolveDomain(ctx interface{}, host interface{}) *MockConnService_ResolveDomain_Call {
+	return &MockConnService_ResolveDomain_Call{Call: _e.mock.On("ResolveDomain", ctx, host)}
+}
+
+func (_c *MockConnService_ResolveDomain_Call) Run(run func(ctx context.Context, host string)) *MockConnService_ResolveDomain_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *MockConnService_ResolveDomain_Call) Return(_a0 string, _a1 error) *MockConnService_ResolveDomain_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockConnService_ResolveDomain_Call) RunAndReturn(run func(context.Context, string) (string, error)) *MockConnService_ResolveDomain_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // SetEndpointGenerator provides a mock function with given fields: generator
 func (_m *MockConnService) SetEndpointGenerator(generator func(string) (string, error)) {
 	_m.Called(generator)
@@ -0,0 +1,236 @@
+package edge
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"maps"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ksysoev/make-it-public/pkg/core"
+	"github.com/ksysoev/make-it-public/pkg/edge/middleware"
+	"github.com/ksysoev/make-it-public/pkg/metrics"
+)
+
+const (
+	contentTypeHTML = "text/html; charset=utf-8"
+	contentTypeJSON = "application/json"
+)
+
+// fallbackErrorPage is the name of the page, in the directory of error pages, used for statuses
+// that have no page of their own.
+const fallbackErrorPage = "error.html"
+
+// builtinErrorPages are the error pages used when no directory of error pages is configured,
+// or it has no page for the status.
+var builtinErrorPages = map[int]string{
+	http.StatusUnauthorized:      htmlErrorTemplate401,
+	http.StatusForbidden:         htmlErrorTemplate403,
+	http.StatusNotFound:          htmlErrorTemplate404,
+	http.StatusTooManyRequests:   htmlErrorTemplate429,
+	http.StatusBadGateway:        htmlErrorTemplate502,
+	statusBandwidthLimitExceeded: htmlErrorTemplate509,
+}
+
+// errorPageData holds the variables available to error page templates.
+type errorPageData struct {
+	StatusText string
+	KeyID      string
+	RequestID  string
+	Status     int
+}
+
+// errorResponse is the body of error responses to clients that accept JSON.
+type errorResponse struct {
+	Error     string `json:"error"`
+	KeyID     string `json:"key_id,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+	Status    int    `json:"status"`
+}
+
+// errorPages renders the responses of the edge server to requests that could not reach a tunnel.
+type errorPages struct {
+	pages    map[int]*template.Template
+	fallback *template.Template
+}
+
+// newErrorPages parses the built-in error pages and, if dir is not empty, the pages found in dir, which replace them.
+// Pages in dir are html/template files named after their status, such as 404.html, and error.html is used
+// for statuses that have no page of their own in dir. Other files are ignored.
+// Returns an error if dir cannot be read or one of its pages is not a valid template.
+func newErrorPages(dir string) (*errorPages, error) {
+	p := &errorPages{pages: make(map[int]*template.Template, len(builtinErrorPages))}
+
+	base := template.Must(template.New("footer").Parse(htmlErrorFooter))
+
+	for status, page := range builtinErrorPages {
+		p.pages[status] = template.Must(template.Must(base.Clone()).New(strconv.Itoa(status)).Parse(page))
+	}
+
+	p.fallback = template.Must(template.Must(base.Clone()).New("error").Parse(htmlErrorTemplate))
+
+	if dir == "" {
+		return p, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read error pages: %w", err)
+	}
+
+	custom := make(map[int]*template.Template)
+
+	var fallback *template.Template
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || filepath.Ext(name) != ".html" {
+			continue
+		}
+
+		status, err := strconv.Atoi(strings.TrimSuffix(name, ".html"))
+		if name != fallbackErrorPage && (err != nil || status < 100 || status > 999) {
+			continue
+		}
+
+		tmpl, err := template.ParseFiles(filepath.Join(dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse error page %s: %w", name, err)
+		}
+
+		if name == fallbackErrorPage {
+			fallback = tmpl
+		} else {
+			custom[status] = tmpl
+		}
+	}
+
+	// A fallback page of the operator replaces all the built-in pages, so that every error page is branded.
+	if fallback != nil {
+		p.pages, p.fallback = custom, fallback
+	} else {
+		maps.Copy(p.pages, custom)
+	}
+
+	return p, nil
+}
+
+// render returns the content type and body of the error response to r with status.
+// Clients that prefer JSON get an errorResponse, and other clients get the HTML page of the status.
+func (p *errorPages) render(r *http.Request, status int) (contentType string, body []byte) {
+	data := errorPageData{
+		Status:     status,
+		StatusText: statusText(status),
+		KeyID:      middleware.GetKeyID(r),
+		RequestID:  middleware.GetReqID(r),
+	}
+
+	if prefersJSON(r.Header.Get("Accept")) {
+		body, _ = json.Marshal(errorResponse{
+			Error:     data.StatusText,
+			Status:    status,
+			KeyID:     data.KeyID,
+			RequestID: data.RequestID,
+		})
+
+		return contentTypeJSON, body
+	}
+
+	tmpl, ok := p.pages[status]
+	if !ok {
+		tmpl = p.fallback
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		slog.ErrorContext(r.Context(), "failed to render error page", slog.Int("status", status), slog.Any("error", err))
+
+		return "text/plain; charset=utf-8", []byte(strconv.Itoa(status) + " " + data.StatusText + "\n")
+	}
+
+	return contentTypeHTML, buf.Bytes()
+}
+
+// prefersJSON reports whether the Accept header of a request ranks application/json above text/html.
+// Wildcards are not taken into account, so that clients such as browsers and curl keep getting HTML pages.
+func prefersJSON(accept string) bool {
+	var jsonQ, htmlQ float64
+
+	for part := range strings.SplitSeq(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+
+		switch mediaType {
+		case contentTypeJSON:
+			jsonQ = max(jsonQ, q)
+		case "text/html":
+			htmlQ = max(htmlQ, q)
+		}
+	}
+
+	return jsonQ > 0 && jsonQ > htmlQ
+}
+
+// errorBody returns the content type and body of the response to r with status, which failed with err.
+// Visitors of tunnels whose client is disconnected get the offline page set by the owner of the tunnel, if any,
+// unless they prefer JSON.
+func (s *HTTPServer) errorBody(r *http.Request, status int, err error) (contentType string, body []byte) {
+	if errors.Is(err, core.ErrTunnelOffline) && !prefersJSON(r.Header.Get("Accept")) {
+		keyID := middleware.GetKeyID(r)
+
+		page, pageErr := s.connService.OfflinePage(r.Context(), keyID)
+
+		switch {
+		case pageErr != nil:
+			slog.ErrorContext(r.Context(), "failed to get offline page", slog.Any("error", pageErr), slog.String("keyID", keyID))
+		case page != "":
+			return contentTypeHTML, []byte(page)
+		}
+	}
+
+	return s.errorPages.render(r, status)
+}
+
+// writeError writes the error response to r with status to w. err is the error the request failed with, if any.
+// Every response is counted in metrics.EdgeResponses by its status code.
+func (s *HTTPServer) writeError(w http.ResponseWriter, r *http.Request, status int, err error) {
+	metrics.EdgeResponses.WithLabelValues(strconv.Itoa(status)).Inc()
+
+	contentType, body := s.errorBody(r, status, err)
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
+
+// writeMiddlewareError writes the error responses to requests rejected by middleware, such as the connection limit.
+func (s *HTTPServer) writeMiddlewareError(w http.ResponseWriter, r *http.Request, status int) {
+	s.writeError(w, r, status, nil)
+}
+
+// sendError sends the error response to r with status over the hijacked connection conn.
+// err is the error the request failed with.
+func (s *HTTPServer) sendError(r *http.Request, conn net.Conn, status int, err error) {
+	contentType, body := s.errorBody(r, status, err)
+
+	sendResponse(r, conn, status, contentType, body)
+}
@@ -0,0 +1,212 @@
+package edge
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ksysoev/make-it-public/pkg/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func writeErrorPage(t *testing.T, dir, name, content string) {
+	t.Helper()
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+}
+
+func newErrorRequest(reqID, accept string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+
+	if accept != "" {
+		r.Header.Set("Accept", accept)
+	}
+
+	if reqID != "" {
+		//nolint:staticcheck,revive // the key set by middleware.ReqID
+		r = r.WithContext(context.WithValue(r.Context(), "req_id", reqID))
+	}
+
+	return r
+}
+
+func TestErrorPages_Builtin(t *testing.T) {
+	pages, err := newErrorPages("")
+	require.NoError(t, err)
+
+	contentType, body := pages.render(newErrorRequest("req-1", ""), http.StatusNotFound)
+	assert.Equal(t, contentTypeHTML, contentType)
+	assert.Contains(t, string(body), "<h1>404 Not Found</h1>")
+	assert.Contains(t, string(body), "Request ID: req-1")
+
+	_, body = pages.render(newErrorRequest("", ""), http.StatusTooManyRequests)
+	assert.Contains(t, string(body), "<h1>429 Too Many Requests</h1>")
+	assert.NotContains(t, string(body), "Request ID")
+
+	// Statuses without a page of their own get the generic one.
+	_, body = pages.render(newErrorRequest("", ""), http.StatusInternalServerError)
+	assert.Contains(t, string(body), "<h1>500 Internal Server Error</h1>")
+}
+
+func TestErrorPages_Dir(t *testing.T) {
+	dir := t.TempDir()
+
+	writeErrorPage(t, dir, "404.html", `<p>{{.Status}} {{.StatusText}} {{.KeyID}} {{.RequestID}}</p>`)
+	writeErrorPage(t, dir, "error.html", `<p>Oops: {{.Status}}</p>`)
+	writeErrorPage(t, dir, "notes.html", `{{ not a template`)
+	writeErrorPage(t, dir, "500.txt", `{{ not a template`)
+
+	pages, err := newErrorPages(dir)
+	require.NoError(t, err)
+
+	_, body := pages.render(newErrorRequest("req-1", ""), http.StatusNotFound)
+	assert.Equal(t, "<p>404 Not Found  req-1</p>", string(body))
+
+	// The fallback page of the directory replaces the built-in pages.
+	_, body = pages.render(newErrorRequest("", ""), http.StatusBadGateway)
+	assert.Equal(t, "<p>Oops: 502</p>", string(body))
+}
+
+func TestErrorPages_DirWithoutFallback(t *testing.T) {
+	dir := t.TempDir()
+
+	writeErrorPage(t, dir, "502.html", `<p>{{.StatusText}}</p>`)
+
+	pages, err := newErrorPages(dir)
+	require.NoError(t, err)
+
+	_, body := pages.render(newErrorRequest("", ""), http.StatusBadGateway)
+	assert.Equal(t, "<p>Bad Gateway</p>", string(body))
+
+	_, body = pages.render(newErrorRequest("", ""), http.StatusNotFound)
+	assert.Contains(t, string(body), "<h1>404 Not Found</h1>")
+}
+
+func TestErrorPages_DirErrors(t *testing.T) {
+	_, err := newErrorPages(filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+
+	dir := t.TempDir()
+	writeErrorPage(t, dir, "502.html", `{{ .Status`)
+
+	_, err = newErrorPages(dir)
+	assert.ErrorContains(t, err, "502.html")
+}
+
+func TestErrorPages_JSON(t *testing.T) {
+	pages, err := newErrorPages("")
+	require.NoError(t, err)
+
+	contentType, body := pages.render(newErrorRequest("req-1", "application/json"), statusBandwidthLimitExceeded)
+	assert.Equal(t, contentTypeJSON, contentType)
+
+	var resp errorResponse
+	require.NoError(t, json.Unmarshal(body, &resp))
+	assert.Equal(t, errorResponse{Error: "Bandwidth Limit Exceeded", RequestID: "req-1", Status: statusBandwidthLimitExceeded}, resp)
+}
+
+func TestPrefersJSON(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   bool
+	}{
+		{accept: "", want: false},
+		{accept: "*/*", want: false},
+		{accept: "application/json", want: true},
+		{accept: "application/json, text/plain, */*", want: true},
+		{accept: "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", want: false},
+		{accept: "text/html;q=0.5, application/json", want: true},
+		{accept: "text/html, application/json;q=0.9", want: false},
+		{accept: "application/json;q=0", want: false},
+		{accept: "application/json;q=bad", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			assert.Equal(t, tt.want, prefersJSON(tt.accept))
+		})
+	}
+}
+
+func TestServeHTTP_OfflinePage(t *testing.T) {
+	tests := []struct {
+		pageErr     error
+		handleError error
+		name        string
+		accept      string
+		page        string
+		wantType    string
+		wantBody    string
+		wantLookup  bool
+	}{
+		{
+			name:        "offline page",
+			page:        "<h1>Back soon</h1>",
+			wantLookup:  true,
+			handleError: core.ErrTunnelOffline,
+			wantType:    contentTypeHTML,
+			wantBody:    "<h1>Back soon</h1>",
+		},
+		{
+			name:        "no offline page",
+			wantLookup:  true,
+			handleError: core.ErrTunnelOffline,
+			wantType:    contentTypeHTML,
+			wantBody:    "<h1>502 Bad Gateway</h1>",
+		},
+		{
+			name:        "lookup failure",
+			pageErr:     assert.AnError,
+			wantLookup:  true,
+			handleError: core.ErrTunnelOffline,
+			wantType:    contentTypeHTML,
+			wantBody:    "<h1>502 Bad Gateway</h1>",
+		},
+		{
+			name:        "JSON client",
+			accept:      "application/json",
+			handleError: core.ErrTunnelOffline,
+			wantType:    contentTypeJSON,
+			wantBody:    `"status":502`,
+		},
+		{
+			name:        "client connected",
+			handleError: core.ErrFailedToConnect,
+			wantType:    contentTypeHTML,
+			wantBody:    "<h1>502 Bad Gateway</h1>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockConnService := NewMockConnService(t)
+			mockConnService.EXPECT().SetEndpointGenerator(mock.Anything).Return()
+			mockConnService.EXPECT().CheckClientIP(mock.Anything, "", "").Return(nil)
+			mockConnService.EXPECT().AuthorizeHTTP(mock.Anything, "", "").Return(false, nil)
+			mockConnService.EXPECT().HandleHTTPConnection(mock.Anything, "", mock.Anything, mock.Anything, "").Return(tt.handleError)
+
+			if tt.wantLookup {
+				mockConnService.EXPECT().OfflinePage(mock.Anything, "").Return(tt.page, tt.pageErr)
+			}
+
+			server, err := New(Config{Public: PublicEndpointConfig{Schema: "http", Domain: "example.com", Port: 80}}, mockConnService)
+			require.NoError(t, err)
+
+			req := newErrorRequest("", tt.accept)
+			rec := httptest.NewRecorder()
+
+			// The recorder can not be hijacked, so the request is proxied.
+			server.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusBadGateway, rec.Code)
+			assert.Equal(t, tt.wantType, rec.Header().Get("Content-Type"))
+			assert.Contains(t, rec.Body.String(), tt.wantBody)
+		})
+	}
+}
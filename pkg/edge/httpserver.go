@@ -1,6 +1,7 @@
 package edge
 
 import (
+	"bytes"
 	"cmp"
 	"context"
 	"errors"
@@ -9,34 +10,94 @@ import (
 	"log/slog"
 	"net"
 	"net/http"
-	"strings"
+	"net/netip"
+	"strconv"
 	"time"
 
 	"github.com/ksysoev/make-it-public/pkg/core"
+	"github.com/ksysoev/make-it-public/pkg/core/ipfilter"
 	"github.com/ksysoev/make-it-public/pkg/core/url"
 	"github.com/ksysoev/make-it-public/pkg/edge/middleware"
+	"github.com/ksysoev/make-it-public/pkg/metrics"
 )
 
 type ConnService interface {
+	CheckClientIP(ctx context.Context, keyID, clientIP string) error
+	ResolveDomain(ctx context.Context, host string) (string, error)
+	AuthorizeHTTP(ctx context.Context, keyID, authorization string) (bool, error)
 	HandleHTTPConnection(ctx context.Context, keyID string, conn net.Conn, write func(net.Conn) error, clientIP string) error
+	OfflinePage(ctx context.Context, keyID string) (string, error)
 	SetEndpointGenerator(generator func(string) (string, error))
+	Draining() <-chan struct{}
 }
 
 type HTTPServer struct {
-	connService ConnService
-	config      Config
+	connService    ConnService
+	rateLimiter    middleware.RateLimiter
+	errorPages     *errorPages
+	trustedProxies []netip.Prefix
+	config         Config
 }
 
 const defaultConnLimitPerKeyID = 4
 
+// statusBandwidthLimitExceeded is the unofficial status code of responses for tunnels whose traffic quota is used up.
+const statusBandwidthLimitExceeded = 509
+
+const (
+	RateLimitStoreMemory = "memory"
+	RateLimitStoreRedis  = "redis"
+)
+
+// authChallenge is sent in the WWW-Authenticate header of responses to requests for protected tunnels.
+const authChallenge = `Basic realm="make-it-public", Bearer realm="make-it-public"`
+
+// Config holds the settings of the HTTP edge server.
+// ErrorPages is a directory of templates replacing the built-in error pages, see newErrorPages.
+// TrustedProxies lists the CIDRs of the proxies whose forwarded headers, such as X-Forwarded-For, identify
+// the IP address of end users. Requests from other addresses are identified by their remote address, which
+// is the address of the end user when ProxyProto is set.
 type Config struct {
 	Listen            string               `mapstructure:"listen"`
+	ErrorPages        string               `mapstructure:"error_pages"`
+	TrustedProxies    []string             `mapstructure:"trusted_proxies"`
 	Public            PublicEndpointConfig `mapstructure:"public"`
+	RateLimit         RateLimitConfig      `mapstructure:"rate_limit"`
 	ConnLimit         int                  `mapstructure:"conn_limit"`
 	ProxyProto        bool                 `mapstructure:"proxy_proto"`
+	H2C               bool                 `mapstructure:"h2c"`
 	FishingProtection bool                 `mapstructure:"fishing_protection"`
 }
 
+// RateLimitConfig holds the limit of the request rate from every client IP to the tunnel of every token.
+// Rate is the number of requests allowed per second, and Burst how many may be sent at once, defaulting to Rate.
+// Store is RateLimitStoreRedis to share the limits across nodes, or empty to keep them in memory.
+// A zero Rate disables the limit.
+type RateLimitConfig struct {
+	Store string  `mapstructure:"store"`
+	Rate  float64 `mapstructure:"rate"`
+	Burst int     `mapstructure:"burst"`
+}
+
+// Validate checks that the RateLimitConfig is valid. It returns an error describing the first violation found.
+func (c *RateLimitConfig) Validate() error {
+	if c.Rate < 0 {
+		return fmt.Errorf("rate must not be negative: %v", c.Rate)
+	}
+
+	if c.Burst < 0 {
+		return fmt.Errorf("burst must not be negative: %d", c.Burst)
+	}
+
+	switch c.Store {
+	case "", RateLimitStoreMemory, RateLimitStoreRedis:
+	default:
+		return fmt.Errorf("unknown rate limit store %q, expected %q or %q", c.Store, RateLimitStoreMemory, RateLimitStoreRedis)
+	}
+
+	return nil
+}
+
 type PublicEndpointConfig struct {
 	Schema string `mapstructure:"schema"`
 	Domain string `mapstructure:"domain"`
@@ -47,58 +108,76 @@ type PublicEndpointConfig struct {
 // It validates the configuration by creating a URL endpoint generator and applies it to the connection service.
 // Accepts cfg, a configuration struct defining server and public endpoint parameters, and connService,
 // an interface to manage HTTP connections.
-// Returns a pointer to an HTTPServer if successful or an error if the configuration or endpoint generator fails.
+// Returns a pointer to an HTTPServer if successful or an error if the configuration, endpoint generator or error pages fail.
 func New(cfg Config, connService ConnService) (*HTTPServer, error) {
+	if err := cfg.RateLimit.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid rate limit: %w", err)
+	}
+
+	trustedProxies, err := ipfilter.ParsePrefixes(cfg.TrustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trusted proxies: %w", err)
+	}
+
 	generator, err := url.NewEndpointGenerator(cfg.Public.Schema, cfg.Public.Domain, cfg.Public.Port)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create endpoint generator: %w", err)
 	}
 
+	pages, err := newErrorPages(cfg.ErrorPages)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load error pages: %w", err)
+	}
+
 	connService.SetEndpointGenerator(generator)
 
 	return &HTTPServer{
-		config:      cfg,
-		connService: connService,
+		config:         cfg,
+		connService:    connService,
+		errorPages:     pages,
+		trustedProxies: trustedProxies,
 	}, nil
 }
 
+// SetRateLimiter sets the limiter of the request rate used when a rate limit is configured,
+// replacing the default one that keeps the limits in memory.
+func (s *HTTPServer) SetRateLimiter(l middleware.RateLimiter) {
+	s.rateLimiter = l
+}
+
 // Run starts the HTTP server and manages its lifecycle using the provided context.
 // It composes middleware, sets up a TCP listener, and creates an HTTP server instance.
 // Accepts ctx to control the server's lifecycle and handle graceful shutdowns.
+// When the connection service starts draining, the server stops accepting connections and returns.
 // Returns an error if the server fails to start, listen, or encounters unexpected termination issues.
 func (s *HTTPServer) Run(ctx context.Context) error {
-	mw := make([]func(next http.Handler) http.Handler, 0, 6)
-
-	if s.config.FishingProtection {
-		mw = append(mw, middleware.NewFishingProtection())
-	}
-
-	mw = append(mw,
-		middleware.ParseKeyID(s.config.Public.Domain),
-		middleware.Metrics(),
-		middleware.LimitConnections(cmp.Or(s.config.ConnLimit, defaultConnLimitPerKeyID)),
-		middleware.ClientIP(),
-		middleware.ReqID(),
-	)
-
-	var handler http.Handler = s
-
-	for i := len(mw) - 1; i >= 0; i-- {
-		handler = mw[i](handler)
-	}
-
 	ln, err := listen(s.config.Listen, s.config.ProxyProto)
 	if err != nil {
 		return fmt.Errorf("failed to listen on %s: %w", s.config.Listen, err)
 	}
 
 	server := &http.Server{
-		Handler:           handler,
+		Handler:           s.handler(),
 		ReadHeaderTimeout: 5 * time.Second,
 	}
 
+	// HTTP/2 without TLS is accepted from reverse proxies that terminate TLS and forward HTTP/2 as is.
+	if s.config.H2C {
+		server.Protocols = new(http.Protocols)
+		server.Protocols.SetHTTP1(true)
+		server.Protocols.SetUnencryptedHTTP2(true)
+	}
+
 	go func() {
-		<-ctx.Done()
+		select {
+		case <-ctx.Done():
+		case <-s.connService.Draining():
+			// Shutdown stops accepting connections; hijacked tunnel connections are not tracked by the server
+			// and keep being served until they finish or ctx is cancelled.
+			if err := server.Shutdown(ctx); err == nil {
+				return
+			}
+		}
 
 		_ = server.Close()
 	}()
@@ -110,15 +189,89 @@ func (s *HTTPServer) Run(ctx context.Context) error {
 	return nil
 }
 
+// handler returns the server wrapped in the middleware chain that identifies the tunnel and the client
+// of every request and enforces the limits of the edge.
+func (s *HTTPServer) handler() http.Handler {
+	// The request ID comes first, so that it is shown on the error pages of requests rejected by other middleware.
+	mw := make([]func(next http.Handler) http.Handler, 0, 7)
+	mw = append(mw, middleware.ReqID())
+
+	if s.config.FishingProtection {
+		mw = append(mw, middleware.NewFishingProtection())
+	}
+
+	mw = append(mw,
+		middleware.ParseKeyID(s.config.Public.Domain, s.connService.ResolveDomain),
+		middleware.Metrics(),
+		middleware.LimitConnections(cmp.Or(s.config.ConnLimit, defaultConnLimitPerKeyID), s.writeMiddlewareError),
+		middleware.ClientIP(s.trustedProxies),
+	)
+
+	if s.config.RateLimit.Rate > 0 {
+		limiter := s.rateLimiter
+		if limiter == nil {
+			limiter = middleware.NewLocalRateLimiter(s.config.RateLimit.Rate, s.config.RateLimit.Burst)
+		}
+
+		mw = append(mw, middleware.LimitRequestRate(limiter, s.writeMiddlewareError))
+	}
+
+	var handler http.Handler = s
+
+	for i := len(mw) - 1; i >= 0; i-- {
+		handler = mw[i](handler)
+	}
+
+	return handler
+}
+
 // ServeHTTP handles incoming HTTP requests by processing the request context and managing hijacked connections.
 // It uses a hijacker to take control of the underlying connection for advanced protocol handling.
+// Requests from client IPs rejected by the token's CIDR rules get 403, and requests to tunnels protected by their
+// owner are rejected with 401, both before a connection is requested from the client; the credentials of authorized
+// requests are removed before they are forwarded. Requests to tunnels
+// whose traffic quota is used up get 509, and requests to tunnels whose client is disconnected get 502
+// with the offline page of the tunnel, if its owner set one.
 // Returns appropriate HTTP error responses for unsupported hijacking, connection issues, or context errors.
 func (s *HTTPServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	hj, ok := w.(http.Hijacker)
-	if !ok {
-		slog.ErrorContext(r.Context(), "webserver doesn't support hijacking", slog.String("host", r.Host))
-		http.Error(w, "webserver doesn't support hijacking", http.StatusInternalServerError)
+	keyID := middleware.GetKeyID(r)
+	clientIP := middleware.GetClientIP(r)
+
+	var protected bool
 
+	err := s.connService.CheckClientIP(r.Context(), keyID, clientIP)
+	if err == nil {
+		protected, err = s.connService.AuthorizeHTTP(r.Context(), keyID, r.Header.Get("Authorization"))
+	}
+
+	switch {
+	case errors.Is(err, core.ErrForbidden):
+		s.writeError(w, r, http.StatusForbidden, err)
+		return
+	case errors.Is(err, core.ErrUnauthorized):
+		w.Header().Set("WWW-Authenticate", authChallenge)
+		s.writeError(w, r, http.StatusUnauthorized, err)
+
+		return
+	case err != nil:
+		s.writeError(w, r, http.StatusBadGateway, err)
+		return
+	}
+
+	// The credentials of a protected tunnel are checked by the edge and are not passed on to the tunnel.
+	if protected {
+		r.Header.Del("Authorization")
+	}
+
+	// Connections can only be hijacked on HTTP/1.x. Other requests, such as HTTP/2 ones, are proxied
+	// at the request/response level. So are requests to protected tunnels and requests when a rate limit
+	// is configured, as the following requests of a hijacked connection would reach the tunnel without being
+	// authorized or counted; upgrades are still hijacked, since the upgraded connection carries no further requests.
+	perRequest := protected || s.config.RateLimit.Rate > 0
+
+	hj, ok := w.(http.Hijacker)
+	if !ok || r.ProtoMajor != 1 || (perRequest && r.Header.Get("Upgrade") == "") {
+		s.proxyRequest(w, r, keyID, clientIP)
 		return
 	}
 
@@ -132,9 +285,6 @@ func (s *HTTPServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	defer func() { _ = clientConn.Close() }()
 
-	keyID := middleware.GetKeyID(r)
-	clientIP := middleware.GetClientIP(r)
-
 	// Prevent context cancellation from affecting the hijacked connection handling.
 	// context.WithoutCancel is used to ensure that the original request context's cancellation
 	// does not propagate to the hijacked connection. Immediately following this, context.WithCancel
@@ -150,9 +300,11 @@ func (s *HTTPServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	switch {
 	case errors.Is(err, core.ErrFailedToConnect):
-		sendResponse(r, clientConn, http.StatusBadGateway, htmlErrorTemplate502)
+		s.sendError(r, clientConn, http.StatusBadGateway, err)
 	case errors.Is(err, core.ErrKeyIDNotFound):
-		sendResponse(r, clientConn, http.StatusNotFound, htmlErrorTemplate404)
+		s.sendError(r, clientConn, http.StatusNotFound, err)
+	case errors.Is(err, core.ErrQuotaExceeded):
+		s.sendError(r, clientConn, statusBandwidthLimitExceeded, err)
 	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
 		slog.DebugContext(ctx, "connection timed out", slog.String("host", r.Host))
 	case err != nil:
@@ -160,22 +312,34 @@ func (s *HTTPServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// statusText returns the reason phrase of status, including the unofficial codes sent by the edge.
+func statusText(status int) string {
+	if status == statusBandwidthLimitExceeded {
+		return "Bandwidth Limit Exceeded"
+	}
+
+	return http.StatusText(status)
+}
+
 // sendResponse constructs and sends an HTTP response over a hijacked connection.
-// It builds the response using the provided request protocol details, status code, and body content.
+// It builds the response using the provided request protocol details, status code, content type and body.
 // r is the original HTTP request from which protocol details are extracted.
 // conn is the hijacked network connection used to write the response.
 // status specifies the HTTP status code for the response.
-// body contains the response body content as a string.
 // Returns nothing but logs an error if writing the response fails.
-func sendResponse(r *http.Request, conn net.Conn, status int, body string) {
+// Every response is counted in metrics.EdgeResponses by its status code.
+func sendResponse(r *http.Request, conn net.Conn, status int, contentType string, body []byte) {
+	metrics.EdgeResponses.WithLabelValues(strconv.Itoa(status)).Inc()
+
 	resp := http.Response{
 		StatusCode:    status,
+		Status:        statusText(status),
 		Proto:         r.Proto,
 		ProtoMajor:    r.ProtoMajor,
 		ProtoMinor:    r.ProtoMinor,
 		ContentLength: int64(len(body)),
-		Header:        http.Header{"Content-Type": []string{"text/html; charset=utf-8"}},
-		Body:          io.NopCloser(strings.NewReader(body)),
+		Header:        http.Header{"Content-Type": []string{contentType}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
 	}
 
 	if err := resp.Write(conn); err != nil {
@@ -62,6 +62,45 @@ func TestNew(t *testing.T) {
 			},
 			expectError: true,
 		},
+		{
+			name: "invalid rate limit",
+			config: Config{
+				Listen: ":8080",
+				Public: PublicEndpointConfig{
+					Schema: "http",
+					Domain: "example.com",
+					Port:   80,
+				},
+				RateLimit: RateLimitConfig{Rate: 10, Store: "memcached"},
+			},
+			expectError: true,
+		},
+		{
+			name: "valid trusted proxies",
+			config: Config{
+				Listen: ":8080",
+				Public: PublicEndpointConfig{
+					Schema: "http",
+					Domain: "example.com",
+					Port:   80,
+				},
+				TrustedProxies: []string{"10.0.0.0/8", "192.0.2.1"},
+			},
+			expectError: false,
+		},
+		{
+			name: "invalid trusted proxies",
+			config: Config{
+				Listen: ":8080",
+				Public: PublicEndpointConfig{
+					Schema: "http",
+					Domain: "example.com",
+					Port:   80,
+				},
+				TrustedProxies: []string{"not-a-cidr"},
+			},
+			expectError: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -97,6 +136,7 @@ func TestRun(t *testing.T) {
 	// Create a mock ConnService
 	mockConnService := NewMockConnService(t)
 	mockConnService.On("SetEndpointGenerator", mock.AnythingOfType("func(string) (string, error)")).Return()
+	mockConnService.EXPECT().Draining().Return(make(chan struct{}))
 
 	// Create a server with a random port
 	config := Config{
@@ -135,6 +175,41 @@ func TestRun(t *testing.T) {
 	}
 }
 
+func TestRun_Draining(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	draining := make(chan struct{})
+
+	mockConnService := NewMockConnService(t)
+	mockConnService.On("SetEndpointGenerator", mock.AnythingOfType("func(string) (string, error)")).Return()
+	mockConnService.EXPECT().Draining().Return(draining)
+
+	server, err := New(Config{
+		Listen: "127.0.0.1:0",
+		Public: PublicEndpointConfig{Schema: "http", Domain: "example.com", Port: 80},
+	}, mockConnService)
+	require.NoError(t, err)
+
+	errCh := make(chan error, 1)
+
+	go func() {
+		errCh <- server.Run(ctx)
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+
+	// The server stops without its context being cancelled.
+	close(draining)
+
+	select {
+	case err := <-errCh:
+		assert.NoError(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("Server did not stop after draining started")
+	}
+}
+
 func TestServeHTTP(t *testing.T) {
 	tests := []struct {
 		handleConnErr  error
@@ -164,6 +239,13 @@ func TestServeHTTP(t *testing.T) {
 			handleConnErr:  core.ErrKeyIDNotFound,
 			expectedStatus: http.StatusNotFound,
 		},
+		{
+			name:           "traffic quota exceeded",
+			keyID:          "test-key",
+			clientIP:       "192.168.1.1",
+			handleConnErr:  core.ErrQuotaExceeded,
+			expectedStatus: statusBandwidthLimitExceeded,
+		},
 		{
 			name:           "context canceled",
 			keyID:          "test-key",
@@ -197,6 +279,8 @@ func TestServeHTTP(t *testing.T) {
 
 			// Set up the mock to return the specified error
 			mockConnService.On("SetEndpointGenerator", mock.AnythingOfType("func(string) (string, error)")).Return()
+			mockConnService.On("CheckClientIP", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+			mockConnService.On("AuthorizeHTTP", mock.Anything, mock.Anything, mock.Anything).Return(false, nil)
 
 			// The actual call to HandleHTTPConnection will use a context with a req_id value
 			// and empty keyID and clientIP because we haven't set them in the request context
@@ -232,6 +316,114 @@ func TestServeHTTP(t *testing.T) {
 	}
 }
 
+func TestServeHTTP_AuthorizeFails(t *testing.T) {
+	tests := []struct {
+		authErr        error
+		name           string
+		expectedStatus int
+	}{
+		{
+			name:           "invalid credentials",
+			authErr:        core.ErrUnauthorized,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "lookup failure",
+			authErr:        core.ErrFailedToConnect,
+			expectedStatus: http.StatusBadGateway,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockConnService := NewMockConnService(t)
+			mockConnService.EXPECT().SetEndpointGenerator(mock.Anything).Return()
+			mockConnService.EXPECT().CheckClientIP(mock.Anything, "", "").Return(nil)
+			mockConnService.EXPECT().AuthorizeHTTP(mock.Anything, "", "Bearer wrong").Return(true, tt.authErr)
+
+			server, err := New(Config{Public: PublicEndpointConfig{Schema: "http", Domain: "example.com", Port: 80}}, mockConnService)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			req.Header.Set("Authorization", "Bearer wrong")
+
+			rec := httptest.NewRecorder()
+
+			server.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+
+			if tt.expectedStatus == http.StatusUnauthorized {
+				assert.Equal(t, authChallenge, rec.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestServeHTTP_ClientIPRejected(t *testing.T) {
+	tests := []struct {
+		ipErr          error
+		name           string
+		expectedStatus int
+	}{
+		{
+			name:           "forbidden client IP",
+			ipErr:          core.ErrForbidden,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "lookup failure",
+			ipErr:          core.ErrFailedToConnect,
+			expectedStatus: http.StatusBadGateway,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockConnService := NewMockConnService(t)
+			mockConnService.EXPECT().SetEndpointGenerator(mock.Anything).Return()
+			mockConnService.EXPECT().CheckClientIP(mock.Anything, "", "").Return(tt.ipErr)
+
+			server, err := New(Config{Public: PublicEndpointConfig{Schema: "http", Domain: "example.com", Port: 80}}, mockConnService)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			rec := httptest.NewRecorder()
+
+			server.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+		})
+	}
+}
+
+func TestRateLimitConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     RateLimitConfig
+		wantErr bool
+	}{
+		{name: "disabled", cfg: RateLimitConfig{}},
+		{name: "memory", cfg: RateLimitConfig{Rate: 10, Burst: 20, Store: RateLimitStoreMemory}},
+		{name: "redis", cfg: RateLimitConfig{Rate: 0.5, Store: RateLimitStoreRedis}},
+		{name: "negative rate", cfg: RateLimitConfig{Rate: -1}, wantErr: true},
+		{name: "negative burst", cfg: RateLimitConfig{Rate: 1, Burst: -1}, wantErr: true},
+		{name: "unknown store", cfg: RateLimitConfig{Rate: 1, Store: "memcached"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
 func TestSendResponse(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -242,13 +434,19 @@ func TestSendResponse(t *testing.T) {
 		{
 			name:       "404 response",
 			status:     http.StatusNotFound,
-			body:       htmlErrorTemplate404,
+			body:       "<h1>404 Not Found</h1>",
 			expectBody: true,
 		},
 		{
 			name:       "502 response",
 			status:     http.StatusBadGateway,
-			body:       htmlErrorTemplate502,
+			body:       "<h1>502 Bad Gateway</h1>",
+			expectBody: true,
+		},
+		{
+			name:       "509 response",
+			status:     statusBandwidthLimitExceeded,
+			body:       "<h1>509 Bandwidth Limit Exceeded</h1>",
 			expectBody: true,
 		},
 		{
@@ -269,7 +467,7 @@ func TestSendResponse(t *testing.T) {
 
 			// Send the response in a goroutine
 			go func() {
-				sendResponse(req, serverWriter, tt.status, tt.body)
+				sendResponse(req, serverWriter, tt.status, contentTypeHTML, []byte(tt.body))
 				_ = serverWriter.Close()
 			}()
 
@@ -281,7 +479,7 @@ func TestSendResponse(t *testing.T) {
 
 			// Check the response
 			response := buf.String()
-			assert.Contains(t, response, fmt.Sprintf("HTTP/1.1 %d %s", tt.status, http.StatusText(tt.status)))
+			assert.Contains(t, response, fmt.Sprintf("HTTP/1.1 %d %s", tt.status, statusText(tt.status)))
 
 			if tt.expectBody {
 				assert.Contains(t, response, "Content-Type: text/html; charset=utf-8")
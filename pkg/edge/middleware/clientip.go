@@ -4,6 +4,7 @@ import (
 	"context"
 	"net"
 	"net/http"
+	"net/netip"
 	"strings"
 )
 
@@ -13,13 +14,15 @@ type clientIPKeyType struct{}
 const headerXRealIP = "X-Real-IP"
 
 // ClientIP is a middleware that identifies the client IP address from an HTTP request
-// and stores it in the request context. It checks various headers including forwarded headers,
-// Cloudflare, and CloudFront headers. If no headers are present, it falls back to the remote IP.
+// and stores it in the request context. The remote address of the connection, which is the address
+// of the end user when the server listens with proxy protocol, is used unless it belongs to one of trustedProxies.
+// Only requests from trusted proxies are identified by forwarded headers, such as Cloudflare and CloudFront ones,
+// since any end user can set them.
 // Returns a middleware function that adds the client IP to the request context.
-func ClientIP() func(next http.Handler) http.Handler {
+func ClientIP(trustedProxies []netip.Prefix) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			clientIP := extractClientIP(r)
+			clientIP := extractClientIP(r, trustedProxies)
 
 			ctx := r.Context()
 			ctx = context.WithValue(ctx, clientIPKeyType{}, clientIP)
@@ -40,8 +43,8 @@ func GetClientIP(r *http.Request) string {
 	return ""
 }
 
-// extractClientIP extracts the client IP address from various headers in the request.
-// It checks headers in the following order:
+// extractClientIP extracts the client IP address of the request. The remote IP of the request is returned
+// unless it belongs to trustedProxies, in which case headers are checked in the following order:
 // 1. CF-Connecting-IP (Cloudflare)
 // 2. X-Forwarded-For
 // 3. X-Real-IP
@@ -49,19 +52,33 @@ func GetClientIP(r *http.Request) string {
 // 5. X-Cluster-Client-IP
 // 6. True-Client-IP
 // 7. X-CloudFront-Forwarded-For (AWS CloudFront)
-// If no headers are present, it falls back to the remote IP from the request.
-func extractClientIP(r *http.Request) string {
+// In X-Forwarded-For, the last address not belonging to trustedProxies is used, as the addresses before it
+// may have been sent by the end user. If no headers are present, it falls back to the remote IP.
+func extractClientIP(r *http.Request, trustedProxies []netip.Prefix) string {
+	remoteIP, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		// If there's an error splitting the address, just use the RemoteAddr as is
+		remoteIP = r.RemoteAddr
+	}
+
+	if !isTrustedProxy(remoteIP, trustedProxies) {
+		return remoteIP
+	}
+
 	// Check Cloudflare header
 	if ip := r.Header.Get("CF-Connecting-IP"); ip != "" {
 		return ip
 	}
 
-	// Check X-Forwarded-For header
+	// Check X-Forwarded-For header, which lists the addresses of the end user and of every proxy but the last one
 	if forwardedFor := r.Header.Get("X-Forwarded-For"); forwardedFor != "" {
-		// X-Forwarded-For can contain multiple IPs, take the first one
 		ips := strings.Split(forwardedFor, ",")
-		if len(ips) > 0 {
-			return strings.TrimSpace(ips[0])
+
+		for i := len(ips) - 1; i >= 0; i-- {
+			ip := strings.TrimSpace(ips[i])
+			if i == 0 || !isTrustedProxy(ip, trustedProxies) {
+				return ip
+			}
 		}
 	}
 
@@ -87,12 +104,27 @@ func extractClientIP(r *http.Request) string {
 		}
 	}
 
-	// Fall back to remote address
-	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	return remoteIP
+}
+
+// isTrustedProxy reports whether ip belongs to one of trustedProxies.
+func isTrustedProxy(ip string, trustedProxies []netip.Prefix) bool {
+	if len(trustedProxies) == 0 {
+		return false
+	}
+
+	addr, err := netip.ParseAddr(ip)
 	if err != nil {
-		// If there's an error splitting the address, just return the RemoteAddr as is
-		return r.RemoteAddr
+		return false
+	}
+
+	addr = addr.Unmap()
+
+	for _, p := range trustedProxies {
+		if p.Contains(addr) {
+			return true
+		}
 	}
 
-	return ip
+	return false
 }
@@ -3,9 +3,13 @@ package middleware
 import (
 	"net/http"
 	"net/http/httptest"
+	"net/netip"
 	"testing"
 )
 
+// testTrustedProxies are the proxies whose forwarded headers are trusted in tests.
+var testTrustedProxies = []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("192.168.0.0/16")}
+
 func TestClientIP(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -62,6 +66,24 @@ func TestClientIP(t *testing.T) {
 			},
 			expectedIP: "203.0.113.1", // CF-Connecting-IP should have highest priority
 		},
+		{
+			name:       "Headers of untrusted peers are ignored",
+			remoteAddr: "198.51.100.1:1234",
+			headers: map[string]string{
+				"CF-Connecting-IP": "203.0.113.1",
+				"X-Forwarded-For":  "203.0.113.2",
+				headerXRealIP:      "203.0.113.3",
+			},
+			expectedIP: "198.51.100.1",
+		},
+		{
+			name:       "X-Forwarded-For entries sent by the end user are skipped",
+			remoteAddr: "10.0.0.1:1234",
+			headers: map[string]string{
+				"X-Forwarded-For": "1.2.3.4, 203.0.113.2, 10.0.0.2",
+			},
+			expectedIP: "203.0.113.2",
+		},
 		{
 			name:       "Invalid remote address",
 			remoteAddr: "invalid-address",
@@ -82,7 +104,7 @@ func TestClientIP(t *testing.T) {
 			})
 
 			// Apply the middleware
-			middleware := ClientIP()
+			middleware := ClientIP(testTrustedProxies)
 			handler := middleware(testHandler)
 
 			// Create a test request
@@ -173,7 +195,7 @@ func TestExtractClientIP(t *testing.T) {
 			}
 
 			// Call the function directly
-			ip := extractClientIP(req)
+			ip := extractClientIP(req, testTrustedProxies)
 
 			// Check the result
 			if ip != tt.expectedIP {
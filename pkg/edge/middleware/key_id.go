@@ -2,8 +2,15 @@ package middleware
 
 import (
 	"context"
+	"errors"
+	"log/slog"
+	"net"
 	"net/http"
 	"strings"
+	"sync"
+	"time"
+
+	"github.com/ksysoev/make-it-public/pkg/core"
 )
 
 // UpstreamHostHeader is the header name injected by Caddy containing the TLS server name (SNI)
@@ -12,24 +19,60 @@ import (
 // (the tunnel subdomain). Caddy captures the SNI and injects it as this header.
 const UpstreamHostHeader = "X-Upstream-Host"
 
+const (
+	// unknownDomainTTL is how long a host found not to be a custom domain is remembered, so that requests for it,
+	// such as those of scanners sending random Host headers, are refused without looking it up again.
+	// Newly verified custom domains are routed once it expires.
+	unknownDomainTTL = 30 * time.Second
+
+	// maxUnknownDomains bounds the number of unknown hosts remembered.
+	maxUnknownDomains = 10_000
+)
+
 type keyIDKeyType struct{}
 
+// DomainResolver returns the key ID of the tunnel serving a custom domain,
+// or an empty string or core.ErrDomainNotFound if host is not a registered custom domain.
+type DomainResolver func(ctx context.Context, host string) (string, error)
+
 // ParseKeyID extracts the tunnel key ID from the request by resolving the effective host.
 // It first checks the X-Upstream-Host header (injected by Caddy from TLS SNI) for CNAME proxy support,
 // then falls back to the Host header for direct subdomain access.
-// Requests with no valid host matching the domain postfix or missing subdomains receive a 404 response.
+// Hosts outside of the domain postfix are looked up as custom domains with resolveDomain, if it is not nil,
+// and hosts that are not custom domains are remembered for a while, so that they are not looked up on every request.
+// Requests with no valid host matching the domain postfix or missing subdomains, and requests for unknown
+// custom domains receive a 404 response, while failures to look up a custom domain receive a 502 response.
 // Accepts domainPostfix as a string specifying the desired domain suffix.
 // Returns a middleware handler function that attaches the subdomain to the request context and processes the next handler.
-func ParseKeyID(domainPostfix string) func(next http.Handler) http.Handler {
+func ParseKeyID(domainPostfix string, resolveDomain DomainResolver) func(next http.Handler) http.Handler {
+	unknown := newHostCache(unknownDomainTTL, maxUnknownDomains)
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			host := resolveHost(r, domainPostfix)
-			if host == "" {
-				http.NotFound(w, r)
-				return
+			var keyID string
+
+			if host := resolveHost(r, domainPostfix); host != "" {
+				keyID = extractKeyIDFromHost(host)
+			} else if host := hostname(r.Host); resolveDomain != nil && !unknown.contains(host) {
+				var err error
+
+				keyID, err = resolveDomain(r.Context(), host)
+
+				switch {
+				case errors.Is(err, core.ErrDomainNotFound):
+					keyID = ""
+				case err != nil:
+					slog.ErrorContext(r.Context(), "failed to resolve custom domain", slog.Any("error", err), slog.String("host", r.Host))
+					http.Error(w, http.StatusText(http.StatusBadGateway), http.StatusBadGateway)
+
+					return
+				}
+
+				if keyID == "" {
+					unknown.add(host)
+				}
 			}
 
-			keyID := extractKeyIDFromHost(host)
 			if keyID == "" {
 				http.NotFound(w, r)
 				return
@@ -54,6 +97,18 @@ func GetKeyID(r *http.Request) string {
 	return ""
 }
 
+// KeyIDFromHost returns the key ID of the tunnel served on host, which must be domainPostfix or one of its subdomains.
+// It is used by edges that learn the host from elsewhere than an HTTP request, such as the TLS server name.
+// Returns an empty string for hosts outside of domainPostfix or without a subdomain.
+func KeyIDFromHost(host, domainPostfix string) string {
+	host = hostname(host)
+	if !matchesDomain(host, domainPostfix) {
+		return ""
+	}
+
+	return extractKeyIDFromHost(host)
+}
+
 // resolveHost determines the effective host for key ID extraction.
 // It checks the X-Upstream-Host header first (injected by Caddy from TLS SNI),
 // which is the authoritative signal when requests arrive via CDN CNAME proxies.
@@ -66,14 +121,14 @@ func resolveHost(r *http.Request, domainPostfix string) string {
 	// (the CNAME target, e.g., mykey.make-it-public.dev) as X-Upstream-Host.
 	// This header is trusted because Caddy strips any client-supplied value before injecting its own.
 	if upstreamHost := r.Header.Get(UpstreamHostHeader); upstreamHost != "" {
-		host := strings.Split(upstreamHost, ":")[0]
+		host := hostname(upstreamHost)
 		if matchesDomain(host, domainPostfix) {
 			return host
 		}
 	}
 
 	// Priority 2: Direct Host header (normal subdomain access).
-	host := strings.Split(r.Host, ":")[0]
+	host := hostname(r.Host)
 	if matchesDomain(host, domainPostfix) {
 		return host
 	}
@@ -94,7 +149,7 @@ func matchesDomain(host, domainPostfix string) bool {
 func extractKeyIDFromHost(host string) string {
 	if host != "" {
 		// Strip port if present
-		h := strings.Split(host, ":")[0]
+		h := hostname(host)
 
 		parts := strings.Split(h, ".")
 		if len(parts) > 2 {
@@ -105,3 +160,69 @@ func extractKeyIDFromHost(host string) string {
 
 	return ""
 }
+
+// hostname returns host without its port. Hosts without a port are returned as they are,
+// and the brackets of IPv6 addresses are removed.
+func hostname(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+
+	return strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+}
+
+// hostCache remembers hosts for a limited time.
+type hostCache struct {
+	expires map[string]time.Time
+	now     func() time.Time
+	ttl     time.Duration
+	size    int
+	mu      sync.Mutex
+}
+
+// newHostCache creates a hostCache remembering up to size hosts for ttl each.
+func newHostCache(ttl time.Duration, size int) *hostCache {
+	return &hostCache{
+		expires: make(map[string]time.Time),
+		now:     time.Now,
+		ttl:     ttl,
+		size:    size,
+	}
+}
+
+// contains reports whether host was added less than ttl ago.
+func (c *hostCache) contains(host string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expires, ok := c.expires[host]
+	if ok && c.now().After(expires) {
+		delete(c.expires, host)
+		return false
+	}
+
+	return ok
+}
+
+// add remembers host for ttl. When the cache is full, the expired hosts are dropped,
+// or all hosts if none of them has expired, so that the cache never grows past its size.
+func (c *hostCache) add(host string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+
+	if len(c.expires) >= c.size {
+		for h, expires := range c.expires {
+			if now.After(expires) {
+				delete(c.expires, h)
+			}
+		}
+
+		if len(c.expires) >= c.size {
+			clear(c.expires)
+		}
+	}
+
+	c.expires[host] = now.Add(c.ttl)
+}
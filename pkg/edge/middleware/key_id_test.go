@@ -2,10 +2,13 @@ package middleware
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/ksysoev/make-it-public/pkg/core"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -113,7 +116,7 @@ func TestParseKeyID(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			middleware := ParseKeyID(tt.domainPostfix)
+			middleware := ParseKeyID(tt.domainPostfix, nil)
 
 			// Create a dummy handler to validate the middleware
 			handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -385,3 +388,161 @@ func TestExtractKeyIDFromHost(t *testing.T) {
 		})
 	}
 }
+
+func TestKeyIDFromHost(t *testing.T) {
+	tests := []struct {
+		name     string
+		host     string
+		expected string
+	}{
+		{
+			name:     "subdomain of the domain",
+			host:     "mykey.example.com",
+			expected: "mykey",
+		},
+		{
+			name:     "subdomain with port",
+			host:     "mykey.example.com:443",
+			expected: "mykey",
+		},
+		{
+			name:     "bare domain",
+			host:     "example.com",
+			expected: "",
+		},
+		{
+			name:     "other domain",
+			host:     "mykey.evil-example.com",
+			expected: "",
+		},
+		{
+			name:     "empty host",
+			host:     "",
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, KeyIDFromHost(tt.host, "example.com"))
+		})
+	}
+}
+
+func TestParseKeyID_CustomDomain(t *testing.T) {
+	resolve := func(_ context.Context, host string) (string, error) {
+		switch host {
+		case "app.custom.org":
+			return "mykey", nil
+		case "broken.custom.org":
+			return "", assert.AnError
+		case "gone.custom.org":
+			return "", fmt.Errorf("failed to resolve domain: %w", core.ErrDomainNotFound)
+		case "[2001:db8::1]", "[2001:db8::1]:8080":
+			t.Errorf("IPv6 host was passed with brackets or port: %s", host)
+			return "", nil
+		default:
+			return "", nil
+		}
+	}
+
+	tests := []struct {
+		name          string
+		host          string
+		expectedKeyID string
+		wantStatus    int
+	}{
+		{name: "registered custom domain", host: "app.custom.org:443", expectedKeyID: "mykey", wantStatus: http.StatusOK},
+		{name: "unknown custom domain", host: "other.custom.org", wantStatus: http.StatusNotFound},
+		{name: "resolver failure", host: "broken.custom.org", wantStatus: http.StatusBadGateway},
+		{name: "custom domain not found", host: "gone.custom.org", wantStatus: http.StatusNotFound},
+		{name: "IPv6 host", host: "[2001:db8::1]:8080", wantStatus: http.StatusNotFound},
+		{name: "tunnel subdomain is not resolved", host: "keyID.example.com", expectedKeyID: "keyID", wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := ParseKeyID("example.com", resolve)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, tt.expectedKeyID, GetKeyID(r))
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			req.Host = tt.host
+
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Result().StatusCode)
+		})
+	}
+}
+
+func TestParseKeyID_CachesUnknownDomains(t *testing.T) {
+	calls := map[string]int{}
+
+	handler := ParseKeyID("example.com", func(_ context.Context, host string) (string, error) {
+		calls[host]++
+
+		switch host {
+		case "app.custom.org":
+			return "mykey", nil
+		case "broken.custom.org":
+			return "", assert.AnError
+		default:
+			return "", nil
+		}
+	})(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for range 3 {
+		for _, host := range []string{"app.custom.org", "unknown.custom.org", "broken.custom.org"} {
+			req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			req.Host = host
+
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+		}
+	}
+
+	// Only hosts that are not custom domains are remembered.
+	assert.Equal(t, map[string]int{"app.custom.org": 3, "unknown.custom.org": 1, "broken.custom.org": 3}, calls)
+}
+
+func TestHostCache(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	cache := newHostCache(time.Minute, 2)
+	cache.now = func() time.Time { return now }
+
+	cache.add("a.example.org")
+	assert.True(t, cache.contains("a.example.org"))
+	assert.False(t, cache.contains("b.example.org"))
+
+	now = now.Add(30 * time.Second)
+	cache.add("b.example.org")
+
+	// The cache is full, so the hosts are dropped to make room.
+	cache.add("c.example.org")
+	assert.False(t, cache.contains("a.example.org"))
+	assert.True(t, cache.contains("c.example.org"))
+
+	now = now.Add(2 * time.Minute)
+	assert.False(t, cache.contains("c.example.org"))
+	assert.Empty(t, cache.expires)
+}
+
+func TestHostname(t *testing.T) {
+	tests := map[string]string{
+		"app.example.com":      "app.example.com",
+		"app.example.com:8080": "app.example.com",
+		"[2001:db8::1]:443":    "2001:db8::1",
+		"[2001:db8::1]":        "2001:db8::1",
+		"2001:db8::1":          "2001:db8::1",
+	}
+
+	for host, want := range tests {
+		assert.Equal(t, want, hostname(host), host)
+	}
+}
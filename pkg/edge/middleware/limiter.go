@@ -49,11 +49,24 @@ func (l *limiter) Release(key string) {
 	l.counter[key]--
 }
 
+// ErrorWriter writes the response to a request that a middleware rejected with status.
+type ErrorWriter func(w http.ResponseWriter, r *http.Request, status int)
+
+// writeError responds to r with status using writeErr, or with a plain text message if writeErr is nil.
+func writeError(w http.ResponseWriter, r *http.Request, status int, writeErr ErrorWriter) {
+	if writeErr == nil {
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+
+	writeErr(w, r, status)
+}
+
 // LimitConnections wraps an HTTP handler to enforce a maximum number of active connections per unique key.
 // It uses a rate limiter to track active connections and rejects requests exceeding the limit with a 429 status code.
-// Accepts max, the maximum number of connections allowed per key.
+// Accepts max, the maximum number of connections allowed per key, and writeErr, which writes the 429 responses.
 // Returns an HTTP handler middleware and a 429 error for excess connections.
-func LimitConnections(maxConcurrentRequestsPerKey int) func(next http.Handler) http.Handler {
+func LimitConnections(maxConcurrentRequestsPerKey int, writeErr ErrorWriter) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		l := newLimiter(maxConcurrentRequestsPerKey)
 
@@ -61,7 +74,7 @@ func LimitConnections(maxConcurrentRequestsPerKey int) func(next http.Handler) h
 			keyID := GetKeyID(r)
 
 			if !l.Allow(keyID) {
-				http.Error(w, "Too many requests", http.StatusTooManyRequests)
+				writeError(w, r, http.StatusTooManyRequests, writeErr)
 				return
 			}
 
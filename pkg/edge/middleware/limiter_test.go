@@ -83,7 +83,7 @@ func TestLimitConnections(t *testing.T) {
 				w.WriteHeader(http.StatusOK)
 			})
 
-			limiterMiddleware := LimitConnections(tt.maxConnections)(mockHandler)
+			limiterMiddleware := LimitConnections(tt.maxConnections, nil)(mockHandler)
 
 			for i := 0; i < tt.requests; i++ {
 				go func() {
@@ -123,3 +123,25 @@ func TestLimitConnections(t *testing.T) {
 		})
 	}
 }
+
+func TestLimitConnections_ErrorWriter(t *testing.T) {
+	var gotStatus int
+
+	writeErr := func(w http.ResponseWriter, _ *http.Request, status int) {
+		gotStatus = status
+
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte("custom page"))
+	}
+
+	handler := LimitConnections(0, writeErr)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		t.Error("request over the limit reached the handler")
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+
+	assert.Equal(t, http.StatusTooManyRequests, gotStatus)
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "custom page", rec.Body.String())
+}
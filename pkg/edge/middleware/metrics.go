@@ -1,20 +1,58 @@
 package middleware
 
 import (
+	"bufio"
+	"errors"
 	"log/slog"
+	"net"
 	"net/http"
+	"strconv"
+
+	"github.com/ksysoev/make-it-public/pkg/metrics"
 )
 
+// statusRecorder records the status code written by downstream handlers while preserving
+// the ability to hijack the underlying connection.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader records the status code and writes it to the underlying ResponseWriter.
+func (sr *statusRecorder) WriteHeader(status int) {
+	sr.status = status
+	sr.ResponseWriter.WriteHeader(status)
+}
+
+// Hijack delegates to the underlying ResponseWriter so that proxied connections keep working.
+// It returns an error if the underlying ResponseWriter does not support hijacking.
+func (sr *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := sr.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+
+	return hj.Hijack()
+}
+
 // Metrics is an HTTP middleware that logs the "key_id" field from the request context
 // and passes the request to the next handler. It is intended for use in edge server
 // applications to track connections and associated keys.
+// Status codes written by downstream handlers, such as 429 from the connection limiter,
+// are counted in metrics.EdgeResponses.
 func Metrics() func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			keyID := GetKeyID(r)
 			slog.InfoContext(r.Context(), "connection to edge server", slog.String("key_id", keyID))
 
-			next.ServeHTTP(w, r)
+			sr := &statusRecorder{ResponseWriter: w}
+
+			next.ServeHTTP(sr, r)
+
+			if sr.status != 0 {
+				metrics.EdgeResponses.WithLabelValues(strconv.Itoa(sr.status)).Inc()
+			}
 		})
 	}
 }
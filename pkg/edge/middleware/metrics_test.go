@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ksysoev/make-it-public/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics_CountsEdgeResponses(t *testing.T) {
+	counter := metrics.EdgeResponses.WithLabelValues("429")
+	before := testutil.ToFloat64(counter)
+
+	handler := Metrics()(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "Too many requests", http.StatusTooManyRequests)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.InDelta(t, before+1, testutil.ToFloat64(counter), 0)
+}
+
+func TestMetrics_PreservesHijacker(t *testing.T) {
+	var hijackErr error
+
+	handler := Metrics()(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		hj, ok := w.(http.Hijacker)
+		assert.True(t, ok)
+
+		// httptest.ResponseRecorder does not support hijacking, so the error must be surfaced.
+		_, _, hijackErr = hj.Hijack()
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+
+	assert.Error(t, hijackErr)
+}
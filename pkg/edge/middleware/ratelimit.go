@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ksysoev/make-it-public/pkg/core/ratelimit"
+)
+
+// RateLimiter decides whether a request identified by key may proceed.
+// When it may not, Allow also returns how long the client should wait before retrying.
+type RateLimiter interface {
+	Allow(ctx context.Context, key string) (bool, time.Duration, error)
+}
+
+// LocalRateLimiter limits the request rate of every key with a token bucket kept in memory.
+// Buckets of keys that have been idle long enough to be full again are dropped.
+type LocalRateLimiter struct {
+	lastSweep time.Time
+	buckets   map[string]*rateEntry
+	now       func() time.Time
+	rate      float64
+	burst     int
+	idle      time.Duration
+	mu        sync.Mutex
+}
+
+type rateEntry struct {
+	bucket   *ratelimit.Bucket
+	lastSeen time.Time
+}
+
+// NewLocalRateLimiter creates a LocalRateLimiter allowing rate requests per second for every key,
+// with bursts of up to burst requests. burst defaults to rate, and to 1 if rate is below 1.
+func NewLocalRateLimiter(rate float64, burst int) *LocalRateLimiter {
+	if burst <= 0 {
+		burst = max(int(rate), 1)
+	}
+
+	return &LocalRateLimiter{
+		buckets: make(map[string]*rateEntry),
+		now:     time.Now,
+		rate:    rate,
+		burst:   burst,
+		idle:    time.Duration(float64(burst) / rate * float64(time.Second)),
+	}
+}
+
+// Allow takes a token from the bucket of key and reports how long to wait when none is left. It never fails.
+func (l *LocalRateLimiter) Allow(_ context.Context, key string) (bool, time.Duration, error) {
+	l.mu.Lock()
+
+	now := l.now()
+	l.sweep(now)
+
+	e, ok := l.buckets[key]
+	if !ok {
+		e = &rateEntry{bucket: ratelimit.NewBucket(l.rate, l.burst)}
+		l.buckets[key] = e
+	}
+
+	e.lastSeen = now
+
+	l.mu.Unlock()
+
+	allowed, wait := e.bucket.Allow()
+
+	return allowed, wait, nil
+}
+
+// sweep drops the buckets that have not been used for the time it takes to refill them.
+// It runs at most once per idle period and must be called with mu held.
+func (l *LocalRateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.idle {
+		return
+	}
+
+	for key, e := range l.buckets {
+		if now.Sub(e.lastSeen) >= l.idle {
+			delete(l.buckets, key)
+		}
+	}
+
+	l.lastSweep = now
+}
+
+// LimitRequestRate wraps an HTTP handler to limit the rate of requests from every client IP to every keyID.
+// It must run after ParseKeyID and ClientIP, so that the client IP is the remote address of the request, or the
+// address forwarded by a trusted proxy: visitors can not escape the limit by changing their forwarded headers.
+// Requests over the limit are rejected with a 429 status code and a Retry-After header, written by writeErr.
+// Requests are allowed when the limiter fails, so that an unavailable store does not stop tunnels.
+func LimitRequestRate(l RateLimiter, writeErr ErrorWriter) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			keyID := GetKeyID(r)
+			clientIP := GetClientIP(r)
+
+			allowed, wait, err := l.Allow(r.Context(), keyID+"::"+clientIP)
+			if err != nil {
+				slog.ErrorContext(r.Context(), "failed to check request rate", slog.Any("error", err), slog.String("keyID", keyID))
+			}
+
+			if err == nil && !allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(wait)))
+				writeError(w, r, http.StatusTooManyRequests, writeErr)
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// retryAfterSeconds rounds wait up to whole seconds, as the Retry-After header requires, and is at least 1.
+func retryAfterSeconds(wait time.Duration) int {
+	return max(int(math.Ceil(wait.Seconds())), 1)
+}
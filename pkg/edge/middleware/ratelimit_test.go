@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubRateLimiter struct {
+	err     error
+	keys    []string
+	wait    time.Duration
+	allowed bool
+}
+
+func (l *stubRateLimiter) Allow(_ context.Context, key string) (bool, time.Duration, error) {
+	l.keys = append(l.keys, key)
+
+	return l.allowed, l.wait, l.err
+}
+
+func TestLocalRateLimiter_Allow(t *testing.T) {
+	l := NewLocalRateLimiter(1, 2)
+
+	for range 2 {
+		allowed, wait, err := l.Allow(t.Context(), "key1::10.0.0.1")
+		require.NoError(t, err)
+		assert.True(t, allowed)
+		assert.Zero(t, wait)
+	}
+
+	allowed, wait, err := l.Allow(t.Context(), "key1::10.0.0.1")
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Greater(t, wait, time.Duration(0))
+	assert.LessOrEqual(t, wait, time.Second)
+
+	allowed, _, err = l.Allow(t.Context(), "key1::10.0.0.2")
+	require.NoError(t, err)
+	assert.True(t, allowed, "other client IPs have their own bucket")
+}
+
+func TestLocalRateLimiter_DefaultBurst(t *testing.T) {
+	assert.Equal(t, 10, NewLocalRateLimiter(10, 0).burst)
+	assert.Equal(t, 1, NewLocalRateLimiter(0.5, 0).burst)
+}
+
+func TestLocalRateLimiter_SweepsIdleBuckets(t *testing.T) {
+	now := time.Now()
+
+	l := NewLocalRateLimiter(10, 10)
+	l.now = func() time.Time { return now }
+
+	_, _, _ = l.Allow(t.Context(), "key1::10.0.0.1")
+	_, _, _ = l.Allow(t.Context(), "key1::10.0.0.2")
+	require.Len(t, l.buckets, 2)
+
+	now = now.Add(500 * time.Millisecond)
+	_, _, _ = l.Allow(t.Context(), "key1::10.0.0.2")
+	assert.Len(t, l.buckets, 2)
+
+	now = now.Add(700 * time.Millisecond)
+	_, _, _ = l.Allow(t.Context(), "key1::10.0.0.2")
+	assert.Len(t, l.buckets, 1)
+	assert.Contains(t, l.buckets, "key1::10.0.0.2")
+}
+
+func TestLimitRequestRate(t *testing.T) {
+	tests := []struct {
+		limiter        *stubRateLimiter
+		name           string
+		wantRetryAfter string
+		wantStatus     int
+	}{
+		{name: "allowed", limiter: &stubRateLimiter{allowed: true}, wantStatus: http.StatusOK},
+		{
+			name:           "over limit",
+			limiter:        &stubRateLimiter{wait: 1500 * time.Millisecond},
+			wantStatus:     http.StatusTooManyRequests,
+			wantRetryAfter: "2",
+		},
+		{
+			name:           "short wait",
+			limiter:        &stubRateLimiter{wait: time.Millisecond},
+			wantStatus:     http.StatusTooManyRequests,
+			wantRetryAfter: "1",
+		},
+		{name: "limiter error", limiter: &stubRateLimiter{err: assert.AnError}, wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+
+			// httptest requests come from 192.0.2.1, which is trusted to forward the address of the end user
+			handler := ClientIP([]netip.Prefix{netip.MustParsePrefix("192.0.2.0/24")})(LimitRequestRate(tt.limiter, nil)(next))
+
+			req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			req = req.WithContext(context.WithValue(req.Context(), keyIDKeyType{}, "key1"))
+			req.Header.Set(headerXRealIP, "10.0.0.1")
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			assert.Equal(t, tt.wantRetryAfter, rec.Header().Get("Retry-After"))
+			assert.Equal(t, []string{"key1::10.0.0.1"}, tt.limiter.keys)
+		})
+	}
+}
+
+func TestLimitRequestRate_SpoofedHeaders(t *testing.T) {
+	limiter := &stubRateLimiter{allowed: true}
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	handler := ClientIP(nil)(LimitRequestRate(limiter, nil)(next))
+
+	// A visitor rotating its forwarded headers keeps being limited by the address of its connection.
+	for _, ip := range []string{"203.0.113.1", "203.0.113.2"} {
+		req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+		req = req.WithContext(context.WithValue(req.Context(), keyIDKeyType{}, "key1"))
+		req.RemoteAddr = "198.51.100.7:4321"
+		req.Header.Set("X-Forwarded-For", ip)
+		req.Header.Set("CF-Connecting-IP", ip)
+
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	assert.Equal(t, []string{"key1::198.51.100.7", "key1::198.51.100.7"}, limiter.keys)
+}
@@ -21,3 +21,12 @@ func ReqID() func(next http.Handler) http.Handler {
 		})
 	}
 }
+
+// GetReqID returns the request ID that ReqID added to the context of r, or an empty string if there is none.
+func GetReqID(r *http.Request) string {
+	if reqID, ok := r.Context().Value("req_id").(string); ok {
+		return reqID
+	}
+
+	return ""
+}
@@ -25,6 +25,7 @@ func TestReqID_BasicRequest(t *testing.T) {
 		require.NoError(t, err)
 
 		capturedReqID = reqIDStr
+		assert.Equal(t, reqIDStr, GetReqID(r))
 
 		w.WriteHeader(http.StatusOK)
 	}))
@@ -39,3 +40,7 @@ func TestReqID_BasicRequest(t *testing.T) {
 	assert.Equal(t, http.StatusOK, resp.Code)
 	assert.NotEmpty(t, capturedReqID)
 }
+
+func TestGetReqID_NotSet(t *testing.T) {
+	assert.Empty(t, GetReqID(httptest.NewRequest(http.MethodGet, "/", http.NoBody)))
+}
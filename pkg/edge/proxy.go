@@ -0,0 +1,145 @@
+package edge
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+
+	"github.com/ksysoev/make-it-public/pkg/core"
+)
+
+// hopHeaders are the hop-by-hop headers of a response from the tunnel that are not sent on to the visitor.
+var hopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Connection",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// proxyRequest serves a request that can not be hijacked, such as an HTTP/2 request, at the request/response level.
+// The request is written to the tunnel as an HTTP/1.1 request that closes the connection, and the response read
+// back from the tunnel is copied to w, including its trailers. Protocol upgrades are not supported on this path.
+func (s *HTTPServer) proxyRequest(w http.ResponseWriter, r *http.Request, keyID, clientIP string) {
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	edgeConn, tunnelConn := net.Pipe()
+
+	outReq := r.Clone(ctx)
+	outReq.Close = true
+	// The edge server has already answered an expectation of the visitor when the body is read.
+	outReq.Header.Del("Expect")
+
+	handled := make(chan error, 1)
+	written := make(chan struct{})
+
+	go func() {
+		err := s.connService.HandleHTTPConnection(ctx, keyID, tunnelConn, func(net.Conn) error { return nil }, clientIP)
+		_ = tunnelConn.Close()
+
+		handled <- err
+	}()
+
+	go func() {
+		defer close(written)
+
+		if err := outReq.Write(edgeConn); err != nil {
+			slog.DebugContext(ctx, "failed to write request to tunnel", slog.Any("error", err))
+		}
+	}()
+
+	// The request body must not be read once the handler returns, so the writer is stopped and awaited.
+	defer func() {
+		_ = edgeConn.Close()
+		_ = r.Body.Close()
+
+		<-written
+	}()
+
+	resp, err := http.ReadResponse(bufio.NewReader(edgeConn), outReq)
+	if err != nil {
+		_ = edgeConn.Close()
+
+		if err := <-handled; err != nil {
+			s.writeProxyError(ctx, w, r, err)
+			return
+		}
+
+		slog.DebugContext(ctx, "failed to read response from tunnel", slog.Any("error", err))
+		s.writeError(w, r, http.StatusBadGateway, err)
+
+		return
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	header := w.Header()
+
+	for name, values := range resp.Header {
+		header[name] = values
+	}
+
+	for _, name := range hopHeaders {
+		header.Del(name)
+	}
+
+	for name := range resp.Trailer {
+		header.Add("Trailer", name)
+	}
+
+	w.WriteHeader(resp.StatusCode)
+
+	if _, err := io.Copy(flushWriter{w: w, rc: http.NewResponseController(w)}, resp.Body); err != nil {
+		slog.DebugContext(ctx, "failed to copy response body", slog.Any("error", err))
+	}
+
+	for name, values := range resp.Trailer {
+		header[http.TrailerPrefix+name] = values
+	}
+
+	_ = edgeConn.Close()
+
+	if err := <-handled; err != nil && !errors.Is(err, context.Canceled) {
+		slog.DebugContext(ctx, "failed to handle connection", slog.Any("error", err))
+	}
+}
+
+// writeProxyError responds to a request served by proxyRequest that could not reach the tunnel.
+func (s *HTTPServer) writeProxyError(ctx context.Context, w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, core.ErrFailedToConnect):
+		s.writeError(w, r, http.StatusBadGateway, err)
+	case errors.Is(err, core.ErrKeyIDNotFound):
+		s.writeError(w, r, http.StatusNotFound, err)
+	case errors.Is(err, core.ErrQuotaExceeded):
+		s.writeError(w, r, statusBandwidthLimitExceeded, err)
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		slog.DebugContext(ctx, "connection timed out", slog.String("host", r.Host))
+	default:
+		slog.ErrorContext(ctx, "failed to handle connection", slog.Any("error", err))
+		s.writeError(w, r, http.StatusBadGateway, err)
+	}
+}
+
+// flushWriter flushes every write, so that streamed responses such as server-sent events
+// and gRPC streams reach the visitor without delay.
+type flushWriter struct {
+	w  io.Writer
+	rc *http.ResponseController
+}
+
+func (f flushWriter) Write(p []byte) (int, error) {
+	n, err := f.w.Write(p)
+	if err != nil {
+		return n, err
+	}
+
+	_ = f.rc.Flush()
+
+	return n, nil
+}
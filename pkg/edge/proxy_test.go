@@ -0,0 +1,228 @@
+package edge
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ksysoev/make-it-public/pkg/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const tunnelResponse = "HTTP/1.1 200 OK\r\n" +
+	"Content-Type: text/plain\r\n" +
+	"Connection: close\r\n" +
+	"Transfer-Encoding: chunked\r\n" +
+	"Trailer: Grpc-Status\r\n" +
+	"\r\n" +
+	"5\r\nhello\r\n" +
+	"0\r\nGrpc-Status: 0\r\n\r\n"
+
+// serveTunnel mimics a client behind the tunnel: it reads one request from conn and answers with resp.
+func serveTunnel(t *testing.T, resp string, check func(*http.Request)) func(context.Context, string, net.Conn, func(net.Conn) error, string) error {
+	t.Helper()
+
+	return func(_ context.Context, _ string, conn net.Conn, write func(net.Conn) error, _ string) error {
+		if err := write(conn); err != nil {
+			return err
+		}
+
+		req, err := http.ReadRequest(bufio.NewReader(conn))
+		if err != nil {
+			return err
+		}
+
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			return err
+		}
+
+		req.Body = io.NopCloser(strings.NewReader(string(body)))
+		check(req)
+
+		_, err = io.WriteString(conn, resp)
+
+		return err
+	}
+}
+
+func newProxyTestServer(t *testing.T, connService *MockConnService) *HTTPServer {
+	t.Helper()
+
+	connService.EXPECT().SetEndpointGenerator(mock.Anything).Return()
+	connService.EXPECT().CheckClientIP(mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	connService.EXPECT().AuthorizeHTTP(mock.Anything, mock.Anything, mock.Anything).Return(false, nil)
+
+	server, err := New(Config{Public: PublicEndpointConfig{Schema: "http", Domain: "example.com", Port: 80}}, connService)
+	require.NoError(t, err)
+
+	return server
+}
+
+func TestServeHTTP_NotHijackable(t *testing.T) {
+	connService := NewMockConnService(t)
+	connService.EXPECT().HandleHTTPConnection(mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		RunAndReturn(serveTunnel(t, tunnelResponse, func(req *http.Request) {
+			assert.Equal(t, http.MethodPost, req.Method)
+			assert.Equal(t, "/api?x=1", req.RequestURI)
+			assert.Equal(t, "app.example.com", req.Host)
+			assert.True(t, req.Close)
+
+			body, _ := io.ReadAll(req.Body)
+			assert.Equal(t, "ping", string(body))
+		}))
+
+	server := newProxyTestServer(t, connService)
+
+	req := httptest.NewRequest(http.MethodPost, "http://app.example.com/api?x=1", strings.NewReader("ping"))
+	rec := httptest.NewRecorder()
+
+	server.ServeHTTP(rec, req)
+
+	resp := rec.Result()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "hello", string(body))
+	assert.Equal(t, "text/plain", resp.Header.Get("Content-Type"))
+	assert.Empty(t, resp.Header.Get("Connection"))
+	assert.Equal(t, "0", resp.Trailer.Get("Grpc-Status"))
+}
+
+func TestServeHTTP_NotHijackable_Errors(t *testing.T) {
+	tests := []struct {
+		handleErr      error
+		name           string
+		expectedStatus int
+	}{
+		{name: "keyID not found", handleErr: core.ErrKeyIDNotFound, expectedStatus: http.StatusNotFound},
+		{name: "failed to connect", handleErr: core.ErrFailedToConnect, expectedStatus: http.StatusBadGateway},
+		{name: "quota exceeded", handleErr: core.ErrQuotaExceeded, expectedStatus: statusBandwidthLimitExceeded},
+		{name: "no response", expectedStatus: http.StatusBadGateway},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			connService := NewMockConnService(t)
+			connService.EXPECT().HandleHTTPConnection(mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+				Return(tt.handleErr)
+
+			server := newProxyTestServer(t, connService)
+
+			rec := httptest.NewRecorder()
+			server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+		})
+	}
+}
+
+func TestServeHTTP_H2C(t *testing.T) {
+	connService := NewMockConnService(t)
+	connService.EXPECT().HandleHTTPConnection(mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		RunAndReturn(serveTunnel(t, tunnelResponse, func(req *http.Request) {
+			assert.Equal(t, "HTTP/1.1", req.Proto)
+		}))
+
+	ts := httptest.NewUnstartedServer(newProxyTestServer(t, connService))
+	ts.Config.Protocols = new(http.Protocols)
+	ts.Config.Protocols.SetHTTP1(true)
+	ts.Config.Protocols.SetUnencryptedHTTP2(true)
+	ts.Start()
+
+	defer ts.Close()
+
+	transport := &http.Transport{Protocols: new(http.Protocols)}
+	transport.Protocols.SetUnencryptedHTTP2(true)
+
+	client := &http.Client{Transport: transport}
+
+	resp, err := client.Get(ts.URL)
+	require.NoError(t, err)
+
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, resp.ProtoMajor)
+	assert.Equal(t, "hello", string(body))
+	assert.Equal(t, "0", resp.Trailer.Get("Grpc-Status"))
+}
+
+func TestServeHTTP_ProtectedTunnel(t *testing.T) {
+	connService := NewMockConnService(t)
+	connService.EXPECT().SetEndpointGenerator(mock.Anything).Return()
+	connService.EXPECT().CheckClientIP(mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	connService.EXPECT().AuthorizeHTTP(mock.Anything, mock.Anything, "Bearer token").Return(true, nil)
+	connService.EXPECT().HandleHTTPConnection(mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		RunAndReturn(serveTunnel(t, tunnelResponse, func(req *http.Request) {
+			// The credentials are not passed on, and the request is proxied on its own even though
+			// the connection could be hijacked.
+			assert.Empty(t, req.Header.Get("Authorization"))
+			assert.True(t, req.Close)
+		}))
+
+	server, err := New(Config{Public: PublicEndpointConfig{Schema: "http", Domain: "example.com", Port: 80}}, connService)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "http://app.example.com/", http.NoBody)
+	req.Header.Set("Authorization", "Bearer token")
+
+	w := newHijackableResponseRecorder()
+
+	server.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "hello", w.Body.String())
+}
+
+func TestServeHTTP_RateLimitedKeepAlive(t *testing.T) {
+	connService := NewMockConnService(t)
+	connService.EXPECT().SetEndpointGenerator(mock.Anything).Return()
+	connService.EXPECT().CheckClientIP(mock.Anything, "app", mock.Anything).Return(nil)
+	connService.EXPECT().AuthorizeHTTP(mock.Anything, "app", mock.Anything).Return(false, nil)
+	connService.EXPECT().HandleHTTPConnection(mock.Anything, "app", mock.Anything, mock.Anything, mock.Anything).
+		RunAndReturn(serveTunnel(t, "HTTP/1.1 200 OK\r\nContent-Length: 5\r\n\r\nhello", func(req *http.Request) {
+			assert.True(t, req.Close)
+		})).Once()
+
+	server, err := New(Config{
+		Public:    PublicEndpointConfig{Schema: "http", Domain: "example.com", Port: 80},
+		RateLimit: RateLimitConfig{Rate: 0.001, Burst: 1},
+	}, connService)
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(server.handler())
+	defer ts.Close()
+
+	conn, err := net.Dial("tcp", ts.Listener.Addr().String())
+	require.NoError(t, err)
+
+	defer func() { _ = conn.Close() }()
+
+	br := bufio.NewReader(conn)
+
+	// Both requests are sent on the same keep-alive connection, and the second one is counted as well.
+	for _, want := range []int{http.StatusOK, http.StatusTooManyRequests} {
+		_, err = io.WriteString(conn, "GET / HTTP/1.1\r\nHost: app.example.com\r\n\r\n")
+		require.NoError(t, err)
+
+		resp, err := http.ReadResponse(br, nil)
+		require.NoError(t, err)
+
+		_, err = io.Copy(io.Discard, resp.Body)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+
+		assert.Equal(t, want, resp.StatusCode)
+	}
+}
@@ -1,5 +1,32 @@
 package edge
 
+// htmlErrorFooter is shared by the built-in error pages, so that visitors can refer to the request when they report it.
+const htmlErrorFooter = `{{define "footer"}}{{with .RequestID}}	<p><small>Request ID: {{.}}</small></p>
+{{end}}{{end}}`
+
+// htmlErrorTemplate is the built-in error page of statuses that have no page of their own.
+const htmlErrorTemplate = `<!DOCTYPE html>
+<html>
+<head>
+	<title>{{.Status}} {{.StatusText}}</title>
+</head>
+<body>
+	<h1>{{.Status}} {{.StatusText}}</h1>
+{{template "footer" .}}</body>
+</html>`
+
+const htmlErrorTemplate429 = `<!DOCTYPE html>
+<html>
+<head>
+	<title>429 Too Many Requests</title>
+</head>
+<body>
+	<h1>429 Too Many Requests</h1>
+	<p>Too many requests have been sent to this tunnel.</p>
+	<p>Please slow down and try again later.</p>
+{{template "footer" .}}</body>
+</html>`
+
 const htmlErrorTemplate502 = `<!DOCTYPE html>
 <html>
 <head>
@@ -9,7 +36,7 @@ const htmlErrorTemplate502 = `<!DOCTYPE html>
 	<h1>502 Bad Gateway</h1>
 	<p>The server received an invalid response from the upstream server.</p>
 	<p>Please try again later.</p>
-</body>
+{{template "footer" .}}</body>
 </html>`
 
 const htmlErrorTemplate404 = `<!DOCTYPE html>
@@ -21,5 +48,40 @@ const htmlErrorTemplate404 = `<!DOCTYPE html>
 	<h1>404 Not Found</h1>
 	<p>The requested resource could not be found on this server.</p>
 	<p>Please check the URL and try again.</p>
-</body>
+{{template "footer" .}}</body>
+</html>`
+
+const htmlErrorTemplate401 = `<!DOCTYPE html>
+<html>
+<head>
+	<title>401 Unauthorized</title>
+</head>
+<body>
+	<h1>401 Unauthorized</h1>
+	<p>This tunnel is protected by its owner.</p>
+	<p>Please provide valid credentials and try again.</p>
+{{template "footer" .}}</body>
+</html>`
+
+const htmlErrorTemplate403 = `<!DOCTYPE html>
+<html>
+<head>
+	<title>403 Forbidden</title>
+</head>
+<body>
+	<h1>403 Forbidden</h1>
+	<p>Access to this tunnel from your IP address is not allowed.</p>
+{{template "footer" .}}</body>
+</html>`
+
+const htmlErrorTemplate509 = `<!DOCTYPE html>
+<html>
+<head>
+	<title>509 Bandwidth Limit Exceeded</title>
+</head>
+<body>
+	<h1>509 Bandwidth Limit Exceeded</h1>
+	<p>This tunnel has used up its traffic quota.</p>
+	<p>Please try again in the next quota period.</p>
+{{template "footer" .}}</body>
 </html>`
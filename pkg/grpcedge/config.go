@@ -0,0 +1,59 @@
+package grpcedge
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ksysoev/make-it-public/pkg/core/ipfilter"
+)
+
+// Config holds configuration for the gRPC edge server.
+// The server is enabled when Listen is set. It serves HTTP/2 with TLS when Cert and Key are set,
+// and HTTP/2 without TLS otherwise, for deployments where a load balancer terminates TLS.
+// TrustedProxies lists the CIDRs of the load balancers whose forwarded headers identify the IP address of end users.
+type Config struct {
+	Listen         string               `mapstructure:"listen"`
+	Cert           string               `mapstructure:"cert"`
+	Key            string               `mapstructure:"key"`
+	Public         PublicEndpointConfig `mapstructure:"public"`
+	TrustedProxies []string             `mapstructure:"trusted_proxies"`
+}
+
+// PublicEndpointConfig defines the public endpoint advertised to clients of gRPC tunnels,
+// whose subdomains of Domain identify the tunnels.
+type PublicEndpointConfig struct {
+	Schema string `mapstructure:"schema"`
+	Domain string `mapstructure:"domain"`
+	Port   int    `mapstructure:"port"`
+}
+
+// Enabled reports whether gRPC tunnels should be served.
+func (c *Config) Enabled() bool {
+	return c.Listen != ""
+}
+
+// Validate checks that the Config is valid. It returns an error describing the
+// first violation found.
+func (c *Config) Validate() error {
+	if c.Listen == "" {
+		return errors.New("listen must not be empty")
+	}
+
+	if (c.Cert == "") != (c.Key == "") {
+		return errors.New("cert and key must be set together")
+	}
+
+	if c.Public.Domain == "" {
+		return errors.New("public.domain must not be empty")
+	}
+
+	if c.Public.Schema == "" {
+		return errors.New("public.schema must not be empty")
+	}
+
+	if _, err := ipfilter.ParsePrefixes(c.TrustedProxies); err != nil {
+		return fmt.Errorf("invalid trusted_proxies: %w", err)
+	}
+
+	return nil
+}
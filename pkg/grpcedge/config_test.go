@@ -0,0 +1,44 @@
+package grpcedge
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	public := PublicEndpointConfig{Schema: "https", Domain: "grpc.example.com", Port: 443}
+
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{name: "h2c", cfg: Config{Listen: ":8443", Public: public}},
+		{name: "tls", cfg: Config{Listen: ":8443", Cert: "cert.pem", Key: "key.pem", Public: public}},
+		{name: "empty listen", cfg: Config{Public: public}, wantErr: true},
+		{name: "cert without key", cfg: Config{Listen: ":8443", Cert: "cert.pem", Public: public}, wantErr: true},
+		{name: "key without cert", cfg: Config{Listen: ":8443", Key: "key.pem", Public: public}, wantErr: true},
+		{name: "empty domain", cfg: Config{Listen: ":8443", Public: PublicEndpointConfig{Schema: "https"}}, wantErr: true},
+		{name: "empty schema", cfg: Config{Listen: ":8443", Public: PublicEndpointConfig{Domain: "grpc.example.com"}}, wantErr: true},
+		{name: "trusted proxies", cfg: Config{Listen: ":8443", Public: public, TrustedProxies: []string{"10.0.0.0/8"}}},
+		{name: "invalid trusted proxies", cfg: Config{Listen: ":8443", Public: public, TrustedProxies: []string{"invalid"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestConfig_Enabled(t *testing.T) {
+	assert.False(t, (&Config{}).Enabled())
+	assert.True(t, (&Config{Listen: ":8443"}).Enabled())
+}
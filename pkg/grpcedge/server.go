@@ -0,0 +1,301 @@
+package grpcedge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ksysoev/make-it-public/pkg/core"
+	"github.com/ksysoev/make-it-public/pkg/core/ipfilter"
+	"github.com/ksysoev/make-it-public/pkg/core/url"
+	"github.com/ksysoev/make-it-public/pkg/edge/middleware"
+)
+
+// gRPC status codes sent in responses to calls that could not reach a tunnel.
+const (
+	codePermissionDenied  = 7
+	codeNotFound          = 5
+	codeResourceExhausted = 8
+	codeUnavailable       = 14
+)
+
+// statusBandwidthLimitExceeded is the unofficial status code of responses for tunnels whose traffic quota is used up.
+const statusBandwidthLimitExceeded = 509
+
+// ConnService is the subset of core.Service required by the gRPC edge server.
+type ConnService interface {
+	CheckClientIP(ctx context.Context, keyID, clientIP string) error
+	HandleGRPCConnection(ctx context.Context, keyID string, conn net.Conn, clientIP string) error
+	SetGRPCEndpointGenerator(generator func(string) (string, error))
+	Draining() <-chan struct{}
+}
+
+// GRPCServer serves the gRPC tunnels. It terminates the HTTP/2 connections of visitors and sends their
+// requests over HTTP/2 connections opened through the tunnels, so that streams, flow control and trailers
+// work the same way as they would with a direct connection to the gRPC service.
+type GRPCServer struct {
+	connService    ConnService
+	connCtx        context.Context
+	cancelConns    context.CancelFunc
+	transport      *http.Transport
+	proxy          *httputil.ReverseProxy
+	trustedProxies []netip.Prefix
+	config         Config
+	conns          sync.WaitGroup
+}
+
+type tunnelKeyType struct{}
+
+// tunnel identifies the tunnel of a request and records why connecting to it failed.
+type tunnel struct {
+	err      error
+	keyID    string
+	clientIP string
+	mu       sync.Mutex
+}
+
+func (t *tunnel) setErr(err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.err = err
+}
+
+func (t *tunnel) getErr() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.err
+}
+
+// New validates cfg, creates a GRPCServer, and injects the generator of the public endpoints
+// of gRPC tunnels into connService.
+func New(cfg Config, connService ConnService) (*GRPCServer, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid gRPC edge config: %w", err)
+	}
+
+	generator, err := url.NewEndpointGenerator(cfg.Public.Schema, cfg.Public.Domain, cfg.Public.Port)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create endpoint generator: %w", err)
+	}
+
+	// The trusted proxies are valid, as cfg is validated above.
+	trustedProxies, _ := ipfilter.ParsePrefixes(cfg.TrustedProxies)
+
+	connService.SetGRPCEndpointGenerator(generator)
+
+	// Connections through the tunnels are shared by calls, so they live until the server stops rather than
+	// until the call that opened them is done.
+	connCtx, cancelConns := context.WithCancel(context.Background())
+
+	s := &GRPCServer{
+		connService:    connService,
+		connCtx:        connCtx,
+		cancelConns:    cancelConns,
+		trustedProxies: trustedProxies,
+		config:         cfg,
+	}
+
+	s.transport = &http.Transport{
+		DialContext:     s.dialTunnel,
+		IdleConnTimeout: 90 * time.Second,
+		Protocols:       new(http.Protocols),
+	}
+
+	s.transport.Protocols.SetUnencryptedHTTP2(true)
+
+	s.proxy = &httputil.ReverseProxy{
+		Transport:     s.transport,
+		FlushInterval: -1,
+		Rewrite: func(r *httputil.ProxyRequest) {
+			t, _ := r.In.Context().Value(tunnelKeyType{}).(*tunnel)
+
+			r.Out.URL.Scheme = "http"
+			r.Out.URL.Host = poolHost(t.keyID, t.clientIP)
+			r.Out.Host = r.In.Host
+			r.SetXForwarded()
+		},
+		ErrorHandler: s.handleProxyError,
+	}
+
+	return s, nil
+}
+
+// Run starts the gRPC edge server and blocks until ctx is cancelled.
+// When the connection service starts draining, the server stops accepting connections and returns
+// once the calls in progress are finished.
+func (s *GRPCServer) Run(ctx context.Context) error {
+	ln, err := net.Listen("tcp", s.config.Listen)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.config.Listen, err)
+	}
+
+	server := &http.Server{
+		Handler:           s.handler(),
+		ReadHeaderTimeout: 5 * time.Second,
+		Protocols:         new(http.Protocols),
+	}
+
+	server.Protocols.SetHTTP1(true)
+
+	if s.config.Cert != "" {
+		server.Protocols.SetHTTP2(true)
+	} else {
+		server.Protocols.SetUnencryptedHTTP2(true)
+	}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-s.connService.Draining():
+			if err := server.Shutdown(ctx); err == nil {
+				return
+			}
+		}
+
+		_ = server.Close()
+	}()
+
+	defer s.closeConns()
+
+	if s.config.Cert != "" {
+		err = server.ServeTLS(ln, s.config.Cert, s.config.Key)
+	} else {
+		err = server.Serve(ln)
+	}
+
+	if !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// handler returns the handler of the server, which resolves the keyID and client IP of requests before serving them.
+func (s *GRPCServer) handler() http.Handler {
+	var handler http.Handler = s
+
+	mw := []func(next http.Handler) http.Handler{
+		middleware.ParseKeyID(s.config.Public.Domain, nil),
+		middleware.ClientIP(s.trustedProxies),
+		middleware.ReqID(),
+	}
+
+	for i := len(mw) - 1; i >= 0; i-- {
+		handler = mw[i](handler)
+	}
+
+	return handler
+}
+
+// ServeHTTP proxies a call to the tunnel of the keyID of the request.
+// Calls from client IPs rejected by the token's CIDR rules fail with PERMISSION_DENIED.
+func (s *GRPCServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	keyID := middleware.GetKeyID(r)
+	clientIP := middleware.GetClientIP(r)
+
+	if err := s.connService.CheckClientIP(r.Context(), keyID, clientIP); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), tunnelKeyType{}, &tunnel{keyID: keyID, clientIP: clientIP})
+
+	s.proxy.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// dialTunnel opens a connection to the tunnel of the request that ctx belongs to.
+// The connection is kept by the transport for further calls of the same client IP to the same keyID,
+// so it is served independently of the request that opened it.
+func (s *GRPCServer) dialTunnel(ctx context.Context, _, _ string) (net.Conn, error) {
+	t, ok := ctx.Value(tunnelKeyType{}).(*tunnel)
+	if !ok {
+		return nil, errors.New("no tunnel for connection")
+	}
+
+	edgeConn, tunnelConn := net.Pipe()
+
+	s.conns.Go(func() {
+		err := s.connService.HandleGRPCConnection(s.connCtx, t.keyID, tunnelConn, t.clientIP)
+		if err != nil {
+			// The error is recorded before the pipe is closed, so that it is known once the call fails.
+			t.setErr(err)
+			slog.DebugContext(ctx, "failed to handle gRPC connection", slog.Any("error", err), slog.String("keyID", t.keyID))
+		}
+
+		_ = tunnelConn.Close()
+	})
+
+	return edgeConn, nil
+}
+
+// closeConns closes the connections opened through the tunnels and waits until they are done.
+func (s *GRPCServer) closeConns() {
+	s.cancelConns()
+	s.transport.CloseIdleConnections()
+	s.conns.Wait()
+}
+
+// handleProxyError responds to a call that failed before the gRPC service answered it.
+func (s *GRPCServer) handleProxyError(w http.ResponseWriter, r *http.Request, err error) {
+	if t, ok := r.Context().Value(tunnelKeyType{}).(*tunnel); ok {
+		if tunErr := t.getErr(); tunErr != nil {
+			err = tunErr
+		}
+	}
+
+	if !errors.Is(err, context.Canceled) {
+		slog.DebugContext(r.Context(), "failed to proxy gRPC call", slog.Any("error", err))
+	}
+
+	writeError(w, r, err)
+}
+
+// writeError responds to a request that could not reach its tunnel. gRPC calls get a trailers-only
+// response with the matching status, so that clients report it as they would report a failed call.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	status, code, msg := http.StatusBadGateway, codeUnavailable, "tunnel is not available"
+
+	switch {
+	case errors.Is(err, core.ErrForbidden):
+		status, code, msg = http.StatusForbidden, codePermissionDenied, "access to the tunnel is denied"
+	case errors.Is(err, core.ErrKeyIDNotFound):
+		status, code, msg = http.StatusNotFound, codeNotFound, "tunnel not found"
+	case errors.Is(err, core.ErrQuotaExceeded):
+		status, code, msg = statusBandwidthLimitExceeded, codeResourceExhausted, "traffic quota of the tunnel is exceeded"
+	}
+
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+		http.Error(w, msg, status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/grpc")
+	w.Header().Set("Grpc-Status", strconv.Itoa(code))
+	w.Header().Set("Grpc-Message", msg)
+	w.WriteHeader(http.StatusOK)
+}
+
+// poolHost returns the host under which the transport pools the connections to the tunnel of keyID
+// opened for clientIP. Every client IP gets its own connections, as the client IP is sent once per connection.
+func poolHost(keyID, clientIP string) string {
+	ip := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' {
+			return r
+		}
+
+		return '-'
+	}, clientIP)
+
+	return keyID + "." + ip
+}
@@ -0,0 +1,207 @@
+package grpcedge
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ksysoev/make-it-public/pkg/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestServer(t *testing.T, connService *MockConnService) *GRPCServer {
+	t.Helper()
+
+	connService.EXPECT().SetGRPCEndpointGenerator(mock.Anything).Return()
+
+	s, err := New(Config{
+		Listen: ":0",
+		Public: PublicEndpointConfig{Schema: "https", Domain: "grpc.example.com", Port: 443},
+	}, connService)
+	require.NoError(t, err)
+
+	t.Cleanup(s.closeConns)
+
+	return s
+}
+
+// startH2C starts a server speaking HTTP/2 without TLS.
+func startH2C(t *testing.T, handler http.Handler) *httptest.Server {
+	t.Helper()
+
+	ts := httptest.NewUnstartedServer(handler)
+	ts.Config.Protocols = new(http.Protocols)
+	ts.Config.Protocols.SetUnencryptedHTTP2(true)
+	ts.Start()
+
+	t.Cleanup(ts.Close)
+
+	return ts
+}
+
+// pipeTo mimics a client behind the tunnel, which pipes the connection to the local service at addr.
+func pipeTo(addr string) func(context.Context, string, net.Conn, string) error {
+	return func(_ context.Context, _ string, conn net.Conn, _ string) error {
+		backend, err := net.Dial("tcp", addr)
+		if err != nil {
+			return err
+		}
+
+		defer func() { _ = backend.Close() }()
+
+		go func() {
+			_, _ = io.Copy(backend, conn)
+			_ = backend.Close()
+		}()
+
+		_, _ = io.Copy(conn, backend)
+
+		return nil
+	}
+}
+
+func newGRPCRequest(t *testing.T, url string) *http.Request {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodPost, url+"/echo.Echo/Say", strings.NewReader("ping"))
+	require.NoError(t, err)
+
+	req.Host = "key1.grpc.example.com"
+	req.Header.Set("Content-Type", "application/grpc")
+
+	return req
+}
+
+func h2cClient() *http.Client {
+	transport := &http.Transport{Protocols: new(http.Protocols)}
+	transport.Protocols.SetUnencryptedHTTP2(true)
+
+	return &http.Client{Transport: transport}
+}
+
+func TestNew(t *testing.T) {
+	connService := NewMockConnService(t)
+	connService.EXPECT().SetGRPCEndpointGenerator(mock.Anything).Run(func(generator func(string) (string, error)) {
+		endpoint, err := generator("key1")
+		require.NoError(t, err)
+		assert.Equal(t, "https://key1.grpc.example.com:443", endpoint)
+	}).Return()
+
+	_, err := New(Config{
+		Listen: ":0",
+		Public: PublicEndpointConfig{Schema: "https", Domain: "grpc.example.com", Port: 443},
+	}, connService)
+	require.NoError(t, err)
+
+	_, err = New(Config{}, NewMockConnService(t))
+	assert.Error(t, err)
+}
+
+func TestServeHTTP_ProxiesOverHTTP2(t *testing.T) {
+	backend := startH2C(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, 2, r.ProtoMajor)
+		assert.Equal(t, "key1.grpc.example.com", r.Host)
+		assert.Equal(t, "/echo.Echo/Say", r.URL.Path)
+		assert.NotEmpty(t, r.Header.Get("X-Forwarded-For"))
+
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, "ping", string(body))
+
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Trailer", "Grpc-Status")
+		_, _ = w.Write([]byte("pong"))
+		w.Header().Set("Grpc-Status", "0")
+	}))
+
+	connService := NewMockConnService(t)
+	connService.EXPECT().CheckClientIP(mock.Anything, "key1", mock.Anything).Return(nil)
+	connService.EXPECT().HandleGRPCConnection(mock.Anything, "key1", mock.Anything, mock.Anything).
+		RunAndReturn(pipeTo(backend.Listener.Addr().String())).Once()
+
+	edge := startH2C(t, newTestServer(t, connService).handler())
+	client := h2cClient()
+
+	// Both calls share the connection opened through the tunnel for the first one.
+	for range 2 {
+		resp, err := client.Do(newGRPCRequest(t, edge.URL))
+		require.NoError(t, err)
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+
+		_ = resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "pong", string(body))
+		assert.Equal(t, "0", resp.Trailer.Get("Grpc-Status"))
+	}
+}
+
+func TestServeHTTP_TunnelErrors(t *testing.T) {
+	tests := []struct {
+		handleErr  error
+		name       string
+		wantStatus string
+	}{
+		{name: "keyID not found", handleErr: core.ErrKeyIDNotFound, wantStatus: "5"},
+		{name: "quota exceeded", handleErr: core.ErrQuotaExceeded, wantStatus: "8"},
+		{name: "failed to connect", handleErr: core.ErrFailedToConnect, wantStatus: "14"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			connService := NewMockConnService(t)
+			connService.EXPECT().CheckClientIP(mock.Anything, "key1", mock.Anything).Return(nil)
+			connService.EXPECT().HandleGRPCConnection(mock.Anything, "key1", mock.Anything, mock.Anything).Return(tt.handleErr)
+
+			edge := startH2C(t, newTestServer(t, connService).handler())
+
+			resp, err := h2cClient().Do(newGRPCRequest(t, edge.URL))
+			require.NoError(t, err)
+
+			_ = resp.Body.Close()
+
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Equal(t, "application/grpc", resp.Header.Get("Content-Type"))
+			assert.Equal(t, tt.wantStatus, resp.Header.Get("Grpc-Status"))
+		})
+	}
+}
+
+func TestServeHTTP_Forbidden(t *testing.T) {
+	connService := NewMockConnService(t)
+	connService.EXPECT().CheckClientIP(mock.Anything, "key1", "10.0.0.1").Return(core.ErrForbidden)
+
+	handler := newTestServer(t, connService).handler()
+
+	req := httptest.NewRequest(http.MethodPost, "http://key1.grpc.example.com/echo.Echo/Say", http.NoBody)
+	req.Header.Set("Content-Type", "application/grpc")
+	req.RemoteAddr = "10.0.0.1:1234"
+	// The header is ignored, as the request does not come from a trusted proxy.
+	req.Header.Set("X-Forwarded-For", "203.0.113.1")
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "7", rec.Header().Get("Grpc-Status"))
+
+	// Requests that are not gRPC calls get a plain HTTP error.
+	req.Header.Del("Content-Type")
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+func TestPoolHost(t *testing.T) {
+	assert.Equal(t, "key1.10-0-0-1", poolHost("key1", "10.0.0.1"))
+	assert.Equal(t, "key1.--1", poolHost("key1", "::1"))
+}
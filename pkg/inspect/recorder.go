@@ -0,0 +1,266 @@
+package inspect
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// maxBodySize is the largest body captured for a single request or response.
+	maxBodySize = 1 << 20
+
+	// maxPipelined is how many requests may wait for their responses before reading
+	// further requests of the same connection blocks.
+	maxPipelined = 16
+
+	// maxPendingChunks is how many chunks of traffic may wait for a parser. When a parser
+	// falls further behind, recording of the connection stops.
+	maxPendingChunks = 64
+)
+
+// Recorder parses the HTTP traffic of tunneled connections and adds every exchange to a Store.
+// It implements revclient.Interceptor. Traffic that is not HTTP, or that follows a protocol
+// upgrade such as WebSocket, is passed through without being recorded.
+type Recorder struct {
+	store *Store
+	now   func() time.Time
+}
+
+// NewRecorder creates a Recorder adding exchanges to store.
+func NewRecorder(store *Store) *Recorder {
+	return &Recorder{
+		store: store,
+		now:   time.Now,
+	}
+}
+
+// pending is an exchange whose request has been read but which is not stored yet.
+// It is stored once both the request body and the response have been read.
+type pending struct {
+	ex      *Exchange
+	req     *http.Request
+	started time.Time
+	parts   atomic.Int32
+}
+
+// Intercept returns the writers receiving a copy of the traffic of a connection from clientIP.
+// toDest receives the data sent to the local service, and toSource the data sent back.
+// Both writers must be closed when the connection ends.
+func (r *Recorder) Intercept(ctx context.Context, clientIP string) (toDest, toSource io.WriteCloser) {
+	reqs := newStream()
+	resps := newStream()
+
+	exchanges := make(chan *pending, maxPipelined)
+
+	go r.readRequests(ctx, reqs, clientIP, exchanges)
+	go r.readResponses(ctx, resps, exchanges)
+
+	return reqs, resps
+}
+
+// readRequests parses requests from src and hands them to the response reader in order.
+func (r *Recorder) readRequests(ctx context.Context, src *stream, clientIP string, exchanges chan<- *pending) {
+	defer src.stop()
+	defer close(exchanges)
+
+	br := bufio.NewReader(src)
+
+	for {
+		req, err := http.ReadRequest(br)
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				slog.DebugContext(ctx, "stopped recording requests", slog.Any("error", err))
+			}
+
+			return
+		}
+
+		now := r.now()
+		p := &pending{
+			req:     req,
+			started: now,
+			ex: &Exchange{
+				Time:     now,
+				ClientIP: clientIP,
+				Request: &Request{
+					Method: req.Method,
+					URL:    req.RequestURI,
+					Host:   req.Host,
+					Proto:  req.Proto,
+				},
+			},
+		}
+
+		p.parts.Store(2)
+
+		// The exchange is handed over before the body is read, so that a response sent before
+		// the whole body arrives does not stall the connection.
+		exchanges <- p
+
+		body, truncated, err := readBody(req.Body)
+		p.ex.Request.Message = Message{Header: req.Header, Body: body, Truncated: truncated}
+
+		r.finish(p)
+
+		if err != nil || isUpgrade(req.Header) {
+			return
+		}
+	}
+}
+
+// readResponses parses responses from src and pairs them with the requests read by readRequests.
+func (r *Recorder) readResponses(ctx context.Context, src *stream, exchanges <-chan *pending) {
+	defer src.stop()
+
+	// Exchanges left without a response are stored as they are.
+	defer func() {
+		go func() {
+			for p := range exchanges {
+				r.finish(p)
+			}
+		}()
+	}()
+
+	br := bufio.NewReader(src)
+
+	for p := range exchanges {
+		resp, err := http.ReadResponse(br, p.req)
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				slog.DebugContext(ctx, "stopped recording responses", slog.Any("error", err))
+			}
+
+			r.finish(p)
+
+			return
+		}
+
+		body, truncated, err := readBody(resp.Body)
+
+		p.ex.Duration = r.now().Sub(p.started)
+		p.ex.Response = &Response{
+			Status:  resp.StatusCode,
+			Proto:   resp.Proto,
+			Message: Message{Header: resp.Header, Body: body, Truncated: truncated},
+		}
+
+		r.finish(p)
+
+		if err != nil || resp.StatusCode == http.StatusSwitchingProtocols {
+			return
+		}
+	}
+}
+
+// finish marks one part of p as read and stores the exchange once both parts are done.
+func (r *Recorder) finish(p *pending) {
+	if p.parts.Add(-1) == 0 {
+		r.store.Add(p.ex)
+	}
+}
+
+// readBody reads up to maxBodySize bytes of body and discards the rest.
+// It reports whether the body was cut.
+func readBody(body io.ReadCloser) ([]byte, bool, error) {
+	defer func() { _ = body.Close() }()
+
+	var buf bytes.Buffer
+
+	if _, err := io.CopyN(&buf, body, maxBodySize); err != nil {
+		if errors.Is(err, io.EOF) {
+			return buf.Bytes(), false, nil
+		}
+
+		return buf.Bytes(), false, err
+	}
+
+	n, err := io.Copy(io.Discard, body)
+
+	return buf.Bytes(), n > 0, err
+}
+
+// isUpgrade reports whether a request asks to switch to another protocol.
+func isUpgrade(h http.Header) bool {
+	return h.Get("Upgrade") != ""
+}
+
+// stream carries a copy of one direction of a connection to a parser.
+// Writes never fail or block, so that recording can not slow down or break the tunneled connection.
+type stream struct {
+	chunks chan []byte
+	done   chan struct{}
+	buf    []byte
+	mu     sync.Mutex
+	closed bool
+}
+
+func newStream() *stream {
+	return &stream{
+		chunks: make(chan []byte, maxPendingChunks),
+		done:   make(chan struct{}),
+	}
+}
+
+// Write queues a copy of p for the parser. When the parser has stopped or fallen too far behind,
+// the data is dropped and the parser sees the end of the stream.
+func (s *stream) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return len(p), nil
+	}
+
+	select {
+	case <-s.done:
+	case s.chunks <- bytes.Clone(p):
+	default:
+		s.closed = true
+		close(s.chunks)
+	}
+
+	return len(p), nil
+}
+
+// Close signals the end of the traffic to the parser.
+func (s *stream) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.closed {
+		s.closed = true
+		close(s.chunks)
+	}
+
+	return nil
+}
+
+// Read returns the queued traffic to the parser, and io.EOF once the stream is closed.
+func (s *stream) Read(p []byte) (int, error) {
+	if len(s.buf) == 0 {
+		chunk, ok := <-s.chunks
+		if !ok {
+			return 0, io.EOF
+		}
+
+		s.buf = chunk
+	}
+
+	n := copy(p, s.buf)
+	s.buf = s.buf[n:]
+
+	return n, nil
+}
+
+// stop is called by the parser when it no longer reads the stream.
+func (s *stream) stop() {
+	close(s.done)
+}
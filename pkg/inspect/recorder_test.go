@@ -0,0 +1,116 @@
+package inspect
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// waitForExchanges polls s until it holds n exchanges.
+func waitForExchanges(t *testing.T, s *Store, n int) []*Exchange {
+	t.Helper()
+
+	var list []*Exchange
+
+	require.Eventually(t, func() bool {
+		list = s.List()
+		return len(list) == n
+	}, time.Second, 10*time.Millisecond)
+
+	return list
+}
+
+func TestRecorder_Intercept(t *testing.T) {
+	store := NewStore(10)
+	rec := NewRecorder(store)
+
+	toDest, toSource := rec.Intercept(context.Background(), "1.2.3.4")
+
+	_, err := io.WriteString(toDest, "POST /hook?x=1 HTTP/1.1\r\nHost: example.com\r\nContent-Type: application/json\r\nContent-Length: 11\r\n\r\n{\"a\": true}")
+	require.NoError(t, err)
+
+	_, err = io.WriteString(toDest, "GET /second HTTP/1.1\r\nHost: example.com\r\n\r\n")
+	require.NoError(t, err)
+
+	_, err = io.WriteString(toSource, "HTTP/1.1 201 Created\r\nContent-Length: 2\r\n\r\nok")
+	require.NoError(t, err)
+
+	require.NoError(t, toDest.Close())
+	require.NoError(t, toSource.Close())
+
+	list := waitForExchanges(t, store, 2)
+
+	second, first := list[0], list[1]
+
+	assert.Equal(t, "1.2.3.4", first.ClientIP)
+	assert.Equal(t, "POST", first.Request.Method)
+	assert.Equal(t, "/hook?x=1", first.Request.URL)
+	assert.Equal(t, "example.com", first.Request.Host)
+	assert.Equal(t, `{"a": true}`, string(first.Request.Body))
+	require.NotNil(t, first.Response)
+	assert.Equal(t, 201, first.Response.Status)
+	assert.Equal(t, "ok", string(first.Response.Body))
+
+	assert.Equal(t, "/second", second.Request.URL)
+	assert.Nil(t, second.Response, "connection closed before the response was received")
+}
+
+func TestRecorder_NonHTTPTraffic(t *testing.T) {
+	store := NewStore(10)
+	rec := NewRecorder(store)
+
+	toDest, toSource := rec.Intercept(context.Background(), "1.2.3.4")
+
+	// Writes never block or fail, even when the traffic can not be parsed.
+	for i := 0; i < 100; i++ {
+		n, err := io.WriteString(toDest, strings.Repeat("\x00", 1024))
+		require.NoError(t, err)
+		assert.Equal(t, 1024, n)
+
+		_, err = io.WriteString(toSource, "binary")
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, toDest.Close())
+	require.NoError(t, toSource.Close())
+
+	assert.Empty(t, store.List())
+}
+
+func TestRecorder_Upgrade(t *testing.T) {
+	store := NewStore(10)
+	rec := NewRecorder(store)
+
+	toDest, toSource := rec.Intercept(context.Background(), "1.2.3.4")
+
+	_, err := io.WriteString(toDest, "GET /ws HTTP/1.1\r\nHost: example.com\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n\r\nframes")
+	require.NoError(t, err)
+
+	_, err = io.WriteString(toSource, "HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n\r\nframes")
+	require.NoError(t, err)
+
+	require.NoError(t, toDest.Close())
+	require.NoError(t, toSource.Close())
+
+	list := waitForExchanges(t, store, 1)
+
+	assert.Equal(t, "/ws", list[0].Request.URL)
+	assert.Equal(t, 101, list[0].Response.Status)
+}
+
+func TestReadBody_Truncates(t *testing.T) {
+	body, truncated, err := readBody(io.NopCloser(strings.NewReader(strings.Repeat("a", maxBodySize+10))))
+	require.NoError(t, err)
+	assert.True(t, truncated)
+	assert.Len(t, body, maxBodySize)
+
+	body, truncated, err = readBody(io.NopCloser(strings.NewReader("short")))
+	require.NoError(t, err)
+	assert.False(t, truncated)
+	assert.Equal(t, "short", string(body))
+}
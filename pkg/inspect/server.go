@@ -0,0 +1,336 @@
+package inspect
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ksysoev/make-it-public/pkg/dummy"
+	"github.com/ksysoev/make-it-public/pkg/target"
+)
+
+const (
+	ListEndpoint   = "GET /api/requests"
+	GetEndpoint    = "GET /api/requests/{id}"
+	ReplayEndpoint = "POST /api/requests/{id}/replay"
+
+	indexPageEndpoint  = "GET /{$}"
+	detailPageEndpoint = "GET /requests/{id}"
+	replayPageEndpoint = "POST /requests/{id}/replay"
+)
+
+const (
+	defaultCapacity = 100
+	replayTimeout   = 30 * time.Second
+)
+
+var (
+	ErrNotFound      = errors.New("request not found")
+	ErrBodyTruncated = errors.New("request body was truncated and can not be replayed")
+)
+
+// Config holds the configuration of the request inspector.
+// Listen is the address of the web UI, and Target the local service requests are replayed to, reached the way
+// the client reaches it: over TLS set up with a copy of TLS for https:// targets, and over HTTP/2 without TLS with H2C.
+type Config struct {
+	TLS      *tls.Config
+	Listen   string
+	Target   target.Target
+	Capacity int
+	H2C      bool
+}
+
+// Server serves the web UI and JSON API of the request inspector, and replays captured requests
+// to the local service.
+type Server struct {
+	store    *Store
+	recorder *Recorder
+	registry *dummy.FormatterRegistry
+	client   *http.Client
+	pages    *template.Template
+	config   Config
+}
+
+// New validates cfg and creates a Server with an empty Store.
+// Capacity defaults to 100 exchanges when not set.
+// Returns an error if the configuration is invalid.
+func New(cfg Config) (*Server, error) {
+	if cfg.Listen == "" {
+		return nil, errors.New("listen address must not be empty")
+	}
+
+	if cfg.Target.Address == "" {
+		return nil, errors.New("destination address must not be empty")
+	}
+
+	if cfg.Capacity < 0 {
+		return nil, fmt.Errorf("invalid capacity: %d", cfg.Capacity)
+	}
+
+	if cfg.Capacity == 0 {
+		cfg.Capacity = defaultCapacity
+	}
+
+	transport := cfg.Target.Transport(cfg.TLS, cfg.H2C)
+	transport.DisableCompression = true
+
+	s := &Server{
+		config:   cfg,
+		store:    NewStore(cfg.Capacity),
+		registry: dummy.NewDefaultFormatterRegistry(),
+		client: &http.Client{
+			Timeout:   replayTimeout,
+			Transport: transport,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+
+	s.recorder = NewRecorder(s.store)
+	s.pages = template.Must(template.New("pages").Funcs(template.FuncMap{
+		"body": s.formatBody,
+	}).Parse(pageTemplates))
+
+	return s, nil
+}
+
+// Recorder returns the Recorder that adds captured exchanges to the Server's Store.
+func (s *Server) Recorder() *Recorder {
+	return s.recorder
+}
+
+// Run serves the web UI and JSON API until ctx is cancelled.
+// Returns an error if the listener fails to start or the server stops unexpectedly.
+func (s *Server) Run(ctx context.Context) error {
+	ln, err := net.Listen("tcp", s.config.Listen)
+	if err != nil {
+		return fmt.Errorf("failed to start request inspector: %w", err)
+	}
+
+	srv := &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		_ = srv.Close()
+	}()
+
+	slog.InfoContext(ctx, "request inspector is available", slog.String("url", "http://"+ln.Addr().String()))
+
+	if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("request inspector failed: %w", err)
+	}
+
+	return nil
+}
+
+// Handler returns the HTTP handler of the web UI and JSON API.
+// Replays can only be requested by the inspector's own pages or by clients that are not browsers, such as the
+// replay command, so that other web sites cannot make the browser of the user replay requests. Requests naming
+// a host other than localhost, an IP address or the host of the listen address are refused, so that other web
+// sites cannot reach the inspector through DNS rebinding either.
+func (s *Server) Handler() http.Handler {
+	router := http.NewServeMux()
+
+	router.HandleFunc(ListEndpoint, s.listHandler)
+	router.HandleFunc(GetEndpoint, s.getHandler)
+	router.HandleFunc(ReplayEndpoint, s.replayHandler)
+	router.HandleFunc(indexPageEndpoint, s.indexPage)
+	router.HandleFunc(detailPageEndpoint, s.detailPage)
+	router.HandleFunc(replayPageEndpoint, s.replayPage)
+
+	return s.checkHost(http.NewCrossOriginProtection().Handler(router))
+}
+
+// checkHost refuses requests whose Host header names a host the inspector is not known by.
+func (s *Server) checkHost(next http.Handler) http.Handler {
+	listenHost, _, _ := net.SplitHostPort(s.config.Listen)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+
+		if host != "localhost" && host != listenHost && net.ParseIP(strings.Trim(host, "[]")) == nil {
+			http.Error(w, "unknown host "+r.Host, http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Replay re-sends the captured request identified by id to the local service and stores the new exchange.
+// Returns ErrNotFound if no such request is stored, ErrBodyTruncated if its body was not fully captured,
+// or an error if the local service can not be reached.
+func (s *Server) Replay(ctx context.Context, id string) (*Exchange, error) {
+	orig, ok := s.store.Get(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	if orig.Request.Truncated {
+		return nil, ErrBodyTruncated
+	}
+
+	reqURL, err := url.ParseRequestURI(orig.Request.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid request URL: %w", err)
+	}
+
+	dest := s.config.Target.Scheme() + "://" + s.config.Target.Host() + reqURL.RequestURI()
+
+	req, err := http.NewRequestWithContext(ctx, orig.Request.Method, dest, bytes.NewReader(orig.Request.Body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header = orig.Request.Header.Clone()
+	req.Host = orig.Request.Host
+
+	ex := &Exchange{
+		Time:     time.Now(),
+		ReplayOf: orig.ID,
+		Request:  orig.Request,
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to replay request: %w", err)
+	}
+
+	body, truncated, err := readBody(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	ex.Duration = time.Since(ex.Time)
+	ex.Response = &Response{
+		Status:  resp.StatusCode,
+		Proto:   resp.Proto,
+		Message: Message{Header: resp.Header, Body: body, Truncated: truncated},
+	}
+
+	s.store.Add(ex)
+
+	return ex, nil
+}
+
+func (s *Server) listHandler(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, s.store.List())
+}
+
+func (s *Server) getHandler(w http.ResponseWriter, r *http.Request) {
+	ex, ok := s.store.Get(r.PathValue("id"))
+	if !ok {
+		http.Error(w, ErrNotFound.Error(), http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ex)
+}
+
+func (s *Server) replayHandler(w http.ResponseWriter, r *http.Request) {
+	ex, err := s.Replay(r.Context(), r.PathValue("id"))
+	if err != nil {
+		http.Error(w, err.Error(), replayErrorStatus(err))
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, ex)
+}
+
+func (s *Server) indexPage(w http.ResponseWriter, _ *http.Request) {
+	s.render(w, "index", s.store.List())
+}
+
+func (s *Server) detailPage(w http.ResponseWriter, r *http.Request) {
+	ex, ok := s.store.Get(r.PathValue("id"))
+	if !ok {
+		http.Error(w, ErrNotFound.Error(), http.StatusNotFound)
+		return
+	}
+
+	s.render(w, "detail", ex)
+}
+
+func (s *Server) replayPage(w http.ResponseWriter, r *http.Request) {
+	ex, err := s.Replay(r.Context(), r.PathValue("id"))
+	if err != nil {
+		http.Error(w, err.Error(), replayErrorStatus(err))
+		return
+	}
+
+	http.Redirect(w, r, "/requests/"+ex.ID, http.StatusSeeOther)
+}
+
+func (s *Server) render(w http.ResponseWriter, name string, data any) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	if err := s.pages.ExecuteTemplate(w, name, data); err != nil {
+		slog.Error("failed to render inspector page", slog.String("page", name), slog.Any("error", err))
+	}
+}
+
+// formatBody renders a captured body for display, using the formatter registered for its content type.
+// Structured content is shown as indented JSON, and binary content as a placeholder.
+func (s *Server) formatBody(m Message) string {
+	if len(m.Body) == 0 {
+		return ""
+	}
+
+	if formatter, params, ok := s.registry.Match(m.Header.Get("Content-Type")); ok {
+		if _, val, err := formatter.FormatStructured(m.Body, params); err == nil {
+			if str, ok := val.(string); ok {
+				return str
+			}
+
+			if data, err := json.MarshalIndent(val, "", "  "); err == nil {
+				return string(data)
+			}
+		}
+	}
+
+	if !utf8.Valid(m.Body) {
+		return fmt.Sprintf("<%d bytes of binary data>", len(m.Body))
+	}
+
+	return string(m.Body)
+}
+
+// replayErrorStatus maps an error returned by Replay to an HTTP status code.
+func replayErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrBodyTruncated):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusBadGateway
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("failed to encode inspector response", slog.Any("error", err))
+	}
+}
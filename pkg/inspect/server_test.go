@@ -0,0 +1,256 @@
+package inspect
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ksysoev/make-it-public/pkg/target"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{name: "valid", cfg: Config{Listen: "localhost:4040", Target: testTarget("localhost:8080")}},
+		{name: "missing listen", cfg: Config{Target: testTarget("localhost:8080")}, wantErr: true},
+		{name: "missing destination", cfg: Config{Listen: "localhost:4040"}, wantErr: true},
+		{name: "negative capacity", cfg: Config{Listen: "localhost:4040", Target: testTarget("localhost:8080"), Capacity: -1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, err := New(tt.cfg)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.NotNil(t, srv.Recorder())
+		})
+	}
+}
+
+func testTarget(addr string) target.Target {
+	return target.Target{Network: "tcp", Address: addr}
+}
+
+func newTestServer(t *testing.T, destAddr string) *Server {
+	t.Helper()
+
+	srv, err := New(Config{Listen: "localhost:0", Target: testTarget(destAddr)})
+	require.NoError(t, err)
+
+	return srv
+}
+
+func TestServer_API(t *testing.T) {
+	srv := newTestServer(t, "localhost:8080")
+	srv.store.Add(&Exchange{
+		ClientIP: "1.2.3.4",
+		Request:  &Request{Method: http.MethodGet, URL: "/hello", Message: Message{Header: http.Header{}}},
+	})
+
+	h := srv.Handler()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://localhost:4040/api/requests", http.NoBody))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var list []Exchange
+
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+	require.Len(t, list, 1)
+	assert.Equal(t, "/hello", list[0].Request.URL)
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://localhost:4040/api/requests/1", http.NoBody))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://localhost:4040/api/requests/42", http.NoBody))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://localhost:4040/", http.NoBody))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "/hello")
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://localhost:4040/requests/1", http.NoBody))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "1.2.3.4")
+}
+
+func TestServer_Replay(t *testing.T) {
+	var (
+		gotHost string
+		gotBody string
+		gotHdr  string
+	)
+
+	local := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotHost = r.Host
+		gotBody = string(body)
+		gotHdr = r.Header.Get("X-Signature")
+
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte("done"))
+	}))
+	defer local.Close()
+
+	srv := newTestServer(t, strings.TrimPrefix(local.URL, "http://"))
+	srv.store.Add(&Exchange{
+		Request: &Request{
+			Method: http.MethodPost,
+			URL:    "/hook?x=1",
+			Host:   "abc.example.com",
+			Message: Message{
+				Header: http.Header{"X-Signature": {"sig"}},
+				Body:   []byte("payload"),
+			},
+		},
+	})
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "http://localhost:4040/api/requests/1/replay", http.NoBody))
+
+	require.Equal(t, http.StatusCreated, rec.Code)
+
+	var ex Exchange
+
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &ex))
+	assert.Equal(t, "2", ex.ID)
+	assert.Equal(t, "1", ex.ReplayOf)
+	assert.Equal(t, http.StatusAccepted, ex.Response.Status)
+	assert.Equal(t, "done", string(ex.Response.Body))
+
+	assert.Equal(t, "abc.example.com", gotHost)
+	assert.Equal(t, "payload", gotBody)
+	assert.Equal(t, "sig", gotHdr)
+}
+
+func TestServer_ReplayErrors(t *testing.T) {
+	srv := newTestServer(t, "127.0.0.1:1")
+	srv.store.Add(&Exchange{Request: &Request{Method: http.MethodPost, URL: "/", Message: Message{Truncated: true}}})
+	srv.store.Add(&Exchange{Request: &Request{Method: http.MethodGet, URL: "/"}})
+
+	_, err := srv.Replay(context.Background(), "42")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = srv.Replay(context.Background(), "1")
+	assert.ErrorIs(t, err, ErrBodyTruncated)
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "http://localhost:4040/api/requests/2/replay", http.NoBody))
+	assert.Equal(t, http.StatusBadGateway, rec.Code)
+}
+
+func TestServer_FormatBody(t *testing.T) {
+	srv := newTestServer(t, "localhost:8080")
+
+	tests := []struct {
+		name        string
+		contentType string
+		want        string
+		body        []byte
+	}{
+		{name: "empty", contentType: "application/json", body: nil, want: ""},
+		{name: "json", contentType: "application/json", body: []byte(`{"a":1}`), want: "{\n  \"a\": 1\n}"},
+		{name: "text", contentType: "text/plain; charset=utf-8", body: []byte("hello"), want: "hello"},
+		{name: "unknown type", contentType: "application/octet-stream", body: []byte("raw"), want: "raw"},
+		{name: "binary", contentType: "", body: []byte{0xff, 0xfe}, want: "<2 bytes of binary data>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := Message{Header: http.Header{"Content-Type": {tt.contentType}}, Body: tt.body}
+			assert.Equal(t, tt.want, srv.formatBody(m))
+		})
+	}
+}
+
+func TestServer_ReplayOverTLS(t *testing.T) {
+	local := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/hook", r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer local.Close()
+
+	srv, err := New(Config{
+		Listen: "localhost:0",
+		Target: target.Target{Network: "tcp", Address: local.Listener.Addr().String(), TLS: true},
+		TLS:    local.Client().Transport.(*http.Transport).TLSClientConfig,
+	})
+	require.NoError(t, err)
+
+	srv.store.Add(&Exchange{Request: &Request{Method: http.MethodGet, URL: "/hook", Host: "abc.example.com"}})
+
+	ex, err := srv.Replay(t.Context(), "1")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, ex.Response.Status)
+}
+
+func TestServer_ReplayRejectsCrossOriginRequests(t *testing.T) {
+	srv := newTestServer(t, "127.0.0.1:1")
+	srv.store.Add(&Exchange{Request: &Request{Method: http.MethodGet, URL: "/"}})
+
+	tests := []struct {
+		header http.Header
+		name   string
+		url    string
+	}{
+		{
+			name:   "cross-site fetch",
+			url:    "http://localhost:4040/api/requests/1/replay",
+			header: http.Header{"Sec-Fetch-Site": {"cross-site"}},
+		},
+		{
+			name:   "foreign origin",
+			url:    "http://localhost:4040/requests/1/replay",
+			header: http.Header{"Origin": {"https://evil.example.com"}},
+		},
+		{
+			name: "rebound host",
+			url:  "http://evil.example.com:4040/api/requests/1/replay",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.url, http.NoBody)
+			req.Header = tt.header
+
+			if req.Header == nil {
+				req.Header = http.Header{}
+			}
+
+			rec := httptest.NewRecorder()
+			srv.Handler().ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusForbidden, rec.Code)
+			assert.Len(t, srv.store.List(), 1)
+		})
+	}
+
+	// The inspector's own pages may replay requests.
+	req := httptest.NewRequest(http.MethodPost, "http://localhost:4040/requests/1/replay", http.NoBody)
+	req.Header.Set("Origin", "http://localhost:4040")
+	req.Header.Set("Sec-Fetch-Site", "same-origin")
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusBadGateway, rec.Code)
+}
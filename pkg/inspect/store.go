@@ -0,0 +1,106 @@
+// Package inspect records the HTTP traffic flowing through a tunnel and lets users browse and replay it.
+package inspect
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Message holds the captured headers and body of a request or a response.
+// Bodies larger than the capture limit are cut, with Truncated set.
+type Message struct {
+	Header    http.Header `json:"header"`
+	Body      []byte      `json:"body,omitempty"`
+	Truncated bool        `json:"truncated,omitempty"`
+}
+
+// Request is a captured HTTP request.
+type Request struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Host   string `json:"host"`
+	Proto  string `json:"proto"`
+	Message
+}
+
+// Response is a captured HTTP response.
+type Response struct {
+	Proto string `json:"proto"`
+	Message
+	Status int `json:"status"`
+}
+
+// Exchange is a request received through the tunnel together with the response of the local service.
+// Response is nil if the connection was closed before a response was received.
+type Exchange struct {
+	Time     time.Time     `json:"time"`
+	Request  *Request      `json:"request"`
+	Response *Response     `json:"response,omitempty"`
+	ID       string        `json:"id"`
+	ClientIP string        `json:"client_ip"`
+	ReplayOf string        `json:"replay_of,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Store keeps the most recent exchanges in a fixed size ring buffer.
+// Exchanges are assigned sequential IDs, starting from 1.
+type Store struct {
+	items  []*Exchange
+	next   int
+	lastID uint64
+	mu     sync.RWMutex
+}
+
+// NewStore creates a Store holding up to capacity exchanges.
+func NewStore(capacity int) *Store {
+	return &Store{
+		items: make([]*Exchange, capacity),
+	}
+}
+
+// Add assigns an ID to ex and stores it, evicting the oldest exchange when the store is full.
+func (s *Store) Add(ex *Exchange) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastID++
+	ex.ID = strconv.FormatUint(s.lastID, 10)
+
+	s.items[s.next] = ex
+	s.next = (s.next + 1) % len(s.items)
+}
+
+// List returns the stored exchanges, newest first.
+func (s *Store) List() []*Exchange {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := make([]*Exchange, 0, len(s.items))
+
+	for i := 1; i <= len(s.items); i++ {
+		ex := s.items[(s.next-i+len(s.items))%len(s.items)]
+		if ex == nil {
+			break
+		}
+
+		list = append(list, ex)
+	}
+
+	return list
+}
+
+// Get returns the exchange with the given ID, or false if it was never stored or has been evicted.
+func (s *Store) Get(id string) (*Exchange, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, ex := range s.items {
+		if ex != nil && ex.ID == id {
+			return ex, true
+		}
+	}
+
+	return nil, false
+}
@@ -0,0 +1,36 @@
+package inspect
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStore(t *testing.T) {
+	s := NewStore(2)
+
+	assert.Empty(t, s.List())
+
+	first := &Exchange{Request: &Request{URL: "/1"}}
+	second := &Exchange{Request: &Request{URL: "/2"}}
+	third := &Exchange{Request: &Request{URL: "/3"}}
+
+	s.Add(first)
+	s.Add(second)
+
+	assert.Equal(t, "1", first.ID)
+	assert.Equal(t, "2", second.ID)
+	assert.Equal(t, []*Exchange{second, first}, s.List())
+
+	s.Add(third)
+
+	assert.Equal(t, "3", third.ID)
+	assert.Equal(t, []*Exchange{third, second}, s.List())
+
+	_, ok := s.Get("1")
+	assert.False(t, ok, "oldest exchange should be evicted")
+
+	got, ok := s.Get("2")
+	assert.True(t, ok)
+	assert.Same(t, second, got)
+}
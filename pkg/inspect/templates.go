@@ -0,0 +1,71 @@
+package inspect
+
+const pageTemplates = `
+{{define "header"}}<!DOCTYPE html>
+<html>
+<head>
+	<meta charset="utf-8">
+	<title>MIT Request Inspector</title>
+	{{if .}}<meta http-equiv="refresh" content="5">{{end}}
+	<style>
+		body { font-family: sans-serif; margin: 2em; }
+		table { border-collapse: collapse; width: 100%; }
+		th, td { text-align: left; padding: 0.3em 0.6em; border-bottom: 1px solid #ddd; }
+		pre { background: #f6f8fa; padding: 1em; overflow-x: auto; }
+		.muted { color: #888; }
+	</style>
+</head>
+<body>
+	<h1><a href="/">Request Inspector</a></h1>
+{{end}}
+
+{{define "footer"}}
+</body>
+</html>
+{{end}}
+
+{{define "index"}}{{template "header" true}}
+	<table>
+		<tr><th>ID</th><th>Time</th><th>Client</th><th>Request</th><th>Status</th><th>Duration</th></tr>
+		{{range .}}
+		<tr>
+			<td><a href="/requests/{{.ID}}">{{.ID}}</a></td>
+			<td>{{.Time.Format "15:04:05"}}</td>
+			<td>{{if .ReplayOf}}replay of {{.ReplayOf}}{{else}}{{.ClientIP}}{{end}}</td>
+			<td>{{.Request.Method}} {{.Request.URL}}</td>
+			<td>{{if .Response}}{{.Response.Status}}{{else}}<span class="muted">no response</span>{{end}}</td>
+			<td>{{.Duration}}</td>
+		</tr>
+		{{else}}
+		<tr><td colspan="6" class="muted">No requests captured yet.</td></tr>
+		{{end}}
+	</table>
+{{template "footer"}}{{end}}
+
+{{define "message"}}
+	<pre>{{range $name, $values := .Header}}{{range $values}}{{$name}}: {{.}}
+{{end}}{{end}}</pre>
+	{{with body .}}<pre>{{.}}</pre>{{end}}
+	{{if .Truncated}}<p class="muted">Body truncated.</p>{{end}}
+{{end}}
+
+{{define "detail"}}{{template "header" false}}
+	<h2>#{{.ID}} {{.Request.Method}} {{.Request.URL}}</h2>
+	<p class="muted">
+		{{.Time.Format "2006-01-02 15:04:05"}}
+		{{if .ReplayOf}}&middot; replay of <a href="/requests/{{.ReplayOf}}">#{{.ReplayOf}}</a>{{else}}&middot; from {{.ClientIP}}{{end}}
+		&middot; {{.Duration}}
+	</p>
+	<form method="post" action="/requests/{{.ID}}/replay"><button type="submit">Replay</button></form>
+	<h3>Request</h3>
+	<p>{{.Request.Method}} {{.Request.URL}} {{.Request.Proto}}<br>Host: {{.Request.Host}}</p>
+	{{template "message" .Request.Message}}
+	<h3>Response</h3>
+	{{with .Response}}
+	<p>{{.Proto}} {{.Status}}</p>
+	{{template "message" .Message}}
+	{{else}}
+	<p class="muted">No response was received.</p>
+	{{end}}
+{{template "footer"}}{{end}}
+`
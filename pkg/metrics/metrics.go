@@ -0,0 +1,100 @@
+// Package metrics defines the Prometheus collectors exported by the MIT server.
+// Collectors are registered with the default Prometheus registry and exposed by Handler.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "mit"
+
+// Directions of the data piped between end-user connections and reverse connections.
+const (
+	DirectionInbound  = "inbound"
+	DirectionOutbound = "outbound"
+)
+
+var (
+	// ControlConnections is the number of established control connections by token type.
+	ControlConnections = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "control_connections",
+		Help:      "Number of established control connections.",
+	}, []string{"token_type"})
+
+	// PendingRequests is the number of connection requests sent to clients and not resolved yet.
+	PendingRequests = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "pending_conn_requests",
+		Help:      "Number of connection requests waiting for a reverse connection.",
+	})
+
+	// BytesPiped counts bytes copied between end-user connections and reverse connections.
+	// Inbound bytes travel from the end user to the client, outbound bytes travel back.
+	BytesPiped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "piped_bytes_total",
+		Help:      "Total number of bytes piped through tunnels.",
+	}, []string{"direction"})
+
+	// ConnWaitDuration observes how long it takes a client to deliver a requested reverse connection.
+	ConnWaitDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "conn_wait_duration_seconds",
+		Help:      "Time spent waiting for a reverse connection from a client.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"result"})
+
+	// TCPPortsTotal is the size of the TCP edge port range.
+	TCPPortsTotal = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "tcp_ports_total",
+		Help:      "Number of ports in the TCP edge port range.",
+	})
+
+	// TCPPortsAvailable is the number of unallocated ports in the TCP edge port range.
+	TCPPortsAvailable = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "tcp_ports_available",
+		Help:      "Number of unallocated ports in the TCP edge port range.",
+	})
+
+	// UDPPortsTotal is the size of the UDP edge port range.
+	UDPPortsTotal = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "udp_ports_total",
+		Help:      "Number of ports in the UDP edge port range.",
+	})
+
+	// UDPPortsAvailable is the number of unallocated ports in the UDP edge port range.
+	UDPPortsAvailable = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "udp_ports_available",
+		Help:      "Number of unallocated ports in the UDP edge port range.",
+	})
+
+	// EdgeResponses counts the HTTP responses produced by the edge server itself, by status code.
+	// Responses proxied from clients are not counted.
+	EdgeResponses = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "edge_responses_total",
+		Help:      "Total number of HTTP responses generated by the edge server.",
+	}, []string{"code"})
+
+	// APIRequestDuration observes the latency of management API requests by route and status code.
+	APIRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "api_request_duration_seconds",
+		Help:      "Latency of management API requests.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "code"})
+)
+
+// Handler returns an HTTP handler that serves all registered metrics in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
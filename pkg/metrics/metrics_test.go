@@ -0,0 +1,25 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler(t *testing.T) {
+	BytesPiped.WithLabelValues(DirectionInbound).Add(10)
+	EdgeResponses.WithLabelValues("502").Inc()
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", http.NoBody)
+	rec := httptest.NewRecorder()
+
+	Handler().ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `mit_piped_bytes_total{direction="inbound"}`)
+	assert.Contains(t, rec.Body.String(), `mit_edge_responses_total{code="502"}`)
+	assert.Contains(t, rec.Body.String(), "mit_pending_conn_requests")
+	assert.Contains(t, rec.Body.String(), "mit_tcp_ports_available")
+}
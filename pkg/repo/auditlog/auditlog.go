@@ -0,0 +1,41 @@
+// Package auditlog stores the audit log of tunnel sessions in a JSON-lines file or in Redis streams.
+package auditlog
+
+import (
+	"fmt"
+
+	"github.com/ksysoev/make-it-public/pkg/core"
+)
+
+// Sink is a core.AuditSink that must be closed when the server stops.
+type Sink interface {
+	core.AuditSink
+	Close() error
+}
+
+// New creates the sink selected by cfg, wrapped in a BufferedSink so that records are written in the background.
+// db is the Redis client shared with the auth repository, and keyPrefix is prepended to every Redis key.
+// Returns an error if the configuration is invalid or the log file cannot be opened.
+func New(cfg Config, db Redis, keyPrefix string) (Sink, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	var sink Sink
+
+	switch cfg.Sink {
+	case SinkFile:
+		fileSink, err := NewFileSink(cfg.File, cfg.MaxSize, cfg.MaxBackups)
+		if err != nil {
+			return nil, err
+		}
+
+		sink = fileSink
+	case SinkRedis:
+		sink = NewRedisSink(db, keyPrefix, cfg.MaxLen, cfg.Retention)
+	default:
+		return nil, fmt.Errorf("audit log is not enabled")
+	}
+
+	return NewBufferedSink(sink, cfg.BufferSize), nil
+}
@@ -0,0 +1,109 @@
+package auditlog
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/ksysoev/make-it-public/pkg/core/audit"
+)
+
+const (
+	defaultBufferSize = 1024
+	writeTimeout      = 5 * time.Second
+)
+
+var (
+	// ErrBufferFull is returned by BufferedSink.Write when the record is dropped because the sink falls behind.
+	ErrBufferFull = errors.New("audit log buffer is full")
+
+	// ErrSinkClosed is returned by BufferedSink.Write after the sink is closed.
+	ErrSinkClosed = errors.New("audit log is closed")
+)
+
+// BufferedSink queues audit records and writes them to the underlying sink in the background,
+// so that recording a session never waits for the disk or Redis. Records are dropped when the queue is full.
+type BufferedSink struct {
+	sink    Sink
+	records chan *audit.Record
+	done    chan struct{}
+	mu      sync.RWMutex
+	closed  bool
+}
+
+// NewBufferedSink creates a BufferedSink that queues up to size records for sink, and starts writing them.
+// size defaults to 1024 records when not set.
+func NewBufferedSink(sink Sink, size int) *BufferedSink {
+	if size <= 0 {
+		size = defaultBufferSize
+	}
+
+	b := &BufferedSink{
+		sink:    sink,
+		records: make(chan *audit.Record, size),
+		done:    make(chan struct{}),
+	}
+
+	go b.run()
+
+	return b
+}
+
+// Write queues rec to be written in the background.
+// Returns ErrBufferFull if the queue is full, or ErrSinkClosed if the sink is closed.
+func (b *BufferedSink) Write(_ context.Context, rec *audit.Record) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.closed {
+		return ErrSinkClosed
+	}
+
+	select {
+	case b.records <- rec:
+		return nil
+	default:
+		return ErrBufferFull
+	}
+}
+
+// Recent returns up to limit of the latest records of keyID stored by the underlying sink, newest first.
+// Records still waiting in the queue are not included.
+func (b *BufferedSink) Recent(ctx context.Context, keyID string, limit int) ([]*audit.Record, error) {
+	return b.sink.Recent(ctx, keyID, limit)
+}
+
+// Close stops accepting records, writes the records already queued and closes the underlying sink.
+func (b *BufferedSink) Close() error {
+	b.mu.Lock()
+
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+
+	b.closed = true
+	close(b.records)
+	b.mu.Unlock()
+
+	<-b.done
+
+	return b.sink.Close()
+}
+
+// run writes the queued records until the queue is closed.
+func (b *BufferedSink) run() {
+	defer close(b.done)
+
+	for rec := range b.records {
+		ctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+
+		if err := b.sink.Write(ctx, rec); err != nil {
+			slog.Error("failed to write audit record", slog.Any("error", err), slog.String("keyID", rec.KeyID), slog.String("kind", string(rec.Kind)))
+		}
+
+		cancel()
+	}
+}
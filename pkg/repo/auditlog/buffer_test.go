@@ -0,0 +1,55 @@
+package auditlog
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ksysoev/make-it-public/pkg/core/audit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingSink is a Sink whose writes wait until release is closed.
+type blockingSink struct {
+	*FileSink
+	release chan struct{}
+}
+
+func (s *blockingSink) Write(ctx context.Context, rec *audit.Record) error {
+	<-s.release
+
+	return s.FileSink.Write(ctx, rec)
+}
+
+func TestBufferedSink_WritesInBackground(t *testing.T) {
+	fileSink, err := NewFileSink(filepath.Join(t.TempDir(), "audit.log"), 0, 0)
+	require.NoError(t, err)
+
+	slow := &blockingSink{FileSink: fileSink, release: make(chan struct{})}
+	sink := NewBufferedSink(slow, 1)
+
+	// The first record is taken by the writer and the second one waits in the queue,
+	// so the third one is dropped instead of blocking the caller.
+	require.NoError(t, sink.Write(t.Context(), &audit.Record{KeyID: "key1", BytesIn: 1}))
+
+	assert.Eventually(t, func() bool { return len(sink.records) == 0 }, time.Second, time.Millisecond)
+
+	require.NoError(t, sink.Write(t.Context(), &audit.Record{KeyID: "key1", BytesIn: 2}))
+	assert.ErrorIs(t, sink.Write(t.Context(), &audit.Record{KeyID: "key1", BytesIn: 3}), ErrBufferFull)
+
+	close(slow.release)
+
+	// Close writes the queued records before closing the underlying sink.
+	require.NoError(t, sink.Close())
+	assert.ErrorIs(t, sink.Write(t.Context(), &audit.Record{KeyID: "key1"}), ErrSinkClosed)
+	require.NoError(t, sink.Close())
+
+	recs, err := fileSink.Recent(t.Context(), "key1", 10)
+	require.NoError(t, err)
+
+	require.Len(t, recs, 2)
+	assert.Equal(t, int64(2), recs[0].BytesIn)
+	assert.Equal(t, int64(1), recs[1].BytesIn)
+}
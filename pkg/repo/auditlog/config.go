@@ -0,0 +1,68 @@
+package auditlog
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	SinkFile  = "file"
+	SinkRedis = "redis"
+)
+
+// Config holds the configuration of the audit log of tunnel sessions.
+// Sink selects where records are stored: SinkFile appends JSON lines to File, which is rotated once it grows
+// past MaxSize bytes keeping MaxBackups of the previous files, and SinkRedis adds them to a Redis stream per keyID
+// that keeps about MaxLen of the latest records, none older than Retention. Records are written in the background
+// through a buffer of BufferSize records. The audit log is disabled when Sink is empty, and defaults are used for zero values.
+type Config struct {
+	Sink       string        `mapstructure:"sink"`
+	File       string        `mapstructure:"file"`
+	MaxLen     int64         `mapstructure:"max_len"`
+	MaxSize    int64         `mapstructure:"max_size"`
+	MaxBackups int           `mapstructure:"max_backups"`
+	Retention  time.Duration `mapstructure:"retention"`
+	BufferSize int           `mapstructure:"buffer_size"`
+}
+
+// Enabled reports whether the audit log is enabled.
+func (c *Config) Enabled() bool {
+	return c.Sink != ""
+}
+
+// Validate checks that the Config is valid. It returns an error describing the
+// first violation found.
+func (c *Config) Validate() error {
+	switch c.Sink {
+	case "", SinkRedis:
+	case SinkFile:
+		if c.File == "" {
+			return errors.New("file must not be empty")
+		}
+	default:
+		return fmt.Errorf("unknown sink %q, expected %q or %q", c.Sink, SinkFile, SinkRedis)
+	}
+
+	if c.MaxLen < 0 {
+		return fmt.Errorf("max_len must not be negative: %d", c.MaxLen)
+	}
+
+	if c.MaxSize < 0 {
+		return fmt.Errorf("max_size must not be negative: %d", c.MaxSize)
+	}
+
+	if c.MaxBackups < 0 {
+		return fmt.Errorf("max_backups must not be negative: %d", c.MaxBackups)
+	}
+
+	if c.Retention < 0 {
+		return fmt.Errorf("retention must not be negative: %s", c.Retention)
+	}
+
+	if c.BufferSize < 0 {
+		return fmt.Errorf("buffer_size must not be negative: %d", c.BufferSize)
+	}
+
+	return nil
+}
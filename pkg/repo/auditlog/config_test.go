@@ -0,0 +1,102 @@
+package auditlog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_Enabled(t *testing.T) {
+	assert.False(t, (&Config{}).Enabled())
+	assert.True(t, (&Config{Sink: SinkRedis}).Enabled())
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{
+			name: "disabled",
+			cfg:  Config{},
+		},
+		{
+			name: "file",
+			cfg:  Config{Sink: SinkFile, File: "/var/log/mit/audit.log"},
+		},
+		{
+			name: "redis",
+			cfg:  Config{Sink: SinkRedis, MaxLen: 500},
+		},
+		{
+			name:    "file without path",
+			cfg:     Config{Sink: SinkFile},
+			wantErr: true,
+		},
+		{
+			name:    "unknown sink",
+			cfg:     Config{Sink: "kafka"},
+			wantErr: true,
+		},
+		{
+			name:    "negative max len",
+			cfg:     Config{Sink: SinkRedis, MaxLen: -1},
+			wantErr: true,
+		},
+		{
+			name:    "negative max size",
+			cfg:     Config{Sink: SinkFile, File: "/var/log/mit/audit.log", MaxSize: -1},
+			wantErr: true,
+		},
+		{
+			name:    "negative max backups",
+			cfg:     Config{Sink: SinkFile, File: "/var/log/mit/audit.log", MaxBackups: -1},
+			wantErr: true,
+		},
+		{
+			name:    "negative retention",
+			cfg:     Config{Sink: SinkRedis, Retention: -time.Hour},
+			wantErr: true,
+		},
+		{
+			name:    "negative buffer size",
+			cfg:     Config{Sink: SinkRedis, BufferSize: -1},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	sink, err := New(Config{Sink: SinkFile, File: t.TempDir() + "/audit.log"}, nil, "")
+	require.NoError(t, err)
+	require.IsType(t, &BufferedSink{}, sink)
+	assert.IsType(t, &FileSink{}, sink.(*BufferedSink).sink)
+	require.NoError(t, sink.Close())
+
+	sink, err = New(Config{Sink: SinkRedis}, nil, "prefix::")
+	require.NoError(t, err)
+	require.IsType(t, &BufferedSink{}, sink)
+	assert.IsType(t, &RedisSink{}, sink.(*BufferedSink).sink)
+	require.NoError(t, sink.Close())
+
+	_, err = New(Config{}, nil, "")
+	assert.Error(t, err)
+
+	_, err = New(Config{Sink: "kafka"}, nil, "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,219 @@
+package auditlog
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/ksysoev/make-it-public/pkg/core/audit"
+)
+
+const (
+	defaultMaxSize    = 100 << 20
+	defaultMaxBackups = 5
+)
+
+// FileSink appends audit records to a file, one JSON object per line.
+// The file is rotated once it grows past maxSize bytes: it is renamed to <path>.1, the previous backups are
+// shifted to <path>.2 and so on, and the oldest backup beyond maxBackups is removed.
+type FileSink struct {
+	f          *os.File
+	path       string
+	size       int64
+	maxSize    int64
+	maxBackups int
+	mu         sync.Mutex
+}
+
+// NewFileSink opens the file at path for appending, creating it if it does not exist.
+// maxSize defaults to 100 MiB and maxBackups to 5 files when not set.
+// Returns an error if the file cannot be opened.
+func NewFileSink(path string, maxSize int64, maxBackups int) (*FileSink, error) {
+	if maxSize <= 0 {
+		maxSize = defaultMaxSize
+	}
+
+	if maxBackups <= 0 {
+		maxBackups = defaultMaxBackups
+	}
+
+	s := &FileSink{
+		path:       path,
+		maxSize:    maxSize,
+		maxBackups: maxBackups,
+	}
+
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Write appends rec to the file as a single line, rotating the file first if the line would take it past maxSize.
+func (s *FileSink) Write(_ context.Context, rec *audit.Record) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit record: %w", err)
+	}
+
+	data = append(data, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.size > 0 && s.size+int64(len(data)) > s.maxSize {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := s.f.Write(data)
+	s.size += int64(n)
+
+	if err != nil {
+		return fmt.Errorf("failed to write audit record: %w", err)
+	}
+
+	return nil
+}
+
+// Recent returns up to limit of the latest records of keyID, newest first.
+// The current file is read first, and the backups only while fewer than limit records are found,
+// so the amount of data read is bounded by the rotation settings.
+// Lines that cannot be parsed, e.g. a record that is still being written, are skipped.
+func (s *FileSink) Recent(ctx context.Context, keyID string, limit int) ([]*audit.Record, error) {
+	recs := []*audit.Record{}
+
+	for i := 0; i <= s.maxBackups && len(recs) < limit; i++ {
+		found, err := readRecent(ctx, s.backupPath(i), keyID, limit-len(recs))
+
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			// The file was not written yet, or it was rotated away while the previous one was read.
+			continue
+		case err != nil:
+			return nil, err
+		}
+
+		recs = append(recs, found...)
+	}
+
+	return recs, nil
+}
+
+// readRecent reads the file at path and returns up to limit of the latest records of keyID, newest first.
+func readRecent(ctx context.Context, path, keyID string, limit int) ([]*audit.Record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+
+	defer func() { _ = f.Close() }()
+
+	// The latest records are kept in a ring of limit entries, so memory use does not grow with the file.
+	ring := make([]*audit.Record, 0, limit)
+	next := 0
+
+	r := bufio.NewReader(f)
+
+	for {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		line, err := r.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("failed to read audit log: %w", err)
+		}
+
+		var rec audit.Record
+		if json.Unmarshal(line, &rec) == nil && rec.KeyID == keyID {
+			if len(ring) < limit {
+				ring = append(ring, &rec)
+			} else {
+				ring[next] = &rec
+				next = (next + 1) % limit
+			}
+		}
+
+		if err != nil {
+			break
+		}
+	}
+
+	recs := make([]*audit.Record, 0, len(ring))
+	recs = append(recs, ring[next:]...)
+	recs = append(recs, ring[:next]...)
+	slices.Reverse(recs)
+
+	return recs, nil
+}
+
+// open opens the file at s.path for appending and records its current size.
+func (s *FileSink) open() error {
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to stat audit log: %w", err)
+	}
+
+	s.f = f
+	s.size = info.Size()
+
+	return nil
+}
+
+// rotate moves the current file to the first backup, shifting the older backups, and opens a new file.
+// It must be called with s.mu held.
+func (s *FileSink) rotate() error {
+	if err := s.f.Close(); err != nil {
+		return fmt.Errorf("failed to close audit log: %w", err)
+	}
+
+	var renameErr error
+
+	for i := s.maxBackups; i > 0; i-- {
+		if err := os.Rename(s.backupPath(i-1), s.backupPath(i)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			renameErr = fmt.Errorf("failed to rotate audit log: %w", err)
+			break
+		}
+	}
+
+	// The file is reopened even if it could not be rotated, so that later records are not lost.
+	if err := s.open(); err != nil {
+		return errors.Join(renameErr, err)
+	}
+
+	return renameErr
+}
+
+// backupPath returns the path of the i-th backup, where 0 is the current file.
+func (s *FileSink) backupPath(i int) string {
+	if i == 0 {
+		return s.path
+	}
+
+	return s.path + "." + strconv.Itoa(i)
+}
+
+// Close closes the file.
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.f.Close()
+}
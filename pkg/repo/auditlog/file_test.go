@@ -0,0 +1,133 @@
+package auditlog
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ksysoev/make-it-public/pkg/core/audit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestFileSink(t *testing.T) (*FileSink, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	sink, err := NewFileSink(path, 0, 0)
+	require.NoError(t, err)
+
+	t.Cleanup(func() { _ = sink.Close() })
+
+	return sink, path
+}
+
+func TestFileSink_WriteAndRecent(t *testing.T) {
+	sink, _ := newTestFileSink(t)
+	now := time.Unix(1700000000, 0).UTC()
+
+	for i, keyID := range []string{"key1", "key2", "key1", "key1"} {
+		rec := &audit.Record{
+			Time:     now.Add(time.Duration(i) * time.Second),
+			Kind:     audit.KindPublicConn,
+			KeyID:    keyID,
+			BytesIn:  int64(i),
+			RemoteIP: "10.0.0.1",
+		}
+
+		require.NoError(t, sink.Write(t.Context(), rec))
+	}
+
+	recs, err := sink.Recent(t.Context(), "key1", 2)
+	require.NoError(t, err)
+
+	require.Len(t, recs, 2)
+	assert.Equal(t, int64(3), recs[0].BytesIn)
+	assert.Equal(t, int64(2), recs[1].BytesIn)
+	assert.Equal(t, now.Add(3*time.Second), recs[0].Time)
+
+	recs, err = sink.Recent(t.Context(), "key1", 10)
+	require.NoError(t, err)
+
+	require.Len(t, recs, 3)
+	assert.Equal(t, int64(3), recs[0].BytesIn)
+	assert.Equal(t, int64(0), recs[2].BytesIn)
+
+	recs, err = sink.Recent(t.Context(), "unknown", 10)
+	require.NoError(t, err)
+	assert.Empty(t, recs)
+}
+
+func TestFileSink_Recent_SkipsInvalidLines(t *testing.T) {
+	sink, path := newTestFileSink(t)
+
+	require.NoError(t, sink.Write(t.Context(), &audit.Record{Kind: audit.KindControlOpened, KeyID: "key1"}))
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	require.NoError(t, err)
+
+	_, err = f.WriteString("not json\n{\"kind\":\"control_clo")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	recs, err := sink.Recent(t.Context(), "key1", 10)
+	require.NoError(t, err)
+
+	require.Len(t, recs, 1)
+	assert.Equal(t, audit.KindControlOpened, recs[0].Kind)
+}
+
+func TestFileSink_Recent_ZeroLimit(t *testing.T) {
+	sink, _ := newTestFileSink(t)
+
+	require.NoError(t, sink.Write(t.Context(), &audit.Record{KeyID: "key1"}))
+
+	recs, err := sink.Recent(t.Context(), "key1", 0)
+	require.NoError(t, err)
+	assert.Empty(t, recs)
+}
+
+func TestNewFileSink_Error(t *testing.T) {
+	_, err := NewFileSink(filepath.Join(t.TempDir(), "missing", "audit.log"), 0, 0)
+
+	assert.ErrorContains(t, err, "failed to open audit log")
+}
+
+func TestFileSink_Rotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	line, err := json.Marshal(&audit.Record{KeyID: "key1", BytesIn: 10})
+	require.NoError(t, err)
+
+	// Every file holds two records, and one backup is kept.
+	sink, err := NewFileSink(path, int64(2*(len(line)+1)), 1)
+	require.NoError(t, err)
+
+	t.Cleanup(func() { _ = sink.Close() })
+
+	for i := range 5 {
+		require.NoError(t, sink.Write(t.Context(), &audit.Record{KeyID: "key1", BytesIn: int64(10 + i)}))
+	}
+
+	assert.FileExists(t, path)
+	assert.FileExists(t, path+".1")
+	assert.NoFileExists(t, path+".2")
+
+	recs, err := sink.Recent(t.Context(), "key1", 10)
+	require.NoError(t, err)
+
+	// The oldest two records were rotated away with the second backup.
+	require.Len(t, recs, 3)
+	assert.Equal(t, int64(14), recs[0].BytesIn)
+	assert.Equal(t, int64(13), recs[1].BytesIn)
+	assert.Equal(t, int64(12), recs[2].BytesIn)
+
+	recs, err = sink.Recent(t.Context(), "key1", 1)
+	require.NoError(t, err)
+
+	require.Len(t, recs, 1)
+	assert.Equal(t, int64(14), recs[0].BytesIn)
+}
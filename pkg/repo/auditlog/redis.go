@@ -0,0 +1,128 @@
+package auditlog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/ksysoev/make-it-public/pkg/core/audit"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	streamPrefix     = "AUDIT::"
+	recordField      = "record"
+	defaultMaxLen    = 1000
+	defaultRetention = 30 * 24 * time.Hour
+)
+
+// Redis is the subset of the Redis client used by the RedisSink.
+type Redis interface {
+	XAdd(ctx context.Context, a *redis.XAddArgs) *redis.StringCmd
+	XRevRangeN(ctx context.Context, stream, start, stop string, count int64) *redis.XMessageSliceCmd
+	Pipelined(ctx context.Context, fn func(redis.Pipeliner) error) ([]redis.Cmder, error)
+}
+
+// RedisSink adds audit records to a Redis stream per keyID.
+// Every stream is trimmed to about maxLen entries and drops the entries older than retention,
+// and a stream that receives no records for the retention period expires altogether.
+type RedisSink struct {
+	db        Redis
+	now       func() time.Time
+	keyPrefix string
+	maxLen    int64
+	retention time.Duration
+}
+
+// NewRedisSink creates a RedisSink. keyPrefix is prepended to every stream key, maxLen defaults
+// to 1000 records per keyID and retention to 30 days when not set.
+func NewRedisSink(db Redis, keyPrefix string, maxLen int64, retention time.Duration) *RedisSink {
+	if maxLen <= 0 {
+		maxLen = defaultMaxLen
+	}
+
+	if retention <= 0 {
+		retention = defaultRetention
+	}
+
+	return &RedisSink{
+		db:        db,
+		now:       time.Now,
+		keyPrefix: keyPrefix,
+		maxLen:    maxLen,
+		retention: retention,
+	}
+}
+
+// Write adds rec to the stream of its keyID, and applies the retention of the stream.
+func (s *RedisSink) Write(ctx context.Context, rec *audit.Record) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit record: %w", err)
+	}
+
+	key := s.streamKey(rec.KeyID)
+
+	// Stream IDs start with the time the entry was added in milliseconds, so entries older than retention
+	// are those with an ID below minID.
+	minID := strconv.FormatInt(s.now().Add(-s.retention).UnixMilli(), 10)
+
+	_, err = s.db.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.XAdd(ctx, &redis.XAddArgs{
+			Stream: key,
+			MaxLen: s.maxLen,
+			Approx: true,
+			Values: map[string]any{recordField: string(data)},
+		})
+		pipe.XTrimMinIDApprox(ctx, key, minID, 0)
+		pipe.Expire(ctx, key, s.retention)
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add audit record: %w", err)
+	}
+
+	return nil
+}
+
+// Recent returns up to limit of the latest records of keyID, newest first.
+func (s *RedisSink) Recent(ctx context.Context, keyID string, limit int) ([]*audit.Record, error) {
+	if limit <= 0 {
+		return []*audit.Record{}, nil
+	}
+
+	msgs, err := s.db.XRevRangeN(ctx, s.streamKey(keyID), "+", "-", int64(limit)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit records: %w", err)
+	}
+
+	recs := make([]*audit.Record, 0, len(msgs))
+
+	for _, msg := range msgs {
+		data, ok := msg.Values[recordField].(string)
+		if !ok {
+			continue
+		}
+
+		var rec audit.Record
+		if err := json.Unmarshal([]byte(data), &rec); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal audit record %s: %w", msg.ID, err)
+		}
+
+		recs = append(recs, &rec)
+	}
+
+	return recs, nil
+}
+
+// Close does nothing, as the Redis client is shared with the rest of the server.
+func (s *RedisSink) Close() error {
+	return nil
+}
+
+func (s *RedisSink) streamKey(keyID string) string {
+	return s.keyPrefix + streamPrefix + keyID
+}
@@ -0,0 +1,117 @@
+package auditlog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/ksysoev/make-it-public/pkg/core/audit"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testStreamKey = "prefix::AUDIT::key1"
+
+func newTestRedisSink(t *testing.T, maxLen int64, retention time.Duration) (*RedisSink, redismock.ClientMock) {
+	t.Helper()
+
+	rdb, mockRDB := redismock.NewClientMock()
+	sink := NewRedisSink(rdb, "prefix::", maxLen, retention)
+	sink.now = func() time.Time { return time.UnixMilli(1700000000000) }
+
+	return sink, mockRDB
+}
+
+func TestRedisSink_Write(t *testing.T) {
+	sink, mockRDB := newTestRedisSink(t, 0, 0)
+
+	mockRDB.ExpectXAdd(&redis.XAddArgs{
+		Stream: testStreamKey,
+		MaxLen: defaultMaxLen,
+		Approx: true,
+		Values: map[string]any{recordField: `{"time":"0001-01-01T00:00:00Z","kind":"control_opened","key_id":"key1"}`},
+	}).SetVal("1-0")
+	mockRDB.ExpectXTrimMinIDApprox(testStreamKey, "1697408000000", 0).SetVal(0)
+	mockRDB.ExpectExpire(testStreamKey, defaultRetention).SetVal(true)
+
+	err := sink.Write(t.Context(), &audit.Record{Kind: audit.KindControlOpened, KeyID: "key1"})
+
+	require.NoError(t, err)
+	assert.NoError(t, mockRDB.ExpectationsWereMet())
+}
+
+func TestRedisSink_Write_Retention(t *testing.T) {
+	sink, mockRDB := newTestRedisSink(t, 10, time.Hour)
+
+	mockRDB.ExpectXAdd(&redis.XAddArgs{
+		Stream: testStreamKey,
+		MaxLen: 10,
+		Approx: true,
+		Values: map[string]any{recordField: `{"time":"0001-01-01T00:00:00Z","kind":"control_opened","key_id":"key1"}`},
+	}).SetVal("1-0")
+	mockRDB.ExpectXTrimMinIDApprox(testStreamKey, "1699996400000", 0).SetVal(3)
+	mockRDB.ExpectExpire(testStreamKey, time.Hour).SetVal(true)
+
+	err := sink.Write(t.Context(), &audit.Record{Kind: audit.KindControlOpened, KeyID: "key1"})
+
+	require.NoError(t, err)
+	assert.NoError(t, mockRDB.ExpectationsWereMet())
+}
+
+func TestRedisSink_Write_Error(t *testing.T) {
+	sink, mockRDB := newTestRedisSink(t, 10, 0)
+
+	mockRDB.ExpectXAdd(&redis.XAddArgs{
+		Stream: testStreamKey,
+		MaxLen: 10,
+		Approx: true,
+		Values: map[string]any{recordField: `{"time":"0001-01-01T00:00:00Z","kind":"control_opened","key_id":"key1"}`},
+	}).SetErr(assert.AnError)
+
+	err := sink.Write(t.Context(), &audit.Record{Kind: audit.KindControlOpened, KeyID: "key1"})
+
+	assert.ErrorIs(t, err, assert.AnError)
+}
+
+func TestRedisSink_Recent(t *testing.T) {
+	rdb, mockRDB := redismock.NewClientMock()
+	sink := NewRedisSink(rdb, "prefix::", 0, 0)
+
+	mockRDB.ExpectXRevRangeN(testStreamKey, "+", "-", 2).SetVal([]redis.XMessage{
+		{ID: "2-0", Values: map[string]any{recordField: `{"kind":"control_closed","key_id":"key1","duration_ms":1500}`}},
+		{ID: "1-0", Values: map[string]any{recordField: `{"kind":"control_opened","key_id":"key1"}`}},
+	})
+
+	recs, err := sink.Recent(t.Context(), "key1", 2)
+
+	require.NoError(t, err)
+	require.Len(t, recs, 2)
+	assert.Equal(t, audit.KindControlClosed, recs[0].Kind)
+	assert.Equal(t, int64(1500), recs[0].DurationMS)
+	assert.Equal(t, audit.KindControlOpened, recs[1].Kind)
+}
+
+func TestRedisSink_Recent_InvalidRecord(t *testing.T) {
+	rdb, mockRDB := redismock.NewClientMock()
+	sink := NewRedisSink(rdb, "prefix::", 0, 0)
+
+	mockRDB.ExpectXRevRangeN(testStreamKey, "+", "-", 10).SetVal([]redis.XMessage{
+		{ID: "1-0", Values: map[string]any{recordField: "not json"}},
+	})
+
+	_, err := sink.Recent(t.Context(), "key1", 10)
+
+	assert.ErrorContains(t, err, "failed to unmarshal audit record 1-0")
+}
+
+func TestRedisSink_Recent_Error(t *testing.T) {
+	rdb, mockRDB := redismock.NewClientMock()
+	sink := NewRedisSink(rdb, "prefix::", 0, 0)
+
+	mockRDB.ExpectXRevRangeN(testStreamKey, "+", "-", 10).SetErr(assert.AnError)
+
+	_, err := sink.Recent(t.Context(), "key1", 10)
+
+	assert.ErrorIs(t, err, assert.AnError)
+}
@@ -3,18 +3,35 @@ package auth
 import (
 	"context"
 	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/ksysoev/make-it-public/pkg/core"
+	"github.com/ksysoev/make-it-public/pkg/core/domain"
+	"github.com/ksysoev/make-it-public/pkg/core/ipfilter"
 	"github.com/ksysoev/make-it-public/pkg/core/token"
 	"github.com/redis/go-redis/v9"
 	"golang.org/x/crypto/scrypt"
 )
 
 const (
-	scryptPrefix = "sc:"
-	apiKeyPrefix = "API_KEY::"
+	scryptPrefix    = "sc:"
+	apiKeyPrefix    = "API_KEY::"
+	ipFilterPrefix  = "IP_FILTER::"
+	tokenTypePrefix = "TOKEN_TYPE::"
+	domainPrefix    = "DOMAIN::"
+	domainsPrefix   = "DOMAINS::"
+	claimPrefix     = "DOMAIN_CLAIM::"
+	tcpPortPrefix   = "TCP_PORT::"
+	portOwnerPrefix = "TCP_PORT_OWNER::"
+	offlinePrefix   = "OFFLINE_PAGE::"
+
+	scanBatchSize = 100
 )
 
 type Config struct {
@@ -28,7 +45,15 @@ type Redis interface {
 	Get(ctx context.Context, key string) *redis.StringCmd
 	Exists(ctx context.Context, keys ...string) *redis.IntCmd
 	SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.BoolCmd
+	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd
 	Del(ctx context.Context, keys ...string) *redis.IntCmd
+	TxPipelined(ctx context.Context, fn func(redis.Pipeliner) error) ([]redis.Cmder, error)
+	PTTL(ctx context.Context, key string) *redis.DurationCmd
+	Expire(ctx context.Context, key string, expiration time.Duration) *redis.BoolCmd
+	SAdd(ctx context.Context, key string, members ...interface{}) *redis.IntCmd
+	SRem(ctx context.Context, key string, members ...interface{}) *redis.IntCmd
+	SMembers(ctx context.Context, key string) *redis.StringSliceCmd
+	Scan(ctx context.Context, cursor uint64, match string, count int64) *redis.ScanCmd
 	Ping(ctx context.Context) *redis.StatusCmd
 	Close() error
 }
@@ -43,18 +68,27 @@ type Repo struct {
 // It sets up a Redis client using the given Redis address, password, and key prefix from the Config struct.
 // Returns a pointer to the initialized Repo. Assumes valid Config is provided and may panic on misconfiguration.
 func New(cfg *Config) *Repo {
-	rdb := redis.NewClient(&redis.Options{
-		Addr:     cfg.RedisAddr,
-		Password: cfg.Password,
-	})
+	return NewWithClient(cfg, NewRedisClient(cfg))
+}
 
+// NewWithClient creates a Repo that uses an existing Redis client, so the client can be shared with other repositories.
+// The key prefix and salt are taken from cfg.
+func NewWithClient(cfg *Config, db Redis) *Repo {
 	return &Repo{
-		db:        rdb,
+		db:        db,
 		keyPrefix: cfg.KeyPrefix,
 		salt:      []byte(cfg.Salt),
 	}
 }
 
+// NewRedisClient creates a Redis client using the address and password from cfg.
+func NewRedisClient(cfg *Config) *redis.Client {
+	return redis.NewClient(&redis.Options{
+		Addr:     cfg.RedisAddr,
+		Password: cfg.Password,
+	})
+}
+
 func (r *Repo) CheckHealth(ctx context.Context) error {
 	if err := r.db.Ping(ctx).Err(); err != nil {
 		return fmt.Errorf("failed to connect to Redis: %w", err)
@@ -117,31 +151,295 @@ func (r *Repo) Verify(ctx context.Context, keyIDWithSuffix, secret string) (*tok
 // It generates a hashed secret using the token's Secret and the Repo's salt.
 // The stored value format is: sc:<hash>
 // The token is stored using its base ID (without type suffix).
+// The type of the token, its reserved TCP port and its IP filter if any, are stored under separate keys with the same TTL
+// in a single transaction. Keys written before a failure are removed again, so a token is either saved completely or not at all.
 // Returns an error if hashing fails, or if the database operation encounters an issue.
-// Returns core.ErrDuplicateTokenID if a token with the same ID already exists.
+// Returns core.ErrDuplicateTokenID if a token with the same ID already exists,
+// or core.ErrPortReserved if the requested port is reserved by another token.
 func (r *Repo) SaveToken(ctx context.Context, t *token.Token) error {
 	secretHash, err := hashSecret(t.Secret, r.salt)
 	if err != nil {
 		return fmt.Errorf("failed to encrypt secret: %w", err)
 	}
 
-	res := r.db.SetNX(ctx, r.keyPrefix+apiKeyPrefix+t.ID, secretHash, t.TTL)
+	var filter []byte
 
-	if res.Err() != nil {
-		return fmt.Errorf("failed to save token: %w", res.Err())
+	if t.IPFilter != nil {
+		if filter, err = json.Marshal(t.IPFilter); err != nil {
+			return fmt.Errorf("failed to marshal IP filter: %w", err)
+		}
+	}
+
+	apiKey := r.keyPrefix + apiKeyPrefix + t.ID
+
+	ok, err := r.db.SetNX(ctx, apiKey, secretHash, t.TTL).Result()
+	if err != nil {
+		return fmt.Errorf("failed to save token: %w", err)
 	}
 
-	if !res.Val() {
+	if !ok {
 		return core.ErrDuplicateTokenID
 	}
 
+	created := []string{apiKey}
+
+	if t.Port > 0 {
+		ownerKey := r.keyPrefix + portOwnerPrefix + strconv.Itoa(t.Port)
+
+		ok, err := r.db.SetNX(ctx, ownerKey, t.ID, t.TTL).Result()
+
+		switch {
+		case err != nil:
+			return r.rollback(ctx, created, fmt.Errorf("failed to reserve port: %w", err))
+		case !ok:
+			return r.rollback(ctx, created, core.ErrPortReserved)
+		}
+
+		created = append(created, ownerKey)
+	}
+
+	typeKey := r.keyPrefix + tokenTypePrefix + t.ID
+	portKey := r.keyPrefix + tcpPortPrefix + t.ID
+	filterKey := r.keyPrefix + ipFilterPrefix + t.ID
+
+	_, err = r.db.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, typeKey, string(t.Type), t.TTL)
+
+		if t.Port > 0 {
+			pipe.Set(ctx, portKey, t.Port, t.TTL)
+		}
+
+		if filter != nil {
+			pipe.Set(ctx, filterKey, filter, t.TTL)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return r.rollback(ctx, append(created, typeKey, portKey, filterKey), fmt.Errorf("failed to save token details: %w", err))
+	}
+
 	return nil
 }
 
-// DeleteToken removes a token identified by tokenID from the database using the configured key prefix.
+// rollback removes the given keys of a token that could not be saved completely and returns err.
+// The removal is not bound to the cancellation of ctx, so that a canceled request does not leave a partial token behind.
+func (r *Repo) rollback(ctx context.Context, keys []string, err error) error {
+	if delErr := r.db.Del(context.WithoutCancel(ctx), keys...).Err(); delErr != nil {
+		return errors.Join(err, fmt.Errorf("failed to remove partially saved token: %w", delErr))
+	}
+
+	return err
+}
+
+// ReservedPort retrieves the TCP port reserved for the token identified by keyID.
+// Returns 0 if the token has no reserved port, or an error if the database operation fails.
+func (r *Repo) ReservedPort(ctx context.Context, keyID string) (int, error) {
+	port, err := r.db.Get(ctx, r.keyPrefix+tcpPortPrefix+keyID).Int()
+
+	switch {
+	case errors.Is(err, redis.Nil):
+		return 0, nil
+	case err != nil:
+		return 0, fmt.Errorf("failed to get reserved port: %w", err)
+	}
+
+	return port, nil
+}
+
+// PortOwner retrieves the ID of the token that reserved the given TCP port.
+// Returns an empty string if the port is not reserved, or an error if the database operation fails.
+func (r *Repo) PortOwner(ctx context.Context, port int) (string, error) {
+	keyID, err := r.db.Get(ctx, r.keyPrefix+portOwnerPrefix+strconv.Itoa(port)).Result()
+
+	switch {
+	case errors.Is(err, redis.Nil):
+		return "", nil
+	case err != nil:
+		return "", fmt.Errorf("failed to get port owner: %w", err)
+	}
+
+	return keyID, nil
+}
+
+// IPFilter retrieves the IP filter stored for the token identified by keyID.
+// Returns nil, nil if the token has no IP filter.
+// Returns an error if the database operation fails or the stored filter is malformed.
+func (r *Repo) IPFilter(ctx context.Context, keyID string) (*ipfilter.Filter, error) {
+	data, err := r.db.Get(ctx, r.keyPrefix+ipFilterPrefix+keyID).Bytes()
+
+	switch {
+	case errors.Is(err, redis.Nil):
+		return nil, nil
+	case err != nil:
+		return nil, fmt.Errorf("failed to get IP filter: %w", err)
+	}
+
+	var f ipfilter.Filter
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal IP filter: %w", err)
+	}
+
+	return &f, nil
+}
+
+// GetToken retrieves the token identified by keyID without its secret.
+// The TTL of the returned token is the time remaining until it expires, or zero if it never expires.
+// Type is empty for tokens saved before token types were stored.
+// Returns core.ErrTokenNotFound if the token does not exist, or an error if the database operation fails.
+func (r *Repo) GetToken(ctx context.Context, keyID string) (*token.Token, error) {
+	ttl, err := r.db.PTTL(ctx, r.keyPrefix+apiKeyPrefix+keyID).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get token TTL: %w", err)
+	}
+
+	// PTTL reports -2 for missing keys and -1 for keys without expiration.
+	switch {
+	case ttl == -2:
+		return nil, core.ErrTokenNotFound
+	case ttl < 0:
+		ttl = 0
+	}
+
+	tokenType, err := r.db.Get(ctx, r.keyPrefix+tokenTypePrefix+keyID).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("failed to get token type: %w", err)
+	}
+
+	port, err := r.ReservedPort(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	ipFilter, err := r.IPFilter(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &token.Token{
+		ID:       keyID,
+		Type:     token.TokenType(tokenType),
+		TTL:      ttl,
+		Port:     port,
+		IPFilter: ipFilter,
+	}, nil
+}
+
+// ListTokens retrieves all stored tokens without their secrets, sorted by ID.
+// Tokens that expire while they are listed are skipped.
+// Returns an error if the database operation fails.
+func (r *Repo) ListTokens(ctx context.Context) ([]*token.Token, error) {
+	prefix := r.keyPrefix + apiKeyPrefix
+
+	var (
+		ids    []string
+		cursor uint64
+	)
+
+	for {
+		keys, next, err := r.db.Scan(ctx, cursor, prefix+"*", scanBatchSize).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan tokens: %w", err)
+		}
+
+		for _, key := range keys {
+			ids = append(ids, strings.TrimPrefix(key, prefix))
+		}
+
+		if next == 0 {
+			break
+		}
+
+		cursor = next
+	}
+
+	// SCAN may return a key more than once.
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+
+	tokens := make([]*token.Token, 0, len(ids))
+
+	for _, id := range ids {
+		t, err := r.GetToken(ctx, id)
+
+		switch {
+		case errors.Is(err, core.ErrTokenNotFound):
+			continue
+		case err != nil:
+			return nil, err
+		}
+
+		tokens = append(tokens, t)
+	}
+
+	return tokens, nil
+}
+
+// UpdateTokenTTL sets the time remaining until the token identified by keyID expires, without changing its secret.
+// The expiration of the token's type, IP filter, offline page, reserved port and custom domains is updated as well.
+// Returns core.ErrTokenNotFound if the token does not exist, or an error if the database operation fails.
+func (r *Repo) UpdateTokenTTL(ctx context.Context, keyID string, ttl time.Duration) error {
+	ok, err := r.db.Expire(ctx, r.keyPrefix+apiKeyPrefix+keyID, ttl).Result()
+	if err != nil {
+		return fmt.Errorf("failed to update token TTL: %w", err)
+	}
+
+	if !ok {
+		return core.ErrTokenNotFound
+	}
+
+	domainKeys, err := r.domainKeys(ctx, keyID)
+	if err != nil {
+		return err
+	}
+
+	port, err := r.ReservedPort(ctx, keyID)
+	if err != nil {
+		return err
+	}
+
+	keys := []string{r.keyPrefix + tokenTypePrefix + keyID, r.keyPrefix + ipFilterPrefix + keyID, r.keyPrefix + offlinePrefix + keyID}
+	if port > 0 {
+		keys = append(keys, r.keyPrefix+tcpPortPrefix+keyID, r.keyPrefix+portOwnerPrefix+strconv.Itoa(port))
+	}
+
+	keys = append(keys, domainKeys...)
+
+	// Related keys are optional, so missing ones are not an error.
+	for _, key := range keys {
+		if err := r.db.Expire(ctx, key, ttl).Err(); err != nil {
+			return fmt.Errorf("failed to update TTL of %s: %w", key, err)
+		}
+	}
+
+	return nil
+}
+
+// DeleteToken removes a token identified by tokenID, together with its type, IP filter, offline page, reserved port and custom domains, from the database
+// using the configured key prefix.
 // It returns an error if the deletion operation fails.
 func (r *Repo) DeleteToken(ctx context.Context, tokenID string) error {
-	res := r.db.Del(ctx, r.keyPrefix+apiKeyPrefix+tokenID)
+	domainKeys, err := r.domainKeys(ctx, tokenID)
+	if err != nil {
+		return err
+	}
+
+	port, err := r.ReservedPort(ctx, tokenID)
+	if err != nil {
+		return err
+	}
+
+	keys := []string{
+		r.keyPrefix + apiKeyPrefix + tokenID, r.keyPrefix + ipFilterPrefix + tokenID,
+		r.keyPrefix + tokenTypePrefix + tokenID, r.keyPrefix + offlinePrefix + tokenID,
+	}
+	if port > 0 {
+		keys = append(keys, r.keyPrefix+tcpPortPrefix+tokenID, r.keyPrefix+portOwnerPrefix+strconv.Itoa(port))
+	}
+
+	keys = append(keys, domainKeys...)
+
+	res := r.db.Del(ctx, keys...)
 
 	if res.Err() != nil {
 		return fmt.Errorf("failed to delete token: %w", res.Err())
@@ -154,6 +452,308 @@ func (r *Repo) DeleteToken(ctx context.Context, tokenID string) error {
 	return nil
 }
 
+// AddDomain stores d as a claim of its token and adds it to the domains of the token.
+// Claims are kept per token, so that a token can not keep others from claiming a domain it does not own:
+// the domain name is only reserved for a token by UpdateDomain, once the domain is verified.
+// The domain expires together with the token it belongs to.
+// Returns core.ErrTokenNotFound if the token does not exist, core.ErrDuplicateDomain if the token already claimed
+// the domain or another token verified it, or an error if the database operation fails.
+func (r *Repo) AddDomain(ctx context.Context, d *domain.Domain) error {
+	ttl, err := r.tokenTTL(ctx, d.KeyID)
+	if err != nil {
+		return err
+	}
+
+	verified, err := r.db.Exists(ctx, r.keyPrefix+domainPrefix+d.Name).Result()
+	if err != nil {
+		return fmt.Errorf("failed to check domain: %w", err)
+	}
+
+	if verified > 0 {
+		return core.ErrDuplicateDomain
+	}
+
+	data, err := json.Marshal(d)
+	if err != nil {
+		return fmt.Errorf("failed to marshal domain: %w", err)
+	}
+
+	ok, err := r.db.SetNX(ctx, r.claimKey(d.KeyID, d.Name), data, ttl).Result()
+	if err != nil {
+		return fmt.Errorf("failed to save domain: %w", err)
+	}
+
+	if !ok {
+		return core.ErrDuplicateDomain
+	}
+
+	setKey := r.keyPrefix + domainsPrefix + d.KeyID
+
+	if err := r.db.SAdd(ctx, setKey, d.Name).Err(); err != nil {
+		return fmt.Errorf("failed to add domain to token: %w", err)
+	}
+
+	if ttl > 0 {
+		if err := r.db.Expire(ctx, setKey, ttl).Err(); err != nil {
+			return fmt.Errorf("failed to set domains expiration: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// GetDomain retrieves the verified domain name, which is reserved for the token it is routed to.
+// Returns core.ErrDomainNotFound if no token verified the domain, or an error if the database operation fails.
+func (r *Repo) GetDomain(ctx context.Context, name string) (*domain.Domain, error) {
+	return r.getDomain(ctx, r.keyPrefix+domainPrefix+name)
+}
+
+// TokenDomain retrieves the domain name claimed by the token identified by keyID, whether it is verified or not.
+// Returns core.ErrDomainNotFound if the token did not claim the domain, or an error if the database operation fails.
+func (r *Repo) TokenDomain(ctx context.Context, keyID, name string) (*domain.Domain, error) {
+	return r.getDomain(ctx, r.claimKey(keyID, name))
+}
+
+// UpdateDomain overwrites the claim of the domain by its token with d, keeping its expiration.
+// A verified domain is also reserved for the token, so that requests for it are routed to the tunnel;
+// the first token to verify a domain keeps it until the domain is removed or expires.
+// Returns core.ErrDuplicateDomain if another token verified the domain, or an error if the database operation fails.
+func (r *Repo) UpdateDomain(ctx context.Context, d *domain.Domain) error {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return fmt.Errorf("failed to marshal domain: %w", err)
+	}
+
+	if d.Verified {
+		if err := r.reserveDomain(ctx, d, data); err != nil {
+			return err
+		}
+	}
+
+	if err := r.db.Set(ctx, r.claimKey(d.KeyID, d.Name), data, redis.KeepTTL).Err(); err != nil {
+		return fmt.Errorf("failed to update domain: %w", err)
+	}
+
+	return nil
+}
+
+// ListDomains returns the domains claimed by the token identified by keyID, sorted by name.
+// Domains that expired in the meantime are skipped.
+// Returns an error if the database operation fails.
+func (r *Repo) ListDomains(ctx context.Context, keyID string) ([]*domain.Domain, error) {
+	names, err := r.db.SMembers(ctx, r.keyPrefix+domainsPrefix+keyID).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list domains: %w", err)
+	}
+
+	slices.Sort(names)
+
+	domains := make([]*domain.Domain, 0, len(names))
+
+	for _, name := range names {
+		d, err := r.TokenDomain(ctx, keyID, name)
+
+		switch {
+		case errors.Is(err, core.ErrDomainNotFound):
+			continue
+		case err != nil:
+			return nil, err
+		}
+
+		domains = append(domains, d)
+	}
+
+	return domains, nil
+}
+
+// RemoveDomain removes the domain name from the token identified by keyID, and releases the name if the token
+// verified it.
+// Returns core.ErrDomainNotFound if the domain is not claimed by the token, or an error if the database operation fails.
+func (r *Repo) RemoveDomain(ctx context.Context, keyID, name string) error {
+	if _, err := r.TokenDomain(ctx, keyID, name); err != nil {
+		return err
+	}
+
+	keys := []string{r.claimKey(keyID, name)}
+
+	owned, err := r.ownsDomain(ctx, keyID, name)
+	if err != nil {
+		return err
+	}
+
+	if owned {
+		keys = append(keys, r.keyPrefix+domainPrefix+name)
+	}
+
+	if err := r.db.Del(ctx, keys...).Err(); err != nil {
+		return fmt.Errorf("failed to delete domain: %w", err)
+	}
+
+	if err := r.db.SRem(ctx, r.keyPrefix+domainsPrefix+keyID, name).Err(); err != nil {
+		return fmt.Errorf("failed to remove domain from token: %w", err)
+	}
+
+	return nil
+}
+
+// reserveDomain reserves the verified domain d, encoded as data, for its token until the token expires.
+// Returns core.ErrDuplicateDomain if another token reserved the domain first.
+func (r *Repo) reserveDomain(ctx context.Context, d *domain.Domain, data []byte) error {
+	ttl, err := r.tokenTTL(ctx, d.KeyID)
+	if err != nil {
+		return err
+	}
+
+	ok, err := r.db.SetNX(ctx, r.keyPrefix+domainPrefix+d.Name, data, ttl).Result()
+	if err != nil {
+		return fmt.Errorf("failed to reserve domain: %w", err)
+	}
+
+	if ok {
+		return nil
+	}
+
+	owned, err := r.ownsDomain(ctx, d.KeyID, d.Name)
+	if err != nil {
+		return err
+	}
+
+	if !owned {
+		return core.ErrDuplicateDomain
+	}
+
+	return nil
+}
+
+// ownsDomain reports whether the domain name is reserved for the token identified by keyID.
+func (r *Repo) ownsDomain(ctx context.Context, keyID, name string) (bool, error) {
+	d, err := r.GetDomain(ctx, name)
+
+	switch {
+	case errors.Is(err, core.ErrDomainNotFound):
+		return false, nil
+	case err != nil:
+		return false, err
+	}
+
+	return d.KeyID == keyID, nil
+}
+
+// domainKeys returns the keys of the domains of the token identified by keyID: the set of its domains,
+// its claims and the names reserved for it. Names that other tokens verified are left out.
+func (r *Repo) domainKeys(ctx context.Context, keyID string) ([]string, error) {
+	names, err := r.db.SMembers(ctx, r.keyPrefix+domainsPrefix+keyID).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list domains: %w", err)
+	}
+
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	keys := []string{r.keyPrefix + domainsPrefix + keyID}
+
+	for _, name := range names {
+		keys = append(keys, r.claimKey(keyID, name))
+
+		owned, err := r.ownsDomain(ctx, keyID, name)
+		if err != nil {
+			return nil, err
+		}
+
+		if owned {
+			keys = append(keys, r.keyPrefix+domainPrefix+name)
+		}
+	}
+
+	return keys, nil
+}
+
+// getDomain retrieves the domain stored under key.
+// Returns core.ErrDomainNotFound if the key does not exist, or an error if the database operation fails.
+func (r *Repo) getDomain(ctx context.Context, key string) (*domain.Domain, error) {
+	data, err := r.db.Get(ctx, key).Bytes()
+
+	switch {
+	case errors.Is(err, redis.Nil):
+		return nil, core.ErrDomainNotFound
+	case err != nil:
+		return nil, fmt.Errorf("failed to get domain: %w", err)
+	}
+
+	var d domain.Domain
+	if err := json.Unmarshal(data, &d); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal domain: %w", err)
+	}
+
+	return &d, nil
+}
+
+// claimKey returns the key of the claim of the domain name by the token identified by keyID.
+func (r *Repo) claimKey(keyID, name string) string {
+	return r.keyPrefix + claimPrefix + keyID + "::" + name
+}
+
+// SetOfflinePage stores the offline page of the token identified by keyID, replacing the previous one.
+// The page expires together with the token it belongs to.
+// Returns core.ErrTokenNotFound if the token does not exist, or an error if the database operation fails.
+func (r *Repo) SetOfflinePage(ctx context.Context, keyID, page string) error {
+	ttl, err := r.tokenTTL(ctx, keyID)
+	if err != nil {
+		return err
+	}
+
+	if err := r.db.Set(ctx, r.keyPrefix+offlinePrefix+keyID, page, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to save offline page: %w", err)
+	}
+
+	return nil
+}
+
+// OfflinePage retrieves the offline page of the token identified by keyID.
+// Returns an empty string if the token has no offline page, or an error if the database operation fails.
+func (r *Repo) OfflinePage(ctx context.Context, keyID string) (string, error) {
+	page, err := r.db.Get(ctx, r.keyPrefix+offlinePrefix+keyID).Result()
+
+	switch {
+	case errors.Is(err, redis.Nil):
+		return "", nil
+	case err != nil:
+		return "", fmt.Errorf("failed to get offline page: %w", err)
+	}
+
+	return page, nil
+}
+
+// RemoveOfflinePage deletes the offline page of the token identified by keyID.
+// Returns an error if the database operation fails.
+func (r *Repo) RemoveOfflinePage(ctx context.Context, keyID string) error {
+	if err := r.db.Del(ctx, r.keyPrefix+offlinePrefix+keyID).Err(); err != nil {
+		return fmt.Errorf("failed to delete offline page: %w", err)
+	}
+
+	return nil
+}
+
+// tokenTTL returns the time remaining until the token identified by keyID expires, or zero if it never expires.
+// Returns core.ErrTokenNotFound if the token does not exist, or an error if the database operation fails.
+func (r *Repo) tokenTTL(ctx context.Context, keyID string) (time.Duration, error) {
+	ttl, err := r.db.PTTL(ctx, r.keyPrefix+apiKeyPrefix+keyID).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get token TTL: %w", err)
+	}
+
+	// PTTL reports -2 for missing keys and -1 for keys without expiration.
+	switch {
+	case ttl == -2:
+		return 0, core.ErrTokenNotFound
+	case ttl < 0:
+		return 0, nil
+	}
+
+	return ttl, nil
+}
+
 // Close releases any resources associated with the Redis connection.
 // Returns an error if the connection fails to close.
 func (r *Repo) Close() error {
@@ -2,11 +2,14 @@ package auth
 
 import (
 	"context"
+	"encoding/json"
 	"testing"
 	"time"
 
 	"github.com/go-redis/redismock/v9"
 	"github.com/ksysoev/make-it-public/pkg/core"
+	"github.com/ksysoev/make-it-public/pkg/core/domain"
+	"github.com/ksysoev/make-it-public/pkg/core/ipfilter"
 	"github.com/ksysoev/make-it-public/pkg/core/token"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -141,9 +144,27 @@ func TestRepo_SaveToken(t *testing.T) {
 			name: "successful token save",
 			mockSetup: func(m redismock.ClientMock) {
 				m.CustomMatch(matcher).ExpectSetNX(mock.Anything, mock.Anything, time.Minute).SetVal(true)
+				m.ExpectTxPipeline()
+				m.ExpectSet("prefix::TOKEN_TYPE::test-id", "w", time.Minute).SetVal("OK")
+				m.ExpectTxPipelineExec()
 			},
 			wantErr: nil,
 		},
+		{
+			name: "failed to save token details",
+			mockSetup: func(m redismock.ClientMock) {
+				m.CustomMatch(matcher).ExpectSetNX(mock.Anything, mock.Anything, time.Minute).SetVal(true)
+				m.ExpectTxPipeline()
+				m.ExpectSet("prefix::TOKEN_TYPE::test-id", "w", time.Minute).SetErr(assert.AnError)
+				m.ExpectDel(
+					"prefix::API_KEY::test-id",
+					"prefix::TOKEN_TYPE::test-id",
+					"prefix::TCP_PORT::test-id",
+					"prefix::IP_FILTER::test-id",
+				).SetVal(1)
+			},
+			wantErr: assert.AnError,
+		},
 		{
 			name: "duplicate token ID",
 			mockSetup: func(m redismock.ClientMock) {
@@ -190,6 +211,809 @@ func TestRepo_SaveToken(t *testing.T) {
 	}
 }
 
+func TestRepo_SaveToken_WithIPFilter(t *testing.T) {
+	rdb, mockRDB := redismock.NewClientMock()
+
+	r := &Repo{
+		db:        rdb,
+		keyPrefix: "prefix::",
+		salt:      []byte("test-salt"),
+	}
+
+	f, err := ipfilter.New([]string{"10.0.0.0/8"}, []string{"10.0.0.1"})
+	require.NoError(t, err)
+
+	data, err := json.Marshal(f)
+	require.NoError(t, err)
+
+	mockRDB.CustomMatch(func(_, _ []interface{}) error { return nil }).
+		ExpectSetNX("prefix::API_KEY::test-id", mock.Anything, time.Minute).SetVal(true)
+	mockRDB.ExpectTxPipeline()
+	mockRDB.ExpectSet("prefix::TOKEN_TYPE::test-id", "w", time.Minute).SetVal("OK")
+	mockRDB.ExpectSet("prefix::IP_FILTER::test-id", data, time.Minute).SetVal("OK")
+	mockRDB.ExpectTxPipelineExec()
+
+	err = r.SaveToken(context.Background(), &token.Token{
+		ID:       "test-id",
+		Secret:   "test-secret",
+		TTL:      time.Minute,
+		Type:     token.TokenTypeWeb,
+		IPFilter: f,
+	})
+	require.NoError(t, err)
+	assert.NoError(t, mockRDB.ExpectationsWereMet())
+}
+
+func TestRepo_SaveToken_WithPort(t *testing.T) {
+	tests := []struct {
+		wantErr   error
+		mockSetup func(m redismock.ClientMock)
+		name      string
+	}{
+		{
+			name: "port reserved",
+			mockSetup: func(m redismock.ClientMock) {
+				m.ExpectSetNX("prefix::TCP_PORT_OWNER::30000", "test-id", time.Minute).SetVal(true)
+				m.ExpectTxPipeline()
+				m.ExpectSet("prefix::TOKEN_TYPE::test-id", "t", time.Minute).SetVal("OK")
+				m.ExpectSet("prefix::TCP_PORT::test-id", 30000, time.Minute).SetVal("OK")
+				m.ExpectTxPipelineExec()
+			},
+		},
+		{
+			name: "failed to save token details",
+			mockSetup: func(m redismock.ClientMock) {
+				m.ExpectSetNX("prefix::TCP_PORT_OWNER::30000", "test-id", time.Minute).SetVal(true)
+				m.ExpectTxPipeline()
+				m.ExpectSet("prefix::TOKEN_TYPE::test-id", "t", time.Minute).SetVal("OK")
+				m.ExpectSet("prefix::TCP_PORT::test-id", 30000, time.Minute).SetErr(assert.AnError)
+				m.ExpectDel(
+					"prefix::API_KEY::test-id",
+					"prefix::TCP_PORT_OWNER::30000",
+					"prefix::TOKEN_TYPE::test-id",
+					"prefix::TCP_PORT::test-id",
+					"prefix::IP_FILTER::test-id",
+				).SetVal(2)
+			},
+			wantErr: assert.AnError,
+		},
+		{
+			name: "port reserved by another token",
+			mockSetup: func(m redismock.ClientMock) {
+				m.ExpectSetNX("prefix::TCP_PORT_OWNER::30000", "test-id", time.Minute).SetVal(false)
+				m.ExpectDel("prefix::API_KEY::test-id").SetVal(1)
+			},
+			wantErr: core.ErrPortReserved,
+		},
+		{
+			name: "redis error",
+			mockSetup: func(m redismock.ClientMock) {
+				m.ExpectSetNX("prefix::TCP_PORT_OWNER::30000", "test-id", time.Minute).SetErr(assert.AnError)
+				m.ExpectDel("prefix::API_KEY::test-id").SetVal(1)
+			},
+			wantErr: assert.AnError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rdb, mockRDB := redismock.NewClientMock()
+
+			r := &Repo{
+				db:        rdb,
+				keyPrefix: "prefix::",
+				salt:      []byte("test-salt"),
+			}
+
+			mockRDB.CustomMatch(func(_, _ []interface{}) error { return nil }).
+				ExpectSetNX("prefix::API_KEY::test-id", mock.Anything, time.Minute).SetVal(true)
+			tt.mockSetup(mockRDB)
+
+			err := r.SaveToken(context.Background(), &token.Token{
+				ID:     "test-id",
+				Secret: "test-secret",
+				TTL:    time.Minute,
+				Type:   token.TokenTypeTCP,
+				Port:   30000,
+			})
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			assert.NoError(t, mockRDB.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRepo_PortOwner(t *testing.T) {
+	rdb, mockRDB := redismock.NewClientMock()
+
+	r := &Repo{
+		db:        rdb,
+		keyPrefix: "prefix::",
+	}
+
+	mockRDB.ExpectGet("prefix::TCP_PORT_OWNER::30000").SetVal("key1")
+	mockRDB.ExpectGet("prefix::TCP_PORT_OWNER::30001").RedisNil()
+	mockRDB.ExpectGet("prefix::TCP_PORT_OWNER::30002").SetErr(assert.AnError)
+
+	owner, err := r.PortOwner(context.Background(), 30000)
+	require.NoError(t, err)
+	assert.Equal(t, "key1", owner)
+
+	owner, err = r.PortOwner(context.Background(), 30001)
+	require.NoError(t, err)
+	assert.Empty(t, owner)
+
+	_, err = r.PortOwner(context.Background(), 30002)
+	assert.ErrorIs(t, err, assert.AnError)
+}
+
+func TestRepo_GetToken(t *testing.T) {
+	tests := []struct {
+		want      *token.Token
+		wantErr   error
+		mockSetup func(m redismock.ClientMock)
+		name      string
+	}{
+		{
+			name: "token with type",
+			mockSetup: func(m redismock.ClientMock) {
+				m.ExpectPTTL("prefix::API_KEY::key1").SetVal(time.Hour)
+				m.ExpectGet("prefix::TOKEN_TYPE::key1").SetVal("t")
+				m.ExpectGet("prefix::TCP_PORT::key1").RedisNil()
+				m.ExpectGet("prefix::IP_FILTER::key1").RedisNil()
+			},
+			want: &token.Token{ID: "key1", Type: token.TokenTypeTCP, TTL: time.Hour},
+		},
+		{
+			name: "token with reserved port",
+			mockSetup: func(m redismock.ClientMock) {
+				m.ExpectPTTL("prefix::API_KEY::key1").SetVal(time.Hour)
+				m.ExpectGet("prefix::TOKEN_TYPE::key1").SetVal("t")
+				m.ExpectGet("prefix::TCP_PORT::key1").SetVal("30000")
+				m.ExpectGet("prefix::IP_FILTER::key1").RedisNil()
+			},
+			want: &token.Token{ID: "key1", Type: token.TokenTypeTCP, TTL: time.Hour, Port: 30000},
+		},
+		{
+			name: "legacy token without type or expiration",
+			mockSetup: func(m redismock.ClientMock) {
+				m.ExpectPTTL("prefix::API_KEY::key1").SetVal(-1)
+				m.ExpectGet("prefix::TOKEN_TYPE::key1").RedisNil()
+				m.ExpectGet("prefix::TCP_PORT::key1").RedisNil()
+				m.ExpectGet("prefix::IP_FILTER::key1").RedisNil()
+			},
+			want: &token.Token{ID: "key1"},
+		},
+		{
+			name: "token not found",
+			mockSetup: func(m redismock.ClientMock) {
+				m.ExpectPTTL("prefix::API_KEY::key1").SetVal(-2)
+			},
+			wantErr: core.ErrTokenNotFound,
+		},
+		{
+			name: "redis error",
+			mockSetup: func(m redismock.ClientMock) {
+				m.ExpectPTTL("prefix::API_KEY::key1").SetVal(time.Hour)
+				m.ExpectGet("prefix::TOKEN_TYPE::key1").SetErr(assert.AnError)
+			},
+			wantErr: assert.AnError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rdb, mockRDB := redismock.NewClientMock()
+			tt.mockSetup(mockRDB)
+
+			r := &Repo{
+				db:        rdb,
+				keyPrefix: "prefix::",
+			}
+
+			got, err := r.GetToken(context.Background(), "key1")
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRepo_ListTokens(t *testing.T) {
+	rdb, mockRDB := redismock.NewClientMock()
+
+	r := &Repo{
+		db:        rdb,
+		keyPrefix: "prefix::",
+	}
+
+	mockRDB.ExpectScan(0, "prefix::API_KEY::*", scanBatchSize).SetVal([]string{"prefix::API_KEY::b", "prefix::API_KEY::a"}, 7)
+	mockRDB.ExpectScan(7, "prefix::API_KEY::*", scanBatchSize).SetVal([]string{"prefix::API_KEY::a", "prefix::API_KEY::c"}, 0)
+	mockRDB.ExpectPTTL("prefix::API_KEY::a").SetVal(time.Hour)
+	mockRDB.ExpectGet("prefix::TOKEN_TYPE::a").SetVal("w")
+	mockRDB.ExpectGet("prefix::TCP_PORT::a").RedisNil()
+	mockRDB.ExpectGet("prefix::IP_FILTER::a").RedisNil()
+	mockRDB.ExpectPTTL("prefix::API_KEY::b").SetVal(-2)
+	mockRDB.ExpectPTTL("prefix::API_KEY::c").SetVal(time.Minute)
+	mockRDB.ExpectGet("prefix::TOKEN_TYPE::c").SetVal("t")
+	mockRDB.ExpectGet("prefix::TCP_PORT::c").RedisNil()
+	mockRDB.ExpectGet("prefix::IP_FILTER::c").RedisNil()
+
+	got, err := r.ListTokens(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []*token.Token{
+		{ID: "a", Type: token.TokenTypeWeb, TTL: time.Hour},
+		{ID: "c", Type: token.TokenTypeTCP, TTL: time.Minute},
+	}, got)
+}
+
+func TestRepo_UpdateTokenTTL(t *testing.T) {
+	tests := []struct {
+		wantErr   error
+		mockSetup func(m redismock.ClientMock)
+		name      string
+	}{
+		{
+			name: "token with domains",
+			mockSetup: func(m redismock.ClientMock) {
+				m.ExpectExpire("prefix::API_KEY::key1", time.Hour).SetVal(true)
+				m.ExpectSMembers("prefix::DOMAINS::key1").SetVal([]string{"app.example.com", "other.example.com"})
+				m.ExpectGet("prefix::DOMAIN::app.example.com").SetVal(domainJSON(t, "app.example.com", "key1"))
+				// Domains that another token verified keep their own expiration.
+				m.ExpectGet("prefix::DOMAIN::other.example.com").SetVal(domainJSON(t, "other.example.com", "key2"))
+				m.ExpectGet("prefix::TCP_PORT::key1").RedisNil()
+				m.ExpectExpire("prefix::TOKEN_TYPE::key1", time.Hour).SetVal(true)
+				m.ExpectExpire("prefix::IP_FILTER::key1", time.Hour).SetVal(false)
+				m.ExpectExpire("prefix::OFFLINE_PAGE::key1", time.Hour).SetVal(false)
+				m.ExpectExpire("prefix::DOMAINS::key1", time.Hour).SetVal(true)
+				m.ExpectExpire("prefix::DOMAIN_CLAIM::key1::app.example.com", time.Hour).SetVal(true)
+				m.ExpectExpire("prefix::DOMAIN::app.example.com", time.Hour).SetVal(true)
+				m.ExpectExpire("prefix::DOMAIN_CLAIM::key1::other.example.com", time.Hour).SetVal(true)
+			},
+		},
+		{
+			name: "token not found",
+			mockSetup: func(m redismock.ClientMock) {
+				m.ExpectExpire("prefix::API_KEY::key1", time.Hour).SetVal(false)
+			},
+			wantErr: core.ErrTokenNotFound,
+		},
+		{
+			name: "token with reserved port",
+			mockSetup: func(m redismock.ClientMock) {
+				m.ExpectExpire("prefix::API_KEY::key1", time.Hour).SetVal(true)
+				m.ExpectSMembers("prefix::DOMAINS::key1").SetVal([]string{})
+				m.ExpectGet("prefix::TCP_PORT::key1").SetVal("30000")
+				m.ExpectExpire("prefix::TOKEN_TYPE::key1", time.Hour).SetVal(true)
+				m.ExpectExpire("prefix::IP_FILTER::key1", time.Hour).SetVal(false)
+				m.ExpectExpire("prefix::OFFLINE_PAGE::key1", time.Hour).SetVal(false)
+				m.ExpectExpire("prefix::TCP_PORT::key1", time.Hour).SetVal(true)
+				m.ExpectExpire("prefix::TCP_PORT_OWNER::30000", time.Hour).SetVal(true)
+			},
+		},
+		{
+			name: "redis error",
+			mockSetup: func(m redismock.ClientMock) {
+				m.ExpectExpire("prefix::API_KEY::key1", time.Hour).SetErr(assert.AnError)
+			},
+			wantErr: assert.AnError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rdb, mockRDB := redismock.NewClientMock()
+			tt.mockSetup(mockRDB)
+
+			r := &Repo{
+				db:        rdb,
+				keyPrefix: "prefix::",
+			}
+
+			err := r.UpdateTokenTTL(context.Background(), "key1", time.Hour)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.NoError(t, mockRDB.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRepo_IPFilter(t *testing.T) {
+	f, err := ipfilter.New([]string{"192.168.0.0/16"}, nil)
+	require.NoError(t, err)
+
+	data, err := json.Marshal(f)
+	require.NoError(t, err)
+
+	tests := []struct {
+		want      *ipfilter.Filter
+		wantErr   error
+		mockSetup func(m redismock.ClientMock)
+		name      string
+	}{
+		{
+			name: "filter exists",
+			mockSetup: func(m redismock.ClientMock) {
+				m.ExpectGet("prefix::IP_FILTER::key1").SetVal(string(data))
+			},
+			want: f,
+		},
+		{
+			name: "no filter",
+			mockSetup: func(m redismock.ClientMock) {
+				m.ExpectGet("prefix::IP_FILTER::key1").RedisNil()
+			},
+			want: nil,
+		},
+		{
+			name: "redis error",
+			mockSetup: func(m redismock.ClientMock) {
+				m.ExpectGet("prefix::IP_FILTER::key1").SetErr(assert.AnError)
+			},
+			wantErr: assert.AnError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rdb, mockRDB := redismock.NewClientMock()
+			tt.mockSetup(mockRDB)
+
+			r := &Repo{
+				db:        rdb,
+				keyPrefix: "prefix::",
+			}
+
+			got, err := r.IPFilter(context.Background(), "key1")
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRepo_AddDomain(t *testing.T) {
+	d := &domain.Domain{Name: "app.example.com", KeyID: "key1", Challenge: "challenge"}
+
+	data, err := json.Marshal(d)
+	require.NoError(t, err)
+
+	tests := []struct {
+		wantErr   error
+		mockSetup func(m redismock.ClientMock)
+		name      string
+	}{
+		{
+			name: "token with TTL",
+			mockSetup: func(m redismock.ClientMock) {
+				m.ExpectPTTL("prefix::API_KEY::key1").SetVal(time.Hour)
+				m.ExpectExists("prefix::DOMAIN::app.example.com").SetVal(0)
+				m.ExpectSetNX("prefix::DOMAIN_CLAIM::key1::app.example.com", data, time.Hour).SetVal(true)
+				m.ExpectSAdd("prefix::DOMAINS::key1", "app.example.com").SetVal(1)
+				m.ExpectExpire("prefix::DOMAINS::key1", time.Hour).SetVal(true)
+			},
+		},
+		{
+			name: "token without TTL",
+			mockSetup: func(m redismock.ClientMock) {
+				m.ExpectPTTL("prefix::API_KEY::key1").SetVal(-1)
+				m.ExpectExists("prefix::DOMAIN::app.example.com").SetVal(0)
+				m.ExpectSetNX("prefix::DOMAIN_CLAIM::key1::app.example.com", data, 0).SetVal(true)
+				m.ExpectSAdd("prefix::DOMAINS::key1", "app.example.com").SetVal(1)
+			},
+		},
+		{
+			name: "verified by another token",
+			mockSetup: func(m redismock.ClientMock) {
+				m.ExpectPTTL("prefix::API_KEY::key1").SetVal(time.Hour)
+				m.ExpectExists("prefix::DOMAIN::app.example.com").SetVal(1)
+			},
+			wantErr: core.ErrDuplicateDomain,
+		},
+		{
+			name: "token not found",
+			mockSetup: func(m redismock.ClientMock) {
+				m.ExpectPTTL("prefix::API_KEY::key1").SetVal(-2)
+			},
+			wantErr: core.ErrTokenNotFound,
+		},
+		{
+			name: "already claimed by the token",
+			mockSetup: func(m redismock.ClientMock) {
+				m.ExpectPTTL("prefix::API_KEY::key1").SetVal(time.Hour)
+				m.ExpectExists("prefix::DOMAIN::app.example.com").SetVal(0)
+				m.ExpectSetNX("prefix::DOMAIN_CLAIM::key1::app.example.com", data, time.Hour).SetVal(false)
+			},
+			wantErr: core.ErrDuplicateDomain,
+		},
+		{
+			name: "redis error",
+			mockSetup: func(m redismock.ClientMock) {
+				m.ExpectPTTL("prefix::API_KEY::key1").SetErr(assert.AnError)
+			},
+			wantErr: assert.AnError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rdb, mockRDB := redismock.NewClientMock()
+			tt.mockSetup(mockRDB)
+
+			r := &Repo{
+				db:        rdb,
+				keyPrefix: "prefix::",
+			}
+
+			err := r.AddDomain(context.Background(), d)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.NoError(t, mockRDB.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRepo_SetOfflinePage(t *testing.T) {
+	tests := []struct {
+		wantErr   error
+		mockSetup func(m redismock.ClientMock)
+		name      string
+	}{
+		{
+			name: "token with TTL",
+			mockSetup: func(m redismock.ClientMock) {
+				m.ExpectPTTL("prefix::API_KEY::key1").SetVal(time.Hour)
+				m.ExpectSet("prefix::OFFLINE_PAGE::key1", "page", time.Hour).SetVal("OK")
+			},
+		},
+		{
+			name: "token without TTL",
+			mockSetup: func(m redismock.ClientMock) {
+				m.ExpectPTTL("prefix::API_KEY::key1").SetVal(-1)
+				m.ExpectSet("prefix::OFFLINE_PAGE::key1", "page", 0).SetVal("OK")
+			},
+		},
+		{
+			name: "token not found",
+			mockSetup: func(m redismock.ClientMock) {
+				m.ExpectPTTL("prefix::API_KEY::key1").SetVal(-2)
+			},
+			wantErr: core.ErrTokenNotFound,
+		},
+		{
+			name: "redis error",
+			mockSetup: func(m redismock.ClientMock) {
+				m.ExpectPTTL("prefix::API_KEY::key1").SetVal(time.Hour)
+				m.ExpectSet("prefix::OFFLINE_PAGE::key1", "page", time.Hour).SetErr(assert.AnError)
+			},
+			wantErr: assert.AnError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rdb, mockRDB := redismock.NewClientMock()
+			tt.mockSetup(mockRDB)
+
+			r := &Repo{
+				db:        rdb,
+				keyPrefix: "prefix::",
+			}
+
+			err := r.SetOfflinePage(context.Background(), "key1", "page")
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.NoError(t, mockRDB.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRepo_OfflinePage(t *testing.T) {
+	rdb, mockRDB := redismock.NewClientMock()
+
+	r := &Repo{
+		db:        rdb,
+		keyPrefix: "prefix::",
+	}
+
+	mockRDB.ExpectGet("prefix::OFFLINE_PAGE::key1").SetVal("page")
+
+	page, err := r.OfflinePage(context.Background(), "key1")
+	require.NoError(t, err)
+	assert.Equal(t, "page", page)
+
+	mockRDB.ExpectGet("prefix::OFFLINE_PAGE::key1").RedisNil()
+
+	page, err = r.OfflinePage(context.Background(), "key1")
+	require.NoError(t, err)
+	assert.Empty(t, page)
+
+	mockRDB.ExpectGet("prefix::OFFLINE_PAGE::key1").SetErr(assert.AnError)
+
+	_, err = r.OfflinePage(context.Background(), "key1")
+	assert.ErrorIs(t, err, assert.AnError)
+
+	mockRDB.ExpectDel("prefix::OFFLINE_PAGE::key1").SetVal(1)
+
+	require.NoError(t, r.RemoveOfflinePage(context.Background(), "key1"))
+	assert.NoError(t, mockRDB.ExpectationsWereMet())
+}
+
+func TestRepo_GetDomain(t *testing.T) {
+	d := &domain.Domain{Name: "app.example.com", KeyID: "key1", Challenge: "challenge", Verified: true}
+
+	data, err := json.Marshal(d)
+	require.NoError(t, err)
+
+	rdb, mockRDB := redismock.NewClientMock()
+	r := &Repo{
+		db:        rdb,
+		keyPrefix: "prefix::",
+	}
+
+	mockRDB.ExpectGet("prefix::DOMAIN::app.example.com").SetVal(string(data))
+
+	got, err := r.GetDomain(context.Background(), "app.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, d.KeyID, got.KeyID)
+	assert.True(t, got.Verified)
+
+	mockRDB.ExpectGet("prefix::DOMAIN::other.example.com").RedisNil()
+
+	_, err = r.GetDomain(context.Background(), "other.example.com")
+	assert.ErrorIs(t, err, core.ErrDomainNotFound)
+
+	mockRDB.ExpectGet("prefix::DOMAIN_CLAIM::key1::app.example.com").SetVal(string(data))
+
+	got, err = r.TokenDomain(context.Background(), "key1", "app.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, d.Challenge, got.Challenge)
+
+	mockRDB.ExpectGet("prefix::DOMAIN_CLAIM::key2::app.example.com").RedisNil()
+
+	_, err = r.TokenDomain(context.Background(), "key2", "app.example.com")
+	assert.ErrorIs(t, err, core.ErrDomainNotFound)
+}
+
+func TestRepo_UpdateDomain(t *testing.T) {
+	verified := &domain.Domain{Name: "app.example.com", KeyID: "key1", Challenge: "challenge", Verified: true}
+
+	data, err := json.Marshal(verified)
+	require.NoError(t, err)
+
+	tests := []struct {
+		wantErr   error
+		domain    *domain.Domain
+		mockSetup func(m redismock.ClientMock)
+		name      string
+	}{
+		{
+			name:   "verified domain",
+			domain: verified,
+			mockSetup: func(m redismock.ClientMock) {
+				m.ExpectPTTL("prefix::API_KEY::key1").SetVal(time.Hour)
+				m.ExpectSetNX("prefix::DOMAIN::app.example.com", data, time.Hour).SetVal(true)
+				m.ExpectSet("prefix::DOMAIN_CLAIM::key1::app.example.com", data, -1).SetVal("OK")
+			},
+		},
+		{
+			name:   "already reserved by the token",
+			domain: verified,
+			mockSetup: func(m redismock.ClientMock) {
+				m.ExpectPTTL("prefix::API_KEY::key1").SetVal(time.Hour)
+				m.ExpectSetNX("prefix::DOMAIN::app.example.com", data, time.Hour).SetVal(false)
+				m.ExpectGet("prefix::DOMAIN::app.example.com").SetVal(string(data))
+				m.ExpectSet("prefix::DOMAIN_CLAIM::key1::app.example.com", data, -1).SetVal("OK")
+			},
+		},
+		{
+			name:   "verified by another token first",
+			domain: verified,
+			mockSetup: func(m redismock.ClientMock) {
+				m.ExpectPTTL("prefix::API_KEY::key1").SetVal(time.Hour)
+				m.ExpectSetNX("prefix::DOMAIN::app.example.com", data, time.Hour).SetVal(false)
+				m.ExpectGet("prefix::DOMAIN::app.example.com").SetVal(domainJSON(t, "app.example.com", "key2"))
+			},
+			wantErr: core.ErrDuplicateDomain,
+		},
+		{
+			name:   "token not found",
+			domain: verified,
+			mockSetup: func(m redismock.ClientMock) {
+				m.ExpectPTTL("prefix::API_KEY::key1").SetVal(-2)
+			},
+			wantErr: core.ErrTokenNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rdb, mockRDB := redismock.NewClientMock()
+			tt.mockSetup(mockRDB)
+
+			r := &Repo{
+				db:        rdb,
+				keyPrefix: "prefix::",
+			}
+
+			err := r.UpdateDomain(context.Background(), tt.domain)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.NoError(t, mockRDB.ExpectationsWereMet())
+		})
+	}
+}
+
+// TestRepo_DomainClaims checks that a token claiming a domain it does not own can not keep its owner from
+// claiming and verifying the domain.
+func TestRepo_DomainClaims(t *testing.T) {
+	squatter := &domain.Domain{Name: "app.example.com", KeyID: "squatter", Challenge: "guess"}
+	owner := &domain.Domain{Name: "app.example.com", KeyID: "owner", Challenge: "challenge"}
+
+	squatterData, err := json.Marshal(squatter)
+	require.NoError(t, err)
+
+	ownerData, err := json.Marshal(owner)
+	require.NoError(t, err)
+
+	rdb, mockRDB := redismock.NewClientMock()
+	r := &Repo{
+		db:        rdb,
+		keyPrefix: "prefix::",
+	}
+
+	mockRDB.ExpectPTTL("prefix::API_KEY::squatter").SetVal(time.Hour)
+	mockRDB.ExpectExists("prefix::DOMAIN::app.example.com").SetVal(0)
+	mockRDB.ExpectSetNX("prefix::DOMAIN_CLAIM::squatter::app.example.com", squatterData, time.Hour).SetVal(true)
+	mockRDB.ExpectSAdd("prefix::DOMAINS::squatter", "app.example.com").SetVal(1)
+	mockRDB.ExpectExpire("prefix::DOMAINS::squatter", time.Hour).SetVal(true)
+
+	require.NoError(t, r.AddDomain(context.Background(), squatter))
+
+	// The unverified claim of the squatter does not reserve the name, so the owner claims it as well.
+	mockRDB.ExpectPTTL("prefix::API_KEY::owner").SetVal(time.Hour)
+	mockRDB.ExpectExists("prefix::DOMAIN::app.example.com").SetVal(0)
+	mockRDB.ExpectSetNX("prefix::DOMAIN_CLAIM::owner::app.example.com", ownerData, time.Hour).SetVal(true)
+	mockRDB.ExpectSAdd("prefix::DOMAINS::owner", "app.example.com").SetVal(1)
+	mockRDB.ExpectExpire("prefix::DOMAINS::owner", time.Hour).SetVal(true)
+
+	require.NoError(t, r.AddDomain(context.Background(), owner))
+
+	owner.Verified = true
+
+	ownerData, err = json.Marshal(owner)
+	require.NoError(t, err)
+
+	mockRDB.ExpectPTTL("prefix::API_KEY::owner").SetVal(time.Hour)
+	mockRDB.ExpectSetNX("prefix::DOMAIN::app.example.com", ownerData, time.Hour).SetVal(true)
+	mockRDB.ExpectSet("prefix::DOMAIN_CLAIM::owner::app.example.com", ownerData, -1).SetVal("OK")
+
+	require.NoError(t, r.UpdateDomain(context.Background(), owner))
+
+	// Once verified, the name can not be claimed any more.
+	mockRDB.ExpectPTTL("prefix::API_KEY::squatter").SetVal(time.Hour)
+	mockRDB.ExpectExists("prefix::DOMAIN::app.example.com").SetVal(1)
+
+	assert.ErrorIs(t, r.AddDomain(context.Background(), squatter), core.ErrDuplicateDomain)
+	assert.NoError(t, mockRDB.ExpectationsWereMet())
+}
+
+func TestRepo_ListDomains(t *testing.T) {
+	rdb, mockRDB := redismock.NewClientMock()
+	r := &Repo{
+		db:        rdb,
+		keyPrefix: "prefix::",
+	}
+
+	mockRDB.ExpectSMembers("prefix::DOMAINS::key1").SetVal([]string{"c.example.com", "b.example.com", "a.example.com"})
+	mockRDB.ExpectGet("prefix::DOMAIN_CLAIM::key1::a.example.com").SetVal(domainJSON(t, "a.example.com", "key1"))
+	mockRDB.ExpectGet("prefix::DOMAIN_CLAIM::key1::b.example.com").SetVal(domainJSON(t, "b.example.com", "key1"))
+	mockRDB.ExpectGet("prefix::DOMAIN_CLAIM::key1::c.example.com").RedisNil()
+
+	got, err := r.ListDomains(context.Background(), "key1")
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	assert.Equal(t, "a.example.com", got[0].Name)
+	assert.Equal(t, "b.example.com", got[1].Name)
+}
+
+func TestRepo_RemoveDomain(t *testing.T) {
+	tests := []struct {
+		wantErr   error
+		mockSetup func(m redismock.ClientMock)
+		name      string
+		keyID     string
+	}{
+		{
+			name:  "verified domain",
+			keyID: "key1",
+			mockSetup: func(m redismock.ClientMock) {
+				m.ExpectGet("prefix::DOMAIN_CLAIM::key1::app.example.com").SetVal(domainJSON(t, "app.example.com", "key1"))
+				m.ExpectGet("prefix::DOMAIN::app.example.com").SetVal(domainJSON(t, "app.example.com", "key1"))
+				m.ExpectDel("prefix::DOMAIN_CLAIM::key1::app.example.com", "prefix::DOMAIN::app.example.com").SetVal(2)
+				m.ExpectSRem("prefix::DOMAINS::key1", "app.example.com").SetVal(1)
+			},
+		},
+		{
+			name:  "domain verified by another token",
+			keyID: "key2",
+			mockSetup: func(m redismock.ClientMock) {
+				m.ExpectGet("prefix::DOMAIN_CLAIM::key2::app.example.com").SetVal(domainJSON(t, "app.example.com", "key2"))
+				m.ExpectGet("prefix::DOMAIN::app.example.com").SetVal(domainJSON(t, "app.example.com", "key1"))
+				m.ExpectDel("prefix::DOMAIN_CLAIM::key2::app.example.com").SetVal(1)
+				m.ExpectSRem("prefix::DOMAINS::key2", "app.example.com").SetVal(1)
+			},
+		},
+		{
+			name:  "unknown domain",
+			keyID: "key1",
+			mockSetup: func(m redismock.ClientMock) {
+				m.ExpectGet("prefix::DOMAIN_CLAIM::key1::app.example.com").RedisNil()
+			},
+			wantErr: core.ErrDomainNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rdb, mockRDB := redismock.NewClientMock()
+			tt.mockSetup(mockRDB)
+
+			r := &Repo{
+				db:        rdb,
+				keyPrefix: "prefix::",
+			}
+
+			err := r.RemoveDomain(context.Background(), tt.keyID, "app.example.com")
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.NoError(t, mockRDB.ExpectationsWereMet())
+		})
+	}
+}
+
+// domainJSON returns the encoded domain name of the token identified by keyID.
+func domainJSON(t *testing.T, name, keyID string) string {
+	t.Helper()
+
+	data, err := json.Marshal(&domain.Domain{Name: name, KeyID: keyID})
+	require.NoError(t, err)
+
+	return string(data)
+}
+
 func TestRepo_Close(t *testing.T) {
 	rdb, _ := redismock.NewClientMock()
 	r := &Repo{
@@ -272,7 +1096,9 @@ func TestRepo_DeleteToken(t *testing.T) {
 			name:    "successfully delete token",
 			tokenID: "token123",
 			mockSetup: func(m redismock.ClientMock) {
-				m.ExpectDel("prefix::API_KEY::token123").SetVal(1)
+				m.ExpectSMembers("prefix::DOMAINS::token123").SetVal([]string{})
+				m.ExpectGet("prefix::TCP_PORT::token123").RedisNil()
+				m.ExpectDel("prefix::API_KEY::token123", "prefix::IP_FILTER::token123", "prefix::TOKEN_TYPE::token123", "prefix::OFFLINE_PAGE::token123").SetVal(1)
 			},
 			wantErr: nil,
 		},
@@ -280,15 +1106,50 @@ func TestRepo_DeleteToken(t *testing.T) {
 			name:    "token does not exist",
 			tokenID: "nonexistentToken",
 			mockSetup: func(m redismock.ClientMock) {
-				m.ExpectDel("prefix::API_KEY::nonexistentToken").SetVal(0)
+				m.ExpectSMembers("prefix::DOMAINS::nonexistentToken").SetVal([]string{})
+				m.ExpectGet("prefix::TCP_PORT::nonexistentToken").RedisNil()
+				m.ExpectDel("prefix::API_KEY::nonexistentToken", "prefix::IP_FILTER::nonexistentToken", "prefix::TOKEN_TYPE::nonexistentToken", "prefix::OFFLINE_PAGE::nonexistentToken").SetVal(0)
 			},
 			wantErr: core.ErrTokenNotFound,
 		},
+		{
+			name:    "delete token with domains",
+			tokenID: "token123",
+			mockSetup: func(m redismock.ClientMock) {
+				m.ExpectSMembers("prefix::DOMAINS::token123").SetVal([]string{"app.example.com", "other.example.com"})
+				m.ExpectGet("prefix::DOMAIN::app.example.com").SetVal(domainJSON(t, "app.example.com", "token123"))
+				// The name of a domain that another token verified is not released.
+				m.ExpectGet("prefix::DOMAIN::other.example.com").SetVal(domainJSON(t, "other.example.com", "token456"))
+				m.ExpectGet("prefix::TCP_PORT::token123").RedisNil()
+				m.ExpectDel("prefix::API_KEY::token123", "prefix::IP_FILTER::token123", "prefix::TOKEN_TYPE::token123", "prefix::OFFLINE_PAGE::token123",
+					"prefix::DOMAINS::token123", "prefix::DOMAIN_CLAIM::token123::app.example.com", "prefix::DOMAIN::app.example.com",
+					"prefix::DOMAIN_CLAIM::token123::other.example.com").SetVal(3)
+			},
+		},
+		{
+			name:    "delete token with reserved port",
+			tokenID: "token123",
+			mockSetup: func(m redismock.ClientMock) {
+				m.ExpectSMembers("prefix::DOMAINS::token123").SetVal([]string{})
+				m.ExpectGet("prefix::TCP_PORT::token123").SetVal("30000")
+				m.ExpectDel("prefix::API_KEY::token123", "prefix::IP_FILTER::token123", "prefix::TOKEN_TYPE::token123", "prefix::OFFLINE_PAGE::token123", "prefix::TCP_PORT::token123", "prefix::TCP_PORT_OWNER::30000").SetVal(5)
+			},
+		},
+		{
+			name:    "redis error listing domains",
+			tokenID: "tokenWithError",
+			mockSetup: func(m redismock.ClientMock) {
+				m.ExpectSMembers("prefix::DOMAINS::tokenWithError").SetErr(assert.AnError)
+			},
+			wantErr: assert.AnError,
+		},
 		{
 			name:    "redis error during deletion",
 			tokenID: "tokenWithError",
 			mockSetup: func(m redismock.ClientMock) {
-				m.ExpectDel("prefix::API_KEY::tokenWithError").SetErr(assert.AnError)
+				m.ExpectSMembers("prefix::DOMAINS::tokenWithError").SetVal([]string{})
+				m.ExpectGet("prefix::TCP_PORT::tokenWithError").RedisNil()
+				m.ExpectDel("prefix::API_KEY::tokenWithError", "prefix::IP_FILTER::tokenWithError", "prefix::TOKEN_TYPE::tokenWithError", "prefix::OFFLINE_PAGE::tokenWithError").SetErr(assert.AnError)
 			},
 			wantErr: assert.AnError,
 		},
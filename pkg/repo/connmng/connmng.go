@@ -9,86 +9,271 @@ import (
 	"github.com/google/uuid"
 	"github.com/ksysoev/make-it-public/pkg/core"
 	"github.com/ksysoev/make-it-public/pkg/core/conn"
+	"github.com/ksysoev/make-it-public/pkg/core/conn/meta"
+	"github.com/ksysoev/make-it-public/pkg/metrics"
 )
 
+// Strategy defines how ConnManager picks a control connection when several
+// clients are registered for the same keyID.
+type Strategy string
+
+const (
+	// StrategyRoundRobin cycles through the registered control connections in order.
+	StrategyRoundRobin Strategy = "round_robin"
+	// StrategyLeastRequests picks the control connection with the fewest outstanding requests.
+	StrategyLeastRequests Strategy = "least_requests"
+)
+
+// Config holds configuration for the connection manager.
+type Config struct {
+	Strategy Strategy `mapstructure:"strategy"`
+}
+
+// Validate checks that the Config is valid. An empty strategy is accepted and
+// resolves to StrategyRoundRobin.
+func (c *Config) Validate() error {
+	switch c.Strategy {
+	case "", StrategyRoundRobin, StrategyLeastRequests:
+		return nil
+	default:
+		return fmt.Errorf("unknown load balancing strategy %q", c.Strategy)
+	}
+}
+
+// Option is a functional option for configuring ConnManager.
+type Option func(*ConnManager)
+
+// WithStrategy sets the load balancing strategy used by RequestConnection.
+// An empty strategy leaves the default round-robin strategy in place.
+func WithStrategy(strategy Strategy) Option {
+	return func(cm *ConnManager) {
+		if strategy != "" {
+			cm.strategy = strategy
+		}
+	}
+}
+
+// poolEntry tracks a single control connection and the number of requests
+// that were sent over it and have not been resolved or canceled yet.
+type poolEntry struct {
+	conn    core.ControlConn
+	pending int
+}
+
+// connPool holds every control connection registered for a single keyID.
+type connPool struct {
+	entries []*poolEntry
+	next    int
+}
+
 type connRequest struct {
-	ctx context.Context
-	req conn.Request
+	ctx   context.Context
+	req   conn.Request
+	entry *poolEntry
 }
 
 type ConnManager struct {
-	conns    map[string]core.ControlConn
+	conns    map[string]*connPool
 	requests map[uuid.UUID]*connRequest
+	strategy Strategy
 	mu       sync.RWMutex
 }
 
 // New creates and returns a new instance of ConnManager.
-// It does not take any parameters.
+// It accepts optional functional options to customise its behaviour; by default round-robin balancing is used.
 // It returns a pointer to a ConnManager with initialized internal maps for conn and requests.
-func New() *ConnManager {
-	return &ConnManager{
-		conns:    make(map[string]core.ControlConn),
+func New(opts ...Option) *ConnManager {
+	cm := &ConnManager{
+		conns:    make(map[string]*connPool),
 		requests: make(map[uuid.UUID]*connRequest),
+		strategy: StrategyRoundRobin,
 	}
+
+	for _, opt := range opts {
+		opt(cm)
+	}
+
+	return cm
 }
 
-// AddConnection adds a server connection to the user's connection pool.
-// It takes a user parameter of type string and a controlConn parameter of type core.ControlConn.
-// It does not return any value and ensures thread-safe access.
-func (cm *ConnManager) AddConnection(keyID string, controlConn core.ControlConn) {
+// AddConnection adds a server connection to the keyID's connection pool.
+// It takes a keyID parameter of type string and a controlConn parameter of type core.ControlConn.
+// Previously registered connections for the same keyID are kept, so several clients can serve one keyID,
+// as long as they all require the same tunnel credentials.
+// It returns core.ErrAuthMismatch if the credentials of controlConn differ from those of the connected clients.
+func (cm *ConnManager) AddConnection(keyID string, controlConn core.ControlConn) error {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 
-	if oldConn, ok := cm.conns[keyID]; ok {
-		_ = oldConn.Close()
+	pool, ok := cm.conns[keyID]
+	if !ok {
+		pool = &connPool{}
+		cm.conns[keyID] = pool
 	}
 
-	cm.conns[keyID] = controlConn
+	if len(pool.entries) > 0 && !controlConn.Auth().Matches(pool.entries[0].conn.Auth()) {
+		return fmt.Errorf("refused control connection for keyID %s: %w", keyID, core.ErrAuthMismatch)
+	}
+
+	pool.entries = append(pool.entries, &poolEntry{conn: controlConn})
+
+	return nil
 }
 
-// RemoveConnection removes a connection associated with a specific user by its unique ID.
-// It takes user of type string and id of type uuid.UUID.
-// It does not return any value but safely does nothing if the user or connection ID does not exist.
+// RemoveConnection removes a connection associated with a specific keyID by its unique ID.
+// It takes keyID of type string and id of type uuid.UUID.
+// The remaining connections registered for the keyID continue to serve requests.
+// It does not return any value but safely does nothing if the keyID or connection ID does not exist.
 func (cm *ConnManager) RemoveConnection(keyID string, id uuid.UUID) {
 	cm.mu.Lock()
 
-	if revConn, ok := cm.conns[keyID]; ok && revConn.ID() == id {
-		delete(cm.conns, keyID)
+	pool, ok := cm.conns[keyID]
+	if !ok {
+		cm.mu.Unlock()
+		return
+	}
+
+	var removed core.ControlConn
+
+	for i, e := range pool.entries {
+		if e.conn.ID() == id {
+			removed = e.conn
+			pool.entries = append(pool.entries[:i], pool.entries[i+1:]...)
 
-		defer func() {
-			_ = revConn.Close()
-		}()
+			break
+		}
+	}
+
+	if len(pool.entries) == 0 {
+		delete(cm.conns, keyID)
+	} else if pool.next >= len(pool.entries) {
+		pool.next = 0
 	}
 
 	cm.mu.Unlock()
+
+	if removed != nil {
+		_ = removed.Close()
+	}
 }
 
-// RequestConnection attempts to establish a new connection for the specified user.
-// It takes ctx of type context.Context and userID of type string.
-// It returns a channel of type net.Conn to receive the connection or an error if the operation fails.
-// It returns an error if no connections are available for the user, the user does not exist, or a command fails to send.
+// RequestConnection attempts to establish a new connection for the specified keyID.
+// It takes ctx of type context.Context and keyID of type string.
+// A control connection is picked according to the configured strategy; if sending the connect command
+// over it fails, the remaining connections in the pool are tried in turn.
+// The connect command is sent without holding the lock, so a slow control connection does not stall the others.
+// It returns the pending connection request or an error if the operation fails.
+// It returns an error if no connections are available for the keyID, or the command fails to send on every connection.
 func (cm *ConnManager) RequestConnection(ctx context.Context, keyID string) (conn.Request, error) {
-	cm.mu.RLock()
-	defer cm.mu.RUnlock()
+	cm.mu.Lock()
 
-	revConn, ok := cm.conns[keyID]
-	if !ok {
+	pool, ok := cm.conns[keyID]
+	if !ok || len(pool.entries) == 0 {
+		cm.mu.Unlock()
 		return nil, core.ErrKeyIDNotFound
 	}
 
-	req, err := revConn.RequestConnection()
-	if err != nil {
-		return nil, fmt.Errorf("failed to send connect command: %w", err)
+	candidates := cm.candidates(pool)
+	req := cm.reserve(ctx, candidates[0])
+
+	cm.mu.Unlock()
+
+	var errs []error
+
+	for i, entry := range candidates {
+		if i > 0 {
+			cm.mu.Lock()
+			req = cm.reserve(ctx, entry)
+			cm.mu.Unlock()
+		}
+
+		err := entry.conn.RequestConnection(req)
+		if err == nil {
+			return req, nil
+		}
+
+		cm.rollback(req.ID())
+
+		errs = append(errs, err)
+	}
+
+	return nil, fmt.Errorf("failed to send connect command: %w", errors.Join(errs...))
+}
+
+// reserve creates a request on the control connection of entry, records it and counts it as pending on entry.
+// The request is recorded before the connect command is sent, as the client may fulfil it before the send returns.
+// It must be called with cm.mu held for writing.
+func (cm *ConnManager) reserve(ctx context.Context, entry *poolEntry) conn.Request {
+	req := entry.conn.NewRequest()
+
+	entry.pending++
+	metrics.PendingRequests.Inc()
+
+	cm.requests[req.ID()] = &connRequest{
+		ctx:   ctx,
+		req:   req,
+		entry: entry,
 	}
 
-	r := &connRequest{
-		ctx: ctx,
-		req: req,
+	return req
+}
+
+// rollback forgets a request whose connect command could not be sent and frees its pending slot,
+// unless the request has already been resolved or canceled.
+func (cm *ConnManager) rollback(id uuid.UUID) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	r, ok := cm.requests[id]
+	if !ok {
+		return
 	}
 
-	cm.requests[req.ID()] = r
+	r.release()
+	delete(cm.requests, id)
+}
+
+// TunnelAuth returns the credentials required by the tunnel of keyID.
+// Every client serving the keyID requires the same credentials, see AddConnection.
+// It returns false if no connections are registered for the keyID.
+func (cm *ConnManager) TunnelAuth(keyID string) (*meta.TunnelAuth, bool) {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	pool, ok := cm.conns[keyID]
+	if !ok || len(pool.entries) == 0 {
+		return nil, false
+	}
 
-	return req, nil
+	return pool.entries[0].conn.Auth(), true
+}
+
+// candidates returns the pool entries in the order they should be tried for the next request.
+// The first entry is chosen by the configured strategy, the rest follow in pool order as failover targets.
+// It must be called with cm.mu held for writing, as round-robin advances the pool cursor.
+func (cm *ConnManager) candidates(pool *connPool) []*poolEntry {
+	n := len(pool.entries)
+	start := 0
+
+	switch cm.strategy {
+	case StrategyLeastRequests:
+		for i, e := range pool.entries {
+			if e.pending < pool.entries[start].pending {
+				start = i
+			}
+		}
+	default:
+		start = pool.next % n
+		pool.next = (start + 1) % n
+	}
+
+	ordered := make([]*poolEntry, 0, n)
+
+	for i := range n {
+		ordered = append(ordered, pool.entries[(start+i)%n])
+	}
+
+	return ordered
 }
 
 // ResolveRequest resolves a pending connection request by sending the provided connection to the request's channel.
@@ -98,6 +283,11 @@ func (cm *ConnManager) ResolveRequest(id uuid.UUID, netConn conn.WithWriteCloser
 	cm.mu.Lock()
 	r, ok := cm.requests[id]
 	delete(cm.requests, id)
+
+	if ok {
+		r.release()
+	}
+
 	cm.mu.Unlock()
 
 	if !ok {
@@ -121,6 +311,7 @@ func (cm *ConnManager) CancelRequest(id uuid.UUID) {
 	}
 
 	r.req.Cancel()
+	r.release()
 	delete(cm.requests, id)
 }
 
@@ -135,13 +326,15 @@ func (cm *ConnManager) Close() error {
 
 	for id, r := range cm.requests {
 		r.req.Cancel()
+		r.release()
 		delete(cm.requests, id)
 	}
 
-	for _, userConn := range cm.conns {
-		err := userConn.Close()
-		if err != nil {
-			errs = append(errs, err)
+	for _, pool := range cm.conns {
+		for _, e := range pool.entries {
+			if err := e.conn.Close(); err != nil {
+				errs = append(errs, err)
+			}
 		}
 	}
 
@@ -151,3 +344,12 @@ func (cm *ConnManager) Close() error {
 
 	return nil
 }
+
+// release decrements the outstanding request counter of the control connection the request was sent over.
+// It must be called with the ConnManager mutex held.
+func (r *connRequest) release() {
+	if r.entry != nil && r.entry.pending > 0 {
+		r.entry.pending--
+		metrics.PendingRequests.Dec()
+	}
+}
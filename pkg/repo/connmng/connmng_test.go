@@ -9,6 +9,9 @@ import (
 	"github.com/google/uuid"
 	"github.com/ksysoev/make-it-public/pkg/core"
 	"github.com/ksysoev/make-it-public/pkg/core/conn"
+	"github.com/ksysoev/make-it-public/pkg/core/conn/meta"
+	"github.com/ksysoev/make-it-public/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -16,48 +19,50 @@ import (
 
 func TestConnManager_AddConnection(t *testing.T) {
 	cm := New()
-	mockConn := core.NewMockControlConn(t)
+	mockConn := newControlConn(t, nil)
 
-	mockConn.EXPECT().Close().Return(nil)
-
-	cm.AddConnection("key1", mockConn)
+	require.NoError(t, cm.AddConnection("key1", mockConn))
 
-	assert.NotNil(t, cm.conns["key1"])
-	assert.Equal(t, mockConn, cm.conns["key1"])
+	require.NotNil(t, cm.conns["key1"])
+	require.Len(t, cm.conns["key1"].entries, 1)
+	assert.Equal(t, mockConn, cm.conns["key1"].entries[0].conn)
 
-	// Overwrite connection
-	newConn := core.NewMockControlConn(t)
+	// A second client for the same keyID joins the pool instead of replacing the first one
+	newConn := newControlConn(t, nil)
 
-	cm.AddConnection("key1", newConn)
+	require.NoError(t, cm.AddConnection("key1", newConn))
 
-	assert.Equal(t, newConn, cm.conns["key1"])
+	require.Len(t, cm.conns["key1"].entries, 2)
+	assert.Equal(t, mockConn, cm.conns["key1"].entries[0].conn)
+	assert.Equal(t, newConn, cm.conns["key1"].entries[1].conn)
 }
 
 func TestConnManager_RemoveConnection(t *testing.T) {
 	cm := New()
-	mockConn := core.NewMockControlConn(t)
+	mockConn := newControlConn(t, nil)
 
 	connID := uuid.New()
 	mockConn.EXPECT().ID().Return(connID)
 	mockConn.EXPECT().Close().Return(nil)
 
-	cm.AddConnection("key1", mockConn)
+	require.NoError(t, cm.AddConnection("key1", mockConn))
 	cm.RemoveConnection("key1", connID)
 
 	assert.Nil(t, cm.conns["key1"])
 }
 
 func TestConnManager_RequestConnection(t *testing.T) {
-	mockConn := core.NewMockControlConn(t)
+	mockConn := newControlConn(t, nil)
 	mockReq := conn.NewMockRequest(t)
 	cm := New()
 
 	reqID := uuid.New()
 
-	mockConn.EXPECT().RequestConnection().Return(mockReq, nil)
+	mockConn.EXPECT().NewRequest().Return(mockReq)
+	mockConn.EXPECT().RequestConnection(mockReq).Return(nil)
 	mockReq.EXPECT().ID().Return(reqID)
 
-	cm.AddConnection("key1", mockConn)
+	require.NoError(t, cm.AddConnection("key1", mockConn))
 
 	req, err := cm.RequestConnection(context.Background(), "key1")
 
@@ -74,15 +79,66 @@ func TestConnManager_RequestConnection_NoConnection(t *testing.T) {
 }
 
 func TestConnManager_RequestConnection_Error(t *testing.T) {
-	mockConn := core.NewMockControlConn(t)
+	mockConn := newControlConn(t, nil)
+	mockReq := conn.NewMockRequest(t)
 	cm := New()
 
-	mockConn.EXPECT().RequestConnection().Return(nil, errors.New("connection error"))
-	cm.AddConnection("key1", mockConn)
+	mockReq.EXPECT().ID().Return(uuid.New())
+	mockConn.EXPECT().NewRequest().Return(mockReq)
+	mockConn.EXPECT().RequestConnection(mockReq).Return(errors.New("connection error"))
+	require.NoError(t, cm.AddConnection("key1", mockConn))
 
 	_, err := cm.RequestConnection(context.Background(), "key1")
 
 	assert.ErrorContains(t, err, "failed to send connect command")
+
+	// The request is rolled back, freeing its pending slot.
+	assert.Empty(t, cm.requests)
+	assert.Zero(t, cm.conns["key1"].entries[0].pending)
+}
+
+func TestConnManager_RequestConnection_DoesNotHoldLockWhileSending(t *testing.T) {
+	cm := New()
+	slowConn := newControlConn(t, nil)
+	mockReq := conn.NewMockRequest(t)
+	reqID := uuid.New()
+
+	sending := make(chan struct{})
+	unblock := make(chan struct{})
+
+	mockReq.EXPECT().ID().Return(reqID)
+	mockReq.EXPECT().SendConn(mock.Anything, mock.Anything).Return()
+	slowConn.EXPECT().NewRequest().Return(mockReq)
+	slowConn.EXPECT().RequestConnection(mockReq).RunAndReturn(func(conn.Request) error {
+		close(sending)
+		<-unblock
+
+		return nil
+	})
+
+	require.NoError(t, cm.AddConnection("slow", slowConn))
+
+	done := make(chan error, 1)
+
+	go func() {
+		_, err := cm.RequestConnection(context.Background(), "slow")
+		done <- err
+	}()
+
+	<-sending
+
+	// Other keyIDs are served while the connect command is stuck, and the client may already
+	// fulfil the request before the command is reported as sent.
+	_, ok := cm.TunnelAuth("slow")
+	assert.True(t, ok)
+	require.NoError(t, cm.AddConnection("other", newControlConn(t, nil)))
+
+	cm.ResolveRequest(reqID, conn.NewMockWithWriteCloser(t))
+
+	close(unblock)
+
+	require.NoError(t, <-done)
+	assert.Empty(t, cm.requests)
 }
 
 func TestConnManager_ResolveRequest(t *testing.T) {
@@ -121,7 +177,7 @@ func TestConnManager_CancelRequest(t *testing.T) {
 }
 
 func TestConnManager_Close(t *testing.T) {
-	mockConn := core.NewMockControlConn(t)
+	mockConn := newControlConn(t, nil)
 	mockReq := conn.NewMockRequest(t)
 	cm := New()
 
@@ -130,7 +186,7 @@ func TestConnManager_Close(t *testing.T) {
 	mockConn.EXPECT().Close().Return(nil)
 	mockReq.EXPECT().Cancel().Return()
 
-	cm.conns["key1"] = mockConn
+	require.NoError(t, cm.AddConnection("key1", mockConn))
 	cm.requests[reqID] = &connRequest{
 		ctx: context.Background(),
 		req: mockReq,
@@ -141,3 +197,237 @@ func TestConnManager_Close(t *testing.T) {
 	require.NoError(t, err)
 	assert.Empty(t, cm.requests)
 }
+
+func TestConnManager_RemoveConnection_KeepsOtherConnections(t *testing.T) {
+	cm := New()
+	conn1 := newControlConn(t, nil)
+	conn2 := newControlConn(t, nil)
+
+	id1, id2 := uuid.New(), uuid.New()
+
+	conn1.EXPECT().ID().Return(id1)
+	conn1.EXPECT().Close().Return(nil)
+	conn2.EXPECT().ID().Return(id2).Maybe()
+
+	require.NoError(t, cm.AddConnection("key1", conn1))
+	require.NoError(t, cm.AddConnection("key1", conn2))
+	cm.RemoveConnection("key1", id1)
+
+	require.Len(t, cm.conns["key1"].entries, 1)
+	assert.Equal(t, conn2, cm.conns["key1"].entries[0].conn)
+}
+
+func TestConnManager_RemoveConnection_UnknownID(t *testing.T) {
+	cm := New()
+	mockConn := newControlConn(t, nil)
+
+	mockConn.EXPECT().ID().Return(uuid.New())
+
+	require.NoError(t, cm.AddConnection("key1", mockConn))
+	cm.RemoveConnection("key1", uuid.New())
+
+	require.Len(t, cm.conns["key1"].entries, 1)
+}
+
+func TestConnManager_RequestConnection_RoundRobin(t *testing.T) {
+	cm := New(WithStrategy(StrategyRoundRobin))
+	conn1 := newControlConn(t, nil)
+	conn2 := newControlConn(t, nil)
+
+	req1, req2, req3 := conn.NewMockRequest(t), conn.NewMockRequest(t), conn.NewMockRequest(t)
+
+	req1.EXPECT().ID().Return(uuid.New())
+	req2.EXPECT().ID().Return(uuid.New())
+	req3.EXPECT().ID().Return(uuid.New())
+
+	conn1.EXPECT().NewRequest().Return(req1).Once()
+	conn1.EXPECT().RequestConnection(req1).Return(nil).Once()
+	conn2.EXPECT().NewRequest().Return(req2).Once()
+	conn2.EXPECT().RequestConnection(req2).Return(nil).Once()
+	conn1.EXPECT().NewRequest().Return(req3).Once()
+	conn1.EXPECT().RequestConnection(req3).Return(nil).Once()
+
+	require.NoError(t, cm.AddConnection("key1", conn1))
+	require.NoError(t, cm.AddConnection("key1", conn2))
+
+	for _, expected := range []conn.Request{req1, req2, req3} {
+		req, err := cm.RequestConnection(context.Background(), "key1")
+
+		require.NoError(t, err)
+		assert.Equal(t, expected, req)
+	}
+}
+
+func TestConnManager_RequestConnection_LeastRequests(t *testing.T) {
+	cm := New(WithStrategy(StrategyLeastRequests))
+	conn1 := newControlConn(t, nil)
+	conn2 := newControlConn(t, nil)
+
+	req1, req2, req3 := conn.NewMockRequest(t), conn.NewMockRequest(t), conn.NewMockRequest(t)
+	reqID1 := uuid.New()
+
+	req1.EXPECT().ID().Return(reqID1)
+	req1.EXPECT().Cancel().Return()
+	req2.EXPECT().ID().Return(uuid.New())
+	req3.EXPECT().ID().Return(uuid.New())
+
+	conn1.EXPECT().NewRequest().Return(req1).Once()
+	conn1.EXPECT().RequestConnection(req1).Return(nil).Once()
+	conn2.EXPECT().NewRequest().Return(req2).Once()
+	conn2.EXPECT().RequestConnection(req2).Return(nil).Once()
+	conn1.EXPECT().NewRequest().Return(req3).Once()
+	conn1.EXPECT().RequestConnection(req3).Return(nil).Once()
+
+	require.NoError(t, cm.AddConnection("key1", conn1))
+	require.NoError(t, cm.AddConnection("key1", conn2))
+
+	// conn1 is idle, so it gets the first request
+	req, err := cm.RequestConnection(context.Background(), "key1")
+	require.NoError(t, err)
+	assert.Equal(t, req1, req)
+
+	// conn1 has one outstanding request, so conn2 is picked
+	req, err = cm.RequestConnection(context.Background(), "key1")
+	require.NoError(t, err)
+	assert.Equal(t, req2, req)
+
+	// Canceling the request on conn1 makes it the least loaded again
+	cm.CancelRequest(reqID1)
+
+	req, err = cm.RequestConnection(context.Background(), "key1")
+	require.NoError(t, err)
+	assert.Equal(t, req3, req)
+}
+
+func TestConnManager_RequestConnection_Failover(t *testing.T) {
+	cm := New()
+	conn1 := newControlConn(t, nil)
+	conn2 := newControlConn(t, nil)
+	mockReq := conn.NewMockRequest(t)
+
+	failedReq := conn.NewMockRequest(t)
+
+	mockReq.EXPECT().ID().Return(uuid.New())
+	failedReq.EXPECT().ID().Return(uuid.New())
+	conn1.EXPECT().NewRequest().Return(failedReq)
+	conn1.EXPECT().RequestConnection(failedReq).Return(errors.New("connection error"))
+	conn2.EXPECT().NewRequest().Return(mockReq)
+	conn2.EXPECT().RequestConnection(mockReq).Return(nil)
+
+	require.NoError(t, cm.AddConnection("key1", conn1))
+	require.NoError(t, cm.AddConnection("key1", conn2))
+
+	req, err := cm.RequestConnection(context.Background(), "key1")
+
+	require.NoError(t, err)
+	assert.Equal(t, mockReq, req)
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy Strategy
+		wantErr  bool
+	}{
+		{name: "empty", strategy: ""},
+		{name: "round robin", strategy: StrategyRoundRobin},
+		{name: "least requests", strategy: StrategyLeastRequests},
+		{name: "unknown", strategy: "random", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{Strategy: tt.strategy}
+
+			if tt.wantErr {
+				assert.Error(t, cfg.Validate())
+			} else {
+				assert.NoError(t, cfg.Validate())
+			}
+		})
+	}
+}
+
+func TestConnManager_PendingRequestsMetric(t *testing.T) {
+	cm := New()
+	ctrl := newControlConn(t, nil)
+	req := conn.NewMockRequest(t)
+	reqID := uuid.New()
+
+	ctrl.EXPECT().NewRequest().Return(req)
+	ctrl.EXPECT().RequestConnection(req).Return(nil)
+	req.EXPECT().ID().Return(reqID)
+	req.EXPECT().Cancel()
+
+	require.NoError(t, cm.AddConnection("key", ctrl))
+
+	before := testutil.ToFloat64(metrics.PendingRequests)
+
+	_, err := cm.RequestConnection(context.Background(), "key")
+	require.NoError(t, err)
+
+	assert.InDelta(t, before+1, testutil.ToFloat64(metrics.PendingRequests), 0)
+
+	cm.CancelRequest(reqID)
+
+	assert.InDelta(t, before, testutil.ToFloat64(metrics.PendingRequests), 0)
+}
+
+func TestConnManager_TunnelAuth(t *testing.T) {
+	cm := New()
+
+	_, ok := cm.TunnelAuth("key")
+	assert.False(t, ok)
+
+	auth := meta.NewTunnelAuth("", "token")
+
+	require.NoError(t, cm.AddConnection("key", newControlConn(t, auth)))
+	require.NoError(t, cm.AddConnection("key", newControlConn(t, meta.NewTunnelAuth("", "token"))))
+
+	got, ok := cm.TunnelAuth("key")
+
+	assert.True(t, ok)
+	assert.Equal(t, auth, got)
+}
+
+func TestConnManager_AddConnection_AuthMismatch(t *testing.T) {
+	tests := []struct {
+		first  *meta.TunnelAuth
+		second *meta.TunnelAuth
+		name   string
+	}{
+		{name: "protected then public", first: meta.NewTunnelAuth("", "token"), second: nil},
+		{name: "public then protected", first: nil, second: meta.NewTunnelAuth("", "token")},
+		{name: "different credentials", first: meta.NewTunnelAuth("", "token"), second: meta.NewTunnelAuth("", "other")},
+		{name: "different schemes", first: meta.NewTunnelAuth("user:pass", "token"), second: meta.NewTunnelAuth("", "token")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cm := New()
+
+			require.NoError(t, cm.AddConnection("key", newControlConn(t, tt.first)))
+
+			err := cm.AddConnection("key", newControlConn(t, tt.second))
+
+			assert.ErrorIs(t, err, core.ErrAuthMismatch)
+			assert.Len(t, cm.conns["key"].entries, 1)
+
+			// The credentials of the connected client keep protecting the tunnel.
+			got, ok := cm.TunnelAuth("key")
+
+			assert.True(t, ok)
+			assert.Equal(t, tt.first, got)
+		})
+	}
+}
+
+// newControlConn returns a mock control connection whose client requires auth.
+func newControlConn(t *testing.T, auth *meta.TunnelAuth) *core.MockControlConn {
+	t.Helper()
+
+	c := core.NewMockControlConn(t)
+	c.EXPECT().Auth().Return(auth).Maybe()
+
+	return c
+}
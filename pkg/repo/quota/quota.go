@@ -0,0 +1,71 @@
+// Package quota tracks the traffic of tokens per quota period in Redis.
+package quota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const usagePrefix = "QUOTA::"
+
+// Redis is the subset of the Redis client used by the Repo.
+type Redis interface {
+	Get(ctx context.Context, key string) *redis.StringCmd
+	IncrBy(ctx context.Context, key string, value int64) *redis.IntCmd
+	ExpireAt(ctx context.Context, key string, tm time.Time) *redis.BoolCmd
+}
+
+// Repo stores the bytes transferred by every keyID in a counter per quota period.
+// It implements core.QuotaRepo.
+type Repo struct {
+	db        Redis
+	keyPrefix string
+}
+
+// New creates a Repo. db is the Redis client shared with the auth repository,
+// and keyPrefix is prepended to every Redis key.
+func New(db Redis, keyPrefix string) *Repo {
+	return &Repo{
+		db:        db,
+		keyPrefix: keyPrefix,
+	}
+}
+
+// TrafficUsage returns the bytes transferred by keyID during period, or 0 if nothing was recorded.
+func (r *Repo) TrafficUsage(ctx context.Context, keyID, period string) (int64, error) {
+	used, err := r.db.Get(ctx, r.usageKey(keyID, period)).Int64()
+
+	switch {
+	case errors.Is(err, redis.Nil):
+		return 0, nil
+	case err != nil:
+		return 0, fmt.Errorf("failed to get traffic usage: %w", err)
+	}
+
+	return used, nil
+}
+
+// AddTraffic adds n bytes to the usage of keyID during period and returns the resulting usage.
+// The counter is removed at expireAt.
+func (r *Repo) AddTraffic(ctx context.Context, keyID, period string, n int64, expireAt time.Time) (int64, error) {
+	key := r.usageKey(keyID, period)
+
+	used, err := r.db.IncrBy(ctx, key, n).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to add traffic usage: %w", err)
+	}
+
+	if err := r.db.ExpireAt(ctx, key, expireAt).Err(); err != nil {
+		return 0, fmt.Errorf("failed to set expiration of traffic usage: %w", err)
+	}
+
+	return used, nil
+}
+
+func (r *Repo) usageKey(keyID, period string) string {
+	return r.keyPrefix + usagePrefix + keyID + "::" + period
+}
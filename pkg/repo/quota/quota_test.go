@@ -0,0 +1,79 @@
+package quota
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testUsageKey = "prefix::QUOTA::key1::2026-10"
+
+func TestRepo_TrafficUsage(t *testing.T) {
+	rdb, mockRDB := redismock.NewClientMock()
+	repo := New(rdb, "prefix::")
+
+	mockRDB.ExpectGet(testUsageKey).SetVal("1024")
+
+	used, err := repo.TrafficUsage(t.Context(), "key1", "2026-10")
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(1024), used)
+}
+
+func TestRepo_TrafficUsage_NotRecorded(t *testing.T) {
+	rdb, mockRDB := redismock.NewClientMock()
+	repo := New(rdb, "prefix::")
+
+	mockRDB.ExpectGet(testUsageKey).RedisNil()
+
+	used, err := repo.TrafficUsage(t.Context(), "key1", "2026-10")
+
+	require.NoError(t, err)
+	assert.Zero(t, used)
+}
+
+func TestRepo_TrafficUsage_Error(t *testing.T) {
+	rdb, mockRDB := redismock.NewClientMock()
+	repo := New(rdb, "prefix::")
+
+	mockRDB.ExpectGet(testUsageKey).SetErr(assert.AnError)
+
+	_, err := repo.TrafficUsage(t.Context(), "key1", "2026-10")
+
+	assert.ErrorIs(t, err, assert.AnError)
+}
+
+func TestRepo_AddTraffic(t *testing.T) {
+	rdb, mockRDB := redismock.NewClientMock()
+	repo := New(rdb, "prefix::")
+	expireAt := time.Date(2026, 11, 2, 0, 0, 0, 0, time.UTC)
+
+	mockRDB.ExpectIncrBy(testUsageKey, 512).SetVal(1536)
+	mockRDB.ExpectExpireAt(testUsageKey, expireAt).SetVal(true)
+
+	used, err := repo.AddTraffic(t.Context(), "key1", "2026-10", 512, expireAt)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(1536), used)
+	assert.NoError(t, mockRDB.ExpectationsWereMet())
+}
+
+func TestRepo_AddTraffic_Error(t *testing.T) {
+	rdb, mockRDB := redismock.NewClientMock()
+	repo := New(rdb, "prefix::")
+	expireAt := time.Date(2026, 11, 2, 0, 0, 0, 0, time.UTC)
+
+	mockRDB.ExpectIncrBy(testUsageKey, 512).SetErr(assert.AnError)
+
+	_, err := repo.AddTraffic(t.Context(), "key1", "2026-10", 512, expireAt)
+	require.ErrorIs(t, err, assert.AnError)
+
+	mockRDB.ExpectIncrBy(testUsageKey, 512).SetVal(512)
+	mockRDB.ExpectExpireAt(testUsageKey, expireAt).SetErr(assert.AnError)
+
+	_, err = repo.AddTraffic(t.Context(), "key1", "2026-10", 512, expireAt)
+	assert.ErrorIs(t, err, assert.AnError)
+}
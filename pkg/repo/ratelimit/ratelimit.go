@@ -0,0 +1,82 @@
+// Package ratelimit limits request rates in Redis, so that the limits hold across all nodes of a cluster.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const ratePrefix = "RATE::"
+
+// allowScript implements the generic cell rate algorithm. The key holds the theoretical arrival time
+// of the next request in microseconds of the Redis clock. A request is allowed when it arrives no earlier
+// than the tolerance before that time, and the script returns 0; otherwise it returns the microseconds to wait.
+var allowScript = redis.NewScript(`
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+local interval = tonumber(ARGV[1])
+local tolerance = tonumber(ARGV[2])
+
+local tat = tonumber(redis.call('GET', KEYS[1]) or now)
+if tat < now then
+	tat = now
+end
+
+local new_tat = tat + interval
+local wait = new_tat - tolerance - now
+if wait > 0 then
+	return wait
+end
+
+redis.call('SET', KEYS[1], new_tat, 'PX', math.ceil((new_tat - now) / 1000))
+
+return 0
+`)
+
+// Redis is the subset of the Redis client used by the Limiter.
+type Redis interface {
+	redis.Scripter
+}
+
+// Limiter limits the request rate of every key in Redis. It implements middleware.RateLimiter.
+type Limiter struct {
+	db        Redis
+	keyPrefix string
+	interval  int64
+	tolerance int64
+}
+
+// New creates a Limiter allowing rate requests per second for every key, with bursts of up to burst requests.
+// burst defaults to rate, and to 1 if rate is below 1. db is the Redis client shared with the auth repository,
+// and keyPrefix is prepended to every Redis key.
+func New(db Redis, keyPrefix string, rate float64, burst int) *Limiter {
+	if burst <= 0 {
+		burst = max(int(rate), 1)
+	}
+
+	interval := int64(float64(time.Second/time.Microsecond) / rate)
+
+	return &Limiter{
+		db:        db,
+		keyPrefix: keyPrefix,
+		interval:  interval,
+		tolerance: interval * int64(burst),
+	}
+}
+
+// Allow counts a request of key and reports how long to wait when it is over the limit.
+func (l *Limiter) Allow(ctx context.Context, key string) (bool, time.Duration, error) {
+	wait, err := allowScript.Run(ctx, l.db, []string{l.keyPrefix + ratePrefix + key}, l.interval, l.tolerance).Int64()
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to check request rate: %w", err)
+	}
+
+	if wait > 0 {
+		return false, time.Duration(wait) * time.Microsecond, nil
+	}
+
+	return true, 0, nil
+}
@@ -0,0 +1,62 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testRateKey = "prefix::RATE::key1::10.0.0.1"
+
+func TestNew(t *testing.T) {
+	l := New(nil, "prefix::", 10, 5)
+
+	assert.Equal(t, int64(100_000), l.interval)
+	assert.Equal(t, int64(500_000), l.tolerance)
+
+	l = New(nil, "prefix::", 0.5, 0)
+
+	assert.Equal(t, int64(2_000_000), l.interval)
+	assert.Equal(t, int64(2_000_000), l.tolerance)
+}
+
+func TestLimiter_Allow(t *testing.T) {
+	rdb, mockRDB := redismock.NewClientMock()
+	l := New(rdb, "prefix::", 10, 5)
+
+	mockRDB.ExpectEvalSha(allowScript.Hash(), []string{testRateKey}, int64(100_000), int64(500_000)).SetVal(int64(0))
+
+	allowed, wait, err := l.Allow(t.Context(), "key1::10.0.0.1")
+
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	assert.Zero(t, wait)
+	assert.NoError(t, mockRDB.ExpectationsWereMet())
+}
+
+func TestLimiter_Allow_OverLimit(t *testing.T) {
+	rdb, mockRDB := redismock.NewClientMock()
+	l := New(rdb, "prefix::", 10, 5)
+
+	mockRDB.ExpectEvalSha(allowScript.Hash(), []string{testRateKey}, int64(100_000), int64(500_000)).SetVal(int64(25_000))
+
+	allowed, wait, err := l.Allow(t.Context(), "key1::10.0.0.1")
+
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, 25*time.Millisecond, wait)
+}
+
+func TestLimiter_Allow_Error(t *testing.T) {
+	rdb, mockRDB := redismock.NewClientMock()
+	l := New(rdb, "prefix::", 10, 5)
+
+	mockRDB.ExpectEvalSha(allowScript.Hash(), []string{testRateKey}, int64(100_000), int64(500_000)).SetErr(assert.AnError)
+
+	_, _, err := l.Allow(t.Context(), "key1::10.0.0.1")
+
+	assert.ErrorIs(t, err, assert.AnError)
+}
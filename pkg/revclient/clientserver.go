@@ -3,15 +3,20 @@ package revclient
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net"
+	"net/http"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ksysoev/make-it-public/pkg/core/conn/meta"
 	"github.com/ksysoev/make-it-public/pkg/core/token"
+	"github.com/ksysoev/make-it-public/pkg/target"
 	"github.com/ksysoev/revdial"
 	"golang.org/x/sync/errgroup"
 )
@@ -25,25 +30,65 @@ const (
 	// A 30s interval ensures silent TCP drops (e.g. firewall idle timeouts) are detected
 	// well within typical NAT/firewall session expiry windows (often 5–30 minutes).
 	defaultKeepAliveInterval = 30 * time.Second
+
+	// reconnectGracePeriod bounds how long a control connection replaced at the server's request
+	// keeps serving its connections before it is closed.
+	reconnectGracePeriod = 30 * time.Second
+	retirePollInterval   = 100 * time.Millisecond
+
+	// tunnelTimeout bounds how long a new control connection opened at the server's request may take
+	// to publish the tunnel before the client gives up on it.
+	tunnelTimeout = 10 * time.Second
 )
 
+// drainingError is returned when a server refuses a new control connection because it is draining.
+// addr is the server address it asks the client to connect to instead, if any.
+type drainingError struct {
+	addr string
+}
+
+func (e *drainingError) Error() string {
+	return "server is draining"
+}
+
 // Config holds the configuration for the ClientServer.
+// Auth, when set, is sent to the server so that visitors of the tunnel must present matching credentials.
+// Port, when set, is the public port a TCP tunnel prefers; the server picks another one if it is taken.
+// H2C, when set, makes the client send the HTTP requests of a web tunnel to DestAddr over HTTP/2 without TLS,
+// for local services such as gRPC servers that only speak HTTP/2.
+// Routes, when set, send the HTTP requests of a web tunnel to the local service of the route matching their path,
+// and DestAddr serves the requests no route matches.
+// Headers, when set, rewrites the headers of the HTTP requests of a web tunnel and of their responses.
+// DestAddr and the addresses of Routes are parsed with target.Parse, and UpstreamTLS, when set, configures the TLS
+// sessions to local services at https:// addresses, such as their server name, trusted CAs and client certificate.
 type Config struct {
-	ServerAddr string
-	DestAddr   string
-	NoTLS      bool
-	Insecure   bool
-	EnableV2   bool
+	Auth        *meta.TunnelAuth
+	UpstreamTLS *tls.Config
+	Headers     HeaderRules
+	ServerAddr  string
+	DestAddr    string
+	Routes      []Route
+	Port        int
+	NoTLS       bool
+	Insecure    bool
+	EnableV2    bool
+	H2C         bool
 }
 
-// listenFunc is the signature for creating a reverse-dial listener.
+// listenFunc is the signature for creating the reverse-dial listener of cc.
 // It is a package-level type so tests can substitute a fake implementation.
-type listenFunc func(ctx context.Context, addr string, opts ...revdial.ListenerOption) (net.Listener, error)
+type listenFunc func(ctx context.Context, cc *controlConn, opts ...revdial.ListenerOption) (net.Listener, error)
+
+// lookupHostFunc is the signature of net.Resolver.LookupHost, so tests can substitute DNS.
+type lookupHostFunc func(ctx context.Context, host string) ([]string, error)
 
 // ClientServer manages the reverse tunnel connection to the server and
 // forwards incoming connections to the configured local destination.
 type ClientServer struct {
 	listen         listenFunc
+	lookupHost     lookupHostFunc
+	interceptor    Interceptor
+	httpProxy      http.Handler
 	onConnected    func(url string)
 	onReconnected  func(url string)
 	onRequest      func(clientIP string)
@@ -53,6 +98,58 @@ type ClientServer struct {
 	wg             sync.WaitGroup
 }
 
+// controlConn is a control connection to the server and the count of the connections it is serving.
+// addr is the server address the connection was opened for, and dialAddr the address it was dialed at,
+// which is another node of addr when the client moves away from a draining server.
+// reconnect receives the reconnect requests the server sends on this connection only, so that requests
+// of retired connections are never acted upon, and ready is closed once the server publishes the tunnel on it.
+type controlConn struct {
+	listener  net.Listener
+	reconnect chan string
+	ready     chan struct{}
+	addr      string
+	dialAddr  string
+	active    atomic.Int64
+	closeOnce sync.Once
+	readyOnce sync.Once
+}
+
+func newControlConn(addr, dialAddr string) *controlConn {
+	return &controlConn{
+		addr:      addr,
+		dialAddr:  dialAddr,
+		reconnect: make(chan string, 1),
+		ready:     make(chan struct{}),
+	}
+}
+
+func (c *controlConn) close() {
+	c.closeOnce.Do(func() { _ = c.listener.Close() })
+}
+
+// markReady records that the server published the tunnel on c.
+func (c *controlConn) markReady() {
+	c.readyOnce.Do(func() { close(c.ready) })
+}
+
+// requestReconnect asks serve to move from c to a new control connection to addr, or to another node of
+// the current server address when addr is empty. Requests arriving while one is pending are dropped.
+func (c *controlConn) requestReconnect(addr string) {
+	select {
+	case c.reconnect <- addr:
+	default:
+	}
+}
+
+// remoteAddr returns the address c is connected to, which is dialAddr resolved by the listener.
+func (c *controlConn) remoteAddr() string {
+	if c.listener == nil || c.listener.Addr() == nil {
+		return c.dialAddr
+	}
+
+	return c.listener.Addr().String()
+}
+
 // Option is a functional option for configuring ClientServer.
 type Option func(*ClientServer)
 
@@ -83,6 +180,21 @@ func WithOnRequest(fn func(clientIP string)) Option {
 	}
 }
 
+// Interceptor observes the traffic of tunneled connections, e.g. to record HTTP requests for inspection.
+type Interceptor interface {
+	// Intercept is called for every incoming connection with the IP address of the end user.
+	// It returns writers receiving a copy of the data sent to the local destination and of the data
+	// sent back. Writes must not fail or block for long; both writers are closed when the connection ends.
+	Intercept(ctx context.Context, clientIP string) (toDest, toSource io.WriteCloser)
+}
+
+// WithInterceptor sets an Interceptor receiving a copy of the traffic of every incoming connection.
+func WithInterceptor(i Interceptor) Option {
+	return func(c *ClientServer) {
+		c.interceptor = i
+	}
+}
+
 // Conn extends net.Conn with CloseWrite to allow half-close of the write side.
 type Conn interface {
 	net.Conn
@@ -99,6 +211,21 @@ func (w *connWrapper) CloseWrite() error {
 	return w.Close()
 }
 
+// teeConn copies everything read from the wrapped Conn to w.
+type teeConn struct {
+	Conn
+	w io.Writer
+}
+
+func (c *teeConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if n > 0 {
+		_, _ = c.w.Write(p[:n])
+	}
+
+	return n, err
+}
+
 // wrapConn wraps a net.Conn to satisfy the Conn interface.
 // If the connection already implements CloseWrite(), it returns the connection as-is.
 // Otherwise, it wraps it in a connWrapper whose CloseWrite is a best-effort
@@ -118,11 +245,16 @@ func NewClientServer(cfg Config, tkn *token.Token, opts ...Option) *ClientServer
 		cfg:            cfg,
 		token:          tkn,
 		initialBackoff: reconnectBackoffInitial,
-		listen: func(ctx context.Context, addr string, opts ...revdial.ListenerOption) (net.Listener, error) {
-			return revdial.Listen(ctx, addr, opts...)
+		lookupHost:     net.DefaultResolver.LookupHost,
+		listen: func(ctx context.Context, cc *controlConn, opts ...revdial.ListenerOption) (net.Listener, error) {
+			return revdial.Listen(ctx, cc.dialAddr, opts...)
 		},
 	}
 
+	if cfg.H2C || len(cfg.Routes) > 0 || !cfg.Headers.IsZero() {
+		cs.httpProxy = newRouter(cfg)
+	}
+
 	for _, opt := range opts {
 		opt(cs)
 	}
@@ -130,13 +262,18 @@ func NewClientServer(cfg Config, tkn *token.Token, opts ...Option) *ClientServer
 	return cs
 }
 
-// buildOpts constructs the revdial listener options from the current configuration.
+// buildOpts constructs the revdial listener options for opening cc.
 // isReconnect indicates whether the URL event handler should fire onReconnected
 // instead of onConnected.
-func (s *ClientServer) buildOpts(ctx context.Context, isReconnect bool) ([]revdial.ListenerOption, error) {
+func (s *ClientServer) buildOpts(ctx context.Context, cc *controlConn, isReconnect bool) ([]revdial.ListenerOption, error) {
 	var opts []revdial.ListenerOption
 
-	authOpt, err := revdial.WithUserPass(s.token.IDWithType(), s.token.Secret)
+	password, err := meta.EncodeSecret(s.token.Secret, meta.ClientOptions{Auth: s.cfg.Auth, Port: s.cfg.Port})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode client options: %w", err)
+	}
+
+	authOpt, err := revdial.WithUserPass(s.token.IDWithType(), password)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create auth option: %w", err)
 	}
@@ -150,6 +287,8 @@ func (s *ClientServer) buildOpts(ctx context.Context, isReconnect bool) ([]revdi
 			return
 		}
 
+		cc.markReady()
+
 		if isReconnect {
 			switch {
 			case s.onReconnected != nil:
@@ -173,8 +312,24 @@ func (s *ClientServer) buildOpts(ctx context.Context, isReconnect bool) ([]revdi
 
 	opts = append(opts, onConnect)
 
+	onReconnect, err := revdial.WithEventHandler(meta.ReconnectEvent, func(event revdial.Event) {
+		var addr string
+		if err := event.ParsePayload(&addr); err != nil {
+			slog.ErrorContext(ctx, "failed to parse payload for event "+meta.ReconnectEvent, "error", err)
+			return
+		}
+
+		cc.requestReconnect(addr)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create event handler: %w", err)
+	}
+
+	opts = append(opts, onReconnect)
+
 	if !s.cfg.NoTLS {
-		host, _, err := net.SplitHostPort(s.cfg.ServerAddr)
+		// The certificate is verified against the server address even when another node of it is dialed.
+		host, _, err := net.SplitHostPort(cc.addr)
 		if err != nil {
 			return nil, fmt.Errorf("failed to split host and port: %w", err)
 		}
@@ -199,8 +354,9 @@ func (s *ClientServer) buildOpts(ctx context.Context, isReconnect bool) ([]revdi
 
 // Run connects to the server and forwards incoming connections to the local destination.
 // If the connection is lost after a successful connection, Run automatically reconnects
-// with exponential backoff (1s up to 30s). The first connection failure is returned
-// immediately without retrying. Run returns nil when ctx is cancelled.
+// with exponential backoff (1s up to 30s). When the server asks the client to reconnect,
+// e.g. before it stops, the new control connection is opened before the old one is closed.
+// The first connection failure is returned immediately without retrying. Run returns nil when ctx is cancelled.
 func (s *ClientServer) Run(ctx context.Context) error {
 	slog.DebugContext(ctx, "initializing revdial client",
 		slog.String("server", s.cfg.ServerAddr),
@@ -212,22 +368,25 @@ func (s *ClientServer) Run(ctx context.Context) error {
 
 	backoff := s.initialBackoff
 	attempt := 0
+	serverAddr := s.cfg.ServerAddr
 
 	for {
 		if ctx.Err() != nil {
 			return nil
 		}
 
-		opts, err := s.buildOpts(ctx, attempt > 0)
+		cc := newControlConn(serverAddr, serverAddr)
+
+		opts, err := s.buildOpts(ctx, cc, attempt > 0)
 		if err != nil {
 			return err
 		}
 
 		slog.DebugContext(ctx, "connecting to server",
-			slog.String("server", s.cfg.ServerAddr),
+			slog.String("server", serverAddr),
 			slog.Int("attempt", attempt+1))
 
-		listener, err := s.listen(ctx, s.cfg.ServerAddr, opts...)
+		cc.listener, err = s.listen(ctx, cc, opts...)
 		if err != nil {
 			if attempt == 0 {
 				// If context was cancelled while the first listen was in progress,
@@ -239,7 +398,7 @@ func (s *ClientServer) Run(ctx context.Context) error {
 				// First connection failed — report immediately, no retry.
 				slog.ErrorContext(ctx, "failed to connect to server",
 					slog.Any("error", err),
-					slog.String("server", s.cfg.ServerAddr),
+					slog.String("server", serverAddr),
 					slog.Bool("v2_enabled", s.cfg.EnableV2),
 					slog.String("hint", "If connection fails, try using --disable-v2 flag for V1 fallback"))
 
@@ -249,7 +408,7 @@ func (s *ClientServer) Run(ctx context.Context) error {
 			// Subsequent reconnect attempt failed — back off and retry.
 			slog.WarnContext(ctx, "reconnect attempt failed, retrying",
 				slog.Any("error", err),
-				slog.String("server", s.cfg.ServerAddr),
+				slog.String("server", serverAddr),
 				slog.Duration("backoff", backoff))
 
 			select {
@@ -264,24 +423,9 @@ func (s *ClientServer) Run(ctx context.Context) error {
 			continue
 		}
 
-		// Successful connection — register a goroutine to close the listener on context
-		// cancellation. The goroutine always calls listener.Close() after either branch
-		// fires so the listener is never leaked when listenAndServe returns for any reason.
-		stopListener := make(chan struct{})
-
-		go func() {
-			select {
-			case <-ctx.Done():
-			case <-stopListener:
-			}
-
-			_ = listener.Close()
-		}()
+		var serveErr error
 
-		serveErr := s.listenAndServe(ctx, listener)
-
-		// Signal the closer goroutine that the listener is already done.
-		close(stopListener)
+		serverAddr, serveErr = s.serve(ctx, cc)
 
 		if ctx.Err() != nil {
 			// Context was cancelled — clean exit.
@@ -298,11 +442,11 @@ func (s *ClientServer) Run(ctx context.Context) error {
 			// Unexpected error that is not a normal disconnect.
 			slog.ErrorContext(ctx, "unexpected error from listener, will reconnect",
 				slog.Any("error", serveErr),
-				slog.String("server", s.cfg.ServerAddr),
+				slog.String("server", serverAddr),
 				slog.Duration("backoff", backoff))
 		} else {
 			slog.InfoContext(ctx, "disconnected from server, reconnecting",
-				slog.String("server", s.cfg.ServerAddr),
+				slog.String("server", serverAddr),
 				slog.Duration("backoff", backoff))
 		}
 
@@ -316,17 +460,203 @@ func (s *ClientServer) Run(ctx context.Context) error {
 	}
 }
 
-func (s *ClientServer) listenAndServe(ctx context.Context, listener net.Listener) error {
+// serve accepts connections from cc until its listener is closed, and returns the address of the server
+// the client is connected to by then. When the server asks the client to reconnect, serve opens a new
+// control connection first and retires cc once its connections are finished, so the tunnel stays available
+// while the client moves between servers. Without a reconnect address the client moves to another node of
+// the current server address, and nodes that refuse the tunnel because they are draining too are avoided.
+// Failed reconnects are retried with backoff while cc is open.
+func (s *ClientServer) serve(ctx context.Context, cc *controlConn) (string, error) {
+	done := s.startServing(ctx, cc)
+
+	var (
+		retry  <-chan time.Time
+		target string
+		avoid  []string
+	)
+
+	backoff := s.initialBackoff
+
 	for {
-		conn, err := listener.Accept()
+		select {
+		case err := <-done:
+			return cc.addr, err
+		case addr := <-cc.reconnect:
+			target = addr
+			if target == "" {
+				target = cc.addr
+			}
+
+			// The node cc is connected to is draining, so it is only dialed again if no other node is found.
+			avoid = []string{cc.remoteAddr()}
+			backoff = s.initialBackoff
+		case <-retry:
+		}
+
+		retry = nil
+
+		dialAddr := s.resolveServer(ctx, target, avoid)
+
+		slog.InfoContext(ctx, "server requested reconnect", slog.String("server", target), slog.String("addr", dialAddr))
+
+		next, err := s.connectTo(ctx, target, dialAddr)
+		if err != nil {
+			if ctx.Err() != nil {
+				continue
+			}
+
+			var drainErr *drainingError
+			if errors.As(err, &drainErr) && drainErr.addr != "" {
+				target = drainErr.addr
+			}
+
+			avoid = append(avoid, dialAddr)
+
+			slog.WarnContext(ctx, "failed to reconnect as requested by server, retrying",
+				slog.Any("error", err),
+				slog.String("server", target),
+				slog.Duration("backoff", backoff))
+
+			retry = time.After(backoff)
+			backoff = min(backoff*reconnectBackoffFactor, reconnectBackoffMax)
+
+			continue
+		}
+
+		go s.retire(ctx, cc, done)
+
+		cc = next
+		done = s.startServing(ctx, cc)
+	}
+}
+
+// resolveServer returns the address to dial to reach the server at addr on a node other than those in avoid.
+// The host of addr is resolved again, so that clients of servers behind round-robin DNS move to another node.
+// addr is returned as is when it cannot be resolved or has no other node, and a load balancer in front of
+// the servers picks the node then.
+func (s *ClientServer) resolveServer(ctx context.Context, addr string, avoid []string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+
+	ips, err := s.lookupHost(ctx, host)
+	if err != nil {
+		slog.DebugContext(ctx, "failed to resolve server address", slog.Any("error", err), slog.String("server", addr))
+		return addr
+	}
+
+	for _, ip := range ips {
+		if candidate := net.JoinHostPort(ip, port); !slices.Contains(avoid, candidate) {
+			return candidate
+		}
+	}
+
+	return addr
+}
+
+// connectTo opens a new control connection to the server at addr through dialAddr, and waits until the server
+// publishes the tunnel on it. Returns a *drainingError if the server asks the client to reconnect instead,
+// as a draining server does, or an error if the tunnel is not published within tunnelTimeout.
+func (s *ClientServer) connectTo(ctx context.Context, addr, dialAddr string) (*controlConn, error) {
+	cc := newControlConn(addr, dialAddr)
+
+	opts, err := s.buildOpts(ctx, cc, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if cc.listener, err = s.listen(ctx, cc, opts...); err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", dialAddr, err)
+	}
+
+	timer := time.NewTimer(tunnelTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-cc.ready:
+		return cc, nil
+	case redirect := <-cc.reconnect:
+		err = &drainingError{addr: redirect}
+	case <-timer.C:
+		err = errors.New("tunnel was not published in time")
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	cc.close()
+
+	return nil, fmt.Errorf("failed to connect to %s: %w", dialAddr, err)
+}
+
+// startServing accepts connections from cc in the background and returns a channel
+// receiving the error that stopped it. The listener is closed when ctx is cancelled,
+// and is never leaked when serving stops for any other reason.
+func (s *ClientServer) startServing(ctx context.Context, cc *controlConn) <-chan error {
+	done := make(chan error, 1)
+	stop := make(chan struct{})
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-stop:
+		}
+
+		cc.close()
+	}()
+
+	go func() {
+		err := s.listenAndServe(ctx, cc)
+
+		close(stop)
+
+		done <- err
+	}()
+
+	return done
+}
+
+// retire closes cc once the connections it serves are finished, the grace period expires,
+// or ctx is done. done receives the error that stopped serving cc, if it stops first.
+func (s *ClientServer) retire(ctx context.Context, cc *controlConn, done <-chan error) {
+	defer cc.close()
+
+	timer := time.NewTimer(reconnectGracePeriod)
+	defer timer.Stop()
+
+	ticker := time.NewTicker(retirePollInterval)
+	defer ticker.Stop()
+
+	for cc.active.Load() > 0 {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			slog.WarnContext(ctx, "closing previous server connection with active connections",
+				slog.String("server", cc.addr),
+				slog.Int64("active", cc.active.Load()))
+
+			return
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *ClientServer) listenAndServe(ctx context.Context, cc *controlConn) error {
+	for {
+		conn, err := cc.listener.Accept()
 		if err != nil {
 			return err
 		}
 
 		s.wg.Add(1)
+		cc.active.Add(1)
 
 		go func() {
 			defer s.wg.Done()
+			defer cc.active.Add(-1)
 
 			s.handleConn(ctx, conn)
 		}()
@@ -351,11 +681,23 @@ func (s *ClientServer) handleConn(ctx context.Context, conn net.Conn) {
 
 	defer slog.DebugContext(ctx, "closing connection", "clientIP", connMeta.IP)
 
-	d := net.Dialer{
-		Timeout: 5 * time.Second,
+	if s.token != nil && s.token.Type == token.TokenTypeUDP {
+		s.relayUDP(ctx, conn)
+		return
+	}
+
+	if s.httpProxy != nil {
+		s.serveHTTP(ctx, conn, connMeta.IP)
+		return
+	}
+
+	dest, err := target.Parse(s.cfg.DestAddr)
+	if err != nil {
+		slog.ErrorContext(ctx, "invalid local service address", "err", err)
+		return
 	}
 
-	dConn, err := d.DialContext(ctx, "tcp", s.cfg.DestAddr)
+	dConn, err := dest.Dial(ctx, s.cfg.UpstreamTLS, s.nextProtos())
 	if err != nil {
 		slog.ErrorContext(ctx, "failed to dial", "err", err)
 		return
@@ -368,6 +710,18 @@ func (s *ClientServer) handleConn(ctx context.Context, conn net.Conn) {
 	// Ensure destConn is fully closed after piping completes
 	defer func() { _ = destConn.Close() }()
 
+	if s.interceptor != nil {
+		toDest, toSource := s.interceptor.Intercept(ctx, connMeta.IP)
+
+		defer func() {
+			_ = toDest.Close()
+			_ = toSource.Close()
+		}()
+
+		revConn = &teeConn{Conn: revConn, w: toDest}
+		destConn = &teeConn{Conn: destConn, w: toSource}
+	}
+
 	eg, ctx := errgroup.WithContext(ctx)
 
 	eg.Go(pipeConn(ctx, revConn, destConn))
@@ -385,6 +739,23 @@ func (s *ClientServer) handleConn(ctx context.Context, conn net.Conn) {
 	}
 }
 
+// nextProtos returns the ALPN protocols offered to local services reached over TLS when connections are piped:
+// HTTP/1.1 for web tunnels, whose requests travel through the tunnel as HTTP/1.1, and HTTP/2 for gRPC tunnels.
+func (s *ClientServer) nextProtos() []string {
+	if s.token == nil {
+		return nil
+	}
+
+	switch s.token.Type {
+	case token.TokenTypeWeb:
+		return []string{"http/1.1"}
+	case token.TokenTypeGRPC:
+		return []string{"h2"}
+	default:
+		return nil
+	}
+}
+
 // pipeConn facilitates data transfer from the source connection to the destination connection in a single direction.
 // It utilizes io.Copy for copying data and closes the writing end of the destination connection afterward.
 // Accepts src as the source Conn interface and dst as the destination Conn interface, both supporting a CloseWrite method.
@@ -1,13 +1,17 @@
 package revclient
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"io"
 	"net"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/ksysoev/make-it-public/pkg/core/conn/meta"
 	"github.com/ksysoev/make-it-public/pkg/core/token"
 	"github.com/ksysoev/revdial"
 	"github.com/stretchr/testify/assert"
@@ -82,13 +86,14 @@ func (e *errorListener) Addr() net.Addr            { return e.addr }
 //
 //  1. WithUserPass       — authentication
 //  2. WithEventHandler   — urlToConnectUpdated event
-//  3. WithListenerKeepAlive — TCP keepalive (the fix for #283)
+//  3. WithEventHandler   — reconnect event
+//  4. WithListenerKeepAlive — TCP keepalive (the fix for #283)
 //
 // Conditional options:
 //
 //	+1 WithListenerTLSConfig  when NoTLS == false (default)
 //	+1 WithEnableV2           when EnableV2 == true
-const baseOptionCount = 3 // auth + event handler + keepalive
+const baseOptionCount = 4 // auth + event handlers + keepalive
 
 func newTestToken(t *testing.T) *token.Token {
 	t.Helper()
@@ -137,7 +142,7 @@ func TestBuildOpts_OptionCount(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			cs := NewClientServer(tt.cfg, newTestToken(t))
-			opts, err := cs.buildOpts(context.Background(), tt.isReconnect)
+			opts, err := cs.buildOpts(context.Background(), newControlConn(tt.cfg.ServerAddr, tt.cfg.ServerAddr), tt.isReconnect)
 
 			require.NoError(t, err)
 			assert.Len(t, opts, tt.wantCount,
@@ -150,7 +155,7 @@ func TestBuildOpts_InvalidServerAddr(t *testing.T) {
 	// When TLS is enabled but the server address has no port, SplitHostPort should fail.
 	cs := NewClientServer(Config{ServerAddr: "no-port-here", NoTLS: false}, newTestToken(t))
 
-	_, err := cs.buildOpts(context.Background(), false)
+	_, err := cs.buildOpts(context.Background(), newControlConn("no-port-here", "no-port-here"), false)
 
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "failed to split host and port")
@@ -164,7 +169,7 @@ func TestRun_ContextCancelledDuringFirstConnect(t *testing.T) {
 	defer cancel()
 
 	cs := NewClientServer(Config{ServerAddr: "localhost:1", NoTLS: true}, newTestToken(t))
-	cs.listen = func(lCtx context.Context, _ string, _ ...revdial.ListenerOption) (net.Listener, error) {
+	cs.listen = func(lCtx context.Context, _ *controlConn, _ ...revdial.ListenerOption) (net.Listener, error) {
 		// Simulate the context being cancelled mid-dial (e.g. user presses Ctrl+C
 		// while the TCP handshake is in flight).
 		cancel()
@@ -182,7 +187,7 @@ func TestRun_FirstConnectFails(t *testing.T) {
 	wantErr := errors.New("dial refused")
 
 	cs := NewClientServer(Config{ServerAddr: "localhost:1", NoTLS: true}, newTestToken(t))
-	cs.listen = func(_ context.Context, _ string, _ ...revdial.ListenerOption) (net.Listener, error) {
+	cs.listen = func(_ context.Context, _ *controlConn, _ ...revdial.ListenerOption) (net.Listener, error) {
 		return nil, wantErr
 	}
 
@@ -202,7 +207,7 @@ func TestRun_ContextCancelledBeforeConnect(t *testing.T) {
 
 	// listen should never be called because ctx is already done.
 	called := atomic.Bool{}
-	cs.listen = func(_ context.Context, _ string, _ ...revdial.ListenerOption) (net.Listener, error) {
+	cs.listen = func(_ context.Context, _ *controlConn, _ ...revdial.ListenerOption) (net.Listener, error) {
 		called.Store(true)
 		return nil, errors.New("should not be called")
 	}
@@ -229,7 +234,7 @@ func TestRun_ReconnectAttemptAfterDisconnect(t *testing.T) {
 	)
 	cs.initialBackoff = time.Millisecond // speed up test
 
-	cs.listen = func(lCtx context.Context, _ string, _ ...revdial.ListenerOption) (net.Listener, error) {
+	cs.listen = func(lCtx context.Context, _ *controlConn, _ ...revdial.ListenerOption) (net.Listener, error) {
 		n := callCount.Add(1)
 
 		switch n {
@@ -277,7 +282,7 @@ func TestRun_NoCallbacksWithoutServerEvent(t *testing.T) {
 	)
 	cs.initialBackoff = time.Millisecond // speed up test
 
-	cs.listen = func(lCtx context.Context, _ string, _ ...revdial.ListenerOption) (net.Listener, error) {
+	cs.listen = func(lCtx context.Context, _ *controlConn, _ ...revdial.ListenerOption) (net.Listener, error) {
 		n := callCount.Add(1)
 
 		if n == 1 {
@@ -318,7 +323,7 @@ func TestRun_SubsequentConnectFailureRetries(t *testing.T) {
 	cs := NewClientServer(Config{ServerAddr: "localhost:1", NoTLS: true}, newTestToken(t))
 	cs.initialBackoff = time.Millisecond // speed up test
 
-	cs.listen = func(lCtx context.Context, _ string, _ ...revdial.ListenerOption) (net.Listener, error) {
+	cs.listen = func(lCtx context.Context, _ *controlConn, _ ...revdial.ListenerOption) (net.Listener, error) {
 		n := callCount.Add(1)
 
 		switch n {
@@ -356,7 +361,7 @@ func TestRun_UnexpectedListenerErrorReconnects(t *testing.T) {
 	cs := NewClientServer(Config{ServerAddr: "localhost:1", NoTLS: true}, newTestToken(t))
 	cs.initialBackoff = time.Millisecond // speed up test
 
-	cs.listen = func(_ context.Context, _ string, _ ...revdial.ListenerOption) (net.Listener, error) {
+	cs.listen = func(_ context.Context, _ *controlConn, _ ...revdial.ListenerOption) (net.Listener, error) {
 		n := callCount.Add(1)
 
 		switch n {
@@ -375,3 +380,353 @@ func TestRun_UnexpectedListenerErrorReconnects(t *testing.T) {
 	require.NoError(t, err)
 	assert.EqualValues(t, 2, callCount.Load(), "Run should reconnect after an unexpected listener error")
 }
+
+// hostLookup returns a lookupHostFunc resolving every host to ips, or to the host itself when ips is empty.
+func hostLookup(ips ...string) lookupHostFunc {
+	return func(_ context.Context, host string) ([]string, error) {
+		if len(ips) == 0 {
+			return []string{host}, nil
+		}
+
+		return ips, nil
+	}
+}
+
+// TestRun_ServerRequestedReconnect verifies that a reconnect requested by the server opens the new
+// control connection before the old one is closed, that the old one is closed once it is idle,
+// and that reconnect requests arriving later on the retired connection are ignored.
+func TestRun_ServerRequestedReconnect(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	oldListener := newFakeListener()
+	newListener := newFakeListener()
+	connected := make(chan *controlConn, 3)
+
+	var addrs []string
+
+	cs := NewClientServer(
+		Config{ServerAddr: "localhost:1", NoTLS: true},
+		newTestToken(t),
+	)
+	cs.lookupHost = hostLookup()
+
+	cs.listen = func(_ context.Context, cc *controlConn, _ ...revdial.ListenerOption) (net.Listener, error) {
+		addrs = append(addrs, cc.dialAddr)
+		connected <- cc
+
+		switch len(addrs) {
+		case 1:
+			return oldListener, nil
+		case 2:
+			select {
+			case <-oldListener.closed:
+				t.Error("old control connection was closed before the new one was opened")
+			default:
+			}
+
+			cc.markReady()
+
+			return newListener, nil
+		default:
+			cancel()
+			return nil, context.Canceled
+		}
+	}
+
+	errCh := make(chan error, 1)
+
+	go func() { errCh <- cs.Run(ctx) }()
+
+	oldCC := <-connected
+	oldCC.requestReconnect("localhost:2")
+
+	select {
+	case <-oldListener.closed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("old control connection was not closed")
+	}
+
+	newCC := <-connected
+	assert.Equal(t, "localhost:2", newCC.addr)
+
+	// A request sent by the retired connection does not move the tunnel again.
+	oldCC.requestReconnect("localhost:3")
+	time.Sleep(50 * time.Millisecond)
+
+	select {
+	case <-newListener.closed:
+		t.Fatal("new control connection was closed")
+	default:
+	}
+
+	cancel()
+
+	require.NoError(t, <-errCh)
+	assert.Equal(t, []string{"localhost:1", "localhost:2"}, addrs)
+}
+
+// TestRun_ServerRequestedReconnectRetries verifies that a failed reconnect requested by the server
+// is retried to the current address while the old control connection keeps serving.
+func TestRun_ServerRequestedReconnectRetries(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	oldListener := newFakeListener()
+	newListener := newFakeListener()
+	connected := make(chan *controlConn, 3)
+	callCount := atomic.Int32{}
+
+	cs := NewClientServer(
+		Config{ServerAddr: "localhost:1", NoTLS: true},
+		newTestToken(t),
+	)
+	cs.initialBackoff = time.Millisecond
+	cs.lookupHost = hostLookup()
+
+	cs.listen = func(_ context.Context, cc *controlConn, _ ...revdial.ListenerOption) (net.Listener, error) {
+		assert.Equal(t, "localhost:1", cc.dialAddr)
+
+		switch callCount.Add(1) {
+		case 1:
+			connected <- cc
+			return oldListener, nil
+		case 2:
+			return nil, errors.New("connection refused")
+		default:
+			cc.markReady()
+			return newListener, nil
+		}
+	}
+
+	errCh := make(chan error, 1)
+
+	go func() { errCh <- cs.Run(ctx) }()
+
+	(<-connected).requestReconnect("")
+
+	select {
+	case <-oldListener.closed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("old control connection was not closed")
+	}
+
+	cancel()
+
+	require.NoError(t, <-errCh)
+	assert.EqualValues(t, 3, callCount.Load())
+}
+
+// TestRun_ServerRequestedReconnectAvoidsDrainingNodes verifies that without a reconnect address the client
+// moves to another node of the server address, skipping the node it leaves and the nodes refusing the tunnel.
+func TestRun_ServerRequestedReconnectAvoidsDrainingNodes(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	oldListener := newFakeListener()
+	oldListener.addr = &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 8081}
+	connected := make(chan *controlConn, 1)
+
+	var (
+		mu    sync.Mutex
+		addrs []string
+	)
+
+	cs := NewClientServer(
+		Config{ServerAddr: "mit.example.com:8081", NoTLS: true},
+		newTestToken(t),
+	)
+	cs.initialBackoff = time.Millisecond
+	cs.lookupHost = hostLookup("10.0.0.1", "10.0.0.2", "10.0.0.3")
+
+	cs.listen = func(_ context.Context, cc *controlConn, _ ...revdial.ListenerOption) (net.Listener, error) {
+		mu.Lock()
+		addrs = append(addrs, cc.dialAddr)
+		mu.Unlock()
+
+		assert.Equal(t, "mit.example.com:8081", cc.addr)
+
+		switch cc.dialAddr {
+		case "mit.example.com:8081":
+			connected <- cc
+			return oldListener, nil
+		case "10.0.0.2:8081":
+			// A draining node asks the client to reconnect instead of publishing the tunnel.
+			cc.requestReconnect("")
+		default:
+			cc.markReady()
+		}
+
+		return newFakeListener(), nil
+	}
+
+	errCh := make(chan error, 1)
+
+	go func() { errCh <- cs.Run(ctx) }()
+
+	(<-connected).requestReconnect("")
+
+	select {
+	case <-oldListener.closed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("old control connection was not closed")
+	}
+
+	cancel()
+
+	require.NoError(t, <-errCh)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	assert.Equal(t, []string{"mit.example.com:8081", "10.0.0.2:8081", "10.0.0.3:8081"}, addrs)
+}
+
+func TestResolveServer(t *testing.T) {
+	cs := NewClientServer(Config{ServerAddr: "mit.example.com:8081"}, newTestToken(t))
+	cs.lookupHost = hostLookup("10.0.0.1", "10.0.0.2")
+
+	assert.Equal(t, "10.0.0.2:8081", cs.resolveServer(t.Context(), "mit.example.com:8081", []string{"10.0.0.1:8081"}))
+	assert.Equal(t, "mit.example.com:8081",
+		cs.resolveServer(t.Context(), "mit.example.com:8081", []string{"10.0.0.1:8081", "10.0.0.2:8081"}))
+
+	cs.lookupHost = func(context.Context, string) ([]string, error) { return nil, assert.AnError }
+
+	assert.Equal(t, "mit.example.com:8081", cs.resolveServer(t.Context(), "mit.example.com:8081", nil))
+}
+
+// TestRetire_WaitsForActiveConnections verifies that a replaced control connection is closed
+// only after the connections it serves are finished.
+func TestRetire_WaitsForActiveConnections(t *testing.T) {
+	cs := NewClientServer(Config{ServerAddr: "localhost:1", NoTLS: true}, newTestToken(t))
+
+	listener := newFakeListener()
+	cc := newControlConn("localhost:1", "localhost:1")
+	cc.listener = listener
+	cc.active.Add(1)
+
+	retired := make(chan struct{})
+
+	go func() {
+		cs.retire(context.Background(), cc, make(chan error))
+		close(retired)
+	}()
+
+	time.Sleep(2 * retirePollInterval)
+
+	select {
+	case <-listener.closed:
+		t.Fatal("control connection was closed while serving a connection")
+	default:
+	}
+
+	cc.active.Add(-1)
+
+	select {
+	case <-retired:
+	case <-time.After(time.Second):
+		t.Fatal("control connection was not retired")
+	}
+
+	select {
+	case <-listener.closed:
+	default:
+		t.Fatal("control connection was not closed")
+	}
+}
+
+// bufferCloser is an io.WriteCloser collecting the data written to it.
+type bufferCloser struct {
+	closed chan struct{}
+	buf    bytes.Buffer
+	mu     sync.Mutex
+}
+
+func (b *bufferCloser) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *bufferCloser) Close() error {
+	close(b.closed)
+	return nil
+}
+
+func (b *bufferCloser) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
+
+type fakeInterceptor struct {
+	toDest   *bufferCloser
+	toSource *bufferCloser
+	clientIP string
+}
+
+func (f *fakeInterceptor) Intercept(_ context.Context, clientIP string) (toDest, toSource io.WriteCloser) {
+	f.clientIP = clientIP
+
+	return f.toDest, f.toSource
+}
+
+func TestHandleConn_Interceptor(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	defer ln.Close()
+
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+
+		defer c.Close()
+
+		buf := make([]byte, 4)
+		if _, err := io.ReadFull(c, buf); err != nil {
+			return
+		}
+
+		_, _ = c.Write([]byte("pong"))
+	}()
+
+	interceptor := &fakeInterceptor{
+		toDest:   &bufferCloser{closed: make(chan struct{})},
+		toSource: &bufferCloser{closed: make(chan struct{})},
+	}
+
+	cs := NewClientServer(Config{DestAddr: ln.Addr().String()}, newTestToken(t), WithInterceptor(interceptor))
+
+	srvSide, cliSide := net.Pipe()
+
+	go cs.handleConn(context.Background(), srvSide)
+
+	require.NoError(t, meta.WriteData(cliSide, &meta.ClientConnMeta{IP: "1.2.3.4"}))
+
+	_, err = cliSide.Write([]byte("ping"))
+	require.NoError(t, err)
+
+	resp := make([]byte, 4)
+	_, err = io.ReadFull(cliSide, resp)
+	require.NoError(t, err)
+	assert.Equal(t, "pong", string(resp))
+
+	_ = cliSide.Close()
+
+	for _, w := range []*bufferCloser{interceptor.toDest, interceptor.toSource} {
+		select {
+		case <-w.closed:
+		case <-time.After(time.Second):
+			t.Fatal("interceptor writers were not closed")
+		}
+	}
+
+	assert.Equal(t, "1.2.3.4", interceptor.clientIP)
+	assert.Equal(t, "ping", interceptor.toDest.String())
+	assert.Equal(t, "pong", interceptor.toSource.String())
+}
@@ -0,0 +1,120 @@
+package revclient
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ksysoev/make-it-public/pkg/core/conn/meta"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newH2CServer starts a local service that only accepts HTTP/2 without TLS.
+func newH2CServer(t *testing.T, handler http.HandlerFunc) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewUnstartedServer(handler)
+	srv.Config.Protocols = new(http.Protocols)
+	srv.Config.Protocols.SetUnencryptedHTTP2(true)
+	srv.Start()
+
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestHandleConn_H2C(t *testing.T) {
+	srv := newH2CServer(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		assert.Equal(t, 2, r.ProtoMajor)
+		assert.Equal(t, "app.example.com", r.Host)
+		assert.Equal(t, "1.2.3.4", r.Header.Get("X-Forwarded-For"))
+
+		w.Header().Set("Trailer", "Grpc-Status")
+		_, _ = w.Write(append([]byte("echo: "), body...))
+		w.Header().Set("Grpc-Status", "0")
+	})
+
+	interceptor := &fakeInterceptor{
+		toDest:   &bufferCloser{closed: make(chan struct{})},
+		toSource: &bufferCloser{closed: make(chan struct{})},
+	}
+
+	cs := NewClientServer(Config{DestAddr: srv.Listener.Addr().String(), H2C: true}, newTestToken(t), WithInterceptor(interceptor))
+
+	srvSide, cliSide := net.Pipe()
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		cs.handleConn(context.Background(), srvSide)
+	}()
+
+	require.NoError(t, meta.WriteData(cliSide, &meta.ClientConnMeta{IP: "1.2.3.4"}))
+
+	req, err := http.NewRequest(http.MethodPost, "http://app.example.com/rpc", strings.NewReader("ping"))
+	require.NoError(t, err)
+
+	req.Header.Set("X-Forwarded-For", "1.2.3.4")
+	req.Close = true
+
+	go func() { _ = req.Write(cliSide) }()
+
+	resp, err := http.ReadResponse(bufio.NewReader(cliSide), req)
+	require.NoError(t, err)
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "echo: ping", string(body))
+	assert.Equal(t, "0", resp.Trailer.Get("Grpc-Status"))
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("connection was not closed after the response")
+	}
+
+	assert.Contains(t, interceptor.toDest.String(), "POST /rpc HTTP/1.1")
+	assert.Contains(t, interceptor.toSource.String(), "echo: ping")
+}
+
+func TestHandleConn_H2C_ServiceUnavailable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	addr := ln.Addr().String()
+	require.NoError(t, ln.Close())
+
+	cs := NewClientServer(Config{DestAddr: addr, H2C: true}, newTestToken(t))
+
+	srvSide, cliSide := net.Pipe()
+
+	go cs.handleConn(context.Background(), srvSide)
+
+	require.NoError(t, meta.WriteData(cliSide, &meta.ClientConnMeta{IP: "1.2.3.4"}))
+
+	req, err := http.NewRequest(http.MethodGet, "http://app.example.com/", http.NoBody)
+	require.NoError(t, err)
+
+	go func() { _ = req.Write(cliSide) }()
+
+	resp, err := http.ReadResponse(bufio.NewReader(cliSide), req)
+	require.NoError(t, err)
+
+	_ = resp.Body.Close()
+	_ = cliSide.Close()
+
+	assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+}
@@ -0,0 +1,47 @@
+package revclient
+
+import (
+	"net/http"
+)
+
+// HeaderRules rewrites the headers of the HTTP requests sent to local services and of their responses.
+// Headers in Remove lists are removed first, then headers in Set lists replace any values of the same name.
+// Host, when set, replaces the Host header of requests, for local services that only accept their own host name.
+type HeaderRules struct {
+	SetRequest     http.Header
+	SetResponse    http.Header
+	Host           string
+	RemoveRequest  []string
+	RemoveResponse []string
+}
+
+// IsZero reports whether the rules leave headers unchanged.
+func (h HeaderRules) IsZero() bool {
+	return h.Host == "" && len(h.SetRequest) == 0 && len(h.SetResponse) == 0 &&
+		len(h.RemoveRequest) == 0 && len(h.RemoveResponse) == 0
+}
+
+// rewriteRequest applies the request rules to r.
+func (h HeaderRules) rewriteRequest(r *http.Request) {
+	rewriteHeader(r.Header, h.RemoveRequest, h.SetRequest)
+
+	if h.Host != "" {
+		r.Host = h.Host
+	}
+}
+
+// rewriteResponse applies the response rules to resp.
+func (h HeaderRules) rewriteResponse(resp *http.Response) {
+	rewriteHeader(resp.Header, h.RemoveResponse, h.SetResponse)
+}
+
+// rewriteHeader removes the headers named in remove from header, then sets the headers of set.
+func rewriteHeader(header http.Header, remove []string, set http.Header) {
+	for _, name := range remove {
+		header.Del(name)
+	}
+
+	for name, values := range set {
+		header[http.CanonicalHeaderKey(name)] = values
+	}
+}
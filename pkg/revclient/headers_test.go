@@ -0,0 +1,56 @@
+package revclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouter_HeaderRules(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "localhost:3000", r.Host)
+		assert.Equal(t, "https", r.Header.Get("X-Forwarded-Proto"))
+		assert.Empty(t, r.Header.Get("X-Forwarded-For"))
+		assert.Empty(t, r.Header.Get("Cookie"))
+		assert.Equal(t, "kept", r.Header.Get("X-Other"))
+
+		w.Header().Set("Server", "dev-server")
+		w.Header().Set("X-Frame-Options", "DENY")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(srv.Close)
+
+	rt := newRouter(Config{
+		DestAddr: srv.Listener.Addr().String(),
+		Headers: HeaderRules{
+			Host:           "localhost:3000",
+			SetRequest:     http.Header{"x-forwarded-proto": {"https"}},
+			RemoveRequest:  []string{"x-forwarded-for", "Cookie"},
+			SetResponse:    http.Header{"X-Frame-Options": {"SAMEORIGIN"}},
+			RemoveResponse: []string{"server"},
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "http://app.example.com/", http.NoBody)
+	req.Header.Set("X-Forwarded-Proto", "http")
+	req.Header.Set("X-Forwarded-For", "1.2.3.4")
+	req.Header.Set("Cookie", "session=secret")
+	req.Header.Set("X-Other", "kept")
+
+	rec := httptest.NewRecorder()
+
+	rt.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Empty(t, rec.Header().Get("Server"))
+	assert.Equal(t, "SAMEORIGIN", rec.Header().Get("X-Frame-Options"))
+}
+
+func TestHeaderRules_IsZero(t *testing.T) {
+	assert.True(t, HeaderRules{}.IsZero())
+	assert.False(t, HeaderRules{Host: "localhost"}.IsZero())
+	assert.False(t, HeaderRules{RemoveResponse: []string{"Server"}}.IsZero())
+	assert.False(t, HeaderRules{SetRequest: http.Header{"X-A": {"1"}}}.IsZero())
+}
@@ -0,0 +1,176 @@
+package revclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"sync"
+	"time"
+
+	"github.com/ksysoev/make-it-public/pkg/target"
+)
+
+// forwardedHeaders are kept on requests sent to the local service, as they are when connections are piped.
+var forwardedHeaders = []string{"Forwarded", "X-Forwarded-For", "X-Forwarded-Host", "X-Forwarded-Proto"}
+
+// newProxy creates a reverse proxy sending the requests of route to its local service at dest with transport.
+// The headers of the requests and of their responses are rewritten by rules.
+// Responses are flushed as they arrive, so that streams such as gRPC server streams are not delayed.
+func newProxy(transport http.RoundTripper, route Route, dest target.Target, rules HeaderRules) *httputil.ReverseProxy {
+	host := dest.Host()
+
+	return &httputil.ReverseProxy{
+		Transport:     transport,
+		FlushInterval: -1,
+		Rewrite: func(r *httputil.ProxyRequest) {
+			r.Out.URL.Scheme = dest.Scheme()
+			r.Out.URL.Host = host
+			r.Out.Host = r.In.Host
+
+			if route.StripPrefix {
+				r.Out.URL.Path = trimPathPrefix(r.Out.URL.Path, route.Path)
+				r.Out.URL.RawPath = trimPathPrefix(r.Out.URL.RawPath, route.Path)
+			}
+
+			for _, name := range forwardedHeaders {
+				if values, ok := r.In.Header[name]; ok {
+					r.Out.Header[name] = values
+				}
+			}
+
+			rules.rewriteRequest(r.Out)
+		},
+		ModifyResponse: func(resp *http.Response) error {
+			// Trailers, such as the status of a gRPC call, can only be sent over HTTP/1.1 with chunked encoding.
+			if len(resp.Trailer) > 0 {
+				resp.Header.Del("Content-Length")
+				resp.ContentLength = -1
+			}
+
+			rules.rewriteResponse(resp)
+
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			slog.ErrorContext(r.Context(), "failed to proxy request", slog.String("dest", route.DestAddr), slog.Any("error", err))
+			w.WriteHeader(http.StatusBadGateway)
+		},
+	}
+}
+
+// serveHTTP serves the HTTP/1.1 requests arriving on conn from the server with the HTTP proxy of the client.
+// It returns once the connection is closed or ctx is done.
+func (s *ClientServer) serveHTTP(ctx context.Context, conn net.Conn, clientIP string) {
+	if s.interceptor != nil {
+		toDest, toSource := s.interceptor.Intercept(ctx, clientIP)
+
+		defer func() {
+			_ = toDest.Close()
+			_ = toSource.Close()
+		}()
+
+		conn = &recordingConn{Conn: conn, toDest: toDest, toSource: toSource}
+	}
+
+	srv := &http.Server{
+		Handler:           s.httpProxy,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	stop := context.AfterFunc(ctx, func() { _ = srv.Close() })
+	defer stop()
+
+	if err := srv.Serve(newSingleConnListener(conn)); err != nil && !errors.Is(err, net.ErrClosed) && !errors.Is(err, http.ErrServerClosed) {
+		slog.DebugContext(ctx, "failed to serve HTTP connection", slog.Any("error", err))
+	}
+}
+
+// recordingConn copies the data read from and written to the wrapped connection to an Interceptor.
+type recordingConn struct {
+	net.Conn
+	toDest   io.Writer
+	toSource io.Writer
+}
+
+func (c *recordingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if n > 0 {
+		_, _ = c.toDest.Write(p[:n])
+	}
+
+	return n, err
+}
+
+func (c *recordingConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	if n > 0 {
+		_, _ = c.toSource.Write(p[:n])
+	}
+
+	return n, err
+}
+
+// singleConnListener is a net.Listener accepting a single connection. Once the connection is accepted,
+// Accept blocks until the connection is closed and then returns net.ErrClosed, which stops the http.Server.
+type singleConnListener struct {
+	conn net.Conn
+	addr net.Addr
+	done chan struct{}
+	once sync.Once
+	mu   sync.Mutex
+}
+
+func newSingleConnListener(conn net.Conn) *singleConnListener {
+	l := &singleConnListener{
+		addr: conn.LocalAddr(),
+		done: make(chan struct{}),
+	}
+
+	l.conn = &notifyCloseConn{Conn: conn, close: l.closeDone}
+
+	return l
+}
+
+func (l *singleConnListener) Accept() (net.Conn, error) {
+	l.mu.Lock()
+	conn := l.conn
+	l.conn = nil
+	l.mu.Unlock()
+
+	if conn != nil {
+		return conn, nil
+	}
+
+	<-l.done
+
+	return nil, net.ErrClosed
+}
+
+func (l *singleConnListener) Close() error {
+	l.closeDone()
+	return nil
+}
+
+func (l *singleConnListener) Addr() net.Addr {
+	return l.addr
+}
+
+func (l *singleConnListener) closeDone() {
+	l.once.Do(func() { close(l.done) })
+}
+
+// notifyCloseConn calls close once the wrapped connection is closed.
+type notifyCloseConn struct {
+	net.Conn
+	close func()
+}
+
+func (c *notifyCloseConn) Close() error {
+	defer c.close()
+
+	return c.Conn.Close()
+}
@@ -0,0 +1,117 @@
+package revclient
+
+import (
+	"cmp"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/ksysoev/make-it-public/pkg/target"
+)
+
+// Route sends the HTTP requests whose path is Path, or is below it, to the local service at DestAddr.
+// With StripPrefix, Path is removed from the path of the requests before they are sent.
+type Route struct {
+	Path        string
+	DestAddr    string
+	StripPrefix bool
+}
+
+// route is a Route with the proxy sending its requests.
+type route struct {
+	proxy http.Handler
+	Route
+}
+
+// router is the HTTP proxy of a web tunnel, sending each request to the local service of
+// the route with the longest path matching the path of the request.
+type router struct {
+	routes []route
+}
+
+// newRouter creates the router of the routes of cfg. Requests no route matches are sent to cfg.DestAddr,
+// if it is set. Requests are sent over HTTP/2 without TLS if cfg.H2C is set, and their headers are
+// rewritten by cfg.Headers. Routes with an invalid address respond with 502 Bad Gateway.
+func newRouter(cfg Config) *router {
+	routes := slices.Clone(cfg.Routes)
+
+	if cfg.DestAddr != "" {
+		routes = append(routes, Route{Path: "/", DestAddr: cfg.DestAddr})
+	}
+
+	rt := &router{routes: make([]route, 0, len(routes))}
+
+	for _, r := range routes {
+		r.Path = cleanRoutePath(r.Path)
+
+		dest, err := target.Parse(r.DestAddr)
+		if err != nil {
+			rt.routes = append(rt.routes, route{Route: r, proxy: badGateway(err)})
+			continue
+		}
+
+		transport := dest.Transport(cfg.UpstreamTLS, cfg.H2C)
+
+		rt.routes = append(rt.routes, route{Route: r, proxy: newProxy(transport, r, dest, cfg.Headers)})
+	}
+
+	// Routes are tried from the longest path, and the stable sort keeps the first of routes with the same path.
+	slices.SortStableFunc(rt.routes, func(a, b route) int {
+		return cmp.Compare(len(b.Path), len(a.Path))
+	})
+
+	return rt
+}
+
+// ServeHTTP sends r to the local service of its route, or responds with 404 Not Found if no route matches its path.
+func (rt *router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	for _, route := range rt.routes {
+		if matchesPathPrefix(r.URL.Path, route.Path) {
+			route.proxy.ServeHTTP(w, r)
+			return
+		}
+	}
+
+	http.Error(w, "no route for "+r.URL.Path, http.StatusNotFound)
+}
+
+// badGateway returns a handler responding with 502 Bad Gateway to requests for a route whose local service
+// cannot be reached because of err.
+func badGateway(err error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		slog.ErrorContext(r.Context(), "failed to proxy request", slog.Any("error", err))
+		w.WriteHeader(http.StatusBadGateway)
+	})
+}
+
+// cleanRoutePath returns path with a leading slash and without trailing slashes.
+func cleanRoutePath(path string) string {
+	return "/" + strings.Trim(path, "/")
+}
+
+// matchesPathPrefix reports whether path is prefix or is below it, so that /api matches /api/users but not /apis.
+func matchesPathPrefix(path, prefix string) bool {
+	if prefix == "/" {
+		return true
+	}
+
+	rest, ok := strings.CutPrefix(path, prefix)
+
+	return ok && (rest == "" || rest[0] == '/')
+}
+
+// trimPathPrefix removes prefix from path, keeping the leading slash of the result.
+// Empty paths, such as the raw path of requests without escaped characters, are left empty.
+func trimPathPrefix(path, prefix string) string {
+	if path == "" || prefix == "/" {
+		return path
+	}
+
+	rest := strings.TrimPrefix(path, prefix)
+	if rest == "" || rest[0] != '/' {
+		rest = "/" + rest
+	}
+
+	return rest
+}
@@ -0,0 +1,175 @@
+package revclient
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ksysoev/make-it-public/pkg/core/conn/meta"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newEchoPathServer starts a local service responding with its name and the path of the request.
+func newEchoPathServer(t *testing.T, name string) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, name+" "+r.URL.RequestURI())
+	}))
+
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestRouter(t *testing.T) {
+	api := newEchoPathServer(t, "api")
+	admin := newEchoPathServer(t, "admin")
+	frontend := newEchoPathServer(t, "frontend")
+
+	rt := newRouter(Config{
+		DestAddr: frontend.Listener.Addr().String(),
+		Routes: []Route{
+			{Path: "/api/", DestAddr: api.Listener.Addr().String(), StripPrefix: true},
+			{Path: "/api/admin", DestAddr: admin.Listener.Addr().String()},
+		},
+	})
+
+	tests := []struct {
+		path     string
+		wantBody string
+	}{
+		{path: "/api/users?page=2", wantBody: "api /users?page=2"},
+		{path: "/api", wantBody: "api /"},
+		{path: "/api/admin/settings", wantBody: "admin /api/admin/settings"},
+		{path: "/apis", wantBody: "frontend /apis"},
+		{path: "/", wantBody: "frontend /"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+
+			rt.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, http.NoBody))
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, tt.wantBody, rec.Body.String())
+		})
+	}
+}
+
+func TestHandleConn_Routes(t *testing.T) {
+	api := newEchoPathServer(t, "api")
+	frontend := newEchoPathServer(t, "frontend")
+
+	cs := NewClientServer(Config{
+		DestAddr: frontend.Listener.Addr().String(),
+		Routes:   []Route{{Path: "/api", DestAddr: api.Listener.Addr().String(), StripPrefix: true}},
+	}, newTestToken(t))
+
+	srvSide, cliSide := net.Pipe()
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		cs.handleConn(context.Background(), srvSide)
+	}()
+
+	require.NoError(t, meta.WriteData(cliSide, &meta.ClientConnMeta{IP: "1.2.3.4"}))
+
+	// Requests of a kept-alive connection are routed one by one.
+	br := bufio.NewReader(cliSide)
+
+	for _, tt := range []struct{ path, wantBody string }{
+		{path: "/api/users", wantBody: "api /users"},
+		{path: "/index.html", wantBody: "frontend /index.html"},
+	} {
+		req, err := http.NewRequest(http.MethodGet, "http://app.example.com"+tt.path, http.NoBody)
+		require.NoError(t, err)
+
+		go func() { _ = req.Write(cliSide) }()
+
+		resp, err := http.ReadResponse(br, req)
+		require.NoError(t, err)
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+
+		assert.Equal(t, tt.wantBody, string(body))
+	}
+
+	_ = cliSide.Close()
+
+	<-done
+}
+
+func TestRouter_NoRoute(t *testing.T) {
+	api := newEchoPathServer(t, "api")
+
+	rt := newRouter(Config{Routes: []Route{{Path: "/api", DestAddr: api.Listener.Addr().String()}}})
+
+	rec := httptest.NewRecorder()
+
+	rt.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/static/app.js", http.NoBody))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestRouter_UnreachableService(t *testing.T) {
+	rt := newRouter(Config{Routes: []Route{{Path: "/", DestAddr: "127.0.0.1:1"}}})
+
+	rec := httptest.NewRecorder()
+
+	rt.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+
+	assert.Equal(t, http.StatusBadGateway, rec.Code)
+}
+
+func TestMatchesPathPrefix(t *testing.T) {
+	tests := []struct {
+		path   string
+		prefix string
+		want   bool
+	}{
+		{path: "/anything", prefix: "/", want: true},
+		{path: "/api", prefix: "/api", want: true},
+		{path: "/api/users", prefix: "/api", want: true},
+		{path: "/apis", prefix: "/api", want: false},
+		{path: "/", prefix: "/api", want: false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, matchesPathPrefix(tt.path, tt.prefix), "%s with prefix %s", tt.path, tt.prefix)
+	}
+}
+
+func TestTrimPathPrefix(t *testing.T) {
+	tests := []struct {
+		path   string
+		prefix string
+		want   string
+	}{
+		{path: "/api/users", prefix: "/api", want: "/users"},
+		{path: "/api", prefix: "/api", want: "/"},
+		{path: "/users", prefix: "/", want: "/users"},
+		{path: "", prefix: "/api", want: ""},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, trimPathPrefix(tt.path, tt.prefix), "%s with prefix %s", tt.path, tt.prefix)
+	}
+}
+
+func TestCleanRoutePath(t *testing.T) {
+	require.Equal(t, "/", cleanRoutePath(""))
+	require.Equal(t, "/", cleanRoutePath("/"))
+	require.Equal(t, "/api", cleanRoutePath("api/"))
+	require.Equal(t, "/api/v1", cleanRoutePath("/api/v1/"))
+}
@@ -0,0 +1,189 @@
+package revclient
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/ksysoev/make-it-public/pkg/core/conn/meta"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTLSServer starts a local HTTPS service responding with the protocol and path of the request,
+// and returns it with a TLS config trusting its certificate.
+func newTLSServer(t *testing.T) (*httptest.Server, *tls.Config) {
+	t.Helper()
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "tls "+r.URL.Path)
+	}))
+	t.Cleanup(srv.Close)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+
+	return srv, &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+}
+
+// newUnixServer starts a local HTTP service listening on a Unix domain socket, responding with the path
+// of the request, and returns its address.
+func newUnixServer(t *testing.T) string {
+	t.Helper()
+
+	sock := filepath.Join(t.TempDir(), "app.sock")
+
+	l, err := net.Listen("unix", sock)
+	require.NoError(t, err)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "unix "+r.URL.Path)
+	}))
+	srv.Listener = l
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	return "unix://" + sock
+}
+
+func TestHandleConn_TLSTarget(t *testing.T) {
+	srv, tlsConfig := newTLSServer(t)
+
+	cs := NewClientServer(Config{DestAddr: "https://" + srv.Listener.Addr().String(), UpstreamTLS: tlsConfig}, newTestToken(t))
+
+	srvSide, cliSide := net.Pipe()
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		cs.handleConn(context.Background(), srvSide)
+	}()
+
+	require.NoError(t, meta.WriteData(cliSide, &meta.ClientConnMeta{IP: "1.2.3.4"}))
+
+	req, err := http.NewRequest(http.MethodGet, "http://app.example.com/health", http.NoBody)
+	require.NoError(t, err)
+
+	req.Close = true
+
+	go func() { _ = req.Write(cliSide) }()
+
+	resp, err := http.ReadResponse(bufio.NewReader(cliSide), req)
+	require.NoError(t, err)
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	assert.Equal(t, "tls /health", string(body))
+
+	_ = cliSide.Close()
+
+	<-done
+}
+
+func TestHandleConn_TLSTargetUntrusted(t *testing.T) {
+	srv, _ := newTLSServer(t)
+
+	cs := NewClientServer(Config{DestAddr: "https://" + srv.Listener.Addr().String()}, newTestToken(t))
+
+	srvSide, cliSide := net.Pipe()
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		cs.handleConn(context.Background(), srvSide)
+	}()
+
+	require.NoError(t, meta.WriteData(cliSide, &meta.ClientConnMeta{IP: "1.2.3.4"}))
+
+	// The certificate of the service is not trusted, so the connection is closed without a response.
+	_, err := io.ReadAll(cliSide)
+	assert.NoError(t, err)
+
+	<-done
+}
+
+func TestRouter_TLSTarget(t *testing.T) {
+	srv, tlsConfig := newTLSServer(t)
+
+	rt := newRouter(Config{
+		Routes: []Route{
+			{Path: "/api", DestAddr: "https://" + srv.Listener.Addr().String(), StripPrefix: true},
+			{Path: "/broken", DestAddr: "ftp://localhost"},
+		},
+		UpstreamTLS: tlsConfig,
+	})
+
+	rec := httptest.NewRecorder()
+
+	rt.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/users", http.NoBody))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "tls /users", rec.Body.String())
+
+	rec = httptest.NewRecorder()
+
+	rt.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/broken", http.NoBody))
+
+	assert.Equal(t, http.StatusBadGateway, rec.Code)
+}
+
+func TestHandleConn_UnixTarget(t *testing.T) {
+	cs := NewClientServer(Config{DestAddr: newUnixServer(t)}, newTestToken(t))
+
+	srvSide, cliSide := net.Pipe()
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		cs.handleConn(context.Background(), srvSide)
+	}()
+
+	require.NoError(t, meta.WriteData(cliSide, &meta.ClientConnMeta{IP: "1.2.3.4"}))
+
+	req, err := http.NewRequest(http.MethodGet, "http://app.example.com/health", http.NoBody)
+	require.NoError(t, err)
+
+	req.Close = true
+
+	go func() { _ = req.Write(cliSide) }()
+
+	resp, err := http.ReadResponse(bufio.NewReader(cliSide), req)
+	require.NoError(t, err)
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	assert.Equal(t, "unix /health", string(body))
+
+	_ = cliSide.Close()
+
+	<-done
+}
+
+func TestRouter_UnixTarget(t *testing.T) {
+	rt := newRouter(Config{
+		Routes:  []Route{{Path: "/docker", DestAddr: newUnixServer(t), StripPrefix: true}},
+		Headers: HeaderRules{Host: "docker"},
+	})
+
+	rec := httptest.NewRecorder()
+
+	rt.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/docker/containers/json", http.NoBody))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "unix /containers/json", rec.Body.String())
+}
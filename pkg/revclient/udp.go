@@ -0,0 +1,80 @@
+package revclient
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/ksysoev/make-it-public/pkg/core/conn/meta"
+)
+
+// relayUDP relays the datagrams of a UDP session arriving on conn, framed with meta.WriteDatagram,
+// to the local service at DestAddr, and frames the datagrams the service sends back in the same way.
+// Every session gets its own local socket, so that the service sees each visitor as a separate peer.
+// It returns once conn is closed or ctx is done.
+func (s *ClientServer) relayUDP(ctx context.Context, conn net.Conn) {
+	d := net.Dialer{
+		Timeout: 5 * time.Second,
+	}
+
+	dConn, err := d.DialContext(ctx, "udp", s.cfg.DestAddr)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to dial", "err", err)
+		return
+	}
+
+	closeBoth := func() {
+		_ = conn.Close()
+		_ = dConn.Close()
+	}
+
+	defer closeBoth()
+
+	stop := context.AfterFunc(ctx, closeBoth)
+	defer stop()
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		defer closeBoth()
+
+		buf := make([]byte, meta.MaxDatagramSize)
+
+		for {
+			n, err := dConn.Read(buf)
+
+			switch {
+			case errors.Is(err, syscall.ECONNREFUSED):
+				// The service is not listening yet; the visitor may retry.
+				continue
+			case err != nil:
+				return
+			}
+
+			if err := meta.WriteDatagram(conn, buf[:n]); err != nil {
+				return
+			}
+		}
+	}()
+
+	buf := make([]byte, meta.MaxDatagramSize)
+
+	for {
+		datagram, err := meta.ReadDatagram(conn, buf)
+		if err != nil {
+			break
+		}
+
+		if _, err := dConn.Write(datagram); err != nil && !errors.Is(err, syscall.ECONNREFUSED) {
+			slog.DebugContext(ctx, "failed to send UDP datagram", slog.Any("error", err))
+			break
+		}
+	}
+
+	closeBoth()
+	<-done
+}
@@ -0,0 +1,89 @@
+package revclient
+
+import (
+	"context"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ksysoev/make-it-public/pkg/core/conn/meta"
+	"github.com/ksysoev/make-it-public/pkg/core/token"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleConn_UDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	defer pc.Close()
+
+	go func() {
+		buf := make([]byte, 1024)
+
+		for {
+			n, addr, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+
+			_, _ = pc.WriteTo(append([]byte("echo:"), buf[:n]...), addr)
+		}
+	}()
+
+	tkn, err := token.GenerateToken("testkey", 3600, token.TokenTypeUDP)
+	require.NoError(t, err)
+
+	cs := NewClientServer(Config{DestAddr: pc.LocalAddr().String()}, tkn)
+
+	srvSide, cliSide := net.Pipe()
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		cs.handleConn(context.Background(), srvSide)
+	}()
+
+	require.NoError(t, meta.WriteData(cliSide, &meta.ClientConnMeta{IP: "1.2.3.4"}))
+
+	buf := make([]byte, meta.MaxDatagramSize)
+
+	// Datagram boundaries are kept in both directions.
+	for _, msg := range []string{"ping", "", "pong"} {
+		require.NoError(t, meta.WriteDatagram(cliSide, []byte(msg)))
+
+		datagram, err := meta.ReadDatagram(cliSide, buf)
+		require.NoError(t, err)
+		assert.Equal(t, "echo:"+msg, string(datagram))
+	}
+
+	_ = cliSide.Close()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("UDP relay did not stop after the connection was closed")
+	}
+}
+
+func TestHandleConn_UDPDialFailure(t *testing.T) {
+	tkn, err := token.GenerateToken("testkey", 3600, token.TokenTypeUDP)
+	require.NoError(t, err)
+
+	cs := NewClientServer(Config{DestAddr: "invalid-address"}, tkn)
+
+	srvSide, cliSide := net.Pipe()
+
+	go cs.handleConn(context.Background(), srvSide)
+
+	require.NoError(t, meta.WriteData(cliSide, &meta.ClientConnMeta{IP: "1.2.3.4"}))
+
+	require.NoError(t, cliSide.SetReadDeadline(time.Now().Add(time.Second)))
+
+	_, err = cliSide.Read(make([]byte, 1))
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, os.ErrDeadlineExceeded)
+}
@@ -0,0 +1,206 @@
+// Package target describes the local services that the client forwards the connections of tunnels to,
+// and the local servers it starts for testing.
+package target
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DialTimeout bounds how long connecting to a local service, including its TLS handshake, may take.
+const DialTimeout = 5 * time.Second
+
+// Target is the address of a local service: a host:port for the tcp network, or the path of a socket
+// for the unix network. TLS is set for services reached over TLS, which the client sets up itself.
+type Target struct {
+	Network string
+	Address string
+	TLS     bool
+}
+
+// Parse parses the address of a local service: host:port for a plain TCP service, an http:// or https:// URL
+// without a path, or a unix:// URL with the path of a Unix domain socket, such as unix:///var/run/app.sock.
+// URLs without a port get the default port of their scheme.
+// Returns an error if addr has an unsupported scheme, or a path for http:// and https:// URLs.
+func Parse(addr string) (Target, error) {
+	if !strings.Contains(addr, "://") {
+		if addr == "" {
+			return Target{}, fmt.Errorf("empty address")
+		}
+
+		return Target{Network: "tcp", Address: addr}, nil
+	}
+
+	u, err := url.Parse(addr)
+	if err != nil {
+		return Target{}, fmt.Errorf("invalid address %s: %w", addr, err)
+	}
+
+	if u.RawQuery != "" {
+		return Target{}, fmt.Errorf("invalid address %s: queries are not supported", addr)
+	}
+
+	if u.Scheme == "unix" {
+		// unix:///var/run/app.sock has an empty host, while the relative unix://./app.sock has the host "."
+		path := u.Host + u.Path
+		if path == "" {
+			return Target{}, fmt.Errorf("invalid address %s: missing socket path", addr)
+		}
+
+		return Target{Network: "unix", Address: path}, nil
+	}
+
+	if u.Path != "" && u.Path != "/" {
+		return Target{}, fmt.Errorf("invalid address %s: paths are not supported", addr)
+	}
+
+	var port string
+
+	switch u.Scheme {
+	case "http":
+		port = "80"
+	case "https":
+		port = "443"
+	default:
+		return Target{}, fmt.Errorf("invalid address %s: unsupported scheme %q", addr, u.Scheme)
+	}
+
+	if u.Hostname() == "" {
+		return Target{}, fmt.Errorf("invalid address %s: missing host", addr)
+	}
+
+	if u.Port() != "" {
+		port = u.Port()
+	}
+
+	return Target{Network: "tcp", Address: net.JoinHostPort(u.Hostname(), port), TLS: u.Scheme == "https"}, nil
+}
+
+// FromAddr returns the target of the local address addr, such as the address of a listener.
+func FromAddr(addr net.Addr) Target {
+	return Target{Network: addr.Network(), Address: addr.String()}
+}
+
+// String returns the target in the form accepted by Parse.
+func (t Target) String() string {
+	switch {
+	case t.Network == "unix":
+		return "unix://" + t.Address
+	case t.TLS:
+		return "https://" + t.Address
+	default:
+		return t.Address
+	}
+}
+
+// Scheme returns the URL scheme of HTTP requests sent to the target.
+func (t Target) Scheme() string {
+	if t.TLS {
+		return "https"
+	}
+
+	return "http"
+}
+
+// Host returns the host of the URLs of HTTP requests sent to the target. It is a placeholder for Unix domain
+// sockets, as the connections of their requests are made by the transport returned by Transport.
+func (t Target) Host() string {
+	if t.Network == "unix" {
+		return "localhost"
+	}
+
+	return t.Address
+}
+
+// Transport creates the transport of HTTP requests sent to the target.
+// With h2c, requests are sent over HTTP/2 without TLS, otherwise over HTTP/1.1. Requests to TLS targets
+// are sent over TLS sessions set up with a copy of tlsConfig.
+func (t Target) Transport(tlsConfig *tls.Config, h2c bool) *http.Transport {
+	transport := &http.Transport{
+		// Connections always go to the target, as the host of request URLs is a placeholder for Unix domain sockets
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return t.DialNetwork(ctx)
+		},
+	}
+
+	if t.TLS {
+		transport.TLSClientConfig = t.TLSConfig(tlsConfig, nil)
+		transport.TLSHandshakeTimeout = DialTimeout
+	}
+
+	if h2c {
+		transport.Protocols = new(http.Protocols)
+		transport.Protocols.SetUnencryptedHTTP2(true)
+	}
+
+	return transport
+}
+
+// DialNetwork connects to the network address of the target, without setting up TLS.
+func (t Target) DialNetwork(ctx context.Context) (net.Conn, error) {
+	d := net.Dialer{Timeout: DialTimeout}
+
+	return d.DialContext(ctx, t.Network, t.Address)
+}
+
+// Dial connects to the target. For TLS targets, the TLS handshake is completed with a copy of tlsConfig
+// offering the ALPN protocols nextProtos. The server name defaults to the host of the target.
+func (t Target) Dial(ctx context.Context, tlsConfig *tls.Config, nextProtos []string) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(ctx, DialTimeout)
+	defer cancel()
+
+	conn, err := t.DialNetwork(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !t.TLS {
+		return conn, nil
+	}
+
+	tlsConn := tls.Client(conn, t.TLSConfig(tlsConfig, nextProtos))
+
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("TLS handshake with %s failed: %w", t.Address, err)
+	}
+
+	return tlsConn, nil
+}
+
+// TLSConfig returns a copy of base for the TLS sessions to the target, offering the ALPN protocols nextProtos.
+func (t Target) TLSConfig(base *tls.Config, nextProtos []string) *tls.Config {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if base != nil {
+		cfg = base.Clone()
+	}
+
+	if cfg.ServerName == "" {
+		cfg.ServerName, _, _ = net.SplitHostPort(t.Address)
+	}
+
+	if nextProtos != nil {
+		cfg.NextProtos = nextProtos
+	}
+
+	return cfg
+}
+
+// Listen starts listening on the target, so that a local server can be reached at it.
+// The socket file of unix targets is removed once the listener is closed.
+// Returns an error for TLS targets, as local servers serve plain connections only.
+func (t Target) Listen(ctx context.Context) (net.Listener, error) {
+	if t.TLS {
+		return nil, fmt.Errorf("cannot listen on %s: TLS is not supported", t)
+	}
+
+	var lc net.ListenConfig
+
+	return lc.Listen(ctx, t.Network, t.Address)
+}
@@ -0,0 +1,118 @@
+package target
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		addr    string
+		wantErr string
+		want    Target
+	}{
+		{addr: "localhost:8080", want: Target{Network: "tcp", Address: "localhost:8080"}},
+		{addr: "http://localhost:8080", want: Target{Network: "tcp", Address: "localhost:8080"}},
+		{addr: "http://localhost", want: Target{Network: "tcp", Address: "localhost:80"}},
+		{addr: "https://localhost:8443/", want: Target{Network: "tcp", Address: "localhost:8443", TLS: true}},
+		{addr: "https://[::1]", want: Target{Network: "tcp", Address: "[::1]:443", TLS: true}},
+		{addr: "unix:///var/run/docker.sock", want: Target{Network: "unix", Address: "/var/run/docker.sock"}},
+		{addr: "unix://./app.sock", want: Target{Network: "unix", Address: "./app.sock"}},
+		{addr: "", wantErr: "empty address"},
+		{addr: "https://localhost:8443/api", wantErr: "paths are not supported"},
+		{addr: "http://localhost:8080?debug=1", wantErr: "queries are not supported"},
+		{addr: "ftp://localhost", wantErr: `unsupported scheme "ftp"`},
+		{addr: "https://:8443", wantErr: "missing host"},
+		{addr: "unix://", wantErr: "missing socket path"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			got, err := Parse(tt.addr)
+
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestTarget_String(t *testing.T) {
+	for _, addr := range []string{"localhost:8080", "https://localhost:8443", "unix:///var/run/docker.sock"} {
+		target, err := Parse(addr)
+		require.NoError(t, err)
+
+		assert.Equal(t, addr, target.String())
+	}
+}
+
+func TestTarget_Host(t *testing.T) {
+	assert.Equal(t, "localhost:8080", Target{Network: "tcp", Address: "localhost:8080"}.Host())
+	assert.Equal(t, "localhost", Target{Network: "unix", Address: "/var/run/docker.sock"}.Host())
+}
+
+func TestTarget_TLSConfig(t *testing.T) {
+	target := Target{Network: "tcp", Address: "localhost:8443", TLS: true}
+
+	cfg := target.TLSConfig(nil, []string{"h2"})
+	assert.Equal(t, "localhost", cfg.ServerName)
+	assert.Equal(t, []string{"h2"}, cfg.NextProtos)
+
+	base := &tls.Config{ServerName: "api.internal", MinVersion: tls.VersionTLS13}
+
+	cfg = target.TLSConfig(base, nil)
+	assert.Equal(t, "api.internal", cfg.ServerName)
+	assert.Equal(t, uint16(tls.VersionTLS13), cfg.MinVersion)
+	assert.NotSame(t, base, cfg)
+}
+
+func TestTarget_ListenAndDial(t *testing.T) {
+	for _, addr := range []string{"localhost:0", "unix://" + filepath.Join(t.TempDir(), "app.sock")} {
+		t.Run(addr, func(t *testing.T) {
+			target, err := Parse(addr)
+			require.NoError(t, err)
+
+			l, err := target.Listen(context.Background())
+			require.NoError(t, err)
+
+			defer func() { _ = l.Close() }()
+
+			go func() {
+				conn, err := l.Accept()
+				if err != nil {
+					return
+				}
+
+				_, _ = io.WriteString(conn, "hello")
+				_ = conn.Close()
+			}()
+
+			listening := FromAddr(l.Addr())
+			assert.Equal(t, target.Network, listening.Network)
+
+			conn, err := listening.Dial(context.Background(), nil, nil)
+			require.NoError(t, err)
+
+			defer func() { _ = conn.Close() }()
+
+			data, err := io.ReadAll(conn)
+			require.NoError(t, err)
+			assert.Equal(t, "hello", string(data))
+		})
+	}
+}
+
+func TestTarget_ListenTLS(t *testing.T) {
+	_, err := Target{Network: "tcp", Address: "localhost:8443", TLS: true}.Listen(context.Background())
+	assert.ErrorContains(t, err, "TLS is not supported")
+}
@@ -26,6 +26,101 @@ func (_m *MockConnService) EXPECT() *MockConnService_Expecter {
 	return &MockConnService_Expecter{mock: &_m.Mock}
 }
 
+// CheckClientIP provides a mock function with given fields: ctx, keyID, clientIP
+func (_m *MockConnService) CheckClientIP(ctx context.Context, keyID string, clientIP string) error {
+	ret := _m.Called(ctx, keyID, clientIP)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CheckClientIP")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, keyID, clientIP)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockConnService_CheckClientIP_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CheckClientIP'
+type MockConnService_CheckClientIP_Call struct {
+	*mock.Call
+}
+
+// CheckClientIP is a helper method to define mock.On call
+//   - ctx context.Context
+//   - keyID string
+//   - clientIP string
+func (_e *MockConnService_Expecter) CheckClientIP(ctx interface{}, keyID interface{}, clientIP interface{}) *MockConnService_CheckClientIP_Call {
+	return &MockConnService_CheckClientIP_Call{Call: _e.mock.On("CheckClientIP", ctx, keyID, clientIP)}
+}
+
+func (_c *MockConnService_CheckClientIP_Call) Run(run func(ctx context.Context, keyID string, clientIP string)) *MockConnService_CheckClientIP_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *MockConnService_CheckClientIP_Call) Return(_a0 error) *MockConnService_CheckClientIP_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockConnService_CheckClientIP_Call) RunAndReturn(run func(context.Context, string, string) error) *MockConnService_CheckClientIP_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Draining provides a mock function with no fields
+func (_m *MockConnService) Draining() <-chan struct{} {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for Draining")
+	}
+
+	var r0 <-chan struct{}
+	if rf, ok := ret.Get(0).(func() <-chan struct{}); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(<-chan struct{})
+		}
+	}
+
+	return r0
+}
+
+// MockConnService_Draining_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Draining'
+type MockConnService_Draining_Call struct {
+	*mock.Call
+}
+
+// Draining is a helper method to define mock.On call
+func (_e *MockConnService_Expecter) Draining() *MockConnService_Draining_Call {
+	return &MockConnService_Draining_Call{Call: _e.mock.On("Draining")}
+}
+
+func (_c *MockConnService_Draining_Call) Run(run func()) *MockConnService_Draining_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockConnService_Draining_Call) Return(_a0 <-chan struct{}) *MockConnService_Draining_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockConnService_Draining_Call) RunAndReturn(run func() <-chan struct{}) *MockConnService_Draining_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // HandleTCPConnection provides a mock function with given fields: ctx, keyID, conn, clientIP
 func (_m *MockConnService) HandleTCPConnection(ctx context.Context, keyID string, conn net.Conn, clientIP string) error {
 	ret := _m.Called(ctx, keyID, conn, clientIP)
@@ -108,6 +203,63 @@ func (_c *MockConnService_SetTCPEndpointAllocator_Call) RunAndReturn(run func(co
 	return _c
 }
 
+// TCPPortOwner provides a mock function with given fields: ctx, port
+func (_m *MockConnService) TCPPortOwner(ctx context.Context, port int) (string, error) {
+	ret := _m.Called(ctx, port)
+
+	if len(ret) == 0 {
+		panic("no return value specified for TCPPortOwner")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) (string, error)); ok {
+		return rf(ctx, port)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) string); ok {
+		r0 = rf(ctx, port)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, port)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockConnService_TCPPortOwner_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'TCPPortOwner'
+type MockConnService_TCPPortOwner_Call struct {
+	*mock.Call
+}
+
+// TCPPortOwner is a helper method to define mock.On call
+//   - ctx context.Context
+//   - port int
+func (_e *MockConnService_Expecter) TCPPortOwner(ctx interface{}, port interface{}) *MockConnService_TCPPortOwner_Call {
+	return &MockConnService_TCPPortOwner_Call{Call: _e.mock.On("TCPPortOwner", ctx, port)}
+}
+
+func (_c *MockConnService_TCPPortOwner_Call) Run(run func(ctx context.Context, port int)) *MockConnService_TCPPortOwner_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int))
+	})
+	return _c
+}
+
+func (_c *MockConnService_TCPPortOwner_Call) Return(_a0 string, _a1 error) *MockConnService_TCPPortOwner_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockConnService_TCPPortOwner_Call) RunAndReturn(run func(context.Context, int) (string, error)) *MockConnService_TCPPortOwner_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // NewMockConnService creates a new instance of MockConnService. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewMockConnService(t interface {
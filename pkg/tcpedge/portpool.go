@@ -2,8 +2,12 @@ package tcpedge
 
 import (
 	"errors"
+	"fmt"
 	"math/rand/v2"
 	"sync"
+
+	"github.com/ksysoev/make-it-public/pkg/core"
+	"github.com/ksysoev/make-it-public/pkg/metrics"
 )
 
 // ErrPortPoolExhausted is returned when no ports are available in the configured range.
@@ -20,11 +24,16 @@ type portPool struct {
 
 // newPortPool creates a portPool for the inclusive range [minPort, maxPort].
 func newPortPool(minPort, maxPort int) *portPool {
-	return &portPool{
+	p := &portPool{
 		min:  minPort,
 		max:  maxPort,
 		used: make(map[int]struct{}),
 	}
+
+	metrics.TCPPortsTotal.Set(float64(maxPort - minPort + 1))
+	p.reportUsage()
+
+	return p
 }
 
 // Allocate picks a random available port from the pool.
@@ -45,6 +54,8 @@ func (p *portPool) Allocate() (int, error) {
 		port := p.min + rand.IntN(size) //nolint:gosec // non-cryptographic port selection is intentional
 		if _, inUse := p.used[port]; !inUse {
 			p.used[port] = struct{}{}
+			p.reportUsage()
+
 			return port, nil
 		}
 	}
@@ -53,6 +64,8 @@ func (p *portPool) Allocate() (int, error) {
 	for port := p.min; port <= p.max; port++ {
 		if _, inUse := p.used[port]; !inUse {
 			p.used[port] = struct{}{}
+			p.reportUsage()
+
 			return port, nil
 		}
 	}
@@ -61,12 +74,33 @@ func (p *portPool) Allocate() (int, error) {
 	panic("portPool: internal invariant violated — no free port found despite available count > 0")
 }
 
+// AllocatePort marks the given port as used.
+// It returns core.ErrPortUnavailable if the port is outside of the range or already in use.
+func (p *portPool) AllocatePort(port int) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if port < p.min || port > p.max {
+		return fmt.Errorf("port %d is outside of range %d-%d: %w", port, p.min, p.max, core.ErrPortUnavailable)
+	}
+
+	if _, inUse := p.used[port]; inUse {
+		return fmt.Errorf("port %d is in use: %w", port, core.ErrPortUnavailable)
+	}
+
+	p.used[port] = struct{}{}
+	p.reportUsage()
+
+	return nil
+}
+
 // Release returns a port to the pool so it can be reused.
 func (p *portPool) Release(port int) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
 	delete(p.used, port)
+	p.reportUsage()
 }
 
 // Available returns the number of unallocated ports remaining in the pool.
@@ -76,3 +110,9 @@ func (p *portPool) Available() int {
 
 	return (p.max - p.min + 1) - len(p.used)
 }
+
+// reportUsage publishes the number of unallocated ports to metrics.TCPPortsAvailable.
+// It must be called with p.mu held or before the pool is shared.
+func (p *portPool) reportUsage() {
+	metrics.TCPPortsAvailable.Set(float64((p.max - p.min + 1) - len(p.used)))
+}
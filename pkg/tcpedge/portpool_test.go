@@ -4,6 +4,9 @@ import (
 	"sync"
 	"testing"
 
+	"github.com/ksysoev/make-it-public/pkg/core"
+	"github.com/ksysoev/make-it-public/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -111,9 +114,39 @@ func TestPortPool_ConcurrentAllocations(t *testing.T) {
 	wg.Wait()
 }
 
+func TestPortPool_AllocatePort(t *testing.T) {
+	p := newPortPool(10000, 10004)
+
+	require.NoError(t, p.AllocatePort(10002))
+	assert.Equal(t, 4, p.Available())
+
+	assert.ErrorIs(t, p.AllocatePort(10002), core.ErrPortUnavailable)
+	assert.ErrorIs(t, p.AllocatePort(9999), core.ErrPortUnavailable)
+	assert.ErrorIs(t, p.AllocatePort(10005), core.ErrPortUnavailable)
+
+	p.Release(10002)
+	assert.Equal(t, 5, p.Available())
+}
+
 func TestPortPool_ReleaseUnknownPort(t *testing.T) {
 	// Releasing a port that was never allocated should not panic.
 	p := newPortPool(10000, 10009)
 
 	assert.NotPanics(t, func() { p.Release(9999) })
 }
+
+func TestPortPool_ReportsAvailablePorts(t *testing.T) {
+	p := newPortPool(10000, 10004) // 5 ports
+
+	assert.InDelta(t, 5, testutil.ToFloat64(metrics.TCPPortsTotal), 0)
+	assert.InDelta(t, 5, testutil.ToFloat64(metrics.TCPPortsAvailable), 0)
+
+	port, err := p.Allocate()
+	require.NoError(t, err)
+
+	assert.InDelta(t, 4, testutil.ToFloat64(metrics.TCPPortsAvailable), 0)
+
+	p.Release(port)
+
+	assert.InDelta(t, 5, testutil.ToFloat64(metrics.TCPPortsAvailable), 0)
+}
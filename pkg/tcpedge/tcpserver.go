@@ -13,17 +13,20 @@ import (
 	"github.com/ksysoev/make-it-public/pkg/core"
 )
 
-// ErrKeyIDAlreadyAllocated is returned when Allocate is called for a keyID that
-// already has an active listener.
-var ErrKeyIDAlreadyAllocated = errors.New("keyID already has an active TCP listener")
+// maxReservedSkips bounds how many ports reserved by other tokens are skipped when picking a free port.
+const maxReservedSkips = 32
 
 // ConnService is the subset of core.Service required by the TCP edge server.
 type ConnService interface {
+	CheckClientIP(ctx context.Context, keyID, clientIP string) error
 	HandleTCPConnection(ctx context.Context, keyID string, conn net.Conn, clientIP string) error
+	TCPPortOwner(ctx context.Context, port int) (string, error)
 	SetTCPEndpointAllocator(allocator core.TCPEndpointAllocator)
+	Draining() <-chan struct{}
 }
 
-// activeListener tracks a running per-keyID TCP listener and its goroutines.
+// activeListener tracks a running per-keyID TCP listener, the number of control connections
+// of the keyID sharing it, and its goroutines.
 //
 // acceptWG tracks the single acceptLoop goroutine; handlerWG tracks the
 // per-connection handler goroutines.  They are kept separate so that
@@ -37,6 +40,7 @@ type activeListener struct {
 	acceptWG  sync.WaitGroup // tracks the single acceptLoop goroutine
 	handlerWG sync.WaitGroup // tracks per-connection handler goroutines
 	port      int
+	refs      int // control connections sharing the listener, guarded by TCPServer.mu
 }
 
 // TCPServer dynamically allocates TCP listeners for each connected MIT client
@@ -46,7 +50,8 @@ type TCPServer struct {
 	portPool    *portPool
 	listeners   map[string]*activeListener
 	config      Config
-	mu          sync.RWMutex
+	mu          sync.Mutex
+	draining    bool
 }
 
 // New validates cfg, creates a TCPServer, and injects it as the
@@ -69,8 +74,16 @@ func New(cfg Config, connService ConnService) (*TCPServer, error) {
 }
 
 // Run blocks until ctx is cancelled, then closes all active per-keyID listeners.
+// When the connection service starts draining, the listeners stop accepting connections
+// while the connections already accepted keep being served until ctx is cancelled.
 func (s *TCPServer) Run(ctx context.Context) error {
-	<-ctx.Done()
+	select {
+	case <-ctx.Done():
+	case <-s.connService.Draining():
+		s.stopAccepting()
+		<-ctx.Done()
+	}
+
 	s.closeAllListeners()
 
 	return nil
@@ -80,17 +93,33 @@ func (s *TCPServer) Run(ctx context.Context) error {
 // string in the form "host:port".  The listener will accept end-user connections
 // and route them through the tunnel.
 //
+// A non-zero requestedPort is used as is, or core.ErrPortUnavailable is returned when
+// it is outside of the range, in use, or reserved by another keyID.  Otherwise a random
+// port is picked, skipping ports reserved by other keyIDs.
+//
+// Control connections of the same keyID share its listener, so that pooled clients serve one endpoint:
+// further calls for a keyID with a listener return its endpoint, whatever port they request.
+//
 // Allocate is called by core.Service when a TCP MIT client completes
-// authentication (StateRegistered).  It must be balanced by a call to Release.
-func (s *TCPServer) Allocate(ctx context.Context, keyID string) (string, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// authentication (StateRegistered).  Every successful call must be balanced by a call to Release.
+func (s *TCPServer) Allocate(ctx context.Context, keyID string, requestedPort int) (string, error) {
+	if endpoint, ok, err := s.share(keyID); err != nil || ok {
+		return endpoint, err
+	}
 
-	if _, exists := s.listeners[keyID]; exists {
-		return "", fmt.Errorf("allocate keyID=%s: %w", keyID, ErrKeyIDAlreadyAllocated)
+	// Ports are picked and bound without holding s.mu, so that the reservation lookups
+	// of one client do not block allocations and releases of others.
+	var (
+		port int
+		err  error
+	)
+
+	if requestedPort > 0 {
+		port, err = requestedPort, s.allocateRequestedPort(ctx, keyID, requestedPort)
+	} else {
+		port, err = s.allocateFreePort(ctx, keyID)
 	}
 
-	port, err := s.portPool.Allocate()
 	if err != nil {
 		return "", fmt.Errorf("allocate port for keyID=%s: %w", keyID, err)
 	}
@@ -100,15 +129,35 @@ func (s *TCPServer) Allocate(ctx context.Context, keyID string) (string, error)
 	ln, err := net.Listen("tcp", addr)
 	if err != nil {
 		s.portPool.Release(port)
+
+		if requestedPort > 0 {
+			return "", fmt.Errorf("listen on %s for keyID=%s: %w: %w", addr, keyID, core.ErrPortUnavailable, err)
+		}
+
 		return "", fmt.Errorf("listen on %s for keyID=%s: %w", addr, keyID, err)
 	}
 
-	listenerCtx, cancel := context.WithCancel(ctx)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// The server may have started draining, or keyID may have been allocated concurrently, while the port was picked.
+	if endpoint, ok, err := s.shareLocked(keyID); err != nil || ok {
+		_ = ln.Close()
+
+		s.portPool.Release(port)
+
+		return endpoint, err
+	}
+
+	// The listener outlives the control connection that allocated it when others share it, so it is only
+	// stopped by Release.
+	listenerCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
 
 	al := &activeListener{
 		listener: ln,
 		port:     port,
 		cancel:   cancel,
+		refs:     1,
 	}
 
 	s.listeners[keyID] = al
@@ -131,8 +180,34 @@ func (s *TCPServer) Allocate(ctx context.Context, keyID string) (string, error)
 	return endpoint, nil
 }
 
-// Release stops the listener associated with keyID and returns its port to the
-// pool.  It is safe to call Release on a keyID that has already been released.
+// share adds a control connection to the listener of keyID and returns its endpoint, if keyID has one.
+// Returns core.ErrDraining if the server is draining.
+func (s *TCPServer) share(keyID string) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.shareLocked(keyID)
+}
+
+// shareLocked is share for callers that already hold s.mu.
+func (s *TCPServer) shareLocked(keyID string) (string, bool, error) {
+	if s.draining {
+		return "", false, fmt.Errorf("allocate keyID=%s: %w", keyID, core.ErrDraining)
+	}
+
+	al, exists := s.listeners[keyID]
+	if !exists {
+		return "", false, nil
+	}
+
+	al.refs++
+
+	return net.JoinHostPort(s.config.Public.Host, strconv.Itoa(al.port)), true, nil
+}
+
+// Release removes a control connection of keyID from its listener.  Once no control
+// connection shares the listener, it is stopped and its port is returned to the pool.
+// It is safe to call Release on a keyID that has already been released.
 //
 // Release is called by core.Service via a deferred call in HandleReverseConn so
 // it executes when the MIT client disconnects.
@@ -145,9 +220,19 @@ func (s *TCPServer) Release(keyID string) {
 		return
 	}
 
+	if al.refs--; al.refs > 0 {
+		s.mu.Unlock()
+		return
+	}
+
 	delete(s.listeners, keyID)
 	s.mu.Unlock()
 
+	s.closeListener(keyID, al)
+}
+
+// closeListener stops al, waits for the connections it accepted and returns its port to the pool.
+func (s *TCPServer) closeListener(keyID string, al *activeListener) {
 	al.cancel()
 
 	_ = al.listener.Close()
@@ -162,13 +247,60 @@ func (s *TCPServer) Release(keyID string) {
 	slog.Info("TCP listener released", slog.String("keyID", keyID), slog.Int("port", al.port))
 }
 
+// allocateRequestedPort takes port from the pool unless it is reserved by a keyID other than keyID.
+func (s *TCPServer) allocateRequestedPort(ctx context.Context, keyID string, port int) error {
+	owner, err := s.connService.TCPPortOwner(ctx, port)
+	if err != nil {
+		return err
+	}
+
+	if owner != "" && owner != keyID {
+		return fmt.Errorf("port %d is reserved: %w", port, core.ErrPortUnavailable)
+	}
+
+	return s.portPool.AllocatePort(port)
+}
+
+// allocateFreePort takes a random port from the pool, skipping ports reserved by keyIDs other than keyID.
+func (s *TCPServer) allocateFreePort(ctx context.Context, keyID string) (int, error) {
+	var skipped []int
+
+	// Skipped ports stay taken until a port is found, so they are not picked again.
+	defer func() {
+		for _, port := range skipped {
+			s.portPool.Release(port)
+		}
+	}()
+
+	for range maxReservedSkips {
+		port, err := s.portPool.Allocate()
+		if err != nil {
+			return 0, err
+		}
+
+		owner, err := s.connService.TCPPortOwner(ctx, port)
+		if err != nil {
+			s.portPool.Release(port)
+			return 0, err
+		}
+
+		if owner == "" || owner == keyID {
+			return port, nil
+		}
+
+		skipped = append(skipped, port)
+	}
+
+	return 0, ErrPortPoolExhausted
+}
+
 // acceptLoop runs the accept loop for a single per-keyID listener.
 func (s *TCPServer) acceptLoop(ctx context.Context, al *activeListener, keyID string) {
 	for {
 		conn, err := al.listener.Accept()
 		if err != nil {
-			if ctx.Err() != nil {
-				return // normal shutdown via Release or server stop
+			if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				return // normal shutdown via Release, drain or server stop
 			}
 
 			slog.ErrorContext(ctx, "TCP accept error",
@@ -196,6 +328,8 @@ func (s *TCPServer) acceptLoop(ctx context.Context, al *activeListener, keyID st
 }
 
 // handleConn routes a single end-user TCP connection through the tunnel.
+// Connections from client IPs rejected by the token's CIDR rules are closed immediately,
+// as are connections to tunnels whose traffic quota is used up.
 func (s *TCPServer) handleConn(ctx context.Context, keyID string, conn net.Conn) {
 	defer func() { _ = conn.Close() }()
 
@@ -208,7 +342,23 @@ func (s *TCPServer) handleConn(ctx context.Context, keyID string, conn net.Conn)
 		slog.String("keyID", keyID),
 		slog.String("clientIP", clientIP))
 
-	if err := s.connService.HandleTCPConnection(ctx, keyID, conn, clientIP); err != nil {
+	if err := s.connService.CheckClientIP(ctx, keyID, clientIP); err != nil {
+		slog.DebugContext(ctx, "TCP connection rejected",
+			slog.String("keyID", keyID),
+			slog.String("clientIP", clientIP),
+			slog.Any("error", err))
+
+		return
+	}
+
+	err := s.connService.HandleTCPConnection(ctx, keyID, conn, clientIP)
+
+	switch {
+	case errors.Is(err, core.ErrQuotaExceeded):
+		slog.InfoContext(ctx, "TCP connection refused, traffic quota exceeded",
+			slog.String("keyID", keyID),
+			slog.String("clientIP", clientIP))
+	case err != nil:
 		slog.DebugContext(ctx, "TCP connection closed",
 			slog.String("keyID", keyID),
 			slog.String("clientIP", clientIP),
@@ -216,18 +366,30 @@ func (s *TCPServer) handleConn(ctx context.Context, keyID string, conn net.Conn)
 	}
 }
 
-// closeAllListeners shuts down every active listener.  Called on server stop.
-func (s *TCPServer) closeAllListeners() {
+// stopAccepting closes every active listener without waiting for the connections it accepted,
+// and refuses further allocations.  Called when the connection service starts draining.
+func (s *TCPServer) stopAccepting() {
 	s.mu.Lock()
-	keys := make([]string, 0, len(s.listeners))
+	defer s.mu.Unlock()
+
+	s.draining = true
 
-	for k := range s.listeners {
-		keys = append(keys, k)
+	for keyID, al := range s.listeners {
+		if err := al.listener.Close(); err != nil {
+			slog.Error("failed to close TCP listener", slog.String("keyID", keyID), slog.Any("error", err))
+		}
 	}
+}
 
+// closeAllListeners shuts down every active listener, whatever the number of control connections
+// sharing it.  Called on server stop.
+func (s *TCPServer) closeAllListeners() {
+	s.mu.Lock()
+	listeners := s.listeners
+	s.listeners = make(map[string]*activeListener)
 	s.mu.Unlock()
 
-	for _, keyID := range keys {
-		s.Release(keyID)
+	for keyID, al := range listeners {
+		s.closeListener(keyID, al)
 	}
 }
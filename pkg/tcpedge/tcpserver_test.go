@@ -3,10 +3,13 @@ package tcpedge
 import (
 	"context"
 	"fmt"
+	"io"
 	"net"
+	"strconv"
 	"testing"
 	"time"
 
+	"github.com/ksysoev/make-it-public/pkg/core"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -64,6 +67,7 @@ func TestNew_InjectsAllocator(t *testing.T) {
 func TestTCPServer_Run_Shutdown(t *testing.T) {
 	svc := NewMockConnService(t)
 	svc.EXPECT().SetTCPEndpointAllocator(mock.Anything)
+	svc.EXPECT().Draining().Return(make(chan struct{}))
 
 	srv, err := New(validConfig(t), svc)
 	require.NoError(t, err)
@@ -84,16 +88,71 @@ func TestTCPServer_Run_Shutdown(t *testing.T) {
 	}
 }
 
+func TestTCPServer_Run_Draining(t *testing.T) {
+	draining := make(chan struct{})
+
+	svc := NewMockConnService(t)
+	svc.EXPECT().SetTCPEndpointAllocator(mock.Anything)
+	svc.EXPECT().TCPPortOwner(mock.Anything, mock.Anything).Return("", nil)
+	svc.EXPECT().Draining().Return(draining)
+	// Connections accepted before the listener is closed are refused.
+	svc.EXPECT().CheckClientIP(mock.Anything, "drainkey", "127.0.0.1").Return(core.ErrForbidden).Maybe()
+
+	srv, err := New(validConfig(t), svc)
+	require.NoError(t, err)
+
+	endpoint, err := srv.Allocate(context.Background(), "drainkey", 0)
+	require.NoError(t, err)
+
+	_, port, err := net.SplitHostPort(endpoint)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+
+	go func() { done <- srv.Run(ctx) }()
+
+	close(draining)
+
+	// The listener stops accepting connections while the server keeps running.
+	require.Eventually(t, func() bool {
+		c, dialErr := net.Dial("tcp", net.JoinHostPort("127.0.0.1", port))
+		if dialErr != nil {
+			return true
+		}
+
+		_ = c.Close()
+
+		return false
+	}, time.Second, 10*time.Millisecond)
+
+	_, err = srv.Allocate(context.Background(), "newkey", 0)
+	assert.ErrorIs(t, err, core.ErrDraining)
+
+	cancel()
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run did not return after context cancel")
+	}
+
+	assert.Equal(t, 11, srv.portPool.Available())
+}
+
 func TestTCPServer_Allocate_ReturnsEndpoint(t *testing.T) {
 	svc := NewMockConnService(t)
 	svc.EXPECT().SetTCPEndpointAllocator(mock.Anything)
+	svc.EXPECT().TCPPortOwner(mock.Anything, mock.Anything).Return("", nil)
 
 	srv, err := New(validConfig(t), svc)
 	require.NoError(t, err)
 
 	defer srv.closeAllListeners()
 
-	endpoint, err := srv.Allocate(context.Background(), "testkey")
+	endpoint, err := srv.Allocate(context.Background(), "testkey", 0)
 	require.NoError(t, err)
 
 	// Endpoint should be "example.com:<port>".
@@ -103,32 +162,206 @@ func TestTCPServer_Allocate_ReturnsEndpoint(t *testing.T) {
 	assert.NotEmpty(t, portStr)
 }
 
-func TestTCPServer_Allocate_DuplicateKeyID(t *testing.T) {
+func TestTCPServer_Allocate_SharedByKeyID(t *testing.T) {
 	svc := NewMockConnService(t)
 	svc.EXPECT().SetTCPEndpointAllocator(mock.Anything)
+	svc.EXPECT().TCPPortOwner(mock.Anything, mock.Anything).Return("", nil).Once()
+	svc.EXPECT().CheckClientIP(mock.Anything, "shared", mock.Anything).Return(nil)
+
+	handled := make(chan struct{})
+
+	svc.EXPECT().HandleTCPConnection(mock.Anything, "shared", mock.Anything, mock.Anything).
+		RunAndReturn(func(context.Context, string, net.Conn, string) error {
+			close(handled)
+			return nil
+		})
 
 	srv, err := New(validConfig(t), svc)
 	require.NoError(t, err)
 
 	defer srv.closeAllListeners()
 
-	_, err = srv.Allocate(context.Background(), "dup")
+	ctx, cancel := context.WithCancel(context.Background())
+
+	first, err := srv.Allocate(ctx, "shared", 0)
+	require.NoError(t, err)
+
+	// Pooled control connections of a keyID share its listener, whatever port they request.
+	second, err := srv.Allocate(context.Background(), "shared", 1)
+	require.NoError(t, err)
+	assert.Equal(t, first, second)
+
+	_, port, err := net.SplitHostPort(first)
 	require.NoError(t, err)
 
-	_, err = srv.Allocate(context.Background(), "dup")
-	assert.ErrorIs(t, err, ErrKeyIDAlreadyAllocated)
+	addr := net.JoinHostPort("127.0.0.1", port)
+	available := srv.portPool.Available()
+
+	// The listener keeps accepting connections once the control connection that allocated it is gone.
+	cancel()
+	srv.Release("shared")
+
+	c, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+
+	defer func() { _ = c.Close() }()
+
+	select {
+	case <-handled:
+	case <-time.After(time.Second):
+		t.Fatal("connection was not routed through the tunnel")
+	}
+
+	assert.Equal(t, available, srv.portPool.Available())
+
+	srv.Release("shared")
+
+	_, err = net.Dial("tcp", addr)
+	assert.Error(t, err)
+	assert.Equal(t, available+1, srv.portPool.Available())
+}
+
+func TestTCPServer_Allocate_RequestedPort(t *testing.T) {
+	cfg := validConfig(t)
+	port := cfg.PortRange.Min + 3
+
+	svc := NewMockConnService(t)
+	svc.EXPECT().SetTCPEndpointAllocator(mock.Anything)
+	svc.EXPECT().TCPPortOwner(mock.Anything, port).Return("", nil)
+
+	srv, err := New(cfg, svc)
+	require.NoError(t, err)
+
+	defer srv.closeAllListeners()
+
+	endpoint, err := srv.Allocate(context.Background(), "testkey", port)
+	require.NoError(t, err)
+	assert.Equal(t, net.JoinHostPort("example.com", strconv.Itoa(port)), endpoint)
+
+	_, err = srv.Allocate(context.Background(), "otherkey", port)
+	assert.ErrorIs(t, err, core.ErrPortUnavailable)
+}
+
+func TestTCPServer_Allocate_RequestedPortOutOfRange(t *testing.T) {
+	cfg := validConfig(t)
+
+	svc := NewMockConnService(t)
+	svc.EXPECT().SetTCPEndpointAllocator(mock.Anything)
+	svc.EXPECT().TCPPortOwner(mock.Anything, cfg.PortRange.Max+1).Return("", nil)
+
+	srv, err := New(cfg, svc)
+	require.NoError(t, err)
+
+	_, err = srv.Allocate(context.Background(), "testkey", cfg.PortRange.Max+1)
+	assert.ErrorIs(t, err, core.ErrPortUnavailable)
+}
+
+func TestTCPServer_Allocate_RequestedPortReservedByOther(t *testing.T) {
+	cfg := validConfig(t)
+
+	svc := NewMockConnService(t)
+	svc.EXPECT().SetTCPEndpointAllocator(mock.Anything)
+	svc.EXPECT().TCPPortOwner(mock.Anything, cfg.PortRange.Min).Return("owner", nil)
+
+	srv, err := New(cfg, svc)
+	require.NoError(t, err)
+
+	_, err = srv.Allocate(context.Background(), "testkey", cfg.PortRange.Min)
+	assert.ErrorIs(t, err, core.ErrPortUnavailable)
+	assert.Equal(t, cfg.PortRange.Max-cfg.PortRange.Min+1, srv.portPool.Available())
+}
+
+func TestTCPServer_Allocate_SkipsReservedPorts(t *testing.T) {
+	cfg := validConfig(t)
+	free := cfg.PortRange.Min + 5
+
+	svc := NewMockConnService(t)
+	svc.EXPECT().SetTCPEndpointAllocator(mock.Anything)
+	svc.EXPECT().TCPPortOwner(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, port int) (string, error) {
+		if port == free {
+			return "", nil
+		}
+
+		return "owner", nil
+	})
+
+	srv, err := New(cfg, svc)
+	require.NoError(t, err)
+
+	defer srv.closeAllListeners()
+
+	endpoint, err := srv.Allocate(context.Background(), "testkey", 0)
+	require.NoError(t, err)
+	assert.Equal(t, net.JoinHostPort("example.com", strconv.Itoa(free)), endpoint)
+
+	// Skipped ports are returned to the pool.
+	assert.Equal(t, cfg.PortRange.Max-cfg.PortRange.Min, srv.portPool.Available())
+}
+
+func TestTCPServer_Allocate_DoesNotBlockOnPortLookup(t *testing.T) {
+	cfg := validConfig(t)
+	slowPort := cfg.PortRange.Min
+	fastPort := cfg.PortRange.Min + 1
+
+	lookupStarted := make(chan struct{})
+	unblock := make(chan struct{})
+
+	svc := NewMockConnService(t)
+	svc.EXPECT().SetTCPEndpointAllocator(mock.Anything)
+	svc.EXPECT().TCPPortOwner(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, port int) (string, error) {
+		if port == slowPort {
+			close(lookupStarted)
+			<-unblock
+		}
+
+		return "", nil
+	})
+
+	srv, err := New(cfg, svc)
+	require.NoError(t, err)
+
+	defer srv.closeAllListeners()
+
+	slowDone := make(chan error, 1)
+
+	go func() {
+		_, err := srv.Allocate(context.Background(), "slow", slowPort)
+		slowDone <- err
+	}()
+
+	<-lookupStarted
+
+	fastDone := make(chan error, 1)
+
+	go func() {
+		_, err := srv.Allocate(context.Background(), "fast", fastPort)
+		fastDone <- err
+	}()
+
+	select {
+	case err := <-fastDone:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Allocate blocked on another client's port lookup")
+	}
+
+	srv.Release("fast")
+	close(unblock)
+
+	require.NoError(t, <-slowDone)
 }
 
 func TestTCPServer_Release_FreesPort(t *testing.T) {
 	svc := NewMockConnService(t)
 	svc.EXPECT().SetTCPEndpointAllocator(mock.Anything)
+	svc.EXPECT().TCPPortOwner(mock.Anything, mock.Anything).Return("", nil)
 
 	srv, err := New(validConfig(t), svc)
 	require.NoError(t, err)
 
 	initial := srv.portPool.Available()
 
-	_, err = srv.Allocate(context.Background(), "releasekey")
+	_, err = srv.Allocate(context.Background(), "releasekey", 0)
 	require.NoError(t, err)
 
 	assert.Equal(t, initial-1, srv.portPool.Available())
@@ -140,6 +373,7 @@ func TestTCPServer_Release_FreesPort(t *testing.T) {
 func TestTCPServer_Release_Idempotent(t *testing.T) {
 	svc := NewMockConnService(t)
 	svc.EXPECT().SetTCPEndpointAllocator(mock.Anything)
+	svc.EXPECT().TCPPortOwner(mock.Anything, mock.Anything).Return("", nil)
 
 	srv, err := New(validConfig(t), svc)
 	require.NoError(t, err)
@@ -147,7 +381,7 @@ func TestTCPServer_Release_Idempotent(t *testing.T) {
 	// Releasing a key that was never allocated should not panic.
 	assert.NotPanics(t, func() { srv.Release("nonexistent") })
 
-	_, err = srv.Allocate(context.Background(), "idem")
+	_, err = srv.Allocate(context.Background(), "idem", 0)
 	require.NoError(t, err)
 
 	srv.Release("idem")
@@ -159,9 +393,11 @@ func TestTCPServer_Release_Idempotent(t *testing.T) {
 func TestTCPServer_AcceptsAndRoutesConnection(t *testing.T) {
 	svc := NewMockConnService(t)
 	svc.EXPECT().SetTCPEndpointAllocator(mock.Anything)
+	svc.EXPECT().TCPPortOwner(mock.Anything, mock.Anything).Return("", nil)
 
 	connReceived := make(chan struct{})
 
+	svc.EXPECT().CheckClientIP(mock.Anything, "routekey", "127.0.0.1").Return(nil)
 	svc.EXPECT().
 		HandleTCPConnection(mock.Anything, "routekey", mock.Anything, mock.MatchedBy(func(ip string) bool {
 			return ip == "127.0.0.1"
@@ -176,7 +412,7 @@ func TestTCPServer_AcceptsAndRoutesConnection(t *testing.T) {
 
 	defer srv.closeAllListeners()
 
-	endpoint, err := srv.Allocate(context.Background(), "routekey")
+	endpoint, err := srv.Allocate(context.Background(), "routekey", 0)
 	require.NoError(t, err)
 
 	// endpoint is "example.com:<port>" — extract the port and dial the listen address.
@@ -199,6 +435,35 @@ func TestTCPServer_AcceptsAndRoutesConnection(t *testing.T) {
 	}
 }
 
+func TestTCPServer_RejectsForbiddenClientIP(t *testing.T) {
+	svc := NewMockConnService(t)
+	svc.EXPECT().SetTCPEndpointAllocator(mock.Anything)
+	svc.EXPECT().TCPPortOwner(mock.Anything, mock.Anything).Return("", nil)
+	svc.EXPECT().CheckClientIP(mock.Anything, "denykey", "127.0.0.1").Return(core.ErrForbidden)
+
+	srv, err := New(validConfig(t), svc)
+	require.NoError(t, err)
+
+	defer srv.closeAllListeners()
+
+	endpoint, err := srv.Allocate(context.Background(), "denykey", 0)
+	require.NoError(t, err)
+
+	_, portStr, err := net.SplitHostPort(endpoint)
+	require.NoError(t, err)
+
+	c, err := net.Dial("tcp", net.JoinHostPort("127.0.0.1", portStr))
+	require.NoError(t, err)
+
+	defer c.Close()
+
+	require.NoError(t, c.SetReadDeadline(time.Now().Add(2*time.Second)))
+
+	// The server closes the connection without routing it through the tunnel.
+	_, err = c.Read(make([]byte, 1))
+	assert.ErrorIs(t, err, io.EOF)
+}
+
 func TestTCPServer_PortExhausted(t *testing.T) {
 	// Use a range of exactly 1 port so exhaustion happens on the second Allocate.
 	// min == max is a valid single-port range.
@@ -211,16 +476,17 @@ func TestTCPServer_PortExhausted(t *testing.T) {
 
 	svc := NewMockConnService(t)
 	svc.EXPECT().SetTCPEndpointAllocator(mock.Anything)
+	svc.EXPECT().TCPPortOwner(mock.Anything, mock.Anything).Return("", nil)
 
 	srv, err := New(cfg, svc)
 	require.NoError(t, err)
 
 	defer srv.closeAllListeners()
 
-	_, err = srv.Allocate(context.Background(), "key1")
+	_, err = srv.Allocate(context.Background(), "key1", 0)
 	require.NoError(t, err)
 
-	_, err = srv.Allocate(context.Background(), "key2")
+	_, err = srv.Allocate(context.Background(), "key2", 0)
 	assert.Error(t, err, "should fail when port pool is exhausted")
 	assert.ErrorIs(t, err, ErrPortPoolExhausted)
 }
@@ -228,21 +494,22 @@ func TestTCPServer_PortExhausted(t *testing.T) {
 func TestTCPServer_CloseAllListeners(t *testing.T) {
 	svc := NewMockConnService(t)
 	svc.EXPECT().SetTCPEndpointAllocator(mock.Anything)
+	svc.EXPECT().TCPPortOwner(mock.Anything, mock.Anything).Return("", nil)
 
 	srv, err := New(validConfig(t), svc)
 	require.NoError(t, err)
 
 	for i := range 3 {
-		_, allocErr := srv.Allocate(context.Background(), fmt.Sprintf("key%d", i))
+		_, allocErr := srv.Allocate(context.Background(), fmt.Sprintf("key%d", i), 0)
 		require.NoError(t, allocErr)
 	}
 
 	srv.closeAllListeners()
 
 	// After closeAll, all listeners should be gone.
-	srv.mu.RLock()
+	srv.mu.Lock()
 	remaining := len(srv.listeners)
-	srv.mu.RUnlock()
+	srv.mu.Unlock()
 
 	assert.Zero(t, remaining)
 
@@ -0,0 +1,43 @@
+package tlsedge
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Config holds configuration for the TLS passthrough edge server.
+// The server is enabled when Listen is set.
+type Config struct {
+	Listen string               `mapstructure:"listen"`
+	Public PublicEndpointConfig `mapstructure:"public"`
+}
+
+// PublicEndpointConfig defines the public endpoint advertised to clients of TLS passthrough tunnels,
+// whose subdomains of Domain identify the tunnels.
+type PublicEndpointConfig struct {
+	Domain string `mapstructure:"domain"`
+	Port   int    `mapstructure:"port"`
+}
+
+// Enabled reports whether TLS passthrough tunnels should be served.
+func (c *Config) Enabled() bool {
+	return c.Listen != ""
+}
+
+// Validate checks that the Config is valid. It returns an error describing the
+// first violation found.
+func (c *Config) Validate() error {
+	if c.Listen == "" {
+		return errors.New("listen must not be empty")
+	}
+
+	if c.Public.Domain == "" {
+		return errors.New("public.domain must not be empty")
+	}
+
+	if c.Public.Port < 1 || c.Public.Port > 65535 {
+		return fmt.Errorf("public.port must be between 1 and 65535, got %d", c.Public.Port)
+	}
+
+	return nil
+}
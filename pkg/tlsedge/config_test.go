@@ -0,0 +1,40 @@
+package tlsedge
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	public := PublicEndpointConfig{Domain: "tls.example.com", Port: 443}
+
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{name: "valid", cfg: Config{Listen: ":8444", Public: public}},
+		{name: "empty listen", cfg: Config{Public: public}, wantErr: true},
+		{name: "empty domain", cfg: Config{Listen: ":8444", Public: PublicEndpointConfig{Port: 443}}, wantErr: true},
+		{name: "missing port", cfg: Config{Listen: ":8444", Public: PublicEndpointConfig{Domain: "tls.example.com"}}, wantErr: true},
+		{name: "port out of range", cfg: Config{Listen: ":8444", Public: PublicEndpointConfig{Domain: "tls.example.com", Port: 70000}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestConfig_Enabled(t *testing.T) {
+	assert.False(t, (&Config{}).Enabled())
+	assert.True(t, (&Config{Listen: ":8444"}).Enabled())
+}
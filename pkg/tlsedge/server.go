@@ -0,0 +1,197 @@
+package tlsedge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ksysoev/make-it-public/pkg/core"
+	"github.com/ksysoev/make-it-public/pkg/edge/middleware"
+)
+
+// helloTimeout bounds how long a visitor may take to send its ClientHello.
+const helloTimeout = 5 * time.Second
+
+// TLS alert descriptions sent to visitors whose connections could not reach a tunnel.
+const (
+	alertAccessDenied     = 49
+	alertInternalError    = 80
+	alertUnrecognizedName = 112
+)
+
+// ConnService is the subset of core.Service required by the TLS passthrough edge server.
+type ConnService interface {
+	CheckClientIP(ctx context.Context, keyID, clientIP string) error
+	HandleTLSConnection(ctx context.Context, keyID string, conn net.Conn, clientIP string) error
+	SetTLSEndpointGenerator(generator func(string) (string, error))
+	Draining() <-chan struct{}
+}
+
+// TLSServer serves the TLS passthrough tunnels on a single shared port. It routes every connection by the
+// server name of its ClientHello and pipes it to the tunnel without decrypting it, so that TLS is terminated
+// by the local service, client certificates included.
+type TLSServer struct {
+	connService ConnService
+	config      Config
+	conns       sync.WaitGroup
+}
+
+// New validates cfg, creates a TLSServer, and injects the generator of the public endpoints
+// of TLS passthrough tunnels into connService.
+func New(cfg Config, connService ConnService) (*TLSServer, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid TLS edge config: %w", err)
+	}
+
+	port := strconv.Itoa(cfg.Public.Port)
+
+	connService.SetTLSEndpointGenerator(func(keyID string) (string, error) {
+		return net.JoinHostPort(keyID+"."+cfg.Public.Domain, port), nil
+	})
+
+	return &TLSServer{
+		connService: connService,
+		config:      cfg,
+	}, nil
+}
+
+// Run starts the TLS passthrough edge server and blocks until ctx is cancelled.
+// When the connection service starts draining, the server stops accepting connections
+// while the connections already accepted keep being served until ctx is cancelled.
+func (s *TLSServer) Run(ctx context.Context) error {
+	ln, err := net.Listen("tcp", s.config.Listen)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.config.Listen, err)
+	}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-s.connService.Draining():
+		}
+
+		_ = ln.Close()
+	}()
+
+	s.serve(ctx, ln)
+
+	<-ctx.Done()
+
+	s.conns.Wait()
+
+	return nil
+}
+
+// serve accepts connections on ln until it is closed.
+func (s *TLSServer) serve(ctx context.Context, ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				return
+			}
+
+			slog.ErrorContext(ctx, "TLS accept error", slog.Any("error", err))
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(100 * time.Millisecond):
+				// retry on transient error (e.g. EMFILE)
+			}
+
+			continue
+		}
+
+		s.conns.Go(func() { s.handleConn(ctx, conn) })
+	}
+}
+
+// handleConn routes a single visitor connection to the tunnel named by its ClientHello.
+// Connections that do not name a tunnel, come from client IPs rejected by the token's CIDR rules,
+// or cannot reach the tunnel are closed with a TLS alert.
+func (s *TLSServer) handleConn(ctx context.Context, conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+
+	clientIP := ""
+	if addr, ok := conn.RemoteAddr().(*net.TCPAddr); ok {
+		clientIP = addr.IP.String()
+	}
+
+	_ = conn.SetReadDeadline(time.Now().Add(helloTimeout))
+
+	serverName, peeked, err := peekServerName(conn)
+	if err != nil {
+		slog.DebugContext(ctx, "failed to read TLS client hello", slog.String("clientIP", clientIP), slog.Any("error", err))
+		return
+	}
+
+	_ = conn.SetReadDeadline(time.Time{})
+
+	keyID := middleware.KeyIDFromHost(strings.ToLower(serverName), s.config.Public.Domain)
+	if keyID == "" {
+		slog.DebugContext(ctx, "TLS connection for unknown server name", slog.String("serverName", serverName))
+		writeAlert(conn, alertUnrecognizedName)
+
+		return
+	}
+
+	if err := s.connService.CheckClientIP(ctx, keyID, clientIP); err != nil {
+		slog.DebugContext(ctx, "TLS connection rejected",
+			slog.String("keyID", keyID),
+			slog.String("clientIP", clientIP),
+			slog.Any("error", err))
+		writeAlert(conn, alertFor(err))
+
+		return
+	}
+
+	err = s.connService.HandleTLSConnection(ctx, keyID, peeked, clientIP)
+
+	switch {
+	case err == nil:
+		return
+	case errors.Is(err, core.ErrQuotaExceeded):
+		slog.InfoContext(ctx, "TLS connection refused, traffic quota exceeded",
+			slog.String("keyID", keyID),
+			slog.String("clientIP", clientIP))
+	default:
+		slog.DebugContext(ctx, "TLS connection closed",
+			slog.String("keyID", keyID),
+			slog.String("clientIP", clientIP),
+			slog.Any("error", err))
+	}
+
+	// HandleTLSConnection only returns errors before the connection is piped, so the visitor is still waiting
+	// for a ServerHello and no encrypted record has been exchanged yet.
+	writeAlert(conn, alertFor(err))
+}
+
+// alertFor returns the TLS alert description matching an error of the connection service.
+func alertFor(err error) byte {
+	switch {
+	case errors.Is(err, core.ErrForbidden):
+		return alertAccessDenied
+	case errors.Is(err, core.ErrKeyIDNotFound):
+		return alertUnrecognizedName
+	default:
+		return alertInternalError
+	}
+}
+
+// writeAlert sends a fatal TLS alert with the given description, so that the visitor reports why the handshake failed.
+func writeAlert(conn net.Conn, description byte) {
+	const (
+		recordTypeAlert = 21
+		alertLevelFatal = 2
+	)
+
+	_ = conn.SetWriteDeadline(time.Now().Add(time.Second))
+	_, _ = conn.Write([]byte{recordTypeAlert, 3, 1, 0, 2, alertLevelFatal, description})
+}
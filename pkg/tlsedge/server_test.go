@@ -0,0 +1,240 @@
+package tlsedge
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ksysoev/make-it-public/pkg/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestServer(t *testing.T, connService *MockConnService) *TLSServer {
+	t.Helper()
+
+	connService.EXPECT().SetTLSEndpointGenerator(mock.Anything).Return()
+
+	s, err := New(Config{
+		Listen: ":0",
+		Public: PublicEndpointConfig{Domain: "tls.example.com", Port: 443},
+	}, connService)
+	require.NoError(t, err)
+
+	return s
+}
+
+// startEdge serves s on a local port and returns its address.
+func startEdge(t *testing.T, s *TLSServer) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		s.serve(ctx, ln)
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+		_ = ln.Close()
+		<-done
+		s.conns.Wait()
+	})
+
+	return ln.Addr().String()
+}
+
+// pipeTo mimics a client behind the tunnel, which pipes the connection to the local service at addr.
+func pipeTo(addr string) func(context.Context, string, net.Conn, string) error {
+	return func(_ context.Context, _ string, conn net.Conn, _ string) error {
+		backend, err := net.Dial("tcp", addr)
+		if err != nil {
+			return err
+		}
+
+		defer func() { _ = backend.Close() }()
+
+		go func() {
+			_, _ = io.Copy(backend, conn)
+			_ = backend.Close()
+		}()
+
+		_, _ = io.Copy(conn, backend)
+
+		return nil
+	}
+}
+
+func dialTLS(addr, serverName string) (*tls.Conn, error) {
+	return tls.Dial("tcp", addr, &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: true, //nolint:gosec // the test backend uses a self-signed certificate
+		MinVersion:         tls.VersionTLS12,
+	})
+}
+
+func TestNew(t *testing.T) {
+	connService := NewMockConnService(t)
+	connService.EXPECT().SetTLSEndpointGenerator(mock.Anything).Run(func(generator func(string) (string, error)) {
+		endpoint, err := generator("key1")
+		require.NoError(t, err)
+		assert.Equal(t, "key1.tls.example.com:443", endpoint)
+	}).Return()
+
+	_, err := New(Config{
+		Listen: ":0",
+		Public: PublicEndpointConfig{Domain: "tls.example.com", Port: 443},
+	}, connService)
+	require.NoError(t, err)
+
+	_, err = New(Config{}, NewMockConnService(t))
+	assert.Error(t, err)
+}
+
+func TestTLSServer_PassesThroughTLS(t *testing.T) {
+	backend := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The ClientHello reaches the backend as the visitor sent it.
+		assert.Equal(t, "KEY1.tls.example.com", r.TLS.ServerName)
+		_, _ = w.Write([]byte("hello"))
+	}))
+
+	defer backend.Close()
+
+	connService := NewMockConnService(t)
+	connService.EXPECT().CheckClientIP(mock.Anything, "key1", "127.0.0.1").Return(nil)
+	connService.EXPECT().HandleTLSConnection(mock.Anything, "key1", mock.Anything, "127.0.0.1").
+		RunAndReturn(pipeTo(backend.Listener.Addr().String()))
+
+	addr := startEdge(t, newTestServer(t, connService))
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			// Server names are routed regardless of their case.
+			DialTLSContext: func(_ context.Context, _, _ string) (net.Conn, error) {
+				return dialTLS(addr, "KEY1.tls.example.com")
+			},
+		},
+	}
+
+	resp, err := client.Get("https://key1.tls.example.com/")
+	require.NoError(t, err)
+
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(body))
+
+	// The TLS session is terminated by the backend rather than by the edge.
+	require.NotNil(t, resp.TLS)
+	assert.True(t, resp.TLS.PeerCertificates[0].Equal(backend.Certificate()))
+}
+
+func TestTLSServer_Alerts(t *testing.T) {
+	tests := []struct {
+		setup      func(connService *MockConnService)
+		name       string
+		serverName string
+		wantErr    string
+	}{
+		{
+			name:       "unknown server name",
+			serverName: "key1.other.example.com",
+			setup:      func(*MockConnService) {},
+			wantErr:    "unrecognized name",
+		},
+		{
+			name:       "forbidden client IP",
+			serverName: "key1.tls.example.com",
+			setup: func(connService *MockConnService) {
+				connService.EXPECT().CheckClientIP(mock.Anything, "key1", "127.0.0.1").Return(core.ErrForbidden)
+			},
+			wantErr: "access denied",
+		},
+		{
+			name:       "keyID not found",
+			serverName: "key1.tls.example.com",
+			setup: func(connService *MockConnService) {
+				connService.EXPECT().CheckClientIP(mock.Anything, "key1", "127.0.0.1").Return(nil)
+				connService.EXPECT().HandleTLSConnection(mock.Anything, "key1", mock.Anything, "127.0.0.1").Return(core.ErrKeyIDNotFound)
+			},
+			wantErr: "unrecognized name",
+		},
+		{
+			name:       "failed to connect",
+			serverName: "key1.tls.example.com",
+			setup: func(connService *MockConnService) {
+				connService.EXPECT().CheckClientIP(mock.Anything, "key1", "127.0.0.1").Return(nil)
+				connService.EXPECT().HandleTLSConnection(mock.Anything, "key1", mock.Anything, "127.0.0.1").Return(core.ErrFailedToConnect)
+			},
+			wantErr: "internal error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			connService := NewMockConnService(t)
+			tt.setup(connService)
+
+			addr := startEdge(t, newTestServer(t, connService))
+
+			_, err := dialTLS(addr, tt.serverName)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestTLSServer_Run_Draining(t *testing.T) {
+	draining := make(chan struct{})
+
+	connService := NewMockConnService(t)
+	connService.EXPECT().Draining().Return(draining)
+
+	s := newTestServer(t, connService)
+	s.config.Listen = "127.0.0.1:0"
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+
+	go func() { done <- s.Run(ctx) }()
+
+	close(draining)
+
+	// Run keeps running while the server drains.
+	select {
+	case <-done:
+		t.Fatal("Run returned before ctx was cancelled")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	cancel()
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run did not return after context cancel")
+	}
+}
+
+func TestTLSServer_Run_ListenError(t *testing.T) {
+	s := newTestServer(t, NewMockConnService(t))
+	s.config.Listen = "invalid-address"
+
+	assert.Error(t, s.Run(context.Background()))
+}
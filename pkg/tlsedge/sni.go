@@ -0,0 +1,64 @@
+package tlsedge
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"time"
+)
+
+// errHelloRead aborts the handshake once the ClientHello has been read.
+var errHelloRead = errors.New("client hello read")
+
+// peekServerName reads the TLS ClientHello sent on conn and returns the server name (SNI) it asks for,
+// together with a connection that replays the bytes read so far before the rest of conn,
+// so that the handshake can still be completed by the local service.
+func peekServerName(conn net.Conn) (string, net.Conn, error) {
+	var (
+		buf        bytes.Buffer
+		serverName string
+		helloRead  bool
+	)
+
+	// The ClientHello is parsed by crypto/tls, which is stopped once it is read and never writes to conn.
+	err := tls.Server(readOnlyConn{r: io.TeeReader(conn, &buf)}, &tls.Config{
+		GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			serverName = hello.ServerName
+			helloRead = true
+
+			return nil, errHelloRead
+		},
+	}).Handshake()
+
+	if !helloRead {
+		return "", nil, err
+	}
+
+	return serverName, &peekedConn{Conn: conn, r: io.MultiReader(&buf, conn)}, nil
+}
+
+// peekedConn is a connection whose first bytes have already been read, and are replayed by r.
+type peekedConn struct {
+	net.Conn
+	r io.Reader
+}
+
+func (c *peekedConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
+
+// readOnlyConn is a net.Conn that reads from r and fails to write, for parsing a ClientHello without answering it.
+type readOnlyConn struct {
+	r io.Reader
+}
+
+func (c readOnlyConn) Read(p []byte) (int, error)       { return c.r.Read(p) }
+func (readOnlyConn) Write(_ []byte) (int, error)        { return 0, io.ErrClosedPipe }
+func (readOnlyConn) Close() error                       { return nil }
+func (readOnlyConn) LocalAddr() net.Addr                { return nil }
+func (readOnlyConn) RemoteAddr() net.Addr               { return nil }
+func (readOnlyConn) SetDeadline(_ time.Time) error      { return nil }
+func (readOnlyConn) SetReadDeadline(_ time.Time) error  { return nil }
+func (readOnlyConn) SetWriteDeadline(_ time.Time) error { return nil }
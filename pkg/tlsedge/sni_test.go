@@ -0,0 +1,64 @@
+package tlsedge
+
+import (
+	"crypto/tls"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPeekServerName(t *testing.T) {
+	srvSide, cliSide := net.Pipe()
+
+	defer func() { _ = srvSide.Close() }()
+
+	go func() {
+		// The handshake fails once the pipe is closed, which is fine as only the ClientHello is needed.
+		_ = tls.Client(cliSide, &tls.Config{ServerName: "key1.tls.example.com", MinVersion: tls.VersionTLS12}).Handshake()
+	}()
+
+	serverName, peeked, err := peekServerName(srvSide)
+	require.NoError(t, err)
+	assert.Equal(t, "key1.tls.example.com", serverName)
+
+	// The ClientHello is replayed to the reader of the peeked connection.
+	header := make([]byte, 3)
+	_, err = io.ReadFull(peeked, header)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{22, 3, 1}, header, "expected a TLS handshake record")
+
+	_ = cliSide.Close()
+}
+
+func TestPeekServerName_NoServerName(t *testing.T) {
+	srvSide, cliSide := net.Pipe()
+
+	defer func() { _ = srvSide.Close() }()
+
+	go func() {
+		_ = tls.Client(cliSide, &tls.Config{InsecureSkipVerify: true, MinVersion: tls.VersionTLS12}).Handshake() //nolint:gosec // no certificate is checked in this test
+	}()
+
+	serverName, _, err := peekServerName(srvSide)
+	require.NoError(t, err)
+	assert.Empty(t, serverName)
+
+	_ = cliSide.Close()
+}
+
+func TestPeekServerName_NotTLS(t *testing.T) {
+	srvSide, cliSide := net.Pipe()
+
+	defer func() { _ = srvSide.Close() }()
+
+	go func() {
+		_, _ = cliSide.Write([]byte("GET / HTTP/1.1\r\nHost: key1.tls.example.com\r\n\r\n"))
+		_ = cliSide.Close()
+	}()
+
+	_, _, err := peekServerName(srvSide)
+	assert.Error(t, err)
+}
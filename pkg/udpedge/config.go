@@ -0,0 +1,74 @@
+package udpedge
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Config holds configuration for the UDP edge server.
+// IdleTimeout bounds how long a session of a visitor is kept without datagrams in either direction.
+// MaxSessions bounds the sessions of each tunnel socket, and MaxSessionsPerIP those of a single visitor IP on it;
+// datagrams of new visitor addresses are dropped once either limit is reached. Defaults are used for zero values.
+type Config struct {
+	ListenHost       string        `mapstructure:"listen_host"`
+	Public           PublicConfig  `mapstructure:"public"`
+	PortRange        PortRange     `mapstructure:"port_range"`
+	IdleTimeout      time.Duration `mapstructure:"idle_timeout"`
+	MaxSessions      int           `mapstructure:"max_sessions"`
+	MaxSessionsPerIP int           `mapstructure:"max_sessions_per_ip"`
+}
+
+// PublicConfig defines the publicly advertised hostname for UDP endpoints.
+type PublicConfig struct {
+	Host string `mapstructure:"host"`
+}
+
+// PortRange defines the inclusive range of UDP ports available for allocation.
+type PortRange struct {
+	Min int `mapstructure:"min"`
+	Max int `mapstructure:"max"`
+}
+
+// Enabled reports whether UDP tunnels should be served.
+func (c *Config) Enabled() bool {
+	return c.PortRange.Min > 0 && c.PortRange.Max > 0
+}
+
+// Validate checks that the Config is valid. It returns an error describing the
+// first violation found.
+func (c *Config) Validate() error {
+	if c.ListenHost == "" {
+		return errors.New("listen_host must not be empty")
+	}
+
+	if c.Public.Host == "" {
+		return errors.New("public.host must not be empty")
+	}
+
+	if c.PortRange.Min < 1024 {
+		return fmt.Errorf("port_range.min must be >= 1024, got %d", c.PortRange.Min)
+	}
+
+	if c.PortRange.Max > 65535 {
+		return fmt.Errorf("port_range.max must be <= 65535, got %d", c.PortRange.Max)
+	}
+
+	if c.PortRange.Min > c.PortRange.Max {
+		return fmt.Errorf("port_range.min (%d) must be <= port_range.max (%d)", c.PortRange.Min, c.PortRange.Max)
+	}
+
+	if c.IdleTimeout < 0 {
+		return fmt.Errorf("idle_timeout must not be negative, got %s", c.IdleTimeout)
+	}
+
+	if c.MaxSessions < 0 {
+		return fmt.Errorf("max_sessions must not be negative, got %d", c.MaxSessions)
+	}
+
+	if c.MaxSessionsPerIP < 0 {
+		return fmt.Errorf("max_sessions_per_ip must not be negative, got %d", c.MaxSessionsPerIP)
+	}
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package udpedge
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	valid := Config{
+		ListenHost: "0.0.0.0",
+		Public:     PublicConfig{Host: "example.com"},
+		PortRange:  PortRange{Min: 10000, Max: 20000},
+	}
+
+	tests := []struct {
+		modify  func(c *Config)
+		name    string
+		wantErr bool
+	}{
+		{name: "valid", modify: func(*Config) {}},
+		{name: "idle timeout", modify: func(c *Config) { c.IdleTimeout = time.Minute }},
+		{name: "empty listen host", modify: func(c *Config) { c.ListenHost = "" }, wantErr: true},
+		{name: "empty public host", modify: func(c *Config) { c.Public.Host = "" }, wantErr: true},
+		{name: "privileged min", modify: func(c *Config) { c.PortRange.Min = 53 }, wantErr: true},
+		{name: "max above 65535", modify: func(c *Config) { c.PortRange.Max = 70000 }, wantErr: true},
+		{name: "min above max", modify: func(c *Config) { c.PortRange.Min = 30000 }, wantErr: true},
+		{name: "negative idle timeout", modify: func(c *Config) { c.IdleTimeout = -time.Second }, wantErr: true},
+		{name: "session limits", modify: func(c *Config) { c.MaxSessions = 100; c.MaxSessionsPerIP = 10 }},
+		{name: "negative max sessions", modify: func(c *Config) { c.MaxSessions = -1 }, wantErr: true},
+		{name: "negative max sessions per IP", modify: func(c *Config) { c.MaxSessionsPerIP = -1 }, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid
+			tt.modify(&cfg)
+
+			if tt.wantErr {
+				assert.Error(t, cfg.Validate())
+			} else {
+				assert.NoError(t, cfg.Validate())
+			}
+		})
+	}
+}
+
+func TestConfig_Enabled(t *testing.T) {
+	assert.False(t, (&Config{}).Enabled())
+	assert.True(t, (&Config{PortRange: PortRange{Min: 10000, Max: 20000}}).Enabled())
+}
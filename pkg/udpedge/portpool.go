@@ -0,0 +1,96 @@
+package udpedge
+
+import (
+	"errors"
+	"math/rand/v2"
+	"sync"
+
+	"github.com/ksysoev/make-it-public/pkg/metrics"
+)
+
+// ErrPortPoolExhausted is returned when no ports are available in the configured range.
+var ErrPortPoolExhausted = errors.New("no available ports in range")
+
+// portPool manages a set of UDP ports available for dynamic allocation.
+// Ports are selected randomly from the configured range.
+type portPool struct {
+	used map[int]struct{}
+	mu   sync.Mutex
+	min  int
+	max  int
+}
+
+// newPortPool creates a portPool for the inclusive range [minPort, maxPort].
+func newPortPool(minPort, maxPort int) *portPool {
+	p := &portPool{
+		min:  minPort,
+		max:  maxPort,
+		used: make(map[int]struct{}),
+	}
+
+	metrics.UDPPortsTotal.Set(float64(maxPort - minPort + 1))
+	p.reportUsage()
+
+	return p
+}
+
+// Allocate picks a random available port from the pool.
+// It returns ErrPortPoolExhausted if every port in the range is in use.
+func (p *portPool) Allocate() (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	size := p.max - p.min + 1
+	if len(p.used) >= size {
+		return 0, ErrPortPoolExhausted
+	}
+
+	// Random probing: fast path for sparse pools.
+	const maxProbes = 10
+
+	for range maxProbes {
+		port := p.min + rand.IntN(size) //nolint:gosec // non-cryptographic port selection is intentional
+		if _, inUse := p.used[port]; !inUse {
+			p.used[port] = struct{}{}
+			p.reportUsage()
+
+			return port, nil
+		}
+	}
+
+	// Linear fallback: used when random probing keeps colliding (dense pool).
+	for port := p.min; port <= p.max; port++ {
+		if _, inUse := p.used[port]; !inUse {
+			p.used[port] = struct{}{}
+			p.reportUsage()
+
+			return port, nil
+		}
+	}
+
+	// Unreachable: the size check above guarantees a free port exists.
+	panic("portPool: internal invariant violated — no free port found despite available count > 0")
+}
+
+// Release returns a port to the pool so it can be reused.
+func (p *portPool) Release(port int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.used, port)
+	p.reportUsage()
+}
+
+// Available returns the number of unallocated ports remaining in the pool.
+func (p *portPool) Available() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return (p.max - p.min + 1) - len(p.used)
+}
+
+// reportUsage publishes the number of unallocated ports to metrics.UDPPortsAvailable.
+// It must be called with p.mu held or before the pool is shared.
+func (p *portPool) reportUsage() {
+	metrics.UDPPortsAvailable.Set(float64((p.max - p.min + 1) - len(p.used)))
+}
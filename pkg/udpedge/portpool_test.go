@@ -0,0 +1,42 @@
+package udpedge
+
+import (
+	"testing"
+
+	"github.com/ksysoev/make-it-public/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPortPool_AllocateAndRelease(t *testing.T) {
+	p := newPortPool(10000, 10002) // 3 ports
+
+	assert.InDelta(t, 3, testutil.ToFloat64(metrics.UDPPortsTotal), 0)
+
+	allocated := make([]int, 0, 3)
+
+	for range 3 {
+		port, err := p.Allocate()
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, port, 10000)
+		assert.LessOrEqual(t, port, 10002)
+
+		allocated = append(allocated, port)
+	}
+
+	assert.ElementsMatch(t, []int{10000, 10001, 10002}, allocated)
+	assert.InDelta(t, 0, testutil.ToFloat64(metrics.UDPPortsAvailable), 0)
+
+	_, err := p.Allocate()
+	assert.ErrorIs(t, err, ErrPortPoolExhausted)
+
+	p.Release(allocated[1])
+
+	assert.Equal(t, 1, p.Available())
+	assert.InDelta(t, 1, testutil.ToFloat64(metrics.UDPPortsAvailable), 0)
+
+	port, err := p.Allocate()
+	require.NoError(t, err)
+	assert.Equal(t, allocated[1], port)
+}
@@ -0,0 +1,458 @@
+package udpedge
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/netip"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ksysoev/make-it-public/pkg/core"
+	"github.com/ksysoev/make-it-public/pkg/core/conn/meta"
+)
+
+const (
+	// defaultIdleTimeout is used when Config.IdleTimeout is not set.
+	defaultIdleTimeout = 60 * time.Second
+
+	// defaultMaxSessions is used when Config.MaxSessions is not set.
+	defaultMaxSessions = 1024
+
+	// defaultMaxSessionsPerIP is used when Config.MaxSessionsPerIP is not set.
+	defaultMaxSessionsPerIP = 64
+
+	// sessionQueueSize bounds the datagrams of a session waiting to be sent through the tunnel.
+	// Datagrams arriving while the queue is full are dropped, as a congested network would do.
+	sessionQueueSize = 64
+)
+
+// ConnService is the subset of core.Service required by the UDP edge server.
+type ConnService interface {
+	CheckClientIP(ctx context.Context, keyID, clientIP string) error
+	HandleUDPSession(ctx context.Context, keyID string, conn net.Conn, clientIP string) error
+	SetUDPEndpointAllocator(allocator core.UDPEndpointAllocator)
+	Draining() <-chan struct{}
+}
+
+// activeSocket tracks a per-keyID UDP socket, the sessions of the visitors sending datagrams to it,
+// the number of those sessions per visitor IP, the number of control connections of the keyID sharing it,
+// and its goroutines.
+type activeSocket struct {
+	conn       *net.UDPConn
+	cancel     context.CancelFunc
+	sessions   map[netip.AddrPort]*session
+	ipSessions map[netip.Addr]int
+	wg         sync.WaitGroup
+	mu         sync.Mutex
+	port       int
+	refs       int // control connections sharing the socket, guarded by UDPServer.mu
+}
+
+// session is the exchange of datagrams between a single visitor address and the tunnel.
+// Every session is served through its own connection to the MIT client.
+type session struct {
+	datagrams  chan []byte
+	done       <-chan struct{}
+	cancel     context.CancelFunc
+	lastActive atomic.Int64
+}
+
+func (s *session) touch() {
+	s.lastActive.Store(time.Now().UnixNano())
+}
+
+func (s *session) idleSince(now time.Time) time.Duration {
+	return now.Sub(time.Unix(0, s.lastActive.Load()))
+}
+
+// UDPServer dynamically allocates UDP sockets for each connected MIT client
+// that authenticated with a UDP token.  It implements core.UDPEndpointAllocator.
+type UDPServer struct {
+	connService ConnService
+	portPool    *portPool
+	sockets     map[string]*activeSocket
+	config      Config
+	mu          sync.Mutex
+	draining    atomic.Bool
+}
+
+// New validates cfg, creates a UDPServer, and injects it as the
+// UDPEndpointAllocator into connService.
+func New(cfg Config, connService ConnService) (*UDPServer, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid UDP edge config: %w", err)
+	}
+
+	cfg.IdleTimeout = cmp.Or(cfg.IdleTimeout, defaultIdleTimeout)
+	cfg.MaxSessions = cmp.Or(cfg.MaxSessions, defaultMaxSessions)
+	cfg.MaxSessionsPerIP = cmp.Or(cfg.MaxSessionsPerIP, defaultMaxSessionsPerIP)
+
+	s := &UDPServer{
+		connService: connService,
+		portPool:    newPortPool(cfg.PortRange.Min, cfg.PortRange.Max),
+		sockets:     make(map[string]*activeSocket),
+		config:      cfg,
+	}
+
+	connService.SetUDPEndpointAllocator(s)
+
+	return s, nil
+}
+
+// Run blocks until ctx is cancelled, then closes all active per-keyID sockets.
+// When the connection service starts draining, datagrams of new visitors are dropped
+// while the sessions already open keep being served until ctx is cancelled.
+func (s *UDPServer) Run(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+	case <-s.connService.Draining():
+		s.draining.Store(true)
+		<-ctx.Done()
+	}
+
+	s.closeAllSockets()
+
+	return nil
+}
+
+// Allocate binds a UDP socket for keyID and returns the public endpoint
+// string in the form "host:port".  Datagrams received on the socket are
+// grouped into sessions by the address of the visitor and routed through the tunnel.
+//
+// Control connections of the same keyID share its socket, so that pooled clients serve one endpoint:
+// further calls for a keyID with a socket return its endpoint.
+//
+// Allocate is called by core.Service when a UDP MIT client completes
+// authentication.  Every successful call must be balanced by a call to Release.
+func (s *UDPServer) Allocate(ctx context.Context, keyID string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.draining.Load() {
+		return "", fmt.Errorf("allocate keyID=%s: %w", keyID, core.ErrDraining)
+	}
+
+	if as, exists := s.sockets[keyID]; exists {
+		as.refs++
+
+		return net.JoinHostPort(s.config.Public.Host, strconv.Itoa(as.port)), nil
+	}
+
+	port, err := s.portPool.Allocate()
+	if err != nil {
+		return "", fmt.Errorf("allocate port for keyID=%s: %w", keyID, err)
+	}
+
+	addr := net.JoinHostPort(s.config.ListenHost, strconv.Itoa(port))
+
+	udpAddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		s.portPool.Release(port)
+		return "", fmt.Errorf("resolve %s for keyID=%s: %w", addr, keyID, err)
+	}
+
+	conn, err := net.ListenUDP("udp", udpAddr)
+	if err != nil {
+		s.portPool.Release(port)
+		return "", fmt.Errorf("listen on %s for keyID=%s: %w", addr, keyID, err)
+	}
+
+	// The socket outlives the control connection that allocated it when others share it, so it is only
+	// closed by Release.
+	socketCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+
+	as := &activeSocket{
+		conn:       conn,
+		cancel:     cancel,
+		sessions:   make(map[netip.AddrPort]*session),
+		ipSessions: make(map[netip.Addr]int),
+		port:       port,
+		refs:       1,
+	}
+
+	s.sockets[keyID] = as
+
+	as.wg.Go(func() { s.readLoop(socketCtx, as, keyID) })
+	as.wg.Go(func() { s.reapIdleSessions(socketCtx, as) })
+
+	endpoint := net.JoinHostPort(s.config.Public.Host, strconv.Itoa(port))
+
+	slog.InfoContext(ctx, "UDP socket allocated",
+		slog.String("keyID", keyID),
+		slog.String("endpoint", endpoint),
+		slog.String("listen", addr))
+
+	return endpoint, nil
+}
+
+// Release removes a control connection of keyID from its socket.  Once no control connection
+// shares the socket, it is closed, its sessions are ended and its port is returned to the pool.
+// It is safe to call Release on a keyID that has already been released.
+//
+// Release is called by core.Service via a deferred call in HandleReverseConn so
+// it executes when the MIT client disconnects.
+func (s *UDPServer) Release(keyID string) {
+	s.mu.Lock()
+
+	as, exists := s.sockets[keyID]
+	if !exists {
+		s.mu.Unlock()
+		return
+	}
+
+	if as.refs--; as.refs > 0 {
+		s.mu.Unlock()
+		return
+	}
+
+	delete(s.sockets, keyID)
+	s.mu.Unlock()
+
+	s.closeSocket(keyID, as)
+}
+
+// closeSocket closes as, waits for its sessions to end and returns its port to the pool.
+func (s *UDPServer) closeSocket(keyID string, as *activeSocket) {
+	as.cancel()
+
+	_ = as.conn.Close()
+
+	// Sessions are only started by the read loop, which is tracked by the same WaitGroup,
+	// so no session can be added once Wait observes a zero counter.
+	as.wg.Wait()
+
+	s.portPool.Release(as.port)
+
+	slog.Info("UDP socket released", slog.String("keyID", keyID), slog.Int("port", as.port))
+}
+
+// readLoop reads the datagrams arriving on the socket of keyID and queues them to the session of their sender,
+// starting a new session for senders that have none.
+func (s *UDPServer) readLoop(ctx context.Context, as *activeSocket, keyID string) {
+	buf := make([]byte, meta.MaxDatagramSize)
+
+	for {
+		n, addr, err := as.conn.ReadFromUDPAddrPort(buf)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				return // normal shutdown via Release or server stop
+			}
+
+			slog.DebugContext(ctx, "UDP read error", slog.String("keyID", keyID), slog.Any("error", err))
+
+			continue
+		}
+
+		sess := s.sessionFor(ctx, as, keyID, addr)
+		if sess == nil {
+			continue
+		}
+
+		select {
+		case sess.datagrams <- slices.Clone(buf[:n]):
+			sess.touch()
+		default:
+			slog.DebugContext(ctx, "UDP datagram dropped, session queue is full",
+				slog.String("keyID", keyID),
+				slog.String("addr", addr.String()))
+		}
+	}
+}
+
+// sessionFor returns the session of addr, starting a new one if there is none or it is ending.
+// While the server is draining, or once the socket or the IP of addr has reached its session limit,
+// no sessions are started for new senders and nil is returned.
+func (s *UDPServer) sessionFor(ctx context.Context, as *activeSocket, keyID string, addr netip.AddrPort) *session {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	old, replacing := as.sessions[addr]
+	if replacing {
+		select {
+		case <-old.done:
+		default:
+			return old
+		}
+	}
+
+	if s.draining.Load() {
+		return nil
+	}
+
+	// An ending session is replaced by the new one, so it does not count towards the limits.
+	ip := addr.Addr().Unmap()
+	if !replacing && (len(as.sessions) >= s.config.MaxSessions || as.ipSessions[ip] >= s.config.MaxSessionsPerIP) {
+		slog.DebugContext(ctx, "UDP datagram dropped, session limit reached",
+			slog.String("keyID", keyID),
+			slog.String("addr", addr.String()))
+
+		return nil
+	}
+
+	if !replacing {
+		as.ipSessions[ip]++
+	}
+
+	sessCtx, cancel := context.WithCancel(ctx)
+
+	sess := &session{
+		datagrams: make(chan []byte, sessionQueueSize),
+		done:      sessCtx.Done(),
+		cancel:    cancel,
+	}
+
+	sess.touch()
+	as.sessions[addr] = sess
+
+	as.wg.Go(func() {
+		defer as.removeSession(addr, sess)
+
+		s.serveSession(sessCtx, as, keyID, addr, sess)
+	})
+
+	return sess
+}
+
+// serveSession routes the datagrams of a session through the tunnel and sends the replies back to addr.
+// Sessions of client IPs rejected by the token's CIDR rules are kept until they are idle, so that their
+// datagrams are dropped without checking the rules again for each of them.
+func (s *UDPServer) serveSession(ctx context.Context, as *activeSocket, keyID string, addr netip.AddrPort, sess *session) {
+	clientIP := addr.Addr().Unmap().String()
+
+	slog.DebugContext(ctx, "UDP session started",
+		slog.String("keyID", keyID),
+		slog.String("addr", addr.String()))
+
+	if err := s.connService.CheckClientIP(ctx, keyID, clientIP); err != nil {
+		slog.DebugContext(ctx, "UDP session rejected",
+			slog.String("keyID", keyID),
+			slog.String("clientIP", clientIP),
+			slog.Any("error", err))
+
+		<-ctx.Done()
+
+		return
+	}
+
+	edgeConn, tunnelConn := net.Pipe()
+
+	stop := context.AfterFunc(ctx, func() { _ = edgeConn.Close() })
+	defer stop()
+
+	var wg sync.WaitGroup
+
+	wg.Go(func() {
+		defer func() { _ = edgeConn.Close() }()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case datagram := <-sess.datagrams:
+				if err := meta.WriteDatagram(edgeConn, datagram); err != nil {
+					return
+				}
+			}
+		}
+	})
+
+	wg.Go(func() {
+		defer func() { _ = edgeConn.Close() }()
+
+		buf := make([]byte, meta.MaxDatagramSize)
+
+		for {
+			datagram, err := meta.ReadDatagram(edgeConn, buf)
+			if err != nil {
+				return
+			}
+
+			if _, err := as.conn.WriteToUDPAddrPort(datagram, addr); err != nil {
+				slog.DebugContext(ctx, "failed to send UDP datagram", slog.String("addr", addr.String()), slog.Any("error", err))
+			}
+
+			sess.touch()
+		}
+	})
+
+	err := s.connService.HandleUDPSession(ctx, keyID, tunnelConn, clientIP)
+
+	_ = tunnelConn.Close()
+	sess.cancel()
+	wg.Wait()
+
+	switch {
+	case errors.Is(err, core.ErrQuotaExceeded):
+		slog.InfoContext(ctx, "UDP session refused, traffic quota exceeded",
+			slog.String("keyID", keyID),
+			slog.String("clientIP", clientIP))
+	case err != nil:
+		slog.DebugContext(ctx, "UDP session closed",
+			slog.String("keyID", keyID),
+			slog.String("clientIP", clientIP),
+			slog.Any("error", err))
+	}
+}
+
+// reapIdleSessions ends the sessions of the socket that have not exchanged datagrams for longer than the idle timeout.
+func (s *UDPServer) reapIdleSessions(ctx context.Context, as *activeSocket) {
+	ticker := time.NewTicker(s.config.IdleTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			as.mu.Lock()
+
+			for _, sess := range as.sessions {
+				if sess.idleSince(now) > s.config.IdleTimeout {
+					sess.cancel()
+				}
+			}
+
+			as.mu.Unlock()
+		}
+	}
+}
+
+// removeSession forgets the session of addr and releases its place in the session limits,
+// unless it has already been replaced by a newer one.
+func (as *activeSocket) removeSession(addr netip.AddrPort, sess *session) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	sess.cancel()
+
+	if as.sessions[addr] != sess {
+		return
+	}
+
+	delete(as.sessions, addr)
+
+	ip := addr.Addr().Unmap()
+	if as.ipSessions[ip]--; as.ipSessions[ip] <= 0 {
+		delete(as.ipSessions, ip)
+	}
+}
+
+// closeAllSockets shuts down every active socket, whatever the number of control connections
+// sharing it.  Called on server stop.
+func (s *UDPServer) closeAllSockets() {
+	s.mu.Lock()
+	sockets := s.sockets
+	s.sockets = make(map[string]*activeSocket)
+	s.mu.Unlock()
+
+	for keyID, as := range sockets {
+		s.closeSocket(keyID, as)
+	}
+}
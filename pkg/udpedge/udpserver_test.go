@@ -0,0 +1,434 @@
+package udpedge
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ksysoev/make-it-public/pkg/core"
+	"github.com/ksysoev/make-it-public/pkg/core/conn/meta"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// findFreePort asks the OS for an available UDP port and returns it.
+// The port is briefly bound then released; there is a small TOCTOU window,
+// but it is far safer than hard-coding a port that may be in use on CI.
+func findFreePort(t *testing.T) int {
+	t.Helper()
+
+	c, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	addr, ok := c.LocalAddr().(*net.UDPAddr)
+	require.True(t, ok, "expected UDP address")
+
+	require.NoError(t, c.Close())
+
+	return addr.Port
+}
+
+// validConfig returns a Config that passes Validate() with a single-port range.
+func validConfig(t *testing.T) Config {
+	t.Helper()
+
+	port := findFreePort(t)
+
+	return Config{
+		ListenHost: "127.0.0.1",
+		Public:     PublicConfig{Host: "example.com"},
+		PortRange:  PortRange{Min: port, Max: port},
+	}
+}
+
+// allocate allocates a socket for keyID and returns a UDP connection of a visitor sending datagrams to it.
+func allocate(t *testing.T, srv *UDPServer, keyID string) *net.UDPConn {
+	t.Helper()
+
+	endpoint, err := srv.Allocate(context.Background(), keyID)
+	require.NoError(t, err)
+
+	_, port, err := net.SplitHostPort(endpoint)
+	require.NoError(t, err)
+
+	addr, err := net.ResolveUDPAddr("udp", net.JoinHostPort("127.0.0.1", port))
+	require.NoError(t, err)
+
+	c, err := net.DialUDP("udp", nil, addr)
+	require.NoError(t, err)
+
+	t.Cleanup(func() { _ = c.Close() })
+
+	return c
+}
+
+// echo mimics a client behind the tunnel, which answers every datagram with the same datagram prefixed by "echo:".
+func echo(_ context.Context, _ string, conn net.Conn, _ string) error {
+	buf := make([]byte, meta.MaxDatagramSize)
+
+	for {
+		datagram, err := meta.ReadDatagram(conn, buf)
+		if err != nil {
+			return nil
+		}
+
+		if err := meta.WriteDatagram(conn, append([]byte("echo:"), datagram...)); err != nil {
+			return nil
+		}
+	}
+}
+
+func readDatagram(t *testing.T, c *net.UDPConn) string {
+	t.Helper()
+
+	require.NoError(t, c.SetReadDeadline(time.Now().Add(2*time.Second)))
+
+	buf := make([]byte, 1024)
+
+	n, err := c.Read(buf)
+	require.NoError(t, err)
+
+	return string(buf[:n])
+}
+
+func TestNew_InvalidConfig(t *testing.T) {
+	_, err := New(Config{}, NewMockConnService(t))
+	assert.Error(t, err)
+}
+
+func TestNew_InjectsAllocator(t *testing.T) {
+	svc := NewMockConnService(t)
+	svc.EXPECT().SetUDPEndpointAllocator(mock.Anything)
+
+	srv, err := New(validConfig(t), svc)
+	require.NoError(t, err)
+
+	assert.Equal(t, defaultIdleTimeout, srv.config.IdleTimeout)
+	assert.Equal(t, defaultMaxSessions, srv.config.MaxSessions)
+	assert.Equal(t, defaultMaxSessionsPerIP, srv.config.MaxSessionsPerIP)
+}
+
+func TestUDPServer_Allocate(t *testing.T) {
+	svc := NewMockConnService(t)
+	svc.EXPECT().SetUDPEndpointAllocator(mock.Anything)
+
+	cfg := validConfig(t)
+
+	srv, err := New(cfg, svc)
+	require.NoError(t, err)
+
+	defer srv.closeAllSockets()
+
+	endpoint, err := srv.Allocate(context.Background(), "testkey")
+	require.NoError(t, err)
+
+	host, port, err := net.SplitHostPort(endpoint)
+	require.NoError(t, err)
+	assert.Equal(t, "example.com", host)
+	assert.Equal(t, strconv.Itoa(cfg.PortRange.Min), port)
+
+	// Pooled control connections of a keyID share its socket.
+	shared, err := srv.Allocate(context.Background(), "testkey")
+	require.NoError(t, err)
+	assert.Equal(t, endpoint, shared)
+
+	_, err = srv.Allocate(context.Background(), "otherkey")
+	assert.ErrorIs(t, err, ErrPortPoolExhausted)
+}
+
+func TestUDPServer_Release_Shared(t *testing.T) {
+	svc := NewMockConnService(t)
+	svc.EXPECT().SetUDPEndpointAllocator(mock.Anything)
+
+	srv, err := New(validConfig(t), svc)
+	require.NoError(t, err)
+
+	defer srv.closeAllSockets()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	_, err = srv.Allocate(ctx, "shared")
+	require.NoError(t, err)
+
+	_, err = srv.Allocate(context.Background(), "shared")
+	require.NoError(t, err)
+
+	// The socket stays open once the control connection that allocated it is gone.
+	cancel()
+	srv.Release("shared")
+
+	srv.mu.Lock()
+	as := srv.sockets["shared"]
+	srv.mu.Unlock()
+
+	require.NotNil(t, as)
+	assert.Equal(t, 0, srv.portPool.Available())
+
+	srv.Release("shared")
+
+	assert.Equal(t, 1, srv.portPool.Available())
+}
+
+func TestUDPServer_Release(t *testing.T) {
+	svc := NewMockConnService(t)
+	svc.EXPECT().SetUDPEndpointAllocator(mock.Anything)
+
+	srv, err := New(validConfig(t), svc)
+	require.NoError(t, err)
+
+	_, err = srv.Allocate(context.Background(), "relkey")
+	require.NoError(t, err)
+
+	assert.Equal(t, 0, srv.portPool.Available())
+
+	srv.Release("relkey")
+
+	assert.Equal(t, 1, srv.portPool.Available())
+	assert.NotPanics(t, func() { srv.Release("relkey") })
+
+	// The port can be bound again once it is released.
+	_, err = srv.Allocate(context.Background(), "relkey")
+	require.NoError(t, err)
+
+	srv.Release("relkey")
+}
+
+func TestUDPServer_RelaysDatagrams(t *testing.T) {
+	svc := NewMockConnService(t)
+	svc.EXPECT().SetUDPEndpointAllocator(mock.Anything)
+	svc.EXPECT().CheckClientIP(mock.Anything, "routekey", "127.0.0.1").Return(nil).Once()
+	svc.EXPECT().HandleUDPSession(mock.Anything, "routekey", mock.Anything, "127.0.0.1").RunAndReturn(echo).Once()
+
+	srv, err := New(validConfig(t), svc)
+	require.NoError(t, err)
+
+	c := allocate(t, srv, "routekey")
+
+	defer srv.closeAllSockets()
+
+	// Datagrams of the same visitor address share a session.
+	for _, msg := range []string{"ping", "pong"} {
+		_, err = c.Write([]byte(msg))
+		require.NoError(t, err)
+
+		assert.Equal(t, "echo:"+msg, readDatagram(t, c))
+	}
+}
+
+func TestUDPServer_SessionsPerAddress(t *testing.T) {
+	svc := NewMockConnService(t)
+	svc.EXPECT().SetUDPEndpointAllocator(mock.Anything)
+	svc.EXPECT().CheckClientIP(mock.Anything, "routekey", "127.0.0.1").Return(nil).Twice()
+	svc.EXPECT().HandleUDPSession(mock.Anything, "routekey", mock.Anything, "127.0.0.1").RunAndReturn(echo).Twice()
+
+	srv, err := New(validConfig(t), svc)
+	require.NoError(t, err)
+
+	c1 := allocate(t, srv, "routekey")
+
+	defer srv.closeAllSockets()
+
+	c2, err := net.DialUDP("udp", nil, c1.RemoteAddr().(*net.UDPAddr))
+	require.NoError(t, err)
+
+	defer func() { _ = c2.Close() }()
+
+	_, err = c1.Write([]byte("one"))
+	require.NoError(t, err)
+
+	_, err = c2.Write([]byte("two"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "echo:one", readDatagram(t, c1))
+	assert.Equal(t, "echo:two", readDatagram(t, c2))
+}
+
+func TestUDPServer_SessionLimits(t *testing.T) {
+	tests := []struct {
+		name        string
+		secondIP    string
+		maxSessions int
+		maxPerIP    int
+	}{
+		{name: "per socket", secondIP: "127.0.0.2", maxSessions: 1, maxPerIP: 10},
+		{name: "per IP", secondIP: "127.0.0.1", maxSessions: 10, maxPerIP: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sessionDone := make(chan struct{}, 2)
+
+			svc := NewMockConnService(t)
+			svc.EXPECT().SetUDPEndpointAllocator(mock.Anything)
+			svc.EXPECT().CheckClientIP(mock.Anything, "limitkey", mock.Anything).Return(nil)
+			svc.EXPECT().HandleUDPSession(mock.Anything, "limitkey", mock.Anything, mock.Anything).
+				RunAndReturn(func(ctx context.Context, keyID string, conn net.Conn, clientIP string) error {
+					defer func() { sessionDone <- struct{}{} }()
+
+					return echo(ctx, keyID, conn, clientIP)
+				})
+
+			cfg := validConfig(t)
+			cfg.MaxSessions = tt.maxSessions
+			cfg.MaxSessionsPerIP = tt.maxPerIP
+			cfg.IdleTimeout = 200 * time.Millisecond
+
+			srv, err := New(cfg, svc)
+			require.NoError(t, err)
+
+			c1 := allocate(t, srv, "limitkey")
+
+			defer srv.closeAllSockets()
+
+			c2, err := net.DialUDP("udp", &net.UDPAddr{IP: net.ParseIP(tt.secondIP)}, c1.RemoteAddr().(*net.UDPAddr))
+			require.NoError(t, err)
+
+			defer func() { _ = c2.Close() }()
+
+			_, err = c1.Write([]byte("one"))
+			require.NoError(t, err)
+			assert.Equal(t, "echo:one", readDatagram(t, c1))
+
+			// The limit is reached, so the datagram of the new sender is dropped.
+			_, err = c2.Write([]byte("two"))
+			require.NoError(t, err)
+
+			require.NoError(t, c2.SetReadDeadline(time.Now().Add(100*time.Millisecond)))
+
+			_, err = c2.Read(make([]byte, 16))
+
+			var netErr net.Error
+			require.ErrorAs(t, err, &netErr)
+			assert.True(t, netErr.Timeout())
+
+			select {
+			case <-sessionDone:
+			case <-time.After(2 * time.Second):
+				t.Fatal("idle session was not ended")
+			}
+
+			// Once the first session ended, the new sender gets a session.
+			require.Eventually(t, func() bool {
+				_, err := c2.Write([]byte("three"))
+				require.NoError(t, err)
+
+				require.NoError(t, c2.SetReadDeadline(time.Now().Add(50*time.Millisecond)))
+
+				buf := make([]byte, 16)
+				n, err := c2.Read(buf)
+
+				return err == nil && string(buf[:n]) == "echo:three"
+			}, 2*time.Second, 10*time.Millisecond)
+		})
+	}
+}
+
+func TestUDPServer_IdleSessionEnds(t *testing.T) {
+	sessionDone := make(chan struct{}, 2)
+
+	svc := NewMockConnService(t)
+	svc.EXPECT().SetUDPEndpointAllocator(mock.Anything)
+	svc.EXPECT().CheckClientIP(mock.Anything, "idlekey", "127.0.0.1").Return(nil).Twice()
+	svc.EXPECT().HandleUDPSession(mock.Anything, "idlekey", mock.Anything, "127.0.0.1").
+		RunAndReturn(func(ctx context.Context, keyID string, conn net.Conn, clientIP string) error {
+			defer func() { sessionDone <- struct{}{} }()
+
+			return echo(ctx, keyID, conn, clientIP)
+		}).Twice()
+
+	cfg := validConfig(t)
+	cfg.IdleTimeout = 100 * time.Millisecond
+
+	srv, err := New(cfg, svc)
+	require.NoError(t, err)
+
+	c := allocate(t, srv, "idlekey")
+
+	defer srv.closeAllSockets()
+
+	_, err = c.Write([]byte("first"))
+	require.NoError(t, err)
+	assert.Equal(t, "echo:first", readDatagram(t, c))
+
+	select {
+	case <-sessionDone:
+	case <-time.After(2 * time.Second):
+		t.Fatal("idle session was not ended")
+	}
+
+	// A datagram arriving after the session ended starts a new one.
+	_, err = c.Write([]byte("second"))
+	require.NoError(t, err)
+	assert.Equal(t, "echo:second", readDatagram(t, c))
+}
+
+func TestUDPServer_RejectsForbiddenClientIP(t *testing.T) {
+	svc := NewMockConnService(t)
+	svc.EXPECT().SetUDPEndpointAllocator(mock.Anything)
+	// The client IP is checked once per session rather than once per datagram.
+	svc.EXPECT().CheckClientIP(mock.Anything, "denykey", "127.0.0.1").Return(core.ErrForbidden).Once()
+
+	srv, err := New(validConfig(t), svc)
+	require.NoError(t, err)
+
+	c := allocate(t, srv, "denykey")
+
+	defer srv.closeAllSockets()
+
+	for range 3 {
+		_, err = c.Write([]byte("ping"))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, c.SetReadDeadline(time.Now().Add(200*time.Millisecond)))
+
+	_, err = c.Read(make([]byte, 16))
+
+	var netErr net.Error
+	require.ErrorAs(t, err, &netErr)
+	assert.True(t, netErr.Timeout())
+}
+
+func TestUDPServer_Run_Draining(t *testing.T) {
+	draining := make(chan struct{})
+
+	svc := NewMockConnService(t)
+	svc.EXPECT().SetUDPEndpointAllocator(mock.Anything)
+	svc.EXPECT().Draining().Return(draining)
+
+	srv, err := New(validConfig(t), svc)
+	require.NoError(t, err)
+
+	c := allocate(t, srv, "drainkey")
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+
+	go func() { done <- srv.Run(ctx) }()
+
+	close(draining)
+
+	require.Eventually(t, srv.draining.Load, time.Second, 10*time.Millisecond)
+
+	// Datagrams of new visitors are dropped without starting a session.
+	_, err = c.Write([]byte("ping"))
+	require.NoError(t, err)
+
+	_, err = srv.Allocate(context.Background(), "newkey")
+	assert.ErrorIs(t, err, core.ErrDraining)
+
+	cancel()
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run did not return after context cancel")
+	}
+
+	assert.Equal(t, 1, srv.portPool.Available())
+}